@@ -1,6 +1,18 @@
 #!/bin/bash
 
 # BUILD
+#
+# Builds release assets named after their platform into dist/, e.g. melodix_1.4.0_linux_amd64.tar.gz
+# (.zip for Windows), with their SHA-256 sums in dist/checksums.txt, which the update command installs from.
+#
+#   TARGETS             platforms to build as os/arch, the current one by default; cross builds need a
+#                       C cross compiler in CC since SQLite uses cgo
+#   MINISIGN_PUBLIC_KEY minisign public key compiled into the binaries to verify updates, read from
+#                       minisign.pub when unset; builds without it can't install updates
+#   MINISIGN_SECRET_KEY secret key signing checksums.txt into checksums.txt.minisig, ~/.minisign/minisign.key
+#                       by default
+
+set -e
 
 # Get Go version
 GO_VERSION=$(go version | awk '{print $3}')
@@ -8,6 +20,53 @@ GO_VERSION=$(go version | awk '{print $3}')
 # Get the build date
 BUILD_DATE=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
 
-go build -o melodix -ldflags "-s -X github.com/keshon/melodix-discord-player/internal/version.BuildDate=$BUILD_DATE -X github.com/keshon/melodix-discord-player/internal/version.GoVersion=$GO_VERSION" cmd/main.go
+# Get the release version, the newest of the changelog unless set
+VERSION=${VERSION:-$(grep -m 1 '^## ' internal/version/CHANGELOG.md | sed -E 's/^## v?([^ ]+).*/\1/')}
+VERSION=${VERSION:-dev}
+
+TARGETS=${TARGETS:-$(go env GOOS)/$(go env GOARCH)}
+
+if [ -z "$MINISIGN_PUBLIC_KEY" ] && [ -f minisign.pub ]; then
+    MINISIGN_PUBLIC_KEY=$(sed -n 2p minisign.pub)
+fi
+if [ -z "$MINISIGN_PUBLIC_KEY" ]; then
+    echo "Warning: no MINISIGN_PUBLIC_KEY, the binaries won't install updates" >&2
+fi
+
+PACKAGE=github.com/keshon/melodix-discord-player/internal
+
+rm -rf dist
+mkdir -p dist
+
+for TARGET in $TARGETS; do
+    GOOS=${TARGET%/*}
+    GOARCH=${TARGET#*/}
+    NAME=melodix_${VERSION}_${GOOS}_${GOARCH}
+    BINARY=melodix
+    if [ "$GOOS" = "windows" ]; then
+        BINARY=melodix.exe
+    fi
+
+    mkdir -p "dist/$NAME"
+    CGO_ENABLED=1 GOOS=$GOOS GOARCH=$GOARCH go build -o "dist/$NAME/$BINARY" -ldflags "-s -X $PACKAGE/version.Version=$VERSION -X $PACKAGE/version.BuildDate=$BUILD_DATE -X $PACKAGE/version.GoVersion=$GO_VERSION -X $PACKAGE/selfupdate.PublicKey=$MINISIGN_PUBLIC_KEY" cmd/main.go
+
+    if command -v upx >/dev/null && [ "$GOOS" != "darwin" ]; then
+        upx "dist/$NAME/$BINARY"
+    fi
+
+    if [ "$GOOS" = "windows" ]; then
+        (cd "dist/$NAME" && zip -q "../$NAME.zip" "$BINARY")
+    else
+        tar -czf "dist/$NAME.tar.gz" -C "dist/$NAME" "$BINARY"
+    fi
+    rm -rf "dist/$NAME"
+done
+
+(cd dist && sha256sum melodix_* > checksums.txt)
 
-upx melodix
\ No newline at end of file
+# The update command only installs releases whose checksums are signed with the compiled-in key
+if command -v minisign >/dev/null; then
+    minisign -S -s "${MINISIGN_SECRET_KEY:-$HOME/.minisign/minisign.key}" -m dist/checksums.txt
+else
+    echo "Warning: minisign not found, sign dist/checksums.txt before publishing the release" >&2
+fi
@@ -0,0 +1,326 @@
+// Command loadtest streams a file or URL to many simulated guild players at once, using the real
+// ffmpeg encode and streaming pipeline with null voice connections, and reports CPU, memory and
+// goroutine counts for capacity planning.
+//
+//	go run ./cmd/loadtest -players 100 -input song.mp3 -duration 10m
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/music/pkg/dca"
+)
+
+func main() {
+	players := flag.Int("players", 10, "number of simulated guild players")
+	input := flag.String("input", "", "file or URL every player streams, restarted when it ends")
+	duration := flag.Duration("duration", 5*time.Minute, "how long to run")
+	interval := flag.Duration("interval", 10*time.Second, "how often to report")
+	stagger := flag.Duration("stagger", 100*time.Millisecond, "delay between starting players")
+	ffmpegPath := flag.String("ffmpeg", "", "ffmpeg binary directory, DCA_FFMPEG_BINARY_PATH of .env by default")
+	flag.Parse()
+
+	if *input == "" || *players < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: loadtest -input <file or URL> [-players N] [-duration 5m] [-interval 10s]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	encodeOptions, streamOptions := pipelineOptions()
+	if *ffmpegPath != "" {
+		encodeOptions.FfmpegBinaryPath = *ffmpegPath
+	}
+
+	test := &loadTest{input: *input, encodeOptions: encodeOptions, streamOptions: streamOptions, stop: make(chan struct{})}
+
+	fmt.Printf("Starting %d players streaming %v for %v (jitter buffer %d frames)\n", *players, *input, *duration, streamOptions.BufferFrames)
+	test.started = time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < *players; i++ {
+		player := &simulatedPlayer{}
+		test.players = append(test.players, player)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			test.run(player)
+		}()
+		time.Sleep(*stagger)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	deadline := time.After(*duration)
+
+	test.report()
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			test.report()
+		case <-deadline:
+			break loop
+		case <-interrupt:
+			fmt.Println("Interrupted")
+			break loop
+		}
+	}
+
+	close(test.stop)
+	wg.Wait()
+
+	test.summary()
+}
+
+// pipelineOptions returns encode and stream options of .env like the bot uses, or the dca defaults without it.
+func pipelineOptions() (*dca.EncodeOptions, dca.StreamOptions) {
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Warnf("Using default encode options, config not loaded: %v", err)
+		options := *dca.StdEncodeOptions
+		return &options, dca.StreamOptions{BufferFrames: 10, MaxBufferFrames: 100}
+	}
+
+	encodeOptions := &dca.EncodeOptions{
+		Volume:                  1.0,
+		FrameDuration:           config.DcaFrameDuration,
+		Bitrate:                 config.DcaBitrate,
+		PacketLoss:              config.DcaPacketLoss,
+		RawOutput:               config.DcaRawOutput,
+		Application:             config.DcaApplication,
+		CompressionLevel:        config.DcaCompressionLevel,
+		BufferedFrames:          config.DcaBufferedFrames,
+		VBR:                     config.DcaVBR,
+		ReconnectAtEOF:          config.DcaReconnectAtEOF,
+		ReconnectStreamed:       config.DcaReconnectStreamed,
+		ReconnectOnNetworkError: config.DcaReconnectOnNetworkError,
+		ReconnectOnHttpError:    config.DcaReconnectOnHttpError,
+		ReconnectDelayMax:       config.DcaReconnectDelayMax,
+		FfmpegBinaryPath:        config.DcaFfmpegBinaryPath,
+		UserAgent:               config.DcaUserAgent,
+	}
+	streamOptions := dca.StreamOptions{
+		BufferFrames:    config.DcaStreamBufferFrames,
+		MaxBufferFrames: config.DcaStreamBufferMaxFrames,
+	}
+
+	return encodeOptions, streamOptions
+}
+
+type loadTest struct {
+	input         string
+	encodeOptions *dca.EncodeOptions
+	streamOptions dca.StreamOptions
+	players       []*simulatedPlayer
+	stop          chan struct{}
+
+	started     time.Time
+	lastReport  time.Time
+	lastCPU     time.Duration
+	lastFrames  int
+	peakCPU     float64
+	peakRSS     int64
+	peakFFmpeg  int64
+	peakRoutine int
+}
+
+// simulatedPlayer is a guild player whose voice connection discards frames.
+type simulatedPlayer struct {
+	sync.Mutex
+	encoding  *dca.EncodeSession
+	streaming *dca.StreamingSession
+	finished  dca.StreamStats // totals of songs that ended
+	plays     int
+	failures  int
+}
+
+// run streams the input over and over until the test stops.
+func (t *loadTest) run(p *simulatedPlayer) {
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		encoding, err := dca.EncodeFile(t.input, t.encodeOptions)
+		if err != nil {
+			slog.Errorf("Error starting encoder: %v", err)
+			p.Lock()
+			p.failures++
+			p.Unlock()
+			time.Sleep(time.Second)
+			continue
+		}
+
+		vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte, 2)}
+		sinkDone := make(chan struct{})
+		go nullSink(vc, encoding.FrameDuration(), sinkDone)
+
+		done := make(chan error, 1)
+		streaming := dca.NewStreamWithOptions(encoding, vc, done, t.streamOptions)
+
+		p.Lock()
+		p.encoding, p.streaming = encoding, streaming
+		p.plays++
+		p.Unlock()
+
+		select {
+		case err = <-done:
+			if err == io.EOF {
+				err = nil
+			}
+		case <-t.stop:
+			streaming.Stop()
+		}
+
+		encoding.Cleanup()
+		close(sinkDone)
+
+		p.Lock()
+		stats := streaming.Stats()
+		p.finished.FramesSent += stats.FramesSent
+		p.finished.FramesLate += stats.FramesLate
+		p.finished.FramesDropped += stats.FramesDropped
+		p.finished.SendStalls += stats.SendStalls
+		p.finished.BytesSent += stats.BytesSent
+		if err != nil || stats.FramesSent == 0 {
+			p.failures++
+		}
+		p.encoding, p.streaming = nil, nil
+		p.Unlock()
+
+		if err != nil {
+			slog.Warnf("Stream ended with an error: %v", err)
+		} else if stats.FramesSent == 0 {
+			slog.Warnf("Stream ended without frames: %v", encoding.FFMPEGMessages())
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// nullSink consumes frames at the pace Discord's voice connection would send them.
+func nullSink(vc *discordgo.VoiceConnection, frameDuration time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-vc.OpusSend:
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// totals sums counters of all players and resource usage of their running encoders.
+func (t *loadTest) totals() (stats dca.StreamStats, encoders int, ffmpegCPU float64, ffmpegRSS int64, plays, failures int) {
+	for _, p := range t.players {
+		p.Lock()
+		stats.FramesSent += p.finished.FramesSent
+		stats.FramesLate += p.finished.FramesLate
+		stats.FramesDropped += p.finished.FramesDropped
+		stats.SendStalls += p.finished.SendStalls
+		stats.BytesSent += p.finished.BytesSent
+		plays += p.plays
+		failures += p.failures
+
+		if p.streaming != nil {
+			current := p.streaming.Stats()
+			stats.FramesSent += current.FramesSent
+			stats.FramesLate += current.FramesLate
+			stats.FramesDropped += current.FramesDropped
+			stats.SendStalls += current.SendStalls
+			stats.BytesSent += current.BytesSent
+		}
+		if p.encoding != nil {
+			if process, err := p.encoding.ProcessStats(); err == nil {
+				encoders++
+				if process.Uptime > 0 {
+					ffmpegCPU += float64(process.CPUTime) / float64(process.Uptime) * 100
+				}
+				ffmpegRSS += process.RSS
+			}
+		}
+		p.Unlock()
+	}
+	return
+}
+
+func (t *loadTest) report() {
+	now := time.Now()
+	stats, encoders, ffmpegCPU, ffmpegRSS, _, failures := t.totals()
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	goroutines := runtime.NumGoroutine()
+
+	var cpu float64
+	var rss int64
+	if self, err := dca.ReadProcessStats(os.Getpid()); err == nil {
+		rss = self.RSS
+		if !t.lastReport.IsZero() {
+			cpu = float64(self.CPUTime-t.lastCPU) / float64(now.Sub(t.lastReport)) * 100
+		}
+		t.lastCPU = self.CPUTime
+	}
+
+	// Every player should send one frame per frame duration
+	realtime := 0.0
+	if !t.lastReport.IsZero() {
+		expected := float64(len(t.players)) * float64(now.Sub(t.lastReport)) / float64(time.Duration(t.encodeOptions.FrameDuration)*time.Millisecond)
+		realtime = float64(stats.FramesSent-t.lastFrames) / expected * 100
+	}
+	t.lastFrames = stats.FramesSent
+	t.lastReport = now
+
+	t.peakCPU = max(t.peakCPU, cpu)
+	t.peakRSS = max(t.peakRSS, rss)
+	t.peakFFmpeg = max(t.peakFFmpeg, ffmpegRSS)
+	t.peakRoutine = max(t.peakRoutine, goroutines)
+
+	fmt.Printf("[%8v] encoders %d/%d · realtime %5.1f%% · late %d · dropped %d · stalls %d · failures %d · bot %5.1f%% CPU %v RSS %v heap %d goroutines · ffmpeg %6.1f%% CPU %v RSS\n",
+		now.Sub(t.started).Round(time.Second), encoders, len(t.players), realtime, stats.FramesLate, stats.FramesDropped, stats.SendStalls, failures,
+		cpu, formatMiB(rss), formatMiB(int64(memStats.HeapAlloc)), goroutines, ffmpegCPU, formatMiB(ffmpegRSS))
+}
+
+func (t *loadTest) summary() {
+	stats, _, _, _, plays, failures := t.totals()
+	elapsed := time.Since(t.started)
+	players := len(t.players)
+
+	fmt.Println()
+	fmt.Printf("Players:        %d for %v, %d plays, %d failures\n", players, elapsed.Round(time.Second), plays, failures)
+	fmt.Printf("Frames:         %d sent, %d late, %d dropped, %d send stalls\n", stats.FramesSent, stats.FramesLate, stats.FramesDropped, stats.SendStalls)
+	fmt.Printf("Streamed:       %v\n", formatMiB(stats.BytesSent))
+	fmt.Printf("Peak bot:       %.1f%% CPU, %v RSS, %d goroutines\n", t.peakCPU, formatMiB(t.peakRSS), t.peakRoutine)
+	fmt.Printf("Peak ffmpeg:    %v RSS in total, %v per player\n", formatMiB(t.peakFFmpeg), formatMiB(t.peakFFmpeg/int64(players)))
+	fmt.Printf("Bot per player: %.2f%% CPU, %v RSS\n", t.peakCPU/float64(players), formatMiB(t.peakRSS/int64(players)))
+}
+
+func formatMiB(bytes int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+}
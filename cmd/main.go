@@ -1,25 +1,34 @@
 package main
 
 import (
-	"net"
+	"flag"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/gin-gonic/gin"
 	"github.com/gookit/slog"
 	"github.com/gookit/slog/handler"
 
+	"github.com/keshon/melodix-discord-player/internal/assets"
 	"github.com/keshon/melodix-discord-player/internal/config"
 	"github.com/keshon/melodix-discord-player/internal/db"
 	"github.com/keshon/melodix-discord-player/internal/manager"
+	"github.com/keshon/melodix-discord-player/internal/mpd"
+	"github.com/keshon/melodix-discord-player/internal/mqtt"
+	"github.com/keshon/melodix-discord-player/internal/redis"
 	"github.com/keshon/melodix-discord-player/internal/rest"
+	"github.com/keshon/melodix-discord-player/internal/selfupdate"
+	"github.com/keshon/melodix-discord-player/internal/telegram"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
 	"github.com/keshon/melodix-discord-player/internal/version"
 	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/sources"
 )
 
-var botInstances map[string]*discord.BotInstance
+var botInstances *discord.BotInstances
 
 func main() {
 	slog.Configure(func(logger *slog.SugaredLogger) {
@@ -29,10 +38,12 @@ func main() {
 		f.ColorTheme = slog.ColorTheme
 	})
 
-	h1 := handler.MustFileHandler("./logs/all-levels.log", handler.WithLogLevels(slog.AllLevels))
-	slog.PushHandler(h1)
-
-	// logger := slog.Std()
+	// The flag takes precedence over DATA_DIR, every part of the bot reads the config from the environment
+	dataDir := flag.String("data", "", "directory of the database, logs, certificate cache and assets, DATA_DIR of .env or the working directory by default")
+	flag.Parse()
+	if *dataDir != "" {
+		os.Setenv("DATA_DIR", *dataDir)
+	}
 
 	config, err := config.NewConfig()
 	if err != nil {
@@ -40,9 +51,35 @@ func main() {
 		os.Exit(0)
 	}
 
+	if err := os.MkdirAll(config.DataDir, 0o755); err != nil {
+		slog.Fatalf("Error creating the data directory: %v", err)
+		os.Exit(0)
+	}
+
+	h1 := handler.MustFileHandler(config.LogPath(), handler.WithLogLevels(slog.AllLevels))
+	slog.PushHandler(h1)
+
+	// logger := slog.Std()
+
 	slog.Info("Config loaded:\n" + config.String())
 
-	if _, err := db.InitDB("./melodix.db"); err != nil {
+	assets.Static = assets.NewStore(config.AssetsDir())
+
+	if config.TracingEndpoint != "" {
+		headers := make(map[string]string)
+		for _, header := range config.TracingHeaders {
+			if key, value, found := strings.Cut(header, "="); found {
+				headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+		tracing.Enable(tracing.Options{
+			Endpoint:    config.TracingEndpoint,
+			ServiceName: config.TracingServiceName,
+			Headers:     headers,
+		})
+	}
+
+	if _, err := db.InitDB(config.DatabasePath(), db.Options{DeleteBrokenReferences: config.DatabaseDeleteBroken}); err != nil {
 		slog.Fatalf("Error initializing the database: %v", err)
 		os.Exit(0)
 	}
@@ -53,20 +90,20 @@ func main() {
 		os.Exit(0)
 	}
 
-	botInstances = make(map[string]*discord.BotInstance)
-
-	guildManager := manager.NewGuildManager(dg, botInstances)
-	guildManager.Start()
+	botInstances = discord.NewBotInstances()
 
-	guildIDs, err := getGuildsOrSetDefault()
-	if err != nil {
-		slog.Fatalf("Error retrieving or creating guilds: %v", err)
-		os.Exit(0)
-	}
+	// The update command installs a new binary and asks for a restart, which stops like a signal does first
+	restart := make(chan struct{}, 1)
+	updater := discord.NewUpdater(func() {
+		select {
+		case restart <- struct{}{}:
+		default:
+		}
+	})
 
-	for _, guildID := range guildIDs {
-		startBotInstances(dg, guildID)
-	}
+	// Instances of the guilds the bot is in are started by the guild manager as Discord reports them
+	guildManager := manager.NewGuildManager(dg, botInstances, updater)
+	guildManager.Start()
 
 	if err := dg.Open(); err != nil {
 		slog.Fatalf("Error opening Discord session: %v", err)
@@ -74,68 +111,76 @@ func main() {
 	}
 	defer dg.Close()
 
-	if config.RestEnabled {
-		startRestServer(config.RestGinRelease, config.RestHostname)
+	// Redis shares the guilds with API services running elsewhere and caches lookups for all processes
+	guilds := rest.NewLocalGuilds(botInstances)
+	if config.RedisAddress != "" {
+		shared := redis.NewRedis(botInstances, redis.Options{
+			Address:    config.RedisAddress,
+			Password:   config.RedisPassword,
+			DB:         config.RedisDB,
+			KeyPrefix:  config.RedisKeyPrefix,
+			InstanceID: config.InstanceID,
+		})
+		shared.Start()
+		sources.Metadata = shared
+		guilds = shared.Guilds()
 	}
 
-	slog.Infof("%v is now running. Press Ctrl+C to exit", version.AppName)
-
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
-}
+	if config.RestEnabled {
+		rest.Serve(guilds, config)
+	}
 
-func getGuildsOrSetDefault() ([]string, error) {
-	guildIDs, err := db.GetAllGuildIDs()
-	if err != nil {
-		return nil, err
+	if config.MqttBroker != "" {
+		mqtt.NewMqtt(botInstances, mqtt.Options{
+			Broker:      config.MqttBroker,
+			ClientID:    config.MqttClientID,
+			Username:    config.MqttUsername,
+			Password:    config.MqttPassword,
+			TopicPrefix: config.MqttTopicPrefix,
+		}).Start()
 	}
 
-	if len(guildIDs) == 0 {
-		guild := db.Guild{ID: "897053062030585916", Name: "default"} // TODO: default guild id is so-so
-		if err := db.CreateGuild(guild); err != nil {
-			return nil, err
+	if config.TelegramBotToken != "" {
+		bridge, err := telegram.NewTelegram(botInstances, config.TelegramBotToken, config.TelegramLinks)
+		if err != nil {
+			slog.Errorf("Error starting Telegram bridge: %v", err)
+		} else {
+			bridge.Start()
 		}
-		guildIDs = append(guildIDs, guild.ID)
 	}
 
-	return guildIDs, nil
-}
-
-func startBotInstances(session *discordgo.Session, guildID string) {
-	botInstances[guildID] = &discord.BotInstance{
-		Melodix: discord.NewDiscord(session, guildID),
-	}
-	botInstances[guildID].Melodix.Start(guildID)
-}
-
-func startRestServer(isReleaseMode bool, hostname string) {
-	if isReleaseMode {
-		gin.SetMode("release")
+	var mpdServer *mpd.Mpd
+	if config.MpdListen != "" {
+		mpdServer = mpd.NewMpd(botInstances, config.MpdPassword)
+		if err := mpdServer.Start(config.MpdListen); err != nil {
+			slog.Errorf("Error starting MPD server: %v", err)
+		}
 	}
 
-	router := gin.Default()
-
-	restAPI := rest.NewRest(botInstances)
-	restAPI.Start(router)
+	slog.Infof("%v is now running. Press Ctrl+C to exit", version.AppName)
 
-	go func() {
-		// parse hostname var - if it has port - use it or fallback to 8080
-		host, port, err := net.SplitHostPort(hostname)
-		if err != nil {
-			// If there's an error, assume the entire input is the host (without port)
-			host = hostname
-			port = "8080"
-		}
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 
-		// If hostname is empty, set it to the default port (8080)
-		if host == "" {
-			host = "localhost"
-		}
+	restarting := false
+	select {
+	case <-sc:
+	case <-restart:
+		restarting = true
+	}
 
-		slog.Infof("REST API server started on %s:%s\n", host, port)
-		if err := router.Run(net.JoinHostPort(host, port)); err != nil {
-			slog.Fatalf("Error starting REST API server: %v", err)
+	if mpdServer != nil {
+		mpdServer.Stop()
+	}
+	guildManager.Stop()
+	history.Flush()
+	tracing.Flush()
+
+	if restarting {
+		slog.Infof("Restarting %v", version.AppName)
+		dg.Close()
+		if err := selfupdate.Restart(); err != nil {
+			slog.Errorf("Error restarting: %v", err)
 		}
-	}()
+	}
 }
@@ -0,0 +1,92 @@
+// Command web serves the API, the dashboard and the listen-along pages without connecting to Discord.
+// It reaches the guilds through the bot workers sharing its Redis server and database, so it can be
+// restarted, scaled or exposed separately from the workers playing music.
+//
+//	go run ./cmd/web -data /var/lib/melodix
+//
+// It reads the same .env as the bot, REDIS_ADDRESS is mandatory.
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gookit/slog"
+	"github.com/gookit/slog/handler"
+
+	"github.com/keshon/melodix-discord-player/internal/assets"
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/redis"
+	"github.com/keshon/melodix-discord-player/internal/rest"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/sources"
+)
+
+func main() {
+	slog.Configure(func(logger *slog.SugaredLogger) {
+		f := logger.Formatter.(*slog.TextFormatter)
+		f.EnableColor = true
+		f.SetTemplate("[{{datetime}}] [{{level}}] [{{caller}}]\t{{message}} {{data}} {{extra}}\n")
+		f.ColorTheme = slog.ColorTheme
+	})
+
+	dataDir := flag.String("data", "", "directory of the database, logs, certificate cache and assets, DATA_DIR of .env or the working directory by default")
+	flag.Parse()
+	if *dataDir != "" {
+		os.Setenv("DATA_DIR", *dataDir)
+	}
+
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Fatalf("Error loading config: %v", err)
+		os.Exit(0)
+	}
+
+	if config.RedisAddress == "" {
+		slog.Fatal("REDIS_ADDRESS is required, the guilds are reached through the bot workers sharing the Redis server")
+		os.Exit(0)
+	}
+
+	if err := os.MkdirAll(config.DataDir, 0o755); err != nil {
+		slog.Fatalf("Error creating the data directory: %v", err)
+		os.Exit(0)
+	}
+
+	h1 := handler.MustFileHandler(config.LogPath(), handler.WithLogLevels(slog.AllLevels))
+	slog.PushHandler(h1)
+
+	slog.Info("Config loaded:\n" + config.String())
+
+	assets.Static = assets.NewStore(config.AssetsDir())
+
+	if _, err := db.InitDB(config.DatabasePath(), db.Options{DeleteBrokenReferences: config.DatabaseDeleteBroken}); err != nil {
+		slog.Fatalf("Error initializing the database: %v", err)
+		os.Exit(0)
+	}
+
+	// No guilds run here, every call is answered by the worker running the guild
+	shared := redis.NewRedis(discord.NewBotInstances(), redis.Options{
+		Address:    config.RedisAddress,
+		Password:   config.RedisPassword,
+		DB:         config.RedisDB,
+		KeyPrefix:  config.RedisKeyPrefix,
+		InstanceID: config.InstanceID,
+	})
+	shared.Start()
+	sources.Metadata = shared
+
+	rest.Serve(shared.Guilds(), config)
+
+	slog.Infof("%v web service is now running. Press Ctrl+C to exit", version.AppName)
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	<-sc
+
+	history.Flush()
+}
@@ -0,0 +1,206 @@
+// Package assets indexes static files under content hashed names, so they can be served with long-lived cache headers.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+const (
+	// hashLength is how many hex digits of the SHA-256 of a file are put in its hashed name
+	hashLength = 16
+	// rescanInterval limits how often the directory is scanned for added or changed files
+	rescanInterval = time.Minute
+)
+
+// Static holds the bot's assets directory, main replaces it with the one of the configured data directory.
+var Static = NewStore("./assets")
+
+// Asset is a static file and the content hashed name it's served under.
+type Asset struct {
+	Name        string    `json:"name"`        // slash separated path in the store, e.g. avatars/cat.png
+	HashedName  string    `json:"hashed_name"` // name with the content hash before the extension, e.g. avatars/cat.3f2a9c1b0d4e5f60.png
+	Hash        string    `json:"hash"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+	path        string
+}
+
+// Path returns the file path of the asset.
+func (a *Asset) Path() string {
+	return a.path
+}
+
+// Read returns the content of the asset.
+func (a *Asset) Read() ([]byte, error) {
+	return os.ReadFile(a.path)
+}
+
+// DataURI returns the content of the asset as a base64 data URI, the form Discord accepts avatars in.
+func (a *Asset) DataURI() (string, error) {
+	data, err := a.Read()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("data:%v;base64,%v", a.ContentType, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// Store indexes the files of a directory by name and hashed name, rescanning it when it may have changed.
+type Store struct {
+	root      string
+	mu        sync.Mutex
+	byName    map[string]*Asset
+	byHashed  map[string]*Asset
+	scannedAt time.Time
+}
+
+// NewStore creates a store of the files under root.
+func NewStore(root string) *Store {
+	return &Store{root: root}
+}
+
+// All returns every asset sorted by name.
+func (s *Store) All() []*Asset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rescan()
+
+	assets := make([]*Asset, 0, len(s.byName))
+	for _, asset := range s.byName {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Name < assets[j].Name })
+
+	return assets
+}
+
+// Images returns the images directly in dir sorted by name.
+func (s *Store) Images(dir string) []*Asset {
+	var images []*Asset
+	for _, asset := range s.All() {
+		if path.Dir(asset.Name) == dir && strings.HasPrefix(asset.ContentType, "image/") {
+			images = append(images, asset)
+		}
+	}
+	return images
+}
+
+// Lookup returns the asset served under a hashed name.
+func (s *Store) Lookup(hashedName string) (*Asset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rescan()
+
+	asset, ok := s.byHashed[hashedName]
+	return asset, ok
+}
+
+// Manifest maps names of the assets to their hashed names.
+func (s *Store) Manifest() map[string]string {
+	manifest := make(map[string]string)
+	for _, asset := range s.All() {
+		manifest[asset.Name] = asset.HashedName
+	}
+	return manifest
+}
+
+// rescan indexes the directory again if the last scan is older than rescanInterval.
+// Files that didn't change keep their hash. Must be called with the lock held.
+func (s *Store) rescan() {
+	if s.byName != nil && time.Since(s.scannedAt) < rescanInterval {
+		return
+	}
+	s.scannedAt = time.Now()
+
+	byName := make(map[string]*Asset)
+	byHashed := make(map[string]*Asset)
+
+	err := filepath.WalkDir(s.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		asset, known := s.byName[name]
+		if !known || asset.Size != info.Size() || !asset.ModTime.Equal(info.ModTime()) {
+			asset, err = newAsset(filePath, name, info)
+			if err != nil {
+				slog.Warnf("Error indexing asset %v: %v", name, err)
+				return nil
+			}
+		}
+
+		byName[name] = asset
+		byHashed[asset.HashedName] = asset
+		return nil
+	})
+	if err != nil {
+		slog.Errorf("Error scanning assets in %v: %v", s.root, err)
+		if s.byName != nil {
+			return
+		}
+	}
+
+	s.byName = byName
+	s.byHashed = byHashed
+}
+
+func newAsset(filePath, name string, info fs.FileInfo) (*Asset, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))[:hashLength]
+
+	ext := path.Ext(name)
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return &Asset{
+		Name:        name,
+		HashedName:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+		Hash:        hash,
+		Size:        info.Size(),
+		ContentType: contentType,
+		ModTime:     info.ModTime(),
+		path:        filePath,
+	}, nil
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gookit/slog"
 	"github.com/joho/godotenv"
@@ -12,11 +14,58 @@ import (
 )
 
 type Config struct {
+	DataDir                    string // root of the database, logs, certificate cache and assets
+	InstanceID                 string // name of this process among several sharing the database
+	DatabaseDeleteBroken       bool   // delete rows of older databases pointing at tracks and other rows that are gone
 	DiscordCommandPrefix       string
 	DiscordBotToken            string
+	DiscordStatusMessagesKept  int    // number of now-playing/queue messages kept per channel, 0 keeps all
+	DiscordOwnerID             string // user ID allowed to administrate all guilds, also by DM
+	DiscordClientID            string // OAuth2 application of the web dashboard login
+	DiscordClientSecret        string
 	RestEnabled                bool
 	RestGinRelease             bool
 	RestHostname               string
+	RestPublicURL              string // external base URL used in links, e.g. behind a reverse proxy
+	RestTLSCert                string // certificate file, serves HTTPS together with RestTLSKey
+	RestTLSKey                 string
+	RestAutocertDomains        []string // domains to obtain Let's Encrypt certificates for, takes precedence over RestTLSCert
+	RestAutocertCacheDir       string
+	RestTrustedProxies         []string // IPs or CIDRs whose X-Forwarded-* headers are trusted
+	RestCORSOrigins            []string // origins allowed to call the API from browsers, "*" allows any
+	RestRateLimit              int      // requests per minute and client IP, 0 disables the limit
+	RestMaxBodyBytes           int
+	RestMiddleware             []string // middleware applied to every request in order, defaults apply if empty
+	RestAdminToken             string   // API token valid for every guild
+	MpdListen                  string   // address of the MPD protocol server, empty disables it
+	MpdPassword                string
+	MqttBroker                 string // host:port of the MQTT broker, empty disables the integration
+	MqttClientID               string
+	MqttUsername               string
+	MqttPassword               string
+	MqttTopicPrefix            string
+	RedisAddress               string // host:port of the Redis server, empty disables the integration
+	RedisPassword              string
+	RedisDB                    int
+	RedisKeyPrefix             string
+	TracingEndpoint            string // base URL of an OTLP/HTTP trace collector, empty disables tracing
+	TracingServiceName         string
+	TracingHeaders             []string // key=value headers sent to the collector, e.g. an API key
+	TelegramBotToken           string   // empty disables the Telegram bridge
+	TelegramLinks              string   // comma separated chat_id:guild_id pairs
+	YoutubePlaylistLimit       int      // most videos queued of a YouTube playlist, 0 for all of them
+	SpotifyClientID            string   // client credentials of a Spotify app, empty disables Spotify links
+	SpotifyClientSecret        string
+	SoundCloudClientID         string   // client ID of the SoundCloud API, empty takes the one of the web player
+	AmbienceDir                string   // directory of ambience loops, each file is a preset named after it
+	AmbienceURLs               []string // name=url ambience presets, taking precedence over files of the same name
+	ResumeMinMinutes           int      // tracks at least this long resume where the guild left them, 0 disables it
+	QueueAutoRestore           bool     // restore the queue saved before a restart without waiting for the restore command
+	VoicePrejoin               bool     // join the voice channel while a play request is looked up rather than after
+	VoiceRegionSuggestions     bool     // ask Discord's latency service for a closer region when the voice server is slow
+	AnnounceUpdates            bool     // post what's new to every guild once after starting a new version
+	UpdateCheck                bool     // compare the running version with the latest GitHub release
+	ResolveTimeoutSeconds      int      // longest looking up the songs of a request may take, 0 waits indefinitely
 	DcaFrameDuration           int
 	DcaBitrate                 int
 	DcaPacketLoss              int
@@ -24,6 +73,8 @@ type Config struct {
 	DcaApplication             dca.AudioApplication
 	DcaCompressionLevel        int
 	DcaBufferedFrames          int
+	DcaStreamBufferFrames      int // jitter buffer depth between encoder and voice connection, 0 disables it
+	DcaStreamBufferMaxFrames   int // the jitter buffer grows up to this depth on send stalls, then shrinks back
 	DcaVBR                     bool
 	DcaReconnectAtEOF          int // boolean value passed to Ffmpeg is treated as int (1 - true, 0 - false)
 	DcaReconnectStreamed       int // boolean value passed to Ffmpeg is treated as int (1 - true, 0 - false)
@@ -44,12 +95,61 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	dataDir := getenvOrDefault("DATA_DIR", ".")
+
 	config := &Config{
+		DataDir:                    dataDir,
+		InstanceID:                 getenvOrDefault("INSTANCE_ID", defaultInstanceID()),
+		DatabaseDeleteBroken:       getenvAsBool("DATABASE_DELETE_BROKEN"),
 		DiscordCommandPrefix:       os.Getenv("DISCORD_COMMAND_PREFIX"),
 		DiscordBotToken:            os.Getenv("DISCORD_BOT_TOKEN"),
+		DiscordStatusMessagesKept:  getenvAsIntOrDefault("DISCORD_STATUS_MESSAGES_KEPT", 3),
+		DiscordOwnerID:             os.Getenv("DISCORD_OWNER_ID"),
+		DiscordClientID:            os.Getenv("DISCORD_CLIENT_ID"),
+		DiscordClientSecret:        os.Getenv("DISCORD_CLIENT_SECRET"),
 		RestEnabled:                getenvAsBool("REST_ENABLED"),
 		RestGinRelease:             getenvAsBool("REST_GIN_RELEASE"),
 		RestHostname:               os.Getenv("REST_HOSTNAME"),
+		RestPublicURL:              strings.TrimRight(os.Getenv("REST_PUBLIC_URL"), "/"),
+		RestTLSCert:                os.Getenv("REST_TLS_CERT"),
+		RestTLSKey:                 os.Getenv("REST_TLS_KEY"),
+		RestAutocertDomains:        getenvAsList("REST_AUTOCERT_DOMAINS"),
+		RestAutocertCacheDir:       getenvOrDefault("REST_AUTOCERT_CACHE_DIR", filepath.Join(dataDir, "certs")),
+		RestTrustedProxies:         getenvAsList("REST_TRUSTED_PROXIES"),
+		RestCORSOrigins:            getenvAsList("REST_CORS_ORIGINS"),
+		RestRateLimit:              getenvAsIntOrDefault("REST_RATE_LIMIT", 120),
+		RestMaxBodyBytes:           getenvAsIntOrDefault("REST_MAX_BODY_BYTES", 1<<20),
+		RestMiddleware:             getenvAsList("REST_MIDDLEWARE"),
+		RestAdminToken:             os.Getenv("REST_ADMIN_TOKEN"),
+		MpdListen:                  os.Getenv("MPD_LISTEN"),
+		MpdPassword:                os.Getenv("MPD_PASSWORD"),
+		MqttBroker:                 os.Getenv("MQTT_BROKER"),
+		MqttClientID:               os.Getenv("MQTT_CLIENT_ID"),
+		MqttUsername:               os.Getenv("MQTT_USERNAME"),
+		MqttPassword:               os.Getenv("MQTT_PASSWORD"),
+		MqttTopicPrefix:            os.Getenv("MQTT_TOPIC_PREFIX"),
+		RedisAddress:               os.Getenv("REDIS_ADDRESS"),
+		RedisPassword:              os.Getenv("REDIS_PASSWORD"),
+		RedisDB:                    getenvAsIntOrDefault("REDIS_DB", 0),
+		RedisKeyPrefix:             os.Getenv("REDIS_KEY_PREFIX"),
+		TracingEndpoint:            os.Getenv("TRACING_ENDPOINT"),
+		TracingServiceName:         getenvOrDefault("TRACING_SERVICE_NAME", "melodix"),
+		TracingHeaders:             getenvAsList("TRACING_HEADERS"),
+		TelegramBotToken:           os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramLinks:              os.Getenv("TELEGRAM_LINKS"),
+		YoutubePlaylistLimit:       getenvAsIntOrDefault("YOUTUBE_PLAYLIST_LIMIT", 100),
+		SpotifyClientID:            os.Getenv("SPOTIFY_CLIENT_ID"),
+		SpotifyClientSecret:        os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		SoundCloudClientID:         os.Getenv("SOUNDCLOUD_CLIENT_ID"),
+		AmbienceDir:                getenvOrDefault("AMBIENCE_DIR", filepath.Join(dataDir, "assets", "ambience")),
+		AmbienceURLs:               getenvAsList("AMBIENCE_URLS"),
+		ResumeMinMinutes:           getenvAsIntOrDefault("RESUME_MIN_MINUTES", 20),
+		QueueAutoRestore:           getenvAsBool("QUEUE_AUTO_RESTORE"),
+		VoicePrejoin:               getenvAsBool("VOICE_PREJOIN"),
+		VoiceRegionSuggestions:     getenvAsBool("VOICE_REGION_SUGGESTIONS"),
+		AnnounceUpdates:            getenvAsBool("ANNOUNCE_UPDATES"),
+		UpdateCheck:                getenvAsBoolOrDefault("UPDATE_CHECK", true),
+		ResolveTimeoutSeconds:      getenvAsIntOrDefault("RESOLVE_TIMEOUT_SECONDS", 60),
 		DcaFrameDuration:           getenvAsInt("DCA_FRAME_DURATION"),
 		DcaBitrate:                 getenvAsInt("DCA_BITRATE"),
 		DcaPacketLoss:              getenvAsInt("DCA_PACKET_LOSS"),
@@ -57,6 +157,8 @@ func NewConfig() (*Config, error) {
 		DcaApplication:             dca.AudioApplication(os.Getenv("DCA_APPLICATION")),
 		DcaCompressionLevel:        getenvAsInt("DCA_COMPRESSION_LEVEL"),
 		DcaBufferedFrames:          getenvAsInt("DCA_BUFFERED_FRAMES"),
+		DcaStreamBufferFrames:      getenvAsIntOrDefault("DCA_STREAM_BUFFER_FRAMES", 0),
+		DcaStreamBufferMaxFrames:   getenvAsIntOrDefault("DCA_STREAM_BUFFER_MAX_FRAMES", 100),
 		DcaVBR:                     getenvAsBool("DCA_VBR"),
 		DcaReconnectAtEOF:          getenvBoolAsInt("DCA_RECONNECT_AT_EOF"),
 		DcaReconnectStreamed:       getenvBoolAsInt("DCA_RECONNECT_STREAMED"),
@@ -74,11 +176,49 @@ func NewConfig() (*Config, error) {
 func (c *Config) String() string {
 	// Create a map for key-value pairs
 	configMap := map[string]interface{}{
+		"DataDir":                    c.DataDir,
+		"InstanceID":                 c.InstanceID,
+		"DatabaseDeleteBroken":       c.DatabaseDeleteBroken,
 		"DiscordCommandPrefix":       c.DiscordCommandPrefix,
 		"DiscordBotToken":            c.DiscordBotToken,
+		"DiscordStatusMessagesKept":  c.DiscordStatusMessagesKept,
+		"DiscordOwnerID":             c.DiscordOwnerID,
+		"DiscordClientID":            c.DiscordClientID,
 		"RestEnabled":                c.RestEnabled,
 		"RestGinRelease":             c.RestGinRelease,
 		"RestHostname":               c.RestHostname,
+		"RestPublicURL":              c.RestPublicURL,
+		"RestTLSCert":                c.RestTLSCert,
+		"RestAutocertDomains":        c.RestAutocertDomains,
+		"RestAutocertCacheDir":       c.RestAutocertCacheDir,
+		"RestTrustedProxies":         c.RestTrustedProxies,
+		"RestCORSOrigins":            c.RestCORSOrigins,
+		"RestRateLimit":              c.RestRateLimit,
+		"RestMaxBodyBytes":           c.RestMaxBodyBytes,
+		"RestMiddleware":             c.RestMiddleware,
+		"MpdListen":                  c.MpdListen,
+		"MqttBroker":                 c.MqttBroker,
+		"MqttClientID":               c.MqttClientID,
+		"MqttUsername":               c.MqttUsername,
+		"MqttTopicPrefix":            c.MqttTopicPrefix,
+		"RedisAddress":               c.RedisAddress,
+		"RedisDB":                    c.RedisDB,
+		"RedisKeyPrefix":             c.RedisKeyPrefix,
+		"TracingEndpoint":            c.TracingEndpoint,
+		"TracingServiceName":         c.TracingServiceName,
+		"TelegramLinks":              c.TelegramLinks,
+		"YoutubePlaylistLimit":       c.YoutubePlaylistLimit,
+		"SpotifyClientID":            c.SpotifyClientID,
+		"SoundCloudClientID":         c.SoundCloudClientID,
+		"AmbienceDir":                c.AmbienceDir,
+		"AmbienceURLs":               c.AmbienceURLs,
+		"ResumeMinMinutes":           c.ResumeMinMinutes,
+		"QueueAutoRestore":           c.QueueAutoRestore,
+		"VoicePrejoin":               c.VoicePrejoin,
+		"VoiceRegionSuggestions":     c.VoiceRegionSuggestions,
+		"AnnounceUpdates":            c.AnnounceUpdates,
+		"UpdateCheck":                c.UpdateCheck,
+		"ResolveTimeoutSeconds":      c.ResolveTimeoutSeconds,
 		"DcaFrameDuration":           c.DcaFrameDuration,
 		"DcaBitrate":                 c.DcaBitrate,
 		"DcaPacketLoss":              c.DcaPacketLoss,
@@ -86,6 +226,8 @@ func (c *Config) String() string {
 		"DcaApplication":             c.DcaApplication,
 		"DcaCompressionLevel":        c.DcaCompressionLevel,
 		"DcaBufferedFrames":          c.DcaBufferedFrames,
+		"DcaStreamBufferFrames":      c.DcaStreamBufferFrames,
+		"DcaStreamBufferMaxFrames":   c.DcaStreamBufferMaxFrames,
 		"DcaVBR":                     c.DcaVBR,
 		"DcaReconnectAtEOF":          c.DcaReconnectAtEOF,
 		"DcaReconnectStreamed":       c.DcaReconnectStreamed,
@@ -107,6 +249,43 @@ func (c *Config) String() string {
 	return string(jsonString)
 }
 
+// DatabasePath returns the SQLite database file in the data directory.
+func (c *Config) DatabasePath() string {
+	return filepath.Join(c.DataDir, "melodix.db")
+}
+
+// LogPath returns the log file in the data directory.
+func (c *Config) LogPath() string {
+	return filepath.Join(c.DataDir, "logs", "all-levels.log")
+}
+
+// AssetsDir returns the directory of avatars and other static files in the data directory.
+func (c *Config) AssetsDir() string {
+	return filepath.Join(c.DataDir, "assets")
+}
+
+// RestTLSEnabled reports whether the REST server serves HTTPS itself.
+func (c *Config) RestTLSEnabled() bool {
+	return len(c.RestAutocertDomains) > 0 || (c.RestTLSCert != "" && c.RestTLSKey != "")
+}
+
+// RestBaseURL returns the external address of the REST server, e.g. "https://melodix.example.com".
+func (c *Config) RestBaseURL() string {
+	if c.RestPublicURL != "" {
+		return c.RestPublicURL
+	}
+
+	hostname := c.RestHostname
+	if os.Getenv("HOST") != "" {
+		hostname = os.Getenv("HOST") // from docker environment
+	}
+
+	if c.RestTLSEnabled() {
+		return "https://" + hostname
+	}
+	return "http://" + hostname
+}
+
 func validateMandatoryConfig() error {
 
 	// Define a list of mandatory environment variable keys
@@ -114,7 +293,28 @@ func validateMandatoryConfig() error {
 	// ignore:
 	// - REST_GIN_RELEASE
 	// - REST_HOSTNAME
+	// - REST_PUBLIC_URL
+	// - REST_TLS_*
+	// - REST_AUTOCERT_*
+	// - REST_TRUSTED_PROXIES
+	// - REST_CORS_ORIGINS
+	// - REST_RATE_LIMIT
+	// - REST_MAX_BODY_BYTES
+	// - REST_MIDDLEWARE
+	// - REST_ADMIN_TOKEN
 	// - DCA_FFMPEG_BINARY_PATH
+	// - DCA_STREAM_BUFFER_*
+	// - DISCORD_STATUS_MESSAGES_KEPT
+	// - DISCORD_OWNER_ID
+	// - DISCORD_CLIENT_ID
+	// - DISCORD_CLIENT_SECRET
+	// - MPD_LISTEN
+	// - MPD_PASSWORD
+	// - MQTT_*
+	// - REDIS_*
+	// - TRACING_*
+	// - TELEGRAM_*
+	// - SPOTIFY_*
 
 	mandatoryKeys := []string{
 		"DISCORD_COMMAND_PREFIX", "DISCORD_BOT_TOKEN", "REST_ENABLED", "DCA_FRAME_DURATION", "DCA_BITRATE", "DCA_PACKET_LOSS",
@@ -146,6 +346,50 @@ func getenvAsInt(key string) int {
 	return intValue
 }
 
+// getenvAsIntOrDefault parses an optional integer env variable, falling back to def if it is not set.
+func getenvAsIntOrDefault(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	intValue, err := strconv.Atoi(val)
+	if err != nil {
+		slog.Errorf("Error parsing integer value from env variable %v", key)
+		return def
+	}
+
+	return intValue
+}
+
+// defaultInstanceID names the process after its host, a restarted process takes its leases back right away.
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "melodix"
+	}
+	return hostname
+}
+
+// getenvOrDefault returns an optional env variable, falling back to def if it is not set.
+func getenvOrDefault(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// getenvAsList parses a comma separated env variable, empty items are skipped.
+func getenvAsList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getenvAsBool(key string) bool {
 	val := os.Getenv(key)
 
@@ -158,6 +402,22 @@ func getenvAsBool(key string) bool {
 	return boolValue
 }
 
+// getenvAsBoolOrDefault parses an optional bool env variable, falling back to def if it is not set.
+func getenvAsBoolOrDefault(key string, def bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	boolValue, err := strconv.ParseBool(val)
+	if err != nil {
+		slog.Errorf("Error parsing bool value from env variable %v", key)
+		return def
+	}
+
+	return boolValue
+}
+
 func getenvBoolAsInt(key string) int {
 	val := os.Getenv(key)
 
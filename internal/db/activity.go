@@ -0,0 +1,53 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListeningActivity stores how many seconds a guild listened to music within an hour.
+type ListeningActivity struct {
+	ID      uint      `gorm:"primaryKey;autoIncrement"`
+	GuildID string    `gorm:"index"`
+	Hour    time.Time `gorm:"index"` // start of the hour in UTC
+	Seconds float64
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// AddListeningActivity adds listened seconds to the hourly bucket the given time belongs to.
+func AddListeningActivity(guildID string, at time.Time, seconds float64) error {
+	hour := at.UTC().Truncate(time.Hour)
+
+	result := DB.Model(&ListeningActivity{}).
+		Where("guild_id = ? AND hour = ?", guildID, hour).
+		UpdateColumn("seconds", gorm.Expr("seconds + ?", seconds))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return DB.Create(&ListeningActivity{GuildID: guildID, Hour: hour, Seconds: seconds}).Error
+	}
+
+	return nil
+}
+
+// GetListeningActivityBetween returns hourly buckets of the guild within [from, to).
+func GetListeningActivityBetween(guildID string, from, to time.Time) ([]ListeningActivity, error) {
+	var activity []ListeningActivity
+	if err := DB.Where("guild_id = ? AND hour >= ? AND hour < ?", guildID, from.UTC(), to.UTC()).Order("hour").Find(&activity).Error; err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// GetListeningActivitySince returns hourly buckets of the guild starting from the given time.
+func GetListeningActivitySince(guildID string, since time.Time) ([]ListeningActivity, error) {
+	var activity []ListeningActivity
+	if err := DB.Where("guild_id = ? AND hour >= ?", guildID, since.UTC()).Order("hour").Find(&activity).Error; err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"time"
+)
+
+// CommandAlias is an alias of a bot command defined by the admins of a guild.
+type CommandAlias struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID   string `gorm:"uniqueIndex:idx_command_alias"`
+	Alias     string `gorm:"uniqueIndex:idx_command_alias"`
+	Command   string // canonical command the alias runs
+	CreatedBy string
+	CreatedAt time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateCommandAlias(alias *CommandAlias) error {
+	alias.CreatedAt = time.Now()
+	return DB.Create(alias).Error
+}
+
+func GetCommandAliasesByGuildID(guildID string) ([]CommandAlias, error) {
+	var aliases []CommandAlias
+	if err := DB.Where("guild_id = ?", guildID).Order("command, alias").Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// DeleteCommandAlias removes an alias of the guild, it returns the number of deleted rows.
+func DeleteCommandAlias(guildID, alias string) (int64, error) {
+	result := DB.Where("guild_id = ? AND alias = ?", guildID, alias).Delete(&CommandAlias{})
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeUserCommandAliases detaches the user from the aliases they created in all guilds, it returns the number of affected rows.
+func AnonymizeUserCommandAliases(userID string) (int64, error) {
+	result := DB.Model(&CommandAlias{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"time"
+)
+
+// API token scopes, control includes read.
+const (
+	ScopeRead    = "read"
+	ScopeControl = "control"
+)
+
+// APIToken grants access to the REST routes of a single guild.
+type APIToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	TokenHash string `gorm:"uniqueIndex" json:"-"` // SHA-256 of the token, the token itself is never stored
+	GuildID   string `gorm:"index"`
+	Scope     string
+	Name      string
+	CreatedBy string
+	CreatedAt time.Time
+	LastUsed  time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateAPIToken(token *APIToken) error {
+	token.CreatedAt = time.Now()
+	return DB.Create(token).Error
+}
+
+func GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	var token APIToken
+	if err := DB.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func GetAPITokensByGuildID(guildID string) ([]APIToken, error) {
+	var tokens []APIToken
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// TouchAPIToken records that the token was just used.
+func TouchAPIToken(id uint) error {
+	return DB.Model(&APIToken{}).Where("id = ?", id).Update("last_used", time.Now()).Error
+}
+
+// DeleteAPIToken removes the guild's token with the given ID, it returns the number of deleted rows.
+func DeleteAPIToken(guildID string, id uint) (int64, error) {
+	result := DB.Where("guild_id = ? AND id = ?", guildID, id).Delete(&APIToken{})
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeUserAPITokens detaches the user from the API tokens they created in all guilds, it returns the number of affected rows.
+func AnonymizeUserAPITokens(userID string) (int64, error) {
+	result := DB.Model(&APIToken{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
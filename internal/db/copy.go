@@ -0,0 +1,114 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TableCopy is the outcome of copying a table to another database.
+type TableCopy struct {
+	Table  string
+	Source int64 // rows in the source table
+	Copied int64 // rows in the destination table after copying
+}
+
+// CopyDatabase copies every row of src into dst, whose tables must be empty. Rows keep their primary keys,
+// so the references between tables stay valid. Rows are read as column maps so zero values like inactive
+// guilds aren't replaced by column defaults. The tables of src must be up to date, src isn't changed.
+// A Postgres dst gets its ID sequences moved past the copied IDs.
+func CopyDatabase(src, dst *gorm.DB, batchSize int) ([]TableCopy, error) {
+	var copies []TableCopy
+
+	for _, model := range models {
+		statement := &gorm.Statement{DB: src}
+		if err := statement.Parse(model); err != nil {
+			return copies, err
+		}
+		table := statement.Schema.Table
+		// Some tables have a primary key of several columns
+		primaryKey := strings.Join(statement.Schema.PrimaryFieldDBNames, ", ")
+
+		var existing int64
+		if err := dst.Table(table).Count(&existing).Error; err != nil {
+			return copies, err
+		}
+		if existing > 0 {
+			return copies, fmt.Errorf("table %v of the destination already has %d rows", table, existing)
+		}
+
+		if !src.Migrator().HasTable(table) {
+			return copies, fmt.Errorf("table %v is missing from the source, start this version of Melodix on it once to update it", table)
+		}
+
+		tableCopy := TableCopy{Table: table}
+		if err := src.Table(table).Count(&tableCopy.Source).Error; err != nil {
+			return copies, err
+		}
+
+		for offset := 0; int64(offset) < tableCopy.Source; offset += batchSize {
+			var rows []map[string]interface{}
+			if err := src.Table(table).Order(primaryKey).Limit(batchSize).Offset(offset).Find(&rows).Error; err != nil {
+				return copies, fmt.Errorf("reading %v: %w", table, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			for _, row := range rows {
+				convertRow(statement.Schema, row)
+			}
+			if err := dst.Table(table).Create(&rows).Error; err != nil {
+				return copies, fmt.Errorf("writing %v: %w", table, err)
+			}
+		}
+
+		if err := dst.Table(table).Count(&tableCopy.Copied).Error; err != nil {
+			return copies, err
+		}
+		if id := statement.Schema.PrioritizedPrimaryField; dst.Dialector.Name() == "postgres" && id != nil && id.AutoIncrement {
+			// Rows were inserted with their IDs, the sequence would hand them out again
+			sql := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%v', '%v'), MAX(%v)) FROM %v", table, id.DBName, id.DBName, table)
+			if err := dst.Exec(sql).Error; err != nil {
+				return copies, fmt.Errorf("updating the ID sequence of %v: %w", table, err)
+			}
+		}
+		copies = append(copies, tableCopy)
+	}
+
+	return copies, nil
+}
+
+// convertRow turns values SQLite stores as numbers back into the types of the model, e.g. booleans,
+// which Postgres doesn't take as numbers.
+func convertRow(modelSchema *schema.Schema, row map[string]interface{}) {
+	for column, value := range row {
+		field := modelSchema.LookUpField(column)
+		if field == nil || field.DataType != schema.Bool {
+			continue
+		}
+		if number, ok := value.(int64); ok {
+			row[column] = number != 0
+		}
+	}
+}
+
+// CountBrokenReferences returns the number of rows pointing at rows that don't exist, by "table.column".
+func CountBrokenReferences(db *gorm.DB) (map[string]int64, error) {
+	refs, err := references(db)
+	if err != nil {
+		return nil, err
+	}
+
+	broken := make(map[string]int64)
+	for _, ref := range refs {
+		var count int64
+		err := db.Table(ref.table).Where(ref.broken()).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		broken[ref.table+"."+ref.column] = count
+	}
+	return broken, nil
+}
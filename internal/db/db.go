@@ -1,6 +1,9 @@
 package db
 
 import (
+	"strings"
+
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -9,14 +12,106 @@ var (
 	DB *gorm.DB
 )
 
-func InitDB(databasePath string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(databasePath), &gorm.Config{})
+// models are the tables of the database, tables come before the tables pointing at them.
+var models = []interface{}{&Guild{}, &Track{}, &History{}, &Request{}, &GuildSettings{}, &ListeningActivity{}, &Webhook{}, &APIToken{}, &DashboardSession{}, &CommandAlias{}, &CommandMacro{}, &MacroTrigger{}, &ListenLink{}, &TrackRating{}, &TrackTag{}, &Playlist{}, &PlaylistTrack{}, &SourceFailure{}, &TrackPosition{}, &SavedQueue{}, &SavedQueueTrack{}, &ListeningSession{}, &ListeningParty{}, &Lease{}, &FeatureFlag{}}
+
+// Options are the choices of opening the database.
+type Options struct {
+	DeleteBrokenReferences bool // delete rows pointing at missing rows other than guilds, see deleteBrokenReferences
+}
+
+func InitDB(databasePath string, options Options) (*gorm.DB, error) {
+	db, err := Open(databasePath, options)
 	if err != nil {
 		return nil, err
 	}
 
-	db.AutoMigrate(&Guild{}, &History{}, &Track{})
-
 	DB = db
 	return db, nil
 }
+
+// Open opens the SQLite database at the path and creates or updates its tables, without making it the global DB.
+// Foreign keys are enforced, deleting a guild, track or playlist deletes the rows that belong to it.
+func Open(databasePath string, options Options) (*gorm.DB, error) {
+	if err := migrate(databasePath, options); err != nil {
+		return nil, err
+	}
+
+	return gorm.Open(sqlite.Open(withParameter(withParameter(databasePath, "_foreign_keys=1"), busyTimeout)), &gorm.Config{})
+}
+
+// OpenReadOnly opens the SQLite database at the path for reading only, its tables are left as they are.
+func OpenReadOnly(databasePath string) (*gorm.DB, error) {
+	return gorm.Open(sqlite.Open(withParameter("file:"+databasePath, "mode=ro")), &gorm.Config{})
+}
+
+// OpenPostgres opens the Postgres database of the DSN and creates or updates its tables, for copying a database
+// into it. Postgres always enforces foreign keys.
+func OpenPostgres(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		return nil, err
+	}
+	return db, createTrackIndex(db)
+}
+
+// busyTimeout makes queries wait for a database locked by another connection or process, instead of failing
+// with SQLITE_BUSY right away.
+const busyTimeout = "_busy_timeout=5000"
+
+// withParameter adds a query parameter to a database path.
+func withParameter(databasePath, parameter string) string {
+	separator := "?"
+	if strings.Contains(databasePath, "?") {
+		separator = "&"
+	}
+	return databasePath + separator + parameter
+}
+
+// migrate creates or updates the tables. It uses a connection of its own without foreign keys: SQLite changes
+// tables by copying them, and dropping the old table would delete the rows pointing at it.
+func migrate(databasePath string, options Options) error {
+	db, err := gorm.Open(sqlite.Open(withParameter(databasePath, busyTimeout)), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	// The foreign key of history used to be declared by tracks, without deleting history along with its track
+	if db.Migrator().HasConstraint(&History{}, "fk_tracks_histories") {
+		if err := db.Migrator().DropConstraint(&History{}, "fk_tracks_histories"); err != nil {
+			return err
+		}
+	}
+
+	if err := deleteBrokenReferences(db, options.DeleteBrokenReferences); err != nil {
+		return err
+	}
+
+	// Tracks weren't unique by YouTube ID before, duplicates are merged so the unique index can be created
+	if db.Migrator().HasTable(&Track{}) && !db.Migrator().HasIndex(&Track{}, "idx_track_ytid") {
+		db.AutoMigrate(&History{}, &Request{}, &TrackRating{}, &TrackTag{})
+		if _, err := MergeDuplicateTracks(db); err != nil {
+			return err
+		}
+	}
+
+	if err := db.AutoMigrate(models...); err != nil {
+		return err
+	}
+
+	return createTrackIndex(db)
+}
+
+// createTrackIndex makes tracks unique by YouTube ID. It's created by hand, gorm turns a unique index tag of one
+// column into a unique column which empty IDs would violate.
+func createTrackIndex(db *gorm.DB) error {
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_track_ytid ON tracks(yt_id) WHERE yt_id <> ''").Error
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// TrackMerge is the outcome of merging duplicate tracks.
+type TrackMerge struct {
+	Songs  int   // YouTube IDs that were stored more than once
+	Merged int64 // duplicate rows merged into the oldest row of their song
+}
+
+// MergeDuplicateTracks merges tracks stored more than once under the same YouTube ID into the oldest row,
+// which takes the title and URL of the newest one. History of the same guild is summed, requests, ratings and
+// tags are moved over, ratings and tags the oldest row already has are dropped.
+func MergeDuplicateTracks(db *gorm.DB) (TrackMerge, error) {
+	var merge TrackMerge
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var groups []struct {
+			YTID   string
+			Keep   uint
+			Newest uint
+		}
+		err := tx.Model(&Track{}).
+			Select("yt_id, MIN(id) AS keep, MAX(id) AS newest").
+			Where("yt_id <> ''").
+			Group("yt_id").Having("COUNT(*) > 1").
+			Scan(&groups).Error
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			var newest Track
+			if err := tx.First(&newest, group.Newest).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Track{}).Where("id = ?", group.Keep).Updates(map[string]interface{}{"name": newest.Name, "url": newest.URL}).Error; err != nil {
+				return err
+			}
+
+			var duplicates []uint
+			if err := tx.Model(&Track{}).Where("yt_id = ? AND id <> ?", group.YTID, group.Keep).Pluck("id", &duplicates).Error; err != nil {
+				return err
+			}
+			for _, duplicate := range duplicates {
+				if err := mergeTrack(tx, duplicate, group.Keep); err != nil {
+					return err
+				}
+			}
+
+			merge.Songs++
+			merge.Merged += int64(len(duplicates))
+		}
+		return nil
+	})
+
+	return merge, err
+}
+
+// mergeTrack moves everything referencing the duplicate track to the kept one and deletes the duplicate.
+func mergeTrack(tx *gorm.DB, duplicate, keep uint) error {
+	var histories []History
+	if err := tx.Where("track_id = ?", duplicate).Find(&histories).Error; err != nil {
+		return err
+	}
+	for _, history := range histories {
+		var kept History
+		err := tx.Where("track_id = ? AND guild_id = ?", keep, history.GuildID).First(&kept).Error
+		if err == gorm.ErrRecordNotFound {
+			if err := tx.Model(&History{}).Where("id = ?", history.ID).Update("track_id", keep).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		lastPlayed := kept.LastPlayed
+		if history.LastPlayed.After(lastPlayed) {
+			lastPlayed = history.LastPlayed
+		}
+		err = tx.Model(&History{}).Where("id = ?", kept.ID).Updates(map[string]interface{}{
+			"play_count":  kept.PlayCount + history.PlayCount,
+			"skip_count":  kept.SkipCount + history.SkipCount,
+			"duration":    kept.Duration + history.Duration,
+			"last_played": lastPlayed,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&History{}, history.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&Request{}).Where("track_id = ?", duplicate).Update("track_id", keep).Error; err != nil {
+		return err
+	}
+
+	// Ratings and tags are unique per track, the kept track's win
+	err := tx.Where("track_id = ? AND EXISTS (SELECT 1 FROM track_ratings kept WHERE kept.track_id = ? AND kept.guild_id = track_ratings.guild_id AND kept.user_id = track_ratings.user_id)", duplicate, keep).
+		Delete(&TrackRating{}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&TrackRating{}).Where("track_id = ?", duplicate).Update("track_id", keep).Error; err != nil {
+		return err
+	}
+
+	err = tx.Where("track_id = ? AND EXISTS (SELECT 1 FROM track_tags kept WHERE kept.track_id = ? AND kept.guild_id = track_tags.guild_id AND kept.tag = track_tags.tag)", duplicate, keep).
+		Delete(&TrackTag{}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&TrackTag{}).Where("track_id = ?", duplicate).Update("track_id", keep).Error; err != nil {
+		return err
+	}
+
+	return tx.Delete(&Track{}, duplicate).Error
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens an in-memory database with the tables but without the unique index of tracks, as databases
+// with duplicate tracks were.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMergeDuplicateTracks(t *testing.T) {
+	db := openTestDB(t)
+
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	rows := []interface{}{
+		&Guild{ID: "g1", Name: "one"},
+		&Guild{ID: "g2", Name: "two"},
+		&Track{ID: 1, YTID: "abc", Name: "Old title", URL: "https://youtu.be/abc"},
+		&Track{ID: 2, YTID: "abc", Name: "New title", URL: "https://www.youtube.com/watch?v=abc"},
+		&Track{ID: 3, YTID: "xyz", Name: "Other"},
+		&History{GuildID: "g1", TrackID: 1, PlayCount: 2, SkipCount: 1, Duration: 10, LastPlayed: earlier},
+		&History{GuildID: "g1", TrackID: 2, PlayCount: 3, Duration: 5, LastPlayed: later},
+		&History{GuildID: "g2", TrackID: 2, PlayCount: 1},
+		&Request{GuildID: "g1", TrackID: 2, UserID: "u1"},
+		&TrackRating{GuildID: "g1", TrackID: 1, UserID: "u1", Value: 1},
+		&TrackRating{GuildID: "g1", TrackID: 2, UserID: "u1", Value: -1},
+		&TrackRating{GuildID: "g1", TrackID: 2, UserID: "u2", Value: 1},
+		&TrackTag{GuildID: "g1", TrackID: 1, Tag: "chill"},
+		&TrackTag{GuildID: "g1", TrackID: 2, Tag: "chill"},
+		&TrackTag{GuildID: "g1", TrackID: 2, Tag: "focus"},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merge, err := MergeDuplicateTracks(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.Songs != 1 || merge.Merged != 1 {
+		t.Errorf("Incorrect merge %+v", merge)
+	}
+
+	var tracks []Track
+	db.Order("id").Find(&tracks)
+	if len(tracks) != 2 || tracks[0].ID != 1 || tracks[1].ID != 3 {
+		t.Fatalf("Incorrect tracks after merging %+v", tracks)
+	}
+	if tracks[0].Name != "New title" || tracks[0].URL != "https://www.youtube.com/watch?v=abc" {
+		t.Errorf("Kept track didn't take the newest title and URL %+v", tracks[0])
+	}
+
+	var histories []History
+	db.Order("guild_id").Find(&histories)
+	if len(histories) != 2 {
+		t.Fatalf("Incorrect history after merging %+v", histories)
+	}
+	g1 := histories[0]
+	if g1.TrackID != 1 || g1.PlayCount != 5 || g1.SkipCount != 1 || g1.Duration != 15 || !g1.LastPlayed.Equal(later) {
+		t.Errorf("History of the same guild wasn't summed %+v", g1)
+	}
+	if g2 := histories[1]; g2.TrackID != 1 || g2.PlayCount != 1 {
+		t.Errorf("History of another guild wasn't moved %+v", g2)
+	}
+
+	var count int64
+	if db.Model(&Request{}).Where("track_id = ?", 1).Count(&count); count != 1 {
+		t.Errorf("Request wasn't moved")
+	}
+
+	var ratings []TrackRating
+	db.Order("user_id").Find(&ratings)
+	if len(ratings) != 2 || ratings[0].TrackID != 1 || ratings[0].Value != 1 || ratings[1].TrackID != 1 {
+		t.Errorf("Incorrect ratings after merging %+v", ratings)
+	}
+
+	var tags []TrackTag
+	db.Order("tag").Find(&tags)
+	if len(tags) != 2 || tags[0].TrackID != 1 || tags[1].TrackID != 1 || tags[1].Tag != "focus" {
+		t.Errorf("Incorrect tags after merging %+v", tags)
+	}
+
+	merge, err = MergeDuplicateTracks(db)
+	if err != nil || merge.Songs != 0 {
+		t.Errorf("Merged again %+v, %v", merge, err)
+	}
+}
+
+func TestGetOrCreateTrack(t *testing.T) {
+	DB = openTestDB(t)
+	if err := createTrackIndex(DB); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := GetOrCreateTrack(&Track{YTID: "abc", Name: "Title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := GetOrCreateTrack(&Track{YTID: "abc", Name: "Another title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing.ID != created.ID || existing.Name != "Title" {
+		t.Errorf("Track was created again %+v", existing)
+	}
+
+	// Concurrent writers of the same song all get the one track instead of failing on the unique index
+	var wg sync.WaitGroup
+	ids := make([]uint, 8)
+	errs := make([]error, len(ids))
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			track, err := GetOrCreateTrack(&Track{YTID: "xyz", Name: "Raced"})
+			if err == nil {
+				ids[i] = track.ID
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+	for i := range ids {
+		if errs[i] != nil || ids[i] != ids[0] {
+			t.Errorf("Incorrect track %v, %v", ids[i], errs[i])
+		}
+	}
+
+	var count int64
+	if DB.Model(&Track{}).Where("yt_id = ?", "xyz").Count(&count); count != 1 {
+		t.Errorf("Track was stored %v times", count)
+	}
+}
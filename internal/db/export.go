@@ -0,0 +1,265 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GuildData holds everything stored for a single guild.
+type GuildData struct {
+	GuildID        string
+	ExportedAt     time.Time
+	Guild          *Guild
+	Settings       *GuildSettings
+	History        []History
+	Tracks         []Track
+	Requests       []Request
+	Activity       []ListeningActivity
+	Webhooks       []Webhook
+	APITokens      []APIToken
+	Aliases        []CommandAlias
+	Macros         []CommandMacro
+	Triggers       []MacroTrigger
+	Listen         []ListenLink
+	Ratings        []TrackRating
+	Tags           []TrackTag
+	Playlists      []Playlist
+	PlaylistTracks []PlaylistTrack
+	Sessions       []ListeningSession
+	Parties        []ListeningParty
+	Failures       []SourceFailure
+	Positions      []TrackPosition
+	SavedQueue     *SavedQueue
+	SavedTracks    []SavedQueueTrack
+	Flags          []FeatureFlag
+	Lease          *Lease
+}
+
+// ExportGuildData collects all stored data that belongs to a guild.
+func ExportGuildData(guildID string) (*GuildData, error) {
+	data := &GuildData{
+		GuildID:    guildID,
+		ExportedAt: time.Now(),
+	}
+
+	guild, err := GetGuildByID(guildID)
+	if err != nil {
+		return nil, err
+	}
+	data.Guild = guild
+
+	settings, err := GetGuildSettings(guildID)
+	if err != nil {
+		return nil, err
+	}
+	data.Settings = settings
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.History).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Requests).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("hour").Find(&data.Activity).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.APITokens).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Aliases).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Macros).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Triggers).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Listen).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Ratings).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Tags).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Playlists).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("playlist_id, position").Find(&data.PlaylistTracks).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Sessions).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Parties).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&data.Failures).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("song_key").Find(&data.Positions).Error; err != nil {
+		return nil, err
+	}
+
+	var savedQueue SavedQueue
+	result := DB.Where("guild_id = ?", guildID).Limit(1).Find(&savedQueue)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		data.SavedQueue = &savedQueue
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("position").Find(&data.SavedTracks).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("guild_id = ?", guildID).Order("feature").Find(&data.Flags).Error; err != nil {
+		return nil, err
+	}
+
+	var lease Lease
+	result = DB.Where("name = ?", GuildLease(guildID)).Limit(1).Find(&lease)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		data.Lease = &lease
+	}
+
+	trackIDs := make([]uint, 0, len(data.History))
+	for _, history := range data.History {
+		trackIDs = append(trackIDs, history.TrackID)
+	}
+
+	if len(trackIDs) > 0 {
+		if err := DB.Where("id IN ?", trackIDs).Order("id").Find(&data.Tracks).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// PurgeGuildData deletes all stored data that belongs to a guild.
+// The guild registration itself is kept, use DeleteGuild to remove it.
+func PurgeGuildData(guildID string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("guild_id = ?", guildID).Delete(&GuildSettings{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&ListeningActivity{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&Webhook{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&APIToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&CommandAlias{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&CommandMacro{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&MacroTrigger{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&ListenLink{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&TrackRating{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&TrackTag{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&Playlist{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&SourceFailure{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&TrackPosition{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&SavedQueueTrack{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&SavedQueue{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&Request{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&History{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&ListeningSession{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&ListeningParty{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("guild_id = ?", guildID).Delete(&FeatureFlag{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("name = ?", GuildLease(guildID)).Delete(&Lease{}).Error; err != nil {
+			return err
+		}
+
+		return deleteOrphanTracks(tx)
+	})
+}
+
+// deleteOrphanTracks removes tracks that are no longer referenced by any history entry.
+func deleteOrphanTracks(tx *gorm.DB) error {
+	return tx.Where("id NOT IN (?)", tx.Model(&History{}).Select("track_id")).Delete(&Track{}).Error
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestGuildDataCoversModels stores a row of the guild in every table with a GuildID, export must return it
+// and purge must delete it.
+func TestGuildDataCoversModels(t *testing.T) {
+	DB = openTestDB(t)
+
+	if err := CreateGuild(Guild{ID: "g"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AcquireLease(GuildLease("g"), "instance", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	var guildModels []interface{}
+	for _, model := range models {
+		modelType := reflect.TypeOf(model).Elem()
+		field, ok := modelType.FieldByName("GuildID")
+		if !ok {
+			continue
+		}
+		guildModels = append(guildModels, model)
+
+		row := reflect.New(modelType)
+		row.Elem().FieldByIndex(field.Index).SetString("g")
+		if err := DB.Create(row.Interface()).Error; err != nil {
+			t.Fatalf("Creating %v: %v", modelType.Name(), err)
+		}
+	}
+
+	data, err := ExportGuildData("g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := map[reflect.Type]bool{}
+	value := reflect.ValueOf(data).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Slice:
+			exported[field.Type().Elem()] = field.Len() > 0
+		case reflect.Ptr:
+			exported[field.Type().Elem()] = !field.IsNil()
+		}
+	}
+	for _, model := range append(guildModels, &Lease{}) {
+		if modelType := reflect.TypeOf(model).Elem(); !exported[modelType] {
+			t.Errorf("%v of the guild wasn't exported", modelType.Name())
+		}
+	}
+
+	if err := PurgeGuildData("g"); err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range guildModels {
+		var count int64
+		if err := DB.Model(model).Where("guild_id = ?", "g").Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%v rows of %T were kept by purge", count, model)
+		}
+	}
+	if holder, err := GetLeaseHolder(GuildLease("g")); err != nil || holder != "" {
+		t.Errorf("Lease of the guild was kept by purge: %v, %v", holder, err)
+	}
+}
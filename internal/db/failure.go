@@ -0,0 +1,49 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SourceFailure counts the failures of a guild within a day by source, stage and error class.
+type SourceFailure struct {
+	ID      uint      `gorm:"primaryKey;autoIncrement"`
+	GuildID string    `gorm:"index"`
+	Day     time.Time `gorm:"index"` // start of the day in UTC
+	Source  string    // e.g. YouTube, Stream
+	Stage   string    // resolution or playback
+	Class   string    // e.g. timeout, unavailable
+	Count   int64
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// AddSourceFailure counts a failure in the daily bucket the given time belongs to.
+func AddSourceFailure(guildID string, at time.Time, source, stage, class string) error {
+	day := at.UTC().Truncate(24 * time.Hour)
+
+	result := DB.Model(&SourceFailure{}).
+		Where("guild_id = ? AND day = ? AND source = ? AND stage = ? AND class = ?", guildID, day, source, stage, class).
+		UpdateColumn("count", gorm.Expr("count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return DB.Create(&SourceFailure{GuildID: guildID, Day: day, Source: source, Stage: stage, Class: class, Count: 1}).Error
+	}
+
+	return nil
+}
+
+// GetSourceFailuresSince returns daily failure counts of the guild starting from the given time, latest day first.
+func GetSourceFailuresSince(guildID string, since time.Time) ([]SourceFailure, error) {
+	var failures []SourceFailure
+	err := DB.Where("guild_id = ? AND day >= ?", guildID, since.UTC().Truncate(24*time.Hour)).
+		Order("day DESC, count DESC").Find(&failures).Error
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// FeatureFlag turns an experimental feature on or off for a guild, or for every guild without a flag of its own
+// if GuildID is empty. Flags are set by the bot owner.
+type FeatureFlag struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID   string `gorm:"uniqueIndex:idx_feature_flag"`
+	Feature   string `gorm:"uniqueIndex:idx_feature_flag"`
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+// GetFeatureFlags returns the flags of every guild and the defaults.
+func GetFeatureFlags() ([]FeatureFlag, error) {
+	var flags []FeatureFlag
+	if err := DB.Order("guild_id, feature").Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// SetFeatureFlag creates or changes the flag of a feature for the guild, or the default for an empty guild ID.
+func SetFeatureFlag(guildID, feature string, enabled bool) error {
+	flag := FeatureFlag{GuildID: guildID, Feature: feature, Enabled: enabled, UpdatedAt: time.Now()}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guild_id"}, {Name: "feature"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&flag).Error
+}
+
+// DeleteFeatureFlag removes the flag of a feature for the guild so it follows the default again,
+// it returns the number of deleted rows.
+func DeleteFeatureFlag(guildID, feature string) (int64, error) {
+	result := DB.Where("guild_id = ? AND feature = ?", guildID, feature).Delete(&FeatureFlag{})
+	return result.RowsAffected, result.Error
+}
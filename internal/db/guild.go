@@ -7,6 +7,14 @@ import (
 type Guild struct {
 	ID   string `gorm:"primaryKey"`
 	Name string
+	// Unregistered guilds are kept inactive so they aren't registered again on startup. A pointer so an inactive
+	// guild can be created, gorm leaves zero values out of inserts and the column default would apply.
+	Active *bool `gorm:"not null;default:true"`
+}
+
+// IsActive reports whether the guild is registered, guilds created without Active are.
+func (g *Guild) IsActive() bool {
+	return g.Active == nil || *g.Active
 }
 
 func CreateGuild(guild Guild) error {
@@ -46,6 +54,17 @@ func DoesGuildExist(guildID string) (bool, error) {
 	return count > 0, nil
 }
 
+// DeleteGuild removes the guild, its foreign keys delete all rows that belong to it.
 func DeleteGuild(guildID string) error {
 	return DB.Where("id = ?", guildID).Delete(&Guild{}).Error
 }
+
+func GetAllGuilds() ([]Guild, error) {
+	var guilds []Guild
+	err := DB.Order("id").Find(&guilds).Error
+	return guilds, err
+}
+
+func SetGuildActive(guildID string, active bool) error {
+	return DB.Model(&Guild{}).Where("id = ?", guildID).Update("active", active).Error
+}
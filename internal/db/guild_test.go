@@ -0,0 +1,31 @@
+package db
+
+import "testing"
+
+func TestCreateGuildActive(t *testing.T) {
+	DB = openTestDB(t)
+
+	inactive := false
+	for _, guild := range []Guild{{ID: "1"}, {ID: "2", Active: &inactive}} {
+		if err := CreateGuild(guild); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for id, want := range map[string]bool{"1": true, "2": false} {
+		guild, err := GetGuildByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guild.IsActive() != want {
+			t.Errorf("Guild %v active: %v, want %v", id, guild.IsActive(), want)
+		}
+	}
+
+	if err := SetGuildActive("2", true); err != nil {
+		t.Fatal(err)
+	}
+	if guild, _ := GetGuildByID("2"); !guild.IsActive() {
+		t.Errorf("Guild wasn't activated")
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// unavailableCodes are the SQLite errors of a database that can't be used right now, as opposed to
+// errors of a single query like a missing record. Busy and locked databases aren't among them: queries wait
+// for locks up to the busy timeout, and one that still fails is a failed write rather than an outage.
+var unavailableCodes = map[sqlite3.ErrNo]bool{
+	sqlite3.ErrIoErr:    true,
+	sqlite3.ErrCorrupt:  true,
+	sqlite3.ErrFull:     true,
+	sqlite3.ErrCantOpen: true,
+	sqlite3.ErrReadonly: true,
+	sqlite3.ErrNotADB:   true,
+}
+
+// IsUnavailable reports whether err means the database itself is unavailable, e.g. read-only or its disk failed.
+func IsUnavailable(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && unavailableCodes[sqliteErr.Code]
+}
+
+// Ping checks whether the database can be read.
+func Ping() error {
+	var count int64
+	return DB.Model(&Guild{}).Count(&count).Error
+}
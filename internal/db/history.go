@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -8,12 +9,24 @@ import (
 )
 
 type History struct {
-	ID         uint `gorm:"primaryKey;autoIncrement"`
-	GuildID    string
-	TrackID    uint
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID    string `gorm:"index"`
+	TrackID    uint   `gorm:"index"`
 	PlayCount  uint
+	SkipCount  uint
 	Duration   float64
 	LastPlayed time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Track *Track `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// HistoryWithTrack is a history entry joined with the track it refers to.
+type HistoryWithTrack struct {
+	History
+	TrackYTID string `gorm:"column:track_yt_id"`
+	TrackName string `gorm:"column:track_name"`
+	TrackURL  string `gorm:"column:track_url"`
 }
 
 func CreateHistory(history *History) error {
@@ -21,7 +34,9 @@ func CreateHistory(history *History) error {
 	return DB.Create(history).Error
 }
 
-func GetAllHistorySortedBy(sortBy string) ([]History, error) {
+// GetAllHistorySortedBy fetches history entries of all guilds.
+// A limit of zero or less fetches all remaining entries after offset.
+func GetAllHistorySortedBy(sortBy string, limit, offset int) ([]History, error) {
 	var history []History
 	var query *gorm.DB
 
@@ -30,20 +45,24 @@ func GetAllHistorySortedBy(sortBy string) ([]History, error) {
 		query = DB.Order("duration DESC")
 	case "play_count":
 		query = DB.Order("play_count DESC")
+	case "skip_count":
+		query = DB.Order("skip_count DESC")
 	case "last_played":
 		query = DB.Order("last_played DESC")
 	default:
 		return nil, fmt.Errorf("unsupported sort criteria: %s", sortBy)
 	}
 
-	if err := query.Find(&history).Error; err != nil {
+	if err := paginate(query, limit, offset).Find(&history).Error; err != nil {
 		return nil, err
 	}
 
 	return history, nil
 }
 
-func GetGuildHistorySortedBy(guildID, sortBy string) ([]History, error) {
+// GetGuildHistorySortedBy fetches history entries of a guild.
+// A limit of zero or less fetches all remaining entries after offset.
+func GetGuildHistorySortedBy(guildID, sortBy string, limit, offset int) ([]History, error) {
 	var history []History
 	var query *gorm.DB
 
@@ -52,22 +71,108 @@ func GetGuildHistorySortedBy(guildID, sortBy string) ([]History, error) {
 		query = DB.Where("guild_id = ?", guildID).Order("duration DESC")
 	case "play_count":
 		query = DB.Where("guild_id = ?", guildID).Order("play_count DESC")
+	case "skip_count":
+		query = DB.Where("guild_id = ?", guildID).Order("skip_count DESC")
 	case "last_played":
 		query = DB.Where("guild_id = ?", guildID).Order("last_played DESC")
 	default:
 		return nil, fmt.Errorf("unsupported sort criteria: %s", sortBy)
 	}
 
-	if err := query.Find(&history).Error; err != nil {
+	if err := paginate(query, limit, offset).Find(&history).Error; err != nil {
 		return nil, err
 	}
 
 	return history, nil
 }
 
-func DoesHistoryExistForGuild(trackID uint, guildID string) (bool, error) {
+// GetHistoryWithTracksSortedBy fetches history entries together with their tracks in a single query.
+// An empty guildID returns entries of all guilds, a limit of zero or less returns all entries after offset.
+func GetHistoryWithTracksSortedBy(ctx context.Context, guildID, sortBy string, limit, offset int) ([]HistoryWithTrack, error) {
+	var rows []HistoryWithTrack
+
+	query, err := historyWithTracksQuery(ctx, guildID, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paginate(query, limit, offset).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// GetTaggedHistoryWithTracksSortedBy fetches history entries of the guild's tracks with the tag together with their tracks.
+// A limit of zero or less returns all entries after offset.
+func GetTaggedHistoryWithTracksSortedBy(ctx context.Context, guildID, tag, sortBy string, limit, offset int) ([]HistoryWithTrack, error) {
+	var rows []HistoryWithTrack
+
+	query, err := historyWithTracksQuery(ctx, guildID, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.
+		Joins("JOIN track_tags ON track_tags.track_id = histories.track_id AND track_tags.guild_id = histories.guild_id").
+		Where("track_tags.tag = ?", tag)
+
+	if err := paginate(query, limit, offset).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// historyWithTracksQuery selects history entries joined with their tracks, of all guilds if guildID is empty.
+func historyWithTracksQuery(ctx context.Context, guildID, sortBy string) (*gorm.DB, error) {
+	order, err := historyOrderClause(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := DB.WithContext(ctx).Table("histories").
+		Select("histories.*, tracks.yt_id AS track_yt_id, tracks.name AS track_name, tracks.url AS track_url").
+		Joins("JOIN tracks ON tracks.id = histories.track_id").
+		Order(order)
+
+	if guildID != "" {
+		query = query.Where("histories.guild_id = ?", guildID)
+	}
+
+	return query, nil
+}
+
+// paginate applies limit and offset to the query, non-positive values are ignored.
+func paginate(query *gorm.DB, limit, offset int) *gorm.DB {
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	return query
+}
+
+// historyOrderClause maps a sort criteria to the ORDER BY clause of the histories table.
+func historyOrderClause(sortBy string) (string, error) {
+	switch sortBy {
+	case "duration":
+		return "histories.duration DESC", nil
+	case "play_count":
+		return "histories.play_count DESC", nil
+	case "skip_count":
+		return "histories.skip_count DESC", nil
+	case "last_played":
+		return "histories.last_played DESC", nil
+	default:
+		return "", fmt.Errorf("unsupported sort criteria: %s", sortBy)
+	}
+}
+
+func DoesHistoryExistForGuild(ctx context.Context, trackID uint, guildID string) (bool, error) {
 	var count int64
-	err := DB.Model(&History{}).Where("track_id = ? AND guild_id = ?", trackID, guildID).Count(&count).Error
+	err := DB.WithContext(ctx).Model(&History{}).Where("track_id = ? AND guild_id = ?", trackID, guildID).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -91,3 +196,10 @@ func UpdateTrackStatsForGuild(trackID uint, guildID string, playCount uint, dura
 			"last_played": time.Now(),
 		}).Error
 }
+
+// IncrementSkipCountForGuild counts one more skip of the track in the guild.
+func IncrementSkipCountForGuild(trackID uint, guildID string) error {
+	return DB.Model(&History{}).
+		Where("track_id = ? AND guild_id = ?", trackID, guildID).
+		UpdateColumn("skip_count", gorm.Expr("skip_count + ?", 1)).Error
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lease gives one of several processes sharing the database ownership of a resource, e.g. the player of a guild,
+// until it expires. The owner renews it while it runs, others take it over once it expired.
+type Lease struct {
+	Name       string `gorm:"primaryKey"` // resource, e.g. "guild:<guild id>"
+	InstanceID string
+	ExpiresAt  time.Time
+}
+
+// GuildLease is the name of the lease on the player of a guild.
+func GuildLease(guildID string) string {
+	return "guild:" + guildID
+}
+
+// AcquireLease takes the lease for the instance or renews it, false if another instance holds it.
+func AcquireLease(name, instanceID string, ttl time.Duration) (bool, error) {
+	// Times are stored in UTC so they compare as text
+	now := time.Now().UTC()
+	lease := Lease{Name: name, InstanceID: instanceID, ExpiresAt: now.Add(ttl)}
+
+	result := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"instance_id", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("leases.instance_id = ? OR leases.expires_at < ?", instanceID, now),
+		}},
+	}).Create(&lease)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseLease gives up a lease of the instance, so others can take it over right away.
+func ReleaseLease(name, instanceID string) error {
+	return DB.Where("name = ? AND instance_id = ?", name, instanceID).Delete(&Lease{}).Error
+}
+
+// ReleaseLeases gives up all leases of the instance.
+func ReleaseLeases(instanceID string) error {
+	return DB.Where("instance_id = ?", instanceID).Delete(&Lease{}).Error
+}
+
+// GetLeaseHolder returns the instance holding a lease, empty if nobody holds it or it expired.
+func GetLeaseHolder(name string) (string, error) {
+	var lease Lease
+	err := DB.Where("name = ? AND expires_at >= ?", name, time.Now().UTC()).Limit(1).Find(&lease).Error
+	return lease.InstanceID, err
+}
+
+// GetLeases returns all leases, expired ones included.
+func GetLeases() ([]Lease, error) {
+	var leases []Lease
+	err := DB.Order("name").Find(&leases).Error
+	return leases, err
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireLease(t *testing.T) {
+	DB = openTestDB(t)
+
+	acquire := func(instanceID string, ttl time.Duration) bool {
+		t.Helper()
+		owned, err := AcquireLease("guild:1", instanceID, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return owned
+	}
+	holder := func() string {
+		t.Helper()
+		holder, err := GetLeaseHolder("guild:1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return holder
+	}
+
+	if !acquire("a", time.Minute) {
+		t.Fatal("Free lease wasn't acquired")
+	}
+	if !acquire("a", time.Minute) {
+		t.Error("Holder couldn't renew its lease")
+	}
+	if acquire("b", time.Minute) || holder() != "a" {
+		t.Error("Lease held by another instance was taken over")
+	}
+
+	// An expired lease is taken over by the first instance that asks
+	if !acquire("a", -time.Second) || holder() != "" {
+		t.Fatal("Expired lease still has a holder")
+	}
+	if !acquire("b", time.Minute) || holder() != "b" {
+		t.Error("Expired lease wasn't taken over")
+	}
+	if acquire("a", time.Minute) {
+		t.Error("Previous holder took its lease back")
+	}
+
+	if err := ReleaseLeases("b"); err != nil {
+		t.Fatal(err)
+	}
+	if holder() != "" || !acquire("a", time.Minute) {
+		t.Error("Released lease wasn't free")
+	}
+}
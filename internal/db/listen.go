@@ -0,0 +1,48 @@
+package db
+
+import (
+	"time"
+)
+
+// ListenLink grants a read-only live view of a guild's player to anyone with the link until it expires.
+type ListenLink struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	TokenHash string `gorm:"uniqueIndex" json:"-"` // SHA-256 of the token in the link, see HashSecret
+	GuildID   string `gorm:"index"`
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateListenLink(link *ListenLink) error {
+	link.CreatedAt = time.Now()
+	return DB.Create(link).Error
+}
+
+// GetListenLinkByHash returns the link of a token hash, expired links are returned too.
+func GetListenLinkByHash(tokenHash string) (*ListenLink, error) {
+	var link ListenLink
+	if err := DB.Where("token_hash = ?", tokenHash).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// DeleteListenLinks revokes all links of the guild, it returns the number of deleted rows.
+func DeleteListenLinks(guildID string) (int64, error) {
+	result := DB.Where("guild_id = ?", guildID).Delete(&ListenLink{})
+	return result.RowsAffected, result.Error
+}
+
+func DeleteExpiredListenLinks() (int64, error) {
+	result := DB.Where("expires_at <= ?", time.Now()).Delete(&ListenLink{})
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeUserListenLinks detaches the user from the listen links they created in all guilds, it returns the number of affected rows.
+func AnonymizeUserListenLinks(userID string) (int64, error) {
+	result := DB.Model(&ListenLink{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"time"
+)
+
+// ListeningSession is the time the bot spends in voice in a guild, from joining a channel until leaving voice.
+// Plays made meanwhile are attached to it.
+type ListeningSession struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID   string `gorm:"index"`
+	ChannelID string // voice channel the bot was last in
+	StartedAt time.Time
+	EndedAt   *time.Time // nil while the bot is in voice
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Duration returns how long the session lasted, until now if it hasn't ended.
+func (session *ListeningSession) Duration() time.Duration {
+	if session.EndedAt == nil {
+		return time.Since(session.StartedAt)
+	}
+	return session.EndedAt.Sub(session.StartedAt)
+}
+
+// ListeningSessionInfo is a session with the number of plays attached to it.
+type ListeningSessionInfo struct {
+	ListeningSession
+	Plays int64
+}
+
+// SessionPlay is a play of a session with its track.
+type SessionPlay struct {
+	RequestedAt time.Time
+	UserID      string
+	Skipped     bool
+	Name        string
+	URL         string
+	YTID        string
+}
+
+// SessionData holds a session and everything played during it.
+type SessionData struct {
+	GuildID    string
+	ExportedAt time.Time
+	Session    ListeningSession
+	Plays      []SessionPlay
+}
+
+// StartListeningSession opens a session of the guild in a voice channel.
+func StartListeningSession(guildID, channelID string) (*ListeningSession, error) {
+	session := &ListeningSession{GuildID: guildID, ChannelID: channelID, StartedAt: time.Now()}
+	if err := DB.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// MoveListeningSession records that the bot moved to another voice channel during the session.
+func MoveListeningSession(id uint, channelID string) error {
+	return DB.Model(&ListeningSession{}).Where("id = ?", id).Update("channel_id", channelID).Error
+}
+
+// EndListeningSession closes a session.
+func EndListeningSession(id uint) error {
+	return DB.Model(&ListeningSession{}).Where("id = ? AND ended_at IS NULL", id).Update("ended_at", time.Now()).Error
+}
+
+// EndAbandonedListeningSessions closes the sessions of the guild left open by a crash or a lost lease.
+// As the bot left voice unnoticed, they end with their last play, or where they started if nothing played.
+func EndAbandonedListeningSessions(guildID string) error {
+	var sessions []ListeningSession
+	if err := DB.Where("guild_id = ? AND ended_at IS NULL", guildID).Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		end := session.StartedAt
+		var last Request
+		err := DB.Where("session_id = ?", session.ID).Order("requested_at DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		if last.ID != 0 {
+			end = last.RequestedAt
+		}
+		if err := DB.Model(&ListeningSession{}).Where("id = ?", session.ID).Update("ended_at", end).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOpenListeningSession returns the session of the guild the bot is in voice for, nil if there is none.
+func GetOpenListeningSession(guildID string) (*ListeningSession, error) {
+	var sessions []ListeningSession
+	if err := DB.Where("guild_id = ? AND ended_at IS NULL", guildID).Order("started_at DESC").Limit(1).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	return &sessions[0], nil
+}
+
+// GetListeningSession returns a session of the guild.
+func GetListeningSession(guildID string, id uint) (*ListeningSession, error) {
+	var session ListeningSession
+	if err := DB.Where("guild_id = ? AND id = ?", guildID, id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetListeningSessions returns the latest sessions of the guild that started since the given time,
+// with the number of plays of each.
+func GetListeningSessions(guildID string, since time.Time, limit int) ([]ListeningSessionInfo, error) {
+	var sessions []ListeningSessionInfo
+
+	query := DB.Table("listening_sessions").
+		Select("listening_sessions.*, COUNT(requests.id) AS plays").
+		Joins("LEFT JOIN requests ON requests.session_id = listening_sessions.id").
+		Where("listening_sessions.guild_id = ? AND listening_sessions.started_at >= ?", guildID, since).
+		Group("listening_sessions.id").
+		Order("listening_sessions.started_at DESC")
+
+	if err := paginate(query, limit, 0).Scan(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// CountSessionRequests counts the plays of a session.
+func CountSessionRequests(sessionID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&Request{}).Where("session_id = ?", sessionID).Count(&count).Error
+	return count, err
+}
+
+// GetSessionTopRequesters returns the users who requested the most plays of a session,
+// plays of no known user are left out.
+func GetSessionTopRequesters(sessionID uint, limit int) ([]UserRequestCount, error) {
+	var counts []UserRequestCount
+
+	query := DB.Model(&Request{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("session_id = ? AND user_id <> ''", sessionID).
+		Group("user_id").
+		Order("count DESC")
+
+	if err := paginate(query, limit, 0).Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetSessionSkippedTracks returns the most skipped tracks of a session.
+func GetSessionSkippedTracks(sessionID uint, limit int) ([]TrackRequestCount, error) {
+	var counts []TrackRequestCount
+
+	query := DB.Table("requests").
+		Select("requests.track_id AS track_id, tracks.name AS name, tracks.url AS url, COUNT(*) AS count, MAX(requests.requested_at) AS last_played").
+		Joins("JOIN tracks ON tracks.id = requests.track_id").
+		Where("requests.session_id = ? AND requests.skipped = ?", sessionID, true).
+		Group("requests.track_id, tracks.name, tracks.url").
+		Order("count DESC")
+
+	if err := paginate(query, limit, 0).Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// ExportListeningSession collects a session of the guild and its plays in the order they were made.
+func ExportListeningSession(guildID string, id uint) (*SessionData, error) {
+	session, err := GetListeningSession(guildID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &SessionData{GuildID: guildID, ExportedAt: time.Now(), Session: *session}
+	err = DB.Table("requests").
+		Select("requests.requested_at, requests.user_id, requests.skipped, tracks.name, tracks.url, tracks.yt_id").
+		Joins("JOIN tracks ON tracks.id = requests.track_id").
+		Where("requests.session_id = ?", id).
+		Order("requests.requested_at, requests.id").
+		Scan(&data.Plays).Error
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
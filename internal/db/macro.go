@@ -0,0 +1,61 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CommandMacro is a named sequence of bot commands defined by the admins of a guild.
+type CommandMacro struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID   string `gorm:"uniqueIndex:idx_command_macro"`
+	Name      string `gorm:"uniqueIndex:idx_command_macro"`
+	Steps     string // commands without the prefix separated by "; ", e.g. "shuffle; play lofi"
+	CreatedBy string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SaveCommandMacro creates the macro, or replaces the steps of the guild's macro of the same name.
+func SaveCommandMacro(macro *CommandMacro) error {
+	var existing CommandMacro
+	err := DB.Where("guild_id = ? AND name = ?", macro.GuildID, macro.Name).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DB.Create(macro).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Steps = macro.Steps
+	existing.CreatedBy = macro.CreatedBy
+	if err := DB.Save(&existing).Error; err != nil {
+		return err
+	}
+	*macro = existing
+	return nil
+}
+
+func GetCommandMacrosByGuildID(guildID string) ([]CommandMacro, error) {
+	var macros []CommandMacro
+	if err := DB.Where("guild_id = ?", guildID).Order("name").Find(&macros).Error; err != nil {
+		return nil, err
+	}
+	return macros, nil
+}
+
+// DeleteCommandMacro removes a macro of the guild, it returns the number of deleted rows.
+func DeleteCommandMacro(guildID, name string) (int64, error) {
+	result := DB.Where("guild_id = ? AND name = ?", guildID, name).Delete(&CommandMacro{})
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeUserCommandMacros detaches the user from the macros they saved in all guilds, it returns the number of affected rows.
+func AnonymizeUserCommandMacros(userID string) (int64, error) {
+	result := DB.Model(&CommandMacro{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"time"
+)
+
+// States of listening parties.
+const (
+	PartyScheduled = "scheduled" // announced, waiting for its start
+	PartyActive    = "active"    // started, ends when the bot leaves voice
+	PartyDone      = "done"
+)
+
+// ListeningParty is an announced time the bot joins a voice or stage channel to play, published as a
+// scheduled event of the guild.
+type ListeningParty struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID        string `gorm:"index"`
+	Title          string
+	Query          string // played when the party starts, empty to only join
+	VoiceChannelID string
+	ChannelID      string // text channel the party was announced in
+	EventID        string // scheduled event of the guild, empty if it couldn't be created
+	StartAt        time.Time
+	Status         string
+	CreatedBy      string
+	CreatedAt      time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateListeningParty(party *ListeningParty) error {
+	party.CreatedAt = time.Now()
+	party.Status = PartyScheduled
+	return DB.Create(party).Error
+}
+
+// SaveListeningParty updates a party, e.g. with its scheduled event or its new status.
+func SaveListeningParty(party *ListeningParty) error {
+	return DB.Save(party).Error
+}
+
+// GetListeningParties returns the parties of the guild that haven't ended, soonest first.
+func GetListeningParties(guildID string) ([]ListeningParty, error) {
+	var parties []ListeningParty
+	if err := DB.Where("guild_id = ? AND status <> ?", guildID, PartyDone).Order("start_at").Find(&parties).Error; err != nil {
+		return nil, err
+	}
+	return parties, nil
+}
+
+// GetListeningPartiesByStatus returns the parties of the guild in a state that start before the given time.
+func GetListeningPartiesByStatus(guildID, status string, before time.Time) ([]ListeningParty, error) {
+	var parties []ListeningParty
+	if err := DB.Where("guild_id = ? AND status = ? AND start_at <= ?", guildID, status, before).Order("start_at").Find(&parties).Error; err != nil {
+		return nil, err
+	}
+	return parties, nil
+}
+
+// GetListeningParty returns a party of the guild.
+func GetListeningParty(guildID string, id uint) (*ListeningParty, error) {
+	var party ListeningParty
+	if err := DB.Where("guild_id = ? AND id = ?", guildID, id).First(&party).Error; err != nil {
+		return nil, err
+	}
+	return &party, nil
+}
+
+// DeleteListeningParty removes a party of the guild.
+func DeleteListeningParty(guildID string, id uint) error {
+	return DB.Where("guild_id = ? AND id = ?", guildID, id).Delete(&ListeningParty{}).Error
+}
+
+// AnonymizeUserListeningParties detaches the user from the listening parties they scheduled in all guilds, it returns the number of affected rows.
+func AnonymizeUserListeningParties(userID string) (int64, error) {
+	result := DB.Model(&ListeningParty{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
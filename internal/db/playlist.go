@@ -0,0 +1,106 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Playlist is a named list of tracks saved by a guild, e.g. the queue left over when playback stopped.
+type Playlist struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID    string `gorm:"uniqueIndex:idx_playlist"`
+	Name       string `gorm:"uniqueIndex:idx_playlist"`
+	TrackCount int
+	CreatedBy  string
+	CreatedAt  time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PlaylistTrack is a track of a playlist.
+type PlaylistTrack struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	PlaylistID uint   `gorm:"index"`
+	GuildID    string `gorm:"index"`
+	Position   int
+	Title      string
+	URL        string
+	SongID     string // YouTube ID, or the ID the source gave the song
+	Source     string // player source name, e.g. "YouTube" or "Stream"
+
+	Playlist *Playlist `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Guild    *Guild    `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// CreatePlaylist saves a playlist with its tracks, positions follow their order.
+func CreatePlaylist(playlist *Playlist, tracks []PlaylistTrack) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		playlist.TrackCount = len(tracks)
+		if err := tx.Create(playlist).Error; err != nil {
+			return err
+		}
+
+		if len(tracks) == 0 {
+			return nil
+		}
+		for i := range tracks {
+			tracks[i].PlaylistID = playlist.ID
+			tracks[i].GuildID = playlist.GuildID
+			tracks[i].Position = i
+		}
+		return tx.Create(&tracks).Error
+	})
+}
+
+func GetPlaylistsByGuildID(guildID string) ([]Playlist, error) {
+	var playlists []Playlist
+	if err := DB.Where("guild_id = ?", guildID).Order("name").Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+func GetPlaylistByName(guildID, name string) (*Playlist, error) {
+	var playlist Playlist
+	if err := DB.Where("guild_id = ? AND name = ?", guildID, name).First(&playlist).Error; err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// GetPlaylistTracks returns the tracks of a playlist in order.
+func GetPlaylistTracks(playlistID uint) ([]PlaylistTrack, error) {
+	var tracks []PlaylistTrack
+	if err := DB.Where("playlist_id = ?", playlistID).Order("position").Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// DeletePlaylist removes a playlist of the guild with its tracks, it returns the number of deleted playlists.
+func DeletePlaylist(guildID, name string) (int64, error) {
+	var deleted int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var playlist Playlist
+		result := tx.Where("guild_id = ? AND name = ?", guildID, name).Limit(1).Find(&playlist)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Where("playlist_id = ?", playlist.ID).Delete(&PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+		result = tx.Delete(&playlist)
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// AnonymizeUserPlaylists detaches the user from the playlists they saved in all guilds, the playlists stay.
+// It returns the number of affected rows.
+func AnonymizeUserPlaylists(userID string) (int64, error) {
+	result := DB.Model(&Playlist{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrackPosition is where a guild stopped listening to a long-form track, it resumes there when played again.
+type TrackPosition struct {
+	GuildID   string        `gorm:"primaryKey"`
+	SongKey   string        `gorm:"primaryKey"` // YouTube ID, or the URL of songs without one
+	Position  time.Duration // from the start of the track
+	UpdatedAt time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SaveTrackPosition remembers the position of a track in the guild, replacing the one remembered before.
+func SaveTrackPosition(guildID, songKey string, position time.Duration) error {
+	return DB.Save(&TrackPosition{GuildID: guildID, SongKey: songKey, Position: position}).Error
+}
+
+// GetTrackPosition returns the remembered position of a track in the guild, 0 if there's none.
+func GetTrackPosition(guildID, songKey string) (time.Duration, error) {
+	var position TrackPosition
+	err := DB.Where("guild_id = ? AND song_key = ?", guildID, songKey).First(&position).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return position.Position, nil
+}
+
+// DeleteTrackPosition forgets the position of a track in the guild.
+func DeleteTrackPosition(guildID, songKey string) error {
+	return DB.Where("guild_id = ? AND song_key = ?", guildID, songKey).Delete(&TrackPosition{}).Error
+}
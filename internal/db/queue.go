@@ -0,0 +1,92 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedQueue is the queue of a guild as it was last seen, so it can be restored after a restart or a crash.
+type SavedQueue struct {
+	GuildID          string        `gorm:"primaryKey"`
+	ChannelID        string        // voice channel the bot was in
+	PlaybackPosition time.Duration // in the first track, the one that was playing
+	SavedAt          time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SavedQueueTrack is a track of a saved queue.
+type SavedQueueTrack struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID     string `gorm:"index"`
+	Position    int
+	Title       string
+	URL         string
+	SongID      string // YouTube ID, or the ID the source gave the song
+	Source      string // player source name, e.g. "YouTube" or "Stream"
+	RequesterID string
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SaveQueue replaces the saved queue of the guild, positions of the tracks follow their order.
+func SaveQueue(queue *SavedQueue, tracks []SavedQueueTrack) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("guild_id = ?", queue.GuildID).Delete(&SavedQueueTrack{}).Error; err != nil {
+			return err
+		}
+
+		queue.SavedAt = time.Now()
+		if err := tx.Save(queue).Error; err != nil {
+			return err
+		}
+
+		if len(tracks) == 0 {
+			return nil
+		}
+		for i := range tracks {
+			tracks[i].GuildID = queue.GuildID
+			tracks[i].Position = i
+		}
+		return tx.Create(&tracks).Error
+	})
+}
+
+// SaveQueuePosition updates the playback position of the saved queue of the guild, its tracks stay.
+func SaveQueuePosition(guildID string, position time.Duration) error {
+	return DB.Model(&SavedQueue{}).Where("guild_id = ?", guildID).
+		Updates(map[string]interface{}{"playback_position": position, "saved_at": time.Now()}).Error
+}
+
+// GetSavedQueue returns the saved queue of the guild with its tracks in order,
+// gorm.ErrRecordNotFound if none is saved.
+func GetSavedQueue(guildID string) (*SavedQueue, []SavedQueueTrack, error) {
+	var queue SavedQueue
+	if err := DB.Where("guild_id = ?", guildID).First(&queue).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var tracks []SavedQueueTrack
+	if err := DB.Where("guild_id = ?", guildID).Order("position").Find(&tracks).Error; err != nil {
+		return nil, nil, err
+	}
+	return &queue, tracks, nil
+}
+
+// DeleteSavedQueue forgets the saved queue of the guild.
+func DeleteSavedQueue(guildID string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("guild_id = ?", guildID).Delete(&SavedQueueTrack{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("guild_id = ?", guildID).Delete(&SavedQueue{}).Error
+	})
+}
+
+// AnonymizeUserSavedQueueTracks detaches the user from the tracks they requested in saved queues of all guilds,
+// it returns the number of affected rows.
+func AnonymizeUserSavedQueueTracks(userID string) (int64, error) {
+	result := DB.Model(&SavedQueueTrack{}).Where("requester_id = ?", userID).Update("requester_id", "")
+	return result.RowsAffected, result.Error
+}
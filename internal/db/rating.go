@@ -0,0 +1,89 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrackRating is a like (+1) or dislike (-1) of a track by a member of a guild.
+type TrackRating struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID   string `gorm:"uniqueIndex:idx_track_rating"`
+	TrackID   uint   `gorm:"uniqueIndex:idx_track_rating;index"`
+	UserID    string `gorm:"uniqueIndex:idx_track_rating"`
+	Value     int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Track *Track `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TrackRatingSummary counts the likes and dislikes of a track in a guild.
+type TrackRatingSummary struct {
+	TrackID  uint
+	Likes    int64
+	Dislikes int64
+}
+
+// Score is likes minus dislikes.
+func (s TrackRatingSummary) Score() int64 {
+	return s.Likes - s.Dislikes
+}
+
+// SaveTrackRating creates the user's rating of the track, or replaces the value of an existing one.
+func SaveTrackRating(rating *TrackRating) error {
+	var existing TrackRating
+	err := DB.Where("guild_id = ? AND track_id = ? AND user_id = ?", rating.GuildID, rating.TrackID, rating.UserID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DB.Create(rating).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Value = rating.Value
+	if err := DB.Save(&existing).Error; err != nil {
+		return err
+	}
+	*rating = existing
+	return nil
+}
+
+// DeleteTrackRating removes the user's rating of the track if it has the value, it returns the number of deleted rows.
+func DeleteTrackRating(guildID string, trackID uint, userID string, value int) (int64, error) {
+	result := DB.Where("guild_id = ? AND track_id = ? AND user_id = ? AND value = ?", guildID, trackID, userID, value).Delete(&TrackRating{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteUserTrackRatings removes the ratings of the user in all guilds, it returns the number of deleted rows.
+func DeleteUserTrackRatings(userID string) (int64, error) {
+	result := DB.Where("user_id = ?", userID).Delete(&TrackRating{})
+	return result.RowsAffected, result.Error
+}
+
+// GetTrackRatingSummaries counts likes and dislikes of the guild's tracks, all rated tracks if trackIDs is empty.
+func GetTrackRatingSummaries(guildID string, trackIDs []uint) (map[uint]TrackRatingSummary, error) {
+	var rows []TrackRatingSummary
+
+	query := DB.Model(&TrackRating{}).
+		Select("track_id, SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END) AS likes, SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END) AS dislikes").
+		Where("guild_id = ?", guildID).
+		Group("track_id")
+
+	if len(trackIDs) > 0 {
+		query = query.Where("track_id IN ?", trackIDs)
+	}
+
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[uint]TrackRatingSummary, len(rows))
+	for _, row := range rows {
+		summaries[row.TrackID] = row
+	}
+	return summaries, nil
+}
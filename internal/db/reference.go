@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/gookit/slog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// reference is a column pointing at the primary key of another table, declared by a belongs-to relation of a
+// model. Deleting the target row deletes the rows pointing at it.
+type reference struct {
+	model                interface{}
+	constraint           string
+	table, column        string
+	target, targetColumn string
+}
+
+// broken is the condition of rows pointing at rows that don't exist.
+func (ref reference) broken() string {
+	return fmt.Sprintf("%v NOT IN (SELECT %v FROM %v)", ref.column, ref.targetColumn, ref.target)
+}
+
+// references returns the relations between the tables of the models.
+func references(db *gorm.DB) ([]reference, error) {
+	var refs []reference
+	for _, model := range models {
+		statement := &gorm.Statement{DB: db}
+		if err := statement.Parse(model); err != nil {
+			return nil, err
+		}
+
+		for _, rel := range statement.Schema.Relationships.Relations {
+			if rel.Type != schema.BelongsTo {
+				continue
+			}
+			constraint := rel.ParseConstraint()
+			for _, ref := range rel.References {
+				refs = append(refs, reference{
+					model:        model,
+					constraint:   constraint.Name,
+					table:        statement.Schema.Table,
+					column:       ref.ForeignKey.DBName,
+					target:       rel.FieldSchema.Table,
+					targetColumn: ref.PrimaryKey.DBName,
+				})
+			}
+		}
+	}
+	return refs, nil
+}
+
+// deleteBrokenReferences repairs rows pointing at missing rows of tables that don't have their foreign keys yet.
+// Databases of older versions have such rows: unregistering deleted the guild and kept its history, and tracks were
+// deleted by hand. Missing guilds are added back as inactive, like guilds unregistered today, so no history is lost.
+// Rows pointing at other missing rows are only counted unless deleting them is asked for; SQLite adds the
+// constraints anyway since the migration runs without enforcing foreign keys.
+func deleteBrokenReferences(db *gorm.DB, deleteBroken bool) error {
+	refs, err := references(db)
+	if err != nil {
+		return err
+	}
+
+	guilds := db.Config.NamingStrategy.TableName("Guild")
+	for _, ref := range refs {
+		migrator := db.Migrator()
+		if !migrator.HasColumn(ref.table, ref.column) || !migrator.HasTable(ref.target) {
+			continue
+		}
+		// Rows kept by an upgrade are still deleted once asked for
+		constrained := migrator.HasConstraint(ref.model, ref.constraint)
+
+		if ref.target == guilds {
+			if !constrained {
+				if err := restoreMissingGuilds(db, ref); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if constrained && !deleteBroken {
+			continue
+		}
+
+		var broken int64
+		if err := db.Table(ref.table).Where(ref.broken()).Count(&broken).Error; err != nil {
+			return err
+		}
+		if broken == 0 {
+			continue
+		}
+
+		if !deleteBroken {
+			slog.Warnf("Keeping %d rows of %v pointing at missing %v, set DATABASE_DELETE_BROKEN=true to delete them", broken, ref.table, ref.target)
+			continue
+		}
+
+		result := db.Exec(fmt.Sprintf("DELETE FROM %v WHERE %v", ref.table, ref.broken()))
+		if result.Error != nil {
+			return result.Error
+		}
+		slog.Warnf("Deleted %d rows of %v pointing at missing %v", result.RowsAffected, ref.table, ref.target)
+	}
+	return nil
+}
+
+// restoreMissingGuilds adds the missing guilds rows of the reference point at, as inactive guilds.
+func restoreMissingGuilds(db *gorm.DB, ref reference) error {
+	// Guilds of older databases have no active column yet
+	if err := db.AutoMigrate(&Guild{}); err != nil {
+		return err
+	}
+
+	result := db.Exec(fmt.Sprintf("INSERT INTO %v (%v, name, active) SELECT DISTINCT %v, '', false FROM %v WHERE %v <> '' AND %v",
+		ref.target, ref.targetColumn, ref.column, ref.table, ref.column, ref.broken()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		slog.Warnf("Added %d missing guilds of %v back as unregistered", result.RowsAffected, ref.table)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"time"
+)
+
+// Request is a single play of a track, attributed to the user who requested it.
+// UserID is empty for plays not requested by a Discord user or anonymized on user request.
+type Request struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID     string `gorm:"index"`
+	TrackID     uint   `gorm:"index"`
+	UserID      string `gorm:"index"`
+	SessionID   *uint  `gorm:"index"` // listening session the play was made in, nil if the bot's session wasn't known
+	RequestedAt time.Time
+	Skipped     bool
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Track *Track `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateRequest(request *Request) error {
+	request.RequestedAt = time.Now()
+	return DB.Create(request).Error
+}
+
+func GetRequestsByUserID(userID string) ([]Request, error) {
+	var requests []Request
+	if err := DB.Where("user_id = ?", userID).Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// AnonymizeUserRequests detaches the user from all of their requests while
+// keeping the rows so aggregate counts stay intact. It returns the number of affected rows.
+func AnonymizeUserRequests(userID string) (int64, error) {
+	result := DB.Model(&Request{}).Where("user_id = ?", userID).Update("user_id", "")
+	return result.RowsAffected, result.Error
+}
+
+// TrackRequestCount is the number of plays of a track and when it was last played in the guild.
+type TrackRequestCount struct {
+	TrackID    uint
+	Name       string
+	URL        string
+	Count      int64
+	LastPlayed time.Time
+}
+
+// UserRequestCount is the number of plays a user requested.
+type UserRequestCount struct {
+	UserID string
+	Count  int64
+}
+
+// MarkLatestRequestSkipped flags the most recent play of the track in the guild as skipped.
+func MarkLatestRequestSkipped(guildID string, trackID uint) error {
+	var request Request
+	err := DB.Where("guild_id = ? AND track_id = ?", guildID, trackID).Order("requested_at DESC").First(&request).Error
+	if err != nil {
+		return err
+	}
+	return DB.Model(&request).Update("skipped", true).Error
+}
+
+// GetTopRequestedTracks returns the most played tracks of the guild within [from, to).
+// If userID is not empty only requests of that user are counted.
+func GetTopRequestedTracks(guildID, userID string, from, to time.Time, limit int) ([]TrackRequestCount, error) {
+	return getTopTracks(guildID, userID, from, to, limit, false)
+}
+
+// GetTopSkippedTracks returns the most skipped tracks of the guild within [from, to).
+// If userID is not empty only requests of that user are counted.
+func GetTopSkippedTracks(guildID, userID string, from, to time.Time, limit int) ([]TrackRequestCount, error) {
+	return getTopTracks(guildID, userID, from, to, limit, true)
+}
+
+func getTopTracks(guildID, userID string, from, to time.Time, limit int, skippedOnly bool) ([]TrackRequestCount, error) {
+	var counts []TrackRequestCount
+
+	query := DB.Table("requests").
+		Select("requests.track_id AS track_id, tracks.name AS name, tracks.url AS url, COUNT(*) AS count, histories.last_played AS last_played").
+		Joins("JOIN tracks ON tracks.id = requests.track_id").
+		Joins("JOIN histories ON histories.track_id = requests.track_id AND histories.guild_id = requests.guild_id").
+		Where("requests.guild_id = ? AND requests.requested_at >= ? AND requests.requested_at < ?", guildID, from, to).
+		Group("requests.track_id, tracks.name, tracks.url, histories.last_played").
+		Order("count DESC")
+
+	if userID != "" {
+		query = query.Where("requests.user_id = ?", userID)
+	}
+	if skippedOnly {
+		query = query.Where("requests.skipped = ?", true)
+	}
+
+	if err := paginate(query, limit, 0).Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// CountRequests counts plays of the guild within [from, to), optionally only those requested by userID.
+func CountRequests(guildID, userID string, from, to time.Time) (int64, error) {
+	var count int64
+
+	query := DB.Model(&Request{}).Where("guild_id = ? AND requested_at >= ? AND requested_at < ?", guildID, from, to)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// GetTopRequesters returns the users who requested the most plays of the guild within [from, to),
+// plays of no known user are left out.
+func GetTopRequesters(guildID string, from, to time.Time, limit int) ([]UserRequestCount, error) {
+	var counts []UserRequestCount
+
+	query := DB.Model(&Request{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("guild_id = ? AND user_id <> '' AND requested_at >= ? AND requested_at < ?", guildID, from, to).
+		Group("user_id").
+		Order("count DESC")
+
+	if err := paginate(query, limit, 0).Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
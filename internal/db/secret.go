@@ -0,0 +1,13 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashSecret returns the stored form of a secret handed out to users: API tokens, listen links and dashboard
+// sessions are looked up by the SHA-256 of their secret, so the database never holds the secret itself.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
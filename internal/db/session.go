@@ -0,0 +1,50 @@
+package db
+
+import (
+	"time"
+)
+
+// DashboardSession is a web dashboard login of a Discord user.
+type DashboardSession struct {
+	ID           string `gorm:"primaryKey"` // SHA-256 of the session cookie, the cookie itself is never stored
+	UserID       string `gorm:"index"`
+	Username     string
+	AccessToken  string `json:"-"` // Discord OAuth2 tokens of the user
+	RefreshToken string `json:"-"`
+	TokenExpiry  time.Time
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+func CreateDashboardSession(session *DashboardSession) error {
+	session.CreatedAt = time.Now()
+	return DB.Create(session).Error
+}
+
+// GetDashboardSession returns the session with the given ID unless it expired.
+func GetDashboardSession(id string) (*DashboardSession, error) {
+	var session DashboardSession
+	if err := DB.Where("id = ? AND expires_at > ?", id, time.Now()).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func SaveDashboardSession(session *DashboardSession) error {
+	return DB.Save(session).Error
+}
+
+func DeleteDashboardSession(id string) error {
+	return DB.Where("id = ?", id).Delete(&DashboardSession{}).Error
+}
+
+// DeleteExpiredDashboardSessions removes sessions past their expiry, it returns the number of deleted rows.
+func DeleteExpiredDashboardSessions() (int64, error) {
+	result := DB.Where("expires_at <= ?", time.Now()).Delete(&DashboardSession{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteUserDashboardSessions logs the user out of the dashboard everywhere.
+func DeleteUserDashboardSessions(userID string) error {
+	return DB.Where("user_id = ?", userID).Delete(&DashboardSession{}).Error
+}
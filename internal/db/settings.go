@@ -0,0 +1,54 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// GuildSettings holds per-guild preferences.
+type GuildSettings struct {
+	GuildID               string `gorm:"primaryKey"`
+	QueueStrategy         string
+	Verbosity             string
+	Locale                string
+	Timezone              string  // IANA timezone name
+	DeleteCommands        bool    // delete command messages after processing
+	RequestChannelID      string  // channel where every message is a song request
+	PublicNowPlaying      bool    // expose the current track on the public badge endpoints
+	PublicHistory         bool    // expose recently played tracks as a public feed
+	DJRoleID              string  // role whose members may control the player from the dashboard
+	NoSuggestions         bool    // don't answer mistyped commands with the closest ones
+	Ducking               bool    // lower the music while people talk in the voice channel
+	Quality               string  // encode quality preset, empty for normal
+	EncodeBitrate         int     // kb/s, 0 uses the global config
+	EncodeFrameDuration   int     // ms, 0 uses the global config
+	VolumeCeiling         float32 // highest volume from 0.0 to 1.0, 0 for no ceiling
+	GreetingClip          string  // audio URL or jingles asset played when joining voice, empty for none
+	GreetingMessage       string  // posted in the voice channel chat when joining voice, empty for none
+	SessionSummary        bool    // post a summary in the voice channel chat when leaving voice
+	TopicChannelID        string  // text channel whose topic shows the playing track, empty for none
+	TopicOriginal         string  // topic of that channel before the bot changed it
+	TopicChanged          bool    // the topic shows a track and TopicOriginal is to be put back
+	Autoplay              bool    // keep playing related tracks once the queue is done rather than leaving
+	UpdatesChannelID      string  // channel "What's new" is posted to after an update, empty for the system channel
+	NoUpdateAnnouncements bool    // don't post "What's new" after an update
+	AnnouncedVersion      string  // version whose changes were last posted
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// GetGuildSettings returns stored settings of the guild or empty settings if none were saved yet.
+func GetGuildSettings(guildID string) (*GuildSettings, error) {
+	var settings GuildSettings
+	err := DB.Where("guild_id = ?", guildID).First(&settings).Error
+	if err == gorm.ErrRecordNotFound {
+		return &GuildSettings{GuildID: guildID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func SaveGuildSettings(settings *GuildSettings) error {
+	return DB.Save(settings).Error
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"time"
+)
+
+// TrackTag labels a track of a guild's history with a tag such as a genre, e.g. "synthwave".
+type TrackTag struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID   string `gorm:"uniqueIndex:idx_track_tag"`
+	TrackID   uint   `gorm:"uniqueIndex:idx_track_tag;index"`
+	Tag       string `gorm:"uniqueIndex:idx_track_tag"`
+	CreatedBy string
+	CreatedAt time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Track *Track `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TagCount is the number of tracks or plays of a tag.
+type TagCount struct {
+	Tag   string
+	Count int64
+}
+
+// CreateTrackTag tags the track, tagging it again with the same tag does nothing.
+func CreateTrackTag(tag *TrackTag) error {
+	return DB.Where(TrackTag{GuildID: tag.GuildID, TrackID: tag.TrackID, Tag: tag.Tag}).FirstOrCreate(tag).Error
+}
+
+// DeleteTrackTag removes a tag of the track, it returns the number of deleted rows.
+func DeleteTrackTag(guildID string, trackID uint, tag string) (int64, error) {
+	result := DB.Where("guild_id = ? AND track_id = ? AND tag = ?", guildID, trackID, tag).Delete(&TrackTag{})
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeUserTrackTags detaches the user from the tags they added in all guilds, the tags stay.
+// It returns the number of affected rows.
+func AnonymizeUserTrackTags(userID string) (int64, error) {
+	result := DB.Model(&TrackTag{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
+
+// GetTagsOfTracks returns the tags of each of the guild's tracks, sorted alphabetically.
+func GetTagsOfTracks(guildID string, trackIDs []uint) (map[uint][]string, error) {
+	var tags []TrackTag
+	if err := DB.Where("guild_id = ? AND track_id IN ?", guildID, trackIDs).Order("tag").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	tagsByTrack := make(map[uint][]string)
+	for _, tag := range tags {
+		tagsByTrack[tag.TrackID] = append(tagsByTrack[tag.TrackID], tag.Tag)
+	}
+	return tagsByTrack, nil
+}
+
+// GetTrackIDsByTag returns IDs of the guild's tracks with the tag, most recently played first.
+func GetTrackIDsByTag(guildID, tag string) ([]uint, error) {
+	var trackIDs []uint
+
+	err := DB.Table("track_tags").
+		Select("track_tags.track_id").
+		Joins("JOIN histories ON histories.track_id = track_tags.track_id AND histories.guild_id = track_tags.guild_id").
+		Where("track_tags.guild_id = ? AND track_tags.tag = ?", guildID, tag).
+		Order("histories.last_played DESC").
+		Pluck("track_tags.track_id", &trackIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return trackIDs, nil
+}
+
+// GetTagTrackCounts returns the tags of the guild with the number of tracks of each, most used first.
+func GetTagTrackCounts(guildID string) ([]TagCount, error) {
+	var counts []TagCount
+
+	err := DB.Model(&TrackTag{}).
+		Select("tag, COUNT(*) AS count").
+		Where("guild_id = ?", guildID).
+		Group("tag").
+		Order("count DESC, tag").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetTopRequestedTags returns the tags of the most played tracks of the guild within [from, to).
+// If userID is not empty only requests of that user are counted.
+func GetTopRequestedTags(guildID, userID string, from, to time.Time, limit int) ([]TagCount, error) {
+	var counts []TagCount
+
+	query := DB.Table("requests").
+		Select("track_tags.tag AS tag, COUNT(*) AS count").
+		Joins("JOIN track_tags ON track_tags.track_id = requests.track_id AND track_tags.guild_id = requests.guild_id").
+		Where("requests.guild_id = ? AND requests.requested_at >= ? AND requests.requested_at < ?", guildID, from, to).
+		Group("track_tags.tag").
+		Order("count DESC, tag")
+
+	if userID != "" {
+		query = query.Where("requests.user_id = ?", userID)
+	}
+
+	if err := paginate(query, limit, 0).Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -1,20 +1,44 @@
 package db
 
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
 type Track struct {
-	ID        uint `gorm:"primaryKey;autoIncrement"`
-	YTID      string
-	Name      string
-	URL       string
-	Histories []History `gorm:"foreignKey:TrackID"`
+	ID   uint `gorm:"primaryKey;autoIncrement"`
+	YTID string
+	Name string
+	URL  string
 }
 
 func CreateTrack(track *Track) error {
 	return DB.Create(track).Error
 }
 
-func GetTrackByID(id uint) (*Track, error) {
+// GetOrCreateTrack returns the track stored under the YouTube ID of the given one, creating it if there is none.
+// Concurrent writers may create the same track, the insert is skipped on conflict and the stored track read back.
+func GetOrCreateTrack(track *Track) (*Track, error) {
+	existing, err := GetTrackByYTID(track.YTID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(track).Error; err != nil {
+		return nil, err
+	}
+	return GetTrackByYTID(track.YTID)
+}
+
+func GetTrackByID(ctx context.Context, id uint) (*Track, error) {
 	var track Track
-	if err := DB.First(&track, id).Error; err != nil {
+	if err := DB.WithContext(ctx).First(&track, id).Error; err != nil {
 		return nil, err
 	}
 	return &track, nil
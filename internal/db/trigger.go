@@ -0,0 +1,63 @@
+package db
+
+import (
+	"time"
+)
+
+// Kinds of macro triggers.
+const (
+	TriggerDaily = "daily" // runs every day at a time of the guild's timezone
+	TriggerVoice = "voice" // runs when a voice channel first reaches a number of members
+)
+
+// MacroTrigger runs a macro of a guild at a time or on a voice channel event.
+type MacroTrigger struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	GuildID        string `gorm:"index"`
+	Macro          string
+	Kind           string
+	At             string // HH:MM of daily triggers
+	Members        int    // members voice triggers wait for
+	VoiceChannelID string // channel voice triggers watch, or the daily trigger joins before running
+	ChannelID      string // text channel replies of the macro go to
+	CreatedBy      string // member the macro runs as
+	CreatedAt      time.Time
+	LastRunAt      time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateMacroTrigger(trigger *MacroTrigger) error {
+	trigger.CreatedAt = time.Now()
+	return DB.Create(trigger).Error
+}
+
+func GetMacroTriggersByGuildID(guildID string) ([]MacroTrigger, error) {
+	var triggers []MacroTrigger
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&triggers).Error; err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+// SetMacroTriggerLastRun records when the trigger ran last.
+func SetMacroTriggerLastRun(id uint, at time.Time) error {
+	return DB.Model(&MacroTrigger{}).Where("id = ?", id).Update("last_run_at", at).Error
+}
+
+// DeleteMacroTrigger removes a trigger of the guild, it returns the number of deleted rows.
+func DeleteMacroTrigger(guildID string, id uint) (int64, error) {
+	result := DB.Where("guild_id = ? AND id = ?", guildID, id).Delete(&MacroTrigger{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteMacroTriggersByMacro removes the triggers of a macro of the guild.
+func DeleteMacroTriggersByMacro(guildID, macro string) error {
+	return DB.Where("guild_id = ? AND macro = ?", guildID, macro).Delete(&MacroTrigger{}).Error
+}
+
+// AnonymizeUserMacroTriggers detaches the user from the macro triggers they created in all guilds, it returns the number of affected rows.
+func AnonymizeUserMacroTriggers(userID string) (int64, error) {
+	result := DB.Model(&MacroTrigger{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
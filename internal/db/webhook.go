@@ -0,0 +1,52 @@
+package db
+
+import (
+	"time"
+)
+
+// Webhook binds an inbound webhook token to a guild and describes how to read a song request from its payload.
+type Webhook struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	Token         string `gorm:"uniqueIndex"`
+	GuildID       string `gorm:"index"`
+	Secret        string `json:"-"` // HMAC-SHA256 key of payload signatures
+	QueryPath     string // dot separated JSON path of the song title, URL or history ID
+	RequesterPath string // optional dot separated JSON path of the requester name, for logging
+	CreatedBy     string
+	CreatedAt     time.Time
+
+	Guild *Guild `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func CreateWebhook(webhook *Webhook) error {
+	webhook.CreatedAt = time.Now()
+	return DB.Create(webhook).Error
+}
+
+func GetWebhookByToken(token string) (*Webhook, error) {
+	var webhook Webhook
+	if err := DB.Where("token = ?", token).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func GetWebhooksByGuildID(guildID string) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := DB.Where("guild_id = ?", guildID).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the guild's webhook with the given token, it returns the number of deleted rows.
+func DeleteWebhook(guildID, token string) (int64, error) {
+	result := DB.Where("guild_id = ? AND token = ?", guildID, token).Delete(&Webhook{})
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeUserWebhooks detaches the user from the webhooks they created in all guilds, it returns the number of affected rows.
+func AnonymizeUserWebhooks(userID string) (int64, error) {
+	result := DB.Model(&Webhook{}).Where("created_by = ?", userID).Update("created_by", "")
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/discord"
+)
+
+// handleGuildCommand runs a command sent by DM on behalf of the bot owner in the given guild.
+// Usage: !guild <guild id> <command> [parameter], or !guild alone to list guilds.
+// The register and unregister commands change the activation of the guild.
+func (gm *GuildManager) handleGuildCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	if gm.ownerID == "" || m.Author.ID != gm.ownerID {
+		return
+	}
+
+	// With several instances, the one running the guild answers, otherwise the one holding the direct messages
+	words := strings.Fields(param)
+	if len(words) == 0 {
+		if !gm.ownedElsewhere(directMessagesLease) {
+			discord.SendMessage(gm.Session, m.ChannelID, gm.listGuilds(s))
+		}
+		return
+	}
+
+	if len(words) == 2 {
+		switch strings.ToLower(words[1]) {
+		case "register":
+			gm.handleDirectRegistration(s, m, words[0], true)
+			return
+		case "unregister":
+			gm.handleDirectRegistration(s, m, words[0], false)
+			return
+		}
+	}
+
+	instance, ok := gm.BotInstances.Get(words[0])
+	if !ok {
+		if gm.ownedElsewhere(db.GuildLease(words[0])) || gm.ownedElsewhere(directMessagesLease) {
+			return
+		}
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Guild %v is not registered", words[0]))
+		return
+	}
+
+	if len(words) < 2 {
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Usage: `%vguild %v <command> [parameter]`", gm.prefix, words[0]))
+		return
+	}
+
+	command := strings.ToLower(words[1])
+	parameter := strings.Join(words[2:], " ")
+
+	if !instance.Melodix.RunDirectCommand(s, m, command, parameter) {
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Unknown command `%v`", command))
+	}
+}
+
+// handleDirectRegistration registers or unregisters a guild on behalf of the owner.
+func (gm *GuildManager) handleDirectRegistration(s *discordgo.Session, m *discordgo.MessageCreate, guildID string, register bool) {
+	if register {
+		if _, err := s.State.Guild(guildID); err != nil {
+			if gm.ownedElsewhere(directMessagesLease) {
+				return
+			}
+			discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("The bot is not a member of guild %v", guildID))
+			return
+		}
+	}
+
+	var err error
+	if register {
+		err = gm.registerGuild(s, guildID)
+	} else {
+		err = gm.unregisterGuild(guildID)
+	}
+
+	switch {
+	case errors.Is(err, errOwnedElsewhere):
+		return
+	case errors.Is(err, errAlreadyRegistered), errors.Is(err, errNotRegistered):
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Guild %v: %v", guildID, err))
+	case err != nil:
+		slog.Errorf("Error changing registration of guild %v: %v", guildID, err)
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Failed to change the registration of guild %v", guildID))
+	case register:
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Guild %v registered", guildID))
+	default:
+		discord.SendMessage(gm.Session, m.ChannelID, fmt.Sprintf("Guild %v unregistered", guildID))
+	}
+}
+
+// listGuilds describes known guilds and whether they are registered for the owner.
+func (gm *GuildManager) listGuilds(s *discordgo.Session) string {
+	guilds, err := db.GetAllGuilds()
+	if err != nil {
+		slog.Errorf("Error getting guilds: %v", err)
+		return "Failed to get guilds"
+	}
+
+	leases, err := db.GetLeases()
+	if err != nil {
+		slog.Warnf("Error getting leases: %v", err)
+	}
+	holders := make(map[string]string)
+	for _, lease := range leases {
+		if time.Now().Before(lease.ExpiresAt) {
+			holders[lease.Name] = lease.InstanceID
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Guilds, use `%vguild <id> <command>` to control one and `%vguild <id> register/unregister` to change its registration:\n", gm.prefix, gm.prefix))
+	for _, guild := range guilds {
+		name := guild.Name
+		if g, err := s.State.Guild(guild.ID); err == nil {
+			name = g.Name
+		}
+		if name == "" {
+			name = "unknown"
+		}
+
+		status := "registered"
+		if !guild.IsActive() {
+			status = "unregistered"
+		}
+		if holder, ok := holders[db.GuildLease(guild.ID)]; ok {
+			status += ", run by " + holder
+		}
+		builder.WriteString(fmt.Sprintf("`%v` %v (%v)\n", guild.ID, name, status))
+	}
+
+	return builder.String()
+}
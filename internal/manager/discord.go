@@ -1,39 +1,64 @@
 package manager
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 
+	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gookit/slog"
 
 	"github.com/keshon/melodix-discord-player/internal/config"
 	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
 	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/history"
 	"github.com/keshon/melodix-discord-player/music/player"
 )
 
 var (
 	prefix = os.Getenv("COMMAND_PREFIX")
+
+	errAlreadyRegistered = errors.New("guild is already registered")
+	errNotRegistered     = errors.New("guild is not registered")
 )
 
 // GuildManager manages guild-related functionality.
 type GuildManager struct {
+	sync.Mutex   // Guards starting and removing bot instances
 	Session      *discordgo.Session
-	BotInstances map[string]*discord.BotInstance
+	BotInstances *discord.BotInstances
 	prefix       string
+	ownerID      string
+	instanceID   string // holder name of the leases of this process
+	updateCheck  bool   // tell the owner about newer releases on GitHub
+	updater      *discord.Updater
+	ctx          context.Context // ends on Stop, background work of the manager runs under it
+	cancel       context.CancelFunc
 }
 
-// NewGuildManager creates a new instance of GuildManager.
-func NewGuildManager(session *discordgo.Session, botInstances map[string]*discord.BotInstance) *GuildManager {
+// NewGuildManager creates a new instance of GuildManager, its instances install updates with the updater.
+func NewGuildManager(session *discordgo.Session, botInstances *discord.BotInstances, updater *discord.Updater) *GuildManager {
 	config, err := config.NewConfig()
 	if err != nil {
 		slog.Fatalf("Error loading config:", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &GuildManager{
 		Session:      session,
 		BotInstances: botInstances,
 		prefix:       config.DiscordCommandPrefix,
+		ownerID:      config.DiscordOwnerID,
+		instanceID:   config.InstanceID,
+		updateCheck:  config.UpdateCheck,
+		updater:      updater,
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -41,16 +66,103 @@ func NewGuildManager(session *discordgo.Session, botInstances map[string]*discor
 func (gm *GuildManager) Start() {
 	slog.Info("Guild manager started")
 	gm.Session.AddHandler(gm.Commands)
+	gm.Session.AddHandler(gm.onSlashCommand)
+	gm.Session.AddHandler(gm.onGuildCreate)
+	gm.Session.AddHandler(gm.onReady)
+	history.SetAvailabilityHandler(gm.notifyDatabaseAvailability)
+
+	// Processes sharing the database split the guilds between them through leases
+	if _, err := db.AcquireLease(directMessagesLease, gm.instanceID, leaseTTL); err != nil {
+		slog.Warnf("Error acquiring lease of direct messages: %v", err)
+	}
+	go gm.maintainLeases()
+
+	if gm.updateCheck {
+		go gm.checkUpdates()
+	}
+}
+
+// notifyDatabaseAvailability tells the bot owner by DM when the database became unavailable or recovered.
+func (gm *GuildManager) notifyDatabaseAvailability(available bool, buffered int) {
+	if gm.ownerID == "" {
+		return
+	}
+
+	text := "📴 The database is unavailable. Playback and queues keep working, history writes are buffered and history commands are paused until it recovers."
+	if available {
+		text = fmt.Sprintf("✅ The database recovered, %d buffered history writes were replayed.", buffered)
+	}
+
+	dm, err := gm.Session.UserChannelCreate(gm.ownerID)
+	if err != nil {
+		slog.Warnf("Error opening DM to the bot owner: %v", err)
+		return
+	}
+	gm.sendEmbed(dm.ID, text)
+}
+
+// onGuildCreate starts an instance for a guild the bot is in, registering the guild when it's seen for the first time.
+// Discord sends it for every guild once the session is ready and when the bot joins a guild.
+// Guilds that were unregistered stay inactive until they register again.
+func (gm *GuildManager) onGuildCreate(s *discordgo.Session, e *discordgo.GuildCreate) {
+	if e.Unavailable {
+		return
+	}
+
+	gm.Lock()
+	defer gm.Unlock()
+
+	if _, running := gm.BotInstances.Get(e.ID); running {
+		return
+	}
+
+	guild, err := db.GetGuildByID(e.ID)
+	if err != nil {
+		slog.Errorf("Error checking if guild is registered: %v", err)
+		return
+	}
+
+	switch {
+	case guild == nil:
+		if err := db.CreateGuild(db.Guild{ID: e.ID, Name: e.Name}); err != nil {
+			slog.Errorf("Error registering guild %v: %v", e.ID, err)
+			return
+		}
+		slog.Infof("Registered guild %v (%v)", e.Name, e.ID)
+	case !guild.IsActive():
+		slog.Infof("Skipping unregistered guild %v (%v)", e.Name, e.ID)
+		return
+	}
+
+	owned, err := gm.acquireGuild(e.ID)
+	if err != nil {
+		slog.Errorf("Error starting guild %v (%v): %v", e.Name, e.ID, err)
+		return
+	}
+	if !owned {
+		slog.Infof("Guild %v (%v) is run by another instance", e.Name, e.ID)
+		return
+	}
+
+	gm.setupBotInstance(s, e.ID)
 }
 
 // Commands handles incoming Discord commands.
 func (gm *GuildManager) Commands(s *discordgo.Session, m *discordgo.MessageCreate) {
-	command, _, err := parseCommand(m.Message.Content, gm.prefix)
+	command, param, err := parseCommand(m.Message.Content, gm.prefix)
 	if err != nil {
 		// slog.Info(err)
 		return
 	}
 
+	// Direct messages carry no guild, the owner addresses one explicitly
+	if m.GuildID == "" {
+		if command == "guild" {
+			gm.handleGuildCommand(s, m, param)
+		}
+		return
+	}
+
 	switch command {
 	case "register":
 		gm.handleRegisterCommand(s, m)
@@ -61,77 +173,187 @@ func (gm *GuildManager) Commands(s *discordgo.Session, m *discordgo.MessageCreat
 	}
 }
 
-// handleRegisterCommand handles the registration of a guild.
-func (gm *GuildManager) handleRegisterCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
-	channelID := m.Message.ChannelID
-	guildID := m.GuildID
+// onReady registers the slash commands once the session is open, they are global to the application.
+func (gm *GuildManager) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	if err := discord.RegisterSlashCommands(s); err != nil {
+		slog.Errorf("Error registering slash commands: %v", err)
+	}
+}
 
-	exists, err := db.DoesGuildExist(guildID)
-	if err != nil {
-		slog.Errorf("Error checking if guild is registered: %v", err)
+// onSlashCommand handles the slash commands of the guild manager, the guild instances handle the others.
+func (gm *GuildManager) onSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.GuildID == "" {
 		return
 	}
 
-	if exists {
-		gm.Session.ChannelMessageSend(channelID, "Guild is already registered")
+	name := i.ApplicationCommandData().Name
+	if name != "register" && name != "unregister" {
 		return
 	}
 
-	guild := db.Guild{ID: guildID, Name: ""}
-	err = db.CreateGuild(guild)
-	if err != nil {
-		slog.Errorf("Error registering guild: %v", err)
-		gm.Session.ChannelMessageSend(channelID, "Error registering guild")
+	discord.RunSlashCommand(s, i, gm.prefix, func(m *discordgo.MessageCreate, command, param string) {
+		if command == "register" {
+			gm.handleRegisterCommand(s, m)
+		} else {
+			gm.handleUnregisterCommand(s, m)
+		}
+	})
+}
+
+// isAdmin reports whether the message author can administrate the guild, the bot owner always can.
+func (gm *GuildManager) isAdmin(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	return (gm.ownerID != "" && m.Author.ID == gm.ownerID) || discord.HasManagePermission(s, m)
+}
+
+// handleRegisterCommand handles the registration of a guild.
+func (gm *GuildManager) handleRegisterCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !gm.isAdmin(s, m) {
+		gm.sendEmbed(m.ChannelID, "Only server administrators can register the bot")
 		return
 	}
 
-	gm.setupBotInstance(gm.BotInstances, s, guildID)
-	gm.Session.ChannelMessageSend(channelID, "Guild registered successfully")
+	err := gm.registerGuild(s, m.GuildID)
+	switch {
+	case errors.Is(err, errOwnedElsewhere):
+		return
+	case errors.Is(err, errAlreadyRegistered):
+		gm.sendEmbed(m.ChannelID, "This server is already registered")
+	case err != nil:
+		slog.Errorf("Error registering guild %v: %v", m.GuildID, err)
+		gm.sendEmbed(m.ChannelID, "Failed to register this server")
+	default:
+		gm.sendEmbed(m.ChannelID, fmt.Sprintf("✅ Server registered, %v listens to commands here again\nUse `%vunregister` to disable it", version.AppName, gm.prefix))
+	}
 }
 
 // handleUnregisterCommand handles the unregistration of a guild.
 func (gm *GuildManager) handleUnregisterCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
-	channelID := m.Message.ChannelID
-	guildID := m.GuildID
-
-	exists, err := db.DoesGuildExist(guildID)
-	if err != nil {
-		slog.Errorf("Error checking if guild is registered: %v", err)
+	if !gm.isAdmin(s, m) {
+		gm.sendEmbed(m.ChannelID, "Only server administrators can unregister the bot")
 		return
 	}
 
-	if !exists {
-		gm.Session.ChannelMessageSend(channelID, "Guild is not registered")
+	err := gm.unregisterGuild(m.GuildID)
+	switch {
+	case errors.Is(err, errOwnedElsewhere):
 		return
+	case errors.Is(err, errNotRegistered):
+		gm.sendEmbed(m.ChannelID, "This server is not registered")
+	case err != nil:
+		slog.Errorf("Error unregistering guild %v: %v", m.GuildID, err)
+		gm.sendEmbed(m.ChannelID, "Failed to unregister this server")
+	default:
+		gm.sendEmbed(m.ChannelID, fmt.Sprintf("🔇 Server unregistered, %v ignores commands here until `%vregister`", version.AppName, gm.prefix))
+	}
+}
+
+// registerGuild marks a guild active and starts its instance.
+func (gm *GuildManager) registerGuild(s *discordgo.Session, guildID string) error {
+	gm.Lock()
+	defer gm.Unlock()
+
+	guild, err := db.GetGuildByID(guildID)
+	if err != nil {
+		return err
 	}
 
-	err = db.DeleteGuild(guildID)
+	_, running := gm.BotInstances.Get(guildID)
+	if !running {
+		owned, err := gm.acquireGuild(guildID)
+		if err != nil {
+			return err
+		}
+		if !owned {
+			return errOwnedElsewhere
+		}
+	}
+
+	switch {
+	case guild == nil:
+		name := ""
+		if g, err := s.State.Guild(guildID); err == nil {
+			name = g.Name
+		}
+		if err := db.CreateGuild(db.Guild{ID: guildID, Name: name}); err != nil {
+			return err
+		}
+	case guild.IsActive() && running:
+		return errAlreadyRegistered
+	case !guild.IsActive():
+		if err := db.SetGuildActive(guildID, true); err != nil {
+			return err
+		}
+	}
+
+	if !running {
+		gm.setupBotInstance(s, guildID)
+	}
+	return nil
+}
+
+// unregisterGuild marks a guild inactive and stops its instance.
+func (gm *GuildManager) unregisterGuild(guildID string) error {
+	gm.Lock()
+	defer gm.Unlock()
+
+	guild, err := db.GetGuildByID(guildID)
 	if err != nil {
-		slog.Errorf("Error unregistering guild: %v", err)
-		gm.Session.ChannelMessageSend(channelID, "Error unregistering guild")
-		return
+		return err
+	}
+	if _, running := gm.BotInstances.Get(guildID); !running {
+		owned, err := gm.acquireGuild(guildID)
+		if err != nil {
+			return err
+		}
+		if !owned {
+			return errOwnedElsewhere
+		}
+	}
+	if guild == nil || !guild.IsActive() {
+		return errNotRegistered
+	}
+
+	if err := db.SetGuildActive(guildID, false); err != nil {
+		return err
 	}
 
 	gm.removeBotInstance(guildID)
-	gm.Session.ChannelMessageSend(channelID, "Guild unregistered successfully")
+	if err := db.ReleaseLease(db.GuildLease(guildID), gm.instanceID); err != nil {
+		slog.Warnf("Error releasing lease of guild %v: %v", guildID, err)
+	}
+	return nil
+}
+
+// sendEmbed sends a short plain embed to a channel.
+func (gm *GuildManager) sendEmbed(channelID, text string) {
+	embedMsg := embed.NewEmbed().
+		SetDescription(text).
+		SetColor(0x9f00d4).
+		SetFooter(version.AppFullName).MessageEmbed
+
+	if _, err := discord.SendEmbed(gm.Session, channelID, embedMsg); err != nil {
+		slog.Warnf("Error sending message: %v", err)
+	}
 }
 
 // setupBotInstance sets up a new BotInstance for a guild.
-func (gm *GuildManager) setupBotInstance(botInstances map[string]*discord.BotInstance, session *discordgo.Session, guildID string) {
-	botInstances[guildID] = &discord.BotInstance{
-		Melodix: discord.NewDiscord(session, guildID),
+func (gm *GuildManager) setupBotInstance(session *discordgo.Session, guildID string) {
+	instance := &discord.BotInstance{
+		Melodix: discord.NewDiscord(session, guildID, gm.updater),
 	}
-	botInstances[guildID].Melodix.Start(guildID)
+	gm.BotInstances.Set(guildID, instance)
+	instance.Melodix.Start(guildID)
 }
 
 // removeBotInstance removes a BotInstance for a guild.
 func (gm *GuildManager) removeBotInstance(guildID string) {
-	instance, ok := gm.BotInstances[guildID]
+	instance, ok := gm.BotInstances.Get(guildID)
 	if !ok {
 		return // Guild instance not found, nothing to do
 	}
 
 	instance.Melodix.InstanceActive = false
+	instance.Melodix.Shutdown()
 
 	if instance.Melodix.Player != nil && instance.Melodix.Player.GetVoiceConnection() != nil {
 		instance.Melodix.Player.GetVoiceConnection().Disconnect()
@@ -139,5 +361,5 @@ func (gm *GuildManager) removeBotInstance(guildID string) {
 	}
 
 	instance.Melodix.Player.SetCurrentStatus(player.StatusResting)
-	delete(gm.BotInstances, guildID)
+	gm.BotInstances.Delete(guildID)
 }
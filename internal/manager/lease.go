@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	// leaseTTL is how long a guild stays with an instance that stopped renewing, e.g. because it crashed
+	leaseTTL = 30 * time.Second
+	// leaseRenewInterval is how often leases are renewed and guilds of stopped instances taken over
+	leaseRenewInterval = 10 * time.Second
+	// directMessagesLease makes one instance answer the owner's direct messages that address no guild it runs
+	directMessagesLease = "direct-messages"
+)
+
+// errOwnedElsewhere means another instance runs the guild, it answers instead.
+var errOwnedElsewhere = errors.New("guild is run by another instance")
+
+// acquireGuild takes the lease of a guild not running here, false if another instance runs it. When the database
+// can't be reached it isn't known who runs the guild, so it isn't started until leases
+// are renewed.
+func (gm *GuildManager) acquireGuild(guildID string) (bool, error) {
+	owned, err := db.AcquireLease(db.GuildLease(guildID), gm.instanceID, leaseTTL)
+	if err != nil {
+		return false, fmt.Errorf("acquiring lease of guild %v: %w", guildID, err)
+	}
+	return owned, nil
+}
+
+// renewGuild renews the lease of a guild running here, false if another instance took it over. When the database
+// can't be reached the guild stays owned, so players keep running through outages.
+func (gm *GuildManager) renewGuild(guildID string) bool {
+	owned, err := db.AcquireLease(db.GuildLease(guildID), gm.instanceID, leaseTTL)
+	if err != nil {
+		slog.Warnf("Error renewing lease of guild %v: %v", guildID, err)
+		return true
+	}
+	return owned
+}
+
+// ownedElsewhere reports whether another instance holds the lease of a resource.
+func (gm *GuildManager) ownedElsewhere(name string) bool {
+	holder, err := db.GetLeaseHolder(name)
+	if err != nil {
+		slog.Warnf("Error getting lease holder of %v: %v", name, err)
+		return false
+	}
+	return holder != "" && holder != gm.instanceID
+}
+
+// maintainLeases renews the leases of the running guilds and takes over guilds of instances that stopped,
+// until the manager stops and released its leases.
+func (gm *GuildManager) maintainLeases() {
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gm.ctx.Done():
+			return
+		case <-ticker.C:
+			gm.renewLeases()
+		}
+	}
+}
+
+// renewLeases stops the guilds another instance took over and starts registered guilds nobody runs.
+func (gm *GuildManager) renewLeases() {
+	gm.Lock()
+	defer gm.Unlock()
+
+	// Stop may have released the leases while waiting for the lock
+	if gm.ctx.Err() != nil {
+		return
+	}
+
+	for _, guildID := range gm.BotInstances.GuildIDs() {
+		if !gm.renewGuild(guildID) {
+			slog.Warnf("Guild %v was taken over by another instance, stopping its player", guildID)
+			gm.removeBotInstance(guildID)
+		}
+	}
+
+	if _, err := db.AcquireLease(directMessagesLease, gm.instanceID, leaseTTL); err != nil {
+		slog.Warnf("Error acquiring lease of direct messages: %v", err)
+	}
+
+	guilds, err := db.GetAllGuilds()
+	if err != nil {
+		slog.Warnf("Error getting guilds to take over: %v", err)
+		return
+	}
+	for _, guild := range guilds {
+		if _, running := gm.BotInstances.Get(guild.ID); running || !guild.IsActive() {
+			continue
+		}
+		if g, err := gm.Session.State.Guild(guild.ID); err != nil || g.Unavailable {
+			continue
+		}
+
+		if owned, err := gm.acquireGuild(guild.ID); err != nil || !owned {
+			continue
+		}
+		slog.Infof("Taking over guild %v (%v)", guild.Name, guild.ID)
+		gm.setupBotInstance(gm.Session, guild.ID)
+	}
+}
+
+// Stop ends playback and lookups of the guilds and gives up the leases of the instance on shutdown,
+// so other instances take its guilds over right away.
+func (gm *GuildManager) Stop() {
+	gm.cancel()
+
+	gm.Lock()
+	defer gm.Unlock()
+
+	for _, instance := range gm.BotInstances.All() {
+		instance.Melodix.Shutdown()
+	}
+
+	if err := db.ReleaseLeases(gm.instanceID); err != nil {
+		slog.Warnf("Error releasing leases: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/version"
+)
+
+const (
+	// updateCheckDelay lets the session connect before the first update check
+	updateCheckDelay = time.Minute
+	// updateCheckInterval is how often GitHub is asked for a newer release
+	updateCheckInterval = 12 * time.Hour
+	// updateCheckTimeout bounds one update check
+	updateCheckTimeout = 30 * time.Second
+)
+
+// checkUpdates compares the running version with the latest GitHub release now and then and tells the
+// bot owner by DM, or the log without an owner, once per newer release. It returns once the manager stops.
+func (gm *GuildManager) checkUpdates() {
+	delay := updateCheckDelay
+
+	var notified string
+	for {
+		select {
+		case <-gm.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = updateCheckInterval
+
+		ctx, cancel := context.WithTimeout(gm.ctx, updateCheckTimeout)
+		latest, err := version.CheckLatestRelease(ctx)
+		cancel()
+
+		switch {
+		case gm.ctx.Err() != nil:
+			return
+		case err != nil:
+			slog.Warnf("Error checking for updates: %v", err)
+		case latest.UpdateAvailable() && latest.Version != notified:
+			notified = latest.Version
+			gm.notifyUpdate(latest)
+		}
+	}
+}
+
+// notifyUpdate tells the bot owner about a newer release, the instance holding the direct messages does.
+func (gm *GuildManager) notifyUpdate(latest version.LatestRelease) {
+	text := fmt.Sprintf("⬆️ %v %v is available, this instance runs %v\n%v", version.AppName, latest.Version, version.Current(), latest.URL)
+	slog.Infof("Update available: %v %v, running %v", version.AppName, latest.Version, version.Current())
+
+	if gm.ownerID == "" || gm.ownedElsewhere(directMessagesLease) {
+		return
+	}
+
+	dm, err := gm.Session.UserChannelCreate(gm.ownerID)
+	if err != nil {
+		slog.Warnf("Error opening DM to the bot owner: %v", err)
+		return
+	}
+	gm.sendEmbed(dm.ID, text)
+}
@@ -0,0 +1,345 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// command is a MPD command handler.
+type command struct {
+	needsGuild bool
+	run        func(ss *session, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"ping":          {false, func(ss *session, args []string) error { return nil }},
+		"close":         {false, func(ss *session, args []string) error { return errClose }},
+		"password":      {false, cmdPassword},
+		"commands":      {false, cmdCommands},
+		"notcommands":   {false, func(ss *session, args []string) error { return nil }},
+		"tagtypes":      {false, cmdTagTypes},
+		"urlhandlers":   {false, cmdURLHandlers},
+		"outputs":       {false, cmdOutputs},
+		"decoders":      {false, func(ss *session, args []string) error { return nil }},
+		"listplaylists": {false, func(ss *session, args []string) error { return nil }},
+		"lsinfo":        {false, func(ss *session, args []string) error { return nil }},
+		"status":        {true, cmdStatus},
+		"stats":         {true, cmdStats},
+		"currentsong":   {true, cmdCurrentSong},
+		"playlistinfo":  {true, cmdPlaylistInfo},
+		"playlistid":    {true, cmdPlaylistInfo},
+		"plchanges":     {true, cmdPlaylistInfo},
+		"idle":          {true, func(ss *session, args []string) error { return ss.idle() }},
+		"play":          {true, cmdPlay},
+		"playid":        {true, cmdPlay},
+		"pause":         {true, cmdPause},
+		"stop":          {true, cmdStop},
+		"next":          {true, cmdNext},
+		"add":           {true, cmdAdd},
+		"addid":         {true, cmdAddID},
+		"clear":         {true, cmdClear},
+		"shuffle":       {true, cmdShuffle},
+	}
+}
+
+func cmdPassword(ss *session, args []string) error {
+	if len(args) != 1 {
+		return &ackError{ackErrorArg, "wrong number of arguments"}
+	}
+
+	guild, ok := ss.mpd.authenticate(args[0])
+	if !ok {
+		return &ackError{ackErrorPassword, "incorrect password"}
+	}
+
+	ss.guild = guild
+	return nil
+}
+
+func cmdCommands(ss *session, args []string) error {
+	names := make([]string, 0, len(commands))
+	for name, cmd := range commands {
+		if cmd.needsGuild && ss.guild == nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ss.writePair("command", name)
+	}
+	return nil
+}
+
+func cmdTagTypes(ss *session, args []string) error {
+	ss.writePair("tagtype", "Title")
+	return nil
+}
+
+func cmdURLHandlers(ss *session, args []string) error {
+	ss.writePair("handler", "http://")
+	ss.writePair("handler", "https://")
+	return nil
+}
+
+func cmdOutputs(ss *session, args []string) error {
+	ss.writePair("outputid", 0)
+	ss.writePair("outputname", "Discord voice")
+	ss.writePair("outputenabled", 1)
+	return nil
+}
+
+func cmdStatus(ss *session, args []string) error {
+	p := ss.guild.Player
+	songs := playlistOf(p)
+
+	// Repeating the track is MPD's repeat with single, repeating the queue its plain repeat
+	repeat, single := 0, 0
+	switch p.GetRepeatMode() {
+	case player.RepeatTrack:
+		repeat, single = 1, 1
+	case player.RepeatQueue:
+		repeat = 1
+	}
+
+	ss.writePair("volume", 100)
+	ss.writePair("repeat", repeat)
+	ss.writePair("random", 0)
+	ss.writePair("single", single)
+	ss.writePair("consume", 1) // played songs leave the queue
+	ss.writePair("playlist", snapshotOf(ss.guild).version())
+	ss.writePair("playlistlength", len(songs))
+	ss.writePair("state", stateOf(p))
+
+	if current := p.GetCurrentSong(); current != nil {
+		ss.writePair("song", 0)
+		ss.writePair("songid", 1)
+
+		elapsed := p.GetPlaybackPosition().Seconds()
+		ss.writePair("time", fmt.Sprintf("%d:%d", int(elapsed), int(current.Duration.Seconds())))
+		ss.writePair("elapsed", fmt.Sprintf("%.3f", elapsed))
+		ss.writePair("duration", fmt.Sprintf("%.3f", current.Duration.Seconds()))
+	}
+
+	if len(songs) > 1 || (len(songs) == 1 && p.GetCurrentSong() == nil) {
+		next := 0
+		if p.GetCurrentSong() != nil {
+			next = 1
+		}
+		ss.writePair("nextsong", next)
+		ss.writePair("nextsongid", next+1)
+	}
+
+	return nil
+}
+
+func cmdStats(ss *session, args []string) error {
+	ss.writePair("uptime", int(time.Since(ss.mpd.startedAt).Seconds()))
+	ss.writePair("playtime", 0)
+	ss.writePair("artists", 0)
+	ss.writePair("albums", 0)
+	ss.writePair("songs", len(playlistOf(ss.guild.Player)))
+	return nil
+}
+
+func cmdCurrentSong(ss *session, args []string) error {
+	if current := ss.guild.Player.GetCurrentSong(); current != nil {
+		writeSong(ss, current, 0)
+	}
+	return nil
+}
+
+func cmdPlaylistInfo(ss *session, args []string) error {
+	for pos, song := range playlistOf(ss.guild.Player) {
+		writeSong(ss, song, pos)
+	}
+	return nil
+}
+
+func cmdPlay(ss *session, args []string) error {
+	p := ss.guild.Player
+
+	switch p.GetCurrentStatus() {
+	case player.StatusPaused:
+		p.Unpause()
+	case player.StatusPlaying:
+	default:
+		if p.GetVoiceConnection() == nil {
+			return &ackError{ackErrorSystem, "bot is not in a voice channel"}
+		}
+		if len(p.GetSongQueue()) == 0 && p.GetCurrentSong() == nil {
+			return &ackError{ackErrorNoExist, "queue is empty"}
+		}
+		go p.Play(ss.guild.Context(), 0, nil)
+	}
+
+	return nil
+}
+
+func cmdPause(ss *session, args []string) error {
+	p := ss.guild.Player
+
+	pause := p.GetCurrentStatus() == player.StatusPlaying // no argument toggles
+	if len(args) == 1 {
+		pause = args[0] == "1"
+	}
+
+	if pause && p.GetCurrentStatus() == player.StatusPlaying {
+		p.Pause()
+	} else if !pause && p.GetCurrentStatus() == player.StatusPaused {
+		p.Unpause()
+	}
+
+	return nil
+}
+
+// cmdStop pauses playback, stopping the player would disconnect it from voice and drop the queue.
+func cmdStop(ss *session, args []string) error {
+	if ss.guild.Player.GetCurrentStatus() == player.StatusPlaying {
+		ss.guild.Player.Pause()
+	}
+	return nil
+}
+
+func cmdNext(ss *session, args []string) error {
+	ss.guild.Player.Skip()
+	return nil
+}
+
+func cmdAdd(ss *session, args []string) error {
+	_, err := addSongs(ss, args)
+	return err
+}
+
+// cmdAddID adds like add and reports the id of the first added song.
+func cmdAddID(ss *session, args []string) error {
+	songs, err := addSongs(ss, args)
+	if err != nil {
+		return err
+	}
+
+	ss.writePair("Id", len(playlistOf(ss.guild.Player))-len(songs)+1)
+	return nil
+}
+
+// addSongs enqueues the songs found for the query or URL of the arguments.
+func addSongs(ss *session, args []string) ([]*player.Song, error) {
+	if len(args) < 1 {
+		return nil, &ackError{ackErrorArg, "wrong number of arguments"}
+	}
+
+	songs, err := ss.guild.EnqueueQuery(args[0])
+	switch {
+	case errors.Is(err, discord.ErrNoSongs):
+		return nil, &ackError{ackErrorNoExist, "no music found"}
+	case errors.Is(err, discord.ErrNotInVoice):
+		return nil, &ackError{ackErrorSystem, "bot is not in a voice channel"}
+	case err != nil:
+		return nil, err
+	}
+	return songs, nil
+}
+
+func cmdClear(ss *session, args []string) error {
+	ss.guild.Player.ClearQueue()
+	return nil
+}
+
+func cmdShuffle(ss *session, args []string) error {
+	return ss.guild.Player.ShuffleQueue()
+}
+
+// writeSong writes song tags, ids are positions starting from 1 since the queue has no stable ids.
+func writeSong(ss *session, song *player.Song, pos int) {
+	ss.writePair("file", song.UserURL)
+	ss.writePair("Title", song.Title)
+	if song.Duration > 0 {
+		ss.writePair("Time", int(song.Duration.Seconds()))
+		ss.writePair("duration", fmt.Sprintf("%.3f", song.Duration.Seconds()))
+	}
+	ss.writePair("Pos", pos)
+	ss.writePair("Id", pos+1)
+}
+
+// playlistOf returns the current song followed by the queue.
+func playlistOf(p player.IPlayer) []*player.Song {
+	var songs []*player.Song
+	if current := p.GetCurrentSong(); current != nil {
+		songs = append(songs, current)
+	}
+	return append(songs, p.GetSongQueue()...)
+}
+
+func stateOf(p player.IPlayer) string {
+	switch p.GetCurrentStatus() {
+	case player.StatusPlaying:
+		return "play"
+	case player.StatusPaused:
+		return "pause"
+	default:
+		return "stop"
+	}
+}
+
+// snapshot captures what idle clients are notified about.
+type snapshot struct {
+	state    string
+	playlist []*player.Song
+}
+
+func snapshotOf(d *discord.Discord) snapshot {
+	return snapshot{
+		state:    stateOf(d.Player),
+		playlist: playlistOf(d.Player),
+	}
+}
+
+// diff returns MPD subsystems that changed between snapshots.
+func (s snapshot) diff(other snapshot) []string {
+	var changed []string
+
+	if s.state != other.state || currentOf(s.playlist) != currentOf(other.playlist) {
+		changed = append(changed, "player")
+	}
+
+	if len(s.playlist) != len(other.playlist) {
+		changed = append(changed, "playlist")
+	} else {
+		for i := range s.playlist {
+			if s.playlist[i] != other.playlist[i] {
+				changed = append(changed, "playlist")
+				break
+			}
+		}
+	}
+
+	return changed
+}
+
+// version derives a playlist version from its content, clients only compare it for changes.
+func (s snapshot) version() uint32 {
+	hash := uint32(2166136261)
+	for _, song := range s.playlist {
+		for _, c := range []byte(song.ID + strconv.Itoa(len(s.playlist))) {
+			hash = (hash ^ uint32(c)) * 16777619
+		}
+	}
+	return hash
+}
+
+func currentOf(songs []*player.Song) *player.Song {
+	if len(songs) == 0 {
+		return nil
+	}
+	return songs[0]
+}
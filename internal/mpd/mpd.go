@@ -0,0 +1,125 @@
+// Package mpd exposes guild players over a minimal subset of the MPD protocol,
+// so desktop and mobile MPD clients can control Melodix like a local music daemon.
+package mpd
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/discord"
+)
+
+// protocolVersion is announced to clients, it's the oldest version whose commands we cover.
+const protocolVersion = "0.21.0"
+
+// Mpd is a MPD protocol server for Melodix.
+type Mpd struct {
+	BotInstances *discord.BotInstances
+	password     string
+	startedAt    time.Time
+	listener     net.Listener
+}
+
+// NewMpd creates a new instance of Mpd.
+// Clients select a guild with the password command, "guild_id" or "guild_id:password" if password is set.
+func NewMpd(botInstances *discord.BotInstances, password string) *Mpd {
+	return &Mpd{
+		BotInstances: botInstances,
+		password:     password,
+		startedAt:    time.Now(),
+	}
+}
+
+// Start listens on the address and serves clients in the background.
+// Without a password anyone reaching the server controls the guilds, so it only listens on loopback then.
+func (mp *Mpd) Start(address string) error {
+	if mp.password == "" {
+		loopback, err := loopbackAddress(address)
+		if err != nil {
+			return err
+		}
+		if loopback != address {
+			slog.Warnf("MPD server has no password, listening on %v instead of %v", loopback, address)
+			address = loopback
+		}
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	mp.listener = listener
+
+	slog.Infof("MPD server started on %v", address)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				slog.Errorf("Error accepting MPD connection: %v", err)
+				return
+			}
+			go newSession(mp, conn).serve()
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops accepting clients.
+func (mp *Mpd) Stop() {
+	if mp.listener != nil {
+		mp.listener.Close()
+	}
+}
+
+// loopbackAddress returns the address if its host is a loopback one, else the address on 127.0.0.1 with its port.
+func loopbackAddress(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if host == "localhost" {
+		return address, nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return address, nil
+	}
+	return net.JoinHostPort("127.0.0.1", port), nil
+}
+
+// authenticate resolves the guild selected by a password command.
+func (mp *Mpd) authenticate(password string) (*discord.Discord, bool) {
+	guildID, secret, _ := strings.Cut(password, ":")
+
+	if mp.password != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(mp.password)) != 1 {
+		return nil, false
+	}
+
+	instance, ok := mp.BotInstances.Get(guildID)
+	if !ok {
+		return nil, false
+	}
+
+	return instance.Melodix, true
+}
+
+// defaultGuild returns the only guild when there is exactly one and no password is required.
+func (mp *Mpd) defaultGuild() *discord.Discord {
+	if mp.password != "" || mp.BotInstances.Len() != 1 {
+		return nil
+	}
+
+	for _, instance := range mp.BotInstances.All() {
+		return instance.Melodix
+	}
+
+	return nil
+}
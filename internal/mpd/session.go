@@ -0,0 +1,248 @@
+package mpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/discord"
+)
+
+// MPD error codes, see https://mpd.readthedocs.io/en/latest/protocol.html#failure-responses
+const (
+	ackErrorArg        = 2
+	ackErrorPassword   = 3
+	ackErrorPermission = 4
+	ackErrorUnknown    = 5
+	ackErrorNoExist    = 50
+	ackErrorSystem     = 52
+)
+
+// idlePollInterval is how often player state is compared while a client idles.
+const idlePollInterval = 500 * time.Millisecond
+
+// ackError is a failed command reported to the client.
+type ackError struct {
+	code    int
+	message string
+}
+
+func (e *ackError) Error() string {
+	return e.message
+}
+
+// session is a single client connection.
+type session struct {
+	mpd    *Mpd
+	conn   net.Conn
+	writer *bufio.Writer
+	lines  chan string
+	done   chan struct{} // closed when the session ends, so the reader stops forwarding lines
+	guild  *discord.Discord
+}
+
+func newSession(mp *Mpd, conn net.Conn) *session {
+	return &session{
+		mpd:    mp,
+		conn:   conn,
+		writer: bufio.NewWriter(conn),
+		lines:  make(chan string),
+		done:   make(chan struct{}),
+		guild:  mp.defaultGuild(),
+	}
+}
+
+// serve runs the request loop until the client disconnects.
+func (ss *session) serve() {
+	defer ss.conn.Close()
+	defer close(ss.done)
+
+	go ss.readLines()
+
+	ss.writeLine("OK MPD " + protocolVersion)
+	ss.flush()
+
+	var list []string
+	inList, listOK := false, false
+
+	for line := range ss.lines {
+		switch {
+		case line == "command_list_begin" || line == "command_list_ok_begin":
+			inList, listOK, list = true, line == "command_list_ok_begin", nil
+			continue
+		case line == "command_list_end" && inList:
+			inList = false
+			ss.runList(list, listOK)
+		case inList:
+			list = append(list, line)
+			continue
+		default:
+			err := ss.run(line, 0)
+			if err == errClose {
+				ss.flush()
+				return
+			}
+			if err == nil {
+				ss.writeLine("OK")
+			}
+		}
+
+		ss.flush()
+	}
+}
+
+// runList executes a command list, stopping at the first failure.
+func (ss *session) runList(list []string, listOK bool) {
+	for i, line := range list {
+		if err := ss.run(line, i); err != nil {
+			return
+		}
+		if listOK {
+			ss.writeLine("list_OK")
+		}
+	}
+	ss.writeLine("OK")
+}
+
+// errClose asks the session to close the connection.
+var errClose = errors.New("close")
+
+// run executes a single command line, errors are reported to the client before returning.
+func (ss *session) run(line string, listNum int) error {
+	args, err := splitArgs(line)
+	if err != nil || len(args) == 0 {
+		ss.writeAck(listNum, "", &ackError{ackErrorArg, "invalid command"})
+		return errors.New("invalid command")
+	}
+
+	name := strings.ToLower(args[0])
+	cmd, ok := commands[name]
+	if !ok {
+		ss.writeAck(listNum, name, &ackError{ackErrorUnknown, fmt.Sprintf("unknown command \"%v\"", name)})
+		return errors.New("unknown command")
+	}
+
+	if cmd.needsGuild && ss.guild == nil {
+		ss.writeAck(listNum, name, &ackError{ackErrorPermission, "select a guild with the password command first"})
+		return errors.New("no guild selected")
+	}
+
+	err = cmd.run(ss, args[1:])
+	if err == nil || err == errClose {
+		return err
+	}
+
+	var ack *ackError
+	if !errors.As(err, &ack) {
+		slog.Warnf("MPD command %v failed: %v", name, err)
+		ack = &ackError{ackErrorSystem, err.Error()}
+	}
+	ss.writeAck(listNum, name, ack)
+
+	return err
+}
+
+// readLines forwards client lines to the session loop.
+func (ss *session) readLines() {
+	defer close(ss.lines)
+
+	scanner := bufio.NewScanner(ss.conn)
+	for scanner.Scan() {
+		select {
+		case ss.lines <- strings.TrimRight(scanner.Text(), "\r"):
+		case <-ss.done:
+			return
+		}
+	}
+}
+
+// idle blocks until the player state changes or the client sends noidle.
+func (ss *session) idle() error {
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+
+	before := snapshotOf(ss.guild)
+	ss.flush()
+
+	for {
+		select {
+		case line, ok := <-ss.lines:
+			if !ok {
+				return errClose
+			}
+			if line == "noidle" {
+				return nil
+			}
+			// Anything but noidle is a protocol violation
+			return errClose
+		case <-ticker.C:
+			after := snapshotOf(ss.guild)
+			if changed := before.diff(after); len(changed) > 0 {
+				for _, subsystem := range changed {
+					ss.writeLine("changed: " + subsystem)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+func (ss *session) writeLine(line string) {
+	ss.writer.WriteString(line + "\n")
+}
+
+func (ss *session) writePair(key string, value interface{}) {
+	ss.writeLine(fmt.Sprintf("%v: %v", key, value))
+}
+
+func (ss *session) writeAck(listNum int, command string, ack *ackError) {
+	ss.writeLine(fmt.Sprintf("ACK [%d@%d] {%v} %v", ack.code, listNum, command, ack.message))
+}
+
+func (ss *session) flush() {
+	if err := ss.writer.Flush(); err != nil {
+		slog.Debugf("Error writing to MPD client: %v", err)
+	}
+}
+
+// splitArgs splits a command line into arguments, honoring double quotes and backslash escapes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inQuotes, escaped, hasArg := false, false, false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && inQuotes:
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+
+	if inQuotes || escaped {
+		return nil, errors.New("unterminated quote")
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
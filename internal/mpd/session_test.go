@@ -0,0 +1,157 @@
+package mpd
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/music/discord"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		args []string
+		err  bool
+	}{
+		{"", nil, false},
+		{"ping", []string{"ping"}, false},
+		{"  pause \t 1 ", []string{"pause", "1"}, false},
+		{`add "never gonna give you up"`, []string{"add", "never gonna give you up"}, false},
+		{`add "say \"hi\" \\ bye"`, []string{"add", `say "hi" \ bye`}, false},
+		{`password ""`, []string{"password", ""}, false},
+		{`add ab"c d"e`, []string{"add", "abc de"}, false},
+		{`add back\slash`, []string{"add", `back\slash`}, false},
+		{`add "unterminated`, nil, true},
+		{`add "escaped end\"`, nil, true},
+	}
+
+	for _, test := range tests {
+		args, err := splitArgs(test.line)
+		if (err != nil) != test.err {
+			t.Errorf("splitArgs(%q) error %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("splitArgs(%q) = %q, want %q", test.line, args, test.args)
+		}
+	}
+}
+
+func TestLoopbackAddress(t *testing.T) {
+	tests := map[string]string{
+		"localhost:6600": "localhost:6600",
+		"127.0.0.1:6600": "127.0.0.1:6600",
+		"[::1]:6600":     "[::1]:6600",
+		":6600":          "127.0.0.1:6600",
+		"0.0.0.0:6600":   "127.0.0.1:6600",
+		"10.0.0.5:6600":  "127.0.0.1:6600",
+		"example.com:66": "127.0.0.1:66",
+	}
+
+	for address, want := range tests {
+		if got, err := loopbackAddress(address); err != nil || got != want {
+			t.Errorf("loopbackAddress(%q) = %q, %v, want %q", address, got, err, want)
+		}
+	}
+	if _, err := loopbackAddress("6600"); err == nil {
+		t.Errorf("Address without a port was accepted")
+	}
+}
+
+// client talks to a session over an in-memory connection.
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newClient(t *testing.T, mp *Mpd) *client {
+	server, conn := net.Pipe()
+	go newSession(mp, server).serve()
+	t.Cleanup(func() { conn.Close() })
+
+	c := &client{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	if greeting := c.readLine(); greeting != "OK MPD "+protocolVersion {
+		t.Fatalf("Incorrect greeting %q", greeting)
+	}
+	return c
+}
+
+func (c *client) readLine() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("Error reading response: %v", err)
+	}
+	return strings.TrimSuffix(line, "\n")
+}
+
+// send writes the lines and returns the response up to the final OK or ACK.
+func (c *client) send(lines ...string) []string {
+	c.t.Helper()
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+		c.t.Fatalf("Error sending command: %v", err)
+	}
+
+	var response []string
+	for {
+		line := c.readLine()
+		response = append(response, line)
+		if line == "OK" || strings.HasPrefix(line, "ACK ") {
+			return response
+		}
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	mp := NewMpd(discord.NewBotInstances(), "secret")
+
+	tests := []struct {
+		lines    []string
+		response []string
+	}{
+		{[]string{"ping"}, []string{"OK"}},
+		{[]string{"PING"}, []string{"OK"}},
+		{[]string{"frobnicate"}, []string{`ACK [5@0] {frobnicate} unknown command "frobnicate"`}},
+		{[]string{`add "unterminated`}, []string{"ACK [2@0] {} invalid command"}},
+		{[]string{"status"}, []string{"ACK [4@0] {status} select a guild with the password command first"}},
+		{[]string{"add song"}, []string{"ACK [4@0] {add} select a guild with the password command first"}},
+		{[]string{"password"}, []string{"ACK [2@0] {password} wrong number of arguments"}},
+		{[]string{"password 1:wrong"}, []string{"ACK [3@0] {password} incorrect password"}},
+		{[]string{"password 1:secret"}, []string{"ACK [3@0] {password} incorrect password"}}, // no such guild
+		{[]string{"tagtypes"}, []string{"tagtype: Title", "OK"}},
+		{[]string{"command_list_begin", "ping", "tagtypes", "command_list_end"}, []string{"tagtype: Title", "OK"}},
+		{[]string{"command_list_ok_begin", "ping", "tagtypes", "command_list_end"}, []string{"list_OK", "tagtype: Title", "list_OK", "OK"}},
+		{[]string{"command_list_ok_begin", "ping", "frobnicate", "ping", "command_list_end"}, []string{"list_OK", `ACK [5@1] {frobnicate} unknown command "frobnicate"`}},
+	}
+
+	c := newClient(t, mp)
+	for _, test := range tests {
+		if response := c.send(test.lines...); !reflect.DeepEqual(response, test.response) {
+			t.Errorf("%q responded %q, want %q", test.lines, response, test.response)
+		}
+	}
+
+	// Commands that need a guild are only listed once one is selected
+	for _, line := range c.send("commands") {
+		if line == "command: status" {
+			t.Errorf("Commands listed status without a guild")
+		}
+	}
+}
+
+func TestSessionClose(t *testing.T) {
+	c := newClient(t, NewMpd(discord.NewBotInstances(), ""))
+
+	c.conn.Write([]byte("close\nping\n"))
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := c.reader.ReadString('\n'); err == nil {
+		t.Errorf("Session answered %q after close", line)
+	}
+}
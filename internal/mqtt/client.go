@@ -0,0 +1,248 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types.
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetSubscribe  = 8
+	packetSuback     = 9
+	packetPingreq    = 12
+	packetPingresp   = 13
+	packetDisconnect = 14
+)
+
+// will is the message the broker publishes when the client disappears.
+type will struct {
+	topic   string
+	payload string
+}
+
+// client is a minimal MQTT 3.1.1 client, all messages are sent and received with QoS 0.
+type client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writeMu   sync.Mutex
+	keepAlive time.Duration
+	nextID    uint16
+	onMessage func(topic string, payload []byte)
+}
+
+// dial connects and authenticates to the broker.
+func dial(address, clientID, username, password string, lastWill *will, keepAlive time.Duration) (*client, error) {
+	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		keepAlive: keepAlive,
+	}
+
+	var payload []byte
+	flags := byte(0x02) // clean session
+
+	payload = appendString(payload, clientID)
+	if lastWill != nil {
+		flags |= 0x04 | 0x20 // will, retained
+		payload = appendString(payload, lastWill.topic)
+		payload = appendString(payload, lastWill.payload)
+	}
+	if username != "" {
+		flags |= 0x80
+		payload = appendString(payload, username)
+	}
+	if password != "" {
+		flags |= 0x40
+		payload = appendString(payload, password)
+	}
+
+	var header []byte
+	header = appendString(header, "MQTT")
+	header = append(header, 4, flags) // protocol level 3.1.1
+	header = binary.BigEndian.AppendUint16(header, uint16(keepAlive.Seconds()))
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	if err := c.writePacket(packetConnect<<4, append(header, payload...)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	packetType, body, err := c.readPacket()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if packetType != packetConnack || len(body) != 2 {
+		conn.Close()
+		return nil, errors.New("unexpected reply to connect")
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("connection refused by broker, code %d", body[1])
+	}
+
+	return c, nil
+}
+
+// publish sends a QoS 0 message.
+func (c *client) publish(topic string, payload []byte, retain bool) error {
+	header := byte(packetPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+
+	body := appendString(nil, topic)
+	return c.writePacket(header, append(body, payload...))
+}
+
+// subscribe asks for QoS 0 messages matching the topic filter, the acknowledgement is read by run.
+func (c *client) subscribe(filter string) error {
+	c.nextID++
+	body := binary.BigEndian.AppendUint16(nil, c.nextID)
+	body = appendString(body, filter)
+	body = append(body, 0) // QoS 0
+
+	return c.writePacket(packetSubscribe<<4|0x02, body)
+}
+
+// run reads packets and keeps the connection alive until it fails.
+func (c *client) run() error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(c.keepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.writePacket(packetPingreq<<4, nil); err != nil {
+					c.conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+
+		packetType, body, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+
+		switch packetType {
+		case packetPublish:
+			c.handlePublish(body)
+		case packetSuback, packetPingresp, packetPuback:
+		default:
+			return fmt.Errorf("unexpected packet type %d", packetType)
+		}
+	}
+}
+
+// handlePublish passes an incoming message to onMessage.
+func (c *client) handlePublish(body []byte) {
+	if len(body) < 2 {
+		return
+	}
+
+	topicLength := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+topicLength {
+		return
+	}
+
+	// Subscriptions are QoS 0 so messages never carry a packet identifier
+	topic := string(body[2 : 2+topicLength])
+	if c.onMessage != nil {
+		c.onMessage(topic, body[2+topicLength:])
+	}
+}
+
+// close disconnects gracefully, the broker discards the will message.
+func (c *client) close() {
+	c.writePacket(packetDisconnect<<4, nil)
+	c.conn.Close()
+}
+
+func (c *client) writePacket(header byte, body []byte) error {
+	packet := []byte{header}
+	packet = appendRemainingLength(packet, len(body))
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// readPacket returns the type and body of the next packet.
+func (c *client) readPacket() (byte, []byte, error) {
+	header, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header >> 4, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendRemainingLength(b []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if length == 0 {
+			return b
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRemainingLength(t *testing.T) {
+	tests := map[int][]byte{
+		0:         {0x00},
+		127:       {0x7f},
+		128:       {0x80, 0x01},
+		16383:     {0xff, 0x7f},
+		16384:     {0x80, 0x80, 0x01},
+		2097151:   {0xff, 0xff, 0x7f},
+		268435455: {0xff, 0xff, 0xff, 0x7f},
+	}
+
+	for length, want := range tests {
+		if got := appendRemainingLength(nil, length); !bytes.Equal(got, want) {
+			t.Errorf("appendRemainingLength(%v) = %x, want %x", length, got, want)
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	read := func(data []byte) (byte, []byte, error) {
+		c := &client{reader: bufio.NewReader(bytes.NewReader(data))}
+		return c.readPacket()
+	}
+
+	body := bytes.Repeat([]byte{'x'}, 200)
+	packet := append(appendRemainingLength([]byte{packetPublish<<4 | 0x01}, len(body)), body...)
+	packetType, got, err := read(packet)
+	if err != nil || packetType != packetPublish || !bytes.Equal(got, body) {
+		t.Errorf("readPacket = %v, %v bytes, %v", packetType, len(got), err)
+	}
+
+	if _, _, err := read([]byte{packetPingresp << 4, 0xff, 0xff, 0xff, 0xff, 0x01}); err == nil {
+		t.Errorf("Remaining length of five bytes was accepted")
+	}
+	if _, _, err := read([]byte{packetPublish << 4, 0x05, 'a'}); err == nil {
+		t.Errorf("Truncated packet was accepted")
+	}
+}
+
+func TestPublishIsReceived(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+
+	sender := &client{conn: conn}
+	receiver := &client{conn: server, reader: bufio.NewReader(server)}
+
+	type message struct {
+		topic   string
+		payload string
+	}
+	received := make(chan message, 1)
+	receiver.onMessage = func(topic string, payload []byte) {
+		received <- message{topic, string(payload)}
+	}
+
+	go sender.publish("melodix/1/command", []byte(`{"command": "add", "query": "song"}`), false)
+
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packetType, body, err := receiver.readPacket()
+	if err != nil || packetType != packetPublish {
+		t.Fatalf("readPacket = %v, %v", packetType, err)
+	}
+	receiver.handlePublish(body)
+
+	if got := <-received; got != (message{"melodix/1/command", `{"command": "add", "query": "song"}`}) {
+		t.Errorf("Received %+v", got)
+	}
+
+	// Malformed publish packets are ignored
+	receiver.handlePublish([]byte{0x00})
+	receiver.handlePublish([]byte{0x00, 0x10, 'a'})
+	if len(received) != 0 {
+		t.Errorf("Malformed packet was passed on")
+	}
+}
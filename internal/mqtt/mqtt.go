@@ -0,0 +1,287 @@
+// Package mqtt publishes guild player state and accepts player commands over MQTT,
+// so home automation (e.g. Home Assistant) can announce tracks or pause music.
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	keepAlive       = 60 * time.Second
+	publishInterval = time.Second
+	reconnectDelay  = 10 * time.Second
+	// commandQueueSize bounds the commands waiting to run, further ones are dropped until the queue drains
+	commandQueueSize = 32
+)
+
+// Options configure the broker connection.
+type Options struct {
+	Broker      string // host:port
+	ClientID    string
+	Username    string
+	Password    string
+	TopicPrefix string
+}
+
+// Mqtt is the MQTT integration for Melodix.
+//
+// Topics, with the default "melodix" prefix:
+//   - melodix/status: "online" or "offline" (retained, offline is the will message)
+//   - melodix/<guild id>/state: player state as JSON (retained)
+//   - melodix/<guild id>/command: accepts pause, resume, skip, stop (pauses, like MPD's stop), clear, shuffle
+//     or {"command": "add", "query": "<title or url>"}
+//
+// Commands run one at a time in the background, a slow search doesn't hold up reading from the broker.
+type Mqtt struct {
+	BotInstances *discord.BotInstances
+	options      Options
+	published    map[string]string // last published state per guild
+	commands     chan command
+}
+
+// command is a player command received on a guild command topic.
+type command struct {
+	guildID string
+	name    string
+	query   string
+}
+
+// NewMqtt creates a new instance of Mqtt.
+func NewMqtt(botInstances *discord.BotInstances, options Options) *Mqtt {
+	if options.ClientID == "" {
+		options.ClientID = "melodix"
+	}
+	if options.TopicPrefix == "" {
+		options.TopicPrefix = "melodix"
+	}
+
+	return &Mqtt{
+		BotInstances: botInstances,
+		options:      options,
+		commands:     make(chan command, commandQueueSize),
+	}
+}
+
+// Start connects to the broker in the background and reconnects whenever the connection drops.
+func (mq *Mqtt) Start() {
+	slog.Infof("MQTT integration started for broker %v", mq.options.Broker)
+
+	go mq.runCommands()
+
+	go func() {
+		for {
+			if err := mq.session(); err != nil {
+				slog.Warnf("MQTT connection lost: %v", err)
+			}
+			time.Sleep(reconnectDelay)
+		}
+	}()
+}
+
+// session runs a single broker connection until it fails.
+func (mq *Mqtt) session() error {
+	statusTopic := mq.options.TopicPrefix + "/status"
+
+	c, err := dial(mq.options.Broker, mq.options.ClientID, mq.options.Username, mq.options.Password, &will{statusTopic, "offline"}, keepAlive)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	c.onMessage = mq.handleMessage
+
+	if err := c.subscribe(mq.options.TopicPrefix + "/+/command"); err != nil {
+		return err
+	}
+	if err := c.publish(statusTopic, []byte("online"), true); err != nil {
+		return err
+	}
+
+	// Every connection starts with a full state publish
+	mq.published = make(map[string]string)
+
+	done := make(chan error, 1)
+	go func() { done <- c.run() }()
+
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if err := mq.publishStates(c); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// playerState is the JSON published to the state topic.
+type playerState struct {
+	Status      string  `json:"status"`
+	Title       string  `json:"title,omitempty"`
+	URL         string  `json:"url,omitempty"`
+	Thumbnail   string  `json:"thumbnail,omitempty"`
+	Duration    float64 `json:"duration,omitempty"`
+	RequesterID string  `json:"requester_id,omitempty"`
+	QueueLength int     `json:"queue_length"`
+}
+
+// publishStates publishes the state of every guild whose player changed.
+func (mq *Mqtt) publishStates(c *client) error {
+	for guildID, instance := range mq.BotInstances.All() {
+		p := instance.Melodix.Player
+
+		state := playerState{
+			Status:      strings.ToLower(p.GetCurrentStatus().String()),
+			QueueLength: len(p.GetSongQueue()),
+		}
+		if song := p.GetCurrentSong(); song != nil {
+			state.Title = song.Title
+			state.URL = song.UserURL
+			state.Thumbnail = song.Thumbnail.URL
+			state.Duration = song.Duration.Seconds()
+			state.RequesterID = song.RequesterID
+		}
+
+		payload, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		last, announced := mq.published[guildID]
+		if last == string(payload) {
+			continue
+		}
+		if !announced {
+			if err := mq.publishDiscovery(c, guildID); err != nil {
+				return err
+			}
+		}
+
+		if err := c.publish(fmt.Sprintf("%v/%v/state", mq.options.TopicPrefix, guildID), payload, true); err != nil {
+			return err
+		}
+		mq.published[guildID] = string(payload)
+	}
+
+	return nil
+}
+
+// publishDiscovery announces the guild player as a Home Assistant sensor.
+func (mq *Mqtt) publishDiscovery(c *client, guildID string) error {
+	stateTopic := fmt.Sprintf("%v/%v/state", mq.options.TopicPrefix, guildID)
+
+	config, err := json.Marshal(map[string]interface{}{
+		"name":                  "Melodix " + guildID,
+		"unique_id":             "melodix_" + guildID,
+		"state_topic":           stateTopic,
+		"value_template":        "{{ value_json.status }}",
+		"json_attributes_topic": stateTopic,
+		"availability_topic":    mq.options.TopicPrefix + "/status",
+		"icon":                  "mdi:music",
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.publish(fmt.Sprintf("homeassistant/sensor/melodix_%v/config", guildID), config, true)
+}
+
+// handleMessage queues a command received on a guild command topic, it's called by the read loop of the connection.
+func (mq *Mqtt) handleMessage(topic string, payload []byte) {
+	cmd, ok := mq.parseCommand(topic, payload)
+	if !ok {
+		return
+	}
+
+	select {
+	case mq.commands <- cmd:
+	default:
+		slog.Warnf("Too many MQTT commands waiting, dropping %q for guild %v", cmd.name, cmd.guildID)
+	}
+}
+
+// parseCommand reads the command of a message, false if the topic isn't a guild command topic.
+// The payload is either the plain command or JSON with the command and its query.
+func (mq *Mqtt) parseCommand(topic string, payload []byte) (command, bool) {
+	rest, found := strings.CutPrefix(topic, mq.options.TopicPrefix+"/")
+	if !found {
+		return command{}, false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "command" {
+		return command{}, false
+	}
+
+	var cmd struct {
+		Command string `json:"command"`
+		Query   string `json:"query"`
+	}
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		cmd.Command = string(payload)
+	}
+
+	return command{
+		guildID: parts[0],
+		name:    strings.ToLower(strings.TrimSpace(cmd.Command)),
+		query:   cmd.Query,
+	}, true
+}
+
+// runCommands runs queued commands one at a time, in the order they were received.
+func (mq *Mqtt) runCommands() {
+	for cmd := range mq.commands {
+		mq.runCommand(cmd)
+	}
+}
+
+// runCommand runs a command on the player of its guild.
+func (mq *Mqtt) runCommand(cmd command) {
+	instance, ok := mq.BotInstances.Get(cmd.guildID)
+	if !ok {
+		slog.Warnf("MQTT command for unknown guild %v", cmd.guildID)
+		return
+	}
+
+	p := instance.Melodix.Player
+
+	switch cmd.name {
+	case "pause":
+		if p.GetCurrentStatus() == player.StatusPlaying {
+			p.Pause()
+		}
+	case "resume", "play":
+		if p.GetCurrentStatus() == player.StatusPaused {
+			p.Unpause()
+		}
+	case "skip", "next":
+		p.Skip()
+	case "stop":
+		// Pauses like MPD's stop, stopping the player would disconnect it from voice and drop the queue
+		if p.GetCurrentStatus() == player.StatusPlaying {
+			p.Pause()
+		}
+	case "clear":
+		p.ClearQueue()
+	case "shuffle":
+		if err := p.ShuffleQueue(); err != nil {
+			slog.Warnf("MQTT shuffle command failed: %v", err)
+		}
+	case "add":
+		if _, err := instance.Melodix.EnqueueQuery(cmd.query); err != nil {
+			slog.Warnf("MQTT add command failed: %v", err)
+		}
+	default:
+		slog.Warnf("Unknown MQTT command %q", cmd.name)
+	}
+}
@@ -0,0 +1,63 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/music/discord"
+)
+
+func TestParseCommand(t *testing.T) {
+	mq := NewMqtt(discord.NewBotInstances(), Options{TopicPrefix: "home/melodix"})
+
+	tests := []struct {
+		topic   string
+		payload string
+		want    command
+		ok      bool
+	}{
+		{"home/melodix/1/command", "pause", command{guildID: "1", name: "pause"}, true},
+		{"home/melodix/1/command", " Skip\n", command{guildID: "1", name: "skip"}, true},
+		{"home/melodix/1/command", `{"command": "add", "query": "never gonna give you up"}`, command{guildID: "1", name: "add", query: "never gonna give you up"}, true},
+		{"home/melodix/1/command", `{"command": "STOP"}`, command{guildID: "1", name: "stop"}, true},
+		{"home/melodix/1/command", "", command{guildID: "1"}, true},
+		{"home/melodix/1/state", "pause", command{}, false},
+		{"home/melodix//command", "pause", command{}, false},
+		{"home/melodix/1/2/command", "pause", command{}, false},
+		{"melodix/1/command", "pause", command{}, false},
+		{"other/home/melodix/1/command", "pause", command{}, false},
+	}
+
+	for _, test := range tests {
+		cmd, ok := mq.parseCommand(test.topic, []byte(test.payload))
+		if cmd != test.want || ok != test.ok {
+			t.Errorf("parseCommand(%q, %q) = %+v, %v, want %+v, %v", test.topic, test.payload, cmd, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestHandleMessageDoesNotBlock(t *testing.T) {
+	mq := NewMqtt(discord.NewBotInstances(), Options{})
+
+	// Nothing runs the commands, the read loop must go on once the queue is full
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < commandQueueSize*2; i++ {
+			mq.handleMessage("melodix/1/command", []byte("skip"))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleMessage blocked on a full command queue")
+	}
+	if len(mq.commands) != commandQueueSize {
+		t.Errorf("Queued %v commands, want %v", len(mq.commands), commandQueueSize)
+	}
+
+	// Commands of guilds without an instance are skipped
+	close(mq.commands)
+	mq.runCommands()
+}
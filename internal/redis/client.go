@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dialTimeout    = 10 * time.Second
+	commandTimeout = 5 * time.Second
+	// maxBulkSize is the largest bulk string Redis accepts by default, larger sizes mean a corrupt reply
+	maxBulkSize = 512 << 20
+)
+
+// replyError is an error reply of the server, the connection stays usable after it.
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+// client is a minimal RESP2 client, commands take string arguments and replies are strings,
+// byte slices for bulk strings, integers, arrays of those or nil. Error replies are returned as errors,
+// within arrays (e.g. of EXEC) they are items of the array.
+type client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+}
+
+// dial connects to the server, authenticates and selects the database.
+func dial(address, password string, db int) (*client, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+
+	if password != "" {
+		if _, err := c.do("AUTH", password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("selecting database %v: %w", db, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *client) close() error {
+	return c.conn.Close()
+}
+
+// do sends a command and waits for its reply.
+func (c *client) do(args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(commandTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.send(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// send writes a command without waiting for its reply, subscribed connections receive replies as messages.
+func (c *client) send(args ...string) error {
+	buf := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, arg := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// read reads a reply.
+func (c *client) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, replyError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		if size > maxBulkSize {
+			return nil, fmt.Errorf("bulk string of %d bytes", size)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		if data[size] != '\r' || data[size+1] != '\n' {
+			return nil, errors.New("malformed bulk string")
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		// Error items don't end the array, the rest of it must still be read to keep the connection in sync
+		items := make([]interface{}, count)
+		for i := range items {
+			items[i], err = c.read()
+			if isReplyError(err) {
+				items[i] = err
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unknown reply type %q", kind)
+}
+
+// isReplyError reports whether err came from the server rather than the connection.
+func isReplyError(err error) bool {
+	var reply replyError
+	return errors.As(err, &reply)
+}
@@ -0,0 +1,127 @@
+package redis
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readReply(data string) (interface{}, error) {
+	c := &client{reader: bufio.NewReader(strings.NewReader(data))}
+	return c.read()
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		reply interface{}
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"empty simple string", "+\r\n", ""},
+		{"integer", ":42\r\n", int64(42)},
+		{"negative integer", ":-1\r\n", int64(-1)},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello")},
+		{"bulk string with CRLF", "$7\r\nhel\r\nlo\r\n", []byte("hel\r\nlo")},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}},
+		{"null bulk string", "$-1\r\n", nil},
+		{"null array", "*-1\r\n", nil},
+		{"empty array", "*0\r\n", []interface{}{}},
+		{"array", "*3\r\n$3\r\nfoo\r\n:1\r\n$-1\r\n", []interface{}{[]byte("foo"), int64(1), nil}},
+		{"nested array", "*2\r\n$1\r\n0\r\n*2\r\n$6\r\nkey:12\r\n+bar\r\n", []interface{}{[]byte("0"), []interface{}{[]byte("key:12"), "bar"}}},
+		{"error item", "*3\r\n+OK\r\n-WRONGTYPE wrong kind\r\n:2\r\n", []interface{}{"OK", replyError("WRONGTYPE wrong kind"), int64(2)}},
+	}
+
+	for _, test := range tests {
+		reply, err := readReply(test.data)
+		if err != nil || !reflect.DeepEqual(reply, test.reply) {
+			t.Errorf("%v: read %#v, %v, want %#v", test.name, reply, err, test.reply)
+		}
+	}
+}
+
+func TestReadErrorReply(t *testing.T) {
+	reply, err := readReply("-ERR unknown command 'FOO'\r\n")
+	if reply != nil || !isReplyError(err) || err.Error() != "ERR unknown command 'FOO'" {
+		t.Errorf("Error reply read as %#v, %v", reply, err)
+	}
+}
+
+func TestReadMalformedReply(t *testing.T) {
+	tests := map[string]string{
+		"no CRLF":             "+OK\n",
+		"too short":           "+\n",
+		"unknown type":        "!5\r\nhello\r\n",
+		"integer":             ":forty\r\n",
+		"bulk size":           "$five\r\nhello\r\n",
+		"bulk size too large": "$1073741824\r\n",
+		"truncated bulk":      "$5\r\nhel",
+		"bulk without CRLF":   "$5\r\nhelloXX",
+		"array size":          "*two\r\n",
+		"truncated array":     "*2\r\n:1\r\n",
+		"empty":               "",
+	}
+
+	for name, data := range tests {
+		if reply, err := readReply(data); err == nil || isReplyError(err) {
+			t.Errorf("%v: read %#v, %v", name, reply, err)
+		}
+	}
+}
+
+// TestReadKeepsSync reads replies one after another from the same connection, an error reply or an error item
+// must not leave parts of a reply unread for the next command.
+func TestReadKeepsSync(t *testing.T) {
+	c := &client{reader: bufio.NewReader(strings.NewReader("-ERR first\r\n*2\r\n-ERR item\r\n$2\r\nok\r\n+PONG\r\n"))}
+
+	if _, err := c.read(); !isReplyError(err) {
+		t.Fatalf("First reply read as %v", err)
+	}
+	if _, err := c.read(); err != nil {
+		t.Fatalf("Array with an error item read as %v", err)
+	}
+	if reply, err := c.read(); reply != "PONG" || err != nil {
+		t.Errorf("Last reply read as %#v, %v", reply, err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	c := &client{conn: conn, reader: bufio.NewReader(conn)}
+	defer c.close()
+
+	received := make(chan string, 1)
+	go func() {
+		reader := bufio.NewReader(server)
+		var command strings.Builder
+		// *3, then the size and value of each of the three arguments
+		for i := 0; i < 7; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command.WriteString(line)
+		}
+		received <- command.String()
+		server.Write([]byte("+OK\r\n"))
+	}()
+
+	reply, err := c.do("SET", "melodix:key", "a value")
+	if err != nil || reply != "OK" {
+		t.Errorf("do = %#v, %v", reply, err)
+	}
+
+	select {
+	case command := <-received:
+		if want := "*3\r\n$3\r\nSET\r\n$11\r\nmelodix:key\r\n$7\r\na value\r\n"; command != want {
+			t.Errorf("Sent %q, want %q", command, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Command wasn't sent")
+	}
+}
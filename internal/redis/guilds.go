@@ -0,0 +1,393 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/rest"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	// requestTimeout is how long API calls wait for the worker running the guild, looking up songs may take a while
+	requestTimeout = 30 * time.Second
+
+	// replyTTL is how long unclaimed replies are kept, e.g. of calls that timed out
+	replyTTL = time.Minute
+)
+
+// request is an API call of a guild method on the worker running the guild.
+type request struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	Arg    string `json:"arg,omitempty"`
+}
+
+// response is the answer of the worker to a request.
+type response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Code   string          `json:"code,omitempty"` // identifies errors the API answers specifically
+}
+
+// errorCodes are the errors that keep their identity across processes.
+var errorCodes = map[string]error{
+	"not_in_voice":      discord.ErrNotInVoice,
+	"no_songs":          discord.ErrNoSongs,
+	"not_voice_channel": discord.ErrNotVoiceChannel,
+	"queue_position":    player.ErrQueuePosition,
+}
+
+func (rd *Redis) requestChannel(guildID string) string {
+	return fmt.Sprintf("%v:%v:requests", rd.options.KeyPrefix, guildID)
+}
+
+func (rd *Redis) replyKey(requestID string) string {
+	return fmt.Sprintf("%v:replies:%v", rd.options.KeyPrefix, requestID)
+}
+
+// Guilds serves the guilds of this process and those run by the workers sharing the Redis server.
+func (rd *Redis) Guilds() rest.Guilds {
+	return sharedGuilds{rd: rd}
+}
+
+// sharedGuilds are the guilds of all processes, local guilds are served directly.
+type sharedGuilds struct {
+	rd *Redis
+}
+
+func (sg sharedGuilds) Guild(guildID string) (rest.Guild, bool) {
+	if guild, exists := sg.rd.local.Guild(guildID); exists {
+		return guild, true
+	}
+
+	payload, ok := sg.rd.State(guildID)
+	if !ok {
+		return nil, false
+	}
+
+	var state PlayerState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		slog.Warnf("Error decoding player state of guild %v: %v", guildID, err)
+		return nil, false
+	}
+
+	return remoteGuild{rd: sg.rd, id: guildID, state: state}, true
+}
+
+func (sg sharedGuilds) GuildIDs() []string {
+	seen := make(map[string]bool)
+	for _, guildID := range sg.rd.local.GuildIDs() {
+		seen[guildID] = true
+	}
+
+	remote, err := sg.rd.stateGuildIDs()
+	if err != nil {
+		slog.Warnf("Error listing guilds of other workers: %v", err)
+	}
+	for _, guildID := range remote {
+		seen[guildID] = true
+	}
+
+	ids := make([]string, 0, len(seen))
+	for guildID := range seen {
+		ids = append(ids, guildID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// stateGuildIDs returns the guilds whose state is stored, i.e. that some worker runs.
+func (rd *Redis) stateGuildIDs() ([]string, error) {
+	var ids []string
+
+	cursor := "0"
+	for {
+		reply, err := rd.do("SCAN", cursor, "MATCH", rd.stateKey("*"), "COUNT", "100")
+		if err != nil {
+			return ids, err
+		}
+
+		page, ok := reply.([]interface{})
+		if !ok || len(page) != 2 {
+			return ids, fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		keys, _ := page[1].([]interface{})
+		for _, key := range keys {
+			ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(bulkString(key), rd.options.KeyPrefix+":"), ":state"))
+		}
+
+		if cursor = bulkString(page[0]); cursor == "0" {
+			return ids, nil
+		}
+	}
+}
+
+// remoteGuild is a guild run by another worker, known by its latest published state.
+type remoteGuild struct {
+	rd    *Redis
+	id    string
+	state PlayerState
+}
+
+func (rg remoteGuild) Name() string {
+	if rg.state.GuildName == "" {
+		return rg.id
+	}
+	return rg.state.GuildName
+}
+
+func (rg remoteGuild) Active() bool {
+	return rg.state.Active
+}
+
+func (rg remoteGuild) NowPlayingPublic() bool {
+	return rg.state.NowPlayingPublic
+}
+
+func (rg remoteGuild) HistoryPublic() bool {
+	return rg.state.HistoryPublic
+}
+
+func (rg remoteGuild) HasDJRole(userID string) bool {
+	var dj bool
+	if err := rg.rd.call(rg.id, "dj_role", userID, &dj); err != nil {
+		slog.Warnf("Error checking the DJ role in guild %v: %v", rg.id, err)
+		return false
+	}
+	return dj
+}
+
+func (rg remoteGuild) State() rest.GuildState {
+	state := rest.GuildState{
+		Status:    playbackStatus(rg.state.Status),
+		Position:  time.Duration(rg.state.Position * float64(time.Second)),
+		Queue:     make([]*player.Song, 0, len(rg.state.Queue)),
+		QueueSize: rg.state.QueueSize,
+		Streaming: rg.state.Streaming,
+	}
+
+	if rg.state.Title != "" {
+		state.CurrentSong = &player.Song{
+			Title:       rg.state.Title,
+			UserURL:     rg.state.URL,
+			Thumbnail:   player.Thumbnail{URL: rg.state.Thumbnail},
+			Duration:    time.Duration(rg.state.Duration * float64(time.Second)),
+			RequesterID: rg.state.RequesterID,
+		}
+	}
+
+	for _, song := range rg.state.Queue {
+		state.Queue = append(state.Queue, &player.Song{
+			Title:    song.Title,
+			UserURL:  song.URL,
+			Duration: time.Duration(song.Duration * float64(time.Second)),
+		})
+	}
+
+	return state
+}
+
+func (rg remoteGuild) Metrics() (player.Metrics, error) {
+	var metrics player.Metrics
+	err := rg.rd.call(rg.id, "metrics", "", &metrics)
+	return metrics, err
+}
+
+func (rg remoteGuild) Diagnostics() (discord.Diagnostics, error) {
+	var diagnostics discord.Diagnostics
+	err := rg.rd.call(rg.id, "debug", "", &diagnostics)
+	return diagnostics, err
+}
+
+func (rg remoteGuild) Pause() error {
+	return rg.rd.call(rg.id, "pause", "", nil)
+}
+
+func (rg remoteGuild) Resume() error {
+	return rg.rd.call(rg.id, "resume", "", nil)
+}
+
+func (rg remoteGuild) Skip() error {
+	return rg.rd.call(rg.id, "skip", "", nil)
+}
+
+func (rg remoteGuild) Stop() error {
+	return rg.rd.call(rg.id, "stop", "", nil)
+}
+
+func (rg remoteGuild) RemoveFromQueue(position int) (*player.Song, error) {
+	var song *player.Song
+	err := rg.rd.call(rg.id, "remove", strconv.Itoa(position), &song)
+	return song, err
+}
+
+func (rg remoteGuild) EnqueueQuery(query string) ([]*player.Song, error) {
+	var songs []*player.Song
+	err := rg.rd.call(rg.id, "enqueue", query, &songs)
+	return songs, err
+}
+
+func (rg remoteGuild) JoinVoiceChannel(channelID string) error {
+	return rg.rd.call(rg.id, "join", channelID, nil)
+}
+
+func (rg remoteGuild) LeaveVoiceChannel() (bool, error) {
+	var left bool
+	err := rg.rd.call(rg.id, "leave", "", &left)
+	return left, err
+}
+
+// playbackStatus returns the status of its name, resting for unknown names.
+func playbackStatus(name string) player.PlaybackStatus {
+	for _, status := range []player.PlaybackStatus{player.StatusPlaying, player.StatusPaused, player.StatusError} {
+		if status.String() == name {
+			return status
+		}
+	}
+	return player.StatusResting
+}
+
+// call runs a guild method on the worker running the guild and decodes its result into result, which may be nil.
+func (rd *Redis) call(guildID, method, arg string, result interface{}) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	req := request{ID: hex.EncodeToString(id), Method: method, Arg: arg}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	// The reply is awaited on a dedicated connection, blocking the shared one would hold up every other command
+	c, err := dial(rd.options.Address, rd.options.Password, rd.options.DB)
+	if err != nil {
+		return fmt.Errorf("%w: %v", rest.ErrGuildUnreachable, err)
+	}
+	defer c.close()
+
+	receivers, err := rd.do("PUBLISH", rd.requestChannel(guildID), string(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", rest.ErrGuildUnreachable, err)
+	}
+	if count, _ := receivers.(int64); count == 0 {
+		return rest.ErrGuildUnreachable
+	}
+
+	c.conn.SetDeadline(time.Now().Add(requestTimeout + commandTimeout))
+	if err := c.send("BLPOP", rd.replyKey(req.ID), strconv.Itoa(int(requestTimeout.Seconds()))); err != nil {
+		return fmt.Errorf("%w: %v", rest.ErrGuildUnreachable, err)
+	}
+	reply, err := c.read()
+	if err != nil {
+		return fmt.Errorf("%w: %v", rest.ErrGuildUnreachable, err)
+	}
+
+	// BLPOP answers [key, value], or nil when it timed out
+	popped, ok := reply.([]interface{})
+	if !ok || len(popped) != 2 {
+		return rest.ErrGuildUnreachable
+	}
+
+	var resp response
+	if err := json.Unmarshal(popped[1].([]byte), &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		if known, ok := errorCodes[resp.Code]; ok {
+			return known
+		}
+		return errors.New(resp.Error)
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// handleRequest answers an API call for a guild if this process runs it, the worker running it answers otherwise.
+func (rd *Redis) handleRequest(guildID string, payload []byte) {
+	guild, exists := rd.local.Guild(guildID)
+	if !exists {
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(payload, &req); err != nil || req.ID == "" {
+		slog.Warnf("Ignoring malformed request for guild %v: %q", guildID, payload)
+		return
+	}
+
+	var resp response
+	result, err := serveRequest(guild, req)
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		for code, known := range errorCodes {
+			if errors.Is(err, known) {
+				resp.Code = code
+			}
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		slog.Errorf("Error encoding reply to %v: %v", req.Method, err)
+		return
+	}
+
+	key := rd.replyKey(req.ID)
+	if _, err := rd.do("RPUSH", key, string(data)); err != nil {
+		slog.Warnf("Error replying to %v for guild %v: %v", req.Method, guildID, err)
+		return
+	}
+	if _, err := rd.do("EXPIRE", key, strconv.Itoa(int(replyTTL.Seconds()))); err != nil {
+		slog.Warnf("Error expiring reply to %v for guild %v: %v", req.Method, guildID, err)
+	}
+}
+
+// serveRequest runs the guild method of a request.
+func serveRequest(guild rest.Guild, req request) (interface{}, error) {
+	switch req.Method {
+	case "dj_role":
+		return guild.HasDJRole(req.Arg), nil
+	case "metrics":
+		return guild.Metrics()
+	case "debug":
+		return guild.Diagnostics()
+	case "pause":
+		return nil, guild.Pause()
+	case "resume":
+		return nil, guild.Resume()
+	case "skip":
+		return nil, guild.Skip()
+	case "stop":
+		return nil, guild.Stop()
+	case "remove":
+		position, err := strconv.Atoi(req.Arg)
+		if err != nil {
+			return nil, player.ErrQueuePosition
+		}
+		return guild.RemoveFromQueue(position)
+	case "enqueue":
+		return guild.EnqueueQuery(req.Arg)
+	case "join":
+		return nil, guild.JoinVoiceChannel(req.Arg)
+	case "leave":
+		return guild.LeaveVoiceChannel()
+	}
+	return nil, fmt.Errorf("unknown method %v", req.Method)
+}
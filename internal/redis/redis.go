@@ -0,0 +1,359 @@
+// Package redis shares guild players and looked up track metadata between processes over Redis,
+// so the API can be served by other processes than the bot workers playing the guilds and every
+// process benefits from the lookups of the others.
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/rest"
+	"github.com/keshon/melodix-discord-player/music/discord"
+)
+
+const (
+	publishInterval = time.Second
+	reconnectDelay  = 10 * time.Second
+	pingInterval    = 30 * time.Second
+
+	// stateTTL is how long a stored state outlives the process that stopped publishing it
+	stateTTL = 30 * time.Second
+
+	// stateRefresh is how often an unchanged state is stored again before it expires
+	stateRefresh = 10 * time.Second
+
+	// stateQueueLimit is the number of queued songs included in a state
+	stateQueueLimit = 25
+)
+
+// Options configure the server connection.
+type Options struct {
+	Address    string // host:port
+	Password   string
+	DB         int
+	KeyPrefix  string
+	InstanceID string // name of this process in published states
+}
+
+// PlayerState is the JSON stored and published for a guild player.
+type PlayerState struct {
+	Instance         string       `json:"instance"`
+	GuildName        string       `json:"guild_name"`
+	Active           bool         `json:"active"`
+	NowPlayingPublic bool         `json:"now_playing_public"`
+	HistoryPublic    bool         `json:"history_public"`
+	Streaming        bool         `json:"streaming"`
+	Status           string       `json:"status"`
+	Title            string       `json:"title,omitempty"`
+	URL              string       `json:"url,omitempty"`
+	Thumbnail        string       `json:"thumbnail,omitempty"`
+	Duration         float64      `json:"duration,omitempty"`
+	Position         float64      `json:"position,omitempty"`
+	RequesterID      string       `json:"requester_id,omitempty"`
+	Queue            []QueuedSong `json:"queue"`
+	QueueSize        int          `json:"queue_size"`
+}
+
+// QueuedSong is an upcoming song of a player state.
+type QueuedSong struct {
+	Title    string  `json:"title"`
+	URL      string  `json:"url,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// receivedState is a state published by any process, kept until it's replaced or expires.
+type receivedState struct {
+	payload    []byte
+	receivedAt time.Time
+}
+
+// publishedState is the last state this process stored for a guild.
+type publishedState struct {
+	payload  string
+	storedAt time.Time
+}
+
+// Redis is the Redis integration for Melodix.
+//
+// Keys and channels, with the default "melodix" prefix:
+//   - melodix:<guild id>:state: player state as JSON, stored with a TTL of 30 seconds
+//     and published on the channel of the same name whenever it changes
+//   - melodix:<guild id>:requests: channel of API calls to the worker running the guild, answered
+//     on the list melodix:replies:<request id>
+//   - melodix:metadata:<key>: cached track metadata, e.g. the video found for a search
+type Redis struct {
+	BotInstances *discord.BotInstances
+	options      Options
+	local        rest.Guilds // the guilds of BotInstances
+	published    map[string]publishedState
+
+	commandsMu sync.Mutex
+	commands   *client // nil until connected and after the connection failed
+
+	statesMu sync.Mutex
+	states   map[string]receivedState // by guild ID
+}
+
+// NewRedis creates a new instance of Redis.
+func NewRedis(botInstances *discord.BotInstances, options Options) *Redis {
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "melodix"
+	}
+
+	return &Redis{
+		BotInstances: botInstances,
+		options:      options,
+		local:        rest.NewLocalGuilds(botInstances),
+		published:    make(map[string]publishedState),
+		states:       make(map[string]receivedState),
+	}
+}
+
+// Start publishes the state of the local guilds, answers API calls for them and follows the states
+// published by all processes in the background, reconnecting whenever a connection drops.
+func (rd *Redis) Start() {
+	slog.Infof("Redis integration started for server %v", rd.options.Address)
+
+	go rd.publishStates()
+
+	go func() {
+		for {
+			if err := rd.subscription(); err != nil {
+				slog.Warnf("Redis subscription lost: %v", err)
+			}
+			time.Sleep(reconnectDelay)
+		}
+	}()
+}
+
+// do runs a command on the shared command connection, connecting first if needed.
+// The connection is dropped on failures so the next command reconnects.
+func (rd *Redis) do(args ...string) (interface{}, error) {
+	rd.commandsMu.Lock()
+	defer rd.commandsMu.Unlock()
+
+	if rd.commands == nil {
+		c, err := dial(rd.options.Address, rd.options.Password, rd.options.DB)
+		if err != nil {
+			return nil, err
+		}
+		rd.commands = c
+	}
+
+	reply, err := rd.commands.do(args...)
+	if err != nil && !isReplyError(err) {
+		rd.commands.close()
+		rd.commands = nil
+	}
+	return reply, err
+}
+
+func (rd *Redis) stateKey(guildID string) string {
+	return fmt.Sprintf("%v:%v:state", rd.options.KeyPrefix, guildID)
+}
+
+func (rd *Redis) metadataKey(key string) string {
+	return fmt.Sprintf("%v:metadata:%v", rd.options.KeyPrefix, key)
+}
+
+// publishStates stores and publishes the state of every local guild whose player changed,
+// unchanged states are stored again before they expire.
+func (rd *Redis) publishStates() {
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+
+	failing := false
+	for range ticker.C {
+		err := rd.publishChangedStates()
+		if err != nil && !failing {
+			slog.Warnf("Error publishing player states to Redis: %v", err)
+		}
+		if err == nil && failing {
+			slog.Info("Publishing player states to Redis again")
+		}
+		failing = err != nil
+	}
+}
+
+func (rd *Redis) publishChangedStates() error {
+	now := time.Now()
+
+	for _, guildID := range rd.local.GuildIDs() {
+		guild, exists := rd.local.Guild(guildID)
+		if !exists {
+			continue
+		}
+
+		payload, err := json.Marshal(rd.playerState(guild))
+		if err != nil {
+			return err
+		}
+
+		last := rd.published[guildID]
+		changed := last.payload != string(payload)
+		if !changed && now.Sub(last.storedAt) < stateRefresh {
+			continue
+		}
+
+		key := rd.stateKey(guildID)
+		if _, err := rd.do("SET", key, string(payload), "EX", strconv.Itoa(int(stateTTL.Seconds()))); err != nil {
+			return err
+		}
+		if changed {
+			if _, err := rd.do("PUBLISH", key, string(payload)); err != nil {
+				return err
+			}
+		}
+		rd.published[guildID] = publishedState{payload: string(payload), storedAt: now}
+	}
+
+	return nil
+}
+
+// playerState returns the now playing song and the upcoming queue of a local guild.
+func (rd *Redis) playerState(guild rest.Guild) PlayerState {
+	snapshot := guild.State()
+	state := PlayerState{
+		Instance:         rd.options.InstanceID,
+		GuildName:        guild.Name(),
+		Active:           guild.Active(),
+		NowPlayingPublic: guild.NowPlayingPublic(),
+		HistoryPublic:    guild.HistoryPublic(),
+		Streaming:        snapshot.Streaming,
+		Status:           snapshot.Status.String(),
+		Queue:            []QueuedSong{},
+	}
+
+	if song := snapshot.CurrentSong; song != nil {
+		state.Title = song.Title
+		state.URL = song.UserURL
+		state.Thumbnail = song.Thumbnail.URL
+		state.Duration = song.Duration.Seconds()
+		// Whole seconds, so the state only changes once per second while playing
+		state.Position = snapshot.Position.Truncate(time.Second).Seconds()
+		state.RequesterID = song.RequesterID
+	}
+
+	state.QueueSize = snapshot.QueueSize
+	for i, song := range snapshot.Queue {
+		if i == stateQueueLimit {
+			break
+		}
+		state.Queue = append(state.Queue, QueuedSong{Title: song.Title, URL: song.UserURL, Duration: song.Duration.Seconds()})
+	}
+
+	return state
+}
+
+// subscription follows the state and request channels of all guilds on a dedicated connection until it fails.
+func (rd *Redis) subscription() error {
+	c, err := dial(rd.options.Address, rd.options.Password, rd.options.DB)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if err := c.send("PSUBSCRIBE", rd.stateKey("*"), rd.requestChannel("*")); err != nil {
+		return err
+	}
+
+	// Pings keep the connection alive and reveal when the server disappeared
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.send("PING"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+		reply, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		// Messages are ["pmessage", pattern, channel, payload]
+		message, ok := reply.([]interface{})
+		if !ok || len(message) != 4 || bulkString(message[0]) != "pmessage" {
+			continue
+		}
+		guildID, kind, _ := strings.Cut(strings.TrimPrefix(bulkString(message[2]), rd.options.KeyPrefix+":"), ":")
+		payload, _ := message[3].([]byte)
+
+		if kind == "requests" {
+			go rd.handleRequest(guildID, payload)
+			continue
+		}
+
+		rd.statesMu.Lock()
+		rd.states[guildID] = receivedState{payload: payload, receivedAt: time.Now()}
+		rd.statesMu.Unlock()
+	}
+}
+
+// State returns the latest player state of a guild published by any process as JSON, false if
+// no process published it recently.
+func (rd *Redis) State(guildID string) ([]byte, bool) {
+	rd.statesMu.Lock()
+	state, ok := rd.states[guildID]
+	rd.statesMu.Unlock()
+	if ok && time.Since(state.receivedAt) < stateTTL {
+		return state.payload, true
+	}
+
+	// Unchanged states aren't published again, they are read from their key
+	reply, err := rd.do("GET", rd.stateKey(guildID))
+	if err != nil {
+		slog.Debugf("Error getting player state of guild %v from Redis: %v", guildID, err)
+		return nil, false
+	}
+	payload, ok := reply.([]byte)
+	if !ok {
+		return nil, false
+	}
+
+	rd.statesMu.Lock()
+	rd.states[guildID] = receivedState{payload: payload, receivedAt: time.Now()}
+	rd.statesMu.Unlock()
+
+	return payload, true
+}
+
+// Get returns cached track metadata, false if it isn't cached or Redis is unreachable.
+func (rd *Redis) Get(key string) ([]byte, bool) {
+	reply, err := rd.do("GET", rd.metadataKey(key))
+	if err != nil {
+		slog.Debugf("Error getting %v from Redis: %v", key, err)
+		return nil, false
+	}
+	value, ok := reply.([]byte)
+	return value, ok
+}
+
+// Set caches track metadata for the given time.
+func (rd *Redis) Set(key string, value []byte, ttl time.Duration) {
+	if _, err := rd.do("SET", rd.metadataKey(key), string(value), "EX", strconv.Itoa(int(ttl.Seconds()))); err != nil {
+		slog.Debugf("Error caching %v in Redis: %v", key, err)
+	}
+}
+
+// bulkString returns a bulk string reply as string, empty for other replies.
+func bulkString(reply interface{}) string {
+	data, _ := reply.([]byte)
+	return string(data)
+}
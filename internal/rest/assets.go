@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/assets"
+)
+
+const (
+	assetsURLPath = "/assets/"
+	manifestName  = "manifest.json"
+)
+
+// registerAssetRoutes registers static asset routes. Assets are served under content hashed names,
+// so a changed file gets a new URL and responses may be cached for good.
+// http://localhost:8080/assets/manifest.json
+// http://localhost:8080/assets/avatars/00013-3296163435.3f2a9c1b0d4e5f60.png
+func (r *Rest) registerAssetRoutes(router *gin.RouterGroup) {
+	router.GET("/*name", func(ctx *gin.Context) {
+		name := strings.TrimPrefix(ctx.Param("name"), "/")
+
+		// The manifest maps plain names to the URLs of their current content, it must not be cached
+		if name == manifestName {
+			manifest := make(map[string]string)
+			for plainName, hashedName := range assets.Static.Manifest() {
+				manifest[plainName] = assetsURLPath + hashedName
+			}
+
+			ctx.Header("Cache-Control", "no-cache")
+			ctx.JSON(http.StatusOK, manifest)
+			return
+		}
+
+		asset, ok := assets.Static.Lookup(name)
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Asset not found, look up its current name in " + assetsURLPath + manifestName})
+			return
+		}
+
+		etag := fmt.Sprintf(`"%v"`, asset.Hash)
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+		ctx.Header("ETag", etag)
+
+		if ctx.GetHeader("If-None-Match") == etag {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+
+		data, err := asset.Read()
+		if err != nil {
+			slog.Errorf("Error reading asset %v: %v", asset.Name, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read asset"})
+			return
+		}
+
+		ctx.Data(http.StatusOK, asset.ContentType, data)
+	})
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"gorm.io/gorm"
+)
+
+// tokenTouchInterval limits how often the last use of a token is written to the database.
+const tokenTouchInterval = time.Minute
+
+// controlTokenKey is the context key set by guildAuthMiddleware when the request carries the admin token
+// or a token with control scope, see requireControlToken.
+const controlTokenKey = "control_token"
+
+// guildAuthMiddleware lets through requests carrying the admin token or a token of the guild in the route.
+// Tokens with read scope may only use safe methods. Without a token, a dashboard session of a user who may
+// control the guild is accepted.
+func (r *Rest) guildAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		secret, ok := bearerToken(ctx.GetHeader("Authorization"))
+		if !ok && r.oauth != nil {
+			if session, loggedIn := r.loadSession(ctx); loggedIn {
+				if !r.sameOrigin(ctx) || !r.canAccessGuild(session, ctx.Param("guild_id")) {
+					ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not allowed to control this guild"})
+					return
+				}
+				ctx.Next()
+				return
+			}
+		}
+		if !ok {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API token not provided"})
+			return
+		}
+
+		if r.options.AdminToken != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(r.options.AdminToken)) == 1 {
+			ctx.Set(controlTokenKey, true)
+			ctx.Next()
+			return
+		}
+
+		token, err := db.GetAPITokenByHash(db.HashSecret(secret))
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				slog.Errorf("Error getting API token: %v", err)
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API token"})
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API token"})
+			return
+		}
+
+		if token.GuildID != ctx.Param("guild_id") {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token is not valid for this guild"})
+			return
+		}
+
+		safeMethod := ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead
+		if token.Scope != db.ScopeControl && !safeMethod {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token is read-only"})
+			return
+		}
+
+		if token.Scope == db.ScopeControl {
+			ctx.Set(controlTokenKey, true)
+		}
+
+		if time.Since(token.LastUsed) > tokenTouchInterval {
+			go func() {
+				if err := db.TouchAPIToken(token.ID); err != nil {
+					slog.Warnf("Error updating API token usage: %v", err)
+				}
+			}()
+		}
+
+		ctx.Next()
+	}
+}
+
+// adminAuthMiddleware lets through requests carrying the admin token, for routes covering every guild or the bot
+// itself. Without an admin token configured these routes are closed.
+func (r *Rest) adminAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		secret, ok := bearerToken(ctx.GetHeader("Authorization"))
+		if !ok {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API token not provided"})
+			return
+		}
+
+		if r.options.AdminToken == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(r.options.AdminToken)) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only the admin token may use this route"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// requireControlToken aborts requests let through by guildAuthMiddleware without the admin token or a token
+// with control scope, e.g. by a dashboard session, it reports whether the request may go on.
+func requireControlToken(ctx *gin.Context) bool {
+	if ctx.GetBool(controlTokenKey) {
+		return true
+	}
+	ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "A control API token or the admin token is required"})
+	return false
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer <token>" header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,170 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decode jpg avatars
+	"image/png"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/assets"
+)
+
+const (
+	avatarsDir        = "avatars"
+	defaultAvatarSize = 256
+	maxAvatarSize     = 1024
+)
+
+// registerAvatarRoutes registers avatar-related routes.
+// http://localhost:8080/avatar
+// http://localhost:8080/avatar/random?size=128
+func (r *Rest) registerAvatarRoutes(router *gin.RouterGroup) {
+	router.GET("/", func(ctx *gin.Context) {
+		names := []string{}
+		for _, avatar := range assets.Static.Images(avatarsDir) {
+			names = append(names, path.Base(avatar.Name))
+		}
+
+		ctx.JSON(http.StatusOK, names)
+	})
+
+	// The avatar of the day, so Discord's media proxy and browsers may cache it until midnight UTC
+	router.GET("/random", func(ctx *gin.Context) {
+		avatars := assets.Static.Images(avatarsDir)
+		if len(avatars) == 0 {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "no valid images found"})
+			return
+		}
+
+		now := time.Now().UTC()
+		avatar := avatarOfDay(avatars, now)
+		size := avatarSize(ctx.Query("size"))
+		etag := fmt.Sprintf(`"%v-%d"`, avatar.Hash, size)
+		midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+		ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(midnight.Sub(now).Seconds())))
+		ctx.Header("ETag", etag)
+		ctx.Header("Last-Modified", avatar.ModTime.UTC().Format(http.TimeFormat))
+
+		if ctx.GetHeader("If-None-Match") == etag {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+
+		data, err := r.resizedAvatar(avatar, size)
+		if err != nil {
+			slog.Errorf("Error resizing avatar %v: %v", avatar.Name, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resize avatar"})
+			return
+		}
+
+		ctx.Data(http.StatusOK, "image/png", data)
+	})
+}
+
+// avatarOfDay picks the avatar for the UTC day, cycling through all of them in name order.
+func avatarOfDay(avatars []*assets.Asset, now time.Time) *assets.Asset {
+	day := now.Unix() / int64(24*time.Hour/time.Second)
+	return avatars[day%int64(len(avatars))]
+}
+
+// avatarSize returns the requested edge length rounded up to a power of two, which bounds the sizes cached.
+func avatarSize(param string) int {
+	requested, err := strconv.Atoi(param)
+	if err != nil || requested <= 0 {
+		return defaultAvatarSize
+	}
+
+	size := 16
+	for size < requested && size < maxAvatarSize {
+		size *= 2
+	}
+	return size
+}
+
+// resizedAvatar returns the avatar scaled to fit size as PNG, cached in memory.
+func (r *Rest) resizedAvatar(avatar *assets.Asset, size int) ([]byte, error) {
+	key := fmt.Sprintf("%v:%v", avatar.Hash, size)
+
+	r.avatarsMu.Lock()
+	defer r.avatarsMu.Unlock()
+
+	if data, ok := r.avatars[key]; ok {
+		return data, nil
+	}
+
+	file, err := os.Open(avatar.Path())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleDown(img, size)); err != nil {
+		return nil, err
+	}
+
+	r.avatars[key] = buf.Bytes()
+	return buf.Bytes(), nil
+}
+
+// scaleDown shrinks the image to fit into a square of size by averaging the source pixels each target pixel covers.
+// Images that fit already are returned as they are.
+func scaleDown(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return src
+	}
+
+	dstWidth, dstHeight := size, size
+	if width > height {
+		dstHeight = max(1, height*size/width)
+	} else {
+		dstWidth = max(1, width*size/height)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// RGBA returns alpha-premultiplied 16 bit values, NRGBA stores straight 8 bit ones
+			i := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[i+0] = uint8(r * 0xff / a)
+				dst.Pix[i+1] = uint8(g * 0xff / a)
+				dst.Pix[i+2] = uint8(b * 0xff / a)
+			}
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	return dst
+}
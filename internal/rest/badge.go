@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// Public routes are unauthenticated, so every client IP gets a small request budget.
+const (
+	publicRateLimit  = 30
+	publicRateWindow = time.Minute
+	badgeTitleLength = 48
+)
+
+// NowPlaying is the public view of a guild player.
+type NowPlaying struct {
+	Status   string  `json:"status"`
+	Title    string  `json:"title,omitempty"`
+	URL      string  `json:"url,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Position float64 `json:"position,omitempty"`
+}
+
+// registerPublicRoutes registers unauthenticated now playing and history routes of guilds that opted in.
+// http://localhost:8080/guilds/897053062030585916/nowplaying.json
+// http://localhost:8080/guilds/897053062030585916/nowplaying.svg
+// http://localhost:8080/guilds/897053062030585916/history.rss
+func (r *Rest) registerPublicRoutes(router *gin.RouterGroup) {
+	limiter := newRateLimiter(publicRateLimit, publicRateWindow)
+
+	router.GET("/nowplaying.json", limiter.middleware(), func(ctx *gin.Context) {
+		nowPlaying, ok := r.publicNowPlaying(ctx.Param("guild_id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found or now playing is not public"})
+			return
+		}
+
+		ctx.Header("Access-Control-Allow-Origin", "*")
+		ctx.Header("Cache-Control", "public, max-age=15")
+		ctx.JSON(http.StatusOK, nowPlaying)
+	})
+
+	router.GET("/nowplaying.svg", limiter.middleware(), func(ctx *gin.Context) {
+		nowPlaying, ok := r.publicNowPlaying(ctx.Param("guild_id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found or now playing is not public"})
+			return
+		}
+
+		message := "nothing playing"
+		if nowPlaying.Title != "" {
+			message = nowPlaying.Title
+			if nowPlaying.Status == player.StatusPaused.String() {
+				message = player.StatusPaused.StringEmoji() + " " + message
+			}
+		}
+
+		// Badge services such as GitHub's camo proxy cache images unless told otherwise
+		ctx.Header("Cache-Control", "no-cache, max-age=0")
+		ctx.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderBadge("now playing", message)))
+	})
+
+	router.GET("/history.rss", limiter.middleware(), r.handleHistoryFeed)
+}
+
+// publicNowPlaying returns the now playing state of the guild if it's public.
+func (r *Rest) publicNowPlaying(guildID string) (NowPlaying, bool) {
+	guild, exists := r.Guilds.Guild(guildID)
+	if !exists || !guild.NowPlayingPublic() {
+		return NowPlaying{}, false
+	}
+
+	state := guild.State()
+	nowPlaying := NowPlaying{Status: state.Status.String()}
+
+	if song := state.CurrentSong; song != nil {
+		nowPlaying.Title = song.Title
+		nowPlaying.URL = song.UserURL
+		nowPlaying.Duration = song.Duration.Seconds()
+		nowPlaying.Position = state.Position.Seconds()
+	}
+
+	return nowPlaying, true
+}
+
+// renderBadge draws a flat two part badge in the style of shields.io.
+func renderBadge(label, message string) string {
+	if utf8.RuneCountInString(message) > badgeTitleLength {
+		message = string([]rune(message)[:badgeTitleLength-1]) + "…"
+	}
+
+	// Verdana 11px averages about 7px per character
+	labelWidth := utf8.RuneCountInString(label)*7 + 10
+	messageWidth := utf8.RuneCountInString(message)*7 + 10
+	width := labelWidth + messageWidth
+
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]v: %[5]v">`+
+		`<title>%[4]v: %[5]v</title>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="#9f00d4"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[6]d" y="14">%[4]v</text><text x="%[7]d" y="14">%[5]v</text></g></svg>`,
+		width, labelWidth, messageWidth, label, message, labelWidth/2, labelWidth+messageWidth/2)
+}
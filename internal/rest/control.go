@@ -0,0 +1,182 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// PlayerNowPlaying is the current song of a guild player with what only its controllers see.
+type PlayerNowPlaying struct {
+	NowPlaying
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	RequesterID string `json:"requester_id,omitempty"`
+	Streaming   bool   `json:"streaming"`
+}
+
+// QueuedSong is a song of the queue of a guild player, at its position counting from 1.
+type QueuedSong struct {
+	Position int     `json:"position"`
+	Title    string  `json:"title"`
+	URL      string  `json:"url,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// PlayerQueue is the queue of a guild player, Songs may hold only the first songs for guilds run elsewhere.
+type PlayerQueue struct {
+	Songs []QueuedSong `json:"songs"`
+	Size  int          `json:"size"`
+}
+
+// registerControlRoutes registers the routes remote controlling the player of a guild. They aren't held back by
+// the queue lock: guildAuthMiddleware only lets through the admin token, tokens administrators created for the
+// guild and dashboard sessions of administrators and DJs, who may change a locked queue anyway.
+// http://localhost:8080/guilds/897053062030585916/play?query=never+gonna+give+you+up
+// http://localhost:8080/guilds/897053062030585916/queue
+// http://localhost:8080/guilds/897053062030585916/queue/3
+// http://localhost:8080/guilds/897053062030585916/nowplaying
+func (r *Rest) registerControlRoutes(router *gin.RouterGroup) {
+	router.POST("/play", func(ctx *gin.Context) {
+		var body struct {
+			Query     string `json:"query"`
+			ChannelID string `json:"channel_id"`
+		}
+		query, channelID := ctx.Query("query"), ctx.Query("channel_id")
+		if query == "" && ctx.ShouldBindJSON(&body) == nil {
+			query, channelID = body.Query, body.ChannelID
+		}
+
+		if query == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Query not provided"})
+			return
+		}
+
+		guild, exists := r.Guilds.Guild(ctx.Param("guild_id"))
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		// Joining first lets a play request start playback without a separate join
+		if channelID != "" && !guild.State().Streaming {
+			if err := guild.JoinVoiceChannel(channelID); err != nil {
+				if errors.Is(err, discord.ErrNotVoiceChannel) {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": "Channel is not a voice channel of the guild"})
+					return
+				}
+				slog.Warnf("Error joining voice channel to play: %v", err)
+			}
+		}
+
+		songs, err := guild.EnqueueQuery(query)
+		if err != nil {
+			slog.Warnf("Error enqueuing query: %v", err)
+			respondEnqueueError(ctx, err)
+			return
+		}
+
+		queued := make([]QueuedSong, 0, len(songs))
+		for _, song := range songs {
+			queued = append(queued, QueuedSong{Title: song.Title, URL: song.UserURL, Duration: song.Duration.Seconds()})
+		}
+		ctx.JSON(http.StatusOK, gin.H{"message": "Songs added to the queue or started playing", "songs": queued})
+	})
+
+	r.registerControlAction(router, "/pause", "Playback paused", Guild.Pause)
+	r.registerControlAction(router, "/resume", "Playback resumed", Guild.Resume)
+	r.registerControlAction(router, "/skip", "Song skipped", Guild.Skip)
+	r.registerControlAction(router, "/stop", "Playback stopped", Guild.Stop)
+
+	router.GET("/queue", func(ctx *gin.Context) {
+		guild, exists := r.Guilds.Guild(ctx.Param("guild_id"))
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		state := guild.State()
+		queue := PlayerQueue{Songs: make([]QueuedSong, 0, len(state.Queue)), Size: state.QueueSize}
+		for i, song := range state.Queue {
+			queue.Songs = append(queue.Songs, QueuedSong{Position: i + 1, Title: song.Title, URL: song.UserURL, Duration: song.Duration.Seconds()})
+		}
+
+		ctx.JSON(http.StatusOK, queue)
+	})
+
+	router.DELETE("/queue/:position", func(ctx *gin.Context) {
+		position, err := strconv.Atoi(ctx.Param("position"))
+		if err != nil || position < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Position must be a number from 1"})
+			return
+		}
+
+		guild, exists := r.Guilds.Guild(ctx.Param("guild_id"))
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		song, err := guild.RemoveFromQueue(position)
+		switch {
+		case errors.Is(err, player.ErrQueuePosition):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No song at this position of the queue"})
+			return
+		case err != nil:
+			slog.Errorf("Error removing song %v from the queue: %v", position, err)
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+			return
+		}
+
+		removed := QueuedSong{Position: position}
+		if song != nil {
+			removed.Title, removed.URL, removed.Duration = song.Title, song.UserURL, song.Duration.Seconds()
+		}
+		ctx.JSON(http.StatusOK, gin.H{"message": "Song removed from the queue", "song": removed})
+	})
+
+	router.GET("/nowplaying", func(ctx *gin.Context) {
+		guild, exists := r.Guilds.Guild(ctx.Param("guild_id"))
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		state := guild.State()
+		nowPlaying := PlayerNowPlaying{NowPlaying: NowPlaying{Status: state.Status.String()}, Streaming: state.Streaming}
+		if song := state.CurrentSong; song != nil {
+			nowPlaying.Title = song.Title
+			nowPlaying.URL = song.UserURL
+			nowPlaying.Duration = song.Duration.Seconds()
+			nowPlaying.Position = state.Position.Truncate(time.Millisecond).Seconds()
+			nowPlaying.Thumbnail = song.Thumbnail.URL
+			nowPlaying.RequesterID = song.RequesterID
+		}
+
+		ctx.JSON(http.StatusOK, nowPlaying)
+	})
+}
+
+// registerControlAction registers a POST route running a player action of the guild.
+func (r *Rest) registerControlAction(router *gin.RouterGroup, path, message string, action func(Guild) error) {
+	router.POST(path, func(ctx *gin.Context) {
+		guild, exists := r.Guilds.Guild(ctx.Param("guild_id"))
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		if err := action(guild); err != nil {
+			slog.Errorf("Error running %v: %v", path, err)
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": message})
+	})
+}
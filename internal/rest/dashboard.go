@@ -0,0 +1,290 @@
+package rest
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// registerAuthRoutes registers Discord OAuth2 login routes of the dashboard.
+// http://localhost:8080/auth/login
+// http://localhost:8080/auth/callback (redirect URL of the Discord application)
+// http://localhost:8080/auth/logout (POST)
+// http://localhost:8080/auth/me
+func (r *Rest) registerAuthRoutes(router *gin.RouterGroup) {
+	router.GET("/login", func(ctx *gin.Context) {
+		state, err := randomHex(16)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+			return
+		}
+
+		r.setCookie(ctx, stateCookie, state, stateTTL)
+		ctx.Redirect(http.StatusFound, r.oauth.authorizeURL(r.baseURL(ctx)+"/auth/callback", state))
+	})
+
+	router.GET("/callback", func(ctx *gin.Context) {
+		state, err := ctx.Cookie(stateCookie)
+		if err != nil || state == "" || ctx.Query("state") != state {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid login state, please try again"})
+			return
+		}
+		r.setCookie(ctx, stateCookie, "", -1)
+
+		code := ctx.Query("code")
+		if code == "" {
+			// The user denied the authorization
+			ctx.Redirect(http.StatusFound, "/dashboard")
+			return
+		}
+
+		token, err := r.oauth.exchangeCode(code, r.baseURL(ctx)+"/auth/callback")
+		if err != nil {
+			slog.Warnf("Error exchanging Discord authorization code: %v", err)
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to log in with Discord"})
+			return
+		}
+
+		user, err := r.oauth.currentUser(token.AccessToken)
+		if err != nil {
+			slog.Warnf("Error getting Discord user: %v", err)
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to log in with Discord"})
+			return
+		}
+
+		if err := r.startSession(ctx, user, token); err != nil {
+			slog.Errorf("Error starting dashboard session: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+			return
+		}
+
+		ctx.Redirect(http.StatusFound, "/dashboard")
+	})
+
+	router.POST("/logout", func(ctx *gin.Context) {
+		if !r.sameOrigin(ctx) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Cross-origin request"})
+			return
+		}
+
+		r.endSession(ctx)
+		ctx.Redirect(http.StatusSeeOther, "/dashboard")
+	})
+
+	router.GET("/me", func(ctx *gin.Context) {
+		session, ok := r.loadSession(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+			return
+		}
+
+		guilds, err := r.accessibleGuilds(session)
+		if err != nil {
+			slog.Warnf("Error getting guilds of user %v: %v", session.UserID, err)
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get guilds from Discord"})
+			return
+		}
+
+		guildInfos := []GuildInfo{}
+		for _, guild := range guilds {
+			guildInfos = append(guildInfos, GuildInfo{GuildID: guild.ID})
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"user_id": session.UserID, "username": session.Username, "guilds": guildInfos})
+	})
+}
+
+// registerDashboardRoutes registers the web dashboard.
+// http://localhost:8080/dashboard
+// http://localhost:8080/dashboard/metrics
+// http://localhost:8080/dashboard/guilds/897053062030585916/pause (POST)
+func (r *Rest) registerDashboardRoutes(router *gin.RouterGroup) {
+	router.GET("", func(ctx *gin.Context) {
+		session, ok := r.loadSession(ctx)
+		if !ok {
+			r.renderDashboard(ctx, dashboardPage{})
+			return
+		}
+
+		guilds, err := r.accessibleGuilds(session)
+		if err != nil {
+			slog.Warnf("Error getting guilds of user %v: %v", session.UserID, err)
+		}
+
+		page := dashboardPage{Username: session.Username, LoggedIn: true, Failed: err != nil}
+		for _, guild := range guilds {
+			state := guild.Guild.State()
+			view := dashboardGuildView{
+				ID:          guild.ID,
+				Name:        guild.Name,
+				Status:      state.Status.String(),
+				StatusEmoji: state.Status.StringEmoji(),
+				QueueLength: state.QueueSize,
+				Playing:     state.Status == player.StatusPlaying,
+				Paused:      state.Status == player.StatusPaused,
+			}
+			if song := state.CurrentSong; song != nil {
+				view.Title = song.Title
+				view.URL = song.UserURL
+			}
+			page.Guilds = append(page.Guilds, view)
+		}
+
+		r.renderDashboard(ctx, page)
+	})
+
+	router.GET("/metrics", func(ctx *gin.Context) {
+		session, ok := r.loadSession(ctx)
+		if !ok {
+			ctx.Redirect(http.StatusFound, "/dashboard")
+			return
+		}
+
+		guilds, err := r.accessibleGuilds(session)
+		if err != nil {
+			slog.Warnf("Error getting guilds of user %v: %v", session.UserID, err)
+		}
+
+		page := metricsPage{Username: session.Username, Failed: err != nil}
+		for _, guild := range guilds {
+			status := guild.Guild.State().Status
+			view := metricsGuildView{
+				Name:        guild.Name,
+				StatusEmoji: status.StringEmoji(),
+				Status:      status.String(),
+			}
+
+			metrics, err := guild.Guild.Metrics()
+			if err != nil {
+				slog.Warnf("Error getting metrics of guild %v: %v", guild.ID, err)
+				view.StatusEmoji, view.Status = player.StatusError.StringEmoji(), "Unreachable"
+			}
+			view.Metrics = metrics
+
+			page.Guilds = append(page.Guilds, view)
+		}
+
+		r.renderMetrics(ctx, page)
+	})
+
+	router.POST("/guilds/:guild_id/:action", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		session, ok := r.loadSession(ctx)
+		if !ok {
+			ctx.Redirect(http.StatusSeeOther, "/dashboard")
+			return
+		}
+
+		if !r.sameOrigin(ctx) || !r.canAccessGuild(session, guildID) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to control this guild"})
+			return
+		}
+
+		guild, exists := r.Guilds.Guild(guildID)
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		var err error
+		switch status := guild.State().Status; ctx.Param("action") {
+		case "pause":
+			if status == player.StatusPlaying {
+				err = guild.Pause()
+			}
+		case "resume":
+			if status == player.StatusPaused {
+				err = guild.Resume()
+			}
+		case "skip":
+			err = guild.Skip()
+		default:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Unknown action"})
+			return
+		}
+		if err != nil {
+			slog.Warnf("Error running dashboard action %v in guild %v: %v", ctx.Param("action"), guildID, err)
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+			return
+		}
+
+		slog.Infof("Dashboard user %v (%v) used %v in guild %v", session.Username, session.UserID, ctx.Param("action"), guildID)
+		ctx.Redirect(http.StatusSeeOther, "/dashboard")
+	})
+}
+
+type dashboardPage struct {
+	AppName  string
+	Username string
+	LoggedIn bool
+	Failed   bool
+	Guilds   []dashboardGuildView
+}
+
+type dashboardGuildView struct {
+	ID          string
+	Name        string
+	Status      string
+	StatusEmoji string
+	Title       string
+	URL         string
+	QueueLength int
+	Playing     bool
+	Paused      bool
+}
+
+func (r *Rest) renderDashboard(ctx *gin.Context, page dashboardPage) {
+	page.AppName = version.AppFullName
+
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+	if err := dashboardTemplate.Execute(ctx.Writer, page); err != nil {
+		slog.Errorf("Error rendering dashboard: %v", err)
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.AppName}}</title>
+<style>
+body { font-family: sans-serif; background: #1e1f22; color: #dbdee1; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; }
+h1 { color: #c77dff; font-size: 1.4rem; }
+a { color: #c77dff; }
+.guild { background: #2b2d31; border-left: 4px solid #9f00d4; border-radius: 4px; padding: 0.8rem 1rem; margin: 1rem 0; }
+.guild h2 { margin: 0 0 0.4rem; font-size: 1.1rem; }
+form { display: inline; }
+button { background: #9f00d4; color: #fff; border: 0; border-radius: 3px; padding: 0.3rem 0.8rem; margin-right: 0.3rem; cursor: pointer; }
+.muted { color: #949ba4; }
+</style>
+</head>
+<body>
+<h1>{{.AppName}}</h1>
+{{if not .LoggedIn}}
+<p><a href="/auth/login">Log in with Discord</a> to control the player on servers where you have Manage Server or the DJ role.</p>
+{{else}}
+<p>Logged in as {{.Username}} · <a href="/dashboard/metrics">Metrics</a> <form method="post" action="/auth/logout"><button>Log out</button></form></p>
+{{if .Failed}}<p class="muted">Your servers could not be loaded from Discord, please reload the page in a minute.</p>{{end}}
+{{range .Guilds}}
+<div class="guild">
+<h2>{{.Name}}</h2>
+<p>{{.StatusEmoji}} {{.Status}}{{if .Title}}: <a href="{{.URL}}">{{.Title}}</a>{{end}} <span class="muted">· {{.QueueLength}} in queue</span></p>
+{{if .Playing}}<form method="post" action="/dashboard/guilds/{{.ID}}/pause"><button>Pause</button></form>{{end}}
+{{if .Paused}}<form method="post" action="/dashboard/guilds/{{.ID}}/resume"><button>Resume</button></form>{{end}}
+{{if .Title}}<form method="post" action="/dashboard/guilds/{{.ID}}/skip"><button>Skip</button></form>{{end}}
+</div>
+{{else}}
+{{if not .Failed}}<p class="muted">No servers to control. You need Manage Server or the DJ role on a server with {{$.AppName}}.</p>{{end}}
+{{end}}
+{{end}}
+</body>
+</html>
+`))
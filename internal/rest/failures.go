@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+// maxFailureDays limits the days of the failures route.
+const maxFailureDays = 30
+
+// SourceFailure is the count of failures of a source at a stage with an error class within a day.
+type SourceFailure struct {
+	Day    string `json:"day"` // UTC date, e.g. 2024-01-31
+	Source string `json:"source"`
+	Stage  string `json:"stage"`
+	Class  string `json:"class"`
+	Count  int64  `json:"count"`
+}
+
+// registerFailureRoutes registers the failure report route of a guild.
+// http://localhost:8080/guilds/897053062030585916/failures?days=7
+func (r *Rest) registerFailureRoutes(router *gin.RouterGroup) {
+	router.GET("/failures", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		days, err := strconv.Atoi(ctx.DefaultQuery("days", "7"))
+		if err != nil || days < 1 || days > maxFailureDays {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 30"})
+			return
+		}
+
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		failures, err := history.NewHistory().GetFailures(guildID, today.AddDate(0, 0, 1-days))
+		if err != nil {
+			slog.Errorf("Error getting failures of guild %v: %v", guildID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting failures"})
+			return
+		}
+
+		report := make([]SourceFailure, 0, len(failures))
+		for _, failure := range failures {
+			report = append(report, SourceFailure{
+				Day:    failure.Day.UTC().Format("2006-01-02"),
+				Source: failure.Source,
+				Stage:  failure.Stage,
+				Class:  failure.Class,
+				Count:  failure.Count,
+			})
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"guild_id": guildID, "days": days, "failures": report})
+	})
+}
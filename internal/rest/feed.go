@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+const feedItemsLimit = 25
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Generator     string    `xml:"generator"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	TTL           int       `xml:"ttl"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// handleHistoryFeed serves recently played tracks of a guild as an RSS 2.0 feed.
+func (r *Rest) handleHistoryFeed(ctx *gin.Context) {
+	guildID := ctx.Param("guild_id")
+
+	guild, exists := r.Guilds.Guild(guildID)
+	if !exists || !guild.HistoryPublic() {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found or history is not public"})
+		return
+	}
+
+	entries, err := history.NewHistory().GetHistory(ctx.Request.Context(), guildID, "last_played", feedItemsLimit, 0)
+	if err != nil {
+		slog.Errorf("Error retrieving history for feed: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
+		return
+	}
+
+	guildName := guild.Name()
+
+	feed := rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         fmt.Sprintf("%v — %v", version.AppName, guildName),
+			Link:          r.baseURL(ctx) + ctx.Request.URL.Path,
+			Description:   "Recently played tracks on " + guildName,
+			Generator:     version.AppFullName,
+			LastBuildDate: time.Now().Format(time.RFC1123Z),
+			TTL:           15,
+		},
+	}
+
+	for _, entry := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       entry.Track.Name,
+			Link:        entry.Track.URL,
+			Description: fmt.Sprintf("Played %d times", entry.History.PlayCount),
+			PubDate:     entry.History.LastPlayed.Format(time.RFC1123Z),
+			// A replayed track gets a new GUID so feed readers show it again
+			GUID: rssGUID{Value: fmt.Sprintf("%v-%v-%v", guildID, entry.History.ID, entry.History.LastPlayed.Unix())},
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate feed"})
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// ErrGuildUnreachable is returned when the worker running a guild doesn't answer.
+var ErrGuildUnreachable = errors.New("the worker running the guild didn't answer")
+
+// Guilds are the guild players served by the API.
+type Guilds interface {
+	Guild(guildID string) (Guild, bool)
+	GuildIDs() []string
+}
+
+// Guild is a guild player served by the API, run by this process or by a bot worker elsewhere.
+type Guild interface {
+	Name() string
+	Active() bool
+	NowPlayingPublic() bool
+	HistoryPublic() bool
+	HasDJRole(userID string) bool
+	State() GuildState
+	Metrics() (player.Metrics, error)
+	Diagnostics() (discord.Diagnostics, error)
+	Pause() error
+	Resume() error
+	Skip() error
+	Stop() error
+	RemoveFromQueue(position int) (*player.Song, error)
+	EnqueueQuery(query string) ([]*player.Song, error)
+	JoinVoiceChannel(channelID string) error
+	LeaveVoiceChannel() (bool, error)
+}
+
+// GuildState is a snapshot of a guild player.
+type GuildState struct {
+	Status      player.PlaybackStatus
+	CurrentSong *player.Song
+	Position    time.Duration
+	Queue       []*player.Song // the first songs of the queue for guilds run elsewhere, see QueueSize
+	QueueSize   int
+	Streaming   bool // audio is being sent to a voice channel, also while paused
+}
+
+// localGuilds are the guilds run by this process.
+type localGuilds struct {
+	botInstances *discord.BotInstances
+}
+
+// NewLocalGuilds serves the guilds of the bot instances of this process.
+func NewLocalGuilds(botInstances *discord.BotInstances) Guilds {
+	return localGuilds{botInstances: botInstances}
+}
+
+func (lg localGuilds) Guild(guildID string) (Guild, bool) {
+	instance, exists := lg.botInstances.Get(guildID)
+	if !exists {
+		return nil, false
+	}
+	return localGuild{id: guildID, melodix: instance.Melodix}, true
+}
+
+func (lg localGuilds) GuildIDs() []string {
+	return lg.botInstances.GuildIDs()
+}
+
+// localGuild is a guild run by this process.
+type localGuild struct {
+	id      string
+	melodix *discord.Discord
+}
+
+func (lg localGuild) Name() string {
+	if guild, err := lg.melodix.Session.State.Guild(lg.id); err == nil {
+		return guild.Name
+	}
+	return lg.id
+}
+
+func (lg localGuild) Active() bool {
+	return lg.melodix.InstanceActive
+}
+
+func (lg localGuild) NowPlayingPublic() bool {
+	return lg.melodix.IsNowPlayingPublic()
+}
+
+func (lg localGuild) HistoryPublic() bool {
+	return lg.melodix.IsHistoryPublic()
+}
+
+func (lg localGuild) HasDJRole(userID string) bool {
+	return lg.melodix.HasDJRole(userID)
+}
+
+func (lg localGuild) State() GuildState {
+	p := lg.melodix.Player
+	queue := p.GetSongQueue()
+
+	return GuildState{
+		Status:      p.GetCurrentStatus(),
+		CurrentSong: p.GetCurrentSong(),
+		Position:    p.GetPlaybackPosition(),
+		Queue:       queue,
+		QueueSize:   len(queue),
+		Streaming:   p.GetStreamingSession() != nil,
+	}
+}
+
+func (lg localGuild) Metrics() (player.Metrics, error) {
+	return lg.melodix.Player.GetMetrics(), nil
+}
+
+func (lg localGuild) Diagnostics() (discord.Diagnostics, error) {
+	return lg.melodix.Diagnostics(), nil
+}
+
+func (lg localGuild) Pause() error {
+	lg.melodix.Player.Pause()
+	return nil
+}
+
+func (lg localGuild) Resume() error {
+	lg.melodix.Player.Unpause()
+	return nil
+}
+
+func (lg localGuild) Skip() error {
+	lg.melodix.Player.Skip()
+	return nil
+}
+
+func (lg localGuild) Stop() error {
+	lg.melodix.StopPlayback()
+	return nil
+}
+
+func (lg localGuild) RemoveFromQueue(position int) (*player.Song, error) {
+	return lg.melodix.Player.RemoveFromQueue(position)
+}
+
+func (lg localGuild) EnqueueQuery(query string) ([]*player.Song, error) {
+	return lg.melodix.EnqueueQuery(query)
+}
+
+func (lg localGuild) JoinVoiceChannel(channelID string) error {
+	return lg.melodix.JoinVoiceChannel(channelID)
+}
+
+func (lg localGuild) LeaveVoiceChannel() (bool, error) {
+	return lg.melodix.LeaveVoiceChannel(), nil
+}
@@ -0,0 +1,147 @@
+package rest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"gorm.io/gorm"
+)
+
+// maxHookBodySize limits inbound webhook payloads.
+const maxHookBodySize = 64 << 10
+
+// registerHookRoutes registers inbound webhook routes.
+// http://localhost:8080/hooks/3f2a... (POST, signed JSON body)
+func (r *Rest) registerHookRoutes(router *gin.RouterGroup) {
+	router.POST("/:token", func(ctx *gin.Context) {
+		webhook, err := db.GetWebhookByToken(ctx.Param("token"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxHookBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Body is larger than %v KB", maxHookBodySize>>10)})
+				return
+			}
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+			return
+		}
+
+		if !validSignature(body, webhook.Secret, ctx.GetHeader("X-Melodix-Signature")) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			return
+		}
+
+		var payload interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Body is not valid JSON"})
+			return
+		}
+
+		query, ok := lookupJSONPath(payload, webhook.QueryPath)
+		if !ok || strings.TrimSpace(query) == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("No song found at %q", webhook.QueryPath)})
+			return
+		}
+
+		guild, exists := r.Guilds.Guild(webhook.GuildID)
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		requester, _ := lookupJSONPath(payload, webhook.RequesterPath)
+		slog.Infof("Webhook %v requested %q for guild %v (requester: %q)", webhook.Token, query, webhook.GuildID, requester)
+
+		songs, err := guild.EnqueueQuery(query)
+		if err != nil {
+			respondEnqueueError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Songs added to the queue", "songs": songs})
+	})
+}
+
+// respondEnqueueError answers a request whose songs couldn't be queued.
+func respondEnqueueError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, discord.ErrNotInVoice):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Bot is not in a voice channel"})
+	case errors.Is(err, discord.ErrNoSongs):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No music found"})
+	case errors.Is(err, ErrGuildUnreachable):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enqueue"})
+	}
+}
+
+// validSignature checks a "sha256=<hex>" HMAC signature of the body.
+func validSignature(body []byte, secret, signature string) bool {
+	signature, found := strings.CutPrefix(signature, "sha256=")
+	if !found {
+		return false
+	}
+
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(received, mac.Sum(nil))
+}
+
+// lookupJSONPath returns the value at a dot separated path (e.g. "data.items.0.name") as a string.
+func lookupJSONPath(value interface{}, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return "", false
+			}
+			value = v[index]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
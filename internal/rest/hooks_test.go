@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+func sign(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignature(t *testing.T) {
+	body := `{"song":"never gonna give you up"}`
+	valid := sign(body, "secret")
+
+	tests := []struct {
+		name      string
+		body      string
+		secret    string
+		signature string
+		want      bool
+	}{
+		{"valid", body, "secret", valid, true},
+		{"uppercase hex", body, "secret", "sha256=" + strings.ToUpper(strings.TrimPrefix(valid, "sha256=")), true},
+		{"other secret", body, "other", valid, false},
+		{"tampered body", body + " ", "secret", valid, false},
+		{"no prefix", body, "secret", strings.TrimPrefix(valid, "sha256="), false},
+		{"other algorithm", body, "secret", "sha1=" + strings.TrimPrefix(valid, "sha256="), false},
+		{"not hex", body, "secret", "sha256=zz", false},
+		{"truncated", body, "secret", valid[:len(valid)-2], false},
+		{"empty", body, "secret", "", false},
+	}
+
+	for _, test := range tests {
+		if got := validSignature([]byte(test.body), test.secret, test.signature); got != test.want {
+			t.Errorf("%v: validSignature = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	var payload interface{}
+	err := json.Unmarshal([]byte(`{
+		"song": "title",
+		"data": {"items": [{"name": "first"}, {"name": "second", "id": 42, "rating": 4.5}]},
+		"empty": "",
+		"flag": true,
+		"nothing": null,
+		"dotted.key": "unreachable"
+	}`), &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		value string
+		ok    bool
+	}{
+		{"song", "title", true},
+		{"data.items.0.name", "first", true},
+		{"data.items.1.name", "second", true},
+		{"data.items.1.id", "42", true},
+		{"data.items.1.rating", "4.5", true},
+		{"empty", "", true},
+		{"", "", false},
+		{"missing", "", false},
+		{"data", "", false},
+		{"data.items", "", false},
+		{"data.items.2.name", "", false},
+		{"data.items.-1.name", "", false},
+		{"data.items.first.name", "", false},
+		{"song.name", "", false},
+		{"flag", "", false},
+		{"nothing", "", false},
+		{"dotted.key", "", false},
+	}
+
+	for _, test := range tests {
+		value, ok := lookupJSONPath(payload, test.path)
+		if value != test.value || ok != test.ok {
+			t.Errorf("lookupJSONPath(%q) = %q, %v, want %q, %v", test.path, value, ok, test.value, test.ok)
+		}
+	}
+}
+
+func TestHookRequests(t *testing.T) {
+	if _, err := db.InitDB(filepath.Join(t.TempDir(), "melodix.db"), db.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateGuild(db.Guild{ID: "1", Name: "guild"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateWebhook(&db.Webhook{Token: "token", GuildID: "1", Secret: "secret", QueryPath: "song"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	(&Rest{}).registerHookRoutes(router.Group("/hooks"))
+
+	large := `{"song":"` + strings.Repeat("a", maxHookBodySize) + `"}`
+	tests := []struct {
+		name      string
+		token     string
+		body      string
+		signature string
+		status    int
+	}{
+		{"unknown token", "other", `{"song":"title"}`, sign(`{"song":"title"}`, "secret"), http.StatusNotFound},
+		{"too large", "token", large, sign(large, "secret"), http.StatusRequestEntityTooLarge},
+		{"unsigned", "token", `{"song":"title"}`, "", http.StatusUnauthorized},
+		{"wrong signature", "token", `{"song":"title"}`, sign(`{"song":"title"}`, "other"), http.StatusUnauthorized},
+		{"not JSON", "token", `song`, sign(`song`, "secret"), http.StatusBadRequest},
+		{"no song", "token", `{"title":"song"}`, sign(`{"title":"song"}`, "secret"), http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/hooks/"+test.token, strings.NewReader(test.body))
+		req.Header.Set("X-Melodix-Signature", test.signature)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%v: status %v, want %v (%v)", test.name, rec.Code, test.status, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
+)
+
+const (
+	listenInterval   = time.Second
+	listenQueueLimit = 25
+	listenWriteWait  = 10 * time.Second
+	// listenMaxViewers bounds the open state feeds of a link
+	listenMaxViewers = 20
+	// listenRevokeCheck is how often a link with open feeds is looked up, revoking deletes it
+	listenRevokeCheck = 10 * time.Second
+)
+
+// ListenState is the live view of a guild player sent to listen-along pages.
+type ListenState struct {
+	NowPlaying
+	Thumbnail string       `json:"thumbnail,omitempty"`
+	Queue     []ListenSong `json:"queue"`
+	QueueSize int          `json:"queue_size"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// ListenSong is a queued song of the listen-along view.
+type ListenSong struct {
+	Title    string  `json:"title"`
+	URL      string  `json:"url,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+var listenUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// registerListenRoutes registers the read-only listen-along pages of links created with the listen command.
+// http://localhost:8080/listen/0123456789abcdef0123456789abcdef
+// http://localhost:8080/listen/0123456789abcdef0123456789abcdef/ws (WebSocket state feed)
+func (r *Rest) registerListenRoutes(router *gin.RouterGroup) {
+	limiter := newRateLimiter(publicRateLimit, publicRateWindow)
+	viewers := newListenViewers()
+
+	router.GET("/:token", limiter.middleware(), func(ctx *gin.Context) {
+		link, ok := r.listenLink(ctx.Param("token"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Link not found or expired"})
+			return
+		}
+
+		page := listenPage{AppName: version.AppFullName, ExpiresAt: link.ExpiresAt.UTC().Format(time.RFC3339)}
+		if guild, exists := r.Guilds.Guild(link.GuildID); exists {
+			page.GuildName = guild.Name()
+		}
+
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		ctx.Header("Cache-Control", "no-store")
+		ctx.Header("Referrer-Policy", "no-referrer")
+		ctx.Status(http.StatusOK)
+		if err := listenTemplate.Execute(ctx.Writer, page); err != nil {
+			slog.Errorf("Error rendering listen page: %v", err)
+		}
+	})
+
+	router.GET("/:token/ws", limiter.middleware(), func(ctx *gin.Context) {
+		link, ok := r.listenLink(ctx.Param("token"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Link not found or expired"})
+			return
+		}
+
+		if !viewers.join(link) {
+			ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many viewers of this link"})
+			return
+		}
+		defer viewers.leave(link)
+
+		conn, err := listenUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			// The upgrader already answered with an error status
+			slog.Debugf("Error upgrading listen connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		r.streamListenState(conn, link, viewers)
+	})
+}
+
+// listenLink returns the link of a token if it's unexpired and its guild is running.
+func (r *Rest) listenLink(token string) (*db.ListenLink, bool) {
+	link, err := db.GetListenLinkByHash(db.HashSecret(token))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Errorf("Error getting listen link: %v", err)
+		}
+		return nil, false
+	}
+
+	if time.Now().After(link.ExpiresAt) {
+		return nil, false
+	}
+	if _, exists := r.Guilds.Guild(link.GuildID); !exists {
+		return nil, false
+	}
+
+	return link, true
+}
+
+// streamListenState sends the player state whenever it changes until the client leaves,
+// the link expires or is revoked.
+func (r *Rest) streamListenState(conn *websocket.Conn, link *db.ListenLink, viewers *listenViewers) {
+	// The page never sends anything, reading only notices the client closing the connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(listenInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		if time.Now().After(link.ExpiresAt) {
+			closeListen(conn, "Link expired")
+			return
+		}
+
+		state, err := json.Marshal(r.listenState(link))
+		if err != nil {
+			slog.Errorf("Error encoding listen state: %v", err)
+			return
+		}
+
+		if !bytes.Equal(state, last) {
+			conn.SetWriteDeadline(time.Now().Add(listenWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, state); err != nil {
+				return
+			}
+			last = state
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		// Revoking deletes the link, the page must stop updating then
+		if !viewers.exists(link) {
+			closeListen(conn, "Link revoked")
+			return
+		}
+	}
+}
+
+// listenViewers counts the open state feeds of each link and shares looking up whether a link was revoked among
+// the feeds of the link, so they don't each query the database every second.
+type listenViewers struct {
+	mu      sync.Mutex
+	feeds   map[uint]int
+	checked map[uint]listenCheck
+}
+
+// listenCheck is the latest lookup of a link.
+type listenCheck struct {
+	at     time.Time
+	exists bool
+}
+
+func newListenViewers() *listenViewers {
+	return &listenViewers{
+		feeds:   make(map[uint]int),
+		checked: make(map[uint]listenCheck),
+	}
+}
+
+// join counts a new feed of the link, false if the link has as many as it may.
+func (v *listenViewers) join(link *db.ListenLink) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.feeds[link.ID] >= listenMaxViewers {
+		return false
+	}
+	v.feeds[link.ID]++
+	return true
+}
+
+// leave stops counting a feed of the link, the lookups of the link are forgotten with its last feed.
+func (v *listenViewers) leave(link *db.ListenLink) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.feeds[link.ID]--
+	if v.feeds[link.ID] <= 0 {
+		delete(v.feeds, link.ID)
+		delete(v.checked, link.ID)
+	}
+}
+
+// exists reports whether the link is still stored, looking it up at most every listenRevokeCheck.
+// Database errors count as stored, so an outage doesn't close every page.
+func (v *listenViewers) exists(link *db.ListenLink) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if check, ok := v.checked[link.ID]; ok && time.Since(check.at) < listenRevokeCheck {
+		return check.exists
+	}
+
+	_, err := db.GetListenLinkByHash(link.TokenHash)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.Warnf("Error checking listen link: %v", err)
+	}
+	exists := !errors.Is(err, gorm.ErrRecordNotFound)
+	v.checked[link.ID] = listenCheck{at: time.Now(), exists: exists}
+	return exists
+}
+
+func closeListen(conn *websocket.Conn, reason string) {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(listenWriteWait))
+}
+
+// listenState returns the now playing song and the upcoming queue of the link's guild,
+// shown as offline while no worker runs it.
+func (r *Rest) listenState(link *db.ListenLink) ListenState {
+	state := ListenState{
+		NowPlaying: NowPlaying{Status: "Offline"},
+		Queue:      []ListenSong{},
+		ExpiresAt:  link.ExpiresAt.UTC(),
+	}
+
+	guild, exists := r.Guilds.Guild(link.GuildID)
+	if !exists {
+		return state
+	}
+
+	snapshot := guild.State()
+	state.Status = snapshot.Status.String()
+
+	if song := snapshot.CurrentSong; song != nil {
+		state.Title = song.Title
+		state.URL = song.UserURL
+		state.Thumbnail = song.Thumbnail.URL
+		state.Duration = song.Duration.Seconds()
+		// Whole seconds, so the state only changes once per second while playing
+		state.Position = snapshot.Position.Truncate(time.Second).Seconds()
+	}
+
+	state.QueueSize = snapshot.QueueSize
+	for i, song := range snapshot.Queue {
+		if i == listenQueueLimit {
+			break
+		}
+		state.Queue = append(state.Queue, ListenSong{Title: song.Title, URL: song.UserURL, Duration: song.Duration.Seconds()})
+	}
+
+	return state
+}
+
+type listenPage struct {
+	AppName   string
+	GuildName string
+	ExpiresAt string
+}
+
+var listenTemplate = template.Must(template.New("listen").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .GuildName}}{{.GuildName}} · {{end}}{{.AppName}}</title>
+<style>
+body { font-family: sans-serif; background: #1e1f22; color: #dbdee1; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; }
+h1 { color: #c77dff; font-size: 1.4rem; }
+a { color: #c77dff; }
+.now { background: #2b2d31; border-left: 4px solid #9f00d4; border-radius: 4px; padding: 0.8rem 1rem; margin: 1rem 0; display: flex; gap: 1rem; align-items: center; }
+.now img { width: 8rem; border-radius: 4px; }
+.now h2 { margin: 0 0 0.4rem; font-size: 1.1rem; }
+.bar { background: #404249; border-radius: 2px; height: 4px; margin-top: 0.6rem; }
+.bar div { background: #9f00d4; border-radius: 2px; height: 4px; width: 0; }
+ol { padding-left: 1.4rem; }
+li { margin: 0.3rem 0; }
+.muted { color: #949ba4; }
+</style>
+</head>
+<body>
+<h1>🎧 {{if .GuildName}}{{.GuildName}}{{else}}{{.AppName}}{{end}}</h1>
+<div class="now">
+<img id="thumbnail" alt="" hidden>
+<div>
+<h2 id="title">Connecting…</h2>
+<span id="status" class="muted"></span>
+<div class="bar"><div id="progress"></div></div>
+</div>
+</div>
+<h2>Up next</h2>
+<ol id="queue"></ol>
+<p id="more" class="muted"></p>
+<p class="muted">This link expires <span id="expires" data-time="{{.ExpiresAt}}"></span>.</p>
+<script>
+const clock = s => { s = Math.floor(s); const m = Math.floor(s / 60); return m + ":" + String(s % 60).padStart(2, "0"); };
+const link = (song) => { const el = document.createElement(song.url ? "a" : "span"); el.textContent = song.title; if (song.url) { el.href = song.url; el.rel = "noreferrer"; } return el; };
+const expires = document.getElementById("expires");
+expires.textContent = new Date(expires.dataset.time).toLocaleString();
+
+function render(state) {
+  const title = document.getElementById("title");
+  title.replaceChildren(state.title ? link(state) : document.createTextNode("Nothing playing"));
+  document.getElementById("status").textContent = state.status + (state.duration ? " · " + clock(state.position) + " / " + clock(state.duration) : "");
+  document.getElementById("progress").style.width = state.duration ? (100 * state.position / state.duration) + "%" : "0";
+  const thumbnail = document.getElementById("thumbnail");
+  thumbnail.hidden = !state.thumbnail;
+  if (state.thumbnail) thumbnail.src = state.thumbnail;
+  document.getElementById("queue").replaceChildren(...state.queue.map(song => {
+    const li = document.createElement("li");
+    li.append(link(song));
+    if (song.duration) li.append(" · " + clock(song.duration));
+    return li;
+  }));
+  const hidden = state.queue_size - state.queue.length;
+  document.getElementById("more").textContent = state.queue_size === 0 ? "The queue is empty" : hidden > 0 ? "and " + hidden + " more" : "";
+}
+
+function connect() {
+  const socket = new WebSocket(location.href.replace(/^http/, "ws").replace(/\/$/, "") + "/ws");
+  socket.onmessage = event => render(JSON.parse(event.data));
+  socket.onclose = event => {
+    if (event.reason) {
+      document.getElementById("title").textContent = event.reason;
+      return;
+    }
+    // Reconnect after network hiccups, expired links answer 404 and stop here
+    if (new Date(expires.dataset.time) > new Date()) setTimeout(connect, 5000);
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+func TestListenViewers(t *testing.T) {
+	if _, err := db.InitDB(filepath.Join(t.TempDir(), "melodix.db"), db.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateGuild(db.Guild{ID: "1", Name: "guild"}); err != nil {
+		t.Fatal(err)
+	}
+	link := &db.ListenLink{TokenHash: db.HashSecret("token"), GuildID: "1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.CreateListenLink(link); err != nil {
+		t.Fatal(err)
+	}
+	other := &db.ListenLink{ID: link.ID + 1}
+
+	viewers := newListenViewers()
+	for i := 0; i < listenMaxViewers; i++ {
+		if !viewers.join(link) {
+			t.Fatalf("Viewer %v was turned away", i+1)
+		}
+	}
+	if viewers.join(link) {
+		t.Errorf("Link got more than %v viewers", listenMaxViewers)
+	}
+	if !viewers.join(other) {
+		t.Errorf("Viewers of another link were counted")
+	}
+	viewers.leave(link)
+	if !viewers.join(link) {
+		t.Errorf("Leaving didn't free a place")
+	}
+
+	if !viewers.exists(link) {
+		t.Fatal("Stored link doesn't exist")
+	}
+	if _, err := db.DeleteListenLinks("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Feeds of the link share the latest lookup until it's due again
+	if !viewers.exists(link) {
+		t.Errorf("Link was looked up again before the check interval")
+	}
+	viewers.checked[link.ID] = listenCheck{at: time.Now().Add(-listenRevokeCheck)}
+	if viewers.exists(link) {
+		t.Errorf("Revoked link still exists")
+	}
+
+	for i := 0; i < listenMaxViewers; i++ {
+		viewers.leave(link)
+	}
+	if _, ok := viewers.feeds[link.ID]; ok {
+		t.Errorf("Feeds of a link without viewers are still counted")
+	}
+	if _, ok := viewers.checked[link.ID]; ok {
+		t.Errorf("Lookup of a link without viewers is still kept")
+	}
+}
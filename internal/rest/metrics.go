@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// metricsRefreshSeconds is how often the dashboard metrics page reloads itself.
+const metricsRefreshSeconds = 5
+
+// GuildMetrics are the playback metrics of a guild.
+type GuildMetrics struct {
+	GuildID string `json:"guild_id"`
+	Status  string `json:"status"`
+	player.Metrics
+	HistoryBuffer history.BufferMetrics  `json:"history_buffer"` // shared by all guilds
+	Commands      discord.CommandMetrics `json:"commands"`       // run by all guilds of this process
+}
+
+// registerMetricsRoutes registers the metrics and debug routes of a guild.
+// http://localhost:8080/guilds/897053062030585916/metrics
+// http://localhost:8080/guilds/897053062030585916/debug
+func (r *Rest) registerMetricsRoutes(router *gin.RouterGroup) {
+	router.GET("/metrics", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		guild, exists := r.Guilds.Guild(guildID)
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		metrics, err := guild.Metrics()
+		if err != nil {
+			slog.Warnf("Error getting metrics of guild %v: %v", guildID, err)
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+			return
+		}
+
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, GuildMetrics{GuildID: guildID, Status: guild.State().Status.String(), Metrics: metrics, HistoryBuffer: history.GetBufferMetrics(), Commands: discord.GetCommandMetrics()})
+	})
+
+	// The diagnostics of the debug command, for attaching to bug reports
+	router.GET("/debug", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		guild, exists := r.Guilds.Guild(guildID)
+		if !exists {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+			return
+		}
+
+		diagnostics, err := guild.Diagnostics()
+		if err != nil {
+			slog.Warnf("Error getting diagnostics of guild %v: %v", guildID, err)
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+			return
+		}
+
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, diagnostics)
+	})
+}
+
+type metricsPage struct {
+	AppName  string
+	Username string
+	Refresh  int
+	Failed   bool
+	Guilds   []metricsGuildView
+}
+
+type metricsGuildView struct {
+	Name        string
+	StatusEmoji string
+	Status      string
+	player.Metrics
+}
+
+// renderMetrics renders the dashboard page with metrics of the user's guilds.
+func (r *Rest) renderMetrics(ctx *gin.Context, page metricsPage) {
+	page.AppName = version.AppFullName
+	page.Refresh = metricsRefreshSeconds
+
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+	if err := metricsTemplate.Execute(ctx.Writer, page); err != nil {
+		slog.Errorf("Error rendering dashboard metrics: %v", err)
+	}
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 1.5 MiB.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+var metricsTemplate = template.Must(template.New("metrics").Funcs(template.FuncMap{
+	"bytes": formatBytes,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>Metrics · {{.AppName}}</title>
+<style>
+body { font-family: sans-serif; background: #1e1f22; color: #dbdee1; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; }
+h1 { color: #c77dff; font-size: 1.4rem; }
+a { color: #c77dff; }
+.guild { background: #2b2d31; border-left: 4px solid #9f00d4; border-radius: 4px; padding: 0.8rem 1rem; margin: 1rem 0; }
+.guild h2 { margin: 0 0 0.4rem; font-size: 1.1rem; }
+table { border-collapse: collapse; }
+td { padding: 0.1rem 1.2rem 0.1rem 0; }
+.muted { color: #949ba4; }
+</style>
+</head>
+<body>
+<h1>{{.AppName}} metrics</h1>
+<p>Logged in as {{.Username}} · <a href="/dashboard">Back to dashboard</a> <span class="muted">· refreshes every {{.Refresh}}s</span></p>
+{{if .Failed}}<p class="muted">Your servers could not be loaded from Discord, please reload the page in a minute.</p>{{end}}
+{{range .Guilds}}
+<div class="guild">
+<h2>{{.Name}}</h2>
+<p>{{.StatusEmoji}} {{.Status}}</p>
+<table>
+<tr><td>Encoder</td><td>{{if .EncoderRunning}}PID {{.EncoderPID}} · {{printf "%.1f" .EncoderCPU}}% CPU · {{bytes .EncoderMemory}}{{else}}<span class="muted">not running</span>{{end}}</td></tr>
+<tr><td>Streamed</td><td>{{bytes .BytesStreamed}} in {{.FramesSent}} frames</td></tr>
+<tr><td>Dropped frames</td><td>{{.FramesDropped}}</td></tr>
+<tr><td>Late frames</td><td>{{.FramesLate}}</td></tr>
+<tr><td>Send stalls</td><td>{{.SendStalls}}</td></tr>
+<tr><td>Jitter</td><td>{{printf "%.1f" .JitterMs}} ms · buffer of {{.BufferFrames}} frames</td></tr>
+<tr><td>Time to first frame</td><td>p50 {{printf "%.0f" .Latency.FirstFrameP50}} ms · p95 {{printf "%.0f" .Latency.FirstFrameP95}} ms <span class="muted">({{.Latency.Plays}} plays)</span></td></tr>
+<tr><td>Request to first frame</td><td>p50 {{printf "%.0f" .Latency.TotalP50}} ms · p95 {{printf "%.0f" .Latency.TotalP95}} ms <span class="muted">(resolution p95 {{printf "%.0f" .Latency.ResolutionP95}} ms, {{.Latency.Requests}} requests)</span></td></tr>
+<tr><td>Reconnects</td><td>{{.Reconnects}} to source, {{.Restarts}} playback restarts</td></tr>
+</table>
+</div>
+{{else}}
+{{if not .Failed}}<p class="muted">No servers to show. You need Manage Server or the DJ role on a server with {{$.AppName}}.</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+)
+
+// DefaultMiddleware is the middleware applied to every request when Options.Middleware is empty, in order.
+var DefaultMiddleware = []string{"recovery", "logger", "cors", "ratelimit", "bodylimit"}
+
+// middlewares builds the middleware selectable by name, nil means it's disabled by the options.
+var middlewares = map[string]func(r *Rest) gin.HandlerFunc{
+	"recovery": func(r *Rest) gin.HandlerFunc {
+		return recoveryMiddleware()
+	},
+	"logger": func(r *Rest) gin.HandlerFunc {
+		return loggerMiddleware()
+	},
+	"cors": func(r *Rest) gin.HandlerFunc {
+		if len(r.options.CORSOrigins) == 0 {
+			return nil
+		}
+		return corsMiddleware(r.options.CORSOrigins)
+	},
+	"ratelimit": func(r *Rest) gin.HandlerFunc {
+		if r.options.RateLimit <= 0 {
+			return nil
+		}
+		return newRateLimiter(r.options.RateLimit, time.Minute).middleware()
+	},
+	"bodylimit": func(r *Rest) gin.HandlerFunc {
+		if r.options.MaxBodyBytes <= 0 {
+			return nil
+		}
+		return bodyLimitMiddleware(r.options.MaxBodyBytes)
+	},
+}
+
+// useMiddleware adds the configured middleware to the router in the given order.
+func (r *Rest) useMiddleware(router *gin.Engine) error {
+	names := r.options.Middleware
+	if len(names) == 0 {
+		names = DefaultMiddleware
+	}
+
+	for _, name := range names {
+		build, ok := middlewares[name]
+		if !ok {
+			return fmt.Errorf("unknown REST middleware %q", name)
+		}
+		if handler := build(r); handler != nil {
+			router.Use(handler)
+		}
+	}
+
+	return nil
+}
+
+// loggerMiddleware writes an access log entry for every request.
+func loggerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		record := slog.WithData(slog.M{
+			"method":   ctx.Request.Method,
+			"path":     ctx.Request.URL.Path,
+			"status":   ctx.Writer.Status(),
+			"duration": time.Since(start).String(),
+			"ip":       ctx.ClientIP(),
+			"bytes":    ctx.Writer.Size(),
+		})
+
+		status := ctx.Writer.Status()
+		switch {
+		case status >= http.StatusInternalServerError:
+			record.Error("REST request failed")
+		case status >= http.StatusBadRequest:
+			record.Warn("REST request rejected")
+		default:
+			record.Info("REST request")
+		}
+	}
+}
+
+// recoveryMiddleware logs panics of handlers and answers with an internal server error.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				slog.Errorf("Panic serving %v %v: %v\n%s", ctx.Request.Method, ctx.Request.URL.Path, err, debug.Stack())
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+		}()
+
+		ctx.Next()
+	}
+}
+
+// corsMiddleware allows browsers on the given origins to call the API and answers preflight requests.
+func corsMiddleware(origins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" || (!allowed["*"] && !allowed[origin]) {
+			ctx.Next()
+			return
+		}
+
+		if allowed["*"] {
+			ctx.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			ctx.Header("Access-Control-Allow-Origin", origin)
+			ctx.Header("Vary", "Origin")
+		}
+
+		if ctx.Request.Method == http.MethodOptions {
+			ctx.Header("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			ctx.Header("Access-Control-Allow-Headers", "Content-Type, X-Melodix-Signature")
+			ctx.Header("Access-Control-Max-Age", "600")
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// bodyLimitMiddleware rejects request bodies larger than limit bytes.
+func bodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.ContentLength > limit {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+
+		// Bodies without a declared length fail to read past the limit
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		ctx.Next()
+	}
+}
+
+// rateLimiter allows a fixed number of requests per client IP in each time window.
+type rateLimiter struct {
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+func (rl *rateLimiter) allow(ip string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// Starting a new window also forgets all clients of the previous one
+	if time.Since(rl.windowStart) >= rl.window {
+		rl.windowStart = time.Now()
+		rl.counts = make(map[string]int)
+	}
+
+	rl.counts[ip]++
+	return rl.counts[ip] <= rl.limit
+}
+
+func (rl *rateLimiter) middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !rl.allow(ctx.ClientIP()) {
+			ctx.Header("Retry-After", fmt.Sprint(int(rl.window.Seconds())))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		ctx.Next()
+	}
+}
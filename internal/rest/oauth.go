@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	discordAuthorizeURL = "https://discord.com/oauth2/authorize"
+	discordAPIURL       = "https://discord.com/api/v10"
+	oauthScopes         = "identify guilds"
+)
+
+// oauthToken is a Discord OAuth2 token response.
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// discordUser is the part of the Discord user object the dashboard uses.
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+}
+
+// discordUserGuild is a guild the user is a member of, with the user's permissions in it.
+type discordUserGuild struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Owner       bool   `json:"owner"`
+	Permissions string `json:"permissions"`
+}
+
+// oauthClient talks to the Discord OAuth2 and user APIs on behalf of dashboard users.
+type oauthClient struct {
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+}
+
+func newOAuthClient(clientID, clientSecret string) *oauthClient {
+	return &oauthClient{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// authorizeURL returns the Discord consent page the user is sent to.
+func (c *oauthClient) authorizeURL(redirectURI, state string) string {
+	params := url.Values{
+		"client_id":     {c.clientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {oauthScopes},
+		"state":         {state},
+		"prompt":        {"none"},
+	}
+	return discordAuthorizeURL + "?" + params.Encode()
+}
+
+// exchangeCode trades the authorization code of the callback for tokens.
+func (c *oauthClient) exchangeCode(code, redirectURI string) (*oauthToken, error) {
+	return c.requestToken(url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+	})
+}
+
+// refresh obtains a new access token with a refresh token.
+func (c *oauthClient) refresh(refreshToken string) (*oauthToken, error) {
+	return c.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+func (c *oauthClient) requestToken(form url.Values) (*oauthToken, error) {
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+
+	resp, err := c.httpClient.Post(discordAPIURL+"/oauth2/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %v", resp.Status)
+	}
+
+	var token oauthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (c *oauthClient) currentUser(accessToken string) (*discordUser, error) {
+	var user discordUser
+	if err := c.get("/users/@me", accessToken, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *oauthClient) currentUserGuilds(accessToken string) ([]discordUserGuild, error) {
+	var guilds []discordUserGuild
+	if err := c.get("/users/@me/guilds", accessToken, &guilds); err != nil {
+		return nil, err
+	}
+	return guilds, nil
+}
+
+func (c *oauthClient) get(path, accessToken string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, discordAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v failed with status %v", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
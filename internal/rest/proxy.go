@@ -0,0 +1,75 @@
+package rest
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// baseURL returns the external scheme and host of the API as seen by the client.
+// Forwarded headers are only honored for requests coming from trusted proxies.
+func (r *Rest) baseURL(ctx *gin.Context) string {
+	if r.options.PublicURL != "" {
+		return r.options.PublicURL
+	}
+
+	scheme, host := "http", ctx.Request.Host
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+
+	if r.isTrustedProxy(ctx.RemoteIP()) {
+		if proto := firstHeaderValue(ctx.GetHeader("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost := firstHeaderValue(ctx.GetHeader("X-Forwarded-Host")); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	return scheme + "://" + host
+}
+
+func (r *Rest) isTrustedProxy(remoteIP string) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range r.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses IPs and CIDRs the same way gin does for SetTrustedProxies.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// firstHeaderValue returns the value set by the proxy closest to the client.
+func firstHeaderValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
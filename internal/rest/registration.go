@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// GuildRegistration is the activation state of a guild, inactive guilds ignore commands.
+type GuildRegistration struct {
+	GuildID    string `json:"guild_id"`
+	Name       string `json:"name"`
+	Registered bool   `json:"registered"`
+	Active     bool   `json:"active"`
+	Running    bool   `json:"running"` // whether the guild's instance is started
+}
+
+// registerRegistrationRoutes registers the activation state route of a guild.
+// http://localhost:8080/guilds/897053062030585916/registration
+func (r *Rest) registerRegistrationRoutes(router *gin.RouterGroup) {
+	router.GET("/registration", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		guild, err := db.GetGuildByID(guildID)
+		if err != nil {
+			slog.Errorf("Error getting guild %v: %v", guildID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get guild"})
+			return
+		}
+
+		_, running := r.Guilds.Guild(guildID)
+		registration := GuildRegistration{GuildID: guildID, Running: running}
+		if guild != nil {
+			registration.Name = guild.Name
+			registration.Registered = true
+			registration.Active = guild.IsActive()
+		}
+
+		ctx.JSON(http.StatusOK, registration)
+	})
+}
@@ -1,35 +1,84 @@
 package rest
 
 import (
+	"errors"
+	"fmt"
 	"io"
-	"math/rand"
-
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
 	"github.com/keshon/melodix-discord-player/music/discord"
 	"github.com/keshon/melodix-discord-player/music/history"
 	"github.com/keshon/melodix-discord-player/music/player"
-	"github.com/keshon/melodix-discord-player/music/sources"
+	"gorm.io/gorm"
 )
 
 // Rest is a struct representing the restful API for Melodix.
 type Rest struct {
-	BotInstances map[string]*discord.BotInstance
+	Guilds         Guilds
+	options        Options
+	trustedProxies []*net.IPNet
+	oauth          *oauthClient // nil if the dashboard is disabled
+	userGuildsMu   sync.Mutex
+	userGuilds     map[string]cachedUserGuilds // guilds of dashboard users by session ID
+	avatarsMu      sync.Mutex
+	avatars        map[string][]byte // resized avatars by content hash and size
+}
+
+// Options configure how the API is exposed.
+type Options struct {
+	PublicURL         string // external base URL, derived from requests if empty
+	AdminToken        string // grants access to the routes of every guild, in addition to guild API tokens
+	OAuthClientID     string // Discord application used for the dashboard login, empty disables the dashboard
+	OAuthClientSecret string
+	OwnerID           string   // Discord user who may access every guild on the dashboard
+	TrustedProxies    []string // IPs or CIDRs whose X-Forwarded-* headers are trusted
+	CORSOrigins       []string // origins allowed to call the API from browsers, "*" allows any
+	RateLimit         int      // requests per minute and client IP, 0 disables the limit
+	MaxBodyBytes      int64    // largest accepted request body, 0 disables the limit
+	Middleware        []string // names of middleware applied to every request in order, DefaultMiddleware if empty
+	LogPath           string   // log file served by the log routes
 }
 
 // NewRest creates a new instance of Rest.
-func NewRest(botInstances map[string]*discord.BotInstance) *Rest {
-	return &Rest{
-		BotInstances: botInstances,
+func NewRest(guilds Guilds, options Options) *Rest {
+	r := &Rest{
+		Guilds:     guilds,
+		options:    options,
+		userGuilds: make(map[string]cachedUserGuilds),
+		avatars:    make(map[string][]byte),
 	}
+
+	if options.OAuthClientID != "" && options.OAuthClientSecret != "" {
+		r.oauth = newOAuthClient(options.OAuthClientID, options.OAuthClientSecret)
+	}
+
+	return r
 }
 
 // Start registers the API routes using the provided gin.Engine.
-func (r *Rest) Start(router *gin.Engine) {
+func (r *Rest) Start(router *gin.Engine) error {
+	trustedProxies, err := parseTrustedProxies(r.options.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	r.trustedProxies = trustedProxies
+
+	// Without trusted proxies the client IP is always the remote address, which rate limits rely on
+	if err := router.SetTrustedProxies(r.options.TrustedProxies); err != nil {
+		return err
+	}
+
+	if err := r.useMiddleware(router); err != nil {
+		return err
+	}
+
 	slog.Info("REST API routes started")
 
 	router.GET("/", func(ctx *gin.Context) {
@@ -37,30 +86,70 @@ func (r *Rest) Start(router *gin.Engine) {
 		ctx.JSON(http.StatusOK, gin.H{"api_methods": toc})
 	})
 
-	logRoutes := router.Group("/log")
+	logRoutes := router.Group("/log", r.adminAuthMiddleware())
 	{
 		r.registerLogRoutes(logRoutes)
 	}
 
-	guildRoutes := router.Group("/guild")
+	guildRoutes := router.Group("/guild", r.adminAuthMiddleware())
 	{
 		r.registerGuildRoutes(guildRoutes)
 	}
 
-	playerRoutes := router.Group("/player")
+	publicRoutes := router.Group("/guilds/:guild_id")
+	{
+		r.registerPublicRoutes(publicRoutes)
+	}
+
+	guildsRoutes := router.Group("/guilds/:guild_id", r.guildAuthMiddleware())
 	{
-		r.registerPlayerRoutes(playerRoutes)
+		r.registerVoiceRoutes(guildsRoutes)
+		r.registerMetricsRoutes(guildsRoutes)
+		r.registerFailureRoutes(guildsRoutes)
+		r.registerRegistrationRoutes(guildsRoutes)
+		r.registerControlRoutes(guildsRoutes)
+		r.registerDataRoutes(guildsRoutes)
+		r.registerGuildHistoryRoutes(guildsRoutes)
 	}
 
-	playlistRoutes := router.Group("/history")
+	playlistRoutes := router.Group("/history", r.adminAuthMiddleware())
 	{
 		r.registerHistoryRoutes(playlistRoutes)
 	}
 
+	hookRoutes := router.Group("/hooks")
+	{
+		r.registerHookRoutes(hookRoutes)
+	}
+
+	if r.oauth != nil {
+		authRoutes := router.Group("/auth")
+		{
+			r.registerAuthRoutes(authRoutes)
+		}
+
+		dashboardRoutes := router.Group("/dashboard")
+		{
+			r.registerDashboardRoutes(dashboardRoutes)
+		}
+	}
+
+	listenRoutes := router.Group("/listen")
+	{
+		r.registerListenRoutes(listenRoutes)
+	}
+
+	assetRoutes := router.Group("/assets")
+	{
+		r.registerAssetRoutes(assetRoutes)
+	}
+
 	avatarRoutes := router.Group("/avatar")
 	{
 		r.registerAvatarRoutes(avatarRoutes)
 	}
+
+	return nil
 }
 
 // GuildInfo represents inforation about a guild.
@@ -101,7 +190,7 @@ func generateTableOfContents(router *gin.Engine) []map[string]string {
 func (r *Rest) registerLogRoutes(router *gin.RouterGroup) {
 
 	router.GET("/", func(ctx *gin.Context) {
-		file, err := os.Open("./logs/all-levels.log")
+		file, err := os.Open(r.options.LogPath)
 		if err != nil {
 			ctx.Status(http.StatusInternalServerError)
 			ctx.Error(err)
@@ -129,7 +218,7 @@ func (r *Rest) registerLogRoutes(router *gin.RouterGroup) {
 	})
 
 	router.GET("/download", func(ctx *gin.Context) {
-		file, err := os.Open("./logs/all-levels.log")
+		file, err := os.Open(r.options.LogPath)
 		if err != nil {
 			ctx.Status(http.StatusInternalServerError)
 			ctx.Error(err)
@@ -163,7 +252,7 @@ func (r *Rest) registerLogRoutes(router *gin.RouterGroup) {
 	})
 
 	router.GET("/clear", func(ctx *gin.Context) {
-		logFilePath := "./logs/all-levels.log"
+		logFilePath := r.options.LogPath
 
 		// Truncate the log file to clear its content
 		err := os.Truncate(logFilePath, 0)
@@ -193,7 +282,7 @@ func (r *Rest) registerGuildRoutes(router *gin.RouterGroup) {
 	router.GET("/ids", func(ctx *gin.Context) {
 		activeSessions := []GuildInfo{}
 
-		for guildID := range r.BotInstances {
+		for _, guildID := range r.Guilds.GuildIDs() {
 			activeSessions = append(activeSessions, GuildInfo{GuildID: guildID})
 		}
 
@@ -203,18 +292,24 @@ func (r *Rest) registerGuildRoutes(router *gin.RouterGroup) {
 	router.GET("/playing", func(ctx *gin.Context) {
 		activeSessions := []GuildSession{}
 
-		for guildID, bot := range r.BotInstances {
-			if bot.Melodix.Player.GetStreamingSession() == nil {
+		for _, guildID := range r.Guilds.GuildIDs() {
+			guild, exists := r.Guilds.Guild(guildID)
+			if !exists {
+				continue
+			}
+
+			state := guild.State()
+			if !state.Streaming {
 				continue
 			}
 
 			session := GuildSession{
 				GuildID:          guildID,
-				GuildActive:      bot.Melodix.InstanceActive,
-				BotStatus:        bot.Melodix.Player.GetCurrentStatus().String(),
-				Queue:            bot.Melodix.Player.GetSongQueue(),
-				CurrentSong:      bot.Melodix.Player.GetCurrentSong(),
-				PlaybackPosition: bot.Melodix.Player.GetStreamingSession().PlaybackPosition().Seconds(),
+				GuildActive:      guild.Active(),
+				BotStatus:        state.Status.String(),
+				Queue:            state.Queue,
+				CurrentSong:      state.CurrentSong,
+				PlaybackPosition: state.Position.Seconds(),
 			}
 
 			activeSessions = append(activeSessions, session)
@@ -224,96 +319,171 @@ func (r *Rest) registerGuildRoutes(router *gin.RouterGroup) {
 	})
 }
 
-// registerPlayerRoutes registers player-related routes.
-// http://localhost:8080/player/play/897053062030585916?url=https://www.com/watch?v=ipFaubyDUT4
-// http://localhost:8080/player/pause/897053062030585916
-// http://localhost:8080/player/resume/897053062030585916
-func (r *Rest) registerPlayerRoutes(router *gin.RouterGroup) {
-	router.GET("/play/:guild_id", func(ctx *gin.Context) {
+// registerDataRoutes registers the routes exporting and deleting the data stored for a guild.
+// http://localhost:8080/guilds/897053062030585916/export
+// http://localhost:8080/guilds/897053062030585916/export/session/12
+// http://localhost:8080/guilds/897053062030585916/purge (DELETE)
+func (r *Rest) registerDataRoutes(router *gin.RouterGroup) {
+	router.GET("/export", func(ctx *gin.Context) {
 		guildID := ctx.Param("guild_id")
-		songURL := ctx.Query("url")
 
-		if songURL == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Song URL not provided"})
+		if !requireControlToken(ctx) {
 			return
 		}
 
-		melodixInstance, exists := r.BotInstances[guildID]
-		if !exists {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
+		data, err := db.ExportGuildData(guildID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export guild data"})
 			return
 		}
 
-		youtube := sources.NewYoutube()
-		song, err := youtube.GetSongFromVideoURL(songURL)
+		ctx.Header("Content-Disposition", "attachment; filename=melodix-"+guildID+".json")
+		ctx.JSON(http.StatusOK, data)
+	})
+
+	router.GET("/export/session/:session_id", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		if !requireControlToken(ctx) {
+			return
+		}
+
+		sessionID, err := strconv.ParseUint(ctx.Param("session_id"), 10, 0)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+			return
+		}
+
+		data, err := db.ExportListeningSession(guildID, uint(sessionID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
 		if err != nil {
-			slog.Warnf("Error fetching song by URL: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export session"})
+			return
+		}
+
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=melodix-%v-session-%v.json", guildID, sessionID))
+		ctx.JSON(http.StatusOK, data)
+	})
+
+	router.DELETE("/purge", func(ctx *gin.Context) {
+		guildID := ctx.Param("guild_id")
+
+		if !requireControlToken(ctx) {
 			return
 		}
 
-		melodixInstance.Melodix.Player.Enqueue(song)
-		if melodixInstance.Melodix.Player.GetCurrentStatus() != player.StatusPlaying {
-			melodixInstance.Melodix.Player.Play(0, nil)
+		if err := db.PurgeGuildData(guildID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge guild data"})
+			return
 		}
+		history.InvalidateCache(guildID)
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "Song added to the queue or started playing"})
+		ctx.JSON(http.StatusOK, gin.H{"message": "Guild data purged"})
 	})
+}
 
-	router.GET("/pause/:guild_id", func(ctx *gin.Context) {
+// registerVoiceRoutes registers voice connection routes of a guild.
+// http://localhost:8080/guilds/897053062030585916/voice/join?channel_id=897053062030585920 (POST)
+// http://localhost:8080/guilds/897053062030585916/voice/leave (POST)
+func (r *Rest) registerVoiceRoutes(router *gin.RouterGroup) {
+	router.POST("/voice/join", func(ctx *gin.Context) {
 		guildID := ctx.Param("guild_id")
 
-		melodixInstance, exists := r.BotInstances[guildID]
+		var body struct {
+			ChannelID string `json:"channel_id"`
+		}
+		channelID := ctx.Query("channel_id")
+		if channelID == "" && ctx.ShouldBindJSON(&body) == nil {
+			channelID = body.ChannelID
+		}
+
+		if channelID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Channel ID not provided"})
+			return
+		}
+
+		guild, exists := r.Guilds.Guild(guildID)
 		if !exists {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
 			return
 		}
 
-		melodixInstance.Melodix.Player.Pause()
+		if err := guild.JoinVoiceChannel(channelID); err != nil {
+			if errors.Is(err, discord.ErrNotVoiceChannel) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Channel is not a voice channel of the guild"})
+				return
+			}
+			if errors.Is(err, ErrGuildUnreachable) {
+				ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+				return
+			}
+			slog.Errorf("Error joining voice channel: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join voice channel"})
+			return
+		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "Playback paused"})
+		ctx.JSON(http.StatusOK, gin.H{"message": "Joined voice channel"})
 	})
 
-	router.GET("/resume/:guild_id", func(ctx *gin.Context) {
+	router.POST("/voice/leave", func(ctx *gin.Context) {
 		guildID := ctx.Param("guild_id")
 
-		melodixInstance, exists := r.BotInstances[guildID]
+		guild, exists := r.Guilds.Guild(guildID)
 		if !exists {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guild not found"})
 			return
 		}
 
-		melodixInstance.Melodix.Player.Unpause()
+		left, err := guild.LeaveVoiceChannel()
+		if err != nil {
+			slog.Errorf("Error leaving voice channel: %v", err)
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Guild is unreachable"})
+			return
+		}
+		if !left {
+			ctx.JSON(http.StatusOK, gin.H{"message": "Not connected to a voice channel"})
+			return
+		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "Playback resumed"})
+		ctx.JSON(http.StatusOK, gin.H{"message": "Left voice channel"})
 	})
 }
 
-// registerHistoryRoutes registers history-related routes.
+// registerHistoryRoutes registers the history route of all guilds.
 // http://localhost:8080/history
-// http://localhost:8080/history/897053062030585916
+// http://localhost:8080/history?limit=25&offset=50
 func (r *Rest) registerHistoryRoutes(router *gin.RouterGroup) {
 	router.GET("/", func(ctx *gin.Context) {
+		limit, offset := parsePagination(ctx)
 
 		h := history.NewHistory()
 
-		// Retrieve history entries for the specified guild
-		history, err := h.GetHistory("", "last_played") // You need to pass appropriate arguments for sorting
+		// Retrieve history entries of every guild
+		history, err := h.GetHistory(ctx.Request.Context(), "", "last_played", limit, offset)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
 			return
 		}
 
-		// Respond with the history for the guild
 		ctx.JSON(http.StatusOK, history)
 	})
+}
 
-	router.GET("/:guild_id", func(ctx *gin.Context) {
+// registerGuildHistoryRoutes registers the history route of a guild.
+// http://localhost:8080/guilds/897053062030585916/history
+// http://localhost:8080/guilds/897053062030585916/history?limit=25&offset=50
+func (r *Rest) registerGuildHistoryRoutes(router *gin.RouterGroup) {
+	router.GET("/history", func(ctx *gin.Context) {
 		guildID := ctx.Param("guild_id")
+		limit, offset := parsePagination(ctx)
 
 		h := history.NewHistory()
 
 		// Retrieve history entries for the specified guild
-		history, err := h.GetHistory(guildID, "last_played") // You need to pass appropriate arguments for sorting
+		history, err := h.GetHistory(ctx.Request.Context(), guildID, "last_played", limit, offset)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
 			return
@@ -324,60 +494,9 @@ func (r *Rest) registerHistoryRoutes(router *gin.RouterGroup) {
 	})
 }
 
-// registerAvatarRoutes registers avatar-related routes.
-// http://localhost:8080/avatar
-// http://localhost:8080/avatar/random
-func (r *Rest) registerAvatarRoutes(router *gin.RouterGroup) {
-	router.GET("/", func(ctx *gin.Context) {
-
-		folderPath := "./assets/avatars"
-
-		var imageList []string
-		files, err := os.ReadDir(folderPath)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-
-		for _, file := range files {
-			// Filter only files with certain extensions (you can modify this if needed)
-			if filepath.Ext(file.Name()) == ".jpg" || filepath.Ext(file.Name()) == ".png" {
-				imageList = append(imageList, file.Name())
-			}
-		}
-
-		ctx.JSON(http.StatusOK, imageList)
-	})
-
-	router.GET("/random", func(ctx *gin.Context) {
-
-		folderPath := "./assets/avatars"
-
-		var validFiles []string
-		files, err := os.ReadDir(folderPath)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-
-		// Filter only files with certain extensions (you can modify this if needed)
-		for _, file := range files {
-			if filepath.Ext(file.Name()) == ".jpg" || filepath.Ext(file.Name()) == ".png" {
-				validFiles = append(validFiles, file.Name())
-			}
-		}
-
-		if len(validFiles) == 0 {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "no valid images found"})
-			return
-		}
-
-		// Get a random index
-		randomIndex := rand.Intn(len(validFiles))
-		randomImage := validFiles[randomIndex]
-		imagePath := filepath.Join(folderPath, randomImage)
-
-		// Return the image file
-		ctx.File(imagePath)
-	})
+// parsePagination reads optional limit and offset query parameters, zero means not set.
+func parsePagination(ctx *gin.Context) (int, int) {
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	return limit, offset
 }
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/keshon/melodix-discord-player/internal/config"
+)
+
+// Serve configures the API for the guilds and serves it in the background as configured, TLS included.
+func Serve(guilds Guilds, cfg *config.Config) {
+	if cfg.RestGinRelease {
+		gin.SetMode("release")
+	}
+
+	// Request logging and recovery are part of the configurable REST middleware
+	router := gin.New()
+
+	restAPI := NewRest(guilds, Options{
+		PublicURL:         cfg.RestPublicURL,
+		AdminToken:        cfg.RestAdminToken,
+		OAuthClientID:     cfg.DiscordClientID,
+		OAuthClientSecret: cfg.DiscordClientSecret,
+		OwnerID:           cfg.DiscordOwnerID,
+		TrustedProxies:    cfg.RestTrustedProxies,
+		CORSOrigins:       cfg.RestCORSOrigins,
+		RateLimit:         cfg.RestRateLimit,
+		MaxBodyBytes:      int64(cfg.RestMaxBodyBytes),
+		Middleware:        cfg.RestMiddleware,
+		LogPath:           cfg.LogPath(),
+	})
+	if err := restAPI.Start(router); err != nil {
+		slog.Fatalf("Error configuring REST API server: %v", err)
+	}
+
+	go func() {
+		// parse hostname var - if it has port - use it or fallback to 8080
+		host, port, err := net.SplitHostPort(cfg.RestHostname)
+		if err != nil {
+			// If there's an error, assume the entire input is the host (without port)
+			host = cfg.RestHostname
+			port = "8080"
+		}
+
+		// If hostname is empty, set it to the default port (8080)
+		if host == "" {
+			host = "localhost"
+		}
+
+		server := &http.Server{
+			Addr:    net.JoinHostPort(host, port),
+			Handler: router,
+		}
+
+		switch {
+		case len(cfg.RestAutocertDomains) > 0:
+			certManager := &autocert.Manager{
+				Prompt:     autocert.AcceptTOS,
+				HostPolicy: autocert.HostWhitelist(cfg.RestAutocertDomains...),
+				Cache:      autocert.DirCache(cfg.RestAutocertCacheDir),
+			}
+			server.TLSConfig = certManager.TLSConfig()
+
+			// TLS-ALPN-01 challenges only reach the server on port 443, HTTP-01 challenges are answered on port 80 whatever
+			// port it listens on.
+			// Plain HTTP requests are redirected to HTTPS.
+			go serveACMEChallenges(host, certManager)
+
+			slog.Infof("REST API server started on https://%s:%s with Let's Encrypt certificates\n", host, port)
+			err = server.ListenAndServeTLS("", "")
+		case cfg.RestTLSEnabled():
+			slog.Infof("REST API server started on https://%s:%s\n", host, port)
+			err = server.ListenAndServeTLS(cfg.RestTLSCert, cfg.RestTLSKey)
+		default:
+			slog.Infof("REST API server started on %s:%s\n", host, port)
+			err = server.ListenAndServe()
+		}
+
+		if err != nil {
+			slog.Fatalf("Error starting REST API server: %v", err)
+		}
+	}()
+}
+
+// serveACMEChallenges answers the HTTP-01 challenges of Let's Encrypt on port 80 of the host.
+func serveACMEChallenges(host string, certManager *autocert.Manager) {
+	server := &http.Server{
+		Addr:              net.JoinHostPort(host, "80"),
+		Handler:           certManager.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		slog.Warnf("Error serving ACME challenges on port 80, certificates can only be obtained on port 443: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionCookie      = "melodix_session"
+	stateCookie        = "melodix_oauth_state"
+	sessionTTL         = 7 * 24 * time.Hour
+	stateTTL           = 10 * time.Minute
+	tokenRefreshMargin = 5 * time.Minute
+	userGuildsCacheTTL = time.Minute // Discord rate limits /users/@me/guilds heavily
+)
+
+// Discord permission bits that grant dashboard access to a guild.
+const (
+	permissionAdministrator = 0x8
+	permissionManageServer  = 0x20
+)
+
+// dashboardGuild is a guild the logged in user may see and control.
+type dashboardGuild struct {
+	ID    string
+	Name  string
+	Guild Guild
+}
+
+type cachedUserGuilds struct {
+	guilds    []discordUserGuild
+	fetchedAt time.Time
+}
+
+// startSession stores a new session for the user and sets its cookie.
+func (r *Rest) startSession(ctx *gin.Context, user *discordUser, token *oauthToken) error {
+	secret, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+
+	username := user.GlobalName
+	if username == "" {
+		username = user.Username
+	}
+
+	session := &db.DashboardSession{
+		ID:           db.HashSecret(secret),
+		UserID:       user.ID,
+		Username:     username,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenExpiry:  time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		ExpiresAt:    time.Now().Add(sessionTTL),
+	}
+	if err := db.CreateDashboardSession(session); err != nil {
+		return err
+	}
+
+	if deleted, err := db.DeleteExpiredDashboardSessions(); err != nil {
+		slog.Warnf("Error deleting expired dashboard sessions: %v", err)
+	} else if deleted > 0 {
+		slog.Debugf("Deleted %d expired dashboard sessions", deleted)
+	}
+
+	r.setCookie(ctx, sessionCookie, secret, sessionTTL)
+	return nil
+}
+
+// loadSession returns the session of the request's cookie, refreshing its Discord token when it's about to expire.
+func (r *Rest) loadSession(ctx *gin.Context) (*db.DashboardSession, bool) {
+	secret, err := ctx.Cookie(sessionCookie)
+	if err != nil || secret == "" {
+		return nil, false
+	}
+
+	session, err := db.GetDashboardSession(db.HashSecret(secret))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Errorf("Error getting dashboard session: %v", err)
+		}
+		return nil, false
+	}
+
+	if time.Until(session.TokenExpiry) < tokenRefreshMargin {
+		token, err := r.oauth.refresh(session.RefreshToken)
+		if err != nil {
+			// The user revoked the app or the refresh token expired, they have to log in again
+			slog.Warnf("Error refreshing Discord token of user %v: %v", session.UserID, err)
+			r.endSession(ctx)
+			return nil, false
+		}
+
+		session.AccessToken = token.AccessToken
+		session.RefreshToken = token.RefreshToken
+		session.TokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+		if err := db.SaveDashboardSession(session); err != nil {
+			slog.Errorf("Error saving dashboard session: %v", err)
+		}
+	}
+
+	return session, true
+}
+
+// endSession deletes the session of the request and clears its cookie.
+func (r *Rest) endSession(ctx *gin.Context) {
+	if secret, err := ctx.Cookie(sessionCookie); err == nil && secret != "" {
+		id := db.HashSecret(secret)
+		if err := db.DeleteDashboardSession(id); err != nil {
+			slog.Errorf("Error deleting dashboard session: %v", err)
+		}
+
+		r.userGuildsMu.Lock()
+		delete(r.userGuilds, id)
+		r.userGuildsMu.Unlock()
+	}
+
+	r.setCookie(ctx, sessionCookie, "", -1)
+}
+
+// accessibleGuilds returns registered guilds where the user has Manage Server or the DJ role.
+// The bot owner may access every guild.
+func (r *Rest) accessibleGuilds(session *db.DashboardSession) ([]dashboardGuild, error) {
+	userGuilds, err := r.userGuildsOf(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var guilds []dashboardGuild
+	seen := make(map[string]bool)
+
+	for _, userGuild := range userGuilds {
+		guild, exists := r.Guilds.Guild(userGuild.ID)
+		if !exists {
+			continue
+		}
+
+		permissions, _ := strconv.ParseInt(userGuild.Permissions, 10, 64)
+		manager := userGuild.Owner || permissions&(permissionAdministrator|permissionManageServer) != 0
+
+		if manager || r.isBotOwner(session.UserID) || guild.HasDJRole(session.UserID) {
+			guilds = append(guilds, dashboardGuild{ID: userGuild.ID, Name: userGuild.Name, Guild: guild})
+			seen[userGuild.ID] = true
+		}
+	}
+
+	if r.isBotOwner(session.UserID) {
+		for _, guildID := range r.Guilds.GuildIDs() {
+			guild, exists := r.Guilds.Guild(guildID)
+			if seen[guildID] || !exists {
+				continue
+			}
+			guilds = append(guilds, dashboardGuild{ID: guildID, Name: guild.Name(), Guild: guild})
+		}
+	}
+
+	return guilds, nil
+}
+
+// isBotOwner reports whether the user is the bot owner, who may access every guild.
+func (r *Rest) isBotOwner(userID string) bool {
+	return r.options.OwnerID != "" && userID == r.options.OwnerID
+}
+
+// canAccessGuild reports whether the user of the session may control the guild.
+func (r *Rest) canAccessGuild(session *db.DashboardSession, guildID string) bool {
+	guilds, err := r.accessibleGuilds(session)
+	if err != nil {
+		slog.Warnf("Error getting guilds of user %v: %v", session.UserID, err)
+		return false
+	}
+
+	for _, guild := range guilds {
+		if guild.ID == guildID {
+			return true
+		}
+	}
+	return false
+}
+
+// userGuildsOf returns the guilds of the session's user, cached for a short while. Expired entries are dropped
+// so sessions that are never logged out of don't stay in the cache.
+func (r *Rest) userGuildsOf(session *db.DashboardSession) ([]discordUserGuild, error) {
+	r.userGuildsMu.Lock()
+	defer r.userGuildsMu.Unlock()
+
+	if cached, ok := r.userGuilds[session.ID]; ok && time.Since(cached.fetchedAt) < userGuildsCacheTTL {
+		return cached.guilds, nil
+	}
+	r.expireUserGuilds()
+
+	guilds, err := r.oauth.currentUserGuilds(session.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	r.userGuilds[session.ID] = cachedUserGuilds{guilds: guilds, fetchedAt: time.Now()}
+	return guilds, nil
+}
+
+// expireUserGuilds drops the cached guilds older than userGuildsCacheTTL, the lock must be held.
+func (r *Rest) expireUserGuilds() {
+	for id, cached := range r.userGuilds {
+		if time.Since(cached.fetchedAt) >= userGuildsCacheTTL {
+			delete(r.userGuilds, id)
+		}
+	}
+}
+
+// setCookie sets an HTTP only cookie, a negative maxAge deletes it.
+func (r *Rest) setCookie(ctx *gin.Context, name, value string, maxAge time.Duration) {
+	cookieMaxAge := int(maxAge.Seconds())
+	if maxAge < 0 {
+		cookieMaxAge = -1
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   cookieMaxAge,
+		Secure:   strings.HasPrefix(r.baseURL(ctx), "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sameOrigin reports whether a state changing request comes from the dashboard itself.
+func (r *Rest) sameOrigin(ctx *gin.Context) bool {
+	origin := ctx.GetHeader("Origin")
+	return origin == "" || origin == r.baseURL(ctx)
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
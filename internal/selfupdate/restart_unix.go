@@ -0,0 +1,18 @@
+//go:build !windows
+
+package selfupdate
+
+import (
+	"os"
+	"syscall"
+)
+
+// Restart replaces the process with a new run of the binary with the same arguments.
+// It only returns if that fails.
+func Restart() error {
+	executable, err := Executable()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(executable, os.Args, restartEnv())
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package selfupdate
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Restart starts a new run of the binary with the same arguments and exits, Windows can't replace a process.
+// It only returns if starting the new run fails.
+func Restart() error {
+	executable, err := Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = restartEnv()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
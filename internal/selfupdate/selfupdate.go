@@ -0,0 +1,315 @@
+// Package selfupdate replaces the running binary with the one of a newer release and restarts the process.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jedisct1/go-minisign"
+
+	"github.com/keshon/melodix-discord-player/internal/version"
+)
+
+// maxDownloadBytes bounds the download of a release asset.
+const maxDownloadBytes = 200 << 20
+
+// signatureSuffix names the minisign signature of a release asset after the asset.
+const signatureSuffix = ".minisig"
+
+// PublicKey is the minisign public key the checksums of releases are signed with. It's compiled in by
+// build-release.sh with -ldflags "-X", builds without it install no updates.
+var PublicKey string
+
+var (
+	// ErrNoAsset is returned when a release has no binary for the platform of the process.
+	ErrNoAsset = errors.New("the release has no binary for this platform")
+	// ErrNoChecksum is returned when a release lists no checksum for its binary, which is then not installed.
+	ErrNoChecksum = errors.New("the release has no checksum for the binary")
+	// ErrChecksumMismatch is returned when a downloaded binary doesn't match its checksum.
+	ErrChecksumMismatch = errors.New("the downloaded binary doesn't match its checksum")
+	// ErrNoPublicKey is returned when the build has no public key to verify releases with.
+	ErrNoPublicKey = errors.New("this build has no key to verify releases with")
+	// ErrNoSignature is returned when the checksums of a release aren't signed, the release is then not installed.
+	ErrNoSignature = errors.New("the release checksums aren't signed")
+	// ErrBadSignature is returned when the checksums of a release aren't signed with the compiled-in key.
+	ErrBadSignature = errors.New("the release checksums aren't signed by the release key")
+)
+
+// Platform names the platform of the process like release assets do, e.g. linux_amd64.
+func Platform() string {
+	return runtime.GOOS + "_" + runtime.GOARCH
+}
+
+// PlatformAsset returns the binary or archive of the release built for the platform of the process.
+func PlatformAsset(release version.LatestRelease) (version.ReleaseAsset, error) {
+	for _, asset := range release.Assets {
+		name := strings.ToLower(asset.Name)
+		if isChecksumFile(name) || strings.HasSuffix(name, signatureSuffix) || strings.HasSuffix(name, ".sig") || strings.HasSuffix(name, ".asc") {
+			continue
+		}
+		// Whole words only, so arm doesn't match arm64
+		words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+		if slices.Contains(words, runtime.GOOS) && slices.Contains(words, runtime.GOARCH) {
+			return asset, nil
+		}
+	}
+	return version.ReleaseAsset{}, fmt.Errorf("%w (%v)", ErrNoAsset, Platform())
+}
+
+// Install downloads the binary of the release for the platform of the process, verifies it against the
+// SHA-256 checksums published with the release, whose minisign signature must match PublicKey, and puts it
+// in place of the running binary, which is kept next to it with an .old suffix. The new binary runs once the
+// process restarts.
+func Install(ctx context.Context, release version.LatestRelease) error {
+	asset, err := PlatformAsset(release)
+	if err != nil {
+		return err
+	}
+
+	checksums, err := signedChecksums(ctx, release, PublicKey)
+	if err != nil {
+		return err
+	}
+	checksum, err := assetChecksum(checksums, asset.Name)
+	if err != nil {
+		return err
+	}
+
+	data, err := download(ctx, asset.DownloadURL)
+	if err != nil {
+		return fmt.Errorf("downloading %v: %w", asset.Name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return ErrChecksumMismatch
+	}
+
+	binary, err := extractBinary(asset.Name, data)
+	if err != nil {
+		return fmt.Errorf("extracting %v: %w", asset.Name, err)
+	}
+
+	return replaceExecutable(binary)
+}
+
+// signedChecksums downloads the checksums file of the release and verifies its signature with the public key.
+func signedChecksums(ctx context.Context, release version.LatestRelease, publicKey string) ([]byte, error) {
+	if publicKey == "" {
+		return nil, ErrNoPublicKey
+	}
+
+	var checksums, signature *version.ReleaseAsset
+	for i, asset := range release.Assets {
+		if isChecksumFile(strings.ToLower(asset.Name)) {
+			checksums = &release.Assets[i]
+		}
+	}
+	if checksums == nil {
+		return nil, ErrNoChecksum
+	}
+	for i, asset := range release.Assets {
+		if asset.Name == checksums.Name+signatureSuffix {
+			signature = &release.Assets[i]
+		}
+	}
+	if signature == nil {
+		return nil, ErrNoSignature
+	}
+
+	data, err := download(ctx, checksums.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %v: %w", checksums.Name, err)
+	}
+	sig, err := download(ctx, signature.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %v: %w", signature.Name, err)
+	}
+
+	if err := verifySignature(publicKey, data, sig); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// verifySignature checks the minisign signature of the data against the base64 public key, as printed on the
+// second line of a minisign.pub file.
+func verifySignature(publicKey string, data, signature []byte) error {
+	key, err := minisign.NewPublicKey(strings.TrimSpace(publicKey))
+	if err != nil {
+		return fmt.Errorf("invalid release key: %w", err)
+	}
+	sig, err := minisign.DecodeSignature(string(signature))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	if ok, err := key.Verify(data, sig); !ok {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	return nil
+}
+
+// assetChecksum returns the SHA-256 checksum of the asset listed in a checksums file.
+func assetChecksum(checksums []byte, name string) (string, error) {
+	// Lines of sha256sum: the checksum, then the file name, marked with * for binary mode
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", ErrNoChecksum
+}
+
+// isChecksumFile reports whether the lowercase asset name is a checksums file, like checksums.txt or SHA256SUMS.
+func isChecksumFile(name string) bool {
+	return strings.HasSuffix(name, "checksums.txt") || strings.HasSuffix(name, "sha256sums") || strings.HasSuffix(name, "sha256sums.txt")
+}
+
+// download fetches a release asset into memory.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.AppName+"/"+version.Current())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP request failed with status code %v", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadBytes {
+		return nil, fmt.Errorf("larger than %v MB", maxDownloadBytes>>20)
+	}
+	return data, nil
+}
+
+// extractBinary returns the melodix executable of a .tar.gz or .zip asset, or the asset itself if it's a plain binary.
+func extractBinary(name string, data []byte) ([]byte, error) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		archive := tar.NewReader(gz)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if header.Typeflag == tar.TypeReg && isExecutableName(header.Name) {
+				return io.ReadAll(io.LimitReader(archive, maxDownloadBytes))
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() || !isExecutableName(file.Name) {
+				continue
+			}
+			f, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return io.ReadAll(io.LimitReader(f, maxDownloadBytes))
+		}
+	default:
+		return data, nil
+	}
+	return nil, errors.New("no melodix executable in the archive")
+}
+
+// isExecutableName reports whether a file of an archive is the bot's executable.
+func isExecutableName(name string) bool {
+	base := strings.ToLower(path.Base(name))
+	return base == "melodix" || base == "melodix.exe"
+}
+
+// replaceExecutable writes the binary next to the running executable and swaps them,
+// the running one is kept with an .old suffix to roll back by hand.
+func replaceExecutable(binary []byte) error {
+	executable, err := Executable()
+	if err != nil {
+		return err
+	}
+
+	staged := executable + ".new"
+	if err := os.WriteFile(staged, binary, 0o755); err != nil {
+		return err
+	}
+
+	previous := executable + ".old"
+	os.Remove(previous)
+	if err := os.Rename(executable, previous); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	if err := os.Rename(staged, executable); err != nil {
+		// Put the running binary back so the next start still finds it
+		if rollback := os.Rename(previous, executable); rollback != nil {
+			return fmt.Errorf("%w, and restoring the previous binary failed: %v", err, rollback)
+		}
+		return err
+	}
+	return nil
+}
+
+// Executable returns the path the running binary was started from, with symlinks resolved.
+// It's read at start, the running binary itself is found under its .old name once an update was installed.
+func Executable() (string, error) {
+	return startedExecutable, startedExecutableErr
+}
+
+var startedExecutable, startedExecutableErr = resolveExecutable()
+
+func resolveExecutable() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(executable)
+}
+
+// restartEnv is the environment of the restarted process, it brings back the queues saved before the restart
+// by itself when somebody is still in their voice channel.
+func restartEnv() []string {
+	return append(os.Environ(), "QUEUE_AUTO_RESTORE=true")
+}
@@ -0,0 +1,268 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/keshon/melodix-discord-player/internal/version"
+)
+
+func TestPlatformAsset(t *testing.T) {
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	other := "plan9_386"
+	if platform == other {
+		other = "linux_amd64"
+	}
+
+	tests := []struct {
+		assets []string
+		want   string
+	}{
+		{[]string{"melodix_1.4.0_" + other + ".tar.gz", "melodix_1.4.0_" + platform + ".tar.gz"}, "melodix_1.4.0_" + platform + ".tar.gz"},
+		{[]string{"checksums.txt", "checksums.txt.minisig", "melodix_" + platform}, "melodix_" + platform},
+		{[]string{"melodix_" + platform + ".tar.gz.sig", "melodix_" + platform + ".tar.gz.minisig", "melodix-" + runtime.GOOS + "-" + runtime.GOARCH + ".zip"}, "melodix-" + runtime.GOOS + "-" + runtime.GOARCH + ".zip"},
+		{[]string{"MELODIX_" + strings.ToUpper(platform) + ".ZIP"}, "MELODIX_" + strings.ToUpper(platform) + ".ZIP"},
+		{[]string{"melodix_" + runtime.GOOS + "_" + runtime.GOARCH + "x.tar.gz"}, ""}, // whole words only, e.g. arm isn't arm64
+		{[]string{"melodix_" + other + ".tar.gz"}, ""},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		release := version.LatestRelease{}
+		for _, name := range test.assets {
+			release.Assets = append(release.Assets, version.ReleaseAsset{Name: name})
+		}
+
+		asset, err := PlatformAsset(release)
+		if test.want == "" {
+			if !errors.Is(err, ErrNoAsset) {
+				t.Errorf("PlatformAsset(%q) = %q, %v, want ErrNoAsset", test.assets, asset.Name, err)
+			}
+			continue
+		}
+		if err != nil || asset.Name != test.want {
+			t.Errorf("PlatformAsset(%q) = %q, %v, want %q", test.assets, asset.Name, err, test.want)
+		}
+	}
+}
+
+func TestAssetChecksum(t *testing.T) {
+	checksums := []byte("AB12  melodix_linux_amd64.tar.gz\n" +
+		"cd34 *melodix_windows_amd64.zip\n" +
+		"ef56  melodix_linux_amd64.tar.gz.minisig\n" +
+		"malformed line\n")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"melodix_linux_amd64.tar.gz", "ab12"},
+		{"melodix_windows_amd64.zip", "cd34"},
+		{"melodix_linux_arm64.tar.gz", ""},
+		{"melodix_linux_amd64", ""},
+	}
+
+	for _, test := range tests {
+		checksum, err := assetChecksum(checksums, test.name)
+		if test.want == "" {
+			if !errors.Is(err, ErrNoChecksum) {
+				t.Errorf("assetChecksum(%q) = %q, %v, want ErrNoChecksum", test.name, checksum, err)
+			}
+			continue
+		}
+		if err != nil || checksum != test.want {
+			t.Errorf("assetChecksum(%q) = %q, %v, want %q", test.name, checksum, err, test.want)
+		}
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for name, content := range files {
+		typeflag := byte(tar.TypeReg)
+		if strings.HasSuffix(name, "/") {
+			typeflag = tar.TypeDir
+		}
+		if err := archive.WriteHeader(&tar.Header{Name: name, Typeflag: typeflag, Mode: 0o755, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write([]byte(content))
+	}
+	archive.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipped(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	archive.Close()
+	return buf.Bytes()
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"melodix_linux_amd64", []byte("plain"), "plain"},
+		{"melodix_linux_amd64.tar.gz", tarGz(t, map[string]string{"README.md": "docs", "melodix_1.4.0/melodix": "binary"}), "binary"},
+		{"melodix_linux_amd64.tgz", tarGz(t, map[string]string{"melodix/": "", "melodix/melodix": "binary"}), "binary"},
+		{"melodix_windows_amd64.zip", zipped(t, map[string]string{"LICENSE": "license", "Melodix.exe": "exe"}), "exe"},
+		{"melodix_linux_amd64.tar.gz", tarGz(t, map[string]string{"README.md": "docs"}), ""},
+		{"melodix_windows_amd64.zip", zipped(t, map[string]string{"melodix-helper.exe": "helper"}), ""},
+		{"melodix_linux_amd64.tar.gz", []byte("not gzip"), ""},
+		{"melodix_windows_amd64.zip", []byte("not zip"), ""},
+	}
+
+	for _, test := range tests {
+		binary, err := extractBinary(test.name, test.data)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("extractBinary(%q) = %q, want an error", test.name, binary)
+			}
+			continue
+		}
+		if err != nil || string(binary) != test.want {
+			t.Errorf("extractBinary(%q) = %q, %v, want %q", test.name, binary, err, test.want)
+		}
+	}
+}
+
+// signer signs like minisign -S with a legacy (not prehashed) key.
+type signer struct {
+	keyID     [8]byte
+	private   ed25519.PrivateKey
+	publicKey string
+}
+
+func newSigner(t *testing.T, keyID byte) *signer {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &signer{private: private}
+	s.keyID[0] = keyID
+	s.publicKey = base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), s.keyID[:]...), public...))
+	return s
+}
+
+func (s *signer) sign(data []byte) []byte {
+	signature := ed25519.Sign(s.private, data)
+	trusted := "timestamp:1700000000\tfile:checksums.txt"
+	global := ed25519.Sign(s.private, append(append([]byte{}, signature...), trusted...))
+
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), s.keyID[:]...), signature...)) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestVerifySignature(t *testing.T) {
+	key := newSigner(t, 1)
+	other := newSigner(t, 2)
+	sameID := newSigner(t, 1)
+
+	data := []byte("ab12  melodix_linux_amd64.tar.gz\n")
+	signature := key.sign(data)
+
+	if err := verifySignature(key.publicKey, data, signature); err != nil {
+		t.Errorf("Valid signature was rejected: %v", err)
+	}
+	if err := verifySignature(key.publicKey+"\n", data, signature); err != nil {
+		t.Errorf("Public key with a trailing newline was rejected: %v", err)
+	}
+
+	tests := map[string]struct {
+		publicKey string
+		data      []byte
+		signature []byte
+	}{
+		"tampered data":      {key.publicKey, []byte("ff00  melodix_linux_amd64.tar.gz\n"), signature},
+		"other key":          {other.publicKey, data, signature},
+		"other key, same id": {sameID.publicKey, data, signature},
+		"other signer":       {key.publicKey, data, other.sign(data)},
+		"truncated":          {key.publicKey, data, signature[:len(signature)/2]},
+		"empty":              {key.publicKey, data, nil},
+	}
+	for name, test := range tests {
+		if err := verifySignature(test.publicKey, test.data, test.signature); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%v: got %v, want ErrBadSignature", name, err)
+		}
+	}
+
+	if err := verifySignature("not a key", data, signature); err == nil || errors.Is(err, ErrBadSignature) {
+		t.Errorf("Invalid public key got %v", err)
+	}
+}
+
+func TestSignedChecksums(t *testing.T) {
+	key := newSigner(t, 1)
+	checksums := []byte("ab12  melodix_linux_amd64.tar.gz\n")
+	files := map[string][]byte{
+		"/checksums.txt":         checksums,
+		"/checksums.txt.minisig": key.sign(checksums),
+		"/forged.txt.minisig":    newSigner(t, 1).sign(checksums),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	asset := func(name, path string) version.ReleaseAsset {
+		return version.ReleaseAsset{Name: name, DownloadURL: server.URL + path}
+	}
+	binary := asset("melodix_linux_amd64.tar.gz", "/binary")
+
+	tests := []struct {
+		name      string
+		publicKey string
+		assets    []version.ReleaseAsset
+		err       error
+	}{
+		{"signed", key.publicKey, []version.ReleaseAsset{binary, asset("checksums.txt", "/checksums.txt"), asset("checksums.txt.minisig", "/checksums.txt.minisig")}, nil},
+		{"no key", "", []version.ReleaseAsset{binary, asset("checksums.txt", "/checksums.txt"), asset("checksums.txt.minisig", "/checksums.txt.minisig")}, ErrNoPublicKey},
+		{"no checksums", key.publicKey, []version.ReleaseAsset{binary}, ErrNoChecksum},
+		{"no signature", key.publicKey, []version.ReleaseAsset{binary, asset("checksums.txt", "/checksums.txt")}, ErrNoSignature},
+		{"forged", key.publicKey, []version.ReleaseAsset{binary, asset("checksums.txt", "/checksums.txt"), asset("checksums.txt.minisig", "/forged.txt.minisig")}, ErrBadSignature},
+	}
+
+	for _, test := range tests {
+		data, err := signedChecksums(context.Background(), version.LatestRelease{Assets: test.assets}, test.publicKey)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%v: got %v, want %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(data, checksums) {
+			t.Errorf("%v: got %q, %v", test.name, data, err)
+		}
+	}
+}
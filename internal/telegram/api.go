@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	apiURL      = "https://api.telegram.org/bot"
+	pollTimeout = 50 // seconds the server holds getUpdates open
+)
+
+// update is an incoming Telegram update, only messages are requested.
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	From *struct {
+		ID        int64  `json:"id"`
+		FirstName string `json:"first_name"`
+		Username  string `json:"username"`
+	} `json:"from"`
+}
+
+// api is a minimal Telegram Bot API client.
+type api struct {
+	token      string
+	httpClient *http.Client
+}
+
+func newAPI(token string) *api {
+	return &api{
+		token:      token,
+		httpClient: &http.Client{Timeout: (pollTimeout + 10) * time.Second},
+	}
+}
+
+// call invokes a Bot API method and decodes its result into result if it's not nil.
+func (a *api) call(method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.httpClient.Post(apiURL+a.token+"/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The error holds the request URL and so the token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %v: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("telegram %v: %w", method, err)
+	}
+	if !reply.OK {
+		return fmt.Errorf("telegram %v: %v", method, reply.Description)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+// getUpdates long polls for messages newer than offset.
+func (a *api) getUpdates(offset int64) ([]update, error) {
+	var updates []update
+	err := a.call("getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         pollTimeout,
+		"allowed_updates": []string{"message"},
+	}, &updates)
+
+	return updates, err
+}
+
+// sendMessage sends a plain text reply to the chat.
+func (a *api) sendMessage(chatID, replyTo int64, text string) error {
+	params := map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+	if replyTo != 0 {
+		params["reply_to_message_id"] = replyTo
+	}
+
+	return a.call("sendMessage", params, nil)
+}
@@ -0,0 +1,215 @@
+// Package telegram bridges linked Telegram chats to guild players,
+// so members of a Telegram group can see the queue and request songs for a Discord guild.
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/discord"
+	"github.com/keshon/melodix-discord-player/music/locale"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	retryDelay     = 10 * time.Second
+	queueListLimit = 10
+)
+
+// Telegram is the Telegram bridge for Melodix.
+type Telegram struct {
+	BotInstances *discord.BotInstances
+	api          *api
+	links        map[int64]string // Telegram chat ID to guild ID
+}
+
+// NewTelegram creates a new instance of Telegram.
+// Links are comma separated "chat_id:guild_id" pairs, e.g. "-1001234567890:897053062030585916".
+func NewTelegram(botInstances *discord.BotInstances, token, links string) (*Telegram, error) {
+	parsed, err := parseLinks(links)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Telegram{
+		BotInstances: botInstances,
+		api:          newAPI(token),
+		links:        parsed,
+	}, nil
+}
+
+// Start polls Telegram for messages in the background.
+func (t *Telegram) Start() {
+	slog.Infof("Telegram bridge started for %d linked chats", len(t.links))
+
+	go func() {
+		var offset int64
+		for {
+			updates, err := t.api.getUpdates(offset)
+			if err != nil {
+				slog.Warnf("Error receiving Telegram updates: %v", err)
+				time.Sleep(retryDelay)
+				continue
+			}
+
+			for _, u := range updates {
+				offset = u.UpdateID + 1
+				if u.Message != nil {
+					t.handleMessage(u.Message)
+				}
+			}
+		}
+	}()
+}
+
+// handleMessage runs a bot command sent in a chat.
+func (t *Telegram) handleMessage(msg *message) {
+	command, parameter := parseCommand(msg.Text)
+	if command == "" {
+		return
+	}
+
+	if command == "chatid" {
+		t.reply(msg, fmt.Sprintf("This chat ID is %d", msg.Chat.ID))
+		return
+	}
+
+	guildID, ok := t.links[msg.Chat.ID]
+	if !ok {
+		t.reply(msg, "This chat is not linked to a Discord server, send /chatid and ask the bot owner to link it")
+		return
+	}
+
+	instance, ok := t.BotInstances.Get(guildID)
+	if !ok {
+		t.reply(msg, "The linked Discord server is not registered")
+		return
+	}
+
+	switch command {
+	case "start", "help":
+		t.reply(msg, "/nowplaying - show the current song\n/queue - show the queue\n/play <title or url> - add songs to the queue")
+	case "nowplaying", "np":
+		t.reply(msg, formatNowPlaying(instance.Melodix.Player))
+	case "queue", "q":
+		t.reply(msg, formatQueue(instance.Melodix.Player))
+	case "play", "p", "add":
+		t.handlePlay(msg, instance.Melodix, parameter)
+	}
+}
+
+func (t *Telegram) handlePlay(msg *message, d *discord.Discord, query string) {
+	if query == "" {
+		t.reply(msg, "Usage: /play <title or url>")
+		return
+	}
+
+	songs, err := d.EnqueueQuery(query)
+	switch {
+	case errors.Is(err, discord.ErrNotInVoice):
+		t.reply(msg, "The bot is not in a voice channel, start playback on Discord first")
+		return
+	case errors.Is(err, discord.ErrNoSongs):
+		t.reply(msg, "No music found")
+		return
+	case err != nil:
+		slog.Errorf("Error enqueuing songs from Telegram: %v", err)
+		t.reply(msg, "Failed to add songs")
+		return
+	}
+
+	if msg.From != nil {
+		slog.Infof("Telegram user %v (%d) added %d songs to guild %v", msg.From.Username, msg.From.ID, len(songs), d.GuildID)
+	}
+
+	if len(songs) == 1 {
+		t.reply(msg, "Added "+songs[0].Title)
+	} else {
+		t.reply(msg, fmt.Sprintf("Added %d songs", len(songs)))
+	}
+}
+
+func (t *Telegram) reply(msg *message, text string) {
+	if err := t.api.sendMessage(msg.Chat.ID, msg.MessageID, text); err != nil {
+		slog.Warnf("Error sending Telegram message: %v", err)
+	}
+}
+
+func formatNowPlaying(p player.IPlayer) string {
+	song := p.GetCurrentSong()
+	if song == nil {
+		return "Nothing is playing"
+	}
+
+	text := song.Title
+	if song.Duration > 0 {
+		text += " (" + locale.Default().Duration(song.Duration.Seconds()) + ")"
+	}
+	if p.GetCurrentStatus() == player.StatusPaused {
+		text += ", paused"
+	}
+
+	return text + "\n" + song.UserURL
+}
+
+func formatQueue(p player.IPlayer) string {
+	var b strings.Builder
+
+	if song := p.GetCurrentSong(); song != nil {
+		b.WriteString("Now playing: " + song.Title + "\n\n")
+	}
+
+	queue := p.GetSongQueue()
+	if len(queue) == 0 {
+		b.WriteString("The queue is empty")
+		return b.String()
+	}
+
+	for i, song := range queue {
+		if i == queueListLimit {
+			fmt.Fprintf(&b, "...and %d more", len(queue)-queueListLimit)
+			break
+		}
+		fmt.Fprintf(&b, "%d. %v\n", i+1, song.Title)
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// parseCommand splits "/command@BotName parameter" into command and parameter.
+func parseCommand(text string) (string, string) {
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+
+	command, parameter, _ := strings.Cut(text[1:], " ")
+	command, _, _ = strings.Cut(command, "@")
+
+	return strings.ToLower(command), strings.TrimSpace(parameter)
+}
+
+// parseLinks parses comma separated "chat_id:guild_id" pairs.
+func parseLinks(links string) (map[int64]string, error) {
+	parsed := make(map[int64]string)
+
+	for _, link := range strings.Split(links, ",") {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
+		}
+
+		chat, guildID, ok := strings.Cut(link, ":")
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if !ok || err != nil || guildID == "" {
+			return nil, fmt.Errorf("invalid Telegram chat link %q, expected chat_id:guild_id", link)
+		}
+
+		parsed[chatID] = guildID
+	}
+
+	return parsed, nil
+}
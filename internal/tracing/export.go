@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+const (
+	exportInterval = 5 * time.Second
+	exportTimeout  = 10 * time.Second
+	batchSize      = 512  // spans sent in one request
+	maxQueued      = 4096 // spans kept while the collector is unreachable, newer ones are dropped
+)
+
+// Options configure the collector spans are exported to.
+type Options struct {
+	Endpoint    string            // base URL of the OTLP/HTTP receiver, e.g. http://localhost:4318
+	ServiceName string            // service.name of the spans
+	Headers     map[string]string // sent with every export, e.g. an API key of a hosted collector
+}
+
+// exporter queues ended spans and sends them to the collector in batches.
+type exporter struct {
+	sync.Mutex
+	options Options
+	url     string
+	client  *http.Client
+	queued  []*Span
+	dropped int
+	wake    chan struct{}
+}
+
+var (
+	active   *exporter
+	activeMu sync.RWMutex
+)
+
+func current() *exporter {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active
+}
+
+// Enable starts recording spans and exporting them in the background.
+func Enable(options Options) {
+	if options.ServiceName == "" {
+		options.ServiceName = "melodix"
+	}
+
+	url := strings.TrimRight(options.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+
+	e := &exporter{
+		options: options,
+		url:     url,
+		client:  &http.Client{Timeout: exportTimeout},
+		wake:    make(chan struct{}, 1),
+	}
+
+	activeMu.Lock()
+	active = e
+	activeMu.Unlock()
+
+	slog.Infof("Tracing enabled, exporting spans to %v", url)
+
+	go e.run()
+}
+
+// Flush exports the spans ended so far, call it before exiting.
+func Flush() {
+	if e := current(); e != nil {
+		e.export()
+	}
+}
+
+func (e *exporter) add(span *Span) {
+	e.Lock()
+	defer e.Unlock()
+
+	if len(e.queued) >= maxQueued {
+		e.dropped++
+		return
+	}
+	e.queued = append(e.queued, span)
+
+	if len(e.queued) >= batchSize {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.wake:
+		}
+		e.export()
+	}
+}
+
+// export sends the queued spans, those of a failed batch are dropped rather than retried.
+func (e *exporter) export() {
+	for {
+		e.Lock()
+		n := min(len(e.queued), batchSize)
+		batch := e.queued[:n:n]
+		e.queued = e.queued[n:]
+		dropped := e.dropped
+		e.dropped = 0
+		e.Unlock()
+
+		if dropped > 0 {
+			slog.Warnf("Dropped %v spans, the trace collector didn't keep up", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := e.send(batch); err != nil {
+			slog.Warnf("Error exporting %v spans: %v", len(batch), err)
+			return
+		}
+	}
+}
+
+func (e *exporter) send(batch []*Span) error {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.options.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %v failed with status %v", e.url, resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of spans, IDs are hex and 64-bit integers are strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Events            []otlpEvent     `json:"events,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string `json:"timeUnixNano"`
+		Name         string `json:"name"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"` // 2 is an error
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// spanKindInternal is the kind of every span, they all are operations within the bot.
+const spanKindInternal = 1
+
+func (e *exporter) request(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, encodeSpan(span))
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]Attribute{String("service.name", e.options.ServiceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "melodix"}, Spans: spans}},
+	}}}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	encoded := otlpSpan{
+		TraceID:           hex.EncodeToString(span.context.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.context.SpanID[:]),
+		Name:              span.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(span.start),
+		EndTimeUnixNano:   unixNano(span.end),
+		Attributes:        encodeAttributes(span.attributes),
+	}
+	if span.parent != [8]byte{} {
+		encoded.ParentSpanID = hex.EncodeToString(span.parent[:])
+	}
+	for _, event := range span.events {
+		encoded.Events = append(encoded.Events, otlpEvent{TimeUnixNano: unixNano(event.at), Name: event.name})
+	}
+	if span.err != nil {
+		encoded.Status = otlpStatus{Code: 2, Message: span.err.Error()}
+	}
+	return encoded
+}
+
+func encodeAttributes(attributes []Attribute) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		var value map[string]any
+		switch v := attribute.Value.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, otlpAttribute{Key: attribute.Key, Value: value})
+	}
+	return encoded
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
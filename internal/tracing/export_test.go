@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// otlpIDFields are encoded as hex in OTLP/JSON, unlike other bytes fields the protobuf JSON mapping encodes as base64.
+var otlpIDFields = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+// toProtoJSON rewrites the hex IDs of an OTLP/JSON payload to base64, so the generic protobuf JSON decoding reads it.
+func toProtoJSON(t *testing.T, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if id, ok := item.(string); ok && otlpIDFields[key] {
+				raw, err := hex.DecodeString(id)
+				if err != nil {
+					t.Fatalf("%v %q isn't hex", key, id)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(raw)
+				continue
+			}
+			v[key] = toProtoJSON(t, item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = toProtoJSON(t, item)
+		}
+	}
+	return value
+}
+
+// TestExportMatchesOTLP decodes an exported payload with the OTLP protobuf definitions, rejecting unknown fields
+// and values of the wrong type, and checks the spans read back.
+func TestExportMatchesOTLP(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Api-Key") != "key" {
+			t.Errorf("Unexpected export request %v %v", r.URL.Path, r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	Enable(Options{Endpoint: collector.URL + "/", ServiceName: "melodix-test", Headers: map[string]string{"X-Api-Key": "key"}})
+	defer func() {
+		activeMu.Lock()
+		active = nil
+		activeMu.Unlock()
+	}()
+
+	ctx, parent := Start(context.Background(), "command play", String("guild", "1"))
+	_, child := Start(ctx, "lookup", Int("songs", 3), Bool("cached", false), Duration("wait", 1500*time.Microsecond))
+	child.AddEvent("resolved")
+	child.SetError(errors.New("no music found"))
+	child.End()
+	parent.End()
+	Flush()
+
+	var body []byte
+	select {
+	case body = <-bodies:
+	default:
+		t.Fatal("Nothing was exported")
+	}
+
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := json.Marshal(toProtoJSON(t, payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var traces tracepb.TracesData
+	if err := protojson.Unmarshal(converted, &traces); err != nil {
+		t.Fatalf("Payload doesn't match the OTLP schema: %v\n%s", err, body)
+	}
+
+	if len(traces.ResourceSpans) != 1 || len(traces.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Incorrect resource spans %v", &traces)
+	}
+	resource := traces.ResourceSpans[0]
+	if attributes := attributeMap(resource.Resource.Attributes); attributes["service.name"].GetStringValue() != "melodix-test" {
+		t.Errorf("Incorrect resource %v", resource.Resource)
+	}
+
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Exported %v spans, want 2", len(spans))
+	}
+	lookup, command := spans[0], spans[1]
+
+	if command.Name != "command play" || len(command.TraceId) != 16 || len(command.SpanId) != 8 || len(command.ParentSpanId) != 0 {
+		t.Errorf("Incorrect root span %v", command)
+	}
+	if lookup.Name != "lookup" || string(lookup.TraceId) != string(command.TraceId) || string(lookup.ParentSpanId) != string(command.SpanId) {
+		t.Errorf("Child span isn't in the trace of its parent %v", lookup)
+	}
+	parentContext, childContext := parent.Context(), child.Context()
+	if string(lookup.TraceId) != string(parentContext.TraceID[:]) || string(lookup.SpanId) != string(childContext.SpanID[:]) {
+		t.Errorf("Exported IDs don't match the span contexts")
+	}
+
+	for _, span := range spans {
+		if span.Kind != tracepb.Span_SPAN_KIND_INTERNAL {
+			t.Errorf("Span %v has kind %v", span.Name, span.Kind)
+		}
+		if span.StartTimeUnixNano == 0 || span.EndTimeUnixNano < span.StartTimeUnixNano {
+			t.Errorf("Span %v has times %v to %v", span.Name, span.StartTimeUnixNano, span.EndTimeUnixNano)
+		}
+	}
+
+	attributes := attributeMap(lookup.Attributes)
+	if attributes["songs"].GetIntValue() != 3 || attributes["cached"].GetValue() == nil || attributes["cached"].GetBoolValue() || attributes["wait"].GetDoubleValue() != 1.5 {
+		t.Errorf("Incorrect attributes %v", lookup.Attributes)
+	}
+	if attributeMap(command.Attributes)["guild"].GetStringValue() != "1" {
+		t.Errorf("Incorrect attributes %v", command.Attributes)
+	}
+
+	if len(lookup.Events) != 1 || lookup.Events[0].Name != "resolved" || lookup.Events[0].TimeUnixNano < lookup.StartTimeUnixNano {
+		t.Errorf("Incorrect events %v", lookup.Events)
+	}
+	if lookup.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || lookup.Status.GetMessage() != "no music found" {
+		t.Errorf("Incorrect status of a failed span %v", lookup.Status)
+	}
+	if command.Status.GetCode() != tracepb.Status_STATUS_CODE_UNSET {
+		t.Errorf("Incorrect status %v", command.Status)
+	}
+}
+
+func attributeMap(attributes []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	values := make(map[string]*commonpb.AnyValue, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.Key] = attribute.Value
+	}
+	return values
+}
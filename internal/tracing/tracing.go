@@ -0,0 +1,167 @@
+// Package tracing records spans of command handling, song lookups, encoding and streaming and exports them
+// to an OpenTelemetry collector over OTLP/HTTP, so slow plays can be broken down in a trace viewer.
+// Until Enable is called every span is nil and recording costs nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span, so work started later (e.g. playing a queued song) can join its trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// Valid reports whether the context identifies a span.
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != [16]byte{}
+}
+
+// String returns the hex trace ID, as trace viewers show it, or an empty string.
+func (sc SpanContext) String() string {
+	if !sc.Valid() {
+		return ""
+	}
+	return hex.EncodeToString(sc.TraceID[:])
+}
+
+// Attribute is a key and a string, int, int64, float64 or bool value describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{key, value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{key, int64(value)}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{key, value}
+}
+
+// Duration returns a duration attribute in milliseconds.
+func Duration(key string, value time.Duration) Attribute {
+	return Attribute{key, float64(value) / float64(time.Millisecond)}
+}
+
+// event is a point in time within a span.
+type event struct {
+	name string
+	at   time.Time
+}
+
+// Span is a timed operation of a trace, its methods do nothing on a nil span.
+type Span struct {
+	mu         sync.Mutex
+	context    SpanContext
+	parent     [8]byte
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes []Attribute
+	events     []event
+	err        error
+}
+
+type spanKey struct{}
+
+// Start begins a span named name, a child of the span of ctx if any, and returns a context carrying it.
+// The span must be ended, it's nil if tracing isn't enabled.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	if current() == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, start: time.Now(), attributes: attributes}
+	if parent := FromContext(ctx); parent.Valid() {
+		span.context.TraceID = parent.TraceID
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.context.TraceID[:])
+	}
+	rand.Read(span.context.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span.context), span
+}
+
+// WithParent returns a context whose spans join the trace of parent, ctx is returned as is for an invalid parent.
+func WithParent(ctx context.Context, parent SpanContext) context.Context {
+	if !parent.Valid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, parent)
+}
+
+// FromContext returns the span context carried by ctx, it's invalid if there's none.
+func FromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanKey{}).(SpanContext)
+	return sc
+}
+
+// Context returns the span context of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attributes = append(s.attributes, attributes...)
+	s.mu.Unlock()
+}
+
+// AddEvent marks a point in time within the span.
+func (s *Span) AddEvent(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.events = append(s.events, event{name, time.Now()})
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed, a nil error leaves it as is.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export, later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if e := current(); e != nil {
+		e.add(s)
+	}
+}
@@ -0,0 +1,55 @@
+package version
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed CHANGELOG.md
+var changelog string
+
+// Release is a version of the changelog with its changes.
+type Release struct {
+	Version string
+	Date    string
+	Changes []string
+}
+
+// Releases returns the releases of the embedded changelog, newest first.
+func Releases() []Release {
+	var releases []Release
+	for _, line := range strings.Split(changelog, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			name, date, _ := strings.Cut(strings.TrimPrefix(line, "## "), " - ")
+			releases = append(releases, Release{Version: strings.TrimSpace(name), Date: strings.TrimSpace(date)})
+		case strings.HasPrefix(line, "- ") && len(releases) > 0:
+			last := &releases[len(releases)-1]
+			last.Changes = append(last.Changes, strings.TrimPrefix(line, "- "))
+		}
+	}
+	return releases
+}
+
+// Current returns the version of the build: the one set at build time, else the newest of the changelog.
+func Current() string {
+	if Version != "" {
+		return Version
+	}
+	if releases := Releases(); len(releases) > 0 {
+		return releases[0].Version
+	}
+	return "dev"
+}
+
+// FindRelease returns the release of the version of the changelog, false if it has none.
+func FindRelease(name string) (Release, bool) {
+	name = strings.TrimPrefix(name, "v")
+	for _, release := range Releases() {
+		if release.Version == name {
+			return release, true
+		}
+	}
+	return Release{}, false
+}
@@ -0,0 +1,142 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// latestReleaseURL is the GitHub API route of the latest published release
+	latestReleaseURL = "https://api.github.com/repos/keshon/melodix-discord-player/releases/latest"
+	// latestReleaseCache is how long a checked latest release is used before GitHub is asked again,
+	// which allows 60 unauthenticated requests an hour
+	latestReleaseCache = time.Hour
+)
+
+// LatestRelease is the newest release published on GitHub.
+type LatestRelease struct {
+	Version string // tag without the leading v
+	URL     string // release page
+	Assets  []ReleaseAsset
+}
+
+// ReleaseAsset is a file attached to a release, e.g. the binary of a platform.
+type ReleaseAsset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+	Size        int64  `json:"size"`
+}
+
+var latest = struct {
+	sync.Mutex
+	release   LatestRelease
+	checkedAt time.Time
+}{}
+
+// CheckLatestRelease returns the latest release published on GitHub, asking GitHub at most once per latestReleaseCache.
+// The cache isn't locked while GitHub is asked, concurrent checks of an expired cache may each ask.
+func CheckLatestRelease(ctx context.Context) (LatestRelease, error) {
+	latest.Lock()
+	if !latest.checkedAt.IsZero() && time.Since(latest.checkedAt) < latestReleaseCache {
+		release := latest.release
+		latest.Unlock()
+		return release, nil
+	}
+	latest.Unlock()
+
+	release, err := fetchLatestRelease(ctx)
+	if err != nil {
+		return LatestRelease{}, err
+	}
+
+	latest.Lock()
+	defer latest.Unlock()
+	latest.release = release
+	latest.checkedAt = time.Now()
+	return release, nil
+}
+
+// fetchLatestRelease asks GitHub for the latest release.
+func fetchLatestRelease(ctx context.Context) (LatestRelease, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return LatestRelease{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", AppName+"/"+Current())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return LatestRelease{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return LatestRelease{}, fmt.Errorf("HTTP request failed with status code %v", resp.StatusCode)
+	}
+
+	var body struct {
+		TagName string         `json:"tag_name"`
+		HTMLURL string         `json:"html_url"`
+		Assets  []ReleaseAsset `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return LatestRelease{}, err
+	}
+
+	return LatestRelease{Version: strings.TrimPrefix(body.TagName, "v"), URL: body.HTMLURL, Assets: body.Assets}, nil
+}
+
+// UpdateAvailable reports whether the release is newer than the running version.
+func (r LatestRelease) UpdateAvailable() bool {
+	return IsNewer(r.Version, Current())
+}
+
+// IsNewer reports whether version a is newer than version b, comparing their dot separated numbers.
+// Versions that aren't numbers, like dev, are never newer nor older.
+func IsNewer(a, b string) bool {
+	partsA, okA := versionNumbers(a)
+	partsB, okB := versionNumbers(b)
+	if !okA || !okB {
+		return false
+	}
+
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var numberA, numberB int
+		if i < len(partsA) {
+			numberA = partsA[i]
+		}
+		if i < len(partsB) {
+			numberB = partsB[i]
+		}
+		if numberA != numberB {
+			return numberA > numberB
+		}
+	}
+	return false
+}
+
+// versionNumbers splits a version like v1.2.3 or 1.2.3-rc1 into its numbers, ignoring the suffix.
+func versionNumbers(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "-")
+	if version == "" {
+		return nil, false
+	}
+
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, true
+}
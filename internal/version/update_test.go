@@ -0,0 +1,57 @@
+package version
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVersionNumbers(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []int
+		ok      bool
+	}{
+		{"1.2.3", []int{1, 2, 3}, true},
+		{"v1.2.3", []int{1, 2, 3}, true},
+		{" v0.10 ", []int{0, 10}, true},
+		{"1.2.3-rc1", []int{1, 2, 3}, true},
+		{"2", []int{2}, true},
+		{"dev", nil, false},
+		{"", nil, false},
+		{"v", nil, false},
+		{"1..2", nil, false},
+		{"1.x", nil, false},
+	}
+
+	for _, test := range tests {
+		got, ok := versionNumbers(test.version)
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("versionNumbers(%q) = %v, %v, expected %v, %v", test.version, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.4", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{"1.2.3", "1.2.3", false},
+		{"1.10.0", "1.9.9", true},
+		{"v2.0", "1.99.99", true},
+		{"1.2.1", "1.2", true},
+		{"1.2.0", "1.2", false},
+		{"1.2", "1.2.1", false},
+		{"1.2.3-rc1", "1.2.3", false},
+		{"1.2.3", "dev", false},
+		{"dev", "1.2.3", false},
+	}
+
+	for _, test := range tests {
+		if got := IsNewer(test.a, test.b); got != test.want {
+			t.Errorf("IsNewer(%q, %q) = %v, expected %v", test.a, test.b, got, test.want)
+		}
+	}
+}
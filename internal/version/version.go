@@ -1,6 +1,7 @@
 package version
 
 var (
+	Version     string // set at build time, the newest release of the changelog otherwise
 	BuildDate   string
 	GoVersion   string
 	AppName     = "Melodix"
@@ -1,8 +1,8 @@
 package discord
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"time"
 
 	embed "github.com/Clinet/discordgo-embed"
@@ -11,9 +11,11 @@ import (
 
 	"github.com/keshon/melodix-discord-player/internal/config"
 	"github.com/keshon/melodix-discord-player/internal/version"
-	"github.com/keshon/melodix-discord-player/music/utils"
 )
 
+// aboutUpdateTimeout bounds asking GitHub for the latest release while answering the about command.
+const aboutUpdateTimeout = 5 * time.Second
+
 // handleAboutCommand handles the about command for Discord.
 func (d *Discord) handleAboutCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
 	d.changeAvatar(s)
@@ -23,14 +25,7 @@ func (d *Discord) handleAboutCommand(s *discordgo.Session, m *discordgo.MessageC
 		slog.Fatalf("Error loading config: %v", err)
 	}
 
-	var hostname string
-	if os.Getenv("HOST") == "" {
-		hostname = config.RestHostname
-	} else {
-		hostname = os.Getenv("HOST") // from docker environment
-	}
-
-	avatarUrl := utils.InferProtocolByPort(hostname, 443) + hostname + "/avatar/random?" + fmt.Sprint(time.Now().UnixNano())
+	avatarUrl := avatarURL(config.RestBaseURL(), 512)
 	slog.Info(avatarUrl)
 
 	title := getRandomAboutTitlePhrase()
@@ -40,12 +35,30 @@ func (d *Discord) handleAboutCommand(s *discordgo.Session, m *discordgo.MessageC
 
 	embedMsg := embed.NewEmbed().
 		SetDescription(embedStr).
+		AddField("```"+version.Current()+"```", "Version").
 		AddField("```"+version.BuildDate+"```", "Build date").
 		AddField("```"+version.GoVersion+"```", "Go version").
 		AddField("```Created by Innokentiy Sokolov```", "[Linkedin](https://www.linkedin.com/in/keshon), [GitHub](https://github.com/keshon), [Homepage](https://keshon.ru)").
 		InlineAllFields().
 		SetImage(avatarUrl).
-		SetColor(0x9f00d4).SetFooter(version.AppFullName).MessageEmbed
+		SetColor(0x9f00d4).SetFooter(version.AppFullName)
+
+	if config.UpdateCheck {
+		ctx, cancel := context.WithTimeout(d.ctx, aboutUpdateTimeout)
+		latest, err := version.CheckLatestRelease(ctx)
+		cancel()
+		if err != nil {
+			slog.Warnf("Error checking for updates: %v", err)
+		} else if latest.UpdateAvailable() {
+			embedMsg.AddField("```"+latest.Version+"```", fmt.Sprintf("[Update available](%v)", latest.URL))
+		}
+	}
+
+	SendEmbed(s, m.Message.ChannelID, embedMsg.MessageEmbed)
+}
 
-	s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+// avatarURL returns the REST URL of the avatar of the day scaled to size. The date in it lets Discord's
+// media proxy cache the image for the day instead of fetching it again for every embed.
+func avatarURL(restBaseURL string, size int) string {
+	return fmt.Sprintf("%v/avatar/random?size=%d&day=%v", restBaseURL, size, time.Now().UTC().Format("2006-01-02"))
 }
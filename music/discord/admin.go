@@ -0,0 +1,144 @@
+package discord
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+const (
+	// failureReportDays is how many days the failure report covers by default
+	failureReportDays = 7
+	// maxFailureReportDays limits the days of a failure report
+	maxFailureReportDays = 30
+)
+
+// handleAdminCommand handles the admin command for Discord.
+func (d *Discord) handleAdminCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(strings.ToLower(param))
+	if len(words) == 1 && words[0] == "dedupe" {
+		d.mergeDuplicateTracks(s, m)
+		return
+	}
+	if len(words) == 0 || len(words) > 2 || words[0] != "report" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vadmin report [days]`, e.g. `%vadmin report 14`, or `%vadmin dedupe` (bot owner only)", d.prefix, d.prefix, d.prefix))
+		return
+	}
+
+	days := failureReportDays
+	if len(words) == 2 {
+		n, err := strconv.Atoi(words[1])
+		if err != nil || n < 1 || n > maxFailureReportDays {
+			d.sendTextEmbed(s, m, fmt.Sprintf("The report covers 1 to %v days", maxFailureReportDays))
+			return
+		}
+		days = n
+	}
+
+	d.sendFailureReport(s, m, days)
+}
+
+// sendFailureReport sends the daily failures of the guild by source, and today's by stage and error class.
+func (d *Discord) sendFailureReport(s *discordgo.Session, m *discordgo.MessageCreate, days int) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	failures, err := history.NewHistory().GetFailures(d.GuildID, today.AddDate(0, 0, 1-days))
+	if err != nil {
+		slog.Errorf("Error getting failures: %v", err)
+		d.sendTextEmbed(s, m, "Error getting the failure report")
+		return
+	}
+
+	if len(failures) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("📉 No failures to resolve or play tracks in the last %v days", days))
+		return
+	}
+
+	content := fmt.Sprintf("📉 Failures of the last %v days (UTC)\n", days)
+	for i := 0; i < days; i++ {
+		day := today.AddDate(0, 0, -i)
+		content += fmt.Sprintf("\n`%v` %v", day.Format("2006-01-02"), describeFailuresBySource(failuresOfDay(failures, day)))
+	}
+
+	todays := failuresOfDay(failures, today)
+	if len(todays) > 0 {
+		content += "\n\n**Today**"
+		for _, failure := range todays {
+			content += fmt.Sprintf("\n%v · %v · %v: %d", failure.Source, failure.Stage, failure.Class, failure.Count)
+		}
+	}
+
+	d.sendTextEmbed(s, m, content)
+}
+
+func failuresOfDay(failures []db.SourceFailure, day time.Time) []db.SourceFailure {
+	var ofDay []db.SourceFailure
+	for _, failure := range failures {
+		if failure.Day.UTC().Equal(day) {
+			ofDay = append(ofDay, failure)
+		}
+	}
+	return ofDay
+}
+
+// describeFailuresBySource sums failures by source, e.g. "12 — YouTube 10, Stream 2".
+func describeFailuresBySource(failures []db.SourceFailure) string {
+	var total int64
+	bySource := make(map[string]int64)
+	for _, failure := range failures {
+		total += failure.Count
+		bySource[failure.Source] += failure.Count
+	}
+	if total == 0 {
+		return "0"
+	}
+
+	sources := make([]string, 0, len(bySource))
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if bySource[sources[i]] != bySource[sources[j]] {
+			return bySource[sources[i]] > bySource[sources[j]]
+		}
+		return sources[i] < sources[j]
+	})
+
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		parts[i] = fmt.Sprintf("%v %d", source, bySource[source])
+	}
+	return fmt.Sprintf("%d — %v", total, strings.Join(parts, ", "))
+}
+
+// mergeDuplicateTracks merges tracks stored more than once under the same YouTube ID. Tracks are shared by
+// all guilds, so only the bot owner may.
+func (d *Discord) mergeDuplicateTracks(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !d.IsBotOwner(m.Author.ID) {
+		d.sendTextEmbed(s, m, "Tracks are shared by all servers, only the bot owner can merge duplicates")
+		return
+	}
+
+	merge, err := db.MergeDuplicateTracks(db.DB)
+	if err != nil {
+		slog.Errorf("Error merging duplicate tracks: %v", err)
+		d.sendTextEmbed(s, m, "Error merging duplicate tracks")
+		return
+	}
+	history.InvalidateCache(d.GuildID)
+
+	if merge.Merged == 0 {
+		d.sendTextEmbed(s, m, "🧹 No duplicate tracks found")
+		return
+	}
+	d.sendConfirmation(s, m, "🧹", fmt.Sprintf("Merged %d duplicate rows of %d tracks, their history, requests, ratings and tags were combined", merge.Merged, merge.Songs))
+}
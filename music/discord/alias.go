@@ -0,0 +1,240 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	maxCustomAliases   = 50
+	maxCustomAliasSize = 32
+)
+
+// reservedCommands are handled by the guild manager outside the registry and can't be used as aliases.
+var reservedCommands = []string{"guild"}
+
+// handleAliasCommand handles the custom alias command for Discord.
+func (d *Discord) handleAliasCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(strings.ToLower(param))
+	if len(words) == 0 || (len(words) == 1 && words[0] == "list") {
+		d.listAliases(s, m)
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change aliases")
+		return
+	}
+
+	switch words[0] {
+	case "command":
+		if len(words) < 3 {
+			break
+		}
+		d.addAliases(s, m, words[1], words[2:])
+		return
+	case "remove":
+		if len(words) < 2 {
+			break
+		}
+		d.removeAliases(s, m, words[1:])
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%valias`, `%valias command [command] [alias...]`, `%valias remove [alias...]`, e.g. `%valias command skip s`", d.prefix, d.prefix, d.prefix, d.prefix))
+}
+
+// listAliases shows the aliases defined for the guild.
+func (d *Discord) listAliases(s *discordgo.Session, m *discordgo.MessageCreate) {
+	text := d.describeCustomAliases()
+	if text == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🏷️ No custom aliases yet, use `%valias command [command] [alias]` to add one, e.g. `%valias command skip s`", d.prefix, d.prefix))
+		return
+	}
+
+	d.sendTextEmbed(s, m, "🏷️ Custom aliases\n"+text)
+}
+
+// addAliases stores new aliases of a command, rejecting aliases that are taken.
+func (d *Discord) addAliases(s *discordgo.Session, m *discordgo.MessageCreate, command string, aliases []string) {
+	cmd := commandByName(command)
+	if cmd == nil {
+		cmd = findCommand(command)
+	}
+	if cmd == nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown command `%v`", command))
+		return
+	}
+	if cmd.run == nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("`%v%v` can't be aliased", d.prefix, cmd.name))
+		return
+	}
+	canonical := cmd.name
+
+	var added []string
+	var problems []string
+	for _, alias := range aliases {
+		if err := d.checkAlias(alias); err != nil {
+			problems = append(problems, fmt.Sprintf("`%v` %v", alias, err))
+			continue
+		}
+
+		record := &db.CommandAlias{GuildID: d.GuildID, Alias: alias, Command: canonical, CreatedBy: m.Author.ID}
+		if err := db.CreateCommandAlias(record); err != nil {
+			slog.Errorf("Error creating alias %v: %v", alias, err)
+			problems = append(problems, fmt.Sprintf("`%v` could not be saved", alias))
+			continue
+		}
+
+		d.aliasesMu.Lock()
+		d.customAliases[alias] = canonical
+		d.aliasesMu.Unlock()
+
+		added = append(added, fmt.Sprintf("`%v%v`", d.prefix, alias))
+	}
+
+	var text string
+	if len(added) > 0 {
+		text = fmt.Sprintf("🏷️ %v now run `%v%v`", strings.Join(added, ", "), d.prefix, canonical)
+	}
+	if len(problems) > 0 {
+		text += "\n" + strings.Join(problems, "\n")
+	}
+	d.sendTextEmbed(s, m, strings.TrimSpace(text))
+}
+
+// checkAlias reports why an alias can't be added, nil if it's free.
+func (d *Discord) checkAlias(alias string) error {
+	if len(alias) > maxCustomAliasSize {
+		return fmt.Errorf("is longer than %d characters", maxCustomAliasSize)
+	}
+
+	if cmd := findCommand(alias); cmd != nil {
+		return fmt.Errorf("is already used by `%v`", cmd.name)
+	}
+
+	for _, reserved := range reservedCommands {
+		if alias == reserved {
+			return errors.New("is reserved")
+		}
+	}
+
+	d.aliasesMu.RLock()
+	defer d.aliasesMu.RUnlock()
+
+	if command, exists := d.customAliases[alias]; exists {
+		return fmt.Errorf("is already an alias of `%v`", command)
+	}
+	if d.macroSteps(alias) != nil {
+		return errors.New("is already a macro")
+	}
+	if len(d.customAliases) >= maxCustomAliases {
+		return fmt.Errorf("exceeds the limit of %d aliases", maxCustomAliases)
+	}
+
+	return nil
+}
+
+// removeAliases deletes aliases of the guild.
+func (d *Discord) removeAliases(s *discordgo.Session, m *discordgo.MessageCreate, aliases []string) {
+	var removed, unknown []string
+	for _, alias := range aliases {
+		deleted, err := db.DeleteCommandAlias(d.GuildID, alias)
+		if err != nil {
+			slog.Errorf("Error deleting alias %v: %v", alias, err)
+			d.sendTextEmbed(s, m, "Error removing aliases")
+			return
+		}
+		if deleted == 0 {
+			unknown = append(unknown, "`"+alias+"`")
+			continue
+		}
+
+		d.aliasesMu.Lock()
+		delete(d.customAliases, alias)
+		d.aliasesMu.Unlock()
+
+		removed = append(removed, "`"+alias+"`")
+	}
+
+	var text string
+	if len(removed) > 0 {
+		text = fmt.Sprintf("🏷️ Removed %v", strings.Join(removed, ", "))
+	}
+	if len(unknown) > 0 {
+		text += fmt.Sprintf("\nNo such aliases: %v", strings.Join(unknown, ", "))
+	}
+	d.sendTextEmbed(s, m, strings.TrimSpace(text))
+}
+
+// loadCommandAliases reads the aliases defined for the guild.
+func (d *Discord) loadCommandAliases() {
+	aliases := make(map[string]string)
+
+	records, err := db.GetCommandAliasesByGuildID(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error loading command aliases: %v", err)
+	}
+	for _, record := range records {
+		aliases[record.Alias] = record.Command
+	}
+
+	d.aliasesMu.Lock()
+	d.customAliases = aliases
+	d.aliasesMu.Unlock()
+}
+
+// customAlias returns the command of an alias defined for the guild, empty if there is none.
+func (d *Discord) customAlias(alias string) string {
+	d.aliasesMu.RLock()
+	defer d.aliasesMu.RUnlock()
+
+	return d.customAliases[alias]
+}
+
+// customAliasesOf returns the guild's aliases of a command with the prefix, sorted.
+func (d *Discord) customAliasesOf(command string) []string {
+	d.aliasesMu.RLock()
+	var aliases []string
+	for alias, c := range d.customAliases {
+		if c == command {
+			aliases = append(aliases, fmt.Sprintf("`%v%v`", d.prefix, alias))
+		}
+	}
+	d.aliasesMu.RUnlock()
+
+	sort.Strings(aliases)
+	return aliases
+}
+
+// describeCustomAliases lists the guild's aliases grouped by command, one command per line.
+func (d *Discord) describeCustomAliases() string {
+	d.aliasesMu.RLock()
+	byCommand := make(map[string][]string)
+	for alias, command := range d.customAliases {
+		byCommand[command] = append(byCommand[command], fmt.Sprintf("`%v%v`", d.prefix, alias))
+	}
+	d.aliasesMu.RUnlock()
+
+	commands := make([]string, 0, len(byCommand))
+	for command := range byCommand {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	var builder strings.Builder
+	for _, command := range commands {
+		aliases := byCommand[command]
+		sort.Strings(aliases)
+		builder.WriteString(fmt.Sprintf("**%v%v**: %v\n", d.prefix, command, strings.Join(aliases, ", ")))
+	}
+	return builder.String()
+}
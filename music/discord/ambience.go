@@ -0,0 +1,46 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/music/player"
+	"github.com/keshon/melodix-discord-player/music/sources"
+)
+
+// ambiencePrefix selects an ambience loop in play commands, e.g. "ambience:rain"
+const ambiencePrefix = "ambience:"
+
+// handleAmbienceCommand plays an endless ambience loop, or lists the presets without a parameter.
+func (d *Discord) handleAmbienceCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	name := strings.ToLower(strings.TrimSpace(param))
+	if name == "" || strings.ContainsAny(name, " /\\") {
+		d.changeAvatar(s)
+		d.sendTextEmbed(s, m, fmt.Sprintf("🌧 Ambience presets: %v\n\nPlay one with `%vambience [preset]`, it loops until skipped or stopped", formatTags(newAmbience().Presets()), d.prefix))
+		return
+	}
+
+	d.handlePlayCommand(s, m, ambiencePrefix+name, false)
+}
+
+// newAmbience returns the ambience source of the configured directory and URLs.
+func newAmbience() *sources.Ambience {
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Errorf("Error loading config: %v", err)
+		return sources.NewAmbience("", nil)
+	}
+	return sources.NewAmbience(config.AmbienceDir, config.AmbienceURLs)
+}
+
+// fetchAmbience returns the loop of an ambience preset.
+func fetchAmbience(name string) ([]*player.Song, error) {
+	song, err := newAmbience().FetchAmbience(name)
+	if err != nil {
+		return nil, err
+	}
+	return []*player.Song{song}, nil
+}
@@ -0,0 +1,131 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// apiTokenPrefix makes Melodix tokens recognizable, e.g. for secret scanners.
+const apiTokenPrefix = "mdx_"
+
+// handleAPITokenCommand handles the REST API token management command for Discord.
+func (d *Discord) handleAPITokenCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(param)
+	if len(words) == 0 {
+		d.listAPITokens(s, m)
+		return
+	}
+
+	switch words[0] {
+	case "add":
+		if len(words) < 2 || (words[1] != db.ScopeRead && words[1] != db.ScopeControl) {
+			break
+		}
+		d.addAPIToken(s, m, words[1], strings.Join(words[2:], " "))
+		return
+	case "remove":
+		if len(words) != 2 {
+			break
+		}
+		id, err := strconv.ParseUint(words[1], 10, 64)
+		if err != nil {
+			break
+		}
+		d.removeAPIToken(s, m, uint(id))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vtoken`, `%vtoken add [read/control] [name]`, `%vtoken remove [id]`", d.prefix, d.prefix, d.prefix))
+}
+
+// listAPITokens shows API tokens of the guild.
+func (d *Discord) listAPITokens(s *discordgo.Session, m *discordgo.MessageCreate) {
+	tokens, err := db.GetAPITokensByGuildID(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting API tokens: %v", err)
+		d.sendTextEmbed(s, m, "Error getting API tokens")
+		return
+	}
+
+	if len(tokens) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔑 No API tokens yet, use `%vtoken add [read/control] [name]` to create one", d.prefix))
+		return
+	}
+
+	content := "🔑 API tokens\n"
+	for _, token := range tokens {
+		content += fmt.Sprintf("\n`%v` %v `%v`", token.ID, token.Name, token.Scope)
+		if !token.LastUsed.IsZero() {
+			content += ", last used " + relativeTimestamp(token.LastUsed)
+		}
+	}
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// addAPIToken creates a token for the guild's REST routes and sends it to the author by DM.
+func (d *Discord) addAPIToken(s *discordgo.Session, m *discordgo.MessageCreate, scope, name string) {
+	secret, err := randomHex(32)
+	if err != nil {
+		slog.Errorf("Error generating API token: %v", err)
+		d.sendTextEmbed(s, m, "Error creating API token")
+		return
+	}
+	secret = apiTokenPrefix + secret
+
+	dm, err := s.UserChannelCreate(m.Author.ID)
+	if err != nil {
+		slog.Warnf("Error opening DM channel: %v", err)
+		d.sendTextEmbed(s, m, "I can't send you a direct message with the token, please allow DMs from server members")
+		return
+	}
+
+	if name == "" {
+		name = "unnamed"
+	}
+
+	token := &db.APIToken{
+		TokenHash: db.HashSecret(secret),
+		GuildID:   d.GuildID,
+		Scope:     scope,
+		Name:      name,
+		CreatedBy: m.Author.ID,
+	}
+	if err := db.CreateAPIToken(token); err != nil {
+		slog.Errorf("Error creating API token: %v", err)
+		d.sendTextEmbed(s, m, "Error creating API token")
+		return
+	}
+
+	_, err = SendMessage(s, dm.ID, fmt.Sprintf("🔑 API token `%v` created with `%v` scope for server `%v`\n\nToken: `%v`\n\n"+
+		"Send it in the `Authorization: Bearer <token>` header to the `/guilds/%v/...` REST routes. It's shown only once.", token.ID, scope, d.GuildID, secret, d.GuildID))
+	if err != nil {
+		slog.Warnf("Error sending API token: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔑 API token `%v` created, it was sent to you by DM", token.ID))
+}
+
+// removeAPIToken deletes an API token of the guild.
+func (d *Discord) removeAPIToken(s *discordgo.Session, m *discordgo.MessageCreate, id uint) {
+	deleted, err := db.DeleteAPIToken(d.GuildID, id)
+	if err != nil {
+		slog.Errorf("Error deleting API token: %v", err)
+		d.sendTextEmbed(s, m, "Error deleting API token")
+		return
+	}
+
+	if deleted == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No API token `%v` found", id))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔑 API token `%v` deleted", id))
+}
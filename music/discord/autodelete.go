@@ -0,0 +1,77 @@
+package discord
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// quietDeleteDelay keeps command messages around for a moment in quiet mode so the reaction can be seen.
+const quietDeleteDelay = 5 * time.Second
+
+// handleAutoDeleteCommand handles the autodelete command for Discord.
+func (d *Discord) handleAutoDeleteCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.handleToggleSetting(s, m, param, toggleSetting{
+		command: "autodelete",
+		show: func() string {
+			state := "off"
+			if d.deleteCommands {
+				state = "on"
+			}
+			return fmt.Sprintf("🧽 Deleting command messages is `%v`\nUse `%vautodelete [on/off]` to change it", state, d.prefix)
+		},
+		check: func(value string) string {
+			if value == "on" && !canManageMessages(s, m.ChannelID) {
+				return "⚠️ I need the **Manage Messages** permission in this channel to delete command messages"
+			}
+			return ""
+		},
+		apply: func(value string) { d.deleteCommands = value == "on" },
+		store: func(settings *db.GuildSettings) { settings.DeleteCommands = d.deleteCommands },
+		changed: func(value string) string {
+			return fmt.Sprintf("🧽 Deleting command messages is `%v`", value)
+		},
+	})
+}
+
+// deleteCommandMessage removes the processed command message if the guild asked for it.
+// Channels where the bot lacks Manage Messages are left untouched, users are told once per channel.
+func (d *Discord) deleteCommandMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !d.deleteCommands {
+		return
+	}
+
+	if !canManageMessages(s, m.ChannelID) {
+		if _, notified := d.deleteNotices.LoadOrStore(m.ChannelID, true); !notified {
+			d.sendTextEmbed(s, m, "⚠️ Command messages can't be deleted here, I need the **Manage Messages** permission in this channel")
+		}
+		return
+	}
+	d.deleteNotices.Delete(m.ChannelID)
+
+	del := func() {
+		if err := s.ChannelMessageDelete(m.ChannelID, m.ID); err != nil {
+			slog.Warnf("Error deleting command message: %v", err)
+		}
+	}
+
+	if d.getVerbosity() == VerbosityQuiet {
+		time.AfterFunc(quietDeleteDelay, del)
+		return
+	}
+	del()
+}
+
+// canManageMessages reports whether the bot may delete other users' messages in the channel.
+func canManageMessages(s *discordgo.Session, channelID string) bool {
+	perms, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+	if err != nil {
+		slog.Warnf("Error getting bot permissions: %v", err)
+		return false
+	}
+
+	return perms&discordgo.PermissionAdministrator != 0 || perms&discordgo.PermissionManageMessages != 0
+}
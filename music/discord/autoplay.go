@@ -0,0 +1,166 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/player"
+	"github.com/keshon/melodix-discord-player/music/recommend"
+	"github.com/keshon/melodix-discord-player/music/sources"
+)
+
+const (
+	// autoplayAttempts is how many candidates are looked up before autoplay gives up
+	autoplayAttempts = 3
+	// autoplayHistoryTracks is how many of the most played tracks of the history are candidates
+	autoplayHistoryTracks = 200
+	// autoplayHistoryPick is how many of the best history candidates one is picked from, so it doesn't always play the same
+	autoplayHistoryPick = 5
+)
+
+// handleAutoplayCommand shows or changes whether related songs keep playing once the queue ran out.
+func (d *Discord) handleAutoplayCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	if param == "" {
+		state := "off"
+		if d.Player.IsAutoplay() {
+			state = "on"
+		}
+		d.sendTextEmbed(s, m, fmt.Sprintf("📻 Autoplay is `%v`\nUse `%vautoplay [on/off]` to change it", state, d.prefix))
+		return
+	}
+
+	if param != "on" && param != "off" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vautoplay [on/off]`", d.prefix))
+		return
+	}
+
+	d.setAutoplay(param == "on")
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.Autoplay = param == "on"
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving autoplay setting: %v", err)
+	}
+
+	content := "Autoplay is off, I leave once the queue is done"
+	if param == "on" {
+		content = "Autoplay is on, once the queue is done I keep playing tracks related to the last one"
+	}
+	d.sendConfirmation(s, m, "📻", content)
+}
+
+// setAutoplay turns autoplay of the player on or off.
+func (d *Discord) setAutoplay(enabled bool) {
+	if enabled {
+		d.Player.SetAutoplay(d.autoplaySong)
+	} else {
+		d.Player.SetAutoplay(nil)
+	}
+}
+
+// autoplaySong finds a song to play after the last one: the video YouTube suggests after it that fits the taste
+// of the guild best, or a track of the guild's history by the same artist or among its favorites otherwise.
+// It finds none while the autoplay feature is off for the guild.
+func (d *Discord) autoplaySong(ctx context.Context, last *player.Song) (*player.Song, error) {
+	if !d.featureEnabled(FeatureAutoplay) {
+		return nil, nil
+	}
+
+	profile, err := recommend.LoadProfile(ctx, d.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	scorer := recommend.NewScorer(profile)
+	youtube := sources.NewYoutube()
+
+	if last.Source == player.SourceYouTube && last.ID != "" {
+		related, err := youtube.RelatedVideos(ctx, last.ID)
+		if err != nil {
+			slog.Warnf("Error getting videos related to %q: %v", last.Title, err)
+		}
+		if song := autoplayFirstPlayable(ctx, youtube, scorer.Rank(related)); song != nil {
+			return song, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	candidates, err := d.autoplayHistoryCandidates(ctx, last)
+	if err != nil {
+		return nil, err
+	}
+	return autoplayFirstPlayable(ctx, youtube, pickFromHistory(scorer.Rank(candidates), last)), nil
+}
+
+// autoplayHistoryCandidates lists the most played tracks of the guild's history other than the last song.
+func (d *Discord) autoplayHistoryCandidates(ctx context.Context, last *player.Song) ([]recommend.Candidate, error) {
+	list, err := history.NewHistory().GetHistory(ctx, d.GuildID, "play_count", autoplayHistoryTracks, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []recommend.Candidate
+	for _, elem := range list {
+		if elem.Track.YTID == last.ID || elem.Track.URL == "" {
+			continue
+		}
+		candidates = append(candidates, recommend.Candidate{ID: elem.Track.YTID, Title: elem.Track.Name, URL: elem.Track.URL})
+	}
+	return candidates, nil
+}
+
+// pickFromHistory orders ranked history candidates for autoplay: the best one by the artist of the last song first,
+// then one of the best few picked at random, then the rest.
+func pickFromHistory(ranked []recommend.Candidate, last *player.Song) []recommend.Candidate {
+	if len(ranked) == 0 {
+		return nil
+	}
+
+	if artist := recommend.Artist(last.Title); artist != "" {
+		for i, candidate := range ranked {
+			if recommend.Artist(candidate.Title) == artist {
+				return append([]recommend.Candidate{candidate}, append(ranked[:i:i], ranked[i+1:]...)...)
+			}
+		}
+	}
+
+	pick := rand.Intn(min(len(ranked), autoplayHistoryPick))
+	return append([]recommend.Candidate{ranked[pick]}, append(ranked[:pick:pick], ranked[pick+1:]...)...)
+}
+
+// autoplayFirstPlayable looks up the candidates in order and returns the first that can be played,
+// nil if none of the first autoplayAttempts can.
+func autoplayFirstPlayable(ctx context.Context, youtube *sources.Youtube, candidates []recommend.Candidate) *player.Song {
+	for i, candidate := range candidates {
+		if i == autoplayAttempts || ctx.Err() != nil {
+			break
+		}
+
+		var songs []*player.Song
+		var err error
+		if source := sources.LinkSourceFor(candidate.URL); source != nil {
+			songs, err = source.FetchSongsByURLs(ctx, []string{candidate.URL})
+		} else {
+			var song *player.Song
+			song, err = youtube.GetSongFromVideoURL(ctx, candidate.URL)
+			songs = []*player.Song{song}
+		}
+		if err != nil || len(songs) == 0 {
+			slog.Warnf("Skipping autoplay candidate %v: %v", candidate.URL, err)
+			continue
+		}
+		return songs[0]
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// handleBadgeCommand handles the badge command for Discord.
+func (d *Discord) handleBadgeCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	paths := fmt.Sprintf("`/guilds/%v/nowplaying.json`\n`/guilds/%v/nowplaying.svg`", d.GuildID, d.GuildID)
+
+	d.handleToggleSetting(s, m, param, toggleSetting{
+		command: "badge",
+		show: func() string {
+			if d.publicNowPlaying {
+				return fmt.Sprintf("📛 The current track is public on the REST API:\n%v\nUse `%vbadge off` to hide it", paths, d.prefix)
+			}
+			return fmt.Sprintf("📛 The current track is not public\nUse `%vbadge on` to show it on websites with a JSON endpoint and an SVG badge", d.prefix)
+		},
+		apply: func(value string) { d.publicNowPlaying = value == "on" },
+		store: func(settings *db.GuildSettings) { settings.PublicNowPlaying = d.publicNowPlaying },
+		changed: func(value string) string {
+			if value == "off" {
+				return "📛 The current track is no longer public"
+			}
+			return "📛 The current track is now public on the REST API:\n" + paths
+		},
+	})
+}
+
+// IsNowPlayingPublic reports whether the guild opted in to the public now playing endpoints.
+func (d *Discord) IsNowPlayingPublic() bool {
+	return d.publicNowPlaying
+}
@@ -0,0 +1,189 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
+)
+
+const (
+	// changelogShown is how many releases the changelog command shows without a parameter
+	changelogShown = 3
+	// changelogMaxShown is how many releases the changelog command shows at most
+	changelogMaxShown = 10
+	// announceDelay lets the guild state and the sender settle after a start before announcing an update
+	announceDelay = 30 * time.Second
+	// announceChanges is how many changes of a release the update announcement lists
+	announceChanges = 5
+)
+
+// handleChangelogCommand shows the newest releases of the changelog, as many as asked for, or one release.
+func (d *Discord) handleChangelogCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	releases := version.Releases()
+	if len(releases) == 0 {
+		d.sendTextEmbed(s, m, "The changelog is empty")
+		return
+	}
+
+	shown := changelogShown
+	if param != "" {
+		if count, err := strconv.Atoi(param); err == nil && count > 0 {
+			shown = min(count, changelogMaxShown)
+		} else if release, ok := version.FindRelease(param); ok {
+			releases, shown = []version.Release{release}, 1
+		} else {
+			d.sendTextEmbed(s, m, fmt.Sprintf("No release `%v` in the changelog, use `%vchangelog` to list the latest", param, d.prefix))
+			return
+		}
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetTitle(fmt.Sprintf("📰 Changelog — running %v", version.Current())).
+		SetColor(0x9f00d4).SetFooter(version.AppFullName)
+	for _, release := range releases[:min(shown, len(releases))] {
+		embedMsg.AddField(releaseTitle(release), describeChanges(release.Changes, len(release.Changes)))
+	}
+
+	SendEmbed(s, m.Message.ChannelID, embedMsg.MessageEmbed)
+}
+
+// releaseTitle names the release with its date.
+func releaseTitle(release version.Release) string {
+	if release.Date == "" {
+		return release.Version
+	}
+	return fmt.Sprintf("%v · %v", release.Version, release.Date)
+}
+
+// describeChanges lists the first changes as bullet points, within the length Discord allows for a field.
+func describeChanges(changes []string, count int) string {
+	var lines []string
+	length := 0
+	for i, change := range changes {
+		line := "• " + change
+		if i == count || length+len(line)+1 > 1000 {
+			lines = append(lines, fmt.Sprintf("…and %v more", len(changes)-i))
+			break
+		}
+		lines = append(lines, line)
+		length += len(line) + 1
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleUpdatesSetting shows or changes the channel "What's new" embeds are posted to after an update.
+func (d *Discord) handleUpdatesSetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting guild settings: %v", err)
+		d.sendTextEmbed(s, m, "Error getting the update announcements setting")
+		return
+	}
+
+	switch value {
+	case "":
+		var text string
+		switch {
+		case settings.NoUpdateAnnouncements:
+			text = "📰 Update announcements are `off`"
+		case settings.UpdatesChannelID != "":
+			text = fmt.Sprintf("📰 What's new after an update is posted in <#%v>", settings.UpdatesChannelID)
+		default:
+			text = "📰 What's new after an update is posted in the system messages channel of the server"
+		}
+		d.sendTextEmbed(s, m, fmt.Sprintf("%v\nUse `%vsettings updates [here/on/off]` to change it", text, d.prefix))
+		return
+	case "here", "on", "off":
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings updates [here/on/off]`", d.prefix))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	settings.NoUpdateAnnouncements = value == "off"
+	switch value {
+	case "here":
+		settings.UpdatesChannelID = m.ChannelID
+	case "on":
+		settings.UpdatesChannelID = ""
+	}
+	if err := db.SaveGuildSettings(settings); err != nil {
+		slog.Errorf("Error saving update announcements setting: %v", err)
+	}
+
+	switch value {
+	case "here":
+		d.sendTextEmbed(s, m, "📰 What's new after an update is posted in this channel")
+	case "on":
+		d.sendTextEmbed(s, m, "📰 What's new after an update is posted in the system messages channel of the server")
+	default:
+		d.sendTextEmbed(s, m, "📰 Update announcements disabled")
+	}
+}
+
+// announceUpdate posts the changes of the running version once per version to the guild,
+// if update announcements are enabled in the config and not turned off for the guild.
+func (d *Discord) announceUpdate() {
+	config, err := config.NewConfig()
+	if err != nil || !config.AnnounceUpdates {
+		return
+	}
+
+	release, ok := version.FindRelease(version.Current())
+	if !ok {
+		return
+	}
+
+	time.Sleep(announceDelay)
+	if !d.InstanceActive {
+		return
+	}
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Warnf("Error getting guild settings for the update announcement: %v", err)
+		return
+	}
+	if settings.NoUpdateAnnouncements || settings.AnnouncedVersion == release.Version {
+		return
+	}
+
+	channelID := settings.UpdatesChannelID
+	if channelID == "" {
+		if guild, err := d.Session.State.Guild(d.GuildID); err == nil {
+			channelID = guild.SystemChannelID
+		}
+	}
+	if channelID == "" {
+		return
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetTitle(fmt.Sprintf("📰 What's new in %v %v", version.AppName, release.Version)).
+		SetDescription(describeChanges(release.Changes, announceChanges)).
+		AddField("", fmt.Sprintf("`%vchangelog` shows earlier releases, `%vsettings updates off` stops these announcements", d.prefix, d.prefix)).
+		SetColor(0x9f00d4).SetFooter(version.AppFullName)
+	if _, err := SendEmbed(d.Session, channelID, embedMsg.MessageEmbed); err != nil {
+		slog.Warnf("Error announcing update to channel %v: %v", channelID, err)
+		return
+	}
+
+	settings.AnnouncedVersion = release.Version
+	if err := db.SaveGuildSettings(settings); err != nil {
+		slog.Errorf("Error saving announced version: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package discord
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+)
+
+// bulkDeleteMaxAge is how old messages may be for Discord to delete them in bulk, older ones are deleted one by one.
+// A minute is kept as margin for clock skew.
+const bulkDeleteMaxAge = 14*24*time.Hour - time.Minute
+
+// statusMessages remembers now-playing/queue messages posted per channel
+// so superseded ones can be deleted during long sessions.
+type statusMessages struct {
+	sync.Mutex
+	kept     int                 // messages kept per channel, 0 keeps all
+	channels map[string][]string // channel ID to message IDs, oldest first
+}
+
+func newStatusMessages(kept int) *statusMessages {
+	return &statusMessages{
+		kept:     kept,
+		channels: make(map[string][]string),
+	}
+}
+
+// add remembers the message and returns IDs of messages that fell out of retention.
+func (sm *statusMessages) add(channelID, messageID string) []string {
+	sm.Lock()
+	defer sm.Unlock()
+
+	if sm.kept <= 0 {
+		return nil
+	}
+
+	ids := sm.channels[channelID]
+	for _, id := range ids {
+		if id == messageID {
+			return nil // edited in place, already tracked
+		}
+	}
+	ids = append(ids, messageID)
+
+	var stale []string
+	if len(ids) > sm.kept {
+		stale = append(stale, ids[:len(ids)-sm.kept]...)
+		ids = ids[len(ids)-sm.kept:]
+	}
+	sm.channels[channelID] = ids
+
+	return stale
+}
+
+// trackStatusMessage registers a now-playing/queue message and deletes superseded ones in the channel.
+func (d *Discord) trackStatusMessage(s *discordgo.Session, channelID, messageID string) {
+	stale := d.statusMessages.add(channelID, messageID)
+	if len(stale) == 0 {
+		return
+	}
+
+	recent, old := splitByAge(stale, time.Now())
+	if len(recent) > 0 {
+		if err := s.ChannelMessagesBulkDelete(channelID, recent); err != nil {
+			slog.Warnf("Error deleting superseded status messages: %v", err)
+		}
+	}
+	for _, id := range old {
+		if err := s.ChannelMessageDelete(channelID, id); err != nil {
+			slog.Warnf("Error deleting superseded status message: %v", err)
+		}
+	}
+}
+
+// splitByAge splits message IDs into those young enough to be deleted in bulk and older ones,
+// the age of a message is part of its ID.
+func splitByAge(ids []string, now time.Time) (recent, old []string) {
+	for _, id := range ids {
+		created, err := discordgo.SnowflakeTimestamp(id)
+		if err != nil || now.Sub(created) >= bulkDeleteMaxAge {
+			old = append(old, id)
+			continue
+		}
+		recent = append(recent, id)
+	}
+	return recent, old
+}
@@ -0,0 +1,43 @@
+package discord
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snowflakeAt returns a message ID created at t.
+func snowflakeAt(t time.Time) string {
+	return strconv.FormatInt((t.UnixMilli()-1420070400000)<<22, 10)
+}
+
+func TestSplitByAge(t *testing.T) {
+	now := time.Now()
+	fresh := snowflakeAt(now.Add(-time.Hour))
+	almost := snowflakeAt(now.Add(-13 * 24 * time.Hour))
+	expired := snowflakeAt(now.Add(-15 * 24 * time.Hour))
+
+	recent, old := splitByAge([]string{fresh, expired, almost, "invalid"}, now)
+	if got, want := strings.Join(recent, " "), fresh+" "+almost; got != want {
+		t.Errorf("Recent messages are %v, expected %v", got, want)
+	}
+	if got, want := strings.Join(old, " "), expired+" invalid"; got != want {
+		t.Errorf("Old messages are %v, expected %v", got, want)
+	}
+}
+
+func TestStatusMessagesRetention(t *testing.T) {
+	sm := newStatusMessages(2)
+	for _, id := range []string{"1", "2", "2"} {
+		if stale := sm.add("c", id); stale != nil {
+			t.Errorf("Message %v made %v stale", id, stale)
+		}
+	}
+	if stale := sm.add("c", "3"); strings.Join(stale, " ") != "1" {
+		t.Errorf("Stale messages are %v, expected 1", stale)
+	}
+	if stale := sm.add("other", "4"); stale != nil {
+		t.Errorf("Messages of another channel made %v stale", stale)
+	}
+}
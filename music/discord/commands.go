@@ -0,0 +1,258 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// commandCategory groups commands in help.
+type commandCategory string
+
+const (
+	categoryPlayback       commandCategory = "Playback"
+	categoryQueue          commandCategory = "Queue"
+	categoryHistory        commandCategory = "History"
+	categoryGeneral        commandCategory = "General"
+	categoryAdministration commandCategory = "Administration"
+)
+
+// commandCategories is the order of categories in help.
+var commandCategories = []commandCategory{categoryPlayback, categoryQueue, categoryHistory, categoryGeneral, categoryAdministration}
+
+// permissionLevel is who may use a command.
+type permissionLevel int
+
+const (
+	permissionEveryone      permissionLevel = iota
+	permissionAdminToChange                 // everyone may view the setting, administrators change it
+	permissionAdmin                         // administrators only, checked before the command runs
+	permissionDJ                            // members with the DJ role and administrators, checked before the command runs
+	permissionDJToChange                    // everyone may view the setting, DJs and administrators change it
+	permissionOwner                         // the bot owner only, checked before the command runs
+)
+
+// String describes who may use commands of the level.
+func (level permissionLevel) String() string {
+	switch level {
+	case permissionAdminToChange:
+		return "Everyone may view, administrators change"
+	case permissionAdmin:
+		return "Administrators"
+	case permissionDJ:
+		return "DJs and administrators"
+	case permissionDJToChange:
+		return "Everyone may view, DJs and administrators change"
+	case permissionOwner:
+		return "Bot owner"
+	default:
+		return "Everyone"
+	}
+}
+
+// command describes a bot command for dispatching and help.
+type command struct {
+	name        string
+	aliases     []string
+	usages      []string // parameter forms shown in help, "" for none
+	examples    []string // parameters of typical uses, shown in the command's detailed help
+	description string
+	category    commandCategory
+	permission  permissionLevel
+	lockable    bool           // only DJs may use it while the queue is locked
+	feature     string         // experimental feature the command belongs to, "" for none
+	run         commandHandler // nil for commands handled by the guild manager
+}
+
+type commandHandler func(d *Discord, s *discordgo.Session, m *discordgo.MessageCreate, param string)
+
+// withoutParam adapts a handler of a command that takes no parameter.
+func withoutParam(handler func(d *Discord, s *discordgo.Session, m *discordgo.MessageCreate)) commandHandler {
+	return func(d *Discord, s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+		handler(d, s, m)
+	}
+}
+
+// playHandler adapts the play command, enqueueOnly adds tracks without starting playback.
+func playHandler(enqueueOnly bool) commandHandler {
+	return func(d *Discord, s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+		d.handlePlayCommand(s, m, param, enqueueOnly)
+	}
+}
+
+// seekHandler adapts the forward and rewind commands.
+func seekHandler(forward bool) commandHandler {
+	return func(d *Discord, s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+		d.handleSeekCommand(s, m, param, forward)
+	}
+}
+
+// rateHandler adapts the like and dislike commands.
+func rateHandler(value int) commandHandler {
+	return func(d *Discord, s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+		d.handleRateCommand(s, m, value)
+	}
+}
+
+// commands is the registry of built-in commands. Names and aliases are matched in this order,
+// so the pause, resume and play commands sharing ">" toggle playback.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "pause", aliases: []string{"!", ">"}, description: "Pause", category: categoryPlayback, run: (*Discord).runPause},
+		{name: "resume", aliases: []string{"play", ">"}, description: "Resume", category: categoryPlayback, run: (*Discord).runResume},
+		{name: "play", aliases: []string{"p", ">"}, usages: []string{"[title/url/id/stream]", "[url] fresh", "tag:[tag] [shuffle]", "playlist:[name]"}, examples: []string{"never gonna give you up", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "42", "tag:synthwave shuffle", "https://www.youtube.com/watch?v=dQw4w9WgXcQ fresh"}, description: "Play", category: categoryPlayback, lockable: true, run: playHandler(false)},
+		{name: "skip", aliases: []string{"next", "ff", ">>"}, description: "Skip track", category: categoryPlayback, lockable: true, run: withoutParam((*Discord).handleSkipCommand)},
+		{name: "forward", aliases: []string{"fwd"}, usages: []string{"", "[step]"}, examples: []string{"30s", "1m30s", "1:30"}, description: "Seek forward", category: categoryPlayback, lockable: true, run: seekHandler(true)},
+		{name: "rewind", aliases: []string{"rw", "back"}, usages: []string{"", "[step]"}, examples: []string{"30s", "90"}, description: "Seek backward", category: categoryPlayback, lockable: true, run: seekHandler(false)},
+		{name: "seek", usages: []string{"[position]"}, examples: []string{"1m30s", "90", "1:30", "0"}, description: "Seek to a position", category: categoryPlayback, lockable: true, run: (*Discord).handleSeekToCommand},
+		{name: "ambience", aliases: []string{"amb"}, usages: []string{"", "[preset]"}, examples: []string{"rain", "brown"}, description: "Play ambience loop", category: categoryPlayback, lockable: true, run: (*Discord).handleAmbienceCommand},
+		{name: "like", description: "Like track", category: categoryPlayback, run: rateHandler(1)},
+		{name: "dislike", description: "Dislike track", category: categoryPlayback, run: rateHandler(-1)},
+		{name: "lock", description: "Lock queue", category: categoryQueue, permission: permissionDJ, run: withoutParam((*Discord).handleLockCommand)},
+		{name: "unlock", description: "Unlock queue", category: categoryQueue, permission: permissionDJ, run: withoutParam((*Discord).handleUnlockCommand)},
+		{name: "now", aliases: []string{"np", "playing"}, description: "Show current track", category: categoryQueue, run: withoutParam((*Discord).handleNowCommand)},
+		{name: "list", aliases: []string{"queue", "l", "q"}, description: "Show queue", category: categoryQueue, run: withoutParam((*Discord).handleShowQueueCommand)},
+		{name: "add", aliases: []string{"a", "+"}, usages: []string{"[title/url/id]"}, examples: []string{"bohemian rhapsody", "https://www.youtube.com/playlist?list=PL..."}, description: "Add track", category: categoryQueue, lockable: true, run: playHandler(true)},
+		{name: "eta", aliases: []string{"when"}, usages: []string{"", "[position]", "@me"}, examples: []string{"3", "@me"}, description: "When a track plays", category: categoryQueue, run: (*Discord).handleEtaCommand},
+		{name: "restore", usages: []string{""}, description: "Restore queue saved before a restart", category: categoryQueue, lockable: true, run: withoutParam((*Discord).handleRestoreCommand)},
+		{name: "order", aliases: []string{"o"}, usages: []string{"[fifo/fair/weighted/shortest]"}, examples: []string{"fair"}, description: "Queue order", category: categoryQueue, lockable: true, run: (*Discord).handleOrderCommand},
+		{name: "loop", aliases: []string{"repeat"}, usages: []string{"[track/queue/off]"}, examples: []string{"queue"}, description: "Repeat track or queue", category: categoryQueue, lockable: true, run: (*Discord).handleLoopCommand},
+		{name: "autoplay", aliases: []string{"radio"}, usages: []string{"[on/off]"}, examples: []string{"on"}, description: "Keep playing related tracks", category: categoryQueue, lockable: true, feature: FeatureAutoplay, run: (*Discord).handleAutoplayCommand},
+		{name: "shuffle", aliases: []string{"mix"}, description: "Shuffle queue", category: categoryQueue, lockable: true, run: withoutParam((*Discord).handleShuffleCommand)},
+		{name: "remove", aliases: []string{"rm", "-"}, usages: []string{"[position]"}, examples: []string{"3"}, description: "Remove track from queue", category: categoryQueue, lockable: true, run: (*Discord).handleRemoveCommand},
+		{name: "move", aliases: []string{"mv"}, usages: []string{"[from] [to]"}, examples: []string{"5 1"}, description: "Move track in queue", category: categoryQueue, lockable: true, run: (*Discord).handleMoveCommand},
+		{name: "clear", aliases: []string{"cl"}, description: "Clear queue", category: categoryQueue, lockable: true, run: withoutParam((*Discord).handleClearCommand)},
+		{name: "undo", aliases: []string{"u"}, description: "Undo queue change", category: categoryQueue, lockable: true, run: withoutParam((*Discord).handleUndoCommand)},
+		{name: "register", description: "Enable commands listening", category: categoryAdministration, permission: permissionAdmin},
+		{name: "unregister", description: "Disable commands listening", category: categoryAdministration, permission: permissionAdmin},
+		{name: "verbosity", usages: []string{"[quiet/normal/verbose]"}, description: "Confirmations", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleVerbosityCommand},
+		{name: "autodelete", usages: []string{"[on/off]"}, description: "Delete command messages", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleAutoDeleteCommand},
+		{name: "requests", usages: []string{"[here/off]"}, description: "Song request channel", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleRequestChannelCommand},
+		{name: "badge", aliases: []string{"public"}, usages: []string{"[on/off]"}, description: "Public now playing badge", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleBadgeCommand},
+		{name: "feed", aliases: []string{"rss"}, usages: []string{"[on/off]"}, description: "Public history feed", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleFeedCommand},
+		{name: "hook", aliases: []string{"webhook"}, usages: []string{"", "add [query path] [requester path]", "remove [token]"}, examples: []string{"add data.message data.user"}, description: "Webhooks", category: categoryAdministration, permission: permissionAdmin, run: (*Discord).handleHookCommand},
+		{name: "token", aliases: []string{"apitoken"}, usages: []string{"", "add [read/control] [name]", "remove [id]"}, examples: []string{"add read grafana"}, description: "API tokens", category: categoryAdministration, permission: permissionAdmin, run: (*Discord).handleAPITokenCommand},
+		{name: "djrole", aliases: []string{"dj"}, usages: []string{"[@role/off]"}, examples: []string{"@DJ"}, description: "DJ role", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleDJRoleCommand},
+		{name: "locale", aliases: []string{"lang"}, usages: []string{"[en/de/ru]"}, description: "Language", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleLocaleCommand},
+		{name: "timezone", aliases: []string{"tz"}, usages: []string{"[name]"}, examples: []string{"Europe/Berlin"}, description: "Timezone", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleTimezoneCommand},
+		{name: "settings", aliases: []string{"set"}, usages: []string{"region [region/auto]", "suggestions [on/off]", "greeting [clip/message/off] [url/jingle/text]", "summary [on/off]", "topic [here/off]", "updates [here/on/off]"}, examples: []string{"region rotterdam", "region auto", "suggestions off", "greeting clip hello.ogg", "summary on", "topic here", "updates here"}, description: "Settings", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleSettingsCommand},
+		{name: "exit", aliases: []string{"stop", "e", "x"}, description: "Stop and exit", category: categoryGeneral, lockable: true, run: withoutParam((*Discord).handleStopCommand)},
+		{name: "help", aliases: []string{"h", "?"}, usages: []string{"", "[category]", "[command]"}, examples: []string{"playback", "skip"}, description: "Show help", category: categoryGeneral, run: (*Discord).handleHelpCommand},
+		{name: "history", aliases: []string{"time", "t"}, usages: []string{"", "duration", "count", "skipped", "count 2", "tag:[tag]"}, examples: []string{"count", "skipped 2", "tag:synthwave"}, description: "Show history", category: categoryHistory, run: (*Discord).handleHistoryCommand},
+		{name: "tag", aliases: []string{"tags"}, usages: []string{"", "[id]", "[id] [tag...]", "remove [id] [tag...]"}, examples: []string{"5 synthwave", "remove 5 synthwave"}, description: "Tag tracks", category: categoryHistory, run: (*Discord).handleTagCommand},
+		{name: "playlist", aliases: []string{"playlists", "pl"}, usages: []string{"", "[name]", "remove [name]"}, examples: []string{"queue-20240101-2130"}, description: "Saved playlists", category: categoryHistory, run: (*Discord).handlePlaylistCommand},
+		{name: "stats", aliases: []string{"graph"}, usages: []string{"", "graph", "sessions", "bot"}, description: "Listening stats", category: categoryHistory, run: (*Discord).handleStatsCommand},
+		{name: "wrapped", aliases: []string{"recap"}, usages: []string{"[year] [me]"}, examples: []string{"2025 me"}, description: "Yearly recap", category: categoryHistory, run: (*Discord).handleWrappedCommand},
+		{name: "about", aliases: []string{"version", "v"}, description: "Show version", category: categoryGeneral, run: withoutParam((*Discord).handleAboutCommand)},
+		{name: "changelog", aliases: []string{"news"}, usages: []string{"", "[count]", "[version]"}, examples: []string{"5", "1.2.0"}, description: "Recent releases", category: categoryGeneral, run: (*Discord).handleChangelogCommand},
+		{name: "listen", aliases: []string{"share"}, usages: []string{"", "[duration]", "revoke"}, examples: []string{"30m", "revoke"}, description: "Listen-along link", category: categoryGeneral, run: (*Discord).handleListenCommand},
+		{name: "debug", aliases: []string{"diag"}, description: "Playback diagnostics", category: categoryGeneral, run: withoutParam((*Discord).handleDebugCommand)},
+		{name: "soundcheck", aliases: []string{"testtone"}, description: "Play a test tone", category: categoryGeneral, lockable: true, run: withoutParam((*Discord).handleSoundcheckCommand)},
+		{name: "quality", aliases: []string{"bitrate"}, usages: []string{"", "[low/normal/high]"}, description: "Encode quality", category: categoryGeneral, permission: permissionDJToChange, run: (*Discord).handleQualityCommand},
+		{name: "admin", usages: []string{"report [days]", "dedupe"}, examples: []string{"report", "report 14", "dedupe"}, description: "Failure report and track maintenance", category: categoryAdministration, permission: permissionAdmin, run: (*Discord).handleAdminCommand},
+		{name: "export", usages: []string{"data", "session [id]"}, examples: []string{"session 12"}, description: "Export guild data", category: categoryAdministration, permission: permissionAdmin, run: (*Discord).handleExportCommand},
+		{name: "purge", usages: []string{"data"}, description: "Delete guild data", category: categoryAdministration, permission: permissionAdmin, run: (*Discord).handlePurgeCommand},
+		{name: "forgetme", description: "Forget my data", category: categoryGeneral, run: withoutParam((*Discord).handleForgetMeCommand)},
+		{name: "macro", aliases: []string{"macros"}, usages: []string{"", "set [name] = [command]; [command]...", "run [name]", "remove [name]", "at [name] [HH:MM]", "when [name] [members]", "triggers", "untrigger [id]"}, examples: []string{"set party = shuffle; order fair; play https://www.youtube.com/playlist?list=PL...", "run party", "at party 20:00", "when party 3"}, description: "Command macros", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleMacroCommand},
+		{name: "event", aliases: []string{"events"}, usages: []string{"", "[HH:MM] [title]", "[YYYY-MM-DD] [HH:MM] [title] | [title/url]", "cancel [id]"}, examples: []string{"21:00 Synthwave night", "2024-06-01 20:30 Album release | https://www.youtube.com/playlist?list=PL...", "cancel 2"}, description: "Listening parties", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleEventCommand},
+		{name: "flags", aliases: []string{"flag"}, usages: []string{"", "[feature] [on/off/default]", "[feature] [on/off/default] all"}, examples: []string{"autoplay on", "autoplay on all", "slash default"}, description: "Experimental features", category: categoryAdministration, permission: permissionOwner, run: (*Discord).handleFlagsCommand},
+		{name: "update", usages: []string{"", "install"}, description: "Install the latest release", category: categoryAdministration, permission: permissionOwner, run: (*Discord).handleUpdateCommand},
+		{name: "alias", usages: []string{"", "command [command] [alias...]", "remove [alias...]"}, examples: []string{"command skip s n", "remove s"}, description: "Custom aliases", category: categoryAdministration, permission: permissionAdminToChange, run: (*Discord).handleAliasCommand},
+	}
+}
+
+// findCommand returns the first command whose name or alias matches the word, nil if there is none.
+func findCommand(word string) *command {
+	for _, cmd := range commands {
+		if cmd.name == word {
+			return cmd
+		}
+		for _, alias := range cmd.aliases {
+			if alias == word {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// lookupCommand returns the command of a name, alias or custom alias of the guild, nil if there is none.
+// Unlike findCommand, canonical names take precedence so "play" is the play command rather than the resume alias.
+func (d *Discord) lookupCommand(word string) *command {
+	if cmd := commandByName(word); cmd != nil {
+		return cmd
+	}
+	if cmd := findCommand(word); cmd != nil {
+		return cmd
+	}
+	return commandByName(d.customAlias(word))
+}
+
+// commandByName returns the command with the canonical name, nil if there is none.
+func commandByName(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runPause pauses playback, or resumes or plays like the resume command otherwise.
+func (d *Discord) runPause(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	if param == "" && d.Player.GetCurrentStatus() == player.StatusPlaying {
+		d.handlePauseCommand(s, m)
+		return
+	}
+	d.runResume(s, m, param)
+}
+
+// runResume resumes paused playback, or plays the parameter otherwise.
+func (d *Discord) runResume(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	if param == "" && d.Player.GetCurrentStatus() != player.StatusPlaying {
+		d.handleResumeCommand(s, m)
+		return
+	}
+	d.handlePlayCommand(s, m, param, false)
+}
+
+// helpLine describes the command for help with the prefix, e.g. "**Show queue**: `!list` \nAliases: `!queue`, `!l`".
+func (cmd *command) helpLine(prefix string) string {
+	usages := cmd.usages
+	if len(usages) == 0 {
+		usages = []string{""}
+	}
+
+	forms := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		forms = append(forms, "`"+strings.TrimSpace(prefix+cmd.name+" "+usage)+"`")
+	}
+
+	line := fmt.Sprintf("**%v**: %v", cmd.description, strings.Join(forms, ", "))
+	switch cmd.permission {
+	case permissionAdmin:
+		line += " *(admins)*"
+	case permissionAdminToChange:
+		line += " *(admins change)*"
+	case permissionDJ:
+		line += " *(DJs)*"
+	case permissionDJToChange:
+		line += " *(DJs change)*"
+	case permissionOwner:
+		line += " *(bot owner)*"
+	}
+
+	if len(cmd.aliases) > 0 {
+		aliases := make([]string, 0, len(cmd.aliases))
+		for _, alias := range cmd.aliases {
+			aliases = append(aliases, "`"+prefix+alias+"`")
+		}
+		line += " \nAliases: " + strings.Join(aliases, ", ")
+	}
+
+	return line + "\n"
+}
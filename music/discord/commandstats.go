@@ -0,0 +1,163 @@
+package discord
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// commandSamples is how many recent runs of each command percentiles are computed over
+	commandSamples = 100
+	// slowestCommands is the number of commands listed in command metrics, slowest first
+	slowestCommands = 5
+)
+
+// CommandStats are the runs of a command since the bot started and percentiles of recent run times in milliseconds.
+type CommandStats struct {
+	Name     string  `json:"name"`
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures"` // runs that crashed or replied with an error
+	P50      float64 `json:"p50_ms"`
+	P95      float64 `json:"p95_ms"`
+}
+
+// CommandMetrics summarize the commands run by all guilds since the bot started.
+type CommandMetrics struct {
+	Runs     int            `json:"runs"`
+	Failures int            `json:"failures"`
+	Slowest  []CommandStats `json:"slowest"` // by p95
+}
+
+// commandRecord keeps the runs of a command.
+type commandRecord struct {
+	runs      int
+	failures  int
+	durations []time.Duration // of recent runs
+}
+
+// commandRun is a command being run, failed once it replied with an error.
+type commandRun struct {
+	failed bool
+}
+
+// commandCollector records the run time and outcome of commands of all guilds.
+type commandCollector struct {
+	sync.Mutex
+	commands map[string]*commandRecord
+	running  map[*discordgo.MessageCreate]*commandRun
+}
+
+var commandStats = &commandCollector{
+	commands: make(map[string]*commandRecord),
+	running:  make(map[*discordgo.MessageCreate]*commandRun),
+}
+
+// timeCommand runs a command and records how long it took and whether it failed. A panic counts
+// as a failure and is passed on.
+func timeCommand(m *discordgo.MessageCreate, name string, run func()) {
+	c := commandStats
+	current := &commandRun{}
+
+	c.Lock()
+	c.running[m] = current
+	c.Unlock()
+
+	start := time.Now()
+	defer func() {
+		r := recover()
+
+		c.Lock()
+		delete(c.running, m)
+		c.add(name, time.Since(start), current.failed || r != nil)
+		c.Unlock()
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	run()
+}
+
+// add records a run, the caller holds the lock.
+func (c *commandCollector) add(name string, duration time.Duration, failed bool) {
+	record, exists := c.commands[name]
+	if !exists {
+		record = &commandRecord{}
+		c.commands[name] = record
+	}
+
+	record.runs++
+	if failed {
+		record.failures++
+	}
+	record.durations = append(record.durations, duration)
+	if len(record.durations) > commandSamples {
+		record.durations = record.durations[len(record.durations)-commandSamples:]
+	}
+}
+
+// noteReply marks the command answering a message as failed if the reply reports an error.
+func (c *commandCollector) noteReply(m *discordgo.MessageCreate, text string) {
+	if !strings.HasPrefix(text, "Error") {
+		return
+	}
+
+	c.Lock()
+	if current, ok := c.running[m]; ok {
+		current.failed = true
+	}
+	c.Unlock()
+}
+
+// GetCommandMetrics returns the number of commands run and failed since the bot started and the slowest commands.
+func GetCommandMetrics() CommandMetrics {
+	c := commandStats
+	c.Lock()
+	defer c.Unlock()
+
+	metrics := CommandMetrics{Slowest: []CommandStats{}}
+	for name, record := range c.commands {
+		metrics.Runs += record.runs
+		metrics.Failures += record.failures
+
+		sorted := append([]time.Duration(nil), record.durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		metrics.Slowest = append(metrics.Slowest, CommandStats{
+			Name:     name,
+			Runs:     record.runs,
+			Failures: record.failures,
+			P50:      commandPercentile(sorted, 50),
+			P95:      commandPercentile(sorted, 95),
+		})
+	}
+
+	sort.Slice(metrics.Slowest, func(i, j int) bool {
+		if metrics.Slowest[i].P95 != metrics.Slowest[j].P95 {
+			return metrics.Slowest[i].P95 > metrics.Slowest[j].P95
+		}
+		return metrics.Slowest[i].Name < metrics.Slowest[j].Name
+	})
+	if len(metrics.Slowest) > slowestCommands {
+		metrics.Slowest = metrics.Slowest[:slowestCommands]
+	}
+
+	return metrics
+}
+
+// commandPercentile returns the nearest rank percentile of sorted durations in milliseconds.
+func commandPercentile(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
@@ -0,0 +1,70 @@
+package discord
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// controlPrefix starts the custom IDs of the player control buttons, the command they run follows.
+const controlPrefix = "control:"
+
+// playerControl is a button of player embeds running a command without parameter.
+type playerControl struct {
+	command string
+	emoji   string
+	label   string
+}
+
+// playerControls are the buttons attached to the now playing and queue messages.
+var playerControls = []playerControl{
+	{command: "pause", emoji: "⏯", label: "Pause/Resume"},
+	{command: "skip", emoji: "⏭", label: "Skip"},
+	{command: "exit", emoji: "⏹", label: "Stop"},
+	{command: "shuffle", emoji: "🔀", label: "Shuffle"},
+	{command: "loop", emoji: "🔁", label: "Loop"},
+}
+
+// controlComponents builds the row of player control buttons.
+func controlComponents() []discordgo.MessageComponent {
+	buttons := make([]discordgo.MessageComponent, 0, len(playerControls))
+	for _, control := range playerControls {
+		buttons = append(buttons, discordgo.Button{
+			Label:    control.label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: controlPrefix + control.command,
+			Emoji:    discordgo.ComponentEmoji{Name: control.emoji},
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// onControlButton runs the command of a pressed player control button like the slash command of it,
+// with the permissions and queue lock of the member who pressed it.
+func (d *Discord) onControlButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID != d.GuildID || !d.InstanceActive || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	name, ok := strings.CutPrefix(i.MessageComponentData().CustomID, controlPrefix)
+	if !ok {
+		return
+	}
+	cmd := commandByName(name)
+	if cmd == nil || cmd.run == nil {
+		return
+	}
+
+	// The loop button steps through the repeat modes
+	var param string
+	if cmd.name == "loop" {
+		next := (int(d.Player.GetRepeatMode()) + 1) % len(player.RepeatModeNames)
+		param = player.RepeatMode(next).String()
+	}
+
+	runInteractionCommand(s, i, d.prefix, cmd.name, param, func(m *discordgo.MessageCreate, _, param string) {
+		d.dispatchCommand(s, m, cmd, param)
+	})
+}
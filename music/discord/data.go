@@ -0,0 +1,121 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"gorm.io/gorm"
+)
+
+// handleExportCommand handles the export command for Discord.
+func (d *Discord) handleExportCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	words := strings.Fields(param)
+	switch {
+	case param == "data":
+		d.exportGuildData(s, m)
+	case len(words) == 2 && words[0] == "session":
+		d.exportSession(s, m, strings.TrimPrefix(words[1], "#"))
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vexport data`, `%vexport session [id]`", d.prefix, d.prefix))
+	}
+}
+
+// exportGuildData sends everything stored for the guild as a JSON file.
+func (d *Discord) exportGuildData(s *discordgo.Session, m *discordgo.MessageCreate) {
+	data, err := db.ExportGuildData(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error exporting guild data: %v", err)
+		d.sendTextEmbed(s, m, "Error exporting guild data")
+		return
+	}
+
+	description := fmt.Sprintf("📦 Exported %v history entries and %v tracks stored for this guild.", len(data.History), len(data.Tracks))
+	d.sendExport(s, m, description, fmt.Sprintf("melodix-%v-%v.json", d.GuildID, time.Now().Format("20060102-150405")), data)
+}
+
+// exportSession sends a listening session of the guild and its plays as a JSON file.
+func (d *Discord) exportSession(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	id, err := strconv.ParseUint(param, 10, 0)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vexport session [id]`, IDs are shown by `%vstats sessions`", d.prefix, d.prefix))
+		return
+	}
+
+	data, err := db.ExportListeningSession(d.GuildID, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No session `#%v`, IDs are shown by `%vstats sessions`", id, d.prefix))
+		return
+	}
+	if err != nil {
+		slog.Errorf("Error exporting listening session: %v", err)
+		d.sendTextEmbed(s, m, "Error exporting the session")
+		return
+	}
+
+	description := fmt.Sprintf("📦 Exported session `#%v` with %v plays.", id, len(data.Plays))
+	d.sendExport(s, m, description, fmt.Sprintf("melodix-%v-session-%v.json", d.GuildID, id), data)
+}
+
+// sendExport sends exported data as a JSON file.
+func (d *Discord) sendExport(s *discordgo.Session, m *discordgo.MessageCreate, description, fileName string, data interface{}) {
+	content, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		slog.Errorf("Error formatting export as JSON: %v", err)
+		d.sendTextEmbed(s, m, "Error exporting data")
+		return
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetDescription(description).
+		SetColor(0x9f00d4).SetFooter(version.AppFullName).MessageEmbed
+
+	_, err = SendComplex(s, m.Message.ChannelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embedMsg},
+		Files: []*discordgo.File{
+			{
+				Name:        fileName,
+				ContentType: "application/json",
+				Reader:      bytes.NewReader(content),
+			},
+		},
+	})
+	if err != nil {
+		slog.Warnf("Error sending export message: %v", err)
+	}
+}
+
+// handlePurgeCommand handles the purge command for Discord.
+func (d *Discord) handlePurgeCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	if param != "data" && param != "data confirm" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vpurge data`", d.prefix))
+		return
+	}
+
+	if param != "data confirm" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("⚠️ This permanently deletes all history and settings stored for this guild.\nType `%vpurge data confirm` to proceed.", d.prefix))
+		return
+	}
+
+	if err := db.PurgeGuildData(d.GuildID); err != nil {
+		slog.Errorf("Error purging guild data: %v", err)
+		d.sendTextEmbed(s, m, "Error purging guild data")
+		return
+	}
+	history.InvalidateCache(d.GuildID)
+	d.loadCommandAliases()
+	d.loadCommandMacros()
+	d.loadMacroTriggers()
+
+	d.sendTextEmbed(s, m, "🗑️ All data stored for this guild has been deleted")
+}
@@ -0,0 +1,127 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// debugTimelineEvents is how many of the latest events the debug command shows.
+const debugTimelineEvents = 5
+
+// Diagnostics is what the debug command shows about the playback of a guild, for attaching to bug reports.
+type Diagnostics struct {
+	GuildID          string  `json:"guild_id"`
+	Version          string  `json:"version"`
+	Status           string  `json:"status"`
+	GatewayLatencyMs float64 `json:"gateway_latency_ms"`
+	VoiceRTTMs       float64 `json:"voice_rtt_ms,omitempty"` // of the latest voice server probe
+	SuggestedRegion  string  `json:"suggested_region,omitempty"`
+	player.Metrics
+	player.Diagnostics
+	HistoryBuffer history.BufferMetrics `json:"history_buffer"` // shared by all guilds
+}
+
+// Diagnostics returns the playback diagnostics of the guild.
+func (d *Discord) Diagnostics() Diagnostics {
+	diagnostics := Diagnostics{
+		GuildID:       d.GuildID,
+		Version:       version.Current(),
+		Status:        d.Player.GetCurrentStatus().String(),
+		Metrics:       d.Player.GetMetrics(),
+		Diagnostics:   d.Player.GetDiagnostics(),
+		HistoryBuffer: history.GetBufferMetrics(),
+	}
+	if d.Session != nil {
+		diagnostics.GatewayLatencyMs = float64(d.Session.HeartbeatLatency()) / float64(time.Millisecond)
+	}
+	if probe := d.latestVoiceProbe(); probe != nil {
+		diagnostics.VoiceRTTMs = float64(probe.RTT) / float64(time.Millisecond)
+		diagnostics.SuggestedRegion = probe.SuggestedRegion
+	}
+	return diagnostics
+}
+
+// handleDebugCommand handles the debug command for Discord.
+// It shows streaming counters to help diagnose stuttering playback.
+func (d *Discord) handleDebugCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	diagnostics := d.Diagnostics()
+	metrics := diagnostics.Metrics
+
+	encoder := "not running"
+	if metrics.EncoderRunning {
+		encoder = fmt.Sprintf("PID %v · %.1f%% CPU · %.1f MiB", metrics.EncoderPID, metrics.EncoderCPU, float64(metrics.EncoderMemory)/(1<<20))
+	}
+
+	lastError := "none"
+	if errors := diagnostics.Errors; len(errors) > 0 {
+		lastError = describeEvent(errors[len(errors)-1])
+	}
+
+	timeline := "nothing yet"
+	if events := diagnostics.Timeline; len(events) > 0 {
+		var lines []string
+		for _, event := range events[max(0, len(events)-debugTimelineEvents):] {
+			lines = append(lines, describeEvent(event))
+		}
+		timeline = strings.Join(lines, "\n")
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetTitle("🩺 Playback diagnostics").
+		SetDescription(fmt.Sprintf("%v %v · Gateway latency: %v\n_Late frames point to a slow host or source, send stalls and jitter to the network._", d.Player.GetCurrentStatus().StringEmoji(), d.Player.GetCurrentStatus().String(), s.HeartbeatLatency().Round(time.Millisecond))).
+		AddField("Encoder", encoder).
+		AddField("Frames sent", fmt.Sprint(metrics.FramesSent)).
+		AddField("Late frames", fmt.Sprint(metrics.FramesLate)).
+		AddField("Dropped frames", fmt.Sprint(metrics.FramesDropped)).
+		AddField("Send stalls", fmt.Sprint(metrics.SendStalls)).
+		AddField("Jitter", fmt.Sprintf("%.1f ms", metrics.JitterMs)).
+		AddField("Jitter buffer", fmt.Sprintf("%v frames", metrics.BufferFrames)).
+		AddField("Voice server", d.describeVoiceRTT()).
+		AddField("Time to first frame", fmt.Sprintf("p50 %.0f ms · p95 %.0f ms (%v plays)", metrics.Latency.FirstFrameP50, metrics.Latency.FirstFrameP95, metrics.Latency.Plays)).
+		AddField("Request to first frame", fmt.Sprintf("p50 %.0f ms · p95 %.0f ms (%v requests)", metrics.Latency.TotalP50, metrics.Latency.TotalP95, metrics.Latency.Requests)).
+		AddField("Reconnects", fmt.Sprintf("%v to source, %v restarts", metrics.Reconnects, metrics.Restarts)).
+		AddField("History buffer", describeHistoryBuffer(diagnostics.HistoryBuffer)).
+		AddField("Running", fmt.Sprintf("%v plays, %v goroutines", diagnostics.Plays, diagnostics.Goroutines)).
+		InlineAllFields().
+		AddField("Last error", lastError).
+		AddField("Timeline", timeline).
+		Truncate().
+		SetFooter(version.AppFullName).
+		SetColor(0x9f00d4).MessageEmbed
+
+	SendEmbed(s, m.Message.ChannelID, embedMsg)
+}
+
+// describeVoiceRTT returns the round trip time of the latest voice server probe.
+func (d *Discord) describeVoiceRTT() string {
+	probe := d.latestVoiceProbe()
+	if probe == nil {
+		return "not probed"
+	}
+
+	if probe.SuggestedRegion != "" {
+		return fmt.Sprintf("%v RTT, try region %v", probe.RTT.Round(time.Millisecond), probe.SuggestedRegion)
+	}
+	return fmt.Sprintf("%v RTT", probe.RTT.Round(time.Millisecond))
+}
+
+// describeHistoryBuffer summarizes the history write buffers shared by all guilds.
+func describeHistoryBuffer(buffer history.BufferMetrics) string {
+	text := fmt.Sprintf("%v pending stats, %v waiting, %v dropped", buffer.PendingStats, buffer.BufferedWrites, buffer.Dropped)
+	if buffer.Unavailable {
+		text += " · database unavailable"
+	}
+	return text
+}
+
+// describeEvent returns a line of the player's timeline with the time it happened.
+func describeEvent(event player.Event) string {
+	return fmt.Sprintf("`%v` %v: %v", event.Time.Format("15:04:05"), event.Kind, event.Message)
+}
@@ -1,15 +1,22 @@
 package discord
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/assets"
 	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/locale"
 	"github.com/keshon/melodix-discord-player/music/player"
-	"github.com/keshon/melodix-discord-player/music/utils"
 )
 
 // BotInstance represents an instance of a Discord bot.
@@ -25,33 +32,188 @@ type Discord struct {
 	GuildID              string
 	InstanceActive       bool
 	prefix               string
+	ownerID              string // Discord user allowed to administrate every guild, including by DM
+	updater              *Updater
 	lastChangeAvatarTime time.Time
 	rateLimitDuration    time.Duration
+	verbosity            string
+	locale               *locale.Locale
+	statusMessages       *statusMessages
+	ratedMessages        *ratedMessages
+	queueSnapshots       *queueSnapshots
+	deleteCommands       bool
+	deleteNotices        sync.Map // channel IDs already told about missing Manage Messages
+	requestChannelID     string
+	publicNowPlaying     bool
+	publicHistory        bool
+	djRoleID             string
+	queueLocked          atomic.Bool // only DJs may change the queue, see the lock command
+	commandSuggestions   bool
+	ducking              bool
+	regionSuggestions    bool // look up a closer region when the voice server is slow
+	voiceProbe           voiceProbeState
+	aliasesMu            sync.RWMutex
+	customAliases        map[string]string // canonical command by alias defined for the guild
+	macrosMu             sync.RWMutex
+	macros               map[string][]string // steps by macro name defined for the guild
+	triggersMu           sync.RWMutex
+	triggers             []db.MacroTrigger
+	armedTriggers        map[uint]bool // voice triggers that run once their channel fills up, by trigger ID
+	session              listeningSession
+	topic                channelTopic
+	stage                stageTopic
+	nowPlaying           nowPlaying
+	lookups              pendingLookups
+	traces               commandTraces
+	savedQueue           savedQueue
+	removeHandlers       []func()        // removes the session handlers added by Start
+	ctx                  context.Context // ends on Shutdown, playback and lookups of the instance run under it
+	cancel               context.CancelFunc
 }
 
-// NewDiscord creates a new instance of Discord.
-func NewDiscord(session *discordgo.Session, guildID string) *Discord {
+// NewDiscord creates a new instance of Discord, the updater is shared by the instances of all guilds.
+func NewDiscord(session *discordgo.Session, guildID string, updater *Updater) *Discord {
 	config, err := config.NewConfig()
 	if err != nil {
 		slog.Fatalf("Error loading config: %v", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Discord{
-		Player:            player.NewPlayer(guildID),
-		Players:           make(map[string]player.IPlayer),
-		Session:           session,
-		InstanceActive:    true,
-		prefix:            config.DiscordCommandPrefix,
-		rateLimitDuration: time.Minute * 10,
+		Player:             player.NewPlayer(guildID, config),
+		Players:            make(map[string]player.IPlayer),
+		Session:            session,
+		InstanceActive:     true,
+		prefix:             config.DiscordCommandPrefix,
+		ownerID:            config.DiscordOwnerID,
+		regionSuggestions:  config.VoiceRegionSuggestions,
+		updater:            updater,
+		rateLimitDuration:  time.Minute * 10,
+		statusMessages:     newStatusMessages(config.DiscordStatusMessagesKept),
+		ratedMessages:      newRatedMessages(),
+		queueSnapshots:     newQueueSnapshots(),
+		customAliases:      make(map[string]string),
+		macros:             make(map[string][]string),
+		commandSuggestions: true,
+		ctx:                ctx,
+		cancel:             cancel,
 	}
 }
 
+// Context returns the context of the instance, it ends on Shutdown.
+func (d *Discord) Context() context.Context {
+	return d.ctx
+}
+
+// Shutdown saves the queue of the instance one last time, stops its playback, calls off its lookups and removes
+// its session handlers, so events of the guild reach only the instance replacing it.
+func (d *Discord) Shutdown() {
+	if d.InstanceActive {
+		d.saveQueue()
+	}
+	d.cancel()
+
+	for _, remove := range d.removeHandlers {
+		remove()
+	}
+	d.removeHandlers = nil
+}
+
 // Start starts the Discord instance.
 func (d *Discord) Start(guildID string) {
 	slog.Infof(`Discord instance started for guild id %v`, guildID)
 
-	d.Session.AddHandler(d.Commands)
+	for _, handler := range []interface{}{
+		d.Commands,
+		d.onVoiceServerUpdate,
+		d.onVoiceStateUpdate,
+		d.onSessionVoiceState,
+		d.onMessageReactionAdd,
+		d.onMessageReactionRemove,
+		d.onInteractionCreate,
+		d.onSlashCommand,
+		d.onControlButton,
+		d.onMessageDelete,
+	} {
+		d.removeHandlers = append(d.removeHandlers, d.Session.AddHandler(handler))
+	}
 	d.GuildID = guildID
+
+	d.applyGuildSettings()
+
+	go d.runDailyTriggers()
+	go d.persistQueue()
+	go d.runTopicUpdater()
+	go d.runStageTopicUpdater()
+	go d.autoRestoreQueue()
+	go d.announceUpdate()
+}
+
+// applyGuildSettings restores persisted guild preferences on the player.
+func (d *Discord) applyGuildSettings() {
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error loading guild settings: %v", err)
+		return
+	}
+
+	if settings.QueueStrategy != "" {
+		strategy, err := player.NewQueueStrategy(settings.QueueStrategy)
+		if err != nil {
+			slog.Warnf("Ignoring stored queue strategy: %v", err)
+		} else {
+			d.Player.SetQueueStrategy(strategy)
+		}
+	}
+
+	overrides := player.EncodeOverrides{
+		Bitrate:       settings.EncodeBitrate,
+		FrameDuration: settings.EncodeFrameDuration,
+		VolumeCeiling: settings.VolumeCeiling,
+	}
+	if err := d.Player.SetEncodeOverrides(overrides); err != nil {
+		slog.Warnf("Ignoring stored encode overrides: %v", err)
+	}
+
+	if settings.Quality != "" {
+		if err := d.Player.SetQuality(settings.Quality); err != nil {
+			slog.Warnf("Ignoring stored quality %v: %v", settings.Quality, err)
+		}
+	}
+
+	if settings.Verbosity != "" {
+		if isVerbosity(settings.Verbosity) {
+			d.verbosity = settings.Verbosity
+		} else {
+			slog.Warnf("Ignoring stored verbosity: %v", settings.Verbosity)
+		}
+	}
+
+	if settings.Locale != "" || settings.Timezone != "" {
+		l, err := locale.NewLocale(settings.Locale, settings.Timezone)
+		if err != nil {
+			slog.Warnf("Ignoring stored locale: %v", err)
+		} else {
+			d.locale = l
+		}
+	}
+
+	d.deleteCommands = settings.DeleteCommands
+	d.requestChannelID = settings.RequestChannelID
+	d.publicNowPlaying = settings.PublicNowPlaying
+	d.publicHistory = settings.PublicHistory
+	d.djRoleID = settings.DJRoleID
+	d.commandSuggestions = !settings.NoSuggestions
+	d.ducking = settings.Ducking
+	d.setAutoplay(settings.Autoplay)
+	d.topic.channelID = settings.TopicChannelID
+	d.topic.original = settings.TopicOriginal
+	d.topic.changed = settings.TopicChanged
+
+	d.loadCommandAliases()
+	d.loadCommandMacros()
+	d.loadMacroTriggers()
 }
 
 // Commands handles incoming Discord commands.
@@ -66,59 +228,59 @@ func (d *Discord) Commands(s *discordgo.Session, m *discordgo.MessageCreate) {
 
 	command, parameter, err := parseCommand(m.Message.Content, d.prefix)
 	if err != nil {
+		if d.requestChannelID != "" && m.ChannelID == d.requestChannelID && !m.Author.Bot {
+			d.handleSongRequest(s, m)
+		}
 		return
 	}
 
-	commandAliases := [][]string{
-		{"pause", "!", ">"},
-		{"resume", "play", ">"},
-		{"play", "p", ">"},
-		{"skip", "next", "ff", ">>"},
-		{"list", "queue", "l", "q"},
-		{"add", "a", "+"},
-		{"exit", "stop", "e", "x"},
-		{"help", "h", "?"},
-		{"history", "time", "t"},
-		{"about", "version", "v"},
+	if !d.runCommand(s, m, command, parameter) {
+		d.suggestUnknownCommand(s, m, command)
+		return
 	}
+	d.deleteCommandMessage(s, m)
+}
 
-	canonicalCommand := getCanonicalCommand(command, commandAliases)
-	if canonicalCommand == "" {
-		return
+// RunDirectCommand runs a command that the bot owner sent by DM for this guild.
+// Replies go to the DM channel. It returns false if the command is unknown.
+func (d *Discord) RunDirectCommand(s *discordgo.Session, m *discordgo.MessageCreate, command, parameter string) bool {
+	if !d.InstanceActive {
+		return false
 	}
 
-	switch canonicalCommand {
-	case "pause":
-		if parameter == "" && d.Player.GetCurrentStatus() == player.StatusPlaying {
-			d.handlePauseCommand(s, m)
-			return
-		}
-		fallthrough
-	case "resume":
-		if parameter == "" && d.Player.GetCurrentStatus() != player.StatusPlaying {
-			d.handleResumeCommand(s, m)
-			return
+	return d.runCommand(s, m, command, parameter)
+}
+
+// runCommand dispatches the command to its handler, it returns false if the command is unknown.
+func (d *Discord) runCommand(s *discordgo.Session, m *discordgo.MessageCreate, command, parameter string) bool {
+	cmd := findCommand(command)
+	if cmd == nil {
+		cmd = commandByName(d.customAlias(command))
+	}
+	if cmd == nil {
+		if steps := d.macroSteps(command); steps != nil {
+			d.runMacro(s, m, command, steps)
+			return true
 		}
-		fallthrough
-	case "play":
-		d.handlePlayCommand(s, m, parameter, false)
-	case "skip":
-		d.handleSkipCommand(s, m)
-	case "list":
-		d.handleShowQueueCommand(s, m)
-	case "add":
-		d.handlePlayCommand(s, m, parameter, true)
-	case "exit":
-		d.handleStopCommand(s, m)
-	case "help":
-		d.handleHelpCommand(s, m)
-	case "history":
-		d.handleHistoryCommand(s, m, parameter)
-	case "about":
-		d.handleAboutCommand(s, m)
-	default:
-		// Unknown command
 	}
+	if cmd == nil || cmd.run == nil {
+		return false
+	}
+
+	d.dispatchCommand(s, m, cmd, parameter)
+	return true
+}
+
+// dispatchCommand runs a command for a prefix or slash command message unless the author may not use it.
+func (d *Discord) dispatchCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *command, parameter string) {
+	if denial := d.commandDenial(s, m, cmd); denial != "" {
+		d.sendTextEmbed(s, m, denial)
+		return
+	}
+
+	d.traceCommand(m, cmd.name, parameter, func() {
+		timeCommand(m, cmd.name, func() { cmd.run(d, s, m, parameter) })
+	})
 }
 
 // parseCommand parses the command and parameter from the Discord input based on the provided pattern.
@@ -143,18 +305,6 @@ func parseCommand(content, pattern string) (string, string, error) {
 	return command, parameter, nil
 }
 
-// getCanonicalCommand gets the canonical command from aliases using the given alias.
-func getCanonicalCommand(alias string, commandAliases [][]string) string {
-	for _, aliases := range commandAliases {
-		for _, a := range aliases {
-			if a == alias {
-				return aliases[0]
-			}
-		}
-	}
-	return ""
-}
-
 // changeAvatar changes bot avatar with randomly picked avatar image within allowed rate limit
 func (d *Discord) changeAvatar(s *discordgo.Session) {
 	// Check if the rate limit duration has passed since the last execution
@@ -163,15 +313,15 @@ func (d *Discord) changeAvatar(s *discordgo.Session) {
 		return
 	}
 
-	imgPath, err := utils.GetRandomImagePathFromPath("./assets/avatars")
-	if err != nil {
-		slog.Errorf("Error getting avatar path: %v", err)
+	avatars := assets.Static.Images("avatars")
+	if len(avatars) == 0 {
+		slog.Error("Error getting avatar: no valid images found")
 		return
 	}
 
-	avatar, err := utils.ReadFileToBase64(imgPath)
+	avatar, err := avatars[rand.Intn(len(avatars))].DataURI()
 	if err != nil {
-		fmt.Printf("Error preparing avatar: %v\n", err)
+		slog.Errorf("Error preparing avatar: %v", err)
 		return
 	}
 
@@ -193,3 +343,38 @@ func findUserVoiceState(userID string, voiceStates []*discordgo.VoiceState) (*di
 	}
 	return nil, false
 }
+
+// HasAdminPermission reports whether the message author can administrate the guild, the bot owner always can.
+func (d *Discord) HasAdminPermission(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	return d.IsBotOwner(m.Author.ID) || HasManagePermission(s, m)
+}
+
+// HasManagePermission reports whether the message author is an administrator or may manage the server.
+func HasManagePermission(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	perms, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		slog.Warnf("Error getting user permissions: %v", err)
+		return false
+	}
+
+	return perms&discordgo.PermissionAdministrator != 0 || perms&discordgo.PermissionManageServer != 0
+}
+
+// sendTextEmbed sends a short plain embed to the channel the command came from.
+func (d *Discord) sendTextEmbed(s *discordgo.Session, m *discordgo.MessageCreate, text string) {
+	commandStats.noteReply(m, text)
+
+	embedMsg := embed.NewEmbed().
+		SetDescription(text).
+		SetColor(0x9f00d4).MessageEmbed
+
+	_, err := SendEmbed(s, m.Message.ChannelID, embedMsg)
+	if err != nil {
+		slog.Warnf("Error sending message: %v", err)
+	}
+}
+
+// relativeTimestamp formats t as a Discord timestamp that each client renders relative to now in its own language.
+func relativeTimestamp(t time.Time) string {
+	return fmt.Sprintf("<t:%d:R>", t.Unix())
+}
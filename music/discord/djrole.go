@@ -0,0 +1,67 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// handleDJRoleCommand handles the DJ role command for Discord.
+func (d *Discord) handleDJRoleCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.handleToggleSetting(s, m, param, toggleSetting{
+		command: "djrole",
+		usage:   "[@role/off]",
+		show: func() string {
+			if d.djRoleID == "" {
+				return fmt.Sprintf("🎧 No DJ role is set\nUse `%vdjrole [@role]` to let members with the role control the player from the dashboard", d.prefix)
+			}
+			return fmt.Sprintf("🎧 The DJ role is <@&%v>\nUse `%vdjrole off` to remove it", d.djRoleID, d.prefix)
+		},
+		// The value is the ID of the role, empty for off
+		parse: func(param string) (string, bool) {
+			if param == "off" {
+				return "", true
+			}
+			roleID := strings.TrimSuffix(strings.TrimPrefix(param, "<@&"), ">")
+			_, err := s.State.Role(d.GuildID, roleID)
+			return roleID, err == nil
+		},
+		apply: func(roleID string) { d.djRoleID = roleID },
+		store: func(settings *db.GuildSettings) { settings.DJRoleID = d.djRoleID },
+		changed: func(roleID string) string {
+			if roleID == "" {
+				return "🎧 DJ role removed"
+			}
+			return fmt.Sprintf("🎧 Members with <@&%v> may now control the player from the dashboard", roleID)
+		},
+	})
+}
+
+// HasDJRole reports whether the guild member holds the guild's DJ role.
+func (d *Discord) HasDJRole(userID string) bool {
+	if d.djRoleID == "" {
+		return false
+	}
+
+	member, err := d.Session.State.Member(d.GuildID, userID)
+	if err != nil {
+		member, err = d.Session.GuildMember(d.GuildID, userID)
+		if err != nil {
+			return false
+		}
+	}
+
+	for _, roleID := range member.Roles {
+		if roleID == d.djRoleID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBotOwner reports whether the user is the bot owner, who may administrate every guild.
+func (d *Discord) IsBotOwner(userID string) bool {
+	return d.ownerID != "" && userID == d.ownerID
+}
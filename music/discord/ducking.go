@@ -0,0 +1,110 @@
+package discord
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	// duckRelease is how long the channel must be silent before the music is restored
+	duckRelease = 1500 * time.Millisecond
+	duckPoll    = 250 * time.Millisecond
+)
+
+// handleDuckingSetting shows or changes whether music is lowered while people talk.
+func (d *Discord) handleDuckingSetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	if value == "" {
+		state := "off"
+		if d.ducking {
+			state = "on"
+		}
+		d.sendTextEmbed(s, m, fmt.Sprintf("🦆 Lowering music while people talk is `%v`\nUse `%vsettings ducking [on/off]` to change it", state, d.prefix))
+		return
+	}
+
+	if value != "on" && value != "off" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings ducking [on/off]`", d.prefix))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	d.ducking = value == "on"
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.Ducking = d.ducking
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving ducking setting: %v", err)
+	}
+
+	text := fmt.Sprintf("🦆 Lowering music while people talk is `%v`", value)
+	if d.ducking && d.Player.GetVoiceConnection() != nil {
+		text += "\nIt starts the next time I join a voice channel, I have to join undeafened to hear who is talking"
+	}
+	d.sendTextEmbed(s, m, text)
+}
+
+// duckUnderVoices lowers the music while anyone talks in the voice channel of the connection,
+// and restores it after a moment of silence. It runs until the connection closes or ducking is turned off.
+func (d *Discord) duckUnderVoices(vc *discordgo.VoiceConnection) {
+	if vc.OpusRecv == nil {
+		slog.Warn("Ducking needs an undeafened voice connection")
+		return
+	}
+
+	// Speaking events duck right away, audio packets keep it ducked while the talk goes on
+	speaking := make(chan struct{}, 1)
+	vc.AddHandler(func(_ *discordgo.VoiceConnection, vs *discordgo.VoiceSpeakingUpdate) {
+		if vs.Speaking {
+			select {
+			case speaking <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	ticker := time.NewTicker(duckPoll)
+	defer ticker.Stop()
+	defer d.Player.SetDucked(false)
+
+	var lastVoice time.Time
+	for {
+		select {
+		case packet := <-vc.OpusRecv:
+			if packet == nil || isSilenceFrame(packet.Opus) {
+				continue
+			}
+			lastVoice = time.Now()
+		case <-speaking:
+			lastVoice = time.Now()
+		case <-ticker.C:
+			if !d.ducking || !d.isVoiceConnected(vc) {
+				return
+			}
+		}
+
+		d.Player.SetDucked(time.Since(lastVoice) < duckRelease)
+	}
+}
+
+// isVoiceConnected reports whether the connection is still the guild's, it's dropped on disconnect.
+func (d *Discord) isVoiceConnected(vc *discordgo.VoiceConnection) bool {
+	d.Session.RLock()
+	defer d.Session.RUnlock()
+	return d.Session.VoiceConnections[d.GuildID] == vc
+}
+
+// isSilenceFrame reports whether an opus packet is one of the silence frames clients send after talking.
+func isSilenceFrame(opus []byte) bool {
+	return len(opus) <= 3
+}
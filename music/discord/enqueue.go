@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"errors"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// Errors returned by EnqueueQuery.
+var (
+	ErrNotInVoice = errors.New("not connected to a voice channel")
+	ErrNoSongs    = errors.New("no music found")
+)
+
+// EnqueueQuery adds songs found by a title, YouTube URL, history ID or stream URL to the queue
+// and starts playback if nothing is playing. The player must already be in a voice channel.
+func (d *Discord) EnqueueQuery(query string) ([]*player.Song, error) {
+	if d.Player.GetVoiceConnection() == nil {
+		return nil, ErrNotInVoice
+	}
+
+	paramType, songsList := parseParameter(query)
+	if len(songsList) == 0 {
+		return nil, ErrNoSongs
+	}
+
+	requestedAt := time.Now()
+	ctx, cancel := d.startLookup("")
+	defer cancel()
+	playlist := fetchSongs(ctx, paramType, songsList, d, nil)
+	if ctx.Err() != nil {
+		return nil, lookupError(ctx)
+	}
+	if len(playlist) == 0 {
+		return nil, ErrNoSongs
+	}
+	d.stampRequest(playlist, requestedAt)
+
+	for _, song := range playlist {
+		d.Player.Enqueue(song)
+	}
+
+	if d.Player.GetCurrentSong() == nil {
+		go d.Player.Play(d.ctx, 0, nil)
+	}
+
+	return playlist, nil
+}
+
+// stampRequest marks the first song of a playlist with the time of its request if it's about to start
+// playing right away, so the player can log how long starting it took.
+func (d *Discord) stampRequest(playlist []*player.Song, requestedAt time.Time) {
+	if len(playlist) == 0 || d.Player.GetCurrentSong() != nil || len(d.Player.GetSongQueue()) > 0 {
+		return
+	}
+
+	playlist[0].RequestedAt = requestedAt
+	playlist[0].ResolvedAt = time.Now()
+}
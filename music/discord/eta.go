@@ -0,0 +1,69 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// handleEtaCommand tells when a track of the queue starts playing, by its position in the list
+// or, without a position or with @me, the next track the author requested.
+func (d *Discord) handleEtaCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	queue := d.Player.GetSongQueue()
+	if len(queue) == 0 {
+		d.sendTextEmbed(s, m, "The queue is empty")
+		return
+	}
+
+	param = strings.ToLower(strings.TrimSpace(param))
+	var position int
+	switch param {
+	case "", "@me", "me", "<@" + m.Author.ID + ">", "<@!" + m.Author.ID + ">":
+		for i, song := range queue {
+			if song.RequesterID == m.Author.ID {
+				position = i + 1
+				break
+			}
+		}
+		if position == 0 {
+			d.sendTextEmbed(s, m, "None of your requests is in the queue")
+			return
+		}
+	default:
+		var err error
+		if position, err = strconv.Atoi(param); err != nil {
+			d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%veta [position/@me]`, positions are shown by `%vlist`", d.prefix, d.prefix))
+			return
+		}
+	}
+
+	until, err := d.Player.TimeUntil(position)
+	switch {
+	case errors.Is(err, player.ErrNoSuchPosition):
+		d.sendTextEmbed(s, m, fmt.Sprintf("The queue has %v tracks, positions start at 1", len(queue)))
+		return
+	case errors.Is(err, player.ErrRepeatingTrack):
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔂 The current track repeats, track `%v` plays once it's skipped or `%vloop off` is used", position, d.prefix))
+		return
+	case errors.Is(err, player.ErrUnknownDuration):
+		d.sendTextEmbed(s, m, fmt.Sprintf("Can't tell when track `%v` plays, a stream or a track of unknown length plays before it", position))
+		return
+	}
+
+	song := queue[position-1]
+	content := fmt.Sprintf("⏱️ Track `%v` %v starts %v", position, songLink(song), relativeTimestamp(time.Now().Add(until)))
+	if d.Player.GetCurrentStatus() == player.StatusPaused {
+		content = fmt.Sprintf("⏱️ Track `%v` %v starts %v after playback resumes", position, songLink(song), d.getLocale().Duration(until.Seconds()))
+	}
+	if strategy := d.Player.GetQueueStrategy().Name(); strategy != player.StrategyFIFO {
+		content += fmt.Sprintf("\nThe `%v` queue order may still place tracks added later ahead of it", strategy)
+	}
+	d.sendTextEmbed(s, m, content)
+}
@@ -0,0 +1,36 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// handleFeedCommand handles the feed command for Discord.
+func (d *Discord) handleFeedCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	path := fmt.Sprintf("`/guilds/%v/history.rss`", d.GuildID)
+
+	d.handleToggleSetting(s, m, param, toggleSetting{
+		command: "feed",
+		show: func() string {
+			if d.publicHistory {
+				return fmt.Sprintf("📰 The play history is public as an RSS feed on the REST API:\n%v\nUse `%vfeed off` to hide it", path, d.prefix)
+			}
+			return fmt.Sprintf("📰 The play history is not public\nUse `%vfeed on` to let members follow it in a feed reader", d.prefix)
+		},
+		apply: func(value string) { d.publicHistory = value == "on" },
+		store: func(settings *db.GuildSettings) { settings.PublicHistory = d.publicHistory },
+		changed: func(value string) string {
+			if value == "off" {
+				return "📰 The play history feed is disabled"
+			}
+			return "📰 The play history is now public as an RSS feed on the REST API:\n" + path
+		},
+	})
+}
+
+// IsHistoryPublic reports whether the guild opted in to the public history feed.
+func (d *Discord) IsHistoryPublic() bool {
+	return d.publicHistory
+}
@@ -0,0 +1,211 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// Experimental features the bot owner rolls out per guild.
+const (
+	FeatureAutoplay      = "autoplay"
+	FeatureSlashCommands = "slash"
+)
+
+// flagsReload is how long loaded flags are used before they're read again, so flags set by another instance apply.
+const flagsReload = time.Minute
+
+// feature describes an experimental feature behind a flag.
+type feature struct {
+	name        string
+	description string
+	enabled     bool // without any flag set
+}
+
+// features lists the experimental features, in the order the flags command shows them.
+var features = []feature{
+	{name: FeatureAutoplay, description: "Autoplay of related tracks once the queue is done"},
+	{name: FeatureSlashCommands, description: "Slash commands", enabled: true},
+}
+
+// findFeature returns the feature of the name, nil if there is none.
+func findFeature(name string) *feature {
+	for i := range features {
+		if features[i].name == name {
+			return &features[i]
+		}
+	}
+	return nil
+}
+
+// featureFlags caches the flags of the database, keyed by guild ID and feature, "" holding the defaults.
+var featureFlags = struct {
+	sync.Mutex
+	flags    map[string]map[string]bool
+	loadedAt time.Time
+}{}
+
+// FeatureEnabled reports whether the feature is on in the guild: by its flag for the guild,
+// else by its default flag for every guild, else by the feature's built-in default.
+func FeatureEnabled(guildID, name string) bool {
+	f := findFeature(name)
+	if f == nil {
+		return false
+	}
+
+	featureFlags.Lock()
+	defer featureFlags.Unlock()
+
+	if featureFlags.flags == nil || time.Since(featureFlags.loadedAt) > flagsReload {
+		loadFeatureFlags()
+	}
+
+	if enabled, ok := featureFlags.flags[guildID][name]; ok {
+		return enabled
+	}
+	if enabled, ok := featureFlags.flags[""][name]; ok {
+		return enabled
+	}
+	return f.enabled
+}
+
+// loadFeatureFlags reads the flags from the database, keeping the ones loaded before if it fails.
+// The caller holds the lock of featureFlags.
+func loadFeatureFlags() {
+	featureFlags.loadedAt = time.Now()
+
+	list, err := db.GetFeatureFlags()
+	if err != nil {
+		slog.Errorf("Error loading feature flags: %v", err)
+		if featureFlags.flags == nil {
+			featureFlags.flags = make(map[string]map[string]bool)
+		}
+		return
+	}
+
+	flags := make(map[string]map[string]bool)
+	for _, flag := range list {
+		if flags[flag.GuildID] == nil {
+			flags[flag.GuildID] = make(map[string]bool)
+		}
+		flags[flag.GuildID][flag.Feature] = flag.Enabled
+	}
+	featureFlags.flags = flags
+}
+
+// reloadFeatureFlags drops the cached flags so the next check reads them again.
+func reloadFeatureFlags() {
+	featureFlags.Lock()
+	defer featureFlags.Unlock()
+	featureFlags.flags = nil
+}
+
+// featureEnabled reports whether the feature is on in the guild of the instance.
+func (d *Discord) featureEnabled(name string) bool {
+	return FeatureEnabled(d.GuildID, name)
+}
+
+// handleFlagsCommand lists the experimental features and their state in the guild, or sets or clears
+// the flag of one for the guild or, with "all", for every guild.
+func (d *Discord) handleFlagsCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(strings.ToLower(param))
+	if len(words) == 0 {
+		d.sendTextEmbed(s, m, d.describeFeatureFlags())
+		return
+	}
+
+	usage := fmt.Sprintf("Usage: `%vflags [feature] [on/off/default] [all]`", d.prefix)
+	if len(words) < 2 || len(words) > 3 || (len(words) == 3 && words[2] != "all") {
+		d.sendTextEmbed(s, m, usage)
+		return
+	}
+
+	f := findFeature(words[0])
+	if f == nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown feature `%v`, use `%vflags` to list them", words[0], d.prefix))
+		return
+	}
+
+	guildID, scope := d.GuildID, "this server"
+	if len(words) == 3 {
+		guildID, scope = "", "every server without a flag of its own"
+	}
+
+	var err error
+	var content string
+	switch words[1] {
+	case "on", "off":
+		err = db.SetFeatureFlag(guildID, f.name, words[1] == "on")
+		content = fmt.Sprintf("`%v` is %v for %v", f.name, words[1], scope)
+	case "default":
+		_, err = db.DeleteFeatureFlag(guildID, f.name)
+		content = fmt.Sprintf("`%v` follows the default again for %v", f.name, scope)
+	default:
+		d.sendTextEmbed(s, m, usage)
+		return
+	}
+	if err != nil {
+		slog.Errorf("Error saving feature flag %v: %v", f.name, err)
+		d.sendTextEmbed(s, m, "Error saving the feature flag")
+		return
+	}
+
+	reloadFeatureFlags()
+	d.sendConfirmation(s, m, "🧪", content)
+}
+
+// describeFeatureFlags lists the features with their state in the guild and where it comes from.
+func (d *Discord) describeFeatureFlags() string {
+	list, err := db.GetFeatureFlags()
+	if err != nil {
+		slog.Errorf("Error getting feature flags: %v", err)
+	}
+
+	guildFlags := make(map[string]bool)
+	defaultFlags := make(map[string]bool)
+	for _, flag := range list {
+		switch flag.GuildID {
+		case d.GuildID:
+			guildFlags[flag.Feature] = true
+		case "":
+			defaultFlags[flag.Feature] = true
+		}
+	}
+
+	lines := []string{"🧪 **Experimental features**"}
+	for _, f := range features {
+		state := "off"
+		if d.featureEnabled(f.name) {
+			state = "on"
+		}
+
+		source := "built-in default"
+		switch {
+		case guildFlags[f.name]:
+			source = "flag of this server"
+		case defaultFlags[f.name]:
+			source = "default flag"
+		}
+		lines = append(lines, fmt.Sprintf("`%v` %v — %v *(%v)*", f.name, state, f.description, source))
+	}
+
+	others := make(map[string]bool)
+	for _, flag := range list {
+		if flag.GuildID != "" && flag.GuildID != d.GuildID {
+			others[flag.GuildID] = true
+		}
+	}
+	if len(others) > 0 {
+		lines = append(lines, fmt.Sprintf("%v other servers have flags of their own", len(others)))
+	}
+
+	lines = append(lines, fmt.Sprintf("Use `%vflags [feature] [on/off/default] [all]` to change them", d.prefix))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,28 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+// handleForgetMeCommand handles the forgetme command for Discord.
+func (d *Discord) handleForgetMeCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	h := history.NewHistory()
+
+	count, err := h.ForgetUser(m.Author.ID)
+	if err != nil {
+		slog.Errorf("Error forgetting user data: %v", err)
+		d.sendTextEmbed(s, m, "Error removing your personal data")
+		return
+	}
+
+	if err := db.DeleteUserDashboardSessions(m.Author.ID); err != nil {
+		slog.Errorf("Error deleting dashboard sessions: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🧹 Done! %v of your requests are no longer attributed to you, play counts of the tracks are kept anonymously. Your likes and dislikes and your dashboard sessions are removed. Tags, playlists, macros, aliases, triggers, listen links, listening parties, API tokens and webhooks you created stay for the server without your name on them.", count))
+}
@@ -0,0 +1,146 @@
+package discord
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/assets"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// jinglesDir is the assets directory greeting clips can be picked from by file name.
+const jinglesDir = "jingles"
+
+// handleGreetingSetting shows or changes what the bot does when it joins a voice channel.
+func (d *Discord) handleGreetingSetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	kind, text, _ := strings.Cut(value, " ")
+	text = strings.TrimSpace(text)
+	kind = strings.ToLower(kind)
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting guild settings: %v", err)
+		d.sendTextEmbed(s, m, "Error getting the greeting")
+		return
+	}
+
+	if kind == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("👋 When joining a voice channel I play %v and post %v\nUse `%vsettings greeting clip [url/jingle]`, `%vsettings greeting message [text]` or `%vsettings greeting off` to change it\nJingles: %v",
+			describeGreeting(settings.GreetingClip), describeGreeting(settings.GreetingMessage), d.prefix, d.prefix, d.prefix, describeJingles()))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	switch kind {
+	case "clip":
+		if text != "" && !isClipURL(text) {
+			if _, ok := jingle(text); !ok {
+				d.sendTextEmbed(s, m, fmt.Sprintf("Unknown jingle `%v`, use an http(s) URL or a jingle, available are %v", text, describeJingles()))
+				return
+			}
+		}
+		settings.GreetingClip = text
+	case "message":
+		settings.GreetingMessage = text
+	case "off":
+		settings.GreetingClip, settings.GreetingMessage = "", ""
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings greeting [clip/message/off] [url/jingle/text]`", d.prefix))
+		return
+	}
+
+	if err := db.SaveGuildSettings(settings); err != nil {
+		slog.Errorf("Error saving greeting: %v", err)
+		d.sendTextEmbed(s, m, "Error saving the greeting")
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("👋 When joining a voice channel I play %v and post %v", describeGreeting(settings.GreetingClip), describeGreeting(settings.GreetingMessage)))
+}
+
+// describeGreeting quotes a greeting setting, "nothing" if it's empty.
+func describeGreeting(value string) string {
+	if value == "" {
+		return "nothing"
+	}
+	return "`" + value + "`"
+}
+
+// greet posts the greeting message in the chat of the voice channel just joined and plays the greeting
+// clip before any song starts. Failures are logged, they must not keep the bot from playing.
+func (d *Discord) greet(channelID string) {
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Warnf("Error getting greeting: %v", err)
+		return
+	}
+
+	if settings.GreetingMessage != "" {
+		if _, err := SendEmbed(d.Session, channelID, &discordgo.MessageEmbed{Description: "👋 " + settings.GreetingMessage, Color: 0x9f00d4}); err != nil {
+			slog.Warnf("Error posting greeting: %v", err)
+		}
+	}
+
+	if settings.GreetingClip == "" {
+		return
+	}
+
+	url := settings.GreetingClip
+	if !isClipURL(url) {
+		asset, ok := jingle(url)
+		if !ok {
+			slog.Warnf("Greeting jingle %v is gone", url)
+			return
+		}
+		url = asset.Path()
+	}
+
+	if err := d.Player.PlayClip(d.ctx, url); err != nil {
+		slog.Warnf("Error playing greeting clip %v: %v", settings.GreetingClip, err)
+	}
+}
+
+func isClipURL(value string) bool {
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
+}
+
+// jingle returns the audio asset of the jingles directory with the given file name.
+func jingle(name string) (*assets.Asset, bool) {
+	for _, asset := range assets.Static.All() {
+		if asset.Name == path.Join(jinglesDir, name) && isAudio(asset) {
+			return asset, true
+		}
+	}
+	return nil, false
+}
+
+// jingleNames returns the file names of the audio assets of the jingles directory.
+func jingleNames() []string {
+	var names []string
+	for _, asset := range assets.Static.All() {
+		if path.Dir(asset.Name) == jinglesDir && isAudio(asset) {
+			names = append(names, path.Base(asset.Name))
+		}
+	}
+	return names
+}
+
+// describeJingles lists the jingles for replies.
+func describeJingles() string {
+	names := jingleNames()
+	if len(names) == 0 {
+		return fmt.Sprintf("none, add audio files to the %v assets directory", jinglesDir)
+	}
+	return formatTags(names)
+}
+
+func isAudio(asset *assets.Asset) bool {
+	return strings.HasPrefix(asset.ContentType, "audio/") || asset.ContentType == "application/ogg"
+}
@@ -2,64 +2,158 @@ package discord
 
 import (
 	"fmt"
-	"os"
-	"time"
+	"strings"
 
 	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gookit/slog"
 	"github.com/keshon/melodix-discord-player/internal/config"
 	"github.com/keshon/melodix-discord-player/internal/version"
-	"github.com/keshon/melodix-discord-player/music/utils"
 )
 
+// maxEmbedFieldLength is the longest value Discord accepts in an embed field.
+const maxEmbedFieldLength = 1024
+
 // handleHelpCommand handles the help command for Discord.
-func (d *Discord) handleHelpCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+// Without a parameter it lists all commands, otherwise the commands of a category or details of one command.
+func (d *Discord) handleHelpCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
 	d.changeAvatar(s)
 
+	topic := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(param)), d.prefix)
+	if topic == "" {
+		d.sendHelp(s, m, commandCategories)
+		return
+	}
+
+	if category, ok := findCategory(topic); ok {
+		d.sendHelp(s, m, []commandCategory{category})
+		return
+	}
+
+	if cmd := d.lookupCommand(topic); cmd != nil {
+		d.sendCommandHelp(s, m, cmd)
+		return
+	}
+
+	text := fmt.Sprintf("No help for `%v`.", topic)
+	if suggestions := d.suggestCommands(topic); len(suggestions) > 0 {
+		text += fmt.Sprintf(" Did you mean `%vhelp %v`?", d.prefix, suggestions[0])
+	}
+
+	categories := make([]string, 0, len(commandCategories))
+	for _, category := range commandCategories {
+		categories = append(categories, "`"+strings.ToLower(string(category))+"`")
+	}
+	text += fmt.Sprintf("\nUse `%vhelp [category]` with one of %v, or `%vhelp [command]`", d.prefix, strings.Join(categories, ", "), d.prefix)
+
+	d.sendTextEmbed(s, m, text)
+}
+
+// sendHelp sends the usage of the commands in the categories.
+func (d *Discord) sendHelp(s *discordgo.Session, m *discordgo.MessageCreate, categories []commandCategory) {
 	config, err := config.NewConfig()
 	if err != nil {
 		slog.Fatalf("Error loading config: %v", err)
 	}
 
-	var hostname string
-	if os.Getenv("HOST") == "" {
-		hostname = config.RestHostname
-	} else {
-		hostname = os.Getenv("HOST") // from docker environment
-	}
-
-	avatarUrl := utils.InferProtocolByPort(hostname, 443) + hostname + "/avatar/random?" + fmt.Sprint(time.Now().UnixNano())
+	avatarUrl := avatarURL(config.RestBaseURL(), 128)
 	slog.Info(avatarUrl)
 
-	play := fmt.Sprintf("**Play**: `%vplay [title/url/id/stream]` \nAliases: `%vp ...`, `%v> ...`\n", d.prefix, d.prefix, d.prefix)
-	pause := fmt.Sprintf("**Pause** / **resume**: `%vpause`, `%vplay` \nAliases: `%v!`, `%v>`\n", d.prefix, d.prefix, d.prefix, d.prefix)
-	queue := fmt.Sprintf("**Add track**: `%vadd [title/url/id]` \nAliases: `%va ...`, `%v+ ...`\n", d.prefix, d.prefix, d.prefix)
-	skip := fmt.Sprintf("**Skip track**: `%vskip` \nAliases: `%vff`, `%v>>`\n", d.prefix, d.prefix, d.prefix)
-	list := fmt.Sprintf("**Show queue**: `%vlist` \nAliases: `%vqueue`, `%vl`, `%vq`\n", d.prefix, d.prefix, d.prefix, d.prefix)
-	history := fmt.Sprintf("**Show history**: `%vhistory`\n", d.prefix)
-	historyByDuration := fmt.Sprintf("**.. by duration**: `%vhistory duration`\n", d.prefix)
-	historyByPlaycount := fmt.Sprintf("**.. by play count**: `%vhistory count`\nAliases: `%vtime ...`, `%vt ...`", d.prefix, d.prefix, d.prefix)
-	stop := fmt.Sprintf("**Stop and exit**: `%vexit` \nAliases: `%ve`, `%vx`\n", d.prefix, d.prefix, d.prefix)
-	help := fmt.Sprintf("**Show help**: `%vhelp` \nAliases: `%vh`, `%v?`\n", d.prefix, d.prefix, d.prefix)
-	about := fmt.Sprintf("**Show version**: `%vabout`", d.prefix)
-	register := fmt.Sprintf("**Enable commands listening**: `%vregister`\n", d.prefix)
-	unregister := fmt.Sprintf("**Disable commands listening**: `%vunregister`", d.prefix)
-
 	embedMsg := embed.NewEmbed().
 		SetTitle("ℹ️ Melodix — Command Usage").
-		SetDescription("Some commands are aliased for shortness.\n`[title]` - track name\n`[url]` - YouTube URL\n`[id]` - track id from *History*\n`[stream]` - valid stream URL (radio).").
-		AddField("", "*Playback*\n"+play+skip+pause).
-		AddField("", "").
-		AddField("", "*Queue*\n"+queue+list).
-		AddField("", "").
-		AddField("", "*History*\n"+history+historyByDuration+historyByPlaycount).
-		AddField("", "").
-		AddField("", "*General*\n"+stop+help+about).
-		AddField("", "").
-		AddField("", "*Adinistration*\n"+register+unregister).
+		SetDescription(fmt.Sprintf("Some commands are aliased for shortness.\n`[title]` - track name\n`[url]` - YouTube URL\n`[id]` - track id from *History*\n`[stream]` - valid stream URL (radio).\n"+
+						"Use `%vhelp [category]` or `%vhelp [command]` for details.", d.prefix, d.prefix)).
 		SetThumbnail(avatarUrl). // TODO: move out to config .env file
-		SetColor(0x9f00d4).SetFooter(version.AppFullName).MessageEmbed
+		SetColor(0x9f00d4).SetFooter(version.AppFullName)
+
+	for i, category := range categories {
+		if i > 0 {
+			embedMsg.AddField("", "")
+		}
+
+		var lines []string
+		for _, cmd := range commands {
+			if cmd.category == category {
+				lines = append(lines, cmd.helpLine(d.prefix))
+			}
+		}
+		addHelpFields(embedMsg, "*"+string(category)+"*\n", lines)
+	}
+
+	if len(categories) == len(commandCategories) {
+		if aliases := d.describeCustomAliases(); aliases != "" {
+			embedMsg.AddField("", "").
+				AddField("", "*Custom aliases of this server*\n"+aliases)
+		}
+		if macros := d.macroNames(); len(macros) > 0 {
+			embedMsg.AddField("", "").
+				AddField("", fmt.Sprintf("*Macros of this server*, `%vmacro list` shows their commands\n%v", d.prefix, strings.Join(macros, ", ")))
+		}
+	}
 
-	s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	SendEmbed(s, m.Message.ChannelID, embedMsg.MessageEmbed)
+}
+
+// sendCommandHelp sends the detailed usage of a command with examples.
+func (d *Discord) sendCommandHelp(s *discordgo.Session, m *discordgo.MessageCreate, cmd *command) {
+	usages := cmd.usages
+	if len(usages) == 0 {
+		usages = []string{""}
+	}
+
+	var usage strings.Builder
+	for _, u := range usages {
+		usage.WriteString("`" + strings.TrimSpace(d.prefix+cmd.name+" "+u) + "`\n")
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetTitle(fmt.Sprintf("ℹ️ %v%v — %v", d.prefix, cmd.name, cmd.description)).
+		AddField("Usage", usage.String()).
+		SetColor(0x9f00d4).SetFooter(version.AppFullName)
+
+	var aliases []string
+	for _, alias := range cmd.aliases {
+		aliases = append(aliases, "`"+d.prefix+alias+"`")
+	}
+	aliases = append(aliases, d.customAliasesOf(cmd.name)...)
+	if len(aliases) > 0 {
+		embedMsg.AddField("Aliases", strings.Join(aliases, ", "))
+	}
+
+	if len(cmd.examples) > 0 {
+		var examples strings.Builder
+		for _, example := range cmd.examples {
+			examples.WriteString("`" + d.prefix + cmd.name + " " + example + "`\n")
+		}
+		embedMsg.AddField("Examples", examples.String())
+	}
+
+	embedMsg.AddField("Permission", cmd.permission.String()).
+		AddField("Category", string(cmd.category))
+
+	SendEmbed(s, m.Message.ChannelID, embedMsg.MessageEmbed)
+}
+
+// addHelpFields adds the lines under a heading, split over as many fields as Discord's field length limit needs.
+func addHelpFields(embedMsg *embed.Embed, heading string, lines []string) {
+	value := heading
+	for _, line := range lines {
+		if len(value)+len(line) > maxEmbedFieldLength && value != heading {
+			embedMsg.AddField("", value)
+			value = ""
+		}
+		value += line
+	}
+	embedMsg.AddField("", value)
+}
+
+// findCategory returns the category named by the topic, which may be abbreviated to three letters or more.
+func findCategory(topic string) (commandCategory, bool) {
+	for _, category := range commandCategories {
+		name := strings.ToLower(string(category))
+		if topic == name || (len(topic) >= 3 && strings.HasPrefix(name, topic)) {
+			return category, true
+		}
+	}
+	return "", false
 }
@@ -2,6 +2,8 @@ package discord
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
@@ -10,6 +12,9 @@ import (
 	"github.com/keshon/melodix-discord-player/music/utils"
 )
 
+// historyPageSize is the number of history entries that fit into a single embed.
+const historyPageSize = 25
+
 // handleHistoryCommand handles the history command for Discord.
 func (d *Discord) handleHistoryCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
 	d.changeAvatar(s)
@@ -17,22 +22,37 @@ func (d *Discord) handleHistoryCommand(s *discordgo.Session, m *discordgo.Messag
 	var sortBy string
 	var title string
 
-	switch param {
+	sortParam, tag, page := parseHistoryParameter(param)
+
+	switch sortParam {
 	case "count", "times", "time":
 		sortBy, title = "play_count", " — by play count"
 	case "duration", "dur":
 		sortBy, title = "duration", " — by total duration"
+	case "skipped", "skips":
+		sortBy, title = "skip_count", " — most skipped"
 	default:
 		sortBy, title = "last_played", " — most recent"
 	}
 
 	h := history.NewHistory()
-	list, err := h.GetHistory(d.GuildID, sortBy)
+
+	var list []history.HistoryTrackInfo
+	var err error
+	if tag != "" {
+		list, err = h.GetTaggedHistory(d.ctx, d.GuildID, tag, sortBy, historyPageSize, (page-1)*historyPageSize)
+		title = fmt.Sprintf(" tagged `%v`%v", tag, title)
+	} else {
+		list, err = h.GetHistory(d.ctx, d.GuildID, sortBy, historyPageSize, (page-1)*historyPageSize)
+	}
 	if err != nil {
 		slog.Warn("No history table found")
 	}
 
-	description := fmt.Sprintf("⏳ History %v", title)
+	description := fmt.Sprintf("⏳ History%v", title)
+	if page > 1 {
+		description = fmt.Sprintf("%v (page %v)", description, page)
+	}
 	if len(description) > 4096 {
 		description = utils.TrimString(description, 4096)
 	}
@@ -44,28 +64,72 @@ func (d *Discord) handleHistoryCommand(s *discordgo.Session, m *discordgo.Messag
 
 	maxLimit := 6000 - descriptionLength
 
-	for i, elem := range list {
-		if i > 24 {
-			break
-		}
+	var trackIDs []uint
+	for _, elem := range list {
+		trackIDs = append(trackIDs, elem.History.TrackID)
+	}
+	ratings, err := h.GetTrackRatings(d.GuildID, trackIDs)
+	if err != nil {
+		slog.Warnf("Error getting track ratings: %v", err)
+	}
+	tags, err := h.GetTrackTags(d.GuildID, trackIDs)
+	if err != nil {
+		slog.Warnf("Error getting track tags: %v", err)
+	}
 
-		duration := utils.FormatDuration(elem.History.Duration)
+	l := d.getLocale()
+
+	for _, elem := range list {
+
+		duration := l.Duration(elem.History.Duration)
 		fieldContent := fmt.Sprintf("```id: %d```    ```count: %d```    ```duration: %v```", elem.History.TrackID, elem.History.PlayCount, duration)
+		if sortBy == "skip_count" {
+			fieldContent = fmt.Sprintf("```id: %d```    ```count: %d```    ```skips: %d```", elem.History.TrackID, elem.History.PlayCount, elem.History.SkipCount)
+		}
 		fieldContentLength := len(fieldContent)
 
-		nameLength := len(elem.Track.Name)
-		urlLength := len(elem.Track.URL)
+		fieldValue := fmt.Sprintf("[%v](%v) · %v", elem.Track.Name, elem.Track.URL, relativeTimestamp(elem.History.LastPlayed))
+		if summary := ratings[elem.History.TrackID]; summary.Likes+summary.Dislikes > 0 {
+			fieldValue += " · " + describeRating(summary.Likes, summary.Dislikes)
+		}
+		if trackTags := tags[elem.History.TrackID]; len(trackTags) > 0 {
+			fieldValue += " · " + formatTags(trackTags)
+		}
+		fieldValueLength := len(fieldValue)
 
-		if maxLimit-len(embedMsg.Fields)-fieldContentLength-nameLength-urlLength < 0 {
+		if maxLimit-len(embedMsg.Fields)-fieldContentLength-fieldValueLength < 0 {
 			break
 		}
 
-		embedMsg.AddField(fieldContent, fmt.Sprintf("[%v](%v)", elem.Track.Name, elem.Track.URL))
+		embedMsg.AddField(fieldContent, fieldValue)
 
 	}
 
-	_, err = s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg.MessageEmbed)
+	_, err = SendEmbed(s, m.Message.ChannelID, embedMsg.MessageEmbed)
 	if err != nil {
 		slog.Warnf("Error sending history message: %v", err)
 	}
 }
+
+// parseHistoryParameter splits the history parameter into sort criteria, tag to filter by and page number (starting from 1).
+func parseHistoryParameter(param string) (string, string, int) {
+	sortParam := ""
+	tag := ""
+	page := 1
+
+	for _, word := range strings.Fields(param) {
+		if number, err := strconv.Atoi(word); err == nil {
+			if number > 0 {
+				page = number
+			}
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(word), tagPrefix) {
+			tag = strings.TrimPrefix(strings.ToLower(word), tagPrefix)
+			continue
+		}
+		sortParam = word
+	}
+
+	return sortParam, tag, page
+}
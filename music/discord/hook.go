@@ -0,0 +1,142 @@
+package discord
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// handleHookCommand handles the webhook management command for Discord.
+func (d *Discord) handleHookCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(param)
+	if len(words) == 0 {
+		d.listHooks(s, m)
+		return
+	}
+
+	switch words[0] {
+	case "add":
+		if len(words) < 2 || len(words) > 3 {
+			break
+		}
+		requesterPath := ""
+		if len(words) == 3 {
+			requesterPath = words[2]
+		}
+		d.addHook(s, m, words[1], requesterPath)
+		return
+	case "remove":
+		if len(words) != 2 {
+			break
+		}
+		d.removeHook(s, m, words[1])
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vhook`, `%vhook add [query path] [requester path]`, `%vhook remove [token]`", d.prefix, d.prefix, d.prefix))
+}
+
+// listHooks shows webhooks bound to the guild.
+func (d *Discord) listHooks(s *discordgo.Session, m *discordgo.MessageCreate) {
+	webhooks, err := db.GetWebhooksByGuildID(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting webhooks: %v", err)
+		d.sendTextEmbed(s, m, "Error getting webhooks")
+		return
+	}
+
+	if len(webhooks) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🪝 No webhooks yet, use `%vhook add [query path]` to create one, e.g. `%vhook add data.message`", d.prefix, d.prefix))
+		return
+	}
+
+	content := "🪝 Webhooks\n"
+	for _, webhook := range webhooks {
+		content += fmt.Sprintf("\n`%v` song from `%v`", webhook.Token, webhook.QueryPath)
+		if webhook.RequesterPath != "" {
+			content += fmt.Sprintf(", requester from `%v`", webhook.RequesterPath)
+		}
+	}
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// addHook creates a webhook and sends its secret to the author by DM.
+func (d *Discord) addHook(s *discordgo.Session, m *discordgo.MessageCreate, queryPath, requesterPath string) {
+	token, err := randomHex(16)
+	if err != nil {
+		slog.Errorf("Error generating webhook token: %v", err)
+		d.sendTextEmbed(s, m, "Error creating webhook")
+		return
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		slog.Errorf("Error generating webhook secret: %v", err)
+		d.sendTextEmbed(s, m, "Error creating webhook")
+		return
+	}
+
+	dm, err := s.UserChannelCreate(m.Author.ID)
+	if err != nil {
+		slog.Warnf("Error opening DM channel: %v", err)
+		d.sendTextEmbed(s, m, "I can't send you a direct message with the webhook secret, please allow DMs from server members")
+		return
+	}
+
+	webhook := &db.Webhook{
+		Token:         token,
+		GuildID:       d.GuildID,
+		Secret:        secret,
+		QueryPath:     queryPath,
+		RequesterPath: requesterPath,
+		CreatedBy:     m.Author.ID,
+	}
+	if err := db.CreateWebhook(webhook); err != nil {
+		slog.Errorf("Error creating webhook: %v", err)
+		d.sendTextEmbed(s, m, "Error creating webhook")
+		return
+	}
+
+	_, err = SendMessage(s, dm.ID, fmt.Sprintf("🪝 Webhook created\n\nURL: `POST /hooks/%v` on the REST API host\nSecret: `%v`\n\n"+
+		"Sign the raw request body with HMAC-SHA256 using the secret and send it hex encoded in the `X-Melodix-Signature: sha256=<signature>` header. "+
+		"The song is read from the `%v` field of the JSON body.", token, secret, queryPath))
+	if err != nil {
+		slog.Warnf("Error sending webhook secret: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🪝 Webhook `%v` created, the secret was sent to you by DM", token))
+}
+
+// removeHook deletes a webhook of the guild.
+func (d *Discord) removeHook(s *discordgo.Session, m *discordgo.MessageCreate, token string) {
+	deleted, err := db.DeleteWebhook(d.GuildID, token)
+	if err != nil {
+		slog.Errorf("Error deleting webhook: %v", err)
+		d.sendTextEmbed(s, m, "Error deleting webhook")
+		return
+	}
+
+	if deleted == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No webhook `%v` found", token))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🪝 Webhook `%v` deleted", token))
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
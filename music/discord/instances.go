@@ -0,0 +1,72 @@
+package discord
+
+import (
+	"sort"
+	"sync"
+)
+
+// BotInstances are the bot instances run by this process, by guild ID. The guild manager starts and removes
+// instances while the bridges and the API look them up, so every access goes through the lock.
+type BotInstances struct {
+	mu        sync.RWMutex
+	instances map[string]*BotInstance
+}
+
+// NewBotInstances creates an empty registry of bot instances.
+func NewBotInstances() *BotInstances {
+	return &BotInstances{instances: make(map[string]*BotInstance)}
+}
+
+// Get returns the instance of the guild.
+func (b *BotInstances) Get(guildID string) (*BotInstance, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	instance, ok := b.instances[guildID]
+	return instance, ok
+}
+
+// Set adds or replaces the instance of the guild.
+func (b *BotInstances) Set(guildID string, instance *BotInstance) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.instances[guildID] = instance
+}
+
+// Delete removes the instance of the guild.
+func (b *BotInstances) Delete(guildID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.instances, guildID)
+}
+
+// Len returns the number of instances.
+func (b *BotInstances) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.instances)
+}
+
+// GuildIDs returns the IDs of the guilds with an instance, sorted.
+func (b *BotInstances) GuildIDs() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ids := make([]string, 0, len(b.instances))
+	for guildID := range b.instances {
+		ids = append(ids, guildID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// All returns a copy of the instances by guild ID, to range over without holding the lock.
+func (b *BotInstances) All() map[string]*BotInstance {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	all := make(map[string]*BotInstance, len(b.instances))
+	for guildID, instance := range b.instances {
+		all[guildID] = instance
+	}
+	return all
+}
@@ -0,0 +1,95 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	defaultListenTTL = 2 * time.Hour
+	maxListenTTL     = 24 * time.Hour
+)
+
+// handleListenCommand creates a listen-along link, a web page showing the live now playing and queue of the guild.
+// Members create links when the now playing badge is public, else DJs and administrators only.
+func (d *Discord) handleListenCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	param = strings.TrimSpace(param)
+	if param == "revoke" {
+		d.revokeListenLinks(s, m)
+		return
+	}
+
+	// The link shows the player to anyone, like the public now playing badge does
+	if !d.publicNowPlaying && !d.isDJ(s, m) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Only DJs and server administrators can create listen-along links while the current track isn't public, see `%vbadge`", d.prefix))
+		return
+	}
+
+	ttl := defaultListenTTL
+	if param != "" {
+		parsed, err := time.ParseDuration(param)
+		if err != nil || parsed < time.Minute || parsed > maxListenTTL {
+			d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vlisten [duration]`, e.g. `%vlisten 30m`, at most %v", d.prefix, d.prefix, maxListenTTL))
+			return
+		}
+		ttl = parsed
+	}
+
+	token, err := randomHex(16)
+	if err != nil {
+		slog.Errorf("Error generating listen link token: %v", err)
+		d.sendTextEmbed(s, m, "Error creating listen-along link")
+		return
+	}
+
+	link := &db.ListenLink{
+		TokenHash: db.HashSecret(token),
+		GuildID:   d.GuildID,
+		CreatedBy: m.Author.ID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.CreateListenLink(link); err != nil {
+		slog.Errorf("Error creating listen link: %v", err)
+		d.sendTextEmbed(s, m, "Error creating listen-along link")
+		return
+	}
+
+	if deleted, err := db.DeleteExpiredListenLinks(); err != nil {
+		slog.Warnf("Error deleting expired listen links: %v", err)
+	} else if deleted > 0 {
+		slog.Debugf("Deleted %d expired listen links", deleted)
+	}
+
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Fatalf("Error loading config: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎧 Listen along at %v/listen/%v\nAnyone with the link sees what's playing, it expires %v", config.RestBaseURL(), token, relativeTimestamp(link.ExpiresAt)))
+}
+
+// revokeListenLinks deletes every listen-along link of the guild.
+func (d *Discord) revokeListenLinks(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can revoke listen-along links")
+		return
+	}
+
+	deleted, err := db.DeleteListenLinks(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error deleting listen links: %v", err)
+		d.sendTextEmbed(s, m, "Error revoking listen-along links")
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎧 Revoked %d listen-along links", deleted))
+}
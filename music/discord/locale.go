@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/locale"
+)
+
+// handleLocaleCommand handles the locale command for Discord.
+func (d *Discord) handleLocaleCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	available := "`" + strings.Join(locale.Tags, "`, `") + "`"
+	current := d.getLocale()
+
+	if param == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🌐 Locale is `%v`, e.g. %v\nUse `%vlocale [name]` to change it, available: %v", current.Tag, current.Date(time.Now()), d.prefix, available))
+		return
+	}
+
+	l, err := locale.NewLocale(param, current.Location.String())
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown locale `%v`, available: %v", param, available))
+		return
+	}
+
+	if !d.saveLocale(s, m, l, func(settings *db.GuildSettings) { settings.Locale = l.Tag }) {
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🌐 Locale set to `%v`, e.g. %v", l.Tag, l.Date(time.Now())))
+}
+
+// handleTimezoneCommand handles the timezone command for Discord.
+func (d *Discord) handleTimezoneCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	current := d.getLocale()
+
+	if param == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🕒 Timezone is `%v`, local time %v\nUse `%vtimezone [name]` to change it, e.g. `%vtimezone Europe/Berlin`", current.Location, time.Now().In(current.Location).Format("15:04"), d.prefix, d.prefix))
+		return
+	}
+
+	l, err := locale.NewLocale(current.Tag, param)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown timezone `%v`, use a name from the IANA database such as `Europe/Berlin` or `UTC`", param))
+		return
+	}
+
+	if !d.saveLocale(s, m, l, func(settings *db.GuildSettings) { settings.Timezone = l.Location.String() }) {
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🕒 Timezone set to `%v`, local time %v", l.Location, time.Now().In(l.Location).Format("15:04")))
+}
+
+// saveLocale applies the locale to the guild and stores the changed setting.
+// It returns false if the author is not allowed to change it.
+func (d *Discord) saveLocale(s *discordgo.Session, m *discordgo.MessageCreate, l *locale.Locale, update func(settings *db.GuildSettings)) bool {
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change locale settings")
+		return false
+	}
+
+	d.locale = l
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		update(settings)
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving locale settings: %v", err)
+	}
+
+	return true
+}
+
+// getLocale returns the locale of the guild, English in the server timezone if not set.
+func (d *Discord) getLocale() *locale.Locale {
+	if d.locale == nil {
+		return locale.Default()
+	}
+	return d.locale
+}
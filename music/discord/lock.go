@@ -0,0 +1,61 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+// handleLockCommand freezes the queue so only DJs can change it, e.g. during events.
+func (d *Discord) handleLockCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	if !d.queueLocked.CompareAndSwap(false, true) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔒 The queue is already locked, use `%vunlock` to open it again", d.prefix))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔒 Queue locked, only DJs and administrators can add, skip or reorder tracks until `%vunlock`", d.prefix))
+}
+
+// handleUnlockCommand lets everyone change the queue again.
+func (d *Discord) handleUnlockCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	if !d.queueLocked.CompareAndSwap(true, false) {
+		d.sendTextEmbed(s, m, "🔓 The queue is not locked")
+		return
+	}
+
+	d.sendTextEmbed(s, m, "🔓 Queue unlocked, everyone can add and skip tracks again")
+}
+
+// IsQueueLocked reports whether only DJs may change the queue.
+func (d *Discord) IsQueueLocked() bool {
+	return d.queueLocked.Load()
+}
+
+// isDJ reports whether the author may control the player while the queue is locked.
+func (d *Discord) isDJ(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	return d.HasAdminPermission(s, m) || d.HasDJRole(m.Author.ID)
+}
+
+// commandDenial returns why the author may not use the command right now, empty if they may.
+func (d *Discord) commandDenial(s *discordgo.Session, m *discordgo.MessageCreate, cmd *command) string {
+	switch {
+	case cmd.permission == permissionOwner && !d.IsBotOwner(m.Author.ID):
+		return fmt.Sprintf("Only the bot owner can use `%v%v`", d.prefix, cmd.name)
+	case cmd.feature != "" && !d.featureEnabled(cmd.feature):
+		return fmt.Sprintf("🧪 `%v%v` is an experimental feature not enabled on this server yet", d.prefix, cmd.name)
+	case cmd.permission == permissionAdmin && !d.HasAdminPermission(s, m):
+		return fmt.Sprintf("Only server administrators can use `%v%v`", d.prefix, cmd.name)
+	case cmd.permission == permissionDJ && !d.isDJ(s, m):
+		return fmt.Sprintf("Only DJs and server administrators can use `%v%v`", d.prefix, cmd.name)
+	case cmd.lockable && d.IsQueueLocked() && !d.isDJ(s, m):
+		return fmt.Sprintf("🔒 The queue is locked, only DJs and server administrators can use `%v%v` until it's unlocked", d.prefix, cmd.name)
+	case cmd.category == categoryHistory && history.Unavailable():
+		return fmt.Sprintf("📴 The database is unavailable, `%v%v` is back once it recovers. Playback and the queue keep working", d.prefix, cmd.name)
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+)
+
+// pendingLookups tracks requests still looking up their songs, so deleting the command message
+// or stopping the player can call them off before they enqueue anything.
+type pendingLookups struct {
+	sync.Mutex
+	cancels map[string]context.CancelFunc // by message ID of the request, or a generated key
+	next    int
+}
+
+// startLookup returns the context looking up the songs of a request is bound to, within the trace of the command
+// of the message. It times out after RESOLVE_TIMEOUT_SECONDS or on Shutdown. The lookup is called off if the message
+// with the ID is deleted, messageID may be empty for requests not made by a message. The returned cancel must be called.
+func (d *Discord) startLookup(messageID string) (context.Context, context.CancelFunc) {
+	timeout := 0
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Warnf("Error loading config, lookups have no timeout: %v", err)
+	} else {
+		timeout = config.ResolveTimeoutSeconds
+	}
+
+	// Requests made without a command, e.g. in the request channel or by the API, are traced on their own
+	parent := d.commandContext(messageID)
+	var span *tracing.Span
+	if !tracing.FromContext(parent).Valid() {
+		parent, span = tracing.Start(parent, "request", tracing.String("guild.id", d.GuildID))
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, time.Duration(timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	d.lookups.Lock()
+	defer d.lookups.Unlock()
+
+	if d.lookups.cancels == nil {
+		d.lookups.cancels = make(map[string]context.CancelFunc)
+	}
+	key := messageID
+	if key == "" {
+		d.lookups.next++
+		key = fmt.Sprintf("#%d", d.lookups.next)
+	}
+	d.lookups.cancels[key] = cancel
+
+	return ctx, func() {
+		cancel()
+		span.End()
+
+		d.lookups.Lock()
+		delete(d.lookups.cancels, key)
+		d.lookups.Unlock()
+	}
+}
+
+// cancelLookup calls off the lookup of the request made by the message, if it's still running.
+func (d *Discord) cancelLookup(messageID string) {
+	d.lookups.Lock()
+	cancel, found := d.lookups.cancels[messageID]
+	d.lookups.Unlock()
+
+	if found {
+		slog.Infof("Calling off lookup of deleted request %v", messageID)
+		cancel()
+	}
+}
+
+// cancelLookups calls off every lookup still running.
+func (d *Discord) cancelLookups() {
+	d.lookups.Lock()
+	defer d.lookups.Unlock()
+
+	for _, cancel := range d.lookups.cancels {
+		cancel()
+	}
+}
+
+// onMessageDelete calls off the lookup of a request whose command message was deleted.
+func (d *Discord) onMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	if m.GuildID != d.GuildID {
+		return
+	}
+
+	d.cancelLookup(m.ID)
+}
+
+// lookupError describes why a lookup ended early.
+func lookupError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("looking up the request took too long: %w", ctx.Err())
+	}
+	return fmt.Errorf("request was called off: %w", ctx.Err())
+}
@@ -0,0 +1,39 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// handleLoopCommand shows or sets whether the current track or the whole queue repeats.
+func (d *Discord) handleLoopCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	available := "`" + strings.Join(player.RepeatModeNames, "`, `") + "`"
+
+	if param == "" {
+		mode := d.Player.GetRepeatMode()
+		d.sendTextEmbed(s, m, fmt.Sprintf("%v Repeat is `%v`\nUse `%vloop [mode]` to change it, available: %v", mode.StringEmoji(), mode, d.prefix, available))
+		return
+	}
+
+	mode, err := player.ParseRepeatMode(param)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown repeat mode `%v`, available: %v", param, available))
+		return
+	}
+
+	d.Player.SetRepeatMode(mode)
+
+	content := "Repeat is off, finished tracks leave the queue"
+	switch mode {
+	case player.RepeatTrack:
+		content = "Repeating the current track until it's skipped"
+	case player.RepeatQueue:
+		content = "Repeating the queue, finished and skipped tracks are queued again"
+	}
+	d.sendConfirmation(s, m, mode.StringEmoji(), content)
+}
@@ -0,0 +1,313 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	maxMacros          = 25
+	maxMacroSteps      = 10
+	macroStepSeparator = ";"
+)
+
+// handleMacroCommand handles the macro command for Discord.
+func (d *Discord) handleMacroCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	action, rest, _ := strings.Cut(param, " ")
+	action = strings.ToLower(action)
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case action == "" || action == "list":
+		d.listMacros(s, m)
+		return
+	case action == "triggers":
+		d.listMacroTriggers(s, m)
+		return
+	case action == "run" && rest != "":
+		name := strings.ToLower(rest)
+		steps := d.macroSteps(name)
+		if steps == nil {
+			d.sendTextEmbed(s, m, fmt.Sprintf("No macro `%v`, see `%vmacro list`", name, d.prefix))
+			return
+		}
+		d.runMacro(s, m, name, steps)
+		return
+	case action != "set" && action != "remove" && action != "at" && action != "when" && action != "untrigger":
+		break
+	case !d.HasAdminPermission(s, m):
+		d.sendTextEmbed(s, m, "Only server administrators can change macros")
+		return
+	case action == "set":
+		name, definition, found := strings.Cut(rest, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if found && name != "" && !strings.Contains(name, " ") {
+			d.setMacro(s, m, name, definition)
+			return
+		}
+	case action == "remove" && rest != "":
+		d.removeMacro(s, m, strings.ToLower(rest))
+		return
+	case action == "at" || action == "when":
+		words := strings.Fields(strings.ToLower(rest))
+		if len(words) == 2 {
+			d.addMacroTrigger(s, m, action, words[0], words[1])
+			return
+		}
+	case action == "untrigger" && rest != "":
+		d.removeMacroTrigger(s, m, rest)
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vmacro`, `%vmacro set [name] = [command]; [command]...`, `%vmacro run [name]`, `%vmacro remove [name]`, e.g. `%vmacro set party = shuffle; play lofi`\n"+
+		"Triggers: `%vmacro at [name] [HH:MM]`, `%vmacro when [name] [members]`, `%vmacro triggers`, `%vmacro untrigger [id]`",
+		d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix))
+}
+
+// listMacros shows the macros defined for the guild.
+func (d *Discord) listMacros(s *discordgo.Session, m *discordgo.MessageCreate) {
+	text := d.describeMacros()
+	if text == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🎬 No macros yet, use `%vmacro set [name] = [command]; [command]...` to add one, e.g. `%vmacro set party = shuffle; play lofi`", d.prefix, d.prefix))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎬 Macros, run them with `%v[name]` or `%vmacro run [name]`\n%v", d.prefix, d.prefix, text))
+}
+
+// setMacro stores a macro, replacing the steps of an existing one of the same name.
+func (d *Discord) setMacro(s *discordgo.Session, m *discordgo.MessageCreate, name, definition string) {
+	if err := d.checkMacroName(name); err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("`%v` %v", name, err))
+		return
+	}
+
+	steps, err := d.parseMacroSteps(definition)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Macro `%v` not saved: %v", name, err))
+		return
+	}
+
+	record := &db.CommandMacro{GuildID: d.GuildID, Name: name, Steps: strings.Join(steps, macroStepSeparator+" "), CreatedBy: m.Author.ID}
+	if err := db.SaveCommandMacro(record); err != nil {
+		slog.Errorf("Error saving macro %v: %v", name, err)
+		d.sendTextEmbed(s, m, "Error saving macro")
+		return
+	}
+
+	d.macrosMu.Lock()
+	d.macros[name] = steps
+	d.macrosMu.Unlock()
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎬 `%v%v` now runs %v", d.prefix, name, d.describeMacroSteps(steps)))
+}
+
+// checkMacroName reports why a macro can't be named so, nil if the name is free or the guild's macro is replaced.
+func (d *Discord) checkMacroName(name string) error {
+	if len(name) > maxCustomAliasSize {
+		return fmt.Errorf("is longer than %d characters", maxCustomAliasSize)
+	}
+
+	if cmd := findCommand(name); cmd != nil {
+		return fmt.Errorf("is already used by `%v`", cmd.name)
+	}
+
+	for _, reserved := range reservedCommands {
+		if name == reserved {
+			return errors.New("is reserved")
+		}
+	}
+
+	if command := d.customAlias(name); command != "" {
+		return fmt.Errorf("is already an alias of `%v`", command)
+	}
+
+	d.macrosMu.RLock()
+	defer d.macrosMu.RUnlock()
+
+	if _, exists := d.macros[name]; !exists && len(d.macros) >= maxMacros {
+		return fmt.Errorf("exceeds the limit of %d macros", maxMacros)
+	}
+
+	return nil
+}
+
+// parseMacroSteps splits a definition into steps, each a known command and its parameter.
+// Macros can't run macros, so they can't loop.
+func (d *Discord) parseMacroSteps(definition string) ([]string, error) {
+	var steps []string
+	for _, step := range strings.Split(definition, macroStepSeparator) {
+		step = strings.TrimPrefix(strings.TrimSpace(step), d.prefix)
+		if step == "" {
+			continue
+		}
+
+		word, param, _ := strings.Cut(step, " ")
+		word = strings.ToLower(word)
+
+		cmd := d.lookupCommand(word)
+		if cmd == nil {
+			if d.macroSteps(word) != nil {
+				return nil, fmt.Errorf("macros can't run other macros like `%v`", word)
+			}
+			return nil, fmt.Errorf("unknown command `%v`", word)
+		}
+		if cmd.run == nil || cmd.name == "macro" {
+			return nil, fmt.Errorf("`%v%v` can't be used in macros", d.prefix, cmd.name)
+		}
+
+		steps = append(steps, strings.TrimSpace(word+" "+strings.TrimSpace(param)))
+	}
+
+	if len(steps) == 0 {
+		return nil, errors.New("no commands given")
+	}
+	if len(steps) > maxMacroSteps {
+		return nil, fmt.Errorf("more than %d commands", maxMacroSteps)
+	}
+
+	return steps, nil
+}
+
+// removeMacro deletes a macro of the guild.
+func (d *Discord) removeMacro(s *discordgo.Session, m *discordgo.MessageCreate, name string) {
+	deleted, err := db.DeleteCommandMacro(d.GuildID, name)
+	if err != nil {
+		slog.Errorf("Error deleting macro %v: %v", name, err)
+		d.sendTextEmbed(s, m, "Error removing macro")
+		return
+	}
+	if deleted == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No macro `%v`", name))
+		return
+	}
+
+	d.macrosMu.Lock()
+	delete(d.macros, name)
+	d.macrosMu.Unlock()
+
+	if err := db.DeleteMacroTriggersByMacro(d.GuildID, name); err != nil {
+		slog.Errorf("Error deleting triggers of macro %v: %v", name, err)
+	}
+	d.loadMacroTriggers()
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎬 Removed macro `%v`", name))
+}
+
+// runMacro runs the steps of a macro one after another as if the author sent them.
+// A failing step is reported and doesn't stop the steps after it.
+func (d *Discord) runMacro(s *discordgo.Session, m *discordgo.MessageCreate, name string, steps []string) {
+	slog.Infof("Running macro %v for %v: %v", name, m.Author.ID, strings.Join(steps, macroStepSeparator+" "))
+
+	var failures []string
+	for i, step := range steps {
+		if err := d.runMacroStep(s, m, step); err != nil {
+			slog.Warnf("Step %d of macro %v failed: %v", i+1, name, err)
+			failures = append(failures, fmt.Sprintf("❌ `%v%v`: %v", d.prefix, step, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🎬 Macro `%v` ran %d of %d commands\n%v", name, len(steps)-len(failures), len(steps), strings.Join(failures, "\n")))
+	}
+}
+
+// runMacroStep runs one command of a macro, a panicking handler fails the step rather than the bot.
+func (d *Discord) runMacroStep(s *discordgo.Session, m *discordgo.MessageCreate, step string) (err error) {
+	word, param, _ := strings.Cut(step, " ")
+
+	// Aliases may have been removed since the macro was saved
+	cmd := d.lookupCommand(word)
+	if cmd == nil || cmd.run == nil {
+		return fmt.Errorf("unknown command `%v`", word)
+	}
+	if denial := d.commandDenial(s, m, cmd); denial != "" {
+		return errors.New(denial)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("command crashed: %v", r)
+		}
+	}()
+
+	timeCommand(m, cmd.name, func() { cmd.run(d, s, m, param) })
+	return nil
+}
+
+// loadCommandMacros reads the macros defined for the guild.
+func (d *Discord) loadCommandMacros() {
+	macros := make(map[string][]string)
+
+	records, err := db.GetCommandMacrosByGuildID(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error loading command macros: %v", err)
+	}
+	for _, record := range records {
+		var steps []string
+		for _, step := range strings.Split(record.Steps, macroStepSeparator) {
+			if step = strings.TrimSpace(step); step != "" {
+				steps = append(steps, step)
+			}
+		}
+		macros[record.Name] = steps
+	}
+
+	d.macrosMu.Lock()
+	d.macros = macros
+	d.macrosMu.Unlock()
+}
+
+// macroSteps returns the steps of a macro defined for the guild, nil if there is none.
+func (d *Discord) macroSteps(name string) []string {
+	d.macrosMu.RLock()
+	defer d.macrosMu.RUnlock()
+
+	return d.macros[name]
+}
+
+// macroNames returns the guild's macros with the prefix, sorted.
+func (d *Discord) macroNames() []string {
+	d.macrosMu.RLock()
+	names := make([]string, 0, len(d.macros))
+	for name := range d.macros {
+		names = append(names, fmt.Sprintf("`%v%v`", d.prefix, name))
+	}
+	d.macrosMu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// describeMacros lists the guild's macros with their steps, one macro per line.
+func (d *Discord) describeMacros() string {
+	d.macrosMu.RLock()
+	names := make([]string, 0, len(d.macros))
+	for name := range d.macros {
+		names = append(names, name)
+	}
+	d.macrosMu.RUnlock()
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("**%v%v**: %v\n", d.prefix, name, d.describeMacroSteps(d.macroSteps(name))))
+	}
+	return builder.String()
+}
+
+func (d *Discord) describeMacroSteps(steps []string) string {
+	quoted := make([]string, len(steps))
+	for i, step := range steps {
+		quoted[i] = fmt.Sprintf("`%v%v`", d.prefix, step)
+	}
+	return strings.Join(quoted, " → ")
+}
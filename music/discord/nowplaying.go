@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	// nowPlayingInterval spaces out edits of the now playing message
+	nowPlayingInterval = 5 * time.Second
+	// nowPlayingMaxAge stops updating the message of an endless stream after a while
+	nowPlayingMaxAge = time.Hour
+)
+
+// nowPlaying is the updater of the latest now playing message, a newer message, a skip or a stop ends it.
+type nowPlaying struct {
+	sync.Mutex
+	stopped  chan struct{}
+	finished chan struct{}
+}
+
+// handleNowCommand posts the current track with a progress bar and keeps it up to date until the track ends.
+func (d *Discord) handleNowCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	song := d.Player.GetCurrentSong()
+	if song == nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Nothing is playing, use `%vplay [title/url/id/stream]` to start", d.prefix))
+		return
+	}
+
+	message, err := SendComplex(s, m.Message.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{d.nowPlayingEmbed(song, false)},
+		Components: controlComponents(),
+	})
+	if err != nil {
+		slog.Warnf("Error sending now playing message: %v", err)
+		return
+	}
+	d.trackStatusMessage(s, m.Message.ChannelID, message.ID)
+
+	d.stopNowPlaying()
+
+	stopped, finished := make(chan struct{}), make(chan struct{})
+	d.nowPlaying.Lock()
+	d.nowPlaying.stopped, d.nowPlaying.finished = stopped, finished
+	d.nowPlaying.Unlock()
+
+	go d.updateNowPlaying(s, m.Message.ChannelID, message.ID, song, stopped, finished)
+}
+
+// stopNowPlaying ends updating the now playing message and waits for its last edit.
+func (d *Discord) stopNowPlaying() {
+	d.nowPlaying.Lock()
+	stopped, finished := d.nowPlaying.stopped, d.nowPlaying.finished
+	d.nowPlaying.stopped, d.nowPlaying.finished = nil, nil
+	d.nowPlaying.Unlock()
+
+	if stopped == nil {
+		return
+	}
+	close(stopped)
+	<-finished
+}
+
+// updateNowPlaying edits the message with the playback position of the song until it stops playing
+// or the updater is stopped, then marks the song as finished.
+func (d *Discord) updateNowPlaying(s *discordgo.Session, channelID, messageID string, song *player.Song, stopped, finished chan struct{}) {
+	defer close(finished)
+
+	ticker := time.NewTicker(nowPlayingInterval)
+	defer ticker.Stop()
+	expired := time.After(nowPlayingMaxAge)
+
+updating:
+	for {
+		select {
+		case <-stopped:
+			break updating
+		case <-expired:
+			break updating
+		case <-ticker.C:
+		}
+
+		if !d.InstanceActive || d.Player.GetCurrentSong() != song {
+			break
+		}
+		if _, err := EditEmbed(s, channelID, messageID, d.nowPlayingEmbed(song, false)); err != nil {
+			slog.Debugf("Stopped updating now playing message: %v", err)
+			return
+		}
+	}
+
+	// The controls go with the progress bar, they would act on another track now
+	_, err := EditComplex(s, &discordgo.MessageEdit{
+		Channel:    channelID,
+		ID:         messageID,
+		Embeds:     []*discordgo.MessageEmbed{d.nowPlayingEmbed(song, true)},
+		Components: []discordgo.MessageComponent{},
+	})
+	if err != nil {
+		slog.Debugf("Error finishing now playing message: %v", err)
+	}
+}
+
+// nowPlayingEmbed shows the song with its position as a progress bar, or only its title once it finished.
+func (d *Discord) nowPlayingEmbed(song *player.Song, ended bool) *discordgo.MessageEmbed {
+	var content string
+	switch {
+	case ended:
+		content = fmt.Sprintf("⏹ *%v*\n\nNo longer playing, `%vnow` shows the current track", songLink(song), d.prefix)
+	case song.Source.Endless() || song.Duration <= 0:
+		content = fmt.Sprintf("%v *%v*\n\n🔴 Live `%v`", d.Player.GetCurrentStatus().StringEmoji(), songLink(song), formatClock(d.Player.GetPlaybackPosition()))
+	default:
+		content = fmt.Sprintf("%v *%v*\n\n%v", d.Player.GetCurrentStatus().StringEmoji(), songLink(song), progressBar(d.Player.GetPlaybackPosition(), song.Duration))
+	}
+	if song.RequesterID != "" {
+		content += fmt.Sprintf("\nRequested by <@%v>", song.RequesterID)
+	} else if song.Autoplayed {
+		content += "\n📻 Autoplay"
+	}
+
+	return embed.NewEmbed().
+		SetDescription(content).
+		SetThumbnail(song.Thumbnail.URL).
+		SetColor(0x9f00d4).MessageEmbed
+}
@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// handleOrderCommand handles the queue order command for Discord.
+func (d *Discord) handleOrderCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	available := "`" + strings.Join(player.QueueStrategyNames, "`, `") + "`"
+
+	if param == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔀 Queue order is `%v`\nUse `%vorder [name]` to change it, available: %v", d.Player.GetQueueStrategy().Name(), d.prefix, available))
+		return
+	}
+
+	strategy, err := player.NewQueueStrategy(param)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown queue order `%v`, available: %v", param, available))
+		return
+	}
+
+	d.Player.SetQueueStrategy(strategy)
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.QueueStrategy = strategy.Name()
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving queue order: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔀 Queue order set to `%v`", strategy.Name()))
+}
+
+// handleShuffleCommand handles the shuffle command for Discord.
+func (d *Discord) handleShuffleCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	if len(d.Player.GetSongQueue()) == 0 {
+		d.sendTextEmbed(s, m, "The queue is empty, nothing to shuffle")
+		return
+	}
+
+	if err := d.Player.ShuffleQueue(); err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("The queue plays in `%v` order, which would undo shuffling. Use `%vorder fifo` or `%vorder fair` first.",
+			d.Player.GetQueueStrategy().Name(), d.prefix, d.prefix))
+		return
+	}
+
+	d.sendConfirmation(s, m, "🔀", fmt.Sprintf("Queue shuffled. Use `%vlist` to see the new order.", d.prefix))
+}
@@ -0,0 +1,309 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"gorm.io/gorm"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// maxListeningParties is how many parties a guild may have announced at once.
+const maxListeningParties = 10
+
+// handleEventCommand lists the announced listening parties, announces one or cancels one.
+func (d *Discord) handleEventCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	action, rest, _ := strings.Cut(strings.TrimSpace(param), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case action == "" || action == "list":
+		d.listParties(s, m)
+		return
+	case !d.HasAdminPermission(s, m):
+		d.sendTextEmbed(s, m, "Only server administrators can announce or cancel listening parties")
+		return
+	case action == "cancel":
+		d.cancelParty(s, m, strings.TrimPrefix(rest, "#"))
+	default:
+		d.announceParty(s, m, param)
+	}
+}
+
+// announceParty schedules a listening party in the author's voice or stage channel and publishes it
+// as a scheduled event of the guild, e.g. "2024-06-01 21:00 Synthwave night | synthwave mix".
+func (d *Discord) announceParty(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	usage := fmt.Sprintf("Usage: `%vevent [YYYY-MM-DD] [HH:MM] [title] | [title/url to play]`, the part after `|` is optional", d.prefix)
+
+	param, query, _ := strings.Cut(param, "|")
+	startAt, title, ok := d.parsePartyStart(param)
+	if !ok {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No start time, use HH:MM like `21:00` in %v\n%v", d.getLocale().Location, usage))
+		return
+	}
+	if !startAt.After(time.Now()) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("That time has passed already, it was %v", relativeTimestamp(startAt)))
+		return
+	}
+	if title == "" {
+		d.sendTextEmbed(s, m, usage)
+		return
+	}
+	if runes := []rune(title); len(runes) > 100 {
+		title = string(runes[:100])
+	}
+
+	var voiceChannelID string
+	if guild, err := s.State.Guild(d.GuildID); err == nil {
+		if vs, found := findUserVoiceState(m.Author.ID, guild.VoiceStates); found {
+			voiceChannelID = vs.ChannelID
+		}
+	}
+	if voiceChannelID == "" {
+		d.sendTextEmbed(s, m, "Join the voice or stage channel the party should take place in first")
+		return
+	}
+
+	parties, err := db.GetListeningParties(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting listening parties: %v", err)
+		d.sendTextEmbed(s, m, "Error getting listening parties")
+		return
+	}
+	if len(parties) >= maxListeningParties {
+		d.sendTextEmbed(s, m, fmt.Sprintf("This server has the maximum of %d parties announced, cancel one with `%vevent cancel [id]`", maxListeningParties, d.prefix))
+		return
+	}
+
+	party := &db.ListeningParty{
+		GuildID:        d.GuildID,
+		Title:          title,
+		Query:          strings.TrimSpace(query),
+		VoiceChannelID: voiceChannelID,
+		ChannelID:      m.ChannelID,
+		StartAt:        startAt,
+		CreatedBy:      m.Author.ID,
+	}
+
+	entityType := discordgo.GuildScheduledEventEntityTypeVoice
+	if d.isStageChannel(voiceChannelID) {
+		entityType = discordgo.GuildScheduledEventEntityTypeStageInstance
+	}
+	description := "🎶 Listening party hosted by Melodix"
+	if party.Query != "" {
+		description += ", playing " + party.Query
+	}
+	event, err := s.GuildScheduledEventCreate(d.GuildID, &discordgo.GuildScheduledEventParams{
+		ChannelID:          voiceChannelID,
+		Name:               title,
+		Description:        description,
+		ScheduledStartTime: &startAt,
+		PrivacyLevel:       discordgo.GuildScheduledEventPrivacyLevelGuildOnly,
+		EntityType:         entityType,
+	})
+	eventNote := ""
+	if err != nil {
+		slog.Warnf("Error creating scheduled event of listening party: %v", err)
+		eventNote = "\n⚠️ I couldn't add it to the server's events, that needs the **Manage Events** permission"
+	} else {
+		party.EventID = event.ID
+	}
+
+	if err := db.CreateListeningParty(party); err != nil {
+		slog.Errorf("Error saving listening party: %v", err)
+		d.sendTextEmbed(s, m, "Error saving the listening party")
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎉 Listening party `#%v` **%v** in <#%v> %v%v\n`%vevent cancel %v` cancels it",
+		party.ID, party.Title, voiceChannelID, relativeTimestamp(startAt), eventNote, d.prefix, party.ID))
+}
+
+// parsePartyStart reads the start of a party at the beginning of a parameter in the guild's timezone and
+// returns it with the rest of the parameter. Without a date the time is today's, or tomorrow's once it passed.
+// It reports false if the parameter doesn't start with a time.
+func (d *Discord) parsePartyStart(param string) (time.Time, string, bool) {
+	location := d.getLocale().Location
+	now := time.Now().In(location)
+	words := strings.Fields(param)
+
+	if len(words) >= 2 {
+		if start, err := time.ParseInLocation("2006-01-02 15:04", words[0]+" "+words[1], location); err == nil {
+			return start, strings.Join(words[2:], " "), true
+		}
+	}
+
+	if len(words) >= 1 {
+		if at, err := time.Parse("15:04", words[0]); err == nil {
+			start := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, location)
+			if !start.After(now) {
+				start = start.AddDate(0, 0, 1)
+			}
+			return start, strings.Join(words[1:], " "), true
+		}
+	}
+
+	return time.Time{}, "", false
+}
+
+// listParties shows the listening parties announced in the guild.
+func (d *Discord) listParties(s *discordgo.Session, m *discordgo.MessageCreate) {
+	parties, err := db.GetListeningParties(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting listening parties: %v", err)
+		d.sendTextEmbed(s, m, "Error getting listening parties")
+		return
+	}
+
+	if len(parties) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🎉 No listening parties announced, use `%vevent [HH:MM] [title]` in a voice or stage channel to announce one", d.prefix))
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🎉 Listening parties\n")
+	for _, party := range parties {
+		builder.WriteString(fmt.Sprintf("\n` %d ` **%v** in <#%v> %v, by <@%v>", party.ID, party.Title, party.VoiceChannelID, relativeTimestamp(party.StartAt), party.CreatedBy))
+		if party.Status == db.PartyActive {
+			builder.WriteString(" · happening now")
+		}
+	}
+	d.sendTextEmbed(s, m, builder.String())
+}
+
+// cancelParty removes a listening party and its scheduled event.
+func (d *Discord) cancelParty(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	id, err := strconv.ParseUint(param, 10, 0)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vevent cancel [id]`, IDs are shown by `%vevent`", d.prefix, d.prefix))
+		return
+	}
+
+	party, err := db.GetListeningParty(d.GuildID, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No listening party `#%v`, see `%vevent`", id, d.prefix))
+		return
+	}
+	if err != nil {
+		slog.Errorf("Error getting listening party %v: %v", id, err)
+		d.sendTextEmbed(s, m, "Error cancelling the listening party")
+		return
+	}
+
+	if party.EventID != "" {
+		if err := s.GuildScheduledEventDelete(d.GuildID, party.EventID); err != nil && !isNotFound(err) {
+			slog.Warnf("Error deleting scheduled event of listening party %v: %v", id, err)
+		}
+	}
+	if err := db.DeleteListeningParty(d.GuildID, uint(id)); err != nil {
+		slog.Errorf("Error deleting listening party %v: %v", id, err)
+		d.sendTextEmbed(s, m, "Error cancelling the listening party")
+		return
+	}
+
+	d.sendConfirmation(s, m, "🗑️", fmt.Sprintf("Cancelled listening party **%v**", party.Title))
+}
+
+// startDueParties starts the listening parties whose time came, called by the scheduler of daily triggers.
+func (d *Discord) startDueParties(now time.Time) {
+	parties, err := db.GetListeningPartiesByStatus(d.GuildID, db.PartyScheduled, now)
+	if err != nil {
+		slog.Warnf("Error getting due listening parties: %v", err)
+		return
+	}
+
+	for i := range parties {
+		party := &parties[i]
+		party.Status = db.PartyActive
+		if err := db.SaveListeningParty(party); err != nil {
+			slog.Errorf("Error starting listening party %v: %v", party.ID, err)
+			continue
+		}
+		go d.startParty(party)
+	}
+}
+
+// startParty joins the channel of a listening party, marks its event as started and plays its query.
+func (d *Discord) startParty(party *db.ListeningParty) {
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: party.ChannelID,
+		GuildID:   d.GuildID,
+		Author:    &discordgo.User{ID: party.CreatedBy},
+	}}
+
+	if err := d.JoinVoiceChannel(party.VoiceChannelID); err != nil {
+		slog.Errorf("Error joining voice channel %v for listening party %v: %v", party.VoiceChannelID, party.ID, err)
+		d.sendTextEmbed(d.Session, m, fmt.Sprintf("⚠️ Listening party **%v** couldn't start, I can't join <#%v>", party.Title, party.VoiceChannelID))
+		d.endParty(party, discordgo.GuildScheduledEventStatusCanceled)
+		return
+	}
+
+	if party.EventID != "" {
+		_, err := d.Session.GuildScheduledEventEdit(d.GuildID, party.EventID, &discordgo.GuildScheduledEventParams{Status: discordgo.GuildScheduledEventStatusActive})
+		if err != nil {
+			slog.Warnf("Error starting scheduled event of listening party %v: %v", party.ID, err)
+		}
+	}
+
+	d.sendTextEmbed(d.Session, m, fmt.Sprintf("🎉 Listening party **%v** starts now in <#%v>", party.Title, party.VoiceChannelID))
+
+	if party.Query == "" {
+		return
+	}
+	paramType, songsList := parseParameter(party.Query)
+	ctx, cancel := d.startLookup("")
+	defer cancel()
+	playlist := fetchSongs(ctx, paramType, songsList, d, nil)
+	if ctx.Err() != nil {
+		d.sendTextEmbed(d.Session, m, fmt.Sprintf("Looking up `%v` was called off or took too long", party.Query))
+		return
+	}
+	if len(playlist) == 0 {
+		d.sendTextEmbed(d.Session, m, fmt.Sprintf("No music found for `%v`", party.Query))
+		return
+	}
+	for _, song := range playlist {
+		song.RequesterID = party.CreatedBy
+		d.Player.Enqueue(song)
+	}
+	if d.Player.GetCurrentSong() == nil {
+		go d.Player.Play(d.ctx, 0, nil)
+	}
+}
+
+// endActiveParties ends the started listening parties and their events once the bot left voice.
+func (d *Discord) endActiveParties() {
+	parties, err := db.GetListeningPartiesByStatus(d.GuildID, db.PartyActive, time.Now())
+	if err != nil {
+		slog.Warnf("Error getting active listening parties: %v", err)
+		return
+	}
+
+	for i := range parties {
+		d.endParty(&parties[i], discordgo.GuildScheduledEventStatusCompleted)
+	}
+}
+
+// endParty marks a listening party done and its event completed or canceled.
+func (d *Discord) endParty(party *db.ListeningParty, status discordgo.GuildScheduledEventStatus) {
+	party.Status = db.PartyDone
+	if err := db.SaveListeningParty(party); err != nil {
+		slog.Errorf("Error ending listening party %v: %v", party.ID, err)
+	}
+
+	if party.EventID == "" {
+		return
+	}
+	_, err := d.Session.GuildScheduledEventEdit(d.GuildID, party.EventID, &discordgo.GuildScheduledEventParams{Status: status})
+	if err != nil && !isNotFound(err) {
+		slog.Warnf("Error ending scheduled event of listening party %v: %v", party.ID, err)
+	}
+}
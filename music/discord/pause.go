@@ -3,7 +3,6 @@ package discord
 import (
 	"log/slog"
 
-	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -17,11 +16,7 @@ func (d *Discord) handlePauseCommand(s *discordgo.Session, m *discordgo.MessageC
 
 	d.Player.Pause()
 
-	embedStr := d.Player.GetCurrentStatus().StringEmoji() + " " + d.Player.GetCurrentStatus().String()
-	embedMsg := embed.NewEmbed().
-		SetDescription(embedStr).
-		SetColor(0x9f00d4).MessageEmbed
-	s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	d.sendConfirmation(s, m, d.Player.GetCurrentStatus().StringEmoji(), d.Player.GetCurrentStatus().String())
 
 	slog.Info(d.Player.GetCurrentStatus().String())
 }
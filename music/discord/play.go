@@ -1,6 +1,7 @@
 package discord
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -11,13 +12,17 @@ import (
 	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
 	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
 	"github.com/keshon/melodix-discord-player/music/player"
 	"github.com/keshon/melodix-discord-player/music/sources"
 )
 
 // handlePlayCommand handles the play command for Discord.
 func (d *Discord) handlePlayCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string, enqueueOnly bool) {
+	requestedAt := time.Now()
 	d.changeAvatar(s)
 
 	// Wait message
@@ -27,11 +32,12 @@ func (d *Discord) handlePlayCommand(s *discordgo.Session, m *discordgo.MessageCr
 		SetDescription(embedStr).
 		SetColor(0x9f00d4).MessageEmbed
 
-	pleaseWaitMessage, err := s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	pleaseWaitMessage, err := SendEmbed(s, m.Message.ChannelID, embedMsg)
 	if err != nil {
 		slog.Warnf("Error sending 'please wait' message: %v", err)
 	}
 
+	param, fresh := cutFreshModifier(param)
 	paramType, songsList := parseParameter(param)
 
 	// Check if any songs were found
@@ -42,34 +48,51 @@ func (d *Discord) handlePlayCommand(s *discordgo.Session, m *discordgo.MessageCr
 			SetDescription(embedStr).
 			SetColor(0x9f00d4).MessageEmbed
 
-		s.ChannelMessageEditEmbed(m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
+		EditEmbed(s, m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
 		return
 	}
 
 	// Join voice channel message
-	c, _ := s.State.Channel(m.Message.ChannelID)
-	g, _ := s.State.Guild(c.GuildID)
+	// The guild is taken from the instance since commands may also arrive by DM
+	g, err := s.State.Guild(d.GuildID)
 
-	if len(g.VoiceStates) == 0 {
+	if err != nil || len(g.VoiceStates) == 0 {
 		embedStr = getJoinVoiceChannelPhrase()
 		embedMsg = embed.NewEmbed().
 			SetColor(0x9f00d4).
 			SetDescription(embedStr).
 			SetColor(0x9f00d4).MessageEmbed
 
-		s.ChannelMessageEditEmbed(m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
+		EditEmbed(s, m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
 		return
 	}
 
-	// Fill-in playlist
-	playlist, err := createPlaylist(paramType, songsList, d, m)
+	// Join the author's voice channel while the songs are looked up, if enabled
+	var prejoin *voicePrejoin
+	if vs, found := findUserVoiceState(m.Author.ID, g.VoiceStates); found && !enqueueOnly {
+		prejoin = d.prejoinVoice(vs.ChannelID)
+	}
+
+	// Fill-in playlist, showing progress if it takes a while
+	lookupCtx, cancelLookup := d.startLookup(m.Message.ID)
+	defer cancelLookup()
+	progress := startFetchProgress(s, m.Message.ChannelID, pleaseWaitMessage.ID, len(songsList))
+	playlist, err := createPlaylist(lookupCtx, paramType, songsList, d, m, progress)
+	progress.stop()
+
+	if err != nil || len(playlist) == 0 {
+		d.revertPrejoin(prejoin)
+	} else {
+		prejoin.wait()
+	}
+
 	if err != nil {
 		embedStr = fmt.Sprintf("%v\n\n**Error details**:\n`%v`", getErrorFormingPlaylistPhrase(), err)
 		embedMsg = embed.NewEmbed().
 			SetColor(0x9f00d4).
 			SetDescription(embedStr).
 			SetColor(0x9f00d4).MessageEmbed
-		s.ChannelMessageEditEmbed(m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
+		EditEmbed(s, m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
 		return
 	}
 
@@ -80,103 +103,178 @@ func (d *Discord) handlePlayCommand(s *discordgo.Session, m *discordgo.MessageCr
 			SetDescription(embedStr).
 			SetColor(0x9f00d4).MessageEmbed
 
-		s.ChannelMessageEditEmbed(m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
+		EditEmbed(s, m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
 		return
 	}
 
+	if !enqueueOnly {
+		d.stampRequest(playlist, requestedAt)
+	}
+
+	for _, song := range playlist {
+		song.Fresh = fresh
+	}
+
 	// Enqueue playlist to the player
 	err = playOrEnqueue(d, playlist, s, m, enqueueOnly, pleaseWaitMessage.ID)
 	if err != nil {
+		d.revertPrejoin(prejoin)
+
 		embedStr = fmt.Sprintf("%v\n\n**Error details**:\n`%v`", getErrorFormingPlaylistPhrase(), err)
 		embedMsg = embed.NewEmbed().
 			SetColor(0x9f00d4).
 			SetDescription(embedStr).
 			SetColor(0x9f00d4).MessageEmbed
 
-		s.ChannelMessageEditEmbed(m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
+		EditEmbed(s, m.Message.ChannelID, pleaseWaitMessage.ID, embedMsg)
 		return
 	}
 }
 
-// createPlaylist creates a playlist of songs based on the parameter type and list of songs.
-func createPlaylist(paramType string, songsList []string, d *Discord, m *discordgo.MessageCreate) ([]*player.Song, error) {
+// createPlaylist creates a playlist of songs based on the parameter type and list of songs
+// and attributes them to the message author. It fails if the context ends before all songs are found.
+func createPlaylist(ctx context.Context, paramType string, songsList []string, d *Discord, m *discordgo.MessageCreate, progress *fetchProgress) ([]*player.Song, error) {
+	playlist := fetchSongs(ctx, paramType, songsList, d, progress)
+	if ctx.Err() != nil {
+		return nil, lookupError(ctx)
+	}
+
+	isAdmin := d.HasAdminPermission(d.Session, m)
+	for _, song := range playlist {
+		song.RequesterID = m.Author.ID
+		if isAdmin {
+			song.Priority = 1
+		}
+	}
+
+	return playlist, nil
+}
+
+// fetchSongs looks up songs based on the parameter type and list of songs, failed lookups are skipped.
+// Each lookup advances the progress, which may be nil. Lookups stop once the context ends.
+func fetchSongs(ctx context.Context, paramType string, songsList []string, d *Discord, progress *fetchProgress) []*player.Song {
 	var playlist []*player.Song
 
 	youtube := sources.NewYoutube()
+	youtube.OnPlaylist(progress.foundPlaylist)
 	stream := sources.NewStream()
 
-	for _, param := range songsList {
-
-		var songs []*player.Song
-		var err error
+	// Playing the songs joins the trace of the request
+	trace := tracing.FromContext(ctx)
 
-		switch paramType {
-		case "history_id":
-			id, err := strconv.Atoi(param)
-			if err != nil {
-				slog.Error("Cannot convert string id to int id")
-				continue
-			}
-			songs, err = youtube.FetchSongsByIDs(m.GuildID, []int{id})
-			if err != nil {
-				slog.Warnf("Error fetching songs by history ID: %v", err)
-				continue
-			}
-		case "youtube_title":
-			songs, err = youtube.FetchSongsByTitle(param)
-			if err != nil {
-				slog.Warnf("Error fetching songs by title: %v", err)
-				continue
-			}
-		case "youtube_url":
-			songs, err = youtube.FetchSongsByURLs([]string{param})
-			if err != nil {
-				slog.Warnf("Error fetching songs by URL: %v", err)
-				continue
-			}
-		case "stream_url":
-			songs, err = stream.FetchStreamsByURLs([]string{param})
-			if err != nil {
-				slog.Warnf("Error fetching stream by URL: %v", err)
-				continue
+	for _, param := range songsList {
+		resolveCtx, span := tracing.Start(ctx, "resolve", tracing.String("resolve.type", paramType), tracing.String("resolve.query", param))
+		songs, err := fetchSongsOf(resolveCtx, paramType, param, d.GuildID, youtube, stream)
+		span.SetAttributes(tracing.Int("resolve.songs", len(songs)))
+		span.SetError(err)
+		span.End()
+
+		if ctx.Err() != nil {
+			// Called off requests aren't failures of their source
+			if ctx.Err() == context.DeadlineExceeded {
+				slog.Warnf("Timed out fetching songs of %v %q", paramType, param)
+				recordFetchFailure(d.GuildID, paramType, param, ctx.Err())
 			}
+			break
+		}
+		progress.advance(len(songs))
+		if err != nil {
+			slog.Warnf("Error fetching songs of %v %q: %v", paramType, param, err)
+			recordFetchFailure(d.GuildID, paramType, param, err)
+			continue
 		}
 
-		// if err != nil {
-		// 	return nil, err
-		// }
-
+		for _, song := range songs {
+			song.Trace = trace
+		}
 		playlist = append(playlist, songs...)
 	}
 
-	return playlist, nil
+	return playlist
 }
 
-func playOrEnqueue(d *Discord, playlist []*player.Song, s *discordgo.Session, m *discordgo.MessageCreate, enqueueOnly bool, prevMessageID string) (err error) {
-	channel, err := s.State.Channel(m.Message.ChannelID)
-	if err != nil {
-		return err
+// fetchSongsOf looks up the songs of one part of a request.
+func fetchSongsOf(ctx context.Context, paramType, param, guildID string, youtube *sources.Youtube, stream *sources.Stream) ([]*player.Song, error) {
+	switch paramType {
+	case "history_id":
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, err
+		}
+		return youtube.FetchSongsByIDs(ctx, guildID, []int{id})
+	case "youtube_title":
+		return youtube.FetchSongsByTitle(ctx, param)
+	case "youtube_url":
+		return youtube.FetchSongsByURLs(ctx, []string{param})
+	case "stream_url":
+		return stream.FetchStreamsByURLs(ctx, []string{param})
+	case "spotify_url":
+		return newSpotify().FetchSongsByURLs(ctx, []string{param})
+	case "source_url":
+		source := sources.LinkSourceFor(param)
+		if source == nil {
+			return nil, fmt.Errorf("no source plays %v", param)
+		}
+		return source.FetchSongsByURLs(ctx, []string{param})
+	case "history_tag", "history_tag_shuffled":
+		return fetchTaggedSongs(ctx, guildID, param, paramType == "history_tag_shuffled", youtube)
+	case "ambience":
+		return fetchAmbience(param)
+	case "playlist":
+		return fetchPlaylistSongs(ctx, guildID, param, youtube, stream)
+	}
+	return nil, fmt.Errorf("unknown parameter type %v", paramType)
+}
+
+// recordFetchFailure counts a failed lookup for the failure report. Saved playlists and tags
+// are left out, they fail for missing names rather than for their sources.
+func recordFetchFailure(guildID, paramType, param string, err error) {
+	var source string
+	switch paramType {
+	case "history_id", "youtube_title", "youtube_url":
+		source = player.SourceYouTube.String()
+	case "stream_url":
+		source = player.SourceStream.String()
+	case "ambience":
+		source = player.SourceAmbience.String()
+	case "spotify_url":
+		source = sources.SpotifySourceName
+	case "source_url":
+		linkSource := sources.LinkSourceFor(param)
+		if linkSource == nil {
+			return
+		}
+		source = linkSource.Name()
+	default:
+		return
+	}
+
+	if err := history.NewHistory().AddFailure(guildID, source, player.FailureResolution, player.ClassifyFailure(err)); err != nil {
+		slog.Warnf("Error counting failure of %v: %v", paramType, err)
 	}
+}
 
-	guild, err := s.State.Guild(channel.GuildID)
+// errNothingPlayable is returned when playback ended without playing anything, e.g. every queued song failed to load.
+var errNothingPlayable = errors.New("none of the tracks could be played")
+
+func playOrEnqueue(d *Discord, playlist []*player.Song, s *discordgo.Session, m *discordgo.MessageCreate, enqueueOnly bool, prevMessageID string) (err error) {
+	guild, err := s.State.Guild(d.GuildID)
 	if err != nil {
 		return err
 	}
 
+	// Macros run by triggers play in the voice channel the bot joined for them
 	vs, found := findUserVoiceState(m.Message.Author.ID, guild.VoiceStates)
-	if !found {
+	if !found && !(isTriggered(m) && d.Player.GetVoiceConnection() != nil) {
 		return errors.New("user not found in voice channel")
 	}
 
 	if d.Player.GetVoiceConnection() == nil {
-		conn, err := d.Session.ChannelVoiceJoin(channel.GuildID, vs.ChannelID, false, true)
-		if err != nil {
+		if err := d.connectVoice(vs.ChannelID); err != nil {
 			slog.Errorf("Error connecting to voice channel: %v", err.Error())
-			s.ChannelMessageSend(m.Message.ChannelID, "Error connecting to voice channel")
+			SendMessage(s, m.Message.ChannelID, "Error connecting to voice channel")
 			return err
 		}
-		d.Player.SetVoiceConnection(conn)
-		conn.LogLevel = discordgo.LogWarning
 	}
 
 	previousPlaylistExist := len(d.Player.GetSongQueue())
@@ -186,32 +284,58 @@ func playOrEnqueue(d *Discord, playlist []*player.Song, s *discordgo.Session, m
 		d.Player.Enqueue(song)
 	}
 
-	if enqueueOnly {
-		showStatusMessage(d, s, m.Message.ChannelID, prevMessageID, playlist, previousPlaylistExist, false)
-	} else {
-		// go func() {
-		// 	for {
-		// 		if d.Player.GetCurrentStatus() == player.StatusPlaying || d.Player.GetCurrentStatus() == player.StatusPaused {
-		// 			showStatusMessage(d, s, m.Message.ChannelID, prevMessageID, playlist, previousPlaylistExist, true)
-		// 			break
-		// 		}
-		// 		time.Sleep(250 * time.Millisecond)
-		// 	}
-		// }()
-
-		go d.Player.Play(0, nil)
-		for {
-			if d.Player.GetCurrentStatus() == player.StatusPlaying || d.Player.GetCurrentStatus() == player.StatusPaused {
-				showStatusMessage(d, s, m.Message.ChannelID, prevMessageID, playlist, previousPlaylistExist, true)
-				break
-			}
-			time.Sleep(250 * time.Millisecond)
+	// Song request channel confirms with reactions, there is no status message to update
+	if prevMessageID == "" {
+		if !enqueueOnly {
+			go d.Player.Play(d.ctx, 0, nil)
 		}
+		return nil
 	}
 
+	if !enqueueOnly {
+		return d.playAndShowStatus(s, m.Message.ChannelID, prevMessageID, playlist, previousPlaylistExist)
+	}
+
+	showStatusMessage(d, s, m.Message.ChannelID, prevMessageID, playlist, previousPlaylistExist, false)
 	return nil
 }
 
+// playAndShowStatus starts playback and shows the status message once a song plays. It fails if playback ended
+// without anything playing, e.g. because none of the queued songs could be resolved, and gives up silently
+// once the instance shuts down.
+func (d *Discord) playAndShowStatus(s *discordgo.Session, channelID, prevMessageID string, playlist []*player.Song, previousPlaylistExist int) error {
+	ctx := d.ctx
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		d.Player.Play(ctx, 0, nil)
+	}()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if status := d.Player.GetCurrentStatus(); status == player.StatusPlaying || status == player.StatusPaused {
+			showStatusMessage(d, s, channelID, prevMessageID, playlist, previousPlaylistExist, true)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ended:
+			if status := d.Player.GetCurrentStatus(); status == player.StatusPlaying || status == player.StatusPaused {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errNothingPlayable
+		case <-ticker.C:
+		}
+	}
+}
+
 func showStatusMessage(d *Discord, s *discordgo.Session, channelID, prevMessageID string, playlist []*player.Song, previousPlaylistExist int, skipFirst bool) {
 
 	embedMsg := embed.NewEmbed().
@@ -219,11 +343,17 @@ func showStatusMessage(d *Discord, s *discordgo.Session, channelID, prevMessageI
 		SetFooter(version.AppFullName)
 
 	playerStatus := fmt.Sprintf("%v %v", d.Player.GetCurrentStatus().StringEmoji(), d.Player.GetCurrentStatus().String())
+	if d.IsQueueLocked() {
+		playerStatus += " · 🔒 Queue locked to DJs"
+	}
+	if mode := d.Player.GetRepeatMode(); mode != player.RepeatOff {
+		playerStatus += fmt.Sprintf(" · %v Repeating %v", mode.StringEmoji(), mode)
+	}
 	content := playerStatus + "\n"
 
 	// Display current song information
 	if currentSong := d.Player.GetCurrentSong(); currentSong != nil {
-		content += fmt.Sprintf("\n*[%v](%v)*\n\n", currentSong.Title, currentSong.UserURL)
+		content += fmt.Sprintf("\n*%v*\n\n", songLink(currentSong))
 		embedMsg.SetThumbnail(currentSong.Thumbnail.URL)
 	} else {
 		if len(d.Player.GetSongQueue()) > 0 {
@@ -237,11 +367,18 @@ func showStatusMessage(d *Discord, s *discordgo.Session, channelID, prevMessageI
 	if len(playlist) > 0 {
 		// Display queue status
 		if !skipFirst || len(playlist) > 1 {
-			content += "\n📑 In queue\n"
+			content += "\n📑 In queue"
+			if strategy := d.Player.GetQueueStrategy().Name(); strategy != player.StrategyFIFO {
+				content += fmt.Sprintf(" (%v order)", strategy)
+			}
+			content += "\n"
 		}
 
-		// Separate counter variable starting from 1
-		counter := 1
+		// Songs are numbered by their position in the queue, the one remove, move and eta take
+		positions := make(map[*player.Song]int)
+		for i, song := range d.Player.GetSongQueue() {
+			positions[song] = i + 1
+		}
 
 		for i, song := range playlist {
 			// Skip the first song if it's already playing
@@ -249,6 +386,12 @@ func showStatusMessage(d *Discord, s *discordgo.Session, channelID, prevMessageI
 				continue
 			}
 
+			// Songs that already started or failed to queue have no position
+			position, queued := positions[song]
+			if !queued {
+				continue
+			}
+
 			// Check if content length exceeds the limit
 			if len(content) > 1800 {
 				content = fmt.Sprintf("%v\n\nList too long to fit..", content)
@@ -265,16 +408,71 @@ func showStatusMessage(d *Discord, s *discordgo.Session, channelID, prevMessageI
 			}
 
 			// Display playlist entry
-			content = fmt.Sprintf("%v\n` %v ` [%v](%v)", content, counter, song.Title, song.UserURL)
-			counter++
+			content = fmt.Sprintf("%v\n` %v ` %v", content, position, songLink(song))
+			if song.Duration > 0 {
+				content = fmt.Sprintf("%v — %v", content, d.getLocale().Duration(song.Duration.Seconds()))
+			}
+		}
+	}
+
+	// Display songs waiting for a retry after a transient failure
+	if retrying := d.Player.GetRetryingSongs(); len(retrying) > 0 {
+		content += "\n\n⏳ Retrying\n"
+		for _, song := range retrying {
+			if len(content) > 1900 {
+				content += "\n…"
+				break
+			}
+			content += fmt.Sprintf("\n` ⏳ ` %v — retrying", songLink(song))
 		}
 	}
 
 	embedMsg.SetDescription(content)
-	s.ChannelMessageEditEmbed(channelID, prevMessageID, embedMsg.MessageEmbed)
+
+	// Controls are offered while there is something to control
+	components := []discordgo.MessageComponent{}
+	if d.Player.GetCurrentSong() != nil || len(d.Player.GetSongQueue()) > 0 {
+		components = controlComponents()
+	}
+
+	_, err := EditComplex(s, &discordgo.MessageEdit{
+		Channel:    channelID,
+		ID:         prevMessageID,
+		Embeds:     []*discordgo.MessageEmbed{embedMsg.MessageEmbed},
+		Components: components,
+	})
+	if err != nil {
+		slog.Warnf("Error updating status message: %v", err)
+		return
+	}
+
+	d.trackStatusMessage(s, channelID, prevMessageID)
+
+	if currentSong := d.Player.GetCurrentSong(); currentSong != nil {
+		d.offerRating(s, channelID, prevMessageID, currentSong)
+	}
+}
+
+// songLink formats a song as a markdown link, or just its title if it has no URL like generated ambience.
+func songLink(song *player.Song) string {
+	if song.UserURL == "" {
+		return song.Title
+	}
+	return fmt.Sprintf("[%v](%v)", song.Title, song.UserURL)
 }
 
 // ParseParameter parses the type and parameters from the input parameter string.
+// cutFreshModifier removes a trailing "fresh" from a play parameter, it plays long-form songs from the start
+// rather than where they were left. A lone "fresh" is a title.
+func cutFreshModifier(param string) (string, bool) {
+	words := strings.Fields(param)
+	if len(words) < 2 || !strings.EqualFold(words[len(words)-1], "fresh") {
+		return param, false
+	}
+	rest := strings.TrimSpace(param)
+	return strings.TrimSpace(rest[:len(rest)-len(words[len(words)-1])]), true
+}
+
 func parseParameter(param string) (string, []string) {
 	// Trim spaces at the beginning and end
 	param = strings.TrimSpace(param)
@@ -283,9 +481,27 @@ func parseParameter(param string) (string, []string) {
 		return "", []string{}
 	}
 
+	// Check if the parameter selects tracks by tag
+	if tag, shuffle, ok := parseTagParameter(param); ok {
+		if shuffle {
+			return "history_tag_shuffled", []string{tag}
+		}
+		return "history_tag", []string{tag}
+	}
+
+	// Check if the parameter selects a saved playlist
+	if name, ok := strings.CutPrefix(param, playlistPrefix); ok && name != "" && !strings.ContainsAny(name, " ,") {
+		return "playlist", []string{name}
+	}
+
+	// Check if the parameter selects an ambience loop
+	if name, ok := strings.CutPrefix(strings.ToLower(param), ambiencePrefix); ok && name != "" {
+		return "ambience", []string{name}
+	}
+
 	// Check if the parameter is a URL
 	u, err := url.Parse(param)
-	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "spotify") {
 		// If it's a URL, split by ",", " ", new line, or carriage return
 		paramSlice := strings.FieldsFunc(param, func(r rune) bool {
 			return r == '\n' || r == '\r' || r == ' ' || r == '\t'
@@ -293,6 +509,10 @@ func parseParameter(param string) (string, []string) {
 
 		if isYouTubeURL(u.Host) {
 			return "youtube_url", paramSlice
+		} else if sources.IsSpotifyURL(paramSlice[0]) {
+			return "spotify_url", paramSlice
+		} else if sources.LinkSourceFor(paramSlice[0]) != nil {
+			return "source_url", paramSlice
 		} else {
 			return "stream_url", paramSlice
 		}
@@ -317,7 +537,21 @@ func parseParameter(param string) (string, []string) {
 	return "youtube_title", []string{encodedTitle}
 }
 
+// newSpotify returns the Spotify source of the configured client credentials.
+func newSpotify() *sources.Spotify {
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Errorf("Error loading config: %v", err)
+		return sources.NewSpotify("", "")
+	}
+	return sources.NewSpotify(config.SpotifyClientID, config.SpotifyClientSecret)
+}
+
 // isYouTubeURL checks if the host is a YouTube URL.
 func isYouTubeURL(host string) bool {
-	return host == "www.youtube.com" || host == "youtube.com" || host == "youtu.be"
+	switch host {
+	case "www.youtube.com", "youtube.com", "m.youtube.com", "music.youtube.com", "youtu.be":
+		return true
+	}
+	return false
 }
@@ -0,0 +1,324 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"gorm.io/gorm"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/player"
+	"github.com/keshon/melodix-discord-player/music/sources"
+)
+
+const (
+	// playlistPrefix selects a saved playlist in play commands, e.g. "playlist:queue-20240101-2130"
+	playlistPrefix = "playlist:"
+
+	// saveQueuePrefix starts the custom IDs of save queue buttons, the snapshot ID follows
+	saveQueuePrefix = "save_queue:"
+
+	// queueSnapshotTTL is how long the save button of a stopped queue works
+	queueSnapshotTTL = 15 * time.Minute
+)
+
+// queueSnapshot is the queue left over when playback stopped, kept until it's saved or expires.
+type queueSnapshot struct {
+	songs     []*player.Song
+	expiresAt time.Time
+}
+
+// queueSnapshots holds the snapshots offered for saving by ID.
+type queueSnapshots struct {
+	sync.Mutex
+	snapshots map[string]queueSnapshot
+}
+
+func newQueueSnapshots() *queueSnapshots {
+	return &queueSnapshots{snapshots: make(map[string]queueSnapshot)}
+}
+
+// add keeps the songs and returns the ID of their snapshot, expired snapshots are dropped.
+func (qs *queueSnapshots) add(songs []*player.Song) (string, error) {
+	qs.Lock()
+	defer qs.Unlock()
+
+	now := time.Now()
+	for id, snapshot := range qs.snapshots {
+		if now.After(snapshot.expiresAt) {
+			delete(qs.snapshots, id)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	qs.snapshots[id] = queueSnapshot{songs: songs, expiresAt: now.Add(queueSnapshotTTL)}
+	return id, nil
+}
+
+// take removes a snapshot and returns its songs, false if it doesn't exist or expired.
+func (qs *queueSnapshots) take(id string) ([]*player.Song, bool) {
+	qs.Lock()
+	defer qs.Unlock()
+
+	snapshot, ok := qs.snapshots[id]
+	delete(qs.snapshots, id)
+	if !ok || time.Now().After(snapshot.expiresAt) {
+		return nil, false
+	}
+	return snapshot.songs, true
+}
+
+// offerQueueSnapshot posts a button that saves the songs as a playlist, used before a stop clears them.
+func (d *Discord) offerQueueSnapshot(s *discordgo.Session, channelID string, songs []*player.Song) {
+	if len(songs) == 0 {
+		return
+	}
+
+	id, err := d.queueSnapshots.add(songs)
+	if err != nil {
+		slog.Errorf("Error keeping the queue: %v", err)
+		return
+	}
+
+	_, err = SendComplex(s, channelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("The queue had %v tracks left, save them as a playlist to play them later?", len(songs)),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Save as playlist",
+					Style:    discordgo.SuccessButton,
+					CustomID: saveQueuePrefix + id,
+					Emoji:    discordgo.ComponentEmoji{Name: "💾"},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		slog.Warnf("Error offering to save the queue: %v", err)
+	}
+}
+
+// onInteractionCreate saves the queue snapshot of a clicked save button.
+func (d *Discord) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID != d.GuildID || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	id, ok := strings.CutPrefix(i.MessageComponentData().CustomID, saveQueuePrefix)
+	if !ok {
+		return
+	}
+
+	var userID string
+	if i.Member != nil && i.Member.User != nil {
+		userID = i.Member.User.ID
+	}
+
+	content := "This queue can't be saved anymore, the button expires after 15 minutes"
+	if songs, ok := d.queueSnapshots.take(id); ok {
+		name, err := d.savePlaylist(songs, userID)
+		if err != nil {
+			slog.Errorf("Error saving queue as playlist: %v", err)
+			content = "Error saving the queue"
+		} else {
+			content = fmt.Sprintf("💾 Saved %v tracks as `%v`, play them with `%vplay %v%v`", len(songs), name, d.prefix, playlistPrefix, name)
+		}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		slog.Warnf("Error answering save queue button: %v", err)
+	}
+}
+
+// savePlaylist saves songs as a playlist named after the current time and returns its name.
+func (d *Discord) savePlaylist(songs []*player.Song, userID string) (string, error) {
+	base := "queue-" + time.Now().In(d.getLocale().Location).Format("20060102-1504")
+	name := base
+	for n := 2; ; n++ {
+		_, err := db.GetPlaylistByName(d.GuildID, name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%v-%v", base, n)
+	}
+
+	tracks := make([]db.PlaylistTrack, 0, len(songs))
+	for _, song := range songs {
+		tracks = append(tracks, db.PlaylistTrack{
+			Title:  song.Title,
+			URL:    song.UserURL,
+			SongID: song.ID,
+			Source: song.Source.String(),
+		})
+	}
+
+	playlist := &db.Playlist{GuildID: d.GuildID, Name: name, CreatedBy: userID}
+	if err := db.CreatePlaylist(playlist, tracks); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// handlePlaylistCommand lists the saved playlists, shows the tracks of one or removes it.
+func (d *Discord) handlePlaylistCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(param)
+	switch {
+	case len(words) == 0:
+		d.listPlaylists(s, m)
+	case len(words) == 1:
+		d.showPlaylist(s, m, words[0])
+	case len(words) == 2 && words[0] == "remove":
+		d.removePlaylist(s, m, words[1])
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vplaylist`, `%vplaylist [name]`, `%vplaylist remove [name]`. Play one with `%vplay %v[name]`",
+			d.prefix, d.prefix, d.prefix, d.prefix, playlistPrefix))
+	}
+}
+
+// listPlaylists shows the saved playlists of the guild.
+func (d *Discord) listPlaylists(s *discordgo.Session, m *discordgo.MessageCreate) {
+	playlists, err := db.GetPlaylistsByGuildID(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting playlists: %v", err)
+		d.sendTextEmbed(s, m, "Error getting playlists")
+		return
+	}
+
+	if len(playlists) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("💾 No playlists yet, stopping with `%vexit` offers to save the queue as one", d.prefix))
+		return
+	}
+
+	content := "💾 Playlists\n"
+	for _, playlist := range playlists {
+		content += fmt.Sprintf("\n`%v` — %d tracks, saved %v", playlist.Name, playlist.TrackCount, relativeTimestamp(playlist.CreatedAt))
+		if len(content) > 1800 {
+			content += "\n…"
+			break
+		}
+	}
+	content += fmt.Sprintf("\n\nPlay one with `%vplay %v%v`", d.prefix, playlistPrefix, playlists[0].Name)
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// showPlaylist shows the tracks of a saved playlist.
+func (d *Discord) showPlaylist(s *discordgo.Session, m *discordgo.MessageCreate, name string) {
+	playlist, tracks, err := getPlaylist(d.GuildID, name)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No playlist named `%v`", name))
+		return
+	}
+
+	content := fmt.Sprintf("💾 `%v` — %d tracks\n", playlist.Name, len(tracks))
+	for i, track := range tracks {
+		if len(content) > 1800 {
+			content += "\n…"
+			break
+		}
+		content += fmt.Sprintf("\n` %v ` %v", i+1, trackLink(track))
+	}
+	content += fmt.Sprintf("\n\nPlay it with `%vplay %v%v`", d.prefix, playlistPrefix, playlist.Name)
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// removePlaylist deletes a saved playlist, only administrators and whoever saved it may.
+func (d *Discord) removePlaylist(s *discordgo.Session, m *discordgo.MessageCreate, name string) {
+	playlist, err := db.GetPlaylistByName(d.GuildID, name)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No playlist named `%v`", name))
+		return
+	}
+
+	if playlist.CreatedBy != m.Author.ID && !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators and whoever saved a playlist can remove it")
+		return
+	}
+
+	if _, err := db.DeletePlaylist(d.GuildID, name); err != nil {
+		slog.Errorf("Error removing playlist %v: %v", name, err)
+		d.sendTextEmbed(s, m, "Error removing the playlist")
+		return
+	}
+
+	d.sendConfirmation(s, m, "🗑️", fmt.Sprintf("Removed playlist `%v`", name))
+}
+
+func getPlaylist(guildID, name string) (*db.Playlist, []db.PlaylistTrack, error) {
+	playlist, err := db.GetPlaylistByName(guildID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracks, err := db.GetPlaylistTracks(playlist.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return playlist, tracks, nil
+}
+
+func trackLink(track db.PlaylistTrack) string {
+	if track.URL == "" {
+		return track.Title
+	}
+	return fmt.Sprintf("[%v](%v)", track.Title, track.URL)
+}
+
+// fetchPlaylistSongs queues the tracks of a saved playlist. YouTube tracks and those of other link sources
+// are queued as lightweight songs, streams and ambience are looked up again.
+func fetchPlaylistSongs(ctx context.Context, guildID, name string, youtube *sources.Youtube, stream *sources.Stream) ([]*player.Song, error) {
+	_, tracks, err := getPlaylist(guildID, name)
+	if err != nil {
+		return nil, fmt.Errorf("no playlist named %v", name)
+	}
+
+	var songs []*player.Song
+	for _, track := range tracks {
+		trackSongs, err := fetchTrackSongs(ctx, track.Title, track.URL, track.SongID, track.Source, youtube, stream)
+		if err != nil {
+			slog.Warnf("Skipping %q of playlist %v: %v", track.Title, name, err)
+			continue
+		}
+		songs = append(songs, trackSongs...)
+	}
+
+	return songs, nil
+}
+
+// fetchTrackSongs creates the songs of a stored track. YouTube tracks and those of other link sources
+// are lightweight songs, streams and ambience are looked up again.
+func fetchTrackSongs(ctx context.Context, title, link, id, source string, youtube *sources.Youtube, stream *sources.Stream) ([]*player.Song, error) {
+	switch source {
+	case player.SourceYouTube.String():
+		return []*player.Song{youtube.LightweightSong(title, link, id)}, nil
+	case player.SourceAmbience.String():
+		return fetchAmbience(sources.AmbienceName(id))
+	}
+
+	if linkSource := sources.LinkSourceFor(link); linkSource != nil {
+		return []*player.Song{linkSource.ReferenceSong(title, link, id)}, nil
+	}
+	return stream.FetchStreamsByURLs(ctx, []string{link})
+}
@@ -0,0 +1,63 @@
+package discord
+
+import (
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/config"
+)
+
+// voicePrejoin is a voice connection made while a play request is still being looked up,
+// so the voice handshake and the greeting don't add to the wait for the first audio.
+type voicePrejoin struct {
+	channelID string
+	joined    bool
+	done      chan struct{}
+}
+
+// prejoinVoice starts joining the voice channel if the guild isn't connected and VOICE_PREJOIN is on,
+// it returns nil otherwise. The returned prejoin must be waited for or reverted.
+func (d *Discord) prejoinVoice(channelID string) *voicePrejoin {
+	config, err := config.NewConfig()
+	if err != nil || !config.VoicePrejoin || d.Player.GetVoiceConnection() != nil {
+		return nil
+	}
+
+	p := &voicePrejoin{channelID: channelID, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		if err := d.connectVoice(channelID); err != nil {
+			// Playing connects again and reports the error
+			slog.Warnf("Error joining voice channel ahead of the request: %v", err)
+			return
+		}
+		p.joined = true
+	}()
+
+	return p
+}
+
+// wait blocks until the join finished, it's nil safe.
+func (p *voicePrejoin) wait() {
+	if p == nil {
+		return
+	}
+	<-p.done
+}
+
+// revertPrejoin leaves the voice channel joined ahead of a request that found nothing to play,
+// unless something started playing there meanwhile. It's nil safe.
+func (d *Discord) revertPrejoin(p *voicePrejoin) {
+	if p == nil {
+		return
+	}
+	p.wait()
+	if !p.joined {
+		return
+	}
+
+	vc := d.Player.GetVoiceConnection()
+	if vc == nil || vc.ChannelID != p.channelID || d.Player.GetCurrentSong() != nil || len(d.Player.GetSongQueue()) > 0 {
+		return
+	}
+	d.LeaveVoiceChannel()
+}
@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+)
+
+const (
+	// fetchProgressDelay is how long resolving a request may take before the wait message shows progress
+	fetchProgressDelay = 2 * time.Second
+	// fetchProgressInterval spaces out edits of the wait message, Discord rate limits them per channel
+	fetchProgressInterval = 1500 * time.Millisecond
+)
+
+// fetchProgress reports how many parts of a request are resolved by editing its wait message,
+// so a slow request doesn't look ignored. It's nil safe, a nil progress reports nothing.
+type fetchProgress struct {
+	mu       sync.Mutex
+	resolved int
+	total    int
+	songs    int
+	videos   int // videos of the playlists found
+	queued   int // videos of the playlists queued, fewer than videos if they were cut off at the limit
+	stopped  chan struct{}
+	finished chan struct{}
+}
+
+// startFetchProgress starts reporting progress of resolving total parts of a request in the message.
+func startFetchProgress(s *discordgo.Session, channelID, messageID string, total int) *fetchProgress {
+	p := &fetchProgress{
+		total:    total,
+		stopped:  make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go p.report(s, channelID, messageID)
+
+	return p
+}
+
+// advance records a resolved part of the request and the songs it yielded.
+func (p *fetchProgress) advance(songs int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.resolved++
+	p.songs += songs
+	p.mu.Unlock()
+}
+
+// stop ends reporting and waits for a pending edit, so it can't overwrite the message edited next.
+// foundPlaylist records a playlist of the request, with the number of its videos and of those queued.
+func (p *fetchProgress) foundPlaylist(videos, queued int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.videos += videos
+	p.queued += queued
+	p.mu.Unlock()
+}
+
+func (p *fetchProgress) stop() {
+	if p == nil {
+		return
+	}
+
+	close(p.stopped)
+	<-p.finished
+}
+
+func (p *fetchProgress) report(s *discordgo.Session, channelID, messageID string) {
+	defer close(p.finished)
+
+	select {
+	case <-p.stopped:
+		return
+	case <-time.After(fetchProgressDelay):
+	}
+
+	ticker := time.NewTicker(fetchProgressInterval)
+	defer ticker.Stop()
+
+	shown := ""
+	for {
+		if text := p.describe(); text != shown {
+			embedMsg := embed.NewEmbed().
+				SetColor(0x9f00d4).
+				SetDescription(text).MessageEmbed
+
+			if _, err := EditEmbed(s, channelID, messageID, embedMsg); err != nil {
+				slog.Warnf("Error updating fetch progress: %v", err)
+				return
+			}
+			shown = text
+		}
+
+		select {
+		case <-p.stopped:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *fetchProgress) describe() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	text := "⏳ Fetching…"
+	if p.total > 1 {
+		text += fmt.Sprintf(" `%d/%d` resolved", p.resolved, p.total)
+	}
+	if p.songs > 0 {
+		text += fmt.Sprintf(", %d songs found so far", p.songs)
+	}
+	if p.videos > 0 {
+		text += fmt.Sprintf("\n📃 Playlist of %d videos", p.videos)
+		if p.queued < p.videos {
+			text += fmt.Sprintf(", queuing the first %d", p.queued)
+		}
+	}
+	return text
+}
@@ -0,0 +1,162 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// handleQualityCommand shows the encode settings of the current song, or switches the quality preset.
+func (d *Discord) handleQualityCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	param = strings.ToLower(strings.TrimSpace(param))
+	available := "`" + strings.Join(player.QualityNames, "`, `") + "`"
+
+	if param == "" {
+		d.showEncodeInfo(s, m)
+		return
+	}
+
+	if !d.isDJ(s, m) {
+		d.sendTextEmbed(s, m, "Only DJs and server administrators can change the quality")
+		return
+	}
+
+	if err := d.Player.SetQuality(param); err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown quality `%v`, available: %v", param, available))
+		return
+	}
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.Quality = param
+		if param == player.QualityNormal {
+			settings.Quality = ""
+		}
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving quality: %v", err)
+	}
+
+	d.sendConfirmation(s, m, "🎚️", fmt.Sprintf("Quality set to `%v`", param))
+}
+
+// showEncodeInfo shows the encode settings and the measured output bitrate of the current song.
+func (d *Discord) showEncodeInfo(s *discordgo.Session, m *discordgo.MessageCreate) {
+	info, encoding := d.Player.GetEncodeInfo()
+	if !encoding {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🎚️ Quality is `%v`, nothing is encoding right now\nUse `%vquality [%v]` to change it",
+			d.Player.GetQuality(), d.prefix, strings.Join(player.QualityNames, "/")))
+		return
+	}
+
+	vbr := "off"
+	if info.VBR {
+		vbr = "on"
+	}
+
+	measured := "measuring…"
+	if info.MeasuredBitrate > 0 {
+		measured = fmt.Sprintf("%.1f kb/s", info.MeasuredBitrate)
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetTitle("🎚️ Encode quality").
+		SetDescription(fmt.Sprintf("Preset `%v`, use `%vquality [%v]` to change it", info.Quality, d.prefix, strings.Join(player.QualityNames, "/"))).
+		AddField("Bitrate", fmt.Sprintf("%v kb/s", info.Bitrate)).
+		AddField("Measured bitrate", measured).
+		AddField("Frame duration", fmt.Sprintf("%v ms", info.FrameDuration)).
+		AddField("VBR", vbr).
+		AddField("Application", info.Application).
+		AddField("Compression level", fmt.Sprint(info.CompressionLevel)).
+		AddField("Filters", "`"+info.Filters+"`").
+		InlineAllFields().
+		SetFooter(version.AppFullName).
+		SetColor(0x9f00d4).MessageEmbed
+
+	SendEmbed(s, m.Message.ChannelID, embedMsg)
+}
+
+// handleEncodeSetting shows or changes the guild's overrides of the global encode config.
+func (d *Discord) handleEncodeSetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	usage := fmt.Sprintf("Usage: `%vsettings encode bitrate [8-128/default]`, `%vsettings encode frameduration [20/40/60/default]`, `%vsettings encode volume [0.05-1.0/default]`, `%vsettings encode reset`",
+		d.prefix, d.prefix, d.prefix, d.prefix)
+
+	overrides := d.Player.GetEncodeOverrides()
+	if value == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🎛️ Encode settings of this server\n\n%v\n\n%v", describeEncodeOverrides(overrides), usage))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	name, arg, _ := strings.Cut(strings.ToLower(value), " ")
+	arg = strings.TrimSpace(arg)
+	if arg == "default" {
+		arg = "0"
+	}
+
+	var err error
+	switch {
+	case name == "reset" && arg == "":
+		overrides = player.EncodeOverrides{}
+	case name == "bitrate":
+		overrides.Bitrate, err = strconv.Atoi(arg)
+	case name == "frameduration":
+		overrides.FrameDuration, err = strconv.Atoi(arg)
+	case name == "volume":
+		var ceiling float64
+		ceiling, err = strconv.ParseFloat(arg, 32)
+		overrides.VolumeCeiling = float32(ceiling)
+	default:
+		d.sendTextEmbed(s, m, usage)
+		return
+	}
+	if err != nil {
+		d.sendTextEmbed(s, m, usage)
+		return
+	}
+
+	if err := d.Player.SetEncodeOverrides(overrides); err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Can't change the encode settings: %v", err))
+		return
+	}
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.EncodeBitrate = overrides.Bitrate
+		settings.EncodeFrameDuration = overrides.FrameDuration
+		settings.VolumeCeiling = overrides.VolumeCeiling
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving encode settings: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎛️ Encode settings of this server changed, they apply from the next track\n\n%v", describeEncodeOverrides(overrides)))
+}
+
+// describeEncodeOverrides lists the overrides of a guild, with the global config for those not set.
+func describeEncodeOverrides(overrides player.EncodeOverrides) string {
+	describe := func(set bool, value string) string {
+		if !set {
+			return "global default"
+		}
+		return "`" + value + "`"
+	}
+
+	return fmt.Sprintf("Bitrate: %v\nFrame duration: %v\nVolume ceiling: %v",
+		describe(overrides.Bitrate != 0, fmt.Sprintf("%v kb/s", overrides.Bitrate)),
+		describe(overrides.FrameDuration != 0, fmt.Sprintf("%v ms", overrides.FrameDuration)),
+		describe(overrides.VolumeCeiling != 0, fmt.Sprintf("%.2f", overrides.VolumeCeiling)))
+}
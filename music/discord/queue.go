@@ -1,9 +1,15 @@
 package discord
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/player"
 )
 
 // handleShowQueueCommand handles the show queue command for Discord.
@@ -19,7 +25,7 @@ func (d *Discord) handleShowQueueCommand(s *discordgo.Session, m *discordgo.Mess
 		SetDescription(embedStr).
 		SetColor(0x9f00d4).MessageEmbed
 
-	pleaseWaitMessage, err := s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	pleaseWaitMessage, err := SendEmbed(s, m.Message.ChannelID, embedMsg)
 	if err != nil {
 		slog.Warnf("Error sending 'please wait' message: %v", err)
 	}
@@ -27,3 +33,84 @@ func (d *Discord) handleShowQueueCommand(s *discordgo.Session, m *discordgo.Mess
 	showStatusMessage(d, s, m.Message.ChannelID, pleaseWaitMessage.ID, playlist, 0, false)
 
 }
+
+// handleRemoveCommand removes a track from the queue by its position in the list.
+func (d *Discord) handleRemoveCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	position, err := strconv.Atoi(strings.TrimSpace(param))
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vremove [position]`, positions are shown by `%vlist`", d.prefix, d.prefix))
+		return
+	}
+
+	song, err := d.Player.RemoveFromQueue(position)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No track at position `%v` of the queue", position))
+		return
+	}
+
+	d.sendConfirmation(s, m, "🗑️", fmt.Sprintf("Removed %v from the queue. Use `%vundo` to bring it back.", songLink(song), d.prefix))
+}
+
+// handleMoveCommand moves a track of the queue to another position.
+func (d *Discord) handleMoveCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(param)
+	var from, to int
+	var err error
+	if len(words) == 2 {
+		if from, err = strconv.Atoi(words[0]); err == nil {
+			to, err = strconv.Atoi(words[1])
+		}
+	}
+	if len(words) != 2 || err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vmove [from] [to]`, positions are shown by `%vlist`", d.prefix, d.prefix))
+		return
+	}
+
+	if err := d.Player.MoveInQueue(from, to); err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("The queue has %v tracks, positions start at 1", len(d.Player.GetSongQueue())))
+		return
+	}
+
+	content := fmt.Sprintf("Moved track `%v` to position `%v`.", from, to)
+	if strategy := d.Player.GetQueueStrategy().Name(); strategy != player.StrategyFIFO {
+		content += fmt.Sprintf(" The `%v` queue order may still place it elsewhere.", strategy)
+	}
+	d.sendConfirmation(s, m, "↕️", content)
+}
+
+// handleClearCommand removes every track from the queue, the current track keeps playing.
+func (d *Discord) handleClearCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	count := len(d.Player.GetSongQueue())
+	if count == 0 {
+		d.sendTextEmbed(s, m, "The queue is already empty")
+		return
+	}
+
+	d.Player.ClearQueue()
+
+	d.sendConfirmation(s, m, "🧹", fmt.Sprintf("Removed %v tracks from the queue. Use `%vundo` to bring them back.", count, d.prefix))
+}
+
+// handleUndoCommand reverts the latest clear, remove, move or shuffle of the queue.
+func (d *Discord) handleUndoCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	action, err := d.Player.UndoQueue()
+	if errors.Is(err, player.ErrNothingToUndo) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Nothing to undo, only changes of the queue from the last %v minutes can be undone", int(player.UndoWindow.Minutes())))
+		return
+	}
+	if err != nil {
+		slog.Errorf("Error undoing queue change: %v", err)
+		d.sendTextEmbed(s, m, "Error undoing the queue change")
+		return
+	}
+
+	d.sendConfirmation(s, m, "↩️", fmt.Sprintf("Undid `%v`, the queue has %v tracks.", action, len(d.Player.GetSongQueue())))
+}
@@ -0,0 +1,160 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	likeEmoji    = "👍"
+	dislikeEmoji = "👎"
+
+	// ratedMessagesKept is the number of recent now playing messages whose reactions count as ratings
+	ratedMessagesKept = 50
+)
+
+// ratedMessages remembers which song each recent now playing message showed,
+// so reactions on it rate that song even after playback moved on.
+type ratedMessages struct {
+	sync.Mutex
+	songs map[string]string // song ID by message ID
+	order []string          // message IDs, oldest first
+}
+
+func newRatedMessages() *ratedMessages {
+	return &ratedMessages{songs: make(map[string]string)}
+}
+
+func (rm *ratedMessages) add(messageID, songID string) {
+	rm.Lock()
+	defer rm.Unlock()
+
+	if _, exists := rm.songs[messageID]; !exists {
+		rm.order = append(rm.order, messageID)
+	}
+	rm.songs[messageID] = songID
+
+	if len(rm.order) > ratedMessagesKept {
+		delete(rm.songs, rm.order[0])
+		rm.order = rm.order[1:]
+	}
+}
+
+func (rm *ratedMessages) song(messageID string) (string, bool) {
+	rm.Lock()
+	defer rm.Unlock()
+
+	songID, ok := rm.songs[messageID]
+	return songID, ok
+}
+
+// isRatable reports whether ratings of the song are kept: it has an ID to keep them by and isn't an endless
+// stream like a radio, whose ratings would all land on the same stream whatever it plays.
+func isRatable(song *player.Song) bool {
+	return song.ID != "" && !song.Source.Endless()
+}
+
+// offerRating adds like and dislike reactions to a now playing message of the song.
+func (d *Discord) offerRating(s *discordgo.Session, channelID, messageID string, song *player.Song) {
+	if !isRatable(song) {
+		return
+	}
+
+	d.ratedMessages.add(messageID, song.ID)
+
+	for _, emoji := range []string{likeEmoji, dislikeEmoji} {
+		if err := s.MessageReactionAdd(channelID, messageID, emoji); err != nil {
+			slog.Warnf("Error adding rating reaction: %v", err)
+			return
+		}
+	}
+}
+
+// ratingOfEmoji returns the rating value of a reaction, zero for other emojis.
+func ratingOfEmoji(emoji string) int {
+	switch emoji {
+	case likeEmoji:
+		return 1
+	case dislikeEmoji:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// onMessageReactionAdd rates the song of a now playing message.
+func (d *Discord) onMessageReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.GuildID != d.GuildID || r.UserID == s.State.User.ID {
+		return
+	}
+
+	value := ratingOfEmoji(r.Emoji.Name)
+	songID, ok := d.ratedMessages.song(r.MessageID)
+	if value == 0 || !ok {
+		return
+	}
+
+	if err := history.NewHistory().RateTrack(d.GuildID, songID, r.UserID, value); err != nil {
+		slog.Warnf("Error rating track %v: %v", songID, err)
+	}
+}
+
+// onMessageReactionRemove withdraws a rating when its reaction is removed.
+func (d *Discord) onMessageReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	if r.GuildID != d.GuildID || r.UserID == s.State.User.ID {
+		return
+	}
+
+	value := ratingOfEmoji(r.Emoji.Name)
+	songID, ok := d.ratedMessages.song(r.MessageID)
+	if value == 0 || !ok {
+		return
+	}
+
+	if err := history.NewHistory().UnrateTrack(d.GuildID, songID, r.UserID, value); err != nil {
+		slog.Warnf("Error withdrawing rating of track %v: %v", songID, err)
+	}
+}
+
+// handleRateCommand rates the current song for the author.
+func (d *Discord) handleRateCommand(s *discordgo.Session, m *discordgo.MessageCreate, value int) {
+	song := d.Player.GetCurrentSong()
+	if song == nil || song.ID == "" {
+		d.sendTextEmbed(s, m, "Nothing is playing to rate")
+		return
+	}
+	if !isRatable(song) {
+		d.sendTextEmbed(s, m, "Streams can't be rated")
+		return
+	}
+
+	if err := history.NewHistory().RateTrack(d.GuildID, song.ID, m.Author.ID, value); err != nil {
+		slog.Errorf("Error rating track %v: %v", song.ID, err)
+		d.sendTextEmbed(s, m, "Error saving your rating")
+		return
+	}
+
+	emoji, verb := likeEmoji, "Liked"
+	if value < 0 {
+		emoji, verb = dislikeEmoji, "Disliked"
+	}
+	d.sendConfirmation(s, m, emoji, fmt.Sprintf("%v %v", verb, songLink(song)))
+}
+
+// describeRating formats the likes and dislikes of a track for history, omitting zero counts.
+func describeRating(likes, dislikes int64) string {
+	var parts []string
+	if likes > 0 {
+		parts = append(parts, fmt.Sprintf("%v %d", likeEmoji, likes))
+	}
+	if dislikes > 0 {
+		parts = append(parts, fmt.Sprintf("%v %d", dislikeEmoji, dislikes))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,306 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+)
+
+const (
+	// highVoiceRTT is the round trip time to the voice server above which a closer region is suggested
+	highVoiceRTT = 150 * time.Millisecond
+	probeCount   = 3
+	probeTimeout = 3 * time.Second
+
+	// rtcLatencyURL lists voice regions ordered by distance to the caller, the Discord client picks regions with it.
+	// It's undocumented, so it's only asked with VOICE_REGION_SUGGESTIONS.
+	rtcLatencyURL = "https://latency.discord.media/rtc"
+)
+
+var errNoVoiceChannel = errors.New("no voice channel")
+
+// voiceProbe is the result of the latest latency probe of a guild's voice server.
+type voiceProbe struct {
+	Endpoint        string
+	RTT             time.Duration
+	SuggestedRegion string // region closest to the bot, set when RTT is high
+}
+
+// voiceProbeState holds the latest probe of the guild, written by voice server update events.
+type voiceProbeState struct {
+	sync.Mutex
+	probe *voiceProbe // nil until the voice server was probed
+}
+
+// latestVoiceProbe returns the latest probe of the guild's voice server, nil if there is none.
+func (d *Discord) latestVoiceProbe() *voiceProbe {
+	d.voiceProbe.Lock()
+	defer d.voiceProbe.Unlock()
+	return d.voiceProbe.probe
+}
+
+// setVoiceProbe replaces the latest probe, nil forgets it.
+func (d *Discord) setVoiceProbe(probe *voiceProbe) {
+	d.voiceProbe.Lock()
+	defer d.voiceProbe.Unlock()
+	d.voiceProbe.probe = probe
+}
+
+// handleSettingsCommand handles the settings command for Discord.
+func (d *Discord) handleSettingsCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	name, value, _ := strings.Cut(param, " ")
+	value = strings.TrimSpace(value)
+
+	switch name {
+	case "region":
+		d.handleRegionSetting(s, m, value)
+	case "suggestions":
+		d.handleSuggestionsSetting(s, m, value)
+	case "ducking":
+		d.handleDuckingSetting(s, m, value)
+	case "encode":
+		d.handleEncodeSetting(s, m, value)
+	case "greeting":
+		d.handleGreetingSetting(s, m, value)
+	case "summary":
+		d.handleSummarySetting(s, m, value)
+	case "topic":
+		d.handleTopicSetting(s, m, value)
+	case "updates":
+		d.handleUpdatesSetting(s, m, value)
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings region [region/auto]`, `%vsettings suggestions [on/off]`, `%vsettings ducking [on/off]`, `%vsettings encode [bitrate/frameduration/volume] [value/default]`, `%vsettings greeting [clip/message/off] [url/jingle/text]`, `%vsettings summary [on/off]`, `%vsettings topic [here/off]`, `%vsettings updates [here/on/off]`",
+			d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix))
+	}
+}
+
+// handleRegionSetting shows or overrides the RTC region of the voice channel the bot or the author is in.
+func (d *Discord) handleRegionSetting(s *discordgo.Session, m *discordgo.MessageCreate, region string) {
+	channelID, err := d.regionChannelID(s, m)
+	if err != nil {
+		d.sendTextEmbed(s, m, "Join a voice channel first, the region is set per voice channel")
+		return
+	}
+
+	if region == "" {
+		current, err := channelRTCRegion(s, channelID)
+		if err != nil {
+			slog.Errorf("Error getting RTC region of channel %v: %v", channelID, err)
+			d.sendTextEmbed(s, m, "Failed to get the region of the voice channel")
+			return
+		}
+		if current == "" {
+			current = "auto"
+		}
+
+		text := fmt.Sprintf("🌍 Voice region of <#%v> is `%v`\n%v\nUse `%vsettings region [region/auto]` to change it", channelID, current, d.describeVoiceProbe(), d.prefix)
+		d.sendTextEmbed(s, m, text)
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change the voice region")
+		return
+	}
+
+	if region != "auto" {
+		regions, err := s.VoiceRegions()
+		if err != nil {
+			slog.Errorf("Error getting voice regions: %v", err)
+			d.sendTextEmbed(s, m, "Failed to get voice regions from Discord")
+			return
+		}
+		if !hasVoiceRegion(regions, region) {
+			var ids []string
+			for _, r := range regions {
+				ids = append(ids, "`"+r.ID+"`")
+			}
+			d.sendTextEmbed(s, m, fmt.Sprintf("Unknown region `%v`, available are `auto`, %v", region, strings.Join(ids, ", ")))
+			return
+		}
+	}
+
+	if err := setChannelRTCRegion(s, channelID, region); err != nil {
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusForbidden {
+			d.sendTextEmbed(s, m, "I need the Manage Channels permission to change the voice region")
+			return
+		}
+		slog.Errorf("Error setting RTC region of channel %v: %v", channelID, err)
+		d.sendTextEmbed(s, m, "Failed to change the voice region")
+		return
+	}
+
+	// Discord moves the call to a new voice server, the probe runs again on its voice server update
+	d.setVoiceProbe(nil)
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🌍 Voice region of <#%v> set to `%v`", channelID, region))
+}
+
+// regionChannelID returns the voice channel of the bot, or the author's if the bot isn't connected.
+func (d *Discord) regionChannelID(s *discordgo.Session, m *discordgo.MessageCreate) (string, error) {
+	if vc := d.Player.GetVoiceConnection(); vc != nil && vc.ChannelID != "" {
+		return vc.ChannelID, nil
+	}
+
+	guild, err := s.State.Guild(d.GuildID)
+	if err != nil {
+		return "", err
+	}
+
+	vs, found := findUserVoiceState(m.Author.ID, guild.VoiceStates)
+	if !found {
+		return "", errNoVoiceChannel
+	}
+	return vs.ChannelID, nil
+}
+
+// onVoiceServerUpdate probes the latency of the voice server the guild was assigned to.
+func (d *Discord) onVoiceServerUpdate(s *discordgo.Session, e *discordgo.VoiceServerUpdate) {
+	if e.GuildID != d.GuildID || e.Endpoint == "" {
+		return
+	}
+
+	go func() {
+		probe, err := probeVoiceServer(e.Endpoint, d.regionSuggestions)
+		if err != nil {
+			slog.Warnf("Error probing voice server %v: %v", e.Endpoint, err)
+			return
+		}
+		d.setVoiceProbe(probe)
+
+		if probe.SuggestedRegion != "" {
+			slog.Warnf("Voice server %v of guild %v is %v away, region %v may be closer (%vsettings region %v)",
+				probe.Endpoint, d.GuildID, probe.RTT.Round(time.Millisecond), probe.SuggestedRegion, d.prefix, probe.SuggestedRegion)
+		}
+	}()
+}
+
+// describeVoiceProbe describes the latest latency probe for the region setting.
+func (d *Discord) describeVoiceProbe() string {
+	probe := d.latestVoiceProbe()
+	if probe == nil {
+		return "_Latency is measured once the bot joins a voice channel_"
+	}
+
+	text := fmt.Sprintf("Voice server `%v` answers in %v", probe.Endpoint, probe.RTT.Round(time.Millisecond))
+	if probe.SuggestedRegion != "" {
+		text += fmt.Sprintf("\n⚠️ That's slow, region `%v` is closest to the bot and may be better", probe.SuggestedRegion)
+	}
+	return text
+}
+
+// probeVoiceServer measures the round trip time to a voice server by timing TCP handshakes,
+// and with suggest, suggests the region nearest to the bot if it's high.
+func probeVoiceServer(endpoint string, suggest bool) (*voiceProbe, error) {
+	host := endpoint
+	if h, _, err := net.SplitHostPort(endpoint); err == nil {
+		host = h
+	}
+
+	var best time.Duration
+	for i := 0; i < probeCount; i++ {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, "443"), probeTimeout)
+		if err != nil {
+			return nil, err
+		}
+		rtt := time.Since(start)
+		conn.Close()
+
+		if best == 0 || rtt < best {
+			best = rtt
+		}
+	}
+
+	probe := &voiceProbe{Endpoint: host, RTT: best}
+	if !suggest || best <= highVoiceRTT {
+		return probe, nil
+	}
+
+	nearest, err := nearestVoiceRegion()
+	if err != nil {
+		slog.Warnf("Error getting nearest voice region: %v", err)
+		return probe, nil
+	}
+	probe.SuggestedRegion = nearest
+
+	return probe, nil
+}
+
+// nearestVoiceRegion returns the voice region closest to the bot's host.
+func nearestVoiceRegion() (string, error) {
+	client := &http.Client{Timeout: probeTimeout}
+	resp, err := client.Get(rtcLatencyURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %v failed with status %v", rtcLatencyURL, resp.Status)
+	}
+
+	var regions []struct {
+		Region string `json:"region"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&regions); err != nil {
+		return "", err
+	}
+	if len(regions) == 0 {
+		return "", errors.New("no voice regions returned")
+	}
+
+	return regions[0].Region, nil
+}
+
+// channelRTCRegion returns the RTC region override of a voice channel, empty for automatic.
+// discordgo's Channel doesn't include the field, so the channel is fetched raw.
+func channelRTCRegion(s *discordgo.Session, channelID string) (string, error) {
+	body, err := s.RequestWithBucketID(http.MethodGet, discordgo.EndpointChannel(channelID), nil, discordgo.EndpointChannel(channelID))
+	if err != nil {
+		return "", err
+	}
+
+	var channel struct {
+		RTCRegion *string `json:"rtc_region"`
+	}
+	if err := json.Unmarshal(body, &channel); err != nil {
+		return "", err
+	}
+
+	if channel.RTCRegion == nil {
+		return "", nil
+	}
+	return *channel.RTCRegion, nil
+}
+
+// setChannelRTCRegion overrides the RTC region of a voice channel, "auto" lets Discord choose.
+func setChannelRTCRegion(s *discordgo.Session, channelID, region string) error {
+	var value interface{}
+	if region != "auto" {
+		value = region
+	}
+
+	_, err := s.RequestWithBucketID(http.MethodPatch, discordgo.EndpointChannel(channelID), map[string]interface{}{"rtc_region": value}, discordgo.EndpointChannel(channelID))
+	return err
+}
+
+func hasVoiceRegion(regions []*discordgo.VoiceRegion, id string) bool {
+	for _, region := range regions {
+		if region.ID == id {
+			return true
+		}
+	}
+	return false
+}
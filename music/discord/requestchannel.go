@@ -0,0 +1,121 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// handleRequestChannelCommand handles the request channel command for Discord.
+func (d *Discord) handleRequestChannelCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	switch param {
+	case "":
+		if d.requestChannelID == "" {
+			d.sendTextEmbed(s, m, fmt.Sprintf("📨 No song request channel is set\nUse `%vrequests here` in the channel that should accept plain song requests", d.prefix))
+		} else {
+			d.sendTextEmbed(s, m, fmt.Sprintf("📨 Song requests are taken in <#%v>\nUse `%vrequests off` to disable", d.requestChannelID, d.prefix))
+		}
+		return
+	case "here", "off":
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vrequests [here/off]`", d.prefix))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change the song request channel")
+		return
+	}
+
+	channelID := ""
+	if param == "here" {
+		if !canManageMessages(s, m.ChannelID) {
+			d.sendTextEmbed(s, m, "⚠️ I need the **Manage Messages** permission in this channel to remove invalid requests")
+			return
+		}
+		channelID = m.ChannelID
+	}
+
+	d.requestChannelID = channelID
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.RequestChannelID = channelID
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving song request channel: %v", err)
+	}
+
+	if channelID == "" {
+		d.sendTextEmbed(s, m, "📨 Song request channel disabled")
+		return
+	}
+	d.sendTextEmbed(s, m, "📨 Every message in this channel is now a song request: a title, YouTube URL, history ID or stream URL.\nCommands still work with the prefix.")
+}
+
+// handleSongRequest treats a plain message in the song request channel as a play or add request.
+// Valid requests are confirmed with a reaction, invalid ones are deleted.
+func (d *Discord) handleSongRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+	requestedAt := time.Now()
+	if d.IsQueueLocked() && !d.isDJ(s, m) {
+		d.rejectSongRequest(s, m, "queue is locked")
+		return
+	}
+
+	paramType, songsList := parseParameter(strings.TrimSpace(m.Content))
+	if len(songsList) == 0 {
+		d.rejectSongRequest(s, m, "empty request")
+		return
+	}
+
+	ctx, cancel := d.startLookup(m.ID)
+	defer cancel()
+	playlist, err := createPlaylist(ctx, paramType, songsList, d, m, nil)
+	if errors.Is(err, context.Canceled) {
+		slog.Infof("Song request %q was called off", m.Content)
+		return
+	}
+	if err != nil || len(playlist) == 0 {
+		d.rejectSongRequest(s, m, "no music found")
+		return
+	}
+
+	enqueueOnly := d.Player.GetCurrentSong() != nil
+	if !enqueueOnly {
+		d.stampRequest(playlist, requestedAt)
+	}
+	if err := playOrEnqueue(d, playlist, s, m, enqueueOnly, ""); err != nil {
+		d.rejectSongRequest(s, m, err.Error())
+		return
+	}
+
+	if err := s.MessageReactionAdd(m.ChannelID, m.ID, "✅"); err != nil {
+		slog.Warnf("Error adding reaction: %v", err)
+	}
+}
+
+// rejectSongRequest deletes an invalid song request, or marks it if the message can't be deleted.
+func (d *Discord) rejectSongRequest(s *discordgo.Session, m *discordgo.MessageCreate, reason string) {
+	slog.Infof("Rejected song request %q: %v", m.Content, reason)
+
+	if canManageMessages(s, m.ChannelID) {
+		err := s.ChannelMessageDelete(m.ChannelID, m.ID)
+		if err == nil {
+			return
+		}
+		slog.Warnf("Error deleting song request: %v", err)
+	}
+
+	if err := s.MessageReactionAdd(m.ChannelID, m.ID, "❌"); err != nil {
+		slog.Warnf("Error adding reaction: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"gorm.io/gorm"
+
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/player"
+	"github.com/keshon/melodix-discord-player/music/sources"
+)
+
+const (
+	// queueSaveInterval is how often the queue is saved, a restart loses at most this much playback
+	queueSaveInterval = 15 * time.Second
+	// autoRestoreDelay gives the gateway time to report the voice channels after the instance started
+	autoRestoreDelay = 10 * time.Second
+	// autoRestoreMaxAge is how old a saved queue may be to come back by itself, older ones wait for the restore command
+	autoRestoreMaxAge = 6 * time.Hour
+)
+
+// savedQueue is what this run saved of the queue.
+type savedQueue struct {
+	sync.Mutex
+	fingerprint string // of the saved tracks, empty if none were saved by this run
+}
+
+// errNothingSaved is returned when restoring a guild that has no saved queue.
+var errNothingSaved = errors.New("no saved queue")
+
+// persistQueue saves the current track, its position and the queue while the instance is active,
+// so they can be restored after a crash or a redeploy. The saved queue is forgotten once playback stops,
+// but not before anything played, the queue saved by the previous run stays until it's restored.
+// It stops on Shutdown, which saves the queue a last time.
+func (d *Discord) persistQueue() {
+	ticker := time.NewTicker(queueSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !d.InstanceActive {
+			return
+		}
+		d.saveQueue()
+	}
+}
+
+// saveQueue saves the current track, its position and the queue, or forgets the queue saved by this run
+// if nothing plays. Only the position is written while the tracks stay the same.
+func (d *Discord) saveQueue() {
+	d.savedQueue.Lock()
+	defer d.savedQueue.Unlock()
+
+	current := d.Player.GetCurrentSong()
+	vc := d.Player.GetVoiceConnection()
+	if current == nil || vc == nil {
+		if d.savedQueue.fingerprint != "" {
+			if err := db.DeleteSavedQueue(d.GuildID); err != nil {
+				slog.Warnf("Error forgetting saved queue: %v", err)
+				return
+			}
+			d.savedQueue.fingerprint = ""
+		}
+		return
+	}
+
+	songs := append([]*player.Song{current}, d.Player.GetSongQueue()...)
+	fingerprint := queueFingerprint(vc.ChannelID, songs)
+
+	var position time.Duration
+	if !current.Source.Endless() {
+		position = d.Player.GetPlaybackPosition()
+	}
+
+	if fingerprint == d.savedQueue.fingerprint {
+		if err := db.SaveQueuePosition(d.GuildID, position); err != nil {
+			slog.Warnf("Error saving playback position of the queue: %v", err)
+		}
+		return
+	}
+
+	tracks := make([]db.SavedQueueTrack, 0, len(songs))
+	for _, song := range songs {
+		tracks = append(tracks, db.SavedQueueTrack{
+			Title:       song.Title,
+			URL:         song.UserURL,
+			SongID:      song.ID,
+			Source:      song.Source.String(),
+			RequesterID: song.RequesterID,
+		})
+	}
+
+	queue := &db.SavedQueue{GuildID: d.GuildID, ChannelID: vc.ChannelID, PlaybackPosition: position}
+	if err := db.SaveQueue(queue, tracks); err != nil {
+		slog.Warnf("Error saving queue: %v", err)
+		return
+	}
+	d.savedQueue.fingerprint = fingerprint
+}
+
+// queueFingerprint tells whether the voice channel or the tracks changed since the queue was saved.
+func queueFingerprint(channelID string, songs []*player.Song) string {
+	var builder strings.Builder
+	builder.WriteString(channelID)
+	for _, song := range songs {
+		builder.WriteString("\n" + song.ID + " " + song.UserURL + " " + song.RequesterID)
+	}
+	return builder.String()
+}
+
+// handleRestoreCommand rebuilds the queue saved before the last restart and continues playback where it was,
+// in the voice channel of the author or else the one the bot was in.
+func (d *Discord) handleRestoreCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	if d.Player.GetCurrentSong() != nil || len(d.Player.GetSongQueue()) > 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Something is already playing, use `%vexit` first to restore the saved queue instead", d.prefix))
+		return
+	}
+
+	var channelID string
+	if guild, err := s.State.Guild(d.GuildID); err == nil {
+		if vs, found := findUserVoiceState(m.Author.ID, guild.VoiceStates); found {
+			channelID = vs.ChannelID
+		}
+	}
+
+	restored, savedAt, err := d.restoreQueue(channelID)
+	switch {
+	case errors.Is(err, errNothingSaved):
+		d.sendTextEmbed(s, m, "♻️ There is no saved queue, it's kept while tracks play and forgotten once playback stops")
+		return
+	case err != nil:
+		slog.Errorf("Error restoring queue: %v", err)
+		d.sendTextEmbed(s, m, fmt.Sprintf("Error restoring the queue: `%v`", err))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("♻️ Restored %v tracks saved %v, continuing where playback stopped", restored, relativeTimestamp(savedAt)))
+}
+
+// autoRestoreQueue restores a recently saved queue by itself once the instance started, if the guild
+// wants it and somebody is waiting in the voice channel it was playing in.
+func (d *Discord) autoRestoreQueue() {
+	config, err := config.NewConfig()
+	if err != nil || !config.QueueAutoRestore {
+		return
+	}
+
+	time.Sleep(autoRestoreDelay)
+	if !d.InstanceActive || d.Player.GetCurrentSong() != nil || len(d.Player.GetSongQueue()) > 0 {
+		return
+	}
+
+	saved, _, err := db.GetSavedQueue(d.GuildID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Warnf("Error getting saved queue: %v", err)
+		}
+		return
+	}
+	if time.Since(saved.SavedAt) > autoRestoreMaxAge || !d.hasListeners(saved.ChannelID) {
+		return
+	}
+
+	restored, _, err := d.restoreQueue(saved.ChannelID)
+	if err != nil {
+		slog.Warnf("Error restoring saved queue: %v", err)
+		return
+	}
+	slog.Infof("Restored %v saved tracks in guild %v", restored, d.GuildID)
+}
+
+// hasListeners reports whether anybody but the bot is in a voice channel.
+func (d *Discord) hasListeners(channelID string) bool {
+	guild, err := d.Session.State.Guild(d.GuildID)
+	if err != nil {
+		return false
+	}
+
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID == channelID && (d.Session.State.User == nil || vs.UserID != d.Session.State.User.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreQueue queues the saved tracks and plays them from the saved position, in the given voice channel
+// or, if it's empty, the one they were saved in. It returns how many tracks were restored and when they were saved.
+func (d *Discord) restoreQueue(channelID string) (int, time.Time, error) {
+	saved, tracks, err := db.GetSavedQueue(d.GuildID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && len(tracks) == 0) {
+		return 0, time.Time{}, errNothingSaved
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	ctx, cancel := d.startLookup("")
+	defer cancel()
+	youtube := sources.NewYoutube()
+	stream := sources.NewStream()
+
+	var songs []*player.Song
+	position := saved.PlaybackPosition
+	for i, track := range tracks {
+		trackSongs, err := fetchTrackSongs(ctx, track.Title, track.URL, track.SongID, track.Source, youtube, stream)
+		if err != nil || len(trackSongs) == 0 {
+			slog.Warnf("Skipping %q of the saved queue: %v", track.Title, err)
+			if i == 0 {
+				position = 0 // the position belongs to the track that's gone
+			}
+			continue
+		}
+		for _, song := range trackSongs {
+			song.RequesterID = track.RequesterID
+		}
+		songs = append(songs, trackSongs...)
+	}
+	if len(songs) == 0 {
+		return 0, time.Time{}, errors.New("none of the saved tracks can be played anymore")
+	}
+
+	if channelID == "" {
+		channelID = saved.ChannelID
+	}
+	if err := d.JoinVoiceChannel(channelID); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	for _, song := range songs {
+		d.Player.Enqueue(song)
+	}
+	// Queue orders other than fifo may put another track first, the position is only its own
+	if queue := d.Player.GetSongQueue(); len(queue) == 0 || queue[0] != songs[0] {
+		position = 0
+	}
+	go d.Player.Play(d.ctx, int(position.Seconds()), nil)
+
+	return len(songs), saved.SavedAt, nil
+}
@@ -1,7 +1,6 @@
 package discord
 
 import (
-	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -19,9 +18,5 @@ func (d *Discord) handleResumeCommand(s *discordgo.Session, m *discordgo.Message
 
 	d.Player.Unpause()
 
-	embedStr := d.Player.GetCurrentStatus().StringEmoji() + " " + phrase
-	embedMsg := embed.NewEmbed().
-		SetDescription(embedStr).
-		SetColor(0x9f00d4).MessageEmbed
-	s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	d.sendConfirmation(s, m, d.Player.GetCurrentStatus().StringEmoji(), phrase)
 }
@@ -0,0 +1,136 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	// defaultSeekStep is how far forward and rewind move without a parameter
+	defaultSeekStep   = 10 * time.Second
+	progressBarLength = 16
+)
+
+var errInvalidSeekStep = errors.New("invalid seek step")
+
+// handleSeekCommand handles the forward and rewind commands for Discord, moving playback by the given step.
+func (d *Discord) handleSeekCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string, forward bool) {
+	d.changeAvatar(s)
+
+	name := "rewind"
+	if forward {
+		name = "forward"
+	}
+
+	step, err := parseSeekStep(param)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%v%v [30s/1m30s/90/1:30]`, %v by default", d.prefix, name, defaultSeekStep))
+		return
+	}
+	if !forward {
+		step = -step
+	}
+
+	emoji := "⏪"
+	if forward {
+		emoji = "⏩"
+	}
+	position, err := d.Player.SeekBy(step)
+	d.answerSeek(s, m, emoji, position, err)
+}
+
+// handleSeekToCommand handles the seek command for Discord, moving playback to the given position in the song.
+func (d *Discord) handleSeekToCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	position, err := parseSeekPosition(param)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vseek [1m30s/90/1:30]`, `%vseek 0` starts the track over", d.prefix, d.prefix))
+		return
+	}
+
+	position, err = d.Player.Seek(position)
+	d.answerSeek(s, m, "🎯", position, err)
+}
+
+// answerSeek confirms the new position of a seek or explains why it failed.
+func (d *Discord) answerSeek(s *discordgo.Session, m *discordgo.MessageCreate, emoji string, position time.Duration, err error) {
+	switch {
+	case errors.Is(err, player.ErrNothingPlaying):
+		d.sendTextEmbed(s, m, fmt.Sprintf("Nothing is playing. Use `%vplay [title/url/id/stream]` to start", d.prefix))
+		return
+	case errors.Is(err, player.ErrNotSeekable):
+		d.sendTextEmbed(s, m, "This track can't be seeked, streams and tracks of unknown length play from where they are")
+		return
+	case err != nil:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Failed to seek: %v", err))
+		return
+	}
+
+	song := d.Player.GetCurrentSong()
+	d.sendConfirmation(s, m, emoji, fmt.Sprintf("*%v*\n%v", songLink(song), progressBar(position, song.Duration)))
+}
+
+// parseSeekStep parses a seek step like 30s, 1m30s, 90 (seconds) or 1:30, empty is the default step.
+func parseSeekStep(param string) (time.Duration, error) {
+	param = strings.TrimSpace(param)
+	if param == "" {
+		return defaultSeekStep, nil
+	}
+
+	if seconds, err := strconv.Atoi(param); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if minutes, seconds, found := strings.Cut(param, ":"); found {
+		m, errM := strconv.Atoi(minutes)
+		s, errS := strconv.Atoi(seconds)
+		if errM != nil || errS != nil || m < 0 || s < 0 || s > 59 || m+s == 0 {
+			return 0, errInvalidSeekStep
+		}
+		return time.Duration(m)*time.Minute + time.Duration(s)*time.Second, nil
+	}
+
+	step, err := time.ParseDuration(param)
+	if err != nil || step <= 0 {
+		return 0, errInvalidSeekStep
+	}
+	return step, nil
+}
+
+// parseSeekPosition parses a position in the song like a seek step, where 0 is the start of the song.
+func parseSeekPosition(param string) (time.Duration, error) {
+	switch strings.TrimSpace(param) {
+	case "":
+		return 0, errInvalidSeekStep
+	case "0", "0s", "0:00":
+		return 0, nil
+	}
+	return parseSeekStep(param)
+}
+
+// progressBar draws the position within the duration, e.g. ▬▬▬🔘▬▬▬▬▬▬ `1:30 / 3:45`.
+func progressBar(position, duration time.Duration) string {
+	filled := 0
+	if duration > 0 {
+		filled = min(progressBarLength-1, int(int64(position)*progressBarLength/int64(duration)))
+	}
+
+	bar := strings.Repeat("▬", filled) + "🔘" + strings.Repeat("▬", progressBarLength-1-filled)
+	return fmt.Sprintf("%v `%v / %v`", bar, formatClock(position), formatClock(duration))
+}
+
+// formatClock formats a duration like a player clock, e.g. 3:05 or 1:02:03.
+func formatClock(duration time.Duration) string {
+	total := int(duration.Seconds())
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
@@ -0,0 +1,144 @@
+package discord
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+)
+
+// maxRateLimitWaits is how often a message waits out a rate limit before it's given up.
+const maxRateLimitWaits = 5
+
+// messageRequest sends or edits a message with the given request options.
+type messageRequest func(options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+// messageJob is a queued message request, its callers wait for done.
+type messageJob struct {
+	request messageRequest
+	edit    string // ID of the edited message, empty for new messages
+	message *discordgo.Message
+	err     error
+	done    chan struct{}
+}
+
+// channelMessages are the queued message requests of a channel.
+type channelMessages struct {
+	jobs []*messageJob
+}
+
+// messageSender queues message requests per channel and sends them one after another. discordgo already waits
+// for the rate limit buckets announced in response headers; when Discord answers 429 anyway, the channel's queue
+// waits for the Retry-After the response asked for instead of hammering the API. Edits of a message that is still
+// queued replace the queued edit, so rapid updates like the progress bar send only the latest state.
+type messageSender struct {
+	sync.Mutex
+	channels map[string]*channelMessages
+}
+
+// messages is the sender of all guilds, they share the session and its rate limits.
+var messages = &messageSender{channels: make(map[string]*channelMessages)}
+
+// SendMessage queues a text message to a channel and waits until it's sent.
+func SendMessage(s *discordgo.Session, channelID, content string) (*discordgo.Message, error) {
+	return messages.send(channelID, "", func(options ...discordgo.RequestOption) (*discordgo.Message, error) {
+		return s.ChannelMessageSend(channelID, content, options...)
+	})
+}
+
+// SendEmbed queues an embed message to a channel and waits until it's sent.
+func SendEmbed(s *discordgo.Session, channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	return messages.send(channelID, "", func(options ...discordgo.RequestOption) (*discordgo.Message, error) {
+		return s.ChannelMessageSendEmbed(channelID, embed, options...)
+	})
+}
+
+// SendComplex queues a message with files or components to a channel and waits until it's sent.
+func SendComplex(s *discordgo.Session, channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	return messages.send(channelID, "", func(options ...discordgo.RequestOption) (*discordgo.Message, error) {
+		return s.ChannelMessageSendComplex(channelID, data, options...)
+	})
+}
+
+// EditEmbed queues an edit of the embed of a message and waits until it's sent, or replaced by a later edit.
+func EditEmbed(s *discordgo.Session, channelID, messageID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	return messages.send(channelID, messageID, func(options ...discordgo.RequestOption) (*discordgo.Message, error) {
+		return s.ChannelMessageEditEmbed(channelID, messageID, embed, options...)
+	})
+}
+
+// EditComplex queues an edit of a message with components and waits until it's sent, or replaced by a later edit.
+func EditComplex(s *discordgo.Session, edit *discordgo.MessageEdit) (*discordgo.Message, error) {
+	return messages.send(edit.Channel, edit.ID, func(options ...discordgo.RequestOption) (*discordgo.Message, error) {
+		return s.ChannelMessageEditComplex(edit, options...)
+	})
+}
+
+// send queues a request of the channel and waits for its result. A queued edit of the same message takes the
+// new request, both callers get its result.
+func (ms *messageSender) send(channelID, edit string, request messageRequest) (*discordgo.Message, error) {
+	ms.Lock()
+	channel, running := ms.channels[channelID]
+	if !running {
+		channel = &channelMessages{}
+		ms.channels[channelID] = channel
+	}
+
+	var job *messageJob
+	if edit != "" {
+		for _, queued := range channel.jobs {
+			if queued.edit == edit {
+				queued.request = request
+				job = queued
+				break
+			}
+		}
+	}
+	if job == nil {
+		job = &messageJob{request: request, edit: edit, done: make(chan struct{})}
+		channel.jobs = append(channel.jobs, job)
+	}
+
+	if !running {
+		go ms.run(channelID, channel)
+	}
+	ms.Unlock()
+
+	<-job.done
+	return job.message, job.err
+}
+
+// run sends the queued requests of a channel until none are left.
+func (ms *messageSender) run(channelID string, channel *channelMessages) {
+	for {
+		ms.Lock()
+		if len(channel.jobs) == 0 {
+			delete(ms.channels, channelID)
+			ms.Unlock()
+			return
+		}
+		job := channel.jobs[0]
+		channel.jobs = channel.jobs[1:]
+		ms.Unlock()
+
+		job.message, job.err = ms.do(channelID, job)
+		close(job.done)
+	}
+}
+
+// do sends a request, waiting out rate limits Discord answered with.
+func (ms *messageSender) do(channelID string, job *messageJob) (*discordgo.Message, error) {
+	for waits := 0; ; waits++ {
+		message, err := job.request(discordgo.WithRetryOnRatelimit(false))
+
+		var rateLimited *discordgo.RateLimitError
+		if !errors.As(err, &rateLimited) || waits >= maxRateLimitWaits {
+			return message, err
+		}
+
+		slog.Warnf("Rate limited sending to channel %v, retrying in %v", channelID, rateLimited.RetryAfter)
+		time.Sleep(rateLimited.RetryAfter)
+	}
+}
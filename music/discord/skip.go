@@ -9,21 +9,17 @@ import (
 func (d *Discord) handleSkipCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
 	d.changeAvatar(s)
 
-	embedStr := "⏩ " + getSkipPhrase()
-	embedMsg := embed.NewEmbed().
-		SetDescription(embedStr).
-		SetColor(0x9f00d4).MessageEmbed
-
-	skipPhrase, _ := s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	skipPhrase := d.sendConfirmation(s, m, "⏩", getSkipPhrase())
 
 	d.Player.Skip()
+	d.stopNowPlaying()
 
-	if len(d.Player.GetSongQueue()) == 0 {
+	if len(d.Player.GetSongQueue()) == 0 && skipPhrase != nil {
 		embedStr := "⏹ " + getStopPhrase()
 		embedMsg := embed.NewEmbed().
 			SetDescription(embedStr).
 			SetColor(0x9f00d4).MessageEmbed
 
-		s.ChannelMessageEditEmbed(m.Message.ChannelID, skipPhrase.ID, embedMsg)
+		EditEmbed(s, m.Message.ChannelID, skipPhrase.ID, embedMsg)
 	}
 }
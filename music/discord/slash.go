@@ -0,0 +1,249 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+const (
+	// queryOption is the option of the play commands, completed with titles from the guild's history
+	queryOption = "query"
+	// argsOption is the option carrying the parameters of other commands, as typed after prefix commands
+	argsOption = "args"
+
+	// autocompleteScan is how many of the most played tracks autocomplete searches
+	autocompleteScan = 500
+	// autocompleteChoices is the most choices Discord accepts in an autocomplete response
+	autocompleteChoices = 25
+	// slashTextLimit is the longest name, description and choice Discord accepts
+	slashTextLimit = 100
+)
+
+// managerCommands are handled by the guild manager, their instances may not be active.
+var managerCommands = []string{"register", "unregister"}
+
+// slashInteraction is the interaction a slash command message stands in for.
+type slashInteraction struct {
+	interaction *discordgo.Interaction
+	answered    bool // the deferred response was replaced by a confirmation
+}
+
+// slashInteractions holds the interactions of the slash commands being run, by their message.
+var slashInteractions sync.Map
+
+// RegisterSlashCommands registers every command as a global slash command of the application,
+// replacing the commands registered before. It needs the session to be open.
+func RegisterSlashCommands(s *discordgo.Session) error {
+	registered, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", slashCommands())
+	if err != nil {
+		return err
+	}
+	slog.Infof("Registered %v slash commands", len(registered))
+	return nil
+}
+
+// slashCommands describes the commands of the registry as application commands.
+func slashCommands() []*discordgo.ApplicationCommand {
+	var slash []*discordgo.ApplicationCommand
+	for _, cmd := range commands {
+		appCommand := &discordgo.ApplicationCommand{
+			Name:        cmd.name,
+			Description: truncateSlashText(cmd.description),
+		}
+
+		switch {
+		case cmd.name == "play" || cmd.name == "add":
+			appCommand.Options = []*discordgo.ApplicationCommandOption{{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         queryOption,
+				Description:  truncateSlashText("Title, URL or ID, " + strings.Join(cmd.usages, " or ")),
+				Required:     cmd.name == "add",
+				Autocomplete: true,
+			}}
+		case hasParameters(cmd):
+			appCommand.Options = []*discordgo.ApplicationCommandOption{{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        argsOption,
+				Description: truncateSlashText(describeUsages(cmd)),
+			}}
+		}
+
+		slash = append(slash, appCommand)
+	}
+	return slash
+}
+
+// hasParameters reports whether any usage of the command takes a parameter.
+func hasParameters(cmd *command) bool {
+	for _, usage := range cmd.usages {
+		if usage != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// describeUsages lists the parameter forms of a command.
+func describeUsages(cmd *command) string {
+	var usages []string
+	for _, usage := range cmd.usages {
+		if usage != "" {
+			usages = append(usages, usage)
+		}
+	}
+	return strings.Join(usages, " or ")
+}
+
+func truncateSlashText(text string) string {
+	if runes := []rune(text); len(runes) > slashTextLimit {
+		return string(runes[:slashTextLimit-1]) + "…"
+	}
+	return text
+}
+
+// RunSlashCommand acknowledges a slash command and runs it as the message of the equivalent prefix command,
+// so slash and prefix commands share their handlers. Replies are posted to the channel like those of prefix
+// commands, the acknowledgement is removed once the command is done unless a quiet confirmation replaced it.
+func RunSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate, prefix string, run func(m *discordgo.MessageCreate, command, param string)) {
+	data := i.ApplicationCommandData()
+
+	var param string
+	for _, option := range data.Options {
+		if option.Type == discordgo.ApplicationCommandOptionString {
+			param = strings.TrimSpace(option.StringValue())
+		}
+	}
+
+	runInteractionCommand(s, i, prefix, data.Name, param, run)
+}
+
+// runInteractionCommand acknowledges an interaction and runs the command as the message of the equivalent
+// prefix command, answerSlash replaces the acknowledgement like for slash commands.
+func runInteractionCommand(s *discordgo.Session, i *discordgo.InteractionCreate, prefix, name, param string, run func(m *discordgo.MessageCreate, command, param string)) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		slog.Warnf("Error acknowledging /%v: %v", name, err)
+		return
+	}
+
+	author := i.User
+	if i.Member != nil {
+		author = i.Member.User
+	}
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        i.ID,
+		ChannelID: i.ChannelID,
+		GuildID:   i.GuildID,
+		Author:    author,
+		Member:    i.Member,
+		Content:   strings.TrimSpace(prefix + name + " " + param),
+	}}
+
+	state := &slashInteraction{interaction: i.Interaction}
+	slashInteractions.Store(m, state)
+	defer func() {
+		slashInteractions.Delete(m)
+		if !state.answered {
+			if err := s.InteractionResponseDelete(i.Interaction); err != nil {
+				slog.Debugf("Error removing acknowledgement of /%v: %v", name, err)
+			}
+		}
+	}()
+
+	run(m, name, param)
+}
+
+// answerSlash replaces the acknowledgement of a slash command with text only its author sees,
+// it reports false if the message isn't one of a slash command.
+func answerSlash(s *discordgo.Session, m *discordgo.MessageCreate, text string) bool {
+	value, ok := slashInteractions.Load(m)
+	if !ok {
+		return false
+	}
+	state := value.(*slashInteraction)
+
+	if _, err := s.InteractionResponseEdit(state.interaction, &discordgo.WebhookEdit{Content: &text}); err != nil {
+		slog.Warnf("Error answering slash command: %v", err)
+		return false
+	}
+	state.answered = true
+	return true
+}
+
+// onSlashCommand runs the slash commands and completes the play queries of the guild.
+func (d *Discord) onSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID != d.GuildID || !d.InstanceActive {
+		return
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		name := i.ApplicationCommandData().Name
+		for _, managed := range managerCommands {
+			if name == managed {
+				return
+			}
+		}
+		cmd := commandByName(name)
+		if cmd == nil || cmd.run == nil {
+			return
+		}
+
+		RunSlashCommand(s, i, d.prefix, func(m *discordgo.MessageCreate, _, param string) {
+			if !d.featureEnabled(FeatureSlashCommands) {
+				answerSlash(s, m, fmt.Sprintf("🧪 Slash commands are not enabled on this server yet, use `%v%v` instead", d.prefix, cmd.name))
+				return
+			}
+			d.dispatchCommand(s, m, cmd, param)
+		})
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		d.completeQuery(s, i)
+	}
+}
+
+// completeQuery offers titles of the guild's most played tracks containing the typed text.
+func (d *Discord) completeQuery(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var typed string
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Focused && option.Name == queryOption {
+			typed = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		}
+	}
+
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	tracks, err := history.NewHistory().GetHistory(d.ctx, d.GuildID, "play_count", autocompleteScan, 0)
+	if err != nil {
+		slog.Warnf("Error getting history for autocomplete: %v", err)
+	}
+	for _, track := range tracks {
+		if len(choices) == autocompleteChoices {
+			break
+		}
+		if track.Track.Name == "" || !strings.Contains(strings.ToLower(track.Track.Name), typed) {
+			continue
+		}
+
+		// Values are played as typed, URLs longer than Discord accepts are played by title
+		value := track.Track.URL
+		if value == "" || len([]rune(value)) > slashTextLimit {
+			value = truncateSlashText(track.Track.Name)
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: truncateSlashText(track.Track.Name), Value: value})
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		slog.Debugf("Error answering autocomplete: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package discord
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// soundcheckTone is the test tone of the soundcheck, a 3 second 440 Hz sine generated by ffmpeg,
+// so it plays without any file or network source.
+const soundcheckTone = "sine=frequency=440:duration=3"
+
+// handleSoundcheckCommand joins the author's voice channel and plays a short test tone, checking that
+// ffmpeg, the encoder and the voice connection work without relying on YouTube or another source.
+func (d *Discord) handleSoundcheckCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.changeAvatar(s)
+
+	if status := d.Player.GetCurrentStatus(); status == player.StatusPlaying || status == player.StatusPaused {
+		d.sendTextEmbed(s, m, fmt.Sprintf("A track is %v, use `%vexit` first to run the soundcheck", status, d.prefix))
+		return
+	}
+
+	var channelID string
+	if guild, err := s.State.Guild(d.GuildID); err == nil {
+		if vs, found := findUserVoiceState(m.Author.ID, guild.VoiceStates); found {
+			channelID = vs.ChannelID
+		}
+	}
+	if channelID == "" {
+		d.sendTextEmbed(s, m, "Join a voice channel first, the soundcheck plays in yours")
+		return
+	}
+
+	if err := d.JoinVoiceChannel(channelID); err != nil {
+		slog.Errorf("Error joining voice channel for soundcheck: %v", err)
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔊 Soundcheck failed, I can't join <#%v>: `%v`\nCheck that I may connect and speak there", channelID, err))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔊 Soundcheck: playing a 3 second test tone in <#%v>", channelID))
+
+	started := time.Now()
+	if err := d.Player.PlayGeneratedClip(d.ctx, soundcheckTone); err != nil {
+		slog.Errorf("Error playing soundcheck tone: %v", err)
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔊 Soundcheck failed: `%v`\nCheck that ffmpeg is installed or `DCA_FFMPEG_BINARY_PATH` points at it, and see `%vdebug`", err, d.prefix))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔊 Soundcheck passed, the tone was encoded and streamed in %v. If you heard nothing, check the bot's volume in your Discord client", time.Since(started).Round(100*time.Millisecond)))
+}
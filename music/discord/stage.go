@@ -0,0 +1,153 @@
+package discord
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+)
+
+const (
+	// stageTopicInterval is how often the stage topic is compared with the playing track
+	stageTopicInterval = 15 * time.Second
+	// stageTopicMinInterval spaces out stage topic edits when tracks are skipped quickly
+	stageTopicMinInterval = 30 * time.Second
+	// maxStageTopicLength is the longest topic of a stage
+	maxStageTopicLength = 120
+)
+
+// stageTopic is the stage the bot plays on, its topic shows the playing track.
+type stageTopic struct {
+	sync.Mutex
+	channelID string
+	created   bool   // the bot started the stage and ends it once playback stops
+	original  string // topic of a stage started by somebody else, put back once playback stops
+	current   string // topic last set by the bot, empty if it didn't change it
+	updatedAt time.Time
+}
+
+// isStageChannel reports whether a channel of the guild is a stage channel.
+func (d *Discord) isStageChannel(channelID string) bool {
+	channel, err := d.Session.State.Channel(channelID)
+	if err != nil {
+		if channel, err = d.Session.Channel(channelID); err != nil {
+			return false
+		}
+	}
+	return channel.Type == discordgo.ChannelTypeGuildStageVoice
+}
+
+// speakOnStage moves the bot from the audience to the speakers of a stage channel, or it isn't heard.
+// It needs the Mute Members permission in the channel.
+func (d *Discord) speakOnStage(channelID string) {
+	if !d.isStageChannel(channelID) {
+		return
+	}
+
+	endpoint := discordgo.EndpointGuild(d.GuildID) + "/voice-states/@me"
+	data := map[string]interface{}{"channel_id": channelID, "suppress": false}
+	if _, err := d.Session.RequestWithBucketID(http.MethodPatch, endpoint, data, endpoint); err != nil {
+		slog.Warnf("Error becoming a speaker of stage %v, it needs the Mute Members permission: %v", channelID, err)
+	}
+}
+
+// runStageTopicUpdater keeps the topic of the stage the bot plays on in line with the playing track
+// while the instance is active.
+func (d *Discord) runStageTopicUpdater() {
+	ticker := time.NewTicker(stageTopicInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !d.InstanceActive {
+			return
+		}
+		d.updateStageTopic()
+	}
+}
+
+// updateStageTopic sets the topic of the stage the bot plays on to the playing track, starting the stage
+// if nobody did. Once playback stops or the bot leaves the stage, a stage it started is ended and the topic
+// of another one is put back.
+func (d *Discord) updateStageTopic() {
+	var channelID string
+	if vc := d.Player.GetVoiceConnection(); vc != nil {
+		channelID = vc.ChannelID
+	}
+	song := d.Player.GetCurrentSong()
+
+	d.stage.Lock()
+	defer d.stage.Unlock()
+
+	if d.stage.channelID != "" && (song == nil || channelID != d.stage.channelID) {
+		d.leaveStageTopic()
+	}
+	if song == nil || channelID == "" || time.Since(d.stage.updatedAt) < stageTopicMinInterval {
+		return
+	}
+	if d.stage.channelID == "" && !d.isStageChannel(channelID) {
+		return
+	}
+
+	topic := song.Title
+	if runes := []rune(topic); len(runes) > maxStageTopicLength {
+		topic = string(runes[:maxStageTopicLength-1]) + "…"
+	}
+	if topic == d.stage.current {
+		return
+	}
+	d.stage.updatedAt = time.Now()
+
+	if d.stage.channelID == "" {
+		instance, err := d.Session.StageInstance(channelID)
+		if err != nil && !isNotFound(err) {
+			slog.Warnf("Error getting stage of channel %v: %v", channelID, err)
+			return
+		}
+
+		if instance == nil || err != nil {
+			_, err = d.Session.StageInstanceCreate(&discordgo.StageInstanceParams{ChannelID: channelID, Topic: topic})
+			if err != nil {
+				slog.Warnf("Error starting stage in channel %v, it needs the Manage Channels, Mute Members and Move Members permissions: %v", channelID, err)
+				return
+			}
+			d.stage.channelID, d.stage.created, d.stage.current = channelID, true, topic
+			return
+		}
+		d.stage.channelID, d.stage.created, d.stage.original = channelID, false, instance.Topic
+	}
+
+	if _, err := d.Session.StageInstanceEdit(channelID, &discordgo.StageInstanceParams{Topic: topic}); err != nil {
+		slog.Warnf("Error changing topic of stage %v: %v", channelID, err)
+		return
+	}
+	d.stage.current = topic
+}
+
+// leaveStageTopic ends the stage the bot started, or puts back the topic of one it didn't.
+// The stage lock must be held.
+func (d *Discord) leaveStageTopic() {
+	channelID := d.stage.channelID
+
+	var err error
+	switch {
+	case d.stage.created:
+		err = d.Session.StageInstanceDelete(channelID)
+	case d.stage.current != "" && d.stage.original != "":
+		_, err = d.Session.StageInstanceEdit(channelID, &discordgo.StageInstanceParams{Topic: d.stage.original})
+	}
+	// A stage ends by itself once everybody left
+	if err != nil && !isNotFound(err) {
+		slog.Warnf("Error restoring stage %v: %v", channelID, err)
+	}
+
+	d.stage.channelID, d.stage.created, d.stage.original, d.stage.current = "", false, "", ""
+}
+
+// isNotFound reports whether a Discord API request failed because the resource doesn't exist.
+func isNotFound(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
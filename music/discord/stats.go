@@ -0,0 +1,174 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/stats"
+)
+
+const (
+	heatmapDays    = 90 // days of activity spread over the weekday/hour heatmap
+	dailyChartDays = 30 // days shown as bars below the heatmap
+	statsTopTags   = 3  // most played tags listed in the summary
+	statsSessions  = 10 // latest listening sessions listed by stats sessions
+)
+
+// handleStatsCommand handles the stats command for Discord.
+func (d *Discord) handleStatsCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	switch param {
+	case "graph":
+		d.sendStatsGraph(s, m)
+	case "bot":
+		d.sendCommandStats(s, m)
+	case "sessions":
+		d.sendSessionStats(s, m)
+	default:
+		d.sendStatsSummary(s, m)
+	}
+}
+
+// sendStatsSummary sends total listening time of the guild.
+func (d *Discord) sendStatsSummary(s *discordgo.Session, m *discordgo.MessageCreate) {
+	h := history.NewHistory()
+
+	activity, err := h.GetListeningActivity(d.GuildID, time.Now().AddDate(0, 0, -dailyChartDays))
+	if err != nil {
+		slog.Errorf("Error getting listening activity: %v", err)
+		d.sendTextEmbed(s, m, "Error getting listening statistics")
+		return
+	}
+
+	content := fmt.Sprintf("📊 Listened in the last %v days: `%v`", dailyChartDays, d.getLocale().Duration(stats.TotalSeconds(activity)))
+
+	now := time.Now()
+	topTags, err := h.GetTopTags(d.GuildID, "", now.AddDate(0, 0, -dailyChartDays), now, statsTopTags)
+	if err != nil {
+		slog.Warnf("Error getting top tags: %v", err)
+	}
+	if len(topTags) > 0 {
+		content += "\nTop tags: " + formatTagCounts(topTags)
+	}
+
+	sessions, err := h.GetSessions(d.GuildID, now.AddDate(0, 0, -dailyChartDays), 0)
+	if err != nil {
+		slog.Warnf("Error getting listening sessions: %v", err)
+	}
+	if len(sessions) > 0 {
+		var total time.Duration
+		var plays int64
+		for _, session := range sessions {
+			total += session.Duration()
+			plays += session.Plays
+		}
+		content += fmt.Sprintf("\nSessions: `%v`, `%v` and `%.1f` tracks on average",
+			len(sessions), d.getLocale().Duration((total / time.Duration(len(sessions))).Seconds()), float64(plays)/float64(len(sessions)))
+	}
+
+	content += fmt.Sprintf("\n\nUse `%vstats graph` to see when this server listens to music, `%vstats sessions` for the latest sessions, `%vstats bot` for how fast commands run.", d.prefix, d.prefix, d.prefix)
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// sendSessionStats sends the latest listening sessions of the guild, how long they lasted and how much played.
+func (d *Discord) sendSessionStats(s *discordgo.Session, m *discordgo.MessageCreate) {
+	sessions, err := history.NewHistory().GetSessions(d.GuildID, time.Time{}, statsSessions)
+	if err != nil {
+		slog.Errorf("Error getting listening sessions: %v", err)
+		d.sendTextEmbed(s, m, "Error getting listening sessions")
+		return
+	}
+
+	if len(sessions) == 0 {
+		d.sendTextEmbed(s, m, "🎧 No listening sessions yet, one starts when I join a voice channel")
+		return
+	}
+
+	l := d.getLocale()
+	content := "🎧 Latest listening sessions\n"
+	for _, session := range sessions {
+		length := l.Duration(session.Duration().Seconds())
+		if session.EndedAt == nil {
+			length += ", ongoing"
+		}
+		content += fmt.Sprintf("\n`#%v` %v — `%v`, %v tracks", session.ID, relativeTimestamp(session.StartedAt), length, session.Plays)
+	}
+	content += fmt.Sprintf("\n\nAdministrators can export one with `%vexport session [id]`", d.prefix)
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// sendCommandStats sends how many commands ran since the bot started and which ones are the slowest.
+func (d *Discord) sendCommandStats(s *discordgo.Session, m *discordgo.MessageCreate) {
+	metrics := GetCommandMetrics()
+	if metrics.Runs == 0 {
+		d.sendTextEmbed(s, m, "No commands recorded yet")
+		return
+	}
+
+	content := fmt.Sprintf("⏱️ Commands since start: `%v` · Failed: `%v` (%.1f%%)", metrics.Runs, metrics.Failures, float64(metrics.Failures)/float64(metrics.Runs)*100)
+
+	content += "\n\nSlowest commands (p50 · p95 over recent runs):"
+	for _, command := range metrics.Slowest {
+		content += fmt.Sprintf("\n`%v%v` %.0f ms · %.0f ms · %v runs, %v failed", d.prefix, command.Name, command.P50, command.P95, command.Runs, command.Failures)
+	}
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// sendStatsGraph sends the listening activity heatmap as an attached image.
+func (d *Discord) sendStatsGraph(s *discordgo.Session, m *discordgo.MessageCreate) {
+	h := history.NewHistory()
+	now := time.Now()
+
+	activity, err := h.GetListeningActivity(d.GuildID, now.AddDate(0, 0, -heatmapDays))
+	if err != nil {
+		slog.Errorf("Error getting listening activity: %v", err)
+		d.sendTextEmbed(s, m, "Error getting listening statistics")
+		return
+	}
+
+	if len(activity) == 0 {
+		d.sendTextEmbed(s, m, "No listening activity recorded yet")
+		return
+	}
+
+	heatmap := stats.BuildHeatmap(activity, time.Local)
+	daily := stats.DailyTotals(activity, dailyChartDays, now, time.Local)
+
+	image, err := stats.RenderActivityGraph(heatmap, daily)
+	if err != nil {
+		slog.Errorf("Error rendering activity graph: %v", err)
+		d.sendTextEmbed(s, m, "Error rendering listening statistics")
+		return
+	}
+
+	description := fmt.Sprintf("📊 Listening activity\n\nTop: weekdays (Monday first) by hour of day over the last %v days.\nBottom: listening time per day over the last %v days.", heatmapDays, dailyChartDays)
+
+	embedMsg := embed.NewEmbed().
+		SetDescription(description).
+		SetImage("attachment://activity.png").
+		SetColor(0x9f00d4).SetFooter(version.AppFullName).MessageEmbed
+
+	_, err = SendComplex(s, m.Message.ChannelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embedMsg},
+		Files: []*discordgo.File{
+			{
+				Name:        "activity.png",
+				ContentType: "image/png",
+				Reader:      bytes.NewReader(image),
+			},
+		},
+	})
+	if err != nil {
+		slog.Warnf("Error sending stats graph message: %v", err)
+	}
+}
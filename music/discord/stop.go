@@ -1,17 +1,27 @@
 package discord
 
 import (
-	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
+	"github.com/keshon/melodix-discord-player/music/player"
 )
 
 // handleStopCommand handles the stop command for Discord.
+// A leftover queue is offered to be saved as a playlist before it's cleared,
+// requests still looking up their songs are called off.
 func (d *Discord) handleStopCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
-	embedStr := "⏹ " + getStopPhrase()
-	embedMsg := embed.NewEmbed().
-		SetDescription(embedStr).
-		SetColor(0x9f00d4).MessageEmbed
-	s.ChannelMessageSendEmbed(m.Message.ChannelID, embedMsg)
+	var leftover []*player.Song
+	if queue := d.Player.GetSongQueue(); len(queue) > 0 {
+		if current := d.Player.GetCurrentSong(); current != nil {
+			leftover = append(leftover, current)
+		}
+		leftover = append(leftover, queue...)
+	}
 
+	d.sendConfirmation(s, m, "⏹", getStopPhrase())
+
+	d.cancelLookups()
 	d.Player.Stop()
+	d.stopNowPlaying()
+
+	d.offerQueueSnapshot(s, m.Message.ChannelID, leftover)
 }
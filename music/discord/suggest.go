@@ -0,0 +1,171 @@
+package discord
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// maxSuggestions is how many close commands are offered for a mistyped one.
+const maxSuggestions = 3
+
+// suggestCommands returns the command names and aliases closest to a mistyped word, best first, at most one per command.
+// Custom aliases of the guild are included. Aliases of a single character are left out, any short word would be close to them.
+func (d *Discord) suggestCommands(word string) []string {
+	type match struct {
+		word     string
+		command  string
+		distance int
+	}
+
+	best := make(map[string]match) // closest match by command
+	maxDistance := maxSuggestionDistance(word)
+
+	consider := func(candidate, command string) {
+		if len([]rune(candidate)) < 2 {
+			return
+		}
+		distance := levenshtein(word, candidate)
+		if distance > maxDistance {
+			return
+		}
+		if current, ok := best[command]; !ok || distance < current.distance {
+			best[command] = match{word: candidate, command: command, distance: distance}
+		}
+	}
+
+	for _, cmd := range commands {
+		if cmd.run == nil {
+			continue
+		}
+		consider(cmd.name, cmd.name)
+		for _, alias := range cmd.aliases {
+			consider(alias, cmd.name)
+		}
+	}
+
+	d.aliasesMu.RLock()
+	for alias, command := range d.customAliases {
+		consider(alias, command)
+	}
+	d.aliasesMu.RUnlock()
+
+	matches := make([]match, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].word < matches[j].word
+	})
+
+	var suggestions []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		// Commands may share an alias, such as play which also resumes
+		if !seen[m.word] {
+			seen[m.word] = true
+			suggestions = append(suggestions, m.word)
+		}
+	}
+	return suggestions
+}
+
+// maxSuggestionDistance is how many edits a word may be away from a command to be suggested.
+func maxSuggestionDistance(word string) int {
+	if len([]rune(word)) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// suggestUnknownCommand replies with the closest commands to a mistyped one, unless the guild turned suggestions off.
+func (d *Discord) suggestUnknownCommand(s *discordgo.Session, m *discordgo.MessageCreate, word string) {
+	if !d.commandSuggestions {
+		return
+	}
+
+	// Commands of the guild manager are unknown here but not mistyped
+	if findCommand(word) != nil {
+		return
+	}
+
+	suggestions := d.suggestCommands(word)
+	if len(suggestions) == 0 {
+		return
+	}
+
+	for i, suggestion := range suggestions {
+		suggestions[i] = "`" + d.prefix + suggestion + "`"
+	}
+	d.sendTextEmbed(s, m, fmt.Sprintf("Unknown command `%v%v`, did you mean %v?", d.prefix, word, strings.Join(suggestions, " or ")))
+}
+
+// handleSuggestionsSetting shows or changes whether mistyped commands are answered with suggestions.
+func (d *Discord) handleSuggestionsSetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	if value == "" {
+		state := "off"
+		if d.commandSuggestions {
+			state = "on"
+		}
+		d.sendTextEmbed(s, m, fmt.Sprintf("💡 Suggestions for mistyped commands are `%v`\nUse `%vsettings suggestions [on/off]` to change it", state, d.prefix))
+		return
+	}
+
+	if value != "on" && value != "off" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings suggestions [on/off]`", d.prefix))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	d.commandSuggestions = value == "on"
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.NoSuggestions = !d.commandSuggestions
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving suggestions setting: %v", err)
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("💡 Suggestions for mistyped commands are `%v`", value))
+}
+
+// levenshtein returns the number of single character insertions, deletions and substitutions that turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
@@ -0,0 +1,62 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"play", "play", 0},
+		{"skp", "skip", 1},
+		{"skip", "skpi", 2},
+		{"flaw", "lawn", 2},
+		{"kitten", "sitting", 3},
+		{"плей", "плеи", 1},
+	}
+
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %v, expected %v", test.a, test.b, got, test.want)
+		}
+		if got := levenshtein(test.b, test.a); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %v, expected %v", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestSuggestCommands(t *testing.T) {
+	d := &Discord{customAliases: map[string]string{
+		"tunes": "play",
+		"aa":    "add",
+		"ab":    "about",
+		"ac":    "clear",
+		"ad":    "debug",
+	}}
+
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"skp", "skip"},
+		{"shufle", "shuffle"},
+		{"skpi", ""},       // two edits are too many for a short word
+		{"pla", "pl play"}, // play is an alias of resume too, suggested once, closest words first
+		{"tune", "tunes"},  // custom aliases of the guild
+		{"ax", "aa ab ac"}, // at most maxSuggestions
+		{"mve", "move"},    // a command and its alias are suggested once
+		{"xyzzy", ""},
+	}
+
+	for _, test := range tests {
+		if got := strings.Join(d.suggestCommands(test.word), " "); got != test.want {
+			t.Errorf("Suggestions for %q are %q, expected %q", test.word, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+// summarySkippedTracks is how many of the skipped tracks a session summary lists.
+const summarySkippedTracks = 5
+
+// listeningSession follows the session stored in history while the bot is in voice,
+// from joining a channel until leaving voice.
+type listeningSession struct {
+	sync.Mutex
+	active    bool
+	channelID string // voice channel the bot is in, its chat gets the summary
+}
+
+// handleSummarySetting shows or changes whether a summary is posted when the bot leaves voice.
+func (d *Discord) handleSummarySetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting guild settings: %v", err)
+		d.sendTextEmbed(s, m, "Error getting the session summary setting")
+		return
+	}
+
+	if value == "" {
+		state := "off"
+		if settings.SessionSummary {
+			state = "on"
+		}
+		d.sendTextEmbed(s, m, fmt.Sprintf("📊 Session summaries are `%v`\nUse `%vsettings summary [on/off]` to change it", state, d.prefix))
+		return
+	}
+
+	if value != "on" && value != "off" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings summary [on/off]`", d.prefix))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	settings.SessionSummary = value == "on"
+	if err := db.SaveGuildSettings(settings); err != nil {
+		slog.Errorf("Error saving session summary setting: %v", err)
+	}
+
+	text := fmt.Sprintf("📊 Session summaries are `%v`", value)
+	if settings.SessionSummary {
+		text += "\nWhen I leave voice I post what was played in the chat of the voice channel"
+	}
+	d.sendTextEmbed(s, m, text)
+}
+
+// onSessionVoiceState follows the voice channel of the bot, a session starts when it joins voice
+// and ends when it leaves, e.g. on stop or once the queue is done. Plays are attached to the session.
+func (d *Discord) onSessionVoiceState(s *discordgo.Session, e *discordgo.VoiceStateUpdate) {
+	if e.GuildID != d.GuildID || !d.InstanceActive || s.State.User == nil || e.UserID != s.State.User.ID {
+		return
+	}
+
+	d.session.Lock()
+	defer d.session.Unlock()
+
+	h := history.NewHistory()
+
+	if e.ChannelID != "" {
+		switch {
+		case !d.session.active:
+			if _, err := h.StartSession(d.GuildID, e.ChannelID); err != nil {
+				slog.Warnf("Error starting listening session: %v", err)
+			}
+			d.session.active = true
+		case e.ChannelID != d.session.channelID:
+			if err := h.MoveSession(d.GuildID, e.ChannelID); err != nil {
+				slog.Warnf("Error moving listening session: %v", err)
+			}
+		}
+		d.session.channelID = e.ChannelID
+		return
+	}
+
+	if !d.session.active {
+		return
+	}
+	channelID := d.session.channelID
+	d.session.active, d.session.channelID = false, ""
+
+	go d.endActiveParties()
+
+	session, err := h.EndSession(d.GuildID)
+	if err != nil {
+		slog.Warnf("Error ending listening session: %v", err)
+		return
+	}
+	if session != nil {
+		go d.postSessionSummary(session, channelID)
+	}
+}
+
+// postSessionSummary posts how long a session lasted, how many tracks played, who requested most of them
+// and which were skipped, if the guild wants summaries and anything was played.
+func (d *Discord) postSessionSummary(session *db.ListeningSession, channelID string) {
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err != nil {
+		slog.Warnf("Error getting session summary setting: %v", err)
+		return
+	}
+	if !settings.SessionSummary {
+		return
+	}
+
+	h := history.NewHistory()
+
+	plays, err := h.CountSessionPlays(session.ID)
+	if err != nil {
+		slog.Warnf("Error counting plays of the session: %v", err)
+		return
+	}
+	if plays == 0 {
+		return
+	}
+
+	requesters, err := h.GetSessionTopRequesters(session.ID, 1)
+	if err != nil {
+		slog.Warnf("Error getting top requester of the session: %v", err)
+		return
+	}
+
+	skipped, err := h.GetSessionSkippedTracks(session.ID, 0)
+	if err != nil {
+		slog.Warnf("Error getting skipped tracks of the session: %v", err)
+		return
+	}
+
+	l := d.getLocale()
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("📊 **Session summary** `#%v`\n\n", session.ID))
+	builder.WriteString(fmt.Sprintf("Duration: `%v`, %v – %v\n", l.Duration(session.Duration().Seconds()), relativeTimestamp(session.StartedAt), relativeTimestamp(*session.EndedAt)))
+	builder.WriteString(fmt.Sprintf("Tracks played: `%v`\n", plays))
+	if len(requesters) > 0 {
+		builder.WriteString(fmt.Sprintf("Top requester: <@%v> with `%v` tracks\n", requesters[0].UserID, requesters[0].Count))
+	}
+
+	var skips int64
+	for _, track := range skipped {
+		skips += track.Count
+	}
+	builder.WriteString(fmt.Sprintf("Skipped: `%v`\n", skips))
+	for i, track := range skipped {
+		if i == summarySkippedTracks {
+			builder.WriteString(fmt.Sprintf("…and %v more\n", len(skipped)-summarySkippedTracks))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("⏭️ [%v](%v)\n", track.Name, track.URL))
+	}
+
+	summaryEmbed := embed.NewEmbed().
+		SetDescription(builder.String()).
+		SetColor(0x9f00d4).
+		SetFooter(version.AppFullName).MessageEmbed
+
+	// Mentions in embeds don't ping, the requester is only named
+	if _, err := SendEmbed(d.Session, channelID, summaryEmbed); err != nil {
+		slog.Warnf("Error posting session summary: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/player"
+	"github.com/keshon/melodix-discord-player/music/sources"
+)
+
+const (
+	maxTagsPerTrack = 10
+	maxTagTracks    = 100 // tracks queued by playing a tag
+
+	// tagPrefix selects the tracks of a tag in play commands, e.g. "tag:synthwave"
+	tagPrefix = "tag:"
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// handleTagCommand handles the tag command for Discord.
+func (d *Discord) handleTagCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	words := strings.Fields(strings.ToLower(param))
+	if len(words) == 0 || (len(words) == 1 && words[0] == "list") {
+		d.listTags(s, m)
+		return
+	}
+
+	remove := words[0] == "remove"
+	if remove {
+		words = words[1:]
+	}
+
+	if len(words) > 0 {
+		if trackID, err := strconv.ParseUint(words[0], 10, 64); err == nil {
+			switch {
+			case remove && len(words) > 1:
+				d.untagTrack(s, m, uint(trackID), words[1:])
+				return
+			case !remove && len(words) == 1:
+				d.showTrackTags(s, m, uint(trackID))
+				return
+			case !remove:
+				d.tagTrack(s, m, uint(trackID), words[1:])
+				return
+			}
+		}
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vtag`, `%vtag [id]`, `%vtag [id] [tag...]`, `%vtag remove [id] [tag...]`, e.g. `%vtag 5 synthwave`. Play a tag with `%vplay tag:synthwave shuffle`",
+		d.prefix, d.prefix, d.prefix, d.prefix, d.prefix, d.prefix))
+}
+
+// listTags shows the tags of the guild with their number of tracks.
+func (d *Discord) listTags(s *discordgo.Session, m *discordgo.MessageCreate) {
+	tags, err := history.NewHistory().GetTags(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error getting tags: %v", err)
+		d.sendTextEmbed(s, m, "Error getting tags")
+		return
+	}
+
+	if len(tags) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔖 No tags yet, tag a track of `%vhistory` by its ID, e.g. `%vtag 5 synthwave`", d.prefix, d.prefix))
+		return
+	}
+
+	content := "🔖 Tags\n"
+	for _, tag := range tags {
+		content += fmt.Sprintf("\n`%v` — %d tracks", tag.Tag, tag.Count)
+	}
+	content += fmt.Sprintf("\n\nPlay a tag with `%vplay tag:%v`, add `shuffle` to mix it", d.prefix, tags[0].Tag)
+
+	d.sendTextEmbed(s, m, content)
+}
+
+// showTrackTags shows the tags of a track of the history.
+func (d *Discord) showTrackTags(s *discordgo.Session, m *discordgo.MessageCreate, trackID uint) {
+	h := history.NewHistory()
+
+	track, err := h.GetTrackFromHistory(d.ctx, d.GuildID, trackID)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No track with ID `%v` in history", trackID))
+		return
+	}
+
+	tags, err := h.GetTrackTags(d.GuildID, []uint{trackID})
+	if err != nil {
+		slog.Errorf("Error getting tags of track %v: %v", trackID, err)
+		d.sendTextEmbed(s, m, "Error getting tags")
+		return
+	}
+
+	if len(tags[trackID]) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("🔖 [%v](%v) has no tags yet", track.Name, track.URL))
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🔖 [%v](%v)\n\n%v", track.Name, track.URL, formatTags(tags[trackID])))
+}
+
+// tagTrack adds tags to a track of the history.
+func (d *Discord) tagTrack(s *discordgo.Session, m *discordgo.MessageCreate, trackID uint, tags []string) {
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			d.sendTextEmbed(s, m, fmt.Sprintf("Tag `%v` is not allowed, use up to 32 letters, digits and dashes", tag))
+			return
+		}
+	}
+
+	h := history.NewHistory()
+
+	track, err := h.GetTrackFromHistory(d.ctx, d.GuildID, trackID)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No track with ID `%v` in history", trackID))
+		return
+	}
+
+	existing, err := h.GetTrackTags(d.GuildID, []uint{trackID})
+	if err != nil {
+		slog.Errorf("Error getting tags of track %v: %v", trackID, err)
+		d.sendTextEmbed(s, m, "Error tagging the track")
+		return
+	}
+	if len(unionTags(existing[trackID], tags)) > maxTagsPerTrack {
+		d.sendTextEmbed(s, m, fmt.Sprintf("A track can have up to %v tags", maxTagsPerTrack))
+		return
+	}
+
+	for _, tag := range tags {
+		if err := h.TagTrack(d.GuildID, trackID, tag, m.Author.ID); err != nil {
+			slog.Errorf("Error tagging track %v: %v", trackID, err)
+			d.sendTextEmbed(s, m, "Error tagging the track")
+			return
+		}
+	}
+
+	d.sendConfirmation(s, m, "🔖", fmt.Sprintf("Tagged [%v](%v) with %v", track.Name, track.URL, formatTags(tags)))
+}
+
+// untagTrack removes tags from a track of the history.
+func (d *Discord) untagTrack(s *discordgo.Session, m *discordgo.MessageCreate, trackID uint, tags []string) {
+	h := history.NewHistory()
+
+	var removed []string
+	for _, tag := range tags {
+		deleted, err := h.UntagTrack(d.GuildID, trackID, tag)
+		if err != nil {
+			slog.Errorf("Error removing tag of track %v: %v", trackID, err)
+			d.sendTextEmbed(s, m, "Error removing tags")
+			return
+		}
+		if deleted {
+			removed = append(removed, tag)
+		}
+	}
+
+	if len(removed) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Track `%v` has none of these tags", trackID))
+		return
+	}
+
+	d.sendConfirmation(s, m, "🔖", fmt.Sprintf("Removed %v from track `%v`", formatTags(removed), trackID))
+}
+
+// parseTagParameter splits a "tag:name [shuffle]" play parameter, ok is false for other parameters.
+func parseTagParameter(param string) (tag string, shuffle bool, ok bool) {
+	words := strings.Fields(strings.ToLower(param))
+	if len(words) == 0 || len(words) > 2 || !strings.HasPrefix(words[0], tagPrefix) {
+		return "", false, false
+	}
+
+	tag = strings.TrimPrefix(words[0], tagPrefix)
+	if !tagPattern.MatchString(tag) {
+		return "", false, false
+	}
+
+	if len(words) == 2 {
+		if words[1] != "shuffle" {
+			return "", false, false
+		}
+		shuffle = true
+	}
+
+	return tag, shuffle, true
+}
+
+// formatTagCounts lists tags with their play counts.
+func formatTagCounts(tags []db.TagCount) string {
+	var parts []string
+	for _, tag := range tags {
+		parts = append(parts, fmt.Sprintf("`%v` (%d plays)", tag.Tag, tag.Count))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatTags(tags []string) string {
+	return "`" + strings.Join(tags, "` `") + "`"
+}
+
+// unionTags returns the distinct tags of both lists.
+func unionTags(a, b []string) []string {
+	seen := make(map[string]bool)
+	var union []string
+	for _, tag := range append(append([]string{}, a...), b...) {
+		if !seen[tag] {
+			seen[tag] = true
+			union = append(union, tag)
+		}
+	}
+	return union
+}
+
+// fetchTaggedSongs queues the guild's tracks with the tag, most recently played or shuffled first.
+func fetchTaggedSongs(ctx context.Context, guildID, tag string, shuffle bool, youtube *sources.Youtube) ([]*player.Song, error) {
+	trackIDs, err := history.NewHistory().GetTrackIDsByTag(guildID, tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(trackIDs) == 0 {
+		return nil, fmt.Errorf("no tracks tagged %v", tag)
+	}
+
+	if shuffle {
+		rand.Shuffle(len(trackIDs), func(i, j int) {
+			trackIDs[i], trackIDs[j] = trackIDs[j], trackIDs[i]
+		})
+	}
+	if len(trackIDs) > maxTagTracks {
+		trackIDs = trackIDs[:maxTagTracks]
+	}
+
+	return youtube.FetchLightweightSongsByIDs(ctx, guildID, trackIDs)
+}
@@ -0,0 +1,69 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// toggleSetting is a guild setting a command shows without a parameter and changes with one, like `badge on`.
+type toggleSetting struct {
+	command string                            // name of the command, for its usage and the log
+	usage   string                            // parameters of the command, [on/off] if empty
+	show    func() string                     // reply without a parameter
+	parse   func(param string) (string, bool) // value of a parameter, nil accepts on and off
+	check   func(value string) string         // reason to refuse a value, nil or empty to change the setting
+	apply   func(value string)                // changes the setting of the instance
+	store   func(settings *db.GuildSettings)  // copies the setting of the instance to the saved settings
+	changed func(value string) string         // reply once the setting changed
+}
+
+// handleToggleSetting shows or changes a guild setting, changes are saved and only administrators may make them.
+func (d *Discord) handleToggleSetting(s *discordgo.Session, m *discordgo.MessageCreate, param string, setting toggleSetting) {
+	d.changeAvatar(s)
+
+	if param == "" {
+		d.sendTextEmbed(s, m, setting.show())
+		return
+	}
+
+	value, ok := param, param == "on" || param == "off"
+	if setting.parse != nil {
+		value, ok = setting.parse(param)
+	}
+	if !ok {
+		usage := setting.usage
+		if usage == "" {
+			usage = "[on/off]"
+		}
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%v%v %v`", d.prefix, setting.command, usage))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	if setting.check != nil {
+		if refusal := setting.check(value); refusal != "" {
+			d.sendTextEmbed(s, m, refusal)
+			return
+		}
+	}
+
+	setting.apply(value)
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		setting.store(settings)
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving %v setting: %v", setting.command, err)
+	}
+
+	d.sendTextEmbed(s, m, setting.changed(value))
+}
@@ -0,0 +1,208 @@
+package discord
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	// topicCheckInterval is how often the playing track is compared with the topic
+	topicCheckInterval = 30 * time.Second
+	// topicUpdateInterval spaces out topic edits, Discord allows two per channel every 10 minutes
+	topicUpdateInterval = 5 * time.Minute
+	// maxTopicLength is the longest topic of a text channel
+	maxTopicLength = 1024
+)
+
+// channelTopic is the text channel whose topic shows the playing track, see the topic setting.
+type channelTopic struct {
+	sync.Mutex
+	channelID string
+	original  string // topic before the bot changed it, put back once playback stops
+	changed   bool   // the topic shows a track rather than the original
+	current   string // topic last set by the bot
+	updatedAt time.Time
+}
+
+// handleTopicSetting shows or changes the text channel whose topic shows the playing track and the queue length.
+func (d *Discord) handleTopicSetting(s *discordgo.Session, m *discordgo.MessageCreate, value string) {
+	d.topic.Lock()
+	channelID := d.topic.channelID
+	d.topic.Unlock()
+
+	switch value {
+	case "":
+		if channelID == "" {
+			d.sendTextEmbed(s, m, fmt.Sprintf("📝 No channel topic shows the playing track\nUse `%vsettings topic here` in the text channel that should", d.prefix))
+		} else {
+			d.sendTextEmbed(s, m, fmt.Sprintf("📝 The topic of <#%v> shows the playing track\nUse `%vsettings topic off` to stop", channelID, d.prefix))
+		}
+		return
+	case "here", "off":
+	default:
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vsettings topic [here/off]`", d.prefix))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change this setting")
+		return
+	}
+
+	if value == "here" && !canManageChannel(s, m.ChannelID) {
+		d.sendTextEmbed(s, m, "⚠️ I need the **Manage Channels** permission in this channel to change its topic")
+		return
+	}
+
+	// The topic of the channel left behind gets its original back
+	d.restoreTopic(true)
+
+	d.topic.Lock()
+	d.topic.channelID = ""
+	if value == "here" {
+		d.topic.channelID = m.ChannelID
+	}
+	d.topic.Unlock()
+	d.saveTopicSettings()
+
+	if value == "off" {
+		d.sendTextEmbed(s, m, "📝 Channel topic updates disabled")
+		return
+	}
+	d.sendTextEmbed(s, m, "📝 The topic of this channel now shows the playing track and the queue length, and gets its original back once playback stops.\nDiscord allows few topic changes, so it's updated at most every 5 minutes.")
+}
+
+// runTopicUpdater keeps the topic of the bound channel in line with the playing track while the instance is active.
+func (d *Discord) runTopicUpdater() {
+	ticker := time.NewTicker(topicCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !d.InstanceActive {
+			return
+		}
+		d.updateTopic()
+	}
+}
+
+// updateTopic shows the playing track and the queue length in the topic of the bound channel,
+// or puts its original topic back once nothing plays. Edits are spaced out by topicUpdateInterval.
+func (d *Discord) updateTopic() {
+	song := d.Player.GetCurrentSong()
+	if song == nil {
+		d.restoreTopic(false)
+		return
+	}
+
+	d.topic.Lock()
+	channelID := d.topic.channelID
+	if channelID == "" || time.Since(d.topic.updatedAt) < topicUpdateInterval {
+		d.topic.Unlock()
+		return
+	}
+
+	topic := fmt.Sprintf("🎶 Now playing: %v", song.Title)
+	if queued := len(d.Player.GetSongQueue()); queued > 0 {
+		topic += fmt.Sprintf(" · %v in queue", queued)
+	}
+	if runes := []rune(topic); len(runes) > maxTopicLength {
+		topic = string(runes[:maxTopicLength-1]) + "…"
+	}
+	if d.topic.changed && topic == d.topic.current {
+		d.topic.Unlock()
+		return
+	}
+
+	if !canManageChannel(d.Session, channelID) {
+		d.topic.Unlock()
+		slog.Debugf("Not updating topic of channel %v, Manage Channels permission is missing", channelID)
+		return
+	}
+
+	firstChange := !d.topic.changed
+	if firstChange {
+		channel, err := d.Session.Channel(channelID)
+		if err != nil {
+			d.topic.Unlock()
+			slog.Warnf("Error getting channel %v: %v", channelID, err)
+			return
+		}
+		d.topic.original = channel.Topic
+	}
+
+	d.topic.updatedAt = time.Now()
+	if err := setChannelTopic(d.Session, channelID, topic); err != nil {
+		d.topic.Unlock()
+		slog.Warnf("Error updating topic of channel %v: %v", channelID, err)
+		return
+	}
+	d.topic.changed, d.topic.current = true, topic
+	d.topic.Unlock()
+
+	// The original is kept in the settings so a restart can still put it back
+	if firstChange {
+		d.saveTopicSettings()
+	}
+}
+
+// restoreTopic puts the original topic of the bound channel back if the bot changed it. Unless forced,
+// e.g. when the channel is unbound, it waits for topicUpdateInterval since the last edit.
+func (d *Discord) restoreTopic(force bool) {
+	d.topic.Lock()
+	if !d.topic.changed || d.topic.channelID == "" || (!force && time.Since(d.topic.updatedAt) < topicUpdateInterval) {
+		d.topic.Unlock()
+		return
+	}
+
+	d.topic.updatedAt = time.Now()
+	if err := setChannelTopic(d.Session, d.topic.channelID, d.topic.original); err != nil {
+		d.topic.Unlock()
+		slog.Warnf("Error restoring topic of channel %v: %v", d.topic.channelID, err)
+		return
+	}
+	d.topic.changed, d.topic.current, d.topic.original = false, "", ""
+	d.topic.Unlock()
+
+	d.saveTopicSettings()
+}
+
+// saveTopicSettings stores the bound channel and, while the bot changed it, its original topic.
+func (d *Discord) saveTopicSettings() {
+	d.topic.Lock()
+	channelID, original, changed := d.topic.channelID, d.topic.original, d.topic.changed
+	d.topic.Unlock()
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.TopicChannelID = channelID
+		settings.TopicOriginal = original
+		settings.TopicChanged = changed
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving channel topic setting: %v", err)
+	}
+}
+
+// setChannelTopic changes the topic of a text channel, an empty topic removes it.
+func setChannelTopic(s *discordgo.Session, channelID, topic string) error {
+	_, err := s.RequestWithBucketID(http.MethodPatch, discordgo.EndpointChannel(channelID), map[string]interface{}{"topic": topic}, discordgo.EndpointChannel(channelID))
+	return err
+}
+
+// canManageChannel reports whether the bot may edit the channel.
+func canManageChannel(s *discordgo.Session, channelID string) bool {
+	perms, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+	if err != nil {
+		slog.Warnf("Error getting bot permissions: %v", err)
+		return false
+	}
+
+	return perms&discordgo.PermissionAdministrator != 0 || perms&discordgo.PermissionManageChannels != 0
+}
@@ -0,0 +1,56 @@
+package discord
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+)
+
+// commandTraces keeps the spans of running commands by message ID, so the lookups they start join their trace.
+type commandTraces struct {
+	sync.Mutex
+	spans map[string]tracing.SpanContext
+}
+
+// traceCommand runs a command within a span of the command.
+func (d *Discord) traceCommand(m *discordgo.MessageCreate, name, parameter string, run func()) {
+	_, span := tracing.Start(d.ctx, "command "+name,
+		tracing.String("command.name", name),
+		tracing.String("command.parameter", parameter),
+		tracing.String("guild.id", d.GuildID),
+		tracing.String("user.id", m.Author.ID))
+	defer span.End()
+
+	if span == nil {
+		run()
+		return
+	}
+
+	d.traces.Lock()
+	if d.traces.spans == nil {
+		d.traces.spans = make(map[string]tracing.SpanContext)
+	}
+	d.traces.spans[m.Message.ID] = span.Context()
+	d.traces.Unlock()
+
+	defer func() {
+		d.traces.Lock()
+		delete(d.traces.spans, m.Message.ID)
+		d.traces.Unlock()
+	}()
+
+	run()
+}
+
+// commandContext returns the instance context within the span of the command made by the message,
+// if it's still running.
+func (d *Discord) commandContext(messageID string) context.Context {
+	d.traces.Lock()
+	parent := d.traces.spans[messageID]
+	d.traces.Unlock()
+
+	return tracing.WithParent(d.ctx, parent)
+}
@@ -0,0 +1,279 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	maxMacroTriggers = 10
+	maxVoiceMembers  = 99
+	// dailyTriggerInterval is how often daily triggers are checked, well below a minute so none is missed
+	dailyTriggerInterval = 15 * time.Second
+)
+
+// addMacroTrigger makes a macro run daily at a time ("at") or when the author's voice channel reaches a number of members ("when").
+// Replies of the macro go to the channel of the command, and it runs with the author's permissions.
+func (d *Discord) addMacroTrigger(s *discordgo.Session, m *discordgo.MessageCreate, kind, name, value string) {
+	if d.macroSteps(name) == nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No macro `%v`, see `%vmacro list`", name, d.prefix))
+		return
+	}
+
+	d.triggersMu.RLock()
+	count := len(d.triggers)
+	d.triggersMu.RUnlock()
+	if count >= maxMacroTriggers {
+		d.sendTextEmbed(s, m, fmt.Sprintf("This server has the maximum of %d triggers, remove one with `%vmacro untrigger [id]`", maxMacroTriggers, d.prefix))
+		return
+	}
+
+	trigger := &db.MacroTrigger{GuildID: d.GuildID, Macro: name, ChannelID: m.ChannelID, CreatedBy: m.Author.ID}
+
+	voiceChannelID := ""
+	if guild, err := s.State.Guild(d.GuildID); err == nil {
+		if vs, found := findUserVoiceState(m.Author.ID, guild.VoiceStates); found {
+			voiceChannelID = vs.ChannelID
+		}
+	}
+	trigger.VoiceChannelID = voiceChannelID
+
+	var description string
+	switch kind {
+	case "at":
+		at, err := time.Parse("15:04", value)
+		if err != nil {
+			d.sendTextEmbed(s, m, fmt.Sprintf("Invalid time `%v`, use HH:MM like `20:00`", value))
+			return
+		}
+		trigger.Kind = db.TriggerDaily
+		trigger.At = at.Format("15:04")
+		description = d.describeMacroTrigger(trigger)
+	case "when":
+		members, err := strconv.Atoi(value)
+		if err != nil || members < 1 || members > maxVoiceMembers {
+			d.sendTextEmbed(s, m, fmt.Sprintf("Invalid number of members `%v`, use 1 to %d", value, maxVoiceMembers))
+			return
+		}
+		if voiceChannelID == "" {
+			d.sendTextEmbed(s, m, "Join the voice channel the trigger should watch first")
+			return
+		}
+		trigger.Kind = db.TriggerVoice
+		trigger.Members = members
+		description = d.describeMacroTrigger(trigger)
+	}
+
+	if err := db.CreateMacroTrigger(trigger); err != nil {
+		slog.Errorf("Error creating trigger of macro %v: %v", name, err)
+		d.sendTextEmbed(s, m, "Error saving trigger")
+		return
+	}
+	d.loadMacroTriggers()
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("⏰ `%v%v` %v\nIt runs as you in this channel, `%vmacro untrigger %d` removes it", d.prefix, name, description, d.prefix, trigger.ID))
+}
+
+// removeMacroTrigger deletes a trigger of the guild by its ID.
+func (d *Discord) removeMacroTrigger(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Invalid trigger ID `%v`, see `%vmacro triggers`", param, d.prefix))
+		return
+	}
+
+	deleted, err := db.DeleteMacroTrigger(d.GuildID, uint(id))
+	if err != nil {
+		slog.Errorf("Error deleting trigger %v: %v", id, err)
+		d.sendTextEmbed(s, m, "Error removing trigger")
+		return
+	}
+	if deleted == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("No trigger `%v`, see `%vmacro triggers`", id, d.prefix))
+		return
+	}
+	d.loadMacroTriggers()
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("⏰ Removed trigger `%v`", id))
+}
+
+// listMacroTriggers shows the triggers of the guild's macros.
+func (d *Discord) listMacroTriggers(s *discordgo.Session, m *discordgo.MessageCreate) {
+	d.triggersMu.RLock()
+	triggers := append([]db.MacroTrigger(nil), d.triggers...)
+	d.triggersMu.RUnlock()
+
+	if len(triggers) == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("⏰ No triggers yet, use `%vmacro at [name] [HH:MM]` or `%vmacro when [name] [members]` to add one", d.prefix, d.prefix))
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString("⏰ Macro triggers\n")
+	for _, trigger := range triggers {
+		builder.WriteString(fmt.Sprintf("` %d ` `%v%v` %v, by <@%v>", trigger.ID, d.prefix, trigger.Macro, d.describeMacroTrigger(&trigger), trigger.CreatedBy))
+		if !trigger.LastRunAt.IsZero() {
+			builder.WriteString(", ran " + relativeTimestamp(trigger.LastRunAt))
+		}
+		builder.WriteString("\n")
+	}
+	d.sendTextEmbed(s, m, builder.String())
+}
+
+func (d *Discord) describeMacroTrigger(trigger *db.MacroTrigger) string {
+	if trigger.Kind == db.TriggerVoice {
+		return fmt.Sprintf("runs when <#%v> reaches %d members", trigger.VoiceChannelID, trigger.Members)
+	}
+
+	text := fmt.Sprintf("runs daily at %v (%v)", trigger.At, d.getLocale().Location)
+	if trigger.VoiceChannelID != "" {
+		text += fmt.Sprintf(" in <#%v>", trigger.VoiceChannelID)
+	}
+	return text
+}
+
+// loadMacroTriggers reads the macro triggers of the guild. Voice triggers are armed if their channel
+// has fewer members than they wait for, so a restart doesn't run them for a channel that's full already.
+func (d *Discord) loadMacroTriggers() {
+	triggers, err := db.GetMacroTriggersByGuildID(d.GuildID)
+	if err != nil {
+		slog.Errorf("Error loading macro triggers: %v", err)
+	}
+
+	armed := make(map[uint]bool)
+	for _, trigger := range triggers {
+		if trigger.Kind == db.TriggerVoice {
+			armed[trigger.ID] = d.voiceChannelMembers(trigger.VoiceChannelID) < trigger.Members
+		}
+	}
+
+	d.triggersMu.Lock()
+	d.triggers = triggers
+	d.armedTriggers = armed
+	d.triggersMu.Unlock()
+}
+
+// runDailyTriggers runs daily macro triggers when their time of the guild's timezone comes and starts listening parties
+// that are due, until the instance is deactivated.
+func (d *Discord) runDailyTriggers() {
+	ticker := time.NewTicker(dailyTriggerInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !d.InstanceActive {
+			return
+		}
+
+		now := time.Now().In(d.getLocale().Location)
+		minute := now.Truncate(time.Minute)
+
+		var due []db.MacroTrigger
+		d.triggersMu.Lock()
+		for i := range d.triggers {
+			trigger := &d.triggers[i]
+			if trigger.Kind == db.TriggerDaily && trigger.At == now.Format("15:04") && trigger.LastRunAt.Before(minute) {
+				trigger.LastRunAt = now
+				due = append(due, *trigger)
+			}
+		}
+		d.triggersMu.Unlock()
+
+		for _, trigger := range due {
+			go d.fireMacroTrigger(trigger, "⏰ "+trigger.At)
+		}
+
+		d.startDueParties(now)
+	}
+}
+
+// onVoiceStateUpdate runs voice macro triggers whose channel reached the number of members they wait for.
+// A trigger runs once until its channel has fewer members again.
+func (d *Discord) onVoiceStateUpdate(s *discordgo.Session, e *discordgo.VoiceStateUpdate) {
+	if e.GuildID != d.GuildID || !d.InstanceActive {
+		return
+	}
+
+	var due []db.MacroTrigger
+	d.triggersMu.Lock()
+	for _, trigger := range d.triggers {
+		if trigger.Kind != db.TriggerVoice {
+			continue
+		}
+
+		members := d.voiceChannelMembers(trigger.VoiceChannelID)
+		switch {
+		case members < trigger.Members:
+			d.armedTriggers[trigger.ID] = true
+		case d.armedTriggers[trigger.ID]:
+			d.armedTriggers[trigger.ID] = false
+			due = append(due, trigger)
+		}
+	}
+	d.triggersMu.Unlock()
+
+	for _, trigger := range due {
+		go d.fireMacroTrigger(trigger, fmt.Sprintf("🎧 <#%v> reached %d members", trigger.VoiceChannelID, trigger.Members))
+	}
+}
+
+// voiceChannelMembers counts the members in a voice channel who aren't bots.
+func (d *Discord) voiceChannelMembers(channelID string) int {
+	guild, err := d.Session.State.Guild(d.GuildID)
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != channelID {
+			continue
+		}
+		if member, err := d.Session.State.Member(d.GuildID, vs.UserID); err == nil && member.User != nil && member.User.Bot {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// isTriggered reports whether the message stands in for a member whose macro a trigger runs.
+// No message was sent for it, so it has no ID.
+func isTriggered(m *discordgo.MessageCreate) bool {
+	return m.Message.ID == ""
+}
+
+// fireMacroTrigger runs the macro of a trigger as if its creator sent the commands to the trigger's channel.
+// The bot joins the trigger's voice channel first, so playing works without the creator in a voice channel.
+func (d *Discord) fireMacroTrigger(trigger db.MacroTrigger, reason string) {
+	steps := d.macroSteps(trigger.Macro)
+	if steps == nil {
+		slog.Warnf("Skipping trigger %v, macro %v doesn't exist anymore", trigger.ID, trigger.Macro)
+		return
+	}
+
+	if err := db.SetMacroTriggerLastRun(trigger.ID, time.Now()); err != nil {
+		slog.Errorf("Error saving last run of trigger %v: %v", trigger.ID, err)
+	}
+
+	s := d.Session
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: trigger.ChannelID,
+		GuildID:   d.GuildID,
+		Author:    &discordgo.User{ID: trigger.CreatedBy},
+	}}
+
+	if trigger.VoiceChannelID != "" && d.Player.GetVoiceConnection() == nil {
+		if err := d.connectVoice(trigger.VoiceChannelID); err != nil {
+			slog.Errorf("Error joining voice channel %v for trigger %v: %v", trigger.VoiceChannelID, trigger.ID, err)
+		}
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("🎬 Running `%v%v`: %v", d.prefix, trigger.Macro, reason))
+	d.runMacro(s, m, trigger.Macro, steps)
+}
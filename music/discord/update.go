@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/selfupdate"
+	"github.com/keshon/melodix-discord-player/internal/version"
+)
+
+// updateTimeout bounds checking, downloading and installing an update.
+const updateTimeout = 5 * time.Minute
+
+// Updater installs updates and restarts the process. The instances of all guilds share one, so owners of
+// several guilds can't start two updates at once.
+type Updater struct {
+	restart  func()      // stops the process gracefully and starts the installed binary
+	updating atomic.Bool // set while an update is installed
+}
+
+// NewUpdater creates an updater restarting the process with restart once an update is installed,
+// nil restart if the process can't restart itself.
+func NewUpdater(restart func()) *Updater {
+	return &Updater{restart: restart}
+}
+
+// handleUpdateCommand shows whether a newer release is available, or installs it and restarts.
+func (d *Discord) handleUpdateCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	if param != "" && param != "install" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vupdate [install]`", d.prefix))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, updateTimeout)
+	defer cancel()
+
+	latest, err := version.CheckLatestRelease(ctx)
+	if err != nil {
+		slog.Errorf("Error checking for updates: %v", err)
+		d.sendTextEmbed(s, m, fmt.Sprintf("Error checking for updates: `%v`", err))
+		return
+	}
+
+	if !latest.UpdateAvailable() {
+		d.sendTextEmbed(s, m, fmt.Sprintf("✅ %v %v is the latest release", version.AppName, version.Current()))
+		return
+	}
+
+	if param == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("⬆️ %v %v is available, this instance runs %v\n%v\nUse `%vupdate install` to install it and restart",
+			version.AppName, latest.Version, version.Current(), latest.URL, d.prefix))
+		return
+	}
+
+	if d.updater == nil || d.updater.restart == nil {
+		d.sendTextEmbed(s, m, "This process can't restart itself, update it the way it was deployed")
+		return
+	}
+	if !d.updater.updating.CompareAndSwap(false, true) {
+		d.sendTextEmbed(s, m, "An update is already being installed")
+		return
+	}
+
+	d.sendTextEmbed(s, m, fmt.Sprintf("⬇️ Downloading and verifying %v %v for %v…", version.AppName, latest.Version, selfupdate.Platform()))
+
+	if err := selfupdate.Install(ctx, latest); err != nil {
+		d.updater.updating.Store(false)
+		slog.Errorf("Error installing update %v: %v", latest.Version, err)
+
+		text := fmt.Sprintf("Error installing the update: `%v`", err)
+		switch {
+		case errors.Is(err, selfupdate.ErrNoAsset):
+			text += fmt.Sprintf("\nThe release has no binary for this platform, download it from %v", latest.URL)
+		case errors.Is(err, selfupdate.ErrNoPublicKey):
+			text += fmt.Sprintf("\nThis build can't verify releases, download it from %v", latest.URL)
+		}
+		d.sendTextEmbed(s, m, text)
+		return
+	}
+
+	slog.Infof("Installed %v %v, restarting", version.AppName, latest.Version)
+	d.sendTextEmbed(s, m, fmt.Sprintf("✅ Installed %v %v, restarting. Playing queues are saved and continue once I'm back, if somebody is still in their voice channel", version.AppName, latest.Version))
+	d.updater.restart()
+}
@@ -0,0 +1,118 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// Verbosity levels of routine confirmations.
+const (
+	VerbosityQuiet   = "quiet"   // react to the command with an emoji instead of posting an embed
+	VerbosityNormal  = "normal"  // post a short embed
+	VerbosityVerbose = "verbose" // post a short embed with player diagnostics
+)
+
+// VerbosityNames lists available verbosity levels.
+var VerbosityNames = []string{VerbosityQuiet, VerbosityNormal, VerbosityVerbose}
+
+// handleVerbosityCommand handles the verbosity command for Discord.
+func (d *Discord) handleVerbosityCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	available := "`" + strings.Join(VerbosityNames, "`, `") + "`"
+
+	if param == "" {
+		d.sendTextEmbed(s, m, fmt.Sprintf("💬 Verbosity is `%v`\nUse `%vverbosity [level]` to change it, available: %v", d.getVerbosity(), d.prefix, available))
+		return
+	}
+
+	if !isVerbosity(param) {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Unknown verbosity `%v`, available: %v", param, available))
+		return
+	}
+
+	if !d.HasAdminPermission(s, m) {
+		d.sendTextEmbed(s, m, "Only server administrators can change verbosity")
+		return
+	}
+
+	d.verbosity = param
+
+	settings, err := db.GetGuildSettings(d.GuildID)
+	if err == nil {
+		settings.Verbosity = param
+		err = db.SaveGuildSettings(settings)
+	}
+	if err != nil {
+		slog.Errorf("Error saving verbosity: %v", err)
+	}
+
+	// Always answer with an embed so the change is visible even in quiet mode
+	d.sendTextEmbed(s, m, fmt.Sprintf("💬 Verbosity set to `%v`", param))
+}
+
+// sendConfirmation acknowledges a routine command according to the guild verbosity.
+// Quiet mode reacts to the command message with the emoji, other modes post an embed.
+func (d *Discord) sendConfirmation(s *discordgo.Session, m *discordgo.MessageCreate, emoji, text string) *discordgo.Message {
+	switch d.getVerbosity() {
+	case VerbosityQuiet:
+		// Slash commands have no command message to react to, only their author sees the confirmation
+		if answerSlash(s, m, emoji+" "+text) {
+			return nil
+		}
+		// Macros run by triggers have no command message to react to
+		if isTriggered(m) {
+			return nil
+		}
+		if err := s.MessageReactionAdd(m.Message.ChannelID, m.Message.ID, emoji); err != nil {
+			slog.Warnf("Error adding reaction: %v", err)
+		}
+		return nil
+	case VerbosityVerbose:
+		text += "\n\n" + d.diagnostics(s)
+	}
+
+	embedMsg := embed.NewEmbed().
+		SetDescription(emoji + " " + text).
+		SetColor(0x9f00d4).MessageEmbed
+
+	msg, err := SendEmbed(s, m.Message.ChannelID, embedMsg)
+	if err != nil {
+		slog.Warnf("Error sending message: %v", err)
+	}
+	return msg
+}
+
+// diagnostics describes the player state for verbose confirmations.
+func (d *Discord) diagnostics(s *discordgo.Session) string {
+	current := "none"
+	if song := d.Player.GetCurrentSong(); song != nil {
+		current = song.Title
+	}
+
+	return fmt.Sprintf("_Status: %v · Current: %v · Queue: %v (%v order) · Gateway latency: %v_",
+		d.Player.GetCurrentStatus().String(), current, len(d.Player.GetSongQueue()), d.Player.GetQueueStrategy().Name(), s.HeartbeatLatency().Round(time.Millisecond))
+}
+
+// getVerbosity returns the verbosity of the guild, normal if not set.
+func (d *Discord) getVerbosity() string {
+	if d.verbosity == "" {
+		return VerbosityNormal
+	}
+	return d.verbosity
+}
+
+func isVerbosity(name string) bool {
+	for _, verbosity := range VerbosityNames {
+		if verbosity == name {
+			return true
+		}
+	}
+	return false
+}
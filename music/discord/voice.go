@@ -0,0 +1,83 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ErrNotVoiceChannel is returned when joining a channel that isn't a voice channel of the guild.
+var ErrNotVoiceChannel = errors.New("not a voice channel of this guild")
+
+// JoinVoiceChannel connects the player to the voice channel, moving it if it's already connected elsewhere.
+func (d *Discord) JoinVoiceChannel(channelID string) error {
+	channel, err := d.Session.State.Channel(channelID)
+	if err != nil {
+		channel, err = d.Session.Channel(channelID)
+		if err != nil {
+			return fmt.Errorf("error getting channel: %w", err)
+		}
+	}
+
+	if channel.GuildID != d.GuildID || (channel.Type != discordgo.ChannelTypeGuildVoice && channel.Type != discordgo.ChannelTypeGuildStageVoice) {
+		return ErrNotVoiceChannel
+	}
+
+	if vc := d.Player.GetVoiceConnection(); vc != nil {
+		if vc.ChannelID == channelID {
+			return nil
+		}
+		if err := vc.ChangeChannel(channelID, false, !d.ducking); err != nil {
+			return err
+		}
+		d.speakOnStage(channelID)
+		return nil
+	}
+
+	if err := d.connectVoice(channelID); err != nil {
+		return fmt.Errorf("error connecting to voice channel: %w", err)
+	}
+
+	return nil
+}
+
+// connectVoice connects the player to a voice channel and greets it. With ducking on the bot joins
+// undeafened, it has to hear the channel to notice people talking. On a stage it becomes a speaker.
+func (d *Discord) connectVoice(channelID string) error {
+	conn, err := d.Session.ChannelVoiceJoin(d.GuildID, channelID, false, !d.ducking)
+	if err != nil {
+		return err
+	}
+	conn.LogLevel = discordgo.LogWarning
+	d.Player.SetVoiceConnection(conn)
+
+	if d.ducking {
+		go d.duckUnderVoices(conn)
+	}
+
+	d.speakOnStage(channelID)
+
+	d.greet(channelID)
+
+	return nil
+}
+
+// LeaveVoiceChannel stops playback and disconnects the player from voice.
+// It reports false if the player wasn't connected.
+func (d *Discord) LeaveVoiceChannel() bool {
+	if d.Player.GetVoiceConnection() == nil {
+		return false
+	}
+
+	d.Player.Stop()
+	return true
+}
+
+// StopPlayback calls off pending lookups, stops playback and leaves voice like the exit command,
+// for remote controls without a channel to answer in.
+func (d *Discord) StopPlayback() {
+	d.cancelLookups()
+	d.Player.Stop()
+	d.stopNowPlaying()
+}
@@ -0,0 +1,150 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	embed "github.com/Clinet/discordgo-embed"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+	"github.com/keshon/melodix-discord-player/internal/version"
+	"github.com/keshon/melodix-discord-player/music/history"
+	"github.com/keshon/melodix-discord-player/music/stats"
+)
+
+const (
+	wrappedTopTracks = 5 // tracks listed in the yearly recap
+	wrappedTopTags   = 3
+)
+
+// handleWrappedCommand handles the wrapped command for Discord.
+func (d *Discord) handleWrappedCommand(s *discordgo.Session, m *discordgo.MessageCreate, param string) {
+	d.changeAvatar(s)
+
+	year, userID, ok := parseWrappedParameter(param, m.Author.ID)
+	if !ok {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Usage: `%vwrapped [year] [me]`", d.prefix))
+		return
+	}
+
+	l := d.getLocale()
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, l.Location)
+	to := from.AddDate(1, 0, 0)
+
+	h := history.NewHistory()
+
+	plays, err := h.CountPlays(d.GuildID, userID, from, to)
+	if err != nil {
+		slog.Errorf("Error counting plays: %v", err)
+		d.sendTextEmbed(s, m, "Error building the yearly recap")
+		return
+	}
+
+	topTracks, err := h.GetTopTracks(d.GuildID, userID, from, to, wrappedTopTracks)
+	if err != nil {
+		slog.Errorf("Error getting top tracks: %v", err)
+		d.sendTextEmbed(s, m, "Error building the yearly recap")
+		return
+	}
+
+	mostSkipped, err := h.GetTopSkippedTracks(d.GuildID, userID, from, to, 1)
+	if err != nil {
+		slog.Errorf("Error getting skipped tracks: %v", err)
+		d.sendTextEmbed(s, m, "Error building the yearly recap")
+		return
+	}
+
+	topTags, err := h.GetTopTags(d.GuildID, userID, from, to, wrappedTopTags)
+	if err != nil {
+		slog.Errorf("Error getting top tags: %v", err)
+		d.sendTextEmbed(s, m, "Error building the yearly recap")
+		return
+	}
+
+	if plays == 0 {
+		d.sendTextEmbed(s, m, fmt.Sprintf("Nothing was played in %v yet", year))
+		return
+	}
+
+	var summary string
+	if userID != "" {
+		summary = fmt.Sprintf("🎁 **%v Wrapped for %v**\n\n", year, m.Author.Username)
+		summary += fmt.Sprintf("Tracks requested: `%v`\n", plays)
+	} else {
+		// Listening time is recorded per guild only, so it's left out of personal recaps
+		activity, err := h.GetListeningActivityBetween(d.GuildID, from, to)
+		if err != nil {
+			slog.Errorf("Error getting listening activity: %v", err)
+			d.sendTextEmbed(s, m, "Error building the yearly recap")
+			return
+		}
+
+		summary = fmt.Sprintf("🎁 **%v Wrapped**\n\n", year)
+		summary += fmt.Sprintf("Tracks played: `%v`\n", plays)
+		summary += fmt.Sprintf("Total listening time: `%.1f hours`\n", stats.TotalSeconds(activity)/3600)
+
+		if streak := stats.LongestStreak(activity); streak.Hours > 0 {
+			summary += fmt.Sprintf("Longest session: `%v` on %v (%v hours in a row)\n", l.Duration(streak.Seconds), l.Date(streak.Start), streak.Hours)
+		}
+	}
+
+	if len(topTags) > 0 {
+		summary += "Top tags: " + formatTagCounts(topTags) + "\n"
+	}
+
+	if len(mostSkipped) > 0 {
+		summary += fmt.Sprintf("Most skipped: [%v](%v) — `%v` skips\n", mostSkipped[0].Name, mostSkipped[0].URL, mostSkipped[0].Count)
+	}
+
+	summaryEmbed := embed.NewEmbed().
+		SetDescription(summary).
+		SetColor(0x9f00d4).MessageEmbed
+
+	tracksEmbed := embed.NewEmbed().
+		SetDescription("🏆 **Top tracks**\n\n" + formatTopTracks(topTracks)).
+		SetColor(0x9f00d4).SetFooter(version.AppFullName).MessageEmbed
+
+	_, err = SendComplex(s, m.Message.ChannelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{summaryEmbed, tracksEmbed},
+	})
+	if err != nil {
+		slog.Warnf("Error sending wrapped message: %v", err)
+	}
+}
+
+// parseWrappedParameter parses the optional year and "me" flag of the wrapped command.
+// It returns the requested year, the user ID to narrow the recap to (empty for the whole guild)
+// and whether the parameter was valid.
+func parseWrappedParameter(param, authorID string) (int, string, bool) {
+	year := time.Now().Year()
+	userID := ""
+
+	for _, field := range strings.Fields(param) {
+		if field == "me" {
+			userID = authorID
+			continue
+		}
+
+		parsed, err := strconv.Atoi(field)
+		if err != nil || parsed < 2000 || parsed > year {
+			return 0, "", false
+		}
+		year = parsed
+	}
+
+	return year, userID, true
+}
+
+// formatTopTracks lists tracks with their play counts.
+func formatTopTracks(tracks []db.TrackRequestCount) string {
+	var builder strings.Builder
+
+	for i, track := range tracks {
+		builder.WriteString(fmt.Sprintf("%v. [%v](%v) — `%v` plays, last %v\n", i+1, track.Name, track.URL, track.Count, relativeTimestamp(track.LastPlayed)))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,163 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+const (
+	// maxBufferedWrites limits the writes kept while the database is unavailable, the oldest are dropped
+	maxBufferedWrites = 10000
+	// recoveryInterval is how often an unavailable database is checked
+	recoveryInterval = 30 * time.Second
+)
+
+// AvailabilityHandler is told when the database becomes unavailable and when it recovered,
+// with the number of writes buffered meanwhile.
+type AvailabilityHandler func(available bool, buffered int)
+
+// bufferedWrite is a history write waiting for the database to recover.
+type bufferedWrite struct {
+	id          uint64 // tells the write apart when the oldest writes are dropped while it's replayed
+	description string
+	write       func() error
+}
+
+// writeBuffer keeps history writes while the database is unavailable and replays them in order once it recovered,
+// so playback keeps going and its statistics aren't lost.
+type writeBuffer struct {
+	sync.Mutex
+	unavailable  bool
+	writes       []bufferedWrite
+	nextID       uint64
+	dropped      int // since the outage started
+	droppedTotal int // since start, including writes that failed when replayed
+	handler      AvailabilityHandler
+}
+
+var buffer = &writeBuffer{}
+
+// SetAvailabilityHandler sets the handler told about database outages, e.g. to notify the bot owner.
+func SetAvailabilityHandler(handler AvailabilityHandler) {
+	buffer.Lock()
+	defer buffer.Unlock()
+
+	buffer.handler = handler
+}
+
+// Unavailable reports whether the database is unavailable and history writes are buffered.
+func Unavailable() bool {
+	buffer.Lock()
+	defer buffer.Unlock()
+
+	return buffer.unavailable
+}
+
+// bufferedWriteOf runs a history write, or keeps it for later while the database is unavailable.
+// Buffered writes report no error, they are replayed once the database recovered.
+func bufferedWriteOf(description string, write func() error) error {
+	buffer.Lock()
+	if buffer.unavailable {
+		buffer.add(description, write)
+		buffer.Unlock()
+		return nil
+	}
+	buffer.Unlock()
+
+	err := write()
+	if err == nil || !db.IsUnavailable(err) {
+		return err
+	}
+
+	buffer.Lock()
+	buffer.add(description, write)
+	outage := !buffer.unavailable
+	buffer.unavailable = true
+	handler := buffer.handler
+	buffer.Unlock()
+
+	if outage {
+		slog.Errorf("Database unavailable, buffering history writes until it recovers: %v", err)
+		if handler != nil {
+			go handler(false, 0)
+		}
+		go buffer.recover()
+	}
+	return nil
+}
+
+// add keeps a write, must be called with the buffer lock held.
+func (b *writeBuffer) add(description string, write func() error) {
+	b.nextID++
+	b.writes = append(b.writes, bufferedWrite{id: b.nextID, description: description, write: write})
+	if len(b.writes) > maxBufferedWrites {
+		b.writes = b.writes[1:]
+		b.dropped++
+		b.droppedTotal++
+	}
+}
+
+// remove removes a replayed write, unless it was dropped meanwhile. Must be called with the buffer lock held.
+func (b *writeBuffer) remove(id uint64) {
+	for i, write := range b.writes {
+		if write.id == id {
+			b.writes = append(b.writes[:i], b.writes[i+1:]...)
+			return
+		}
+	}
+}
+
+// countDropped counts a write that failed and was given up.
+func (b *writeBuffer) countDropped() {
+	b.Lock()
+	b.droppedTotal++
+	b.Unlock()
+}
+
+// recover waits for the database to recover, then replays the buffered writes in order.
+func (b *writeBuffer) recover() {
+	replayed := 0
+	for {
+		time.Sleep(recoveryInterval)
+		if err := db.Ping(); err != nil {
+			continue
+		}
+
+		for {
+			b.Lock()
+			if len(b.writes) == 0 {
+				b.unavailable = false
+				dropped, handler := b.dropped, b.handler
+				b.dropped = 0
+				b.Unlock()
+
+				slog.Infof("Database recovered, replayed %d history writes, %d were dropped", replayed, dropped)
+				if handler != nil {
+					handler(true, replayed)
+				}
+				return
+			}
+			next := b.writes[0]
+			b.Unlock()
+
+			err := next.write()
+			if db.IsUnavailable(err) {
+				// Still down, try again later
+				break
+			}
+			b.Lock()
+			b.remove(next.id)
+			if err != nil {
+				b.droppedTotal++
+			}
+			b.Unlock()
+			if err != nil {
+				slog.Warnf("Error replaying %v: %v", next.description, err)
+			}
+			replayed++
+		}
+	}
+}
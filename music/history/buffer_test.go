@@ -0,0 +1,31 @@
+package history
+
+import "testing"
+
+func TestBufferRemovesReplayedWrite(t *testing.T) {
+	b := &writeBuffer{}
+	for i := 0; i < maxBufferedWrites; i++ {
+		b.add("write", func() error { return nil })
+	}
+
+	// The oldest write is replayed while a new write drops it from the full buffer
+	replayed := b.writes[0]
+	b.add("newest", func() error { return nil })
+	if b.dropped != 1 || b.writes[0].id == replayed.id {
+		t.Fatalf("Oldest write wasn't dropped")
+	}
+	second := b.writes[0]
+
+	b.remove(replayed.id)
+	if len(b.writes) != maxBufferedWrites || b.writes[0].id != second.id {
+		t.Errorf("Removing a dropped write removed another one")
+	}
+
+	b.remove(second.id)
+	if len(b.writes) != maxBufferedWrites-1 || b.writes[0].id == second.id {
+		t.Errorf("Replayed write wasn't removed")
+	}
+	if last := b.writes[len(b.writes)-1]; last.description != "newest" {
+		t.Errorf("Newest write was lost")
+	}
+}
@@ -0,0 +1,85 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTTL is how long a history query result stays valid.
+const cacheTTL = 15 * time.Second
+
+// maxCachedQueries bounds the cache, every guild, sort order and page of history is cached apart.
+const maxCachedQueries = 1000
+
+type cacheEntry struct {
+	entries   []HistoryTrackInfo
+	expiresAt time.Time
+}
+
+// queryCache keeps recent history query results to avoid hitting the database on every embed.
+var queryCache = struct {
+	sync.Mutex
+	entries map[string]cacheEntry
+}{entries: make(map[string]cacheEntry)}
+
+func cacheKey(guildID, sortBy string, limit, offset int) string {
+	return fmt.Sprintf("%v|%v|%d|%d", guildID, sortBy, limit, offset)
+}
+
+func getCachedHistory(key string) ([]HistoryTrackInfo, bool) {
+	queryCache.Lock()
+	defer queryCache.Unlock()
+
+	entry, ok := queryCache.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(queryCache.entries, key)
+		return nil, false
+	}
+
+	return entry.entries, true
+}
+
+func setCachedHistory(key string, entries []HistoryTrackInfo) {
+	queryCache.Lock()
+	defer queryCache.Unlock()
+
+	if _, ok := queryCache.entries[key]; !ok && len(queryCache.entries) >= maxCachedQueries {
+		evictCachedHistory(time.Now())
+	}
+
+	queryCache.entries[key] = cacheEntry{
+		entries:   entries,
+		expiresAt: time.Now().Add(cacheTTL),
+	}
+}
+
+// evictCachedHistory drops the expired results, or the one expiring first if none expired. The cache must be locked.
+func evictCachedHistory(now time.Time) {
+	var firstKey string
+	var first time.Time
+	for key, entry := range queryCache.entries {
+		if now.After(entry.expiresAt) {
+			delete(queryCache.entries, key)
+		} else if firstKey == "" || entry.expiresAt.Before(first) {
+			firstKey, first = key, entry.expiresAt
+		}
+	}
+
+	if len(queryCache.entries) >= maxCachedQueries {
+		delete(queryCache.entries, firstKey)
+	}
+}
+
+// InvalidateCache drops cached history of the guild as well as the cross-guild results.
+func InvalidateCache(guildID string) {
+	queryCache.Lock()
+	defer queryCache.Unlock()
+
+	for key := range queryCache.entries {
+		if strings.HasPrefix(key, guildID+"|") || strings.HasPrefix(key, "|") {
+			delete(queryCache.entries, key)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheIsBounded(t *testing.T) {
+	for i := 0; i < maxCachedQueries+10; i++ {
+		setCachedHistory(cacheKey("g", "", 10, i), nil)
+	}
+	if len(queryCache.entries) != maxCachedQueries {
+		t.Errorf("%v results cached, expected at most %v", len(queryCache.entries), maxCachedQueries)
+	}
+	if _, ok := getCachedHistory(cacheKey("g", "", 10, maxCachedQueries+9)); !ok {
+		t.Errorf("Latest result was evicted")
+	}
+
+	// Expired results are swept once the cache is full
+	queryCache.Lock()
+	for key, entry := range queryCache.entries {
+		entry.expiresAt = time.Now().Add(-time.Second)
+		queryCache.entries[key] = entry
+	}
+	queryCache.Unlock()
+	setCachedHistory(fmt.Sprintf("g|%v", t.Name()), nil)
+	if len(queryCache.entries) != 1 {
+		t.Errorf("%v results cached after sweeping expired ones, expected 1", len(queryCache.entries))
+	}
+
+	InvalidateCache("g")
+}
@@ -1,6 +1,7 @@
 package history
 
 import (
+	"context"
 	"time"
 
 	"github.com/keshon/melodix-discord-player/internal/db"
@@ -20,6 +21,7 @@ type Song struct {
 	Thumbnail   Thumbnail     // Thumbnail image for the song
 	Duration    time.Duration // Duration of the song
 	ID          string        // Unique ID for the song
+	RequesterID string        // Discord user ID of the requester, empty if not attributed
 }
 
 // History manages the history of songs played in the application.
@@ -34,11 +36,42 @@ type HistoryTrackInfo struct {
 // IHistory defines the interface for managing the application's play history.
 type IHistory interface {
 	AddTrackToHistory(guildID string, song *Song) error
+	AddRequestToHistory(guildID string, song *Song) error
 	AddPlaybackAllStats(guildID, ytid string, duration float64) error
 	AddPlaybackCountStats(guildID, ytid string) error
 	AddPlaybackDurationStats(guildID, ytid string, duration float64) error
-	GetHistory(guildID string, sortBy string) ([]HistoryTrackInfo, error)
-	GetTrackFromHistory(guildID string, trackID uint) (db.Track, error)
+	AddSkipStats(guildID, ytid string) error
+	GetHistory(ctx context.Context, guildID string, sortBy string, limit, offset int) ([]HistoryTrackInfo, error)
+	GetTaggedHistory(ctx context.Context, guildID, tag, sortBy string, limit, offset int) ([]HistoryTrackInfo, error)
+	GetTrackFromHistory(ctx context.Context, guildID string, trackID uint) (db.Track, error)
+	ForgetUser(userID string) (int64, error)
+	GetListeningActivity(guildID string, since time.Time) ([]db.ListeningActivity, error)
+	GetListeningActivityBetween(guildID string, from, to time.Time) ([]db.ListeningActivity, error)
+	GetTopTracks(guildID, userID string, from, to time.Time, limit int) ([]db.TrackRequestCount, error)
+	GetTopSkippedTracks(guildID, userID string, from, to time.Time, limit int) ([]db.TrackRequestCount, error)
+	CountPlays(guildID, userID string, from, to time.Time) (int64, error)
+	GetTopRequesters(guildID string, from, to time.Time, limit int) ([]db.UserRequestCount, error)
+	RateTrack(guildID, ytid, userID string, value int) error
+	UnrateTrack(guildID, ytid, userID string, value int) error
+	GetTrackRatings(guildID string, trackIDs []uint) (map[uint]db.TrackRatingSummary, error)
+	TagTrack(guildID string, trackID uint, tag, userID string) error
+	UntagTrack(guildID string, trackID uint, tag string) (bool, error)
+	GetTrackTags(guildID string, trackIDs []uint) (map[uint][]string, error)
+	GetTrackIDsByTag(guildID, tag string) ([]uint, error)
+	GetTags(guildID string) ([]db.TagCount, error)
+	GetTopTags(guildID, userID string, from, to time.Time, limit int) ([]db.TagCount, error)
+	AddFailure(guildID, source, stage, class string) error
+	GetFailures(guildID string, since time.Time) ([]db.SourceFailure, error)
+	SavePosition(guildID, songKey string, position time.Duration) error
+	GetPosition(guildID, songKey string) (time.Duration, error)
+	ForgetPosition(guildID, songKey string) error
+	StartSession(guildID, channelID string) (*db.ListeningSession, error)
+	MoveSession(guildID, channelID string) error
+	EndSession(guildID string) (*db.ListeningSession, error)
+	GetSessions(guildID string, since time.Time, limit int) ([]db.ListeningSessionInfo, error)
+	CountSessionPlays(sessionID uint) (int64, error)
+	GetSessionTopRequesters(sessionID uint, limit int) ([]db.UserRequestCount, error)
+	GetSessionSkippedTracks(sessionID uint, limit int) ([]db.TrackRequestCount, error)
 }
 
 // NewHistory creates a new History instance.
@@ -48,35 +81,22 @@ func NewHistory() IHistory {
 
 // AddTrackToHistory adds a song to the application's play history.
 func (h *History) AddTrackToHistory(guildID string, song *Song) error {
-	var track *db.Track
+	return bufferedWriteOf("history of "+song.ID, func() error {
+		return h.addTrackToHistory(guildID, song)
+	})
+}
 
-	existingTrack, err := db.GetTrackByYTID(song.ID)
+func (h *History) addTrackToHistory(guildID string, song *Song) error {
+	track, err := db.GetOrCreateTrack(&db.Track{
+		YTID: song.ID,
+		Name: song.Name,
+		URL:  song.UserURL,
+	})
 	if err != nil {
-		newTrack := &db.Track{
-			YTID: song.ID,
-			Name: song.Name,
-			URL:  song.UserURL,
-		}
-
-		if err := db.CreateTrack(newTrack); err != nil {
-			return err
-		}
-
-		existingTrack, _ = db.GetTrackByYTID(song.ID)
-	}
-
-	if existingTrack == nil {
-		newTrack := &db.Track{
-			YTID: song.ID,
-			Name: song.Name,
-			URL:  song.UserURL,
-		}
-		track = newTrack
-	} else {
-		track = existingTrack
+		return err
 	}
 
-	exists, err := db.DoesHistoryExistForGuild(track.ID, guildID)
+	exists, err := db.DoesHistoryExistForGuild(context.Background(), track.ID, guildID)
 	if err != nil {
 		return err
 	}
@@ -86,14 +106,48 @@ func (h *History) AddTrackToHistory(guildID string, song *Song) error {
 			GuildID: guildID,
 			TrackID: track.ID,
 		}
-		return db.CreateHistory(&history)
+		if err := db.CreateHistory(&history); err != nil {
+			return err
+		}
+		InvalidateCache(guildID)
 	}
 
 	return nil
 }
 
+// AddRequestToHistory records a single play of the song attributed to its requester, if any.
+// The track must already be in history.
+// The play is attached to the open listening session of the guild.
+func (h *History) AddRequestToHistory(guildID string, song *Song) error {
+	sessionID := openSessionOf(guildID)
+	return bufferedWriteOf("request of "+song.ID, func() error {
+		return h.addRequestToHistory(guildID, song, sessionID)
+	})
+}
+
+func (h *History) addRequestToHistory(guildID string, song *Song, sessionID *uint) error {
+	track, err := db.GetTrackByYTID(song.ID)
+	if err != nil {
+		return err
+	}
+
+	request := db.Request{
+		GuildID:   guildID,
+		TrackID:   track.ID,
+		UserID:    song.RequesterID,
+		SessionID: sessionID,
+	}
+	return db.CreateRequest(&request)
+}
+
 // AddPlaybackStats updates all playback statistics (duration and count) for a track.
 func (h *History) AddPlaybackAllStats(guildID, ytid string, duration float64) error {
+	return bufferedWriteOf("playback stats of "+ytid, func() error {
+		return h.addPlaybackAllStats(guildID, ytid, duration)
+	})
+}
+
+func (h *History) addPlaybackAllStats(guildID, ytid string, duration float64) error {
 
 	existingTrackRecord, err := db.GetTrackByYTID(ytid)
 	if err != nil {
@@ -111,9 +165,21 @@ func (h *History) AddPlaybackAllStats(guildID, ytid string, duration float64) er
 	return db.UpdateTrackStatsForGuild(existingTrackRecord.ID, guildID, newPlayCount, newDuration)
 }
 
-// AddPlaybackCountStats updates playback count statistics for a track.
+// AddPlaybackCountStats counts a play of a track, it's written with the next flush of the stats buffer.
 func (h *History) AddPlaybackCountStats(guildID, ytid string) error {
+	stats.add(guildID, ytid, 1, 0, time.Now())
+	return nil
+}
+
+// AddPlaybackDurationStats adds played time to a track and the guild's listening activity,
+// it's written with the next flush of the stats buffer.
+func (h *History) AddPlaybackDurationStats(guildID, ytid string, duration float64) error {
+	stats.add(guildID, ytid, 0, duration, time.Now())
+	return nil
+}
 
+// addTrackStats adds plays and played seconds to the history of a track.
+func (h *History) addTrackStats(guildID, ytid string, plays uint, duration float64) error {
 	existingTrackRecord, err := db.GetTrackByYTID(ytid)
 	if err != nil {
 		return err
@@ -124,78 +190,94 @@ func (h *History) AddPlaybackCountStats(guildID, ytid string) error {
 		return err
 	}
 
-	newPlayCount := existingHistoryRecord.PlayCount + 1
-	newDuration := existingHistoryRecord.Duration
+	newPlayCount := existingHistoryRecord.PlayCount + plays
+	newDuration := existingHistoryRecord.Duration + duration
 
 	return db.UpdateTrackStatsForGuild(existingTrackRecord.ID, guildID, newPlayCount, newDuration)
 }
 
-// AddPlaybackDurationStats updates playback duration statistics for a track.
-func (h *History) AddPlaybackDurationStats(guildID, ytid string, duration float64) error {
+// AddSkipStats counts a skip of a track and flags its latest play as skipped.
+func (h *History) AddSkipStats(guildID, ytid string) error {
+	return bufferedWriteOf("skip of "+ytid, func() error {
+		return h.addSkipStats(guildID, ytid)
+	})
+}
+
+func (h *History) addSkipStats(guildID, ytid string) error {
 
 	existingTrackRecord, err := db.GetTrackByYTID(ytid)
 	if err != nil {
 		return err
 	}
 
-	existingHistoryRecord, err := db.GetHistoryByTrackIDAndGuildID(existingTrackRecord.ID, guildID)
-	if err != nil {
+	if err := db.IncrementSkipCountForGuild(existingTrackRecord.ID, guildID); err != nil {
 		return err
 	}
+	InvalidateCache(guildID)
 
-	newPlayCount := existingHistoryRecord.PlayCount
-	newDuration := existingHistoryRecord.Duration + duration
-
-	return db.UpdateTrackStatsForGuild(existingTrackRecord.ID, guildID, newPlayCount, newDuration)
+	return db.MarkLatestRequestSkipped(guildID, existingTrackRecord.ID)
 }
 
 // GetHistory retrieves the play history for a guild, sorted by the specified criteria.
-func (h *History) GetHistory(guildID string, sortBy string) ([]HistoryTrackInfo, error) {
-	var historyEntries []db.History
-	var err error
+// Only limit entries starting at offset are fetched, a limit of zero or less fetches the rest of the history.
+// Results are cached for a short time since embeds ask for the same page repeatedly.
+func (h *History) GetHistory(ctx context.Context, guildID string, sortBy string, limit, offset int) ([]HistoryTrackInfo, error) {
+	key := cacheKey(guildID, sortBy, limit, offset)
+	if cached, ok := getCachedHistory(key); ok {
+		return cached, nil
+	}
 
-	if guildID == "" {
-		historyEntries, err = db.GetAllHistorySortedBy(sortBy)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		historyEntries, err = db.GetGuildHistorySortedBy(guildID, sortBy)
-		if err != nil {
-			return nil, err
-		}
+	rows, err := db.GetHistoryWithTracksSortedBy(ctx, guildID, sortBy, limit, offset)
+	if err != nil {
+		return nil, err
 	}
 
-	var historyWithTracks []HistoryTrackInfo
+	historyWithTracks := historyTrackInfos(rows)
+	setCachedHistory(key, historyWithTracks)
 
-	for _, historyEntry := range historyEntries {
+	return historyWithTracks, nil
+}
 
-		track, err := db.GetTrackByID(historyEntry.TrackID)
-		if err != nil {
-			return nil, err
-		}
+// GetTaggedHistory retrieves the play history of a guild's tracks with the tag, sorted by the specified criteria.
+func (h *History) GetTaggedHistory(ctx context.Context, guildID, tag, sortBy string, limit, offset int) ([]HistoryTrackInfo, error) {
+	rows, err := db.GetTaggedHistoryWithTracksSortedBy(ctx, guildID, tag, sortBy, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return historyTrackInfos(rows), nil
+}
+
+func historyTrackInfos(rows []db.HistoryWithTrack) []HistoryTrackInfo {
+	historyWithTracks := make([]HistoryTrackInfo, 0, len(rows))
 
+	for _, row := range rows {
 		combinedInfo := HistoryTrackInfo{
-			History: historyEntry,
-			Track:   *track,
+			History: row.History,
+			Track: db.Track{
+				ID:   row.TrackID,
+				YTID: row.TrackYTID,
+				Name: row.TrackName,
+				URL:  row.TrackURL,
+			},
 		}
 
 		historyWithTracks = append(historyWithTracks, combinedInfo)
 	}
 
-	return historyWithTracks, nil
+	return historyWithTracks
 }
 
 // GetTrackFromHistory retrieves a track from the play history based on its ID and guild.
-func (h *History) GetTrackFromHistory(guildID string, trackID uint) (db.Track, error) {
+func (h *History) GetTrackFromHistory(ctx context.Context, guildID string, trackID uint) (db.Track, error) {
 
-	exists, err := db.DoesHistoryExistForGuild(trackID, guildID)
+	exists, err := db.DoesHistoryExistForGuild(ctx, trackID, guildID)
 	if err != nil {
 		return db.Track{}, err
 	}
 
 	if exists {
-		track, err := db.GetTrackByID(trackID)
+		track, err := db.GetTrackByID(ctx, trackID)
 		if err != nil {
 			return db.Track{}, err
 		}
@@ -205,3 +287,142 @@ func (h *History) GetTrackFromHistory(guildID string, trackID uint) (db.Track, e
 
 	return db.Track{}, err
 }
+
+// ForgetUser anonymizes every request made by the user across all guilds and deletes their ratings. Everything
+// else the user requested or created, e.g. tracks of saved queues, tags, playlists, macros or listen links, is
+// kept without them as its author. It returns the number of anonymized requests.
+func (h *History) ForgetUser(userID string) (int64, error) {
+	for _, forget := range []func(userID string) (int64, error){
+		db.DeleteUserTrackRatings,
+		db.AnonymizeUserSavedQueueTracks,
+		db.AnonymizeUserTrackTags,
+		db.AnonymizeUserPlaylists,
+		db.AnonymizeUserListenLinks,
+		db.AnonymizeUserListeningParties,
+		db.AnonymizeUserCommandMacros,
+		db.AnonymizeUserCommandAliases,
+		db.AnonymizeUserMacroTriggers,
+		db.AnonymizeUserAPITokens,
+		db.AnonymizeUserWebhooks,
+	} {
+		if _, err := forget(userID); err != nil {
+			return 0, err
+		}
+	}
+	return db.AnonymizeUserRequests(userID)
+}
+
+// GetListeningActivity retrieves hourly listening activity of a guild starting from the given time.
+func (h *History) GetListeningActivity(guildID string, since time.Time) ([]db.ListeningActivity, error) {
+	return db.GetListeningActivitySince(guildID, since)
+}
+
+// GetListeningActivityBetween retrieves hourly listening activity of a guild within the given time range.
+func (h *History) GetListeningActivityBetween(guildID string, from, to time.Time) ([]db.ListeningActivity, error) {
+	return db.GetListeningActivityBetween(guildID, from, to)
+}
+
+// GetTopTracks retrieves the most played tracks of a guild within the given time range, optionally requested by a single user.
+func (h *History) GetTopTracks(guildID, userID string, from, to time.Time, limit int) ([]db.TrackRequestCount, error) {
+	return db.GetTopRequestedTracks(guildID, userID, from, to, limit)
+}
+
+// CountPlays counts plays of a guild within the given time range, optionally requested by a single user.
+func (h *History) CountPlays(guildID, userID string, from, to time.Time) (int64, error) {
+	return db.CountRequests(guildID, userID, from, to)
+}
+
+// GetTopRequesters retrieves the users who requested the most plays of a guild within the given time range.
+func (h *History) GetTopRequesters(guildID string, from, to time.Time, limit int) ([]db.UserRequestCount, error) {
+	return db.GetTopRequesters(guildID, from, to, limit)
+}
+
+// GetTopSkippedTracks retrieves the most skipped tracks of a guild within the given time range, optionally requested by a single user.
+func (h *History) GetTopSkippedTracks(guildID, userID string, from, to time.Time, limit int) ([]db.TrackRequestCount, error) {
+	return db.GetTopSkippedTracks(guildID, userID, from, to, limit)
+}
+
+// RateTrack records a like (1) or dislike (-1) of a track in history by the user, replacing their previous rating.
+func (h *History) RateTrack(guildID, ytid, userID string, value int) error {
+	track, err := db.GetTrackByYTID(ytid)
+	if err != nil {
+		return err
+	}
+
+	return db.SaveTrackRating(&db.TrackRating{GuildID: guildID, TrackID: track.ID, UserID: userID, Value: value})
+}
+
+// UnrateTrack withdraws the user's rating of a track if it has the value.
+func (h *History) UnrateTrack(guildID, ytid, userID string, value int) error {
+	track, err := db.GetTrackByYTID(ytid)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.DeleteTrackRating(guildID, track.ID, userID, value)
+	return err
+}
+
+// GetTrackRatings counts likes and dislikes of tracks in a guild, of all rated tracks if trackIDs is empty.
+func (h *History) GetTrackRatings(guildID string, trackIDs []uint) (map[uint]db.TrackRatingSummary, error) {
+	return db.GetTrackRatingSummaries(guildID, trackIDs)
+}
+
+// TagTrack tags a track of the guild's history.
+func (h *History) TagTrack(guildID string, trackID uint, tag, userID string) error {
+	return db.CreateTrackTag(&db.TrackTag{GuildID: guildID, TrackID: trackID, Tag: tag, CreatedBy: userID})
+}
+
+// UntagTrack removes a tag of a track, it reports whether the track had the tag.
+func (h *History) UntagTrack(guildID string, trackID uint, tag string) (bool, error) {
+	deleted, err := db.DeleteTrackTag(guildID, trackID, tag)
+	return deleted > 0, err
+}
+
+// GetTrackTags retrieves the tags of tracks in a guild.
+func (h *History) GetTrackTags(guildID string, trackIDs []uint) (map[uint][]string, error) {
+	return db.GetTagsOfTracks(guildID, trackIDs)
+}
+
+// GetTrackIDsByTag retrieves history IDs of the guild's tracks with the tag, most recently played first.
+func (h *History) GetTrackIDsByTag(guildID, tag string) ([]uint, error) {
+	return db.GetTrackIDsByTag(guildID, tag)
+}
+
+// GetTags retrieves the tags of a guild with their number of tracks.
+func (h *History) GetTags(guildID string) ([]db.TagCount, error) {
+	return db.GetTagTrackCounts(guildID)
+}
+
+// GetTopTags retrieves the most played tags of a guild within the given time range, optionally requested by a single user.
+func (h *History) GetTopTags(guildID, userID string, from, to time.Time, limit int) ([]db.TagCount, error) {
+	return db.GetTopRequestedTags(guildID, userID, from, to, limit)
+}
+
+// AddFailure counts a failure to resolve or play a track of the given source for today.
+func (h *History) AddFailure(guildID, source, stage, class string) error {
+	at := time.Now()
+	return bufferedWriteOf("failure of "+source, func() error {
+		return db.AddSourceFailure(guildID, at, source, stage, class)
+	})
+}
+
+// GetFailures retrieves daily failure counts of a guild starting from the given time.
+func (h *History) GetFailures(guildID string, since time.Time) ([]db.SourceFailure, error) {
+	return db.GetSourceFailuresSince(guildID, since)
+}
+
+// SavePosition remembers where the guild stopped listening to a long-form track.
+func (h *History) SavePosition(guildID, songKey string, position time.Duration) error {
+	return db.SaveTrackPosition(guildID, songKey, position)
+}
+
+// GetPosition retrieves where the guild stopped listening to a track, 0 if it isn't remembered.
+func (h *History) GetPosition(guildID, songKey string) (time.Duration, error) {
+	return db.GetTrackPosition(guildID, songKey)
+}
+
+// ForgetPosition forgets where the guild stopped listening to a track, e.g. once it was listened to the end.
+func (h *History) ForgetPosition(guildID, songKey string) error {
+	return db.DeleteTrackPosition(guildID, songKey)
+}
@@ -0,0 +1,94 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+func TestForgetUser(t *testing.T) {
+	if _, err := db.InitDB(filepath.Join(t.TempDir(), "melodix.db"), db.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateGuild(db.Guild{ID: "g"}); err != nil {
+		t.Fatal(err)
+	}
+	track := &db.Track{YTID: "yt", Name: "Track"}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every column holding a user, with the rows of a user to forget and of one to keep
+	columns := []struct {
+		model  interface{}
+		column string
+		row    func(userID string) interface{}
+	}{
+		{&db.Request{}, "user_id", func(userID string) interface{} {
+			return &db.Request{GuildID: "g", TrackID: track.ID, UserID: userID}
+		}},
+		{&db.TrackRating{}, "user_id", func(userID string) interface{} {
+			return &db.TrackRating{GuildID: "g", TrackID: track.ID, UserID: userID, Value: 1}
+		}},
+		{&db.SavedQueueTrack{}, "requester_id", func(userID string) interface{} {
+			return &db.SavedQueueTrack{GuildID: "g", Title: "Track", RequesterID: userID}
+		}},
+		{&db.TrackTag{}, "created_by", func(userID string) interface{} {
+			return &db.TrackTag{GuildID: "g", TrackID: track.ID, Tag: "tag-" + userID, CreatedBy: userID}
+		}},
+		{&db.Playlist{}, "created_by", func(userID string) interface{} {
+			return &db.Playlist{GuildID: "g", Name: "list-" + userID, CreatedBy: userID}
+		}},
+		{&db.ListenLink{}, "created_by", func(userID string) interface{} {
+			return &db.ListenLink{GuildID: "g", TokenHash: "link-" + userID, CreatedBy: userID}
+		}},
+		{&db.ListeningParty{}, "created_by", func(userID string) interface{} {
+			return &db.ListeningParty{GuildID: "g", Title: "Party", CreatedBy: userID}
+		}},
+		{&db.CommandMacro{}, "created_by", func(userID string) interface{} {
+			return &db.CommandMacro{GuildID: "g", Name: "macro-" + userID, Steps: "skip", CreatedBy: userID}
+		}},
+		{&db.CommandAlias{}, "created_by", func(userID string) interface{} {
+			return &db.CommandAlias{GuildID: "g", Alias: "alias-" + userID, Command: "skip", CreatedBy: userID}
+		}},
+		{&db.MacroTrigger{}, "created_by", func(userID string) interface{} {
+			return &db.MacroTrigger{GuildID: "g", Macro: "macro", Kind: db.TriggerDaily, CreatedBy: userID}
+		}},
+		{&db.APIToken{}, "created_by", func(userID string) interface{} {
+			return &db.APIToken{GuildID: "g", TokenHash: "token-" + userID, CreatedBy: userID}
+		}},
+		{&db.Webhook{}, "created_by", func(userID string) interface{} {
+			return &db.Webhook{GuildID: "g", Token: "hook-" + userID, CreatedBy: userID}
+		}},
+	}
+
+	for _, c := range columns {
+		for _, userID := range []string{"forgotten", "kept"} {
+			if err := db.DB.Create(c.row(userID)).Error; err != nil {
+				t.Fatalf("Creating %T: %v", c.model, err)
+			}
+		}
+	}
+
+	count, err := NewHistory().ForgetUser("forgotten")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%v requests anonymized, expected 1", count)
+	}
+
+	for _, c := range columns {
+		for userID, want := range map[string]int64{"forgotten": 0, "kept": 1} {
+			var got int64
+			if err := db.DB.Model(c.model).Where(fmt.Sprintf("%v = ?", c.column), userID).Count(&got).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%v rows of %T hold %v in %v, expected %v", got, c.model, userID, c.column, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// openSessions holds the listening session each guild's bot is in voice for, plays are attached to it
+// when they are requested rather than when a buffered write reaches the database.
+var openSessions = struct {
+	sync.Mutex
+	ids map[string]uint
+}{ids: make(map[string]uint)}
+
+func openSessionOf(guildID string) *uint {
+	openSessions.Lock()
+	defer openSessions.Unlock()
+
+	id, ok := openSessions.ids[guildID]
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// StartSession opens a listening session of the guild once the bot joined a voice channel.
+// Sessions a previous run left open are ended first.
+func (h *History) StartSession(guildID, channelID string) (*db.ListeningSession, error) {
+	if err := db.EndAbandonedListeningSessions(guildID); err != nil {
+		return nil, err
+	}
+
+	session, err := db.StartListeningSession(guildID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	openSessions.Lock()
+	openSessions.ids[guildID] = session.ID
+	openSessions.Unlock()
+
+	return session, nil
+}
+
+// MoveSession records that the bot moved to another voice channel during the open session of the guild.
+func (h *History) MoveSession(guildID, channelID string) error {
+	id := openSessionOf(guildID)
+	if id == nil {
+		return nil
+	}
+	return db.MoveListeningSession(*id, channelID)
+}
+
+// EndSession closes the open listening session of the guild once the bot left voice and returns it,
+// nil if none was open.
+func (h *History) EndSession(guildID string) (*db.ListeningSession, error) {
+	openSessions.Lock()
+	id, ok := openSessions.ids[guildID]
+	delete(openSessions.ids, guildID)
+	openSessions.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+	if err := db.EndListeningSession(id); err != nil {
+		return nil, err
+	}
+	return db.GetListeningSession(guildID, id)
+}
+
+// GetSessions retrieves the latest listening sessions of a guild started since the given time, with their plays.
+func (h *History) GetSessions(guildID string, since time.Time, limit int) ([]db.ListeningSessionInfo, error) {
+	return db.GetListeningSessions(guildID, since, limit)
+}
+
+// CountSessionPlays counts the plays of a listening session.
+func (h *History) CountSessionPlays(sessionID uint) (int64, error) {
+	return db.CountSessionRequests(sessionID)
+}
+
+// GetSessionTopRequesters retrieves the users who requested the most plays of a listening session.
+func (h *History) GetSessionTopRequesters(sessionID uint, limit int) ([]db.UserRequestCount, error) {
+	return db.GetSessionTopRequesters(sessionID, limit)
+}
+
+// GetSessionSkippedTracks retrieves the most skipped tracks of a listening session.
+func (h *History) GetSessionSkippedTracks(sessionID uint, limit int) ([]db.TrackRequestCount, error) {
+	return db.GetSessionSkippedTracks(sessionID, limit)
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// statsFlushInterval is how often buffered play counts and played time are written to the database.
+const statsFlushInterval = time.Minute
+
+// BufferMetrics describe the history write buffers.
+type BufferMetrics struct {
+	PendingStats   int       `json:"pending_stats"`   // tracks and hours with stats waiting for the next flush
+	BufferedWrites int       `json:"buffered_writes"` // writes waiting for the database to recover
+	Dropped        int       `json:"dropped"`         // writes given up because the buffer was full or they failed
+	Unavailable    bool      `json:"database_unavailable"`
+	LastFlush      time.Time `json:"last_flush"`
+}
+
+type trackStatsKey struct {
+	guildID, ytid string
+}
+
+type activityKey struct {
+	guildID string
+	hour    time.Time
+}
+
+// trackStats are the plays and played seconds of a track since the last flush.
+type trackStats struct {
+	plays    uint
+	duration float64
+}
+
+// statsBuffer sums the frequent play count and played time updates of every player in memory and writes them
+// in one go, instead of a database round trip for every stats tick of every guild.
+type statsBuffer struct {
+	sync.Mutex
+	tracks    map[trackStatsKey]*trackStats
+	activity  map[activityKey]float64
+	lastFlush time.Time
+	start     sync.Once
+}
+
+var stats = &statsBuffer{
+	tracks:   make(map[trackStatsKey]*trackStats),
+	activity: make(map[activityKey]float64),
+}
+
+// add sums plays and played seconds of a track, flushing starts with the first stats.
+func (sb *statsBuffer) add(guildID, ytid string, plays uint, duration float64, at time.Time) {
+	sb.start.Do(func() { go sb.flushPeriodically() })
+
+	sb.Lock()
+	defer sb.Unlock()
+
+	key := trackStatsKey{guildID, ytid}
+	if sb.tracks[key] == nil {
+		sb.tracks[key] = &trackStats{}
+	}
+	sb.tracks[key].plays += plays
+	sb.tracks[key].duration += duration
+
+	if duration > 0 {
+		sb.activity[activityKey{guildID, at.UTC().Truncate(time.Hour)}] += duration
+	}
+}
+
+func (sb *statsBuffer) flushPeriodically() {
+	for range time.Tick(statsFlushInterval) {
+		sb.flush()
+	}
+}
+
+// flush writes the summed stats, writes are buffered for later while the database is unavailable.
+func (sb *statsBuffer) flush() {
+	sb.Lock()
+	tracks, activity := sb.tracks, sb.activity
+	sb.tracks, sb.activity = make(map[trackStatsKey]*trackStats), make(map[activityKey]float64)
+	sb.lastFlush = time.Now()
+	sb.Unlock()
+
+	h := &History{}
+	for key, track := range tracks {
+		key, track := key, track
+		err := bufferedWriteOf("stats of "+key.ytid, func() error {
+			return h.addTrackStats(key.guildID, key.ytid, track.plays, track.duration)
+		})
+		if err != nil {
+			slog.Warnf("Error writing stats of track %v: %v", key.ytid, err)
+			buffer.countDropped()
+		}
+	}
+
+	for key, seconds := range activity {
+		key, seconds := key, seconds
+		err := bufferedWriteOf(fmt.Sprintf("listening time of %v", key.guildID), func() error {
+			return db.AddListeningActivity(key.guildID, key.hour, seconds)
+		})
+		if err != nil {
+			slog.Warnf("Error writing listening time of guild %v: %v", key.guildID, err)
+			buffer.countDropped()
+		}
+	}
+}
+
+// Flush writes the buffered stats right away, e.g. before shutting down.
+// Writes still waiting for the database to recover are lost then, they are only logged.
+func Flush() {
+	stats.flush()
+
+	if metrics := GetBufferMetrics(); metrics.BufferedWrites > 0 {
+		slog.Warnf("%d history writes are lost, the database is unavailable", metrics.BufferedWrites)
+	}
+}
+
+// GetBufferMetrics returns the depth of the history write buffers and the writes dropped since start.
+func GetBufferMetrics() BufferMetrics {
+	stats.Lock()
+	metrics := BufferMetrics{
+		PendingStats: len(stats.tracks) + len(stats.activity),
+		LastFlush:    stats.lastFlush,
+	}
+	stats.Unlock()
+
+	buffer.Lock()
+	metrics.BufferedWrites = len(buffer.writes)
+	metrics.Dropped = buffer.droppedTotal
+	metrics.Unavailable = buffer.unavailable
+	buffer.Unlock()
+
+	return metrics
+}
@@ -0,0 +1,109 @@
+// Package locale formats durations and dates for a guild's language and timezone.
+package locale
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	_ "time/tzdata" // timezones must resolve in minimal containers without system zoneinfo
+)
+
+// DefaultTag is the locale used when a guild has not chosen one.
+const DefaultTag = "en"
+
+type language struct {
+	months [12]string
+	date   func(t time.Time, months [12]string) string
+	units  map[string]string // short names of time units
+}
+
+var languages = map[string]language{
+	"en": {
+		months: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		date: func(t time.Time, months [12]string) string {
+			return fmt.Sprintf("%v %v, %v", months[t.Month()-1], t.Day(), t.Year())
+		},
+		units: map[string]string{"hour": "h", "minute": "min", "second": "s"},
+	},
+	"de": {
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		date: func(t time.Time, months [12]string) string {
+			return fmt.Sprintf("%v. %v %v", t.Day(), months[t.Month()-1], t.Year())
+		},
+		units: map[string]string{"hour": "Std.", "minute": "Min.", "second": "Sek."},
+	},
+	"ru": {
+		months: [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		date: func(t time.Time, months [12]string) string {
+			return fmt.Sprintf("%v %v %v", t.Day(), months[t.Month()-1], t.Year())
+		},
+		units: map[string]string{"hour": "ч", "minute": "мин", "second": "с"},
+	},
+}
+
+// Tags lists available locales.
+var Tags = []string{"en", "de", "ru"}
+
+// Locale formats values for a language and timezone.
+type Locale struct {
+	Tag      string
+	Location *time.Location
+	lang     language
+}
+
+// NewLocale creates a locale for the language tag and IANA timezone name.
+// Empty values fall back to English and the server's local timezone.
+func NewLocale(tag, timezone string) (*Locale, error) {
+	if tag == "" {
+		tag = DefaultTag
+	}
+
+	lang, ok := languages[strings.ToLower(tag)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported locale: %s", tag)
+	}
+
+	location := time.Local
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone: %s", timezone)
+		}
+	}
+
+	return &Locale{Tag: strings.ToLower(tag), Location: location, lang: lang}, nil
+}
+
+// Default returns the English locale in the server's local timezone.
+func Default() *Locale {
+	return &Locale{Tag: DefaultTag, Location: time.Local, lang: languages[DefaultTag]}
+}
+
+// Duration formats seconds as a compact duration, e.g. "1 h 2 min" or "45 s".
+func (l *Locale) Duration(seconds float64) string {
+	total := int(math.Round(seconds))
+	hours := total / 3600
+	minutes := total % 3600 / 60
+	secs := total % 60
+
+	var parts []string
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%v %v", hours, l.lang.units["hour"]))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%v %v", minutes, l.lang.units["minute"]))
+	}
+	if hours == 0 && (secs > 0 || minutes == 0) {
+		parts = append(parts, fmt.Sprintf("%v %v", secs, l.lang.units["second"]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Date formats the calendar date of t in the locale's timezone.
+func (l *Locale) Date(t time.Time) string {
+	return l.lang.date(t.In(l.Location), l.lang.months)
+}
@@ -68,6 +68,7 @@ type EncodeOptions struct {
 	FfmpegBinaryPath        string           // Specify path to ffmpeg binary location
 	EncodingLineLog         bool             // Print encoding line one by one
 	UserAgent               string           // Override the User-Agent header.
+	InputFormat             string           // Format of the input, e.g. "lavfi" for generated audio. Leave empty to probe it.
 
 	// The ffmpeg audio filters to use, see https://ffmpeg.org/ffmpeg-filters.html#Audio-Filters for more info
 	// Leave empty to use no filters.
@@ -81,6 +82,18 @@ func (e EncodeOptions) PCMFrameLen() int {
 	return 960 * e.Channels * (e.FrameDuration / 20)
 }
 
+// AudioFilters returns the ffmpeg filter chain applied to the audio
+func (e EncodeOptions) AudioFilters() string {
+	filters := []string{
+		fmt.Sprintf("volume=%v", e.Volume),
+	}
+	if e.AudioFilter != "" {
+		// Lit af
+		filters = append(filters, e.AudioFilter)
+	}
+	return strings.Join(filters, ",")
+}
+
 // Validate returns an error if the options are not correct
 func (opts *EncodeOptions) Validate() error {
 	if opts.Volume < 0 || opts.Volume > 1.0 {
@@ -157,10 +170,12 @@ type EncodeSession struct {
 	started      time.Time
 	frameChannel chan *Frame
 	process      *os.Process
+	stdin        io.WriteCloser // ffmpeg's interactive commands, nil when encoding from memory
 	lastStats    *EncodeStats
 
-	lastFrame int
-	err       error
+	lastFrame  int
+	reconnects int
+	err        error
 
 	ffmpegOutput string
 
@@ -275,6 +290,11 @@ func (e *EncodeSession) run() {
 		"-ss", strconv.Itoa(e.options.StartTime),
 	}
 
+	// Generated inputs can't be probed, their format must be given
+	if e.options.InputFormat != "" {
+		args = append([]string{"-f", e.options.InputFormat}, args...)
+	}
+
 	// Only add reconnect args if we're streaming from a URL
 	if e.isURL {
 		reconnectArgs := []string{
@@ -287,14 +307,7 @@ func (e *EncodeSession) run() {
 		args = append(reconnectArgs, args...)
 	}
 
-	filters := []string{
-		fmt.Sprintf("volume=%v", e.options.Volume),
-	}
-	if e.options.AudioFilter != "" {
-		// Lit af
-		filters = append(filters, e.options.AudioFilter)
-	}
-	args = append(args, "-af", strings.Join(filters, ","))
+	args = append(args, "-af", e.options.AudioFilters())
 
 	args = append(args, "pipe:1")
 
@@ -308,6 +321,16 @@ func (e *EncodeSession) run() {
 
 	if e.pipeReader != nil {
 		ffmpeg.Stdin = e.pipeReader
+	} else {
+		// ffmpeg reads interactive commands from stdin, SetVolume sends them
+		stdin, err := ffmpeg.StdinPipe()
+		if err != nil {
+			e.Unlock()
+			slog.Error("StdinPipe Error:", err)
+			close(e.frameChannel)
+			return
+		}
+		e.stdin = stdin
 	}
 
 	stdout, err := ffmpeg.StdoutPipe()
@@ -525,6 +548,9 @@ func (e *EncodeSession) readStderr(stderr io.ReadCloser, wg *sync.WaitGroup) {
 			// Message
 			e.Lock()
 			e.ffmpegOutput += outBuf.String() + "\n"
+			if strings.Contains(outBuf.String(), "Will reconnect at") {
+				e.reconnects++
+			}
 			e.Unlock()
 			outBuf.Reset()
 		default:
@@ -639,6 +665,24 @@ func (e *EncodeSession) Stop() error {
 	return err
 }
 
+// SetVolume changes the volume of the running ffmpeg process (0.0-1.0) without restarting it.
+// Frames already encoded keep their volume, so the change is heard once the buffered frames are played.
+func (e *EncodeSession) SetVolume(volume float32) error {
+	if volume < 0 || volume > 1.0 {
+		return errors.New("out of bounds volume (0.0-1.0)")
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if !e.running || e.stdin == nil {
+		return errors.New("Not running")
+	}
+
+	// "c" sends a command to the first matching filter, -1 applies it right away
+	_, err := fmt.Fprintf(e.stdin, "cvolume -1 volume %.2f\n", volume)
+	return err
+}
+
 // ReadFrame blocks until a frame is read or there are no more frames
 // Note: If rawoutput is not set, the first frame will be a metadata frame
 func (e *EncodeSession) ReadFrame() (frame []byte, err error) {
@@ -691,6 +735,13 @@ func (e *EncodeSession) Stats() *EncodeStats {
 	return s
 }
 
+// Reconnects returns how many times ffmpeg reconnected to the input URL
+func (e *EncodeSession) Reconnects() int {
+	e.Lock()
+	defer e.Unlock()
+	return e.reconnects
+}
+
 // Options returns the options used
 func (e *EncodeSession) Options() *EncodeOptions {
 	return e.options
@@ -0,0 +1,113 @@
+package dca
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of process times in /proc. It's 100 on every mainstream Linux build.
+const clockTicks = 100
+
+var (
+	ErrNoProcess = errors.New("ffmpeg is not running")
+)
+
+// ProcessStats is the resource usage of the ffmpeg subprocess.
+type ProcessStats struct {
+	PID     int
+	CPUTime time.Duration // user and system time consumed so far
+	RSS     int64         // resident memory in bytes
+	Uptime  time.Duration
+}
+
+// ProcessStats reads the resource usage of the ffmpeg subprocess from /proc.
+// It returns an error on systems without procfs.
+func (e *EncodeSession) ProcessStats() (*ProcessStats, error) {
+	e.Lock()
+	process, running, started := e.process, e.running, e.started
+	e.Unlock()
+
+	if process == nil || !running {
+		return nil, ErrNoProcess
+	}
+
+	stats, err := ReadProcessStats(process.Pid)
+	if err != nil {
+		return nil, err
+	}
+	stats.Uptime = time.Since(started)
+
+	return stats, nil
+}
+
+// ReadProcessStats reads CPU time and resident memory of any process from /proc, Uptime is left empty.
+// It returns an error on systems without procfs.
+func ReadProcessStats(pid int) (*ProcessStats, error) {
+	cpuTime, err := readProcessCPUTime(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	rss, err := readProcessRSS(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProcessStats{PID: pid, CPUTime: cpuTime, RSS: rss}, nil
+}
+
+// readProcessCPUTime returns utime + stime of /proc/<pid>/stat.
+func readProcessCPUTime(pid int) (time.Duration, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	// The command name may contain spaces, so fields are counted from its closing parenthesis
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+
+	// Fields after the name start with state (3rd field), utime and stime are the 14th and 15th
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(utime+stime) * time.Second / clockTicks, nil
+}
+
+// readProcessRSS returns the resident set size of /proc/<pid>/statm in bytes.
+func readProcessRSS(pid int) (int64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected format of /proc/%d/statm", pid)
+	}
+
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return pages * int64(os.Getpagesize()), nil
+}
@@ -14,6 +14,25 @@ var (
 	ErrVoiceConnClosed = errors.New("voice connection closed")
 )
 
+const (
+	// SendStallThreshold is how long the voice connection may take to accept a frame before it counts as a stall
+	SendStallThreshold = 200 * time.Millisecond
+
+	// Late frames are checked in windows of about 5 seconds of 20ms frames
+	lateFramesWindow        = 250
+	lateFramesWarnThreshold = 10
+
+	// A grown jitter buffer halves again after about 30 seconds of 20ms frames sent without a stall
+	bufferShrinkFrames = 1500
+)
+
+// StreamOptions configure the jitter buffer between the source and the voice connection.
+type StreamOptions struct {
+	BufferFrames    int // frames to read ahead before sending starts and after the buffer runs dry, 0 disables the buffer
+	MaxBufferFrames int // the buffer depth doubles on send stalls up to this size, BufferFrames if smaller
+	MinBufferFrames int // the buffer depth halves back down to this size once stalls stop, BufferFrames if zero or larger
+}
+
 // StreamingSession provides an easy way to directly transmit opus audio
 // to discord from an encode session.
 type StreamingSession struct {
@@ -25,8 +44,31 @@ type StreamingSession struct {
 	source OpusReader
 	vc     *discordgo.VoiceConnection
 
-	paused     bool
-	framesSent int
+	// Jitter buffer, nil if disabled. A prefetch goroutine fills it from the source.
+	buffer      chan []byte
+	bufferDepth int
+	bufferMin   int
+	bufferMax   int
+	stallFree   int   // frames sent since the last stall or depth change
+	bufferDone  bool  // the source is exhausted, frames left in the buffer are the last ones
+	bufferErr   error // why the source is exhausted
+	rebuffering bool  // waiting for the buffer to fill up to its depth before sending
+
+	stop       chan struct{}
+	stopOnce   sync.Once
+	firstFrame chan struct{} // closed once the first frame was sent
+
+	paused        bool
+	framesSent    int
+	framesDropped int
+	framesLate    int
+	sendStalls    int
+	bytesSent     int64
+	jitter        time.Duration
+	lastSent      time.Time // zero until the first frame after (re)starting
+
+	windowFrames int
+	windowLate   int
 
 	finished bool
 	running  bool
@@ -38,10 +80,33 @@ type StreamingSession struct {
 // vc       : The voice connecion to stream to.
 // done     : If not nil, an error will be sent on it when completed.
 func NewStream(source OpusReader, vc *discordgo.VoiceConnection, done chan error) *StreamingSession {
+	return NewStreamWithOptions(source, vc, done, StreamOptions{})
+}
+
+// NewStreamWithOptions creates a new stream like NewStream, with a jitter buffer configured by options.
+func NewStreamWithOptions(source OpusReader, vc *discordgo.VoiceConnection, done chan error, options StreamOptions) *StreamingSession {
 	session := &StreamingSession{
-		source: source,
-		vc:     vc,
-		done:   done,
+		source:     source,
+		vc:         vc,
+		done:       done,
+		stop:       make(chan struct{}),
+		firstFrame: make(chan struct{}),
+	}
+
+	if options.BufferFrames > 0 {
+		session.bufferDepth = options.BufferFrames
+		session.bufferMin = options.MinBufferFrames
+		if session.bufferMin <= 0 || session.bufferMin > session.bufferDepth {
+			session.bufferMin = session.bufferDepth
+		}
+		session.bufferMax = options.MaxBufferFrames
+		if session.bufferMax < session.bufferDepth {
+			session.bufferMax = session.bufferDepth
+		}
+		session.buffer = make(chan []byte, session.bufferMax)
+		session.rebuffering = true
+
+		go session.prefetch()
 	}
 
 	go session.stream()
@@ -49,6 +114,93 @@ func NewStream(source OpusReader, vc *discordgo.VoiceConnection, done chan error
 	return session
 }
 
+// prefetch reads frames from the source into the jitter buffer until the source is exhausted or the stream stops.
+func (s *StreamingSession) prefetch() {
+	for {
+		opus, err := s.source.OpusFrame()
+		if err != nil {
+			s.Lock()
+			s.bufferDone = true
+			s.bufferErr = err
+			s.Unlock()
+			close(s.buffer)
+			return
+		}
+
+		select {
+		case s.buffer <- opus:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// nextFrame returns the next frame from the jitter buffer, or straight from the source if there is none.
+func (s *StreamingSession) nextFrame() ([]byte, error) {
+	if s.buffer == nil {
+		return s.source.OpusFrame()
+	}
+
+	s.Lock()
+	if len(s.buffer) == 0 && !s.bufferDone {
+		s.rebuffering = true
+	}
+	s.Unlock()
+
+	// Poll instead of blocking on a frame, a channel can't be waited on until it holds enough of them
+	for {
+		s.Lock()
+		ready := !s.rebuffering || len(s.buffer) >= s.bufferDepth || s.bufferDone
+		if ready {
+			s.rebuffering = false
+		}
+		s.Unlock()
+
+		if ready {
+			break
+		}
+
+		select {
+		case <-s.stop:
+			return nil, io.EOF
+		case <-time.After(s.source.FrameDuration()):
+		}
+	}
+
+	select {
+	case <-s.stop:
+		return nil, io.EOF
+	case opus, ok := <-s.buffer:
+		if !ok {
+			s.Lock()
+			err := s.bufferErr
+			s.Unlock()
+			return nil, err
+		}
+		return opus, nil
+	}
+}
+
+// Stop ends the stream after the frame being sent, discarding buffered frames.
+// Streams also stop by themselves when the source is exhausted.
+func (s *StreamingSession) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// FirstFrameSent returns a channel that is closed once the first frame was sent to the voice connection.
+func (s *StreamingSession) FirstFrameSent() <-chan struct{} {
+	return s.firstFrame
+}
+
+// BufferFrames returns the current depth of the jitter buffer, 0 if it's disabled.
+func (s *StreamingSession) BufferFrames() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.bufferDepth
+}
+
 func (s *StreamingSession) stream() {
 	// Check if we are already running and if so stop
 	s.Lock()
@@ -58,6 +210,7 @@ func (s *StreamingSession) stream() {
 		return
 	}
 	s.running = true
+	s.lastSent = time.Time{}
 	s.Unlock()
 
 	defer func() {
@@ -82,6 +235,7 @@ func (s *StreamingSession) stream() {
 			if err != io.EOF {
 				s.err = err
 			}
+			s.Stop()
 
 			if s.done != nil {
 				go func() {
@@ -96,11 +250,18 @@ func (s *StreamingSession) stream() {
 }
 
 func (s *StreamingSession) readNext() error {
-	opus, err := s.source.OpusFrame()
+	readStart := time.Now()
+	opus, err := s.nextFrame()
 	if err != nil {
 		return err
 	}
 
+	// The source has frames buffered, waiting longer than a frame means it couldn't keep up.
+	// With a jitter buffer this is the frame after it ran dry and had to fill up again.
+	frameDuration := s.source.FrameDuration()
+	late := time.Since(readStart) > frameDuration
+	sendStart := time.Now()
+
 	// Timeout after 10 seconds
 	timeOut := time.NewTimer(time.Second * 10)
 	defer timeOut.Stop()
@@ -108,17 +269,84 @@ func (s *StreamingSession) readNext() error {
 	// This will attempt to send on the channel before the timeout, which is 10s
 	select {
 	case <-timeOut.C:
+		s.Lock()
+		s.framesDropped++
+		s.Unlock()
 		return ErrVoiceConnClosed
 	case s.vc.OpusSend <- opus:
 	}
 
 	s.Lock()
 	s.framesSent++
+	s.bytesSent += int64(len(opus))
+	s.recordTiming(late, time.Since(sendStart), frameDuration)
 	s.Unlock()
 
 	return nil
 }
 
+// recordTiming updates late frame, stall and jitter counters after a frame was sent, warning when
+// they exceed their thresholds. Must be called with the lock held.
+func (s *StreamingSession) recordTiming(late bool, sendWait, frameDuration time.Duration) {
+	now := time.Now()
+
+	if s.lastSent.IsZero() {
+		// The first frame waits for ffmpeg to start, it's neither late nor has an interval
+		if s.framesSent == 1 {
+			close(s.firstFrame)
+		}
+		s.lastSent = now
+		return
+	}
+
+	if late {
+		s.framesLate++
+		s.windowLate++
+	}
+
+	if sendWait > SendStallThreshold {
+		s.sendStalls++
+		slog.Warnf("Voice connection stalled for %v before accepting a frame", sendWait.Round(time.Millisecond))
+
+		// Read further ahead so the next network hiccup is covered by buffered frames
+		s.stallFree = 0
+		if s.buffer != nil && s.bufferDepth < s.bufferMax {
+			s.bufferDepth *= 2
+			if s.bufferDepth > s.bufferMax {
+				s.bufferDepth = s.bufferMax
+			}
+			slog.Infof("Jitter buffer grown to %d frames", s.bufferDepth)
+		}
+	} else {
+		// Each frame read ahead is latency, give the depth back once the network calmed down
+		s.stallFree++
+		if s.buffer != nil && s.bufferDepth > s.bufferMin && s.stallFree >= bufferShrinkFrames {
+			s.stallFree = 0
+			s.bufferDepth /= 2
+			if s.bufferDepth < s.bufferMin {
+				s.bufferDepth = s.bufferMin
+			}
+			slog.Infof("Jitter buffer shrunk to %d frames", s.bufferDepth)
+		}
+	}
+
+	// Interarrival jitter estimate of RFC 3550: the smoothed deviation of frame intervals from the frame duration
+	deviation := now.Sub(s.lastSent) - frameDuration
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	s.jitter += (deviation - s.jitter) / 16
+	s.lastSent = now
+
+	s.windowFrames++
+	if s.windowFrames >= lateFramesWindow {
+		if s.windowLate >= lateFramesWarnThreshold {
+			slog.Warnf("%d of the last %d frames were late, jitter is %v", s.windowLate, s.windowFrames, s.jitter.Round(time.Millisecond))
+		}
+		s.windowFrames, s.windowLate = 0, 0
+	}
+}
+
 // SetPaused provides pause/unpause functionality
 func (s *StreamingSession) SetPaused(paused bool) {
 	s.Lock()
@@ -167,6 +395,30 @@ func (s *StreamingSession) PlaybackPosition() time.Duration {
 	return dur
 }
 
+// StreamStats are counters of a streaming session.
+type StreamStats struct {
+	FramesSent    int
+	FramesDropped int           // frames the voice connection didn't accept in time
+	FramesLate    int           // frames the source couldn't provide within a frame duration
+	SendStalls    int           // sends blocked longer than SendStallThreshold
+	BytesSent     int64         // opus payload bytes, excluding RTP and encryption overhead
+	Jitter        time.Duration // smoothed deviation of send intervals from the frame duration
+}
+
+// Stats returns the counters of frames and bytes sent so far and the current jitter
+func (s *StreamingSession) Stats() StreamStats {
+	s.Lock()
+	defer s.Unlock()
+	return StreamStats{
+		FramesSent:    s.framesSent,
+		FramesDropped: s.framesDropped,
+		FramesLate:    s.framesLate,
+		SendStalls:    s.sendStalls,
+		BytesSent:     s.bytesSent,
+		Jitter:        s.jitter,
+	}
+}
+
 // Finished returns wether the stream finished or not, and any error that caused it to stop
 func (s *StreamingSession) Finished() (bool, error) {
 	s.Lock()
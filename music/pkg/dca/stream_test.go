@@ -0,0 +1,138 @@
+package dca
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// frameSource provides numbered frames, then io.EOF.
+type frameSource struct {
+	sync.Mutex
+	next, count int
+}
+
+func (f *frameSource) OpusFrame() ([]byte, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.next >= f.count {
+		return nil, io.EOF
+	}
+	f.next++
+	return []byte(fmt.Sprint(f.next)), nil
+}
+
+func (f *frameSource) FrameDuration() time.Duration {
+	return time.Millisecond
+}
+
+func TestStreamBuffered(t *testing.T) {
+	for _, options := range []StreamOptions{{}, {BufferFrames: 4, MaxBufferFrames: 16}, {BufferFrames: 64}} {
+		vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte)}
+		done := make(chan error, 1)
+		stream := NewStreamWithOptions(&frameSource{count: 50}, vc, done, options)
+
+		for i := 1; i <= 50; i++ {
+			select {
+			case frame := <-vc.OpusSend:
+				if string(frame) != fmt.Sprint(i) {
+					t.Fatalf("Options %+v: frame %v sent as %q", options, i, frame)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Options %+v: frame %v wasn't sent", options, i)
+			}
+		}
+
+		if err := <-done; err != io.EOF {
+			t.Errorf("Options %+v: stream ended with %v", options, err)
+		}
+		if stats := stream.Stats(); stats.FramesSent != 50 {
+			t.Errorf("Options %+v: %v frames counted as sent", options, stats.FramesSent)
+		}
+	}
+}
+
+func TestBufferDepth(t *testing.T) {
+	s := &StreamingSession{buffer: make(chan []byte, 40), bufferDepth: 5, bufferMin: 5, bufferMax: 40, firstFrame: make(chan struct{})}
+	s.framesSent = 1
+	s.recordTiming(false, 0, time.Millisecond)
+
+	stall := SendStallThreshold + time.Millisecond
+	for _, want := range []int{10, 20, 40, 40} {
+		s.recordTiming(false, stall, time.Millisecond)
+		if s.bufferDepth != want {
+			t.Errorf("Depth after a stall is %v, want %v", s.bufferDepth, want)
+		}
+	}
+
+	for _, want := range []int{20, 10, 5, 5} {
+		before := s.bufferDepth
+		for i := 0; i < bufferShrinkFrames-1; i++ {
+			s.recordTiming(false, 0, time.Millisecond)
+		}
+		if s.bufferDepth != before {
+			t.Fatalf("Depth shrunk to %v too early", s.bufferDepth)
+		}
+		s.recordTiming(false, 0, time.Millisecond)
+		if s.bufferDepth != want {
+			t.Errorf("Depth after stall free frames is %v, want %v", s.bufferDepth, want)
+		}
+	}
+
+	// A stall restarts the count towards shrinking
+	s.recordTiming(false, stall, time.Millisecond)
+	for i := 0; i < bufferShrinkFrames-1; i++ {
+		s.recordTiming(false, 0, time.Millisecond)
+	}
+	s.recordTiming(false, stall, time.Millisecond)
+	s.recordTiming(false, 0, time.Millisecond)
+	if s.bufferDepth != 20 {
+		t.Errorf("Depth is %v after two stalls, want 20", s.bufferDepth)
+	}
+}
+
+// TestStreamStopConcurrently is meant for the race detector: the buffer is filled, drained and read from while
+// the stream stops.
+func TestStreamStopConcurrently(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte, 8)}
+		stream := NewStreamWithOptions(&frameSource{count: 1000}, vc, nil, StreamOptions{BufferFrames: 4, MaxBufferFrames: 8})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				<-vc.OpusSend
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = stream.BufferFrames()
+				_ = stream.Stats()
+				_ = stream.PlaybackPosition()
+			}
+		}()
+		wg.Wait()
+
+		stream.Stop()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			// Drain so a send in progress returns
+			select {
+			case <-vc.OpusSend:
+			default:
+			}
+			if finished, _ := stream.Finished(); finished {
+				break
+			}
+		}
+		if finished, _ := stream.Finished(); !finished {
+			t.Fatalf("Stream didn't finish after Stop")
+		}
+	}
+}
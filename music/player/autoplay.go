@@ -0,0 +1,61 @@
+package player
+
+import (
+	"context"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+// autoplayTimeout bounds looking up the song autoplay goes on with.
+const autoplayTimeout = 30 * time.Second
+
+// AutoplaySource finds a song related to the one that played last, nil if it has none.
+type AutoplaySource func(ctx context.Context, last *Song) (*Song, error)
+
+// SetAutoplay sets where songs come from once the queue ran out, nil disconnects instead.
+func (p *Player) SetAutoplay(source AutoplaySource) {
+	slog.Infof("Setting autoplay to %v", source != nil)
+
+	p.Lock()
+	defer p.Unlock()
+	p.autoplay = source
+}
+
+// IsAutoplay reports whether the player goes on with related songs once the queue ran out.
+func (p *Player) IsAutoplay() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.autoplay != nil
+}
+
+// autoplayNext queues a song related to the last one, it reports false if autoplay is off or found nothing.
+func (p *Player) autoplayNext(ctx context.Context, last *Song) bool {
+	p.Lock()
+	source := p.autoplay
+	p.Unlock()
+
+	if source == nil || last == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, autoplayTimeout)
+	defer cancel()
+
+	song, err := source(ctx, last)
+	if err != nil {
+		slog.Warnf("Error finding a song to autoplay after %q: %v", last.Title, err)
+		p.recordEvent(EventError, "Finding a song to autoplay after %q: %v", last.Title, err)
+		return false
+	}
+	if song == nil {
+		slog.Infof("Found no song to autoplay after %q", last.Title)
+		return false
+	}
+
+	slog.Infof("Autoplaying %q after %q", song.Title, last.Title)
+	p.recordEvent(EventAutoplay, "%q after %q", song.Title, last.Title)
+	song.Autoplayed = true
+	p.Enqueue(song)
+	return true
+}
@@ -0,0 +1,72 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/pkg/dca"
+)
+
+// clipLimit cuts clips off, they are meant to be short jingles rather than songs.
+const clipLimit = 15 * time.Second
+
+// PlayClip plays a short audio file or URL over the voice connection and returns once it's done
+// or the context ends, without touching the queue or the history. It refuses to play over a song.
+func (p *Player) PlayClip(ctx context.Context, url string) error {
+	return p.playClip(ctx, url, "")
+}
+
+// PlayGeneratedClip plays audio generated by an ffmpeg lavfi source, e.g. "sine=frequency=440:duration=3",
+// the same way as PlayClip. It needs no file or network, which makes it a check of the encode and stream path alone.
+func (p *Player) PlayGeneratedClip(ctx context.Context, source string) error {
+	return p.playClip(ctx, source, "lavfi")
+}
+
+// playClip plays a clip of the given ffmpeg input format, empty to probe it.
+func (p *Player) playClip(ctx context.Context, url, inputFormat string) error {
+	if status := p.GetCurrentStatus(); status == StatusPlaying || status == StatusPaused {
+		return fmt.Errorf("a song is %v", status)
+	}
+
+	vc := p.GetVoiceConnection()
+	if vc == nil {
+		return fmt.Errorf("not connected to voice")
+	}
+
+	options := p.createEncodeOptions(0)
+	options.InputFormat = inputFormat
+	encoding, err := dca.EncodeFile(url, options)
+	if err != nil {
+		return fmt.Errorf("error encoding clip: %w", err)
+	}
+	defer encoding.Cleanup()
+
+	p.setupVoiceConnection()
+	defer vc.Speaking(false)
+
+	done := make(chan error, 1)
+	streaming := dca.NewStream(encoding, vc, done)
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		streaming.Stop()
+		encoding.Stop()
+		return ctx.Err()
+	case <-time.After(clipLimit):
+		slog.Infof("Clip %v cut off after %v", url, clipLimit)
+		streaming.Stop()
+		encoding.Stop()
+		return nil
+	}
+
+	if _, streamErr := streaming.Finished(); streamErr != nil {
+		return fmt.Errorf("error streaming clip: %w", streamErr)
+	}
+	if err := encoding.Error(); err != nil {
+		return fmt.Errorf("error encoding clip: %w", err)
+	}
+	return nil
+}
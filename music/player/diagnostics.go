@@ -0,0 +1,102 @@
+package player
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// timelineEvents is how many of the latest events a player keeps
+	timelineEvents = 50
+	// lastErrors is how many of the latest errors a player keeps
+	lastErrors = 10
+)
+
+// Kinds of events of the player's timeline.
+const (
+	EventPlay      = "play"
+	EventRestart   = "restart" // the current song played again, after an interruption, a seek or for repeat
+	EventSkip      = "skip"
+	EventPause     = "pause"
+	EventResume    = "resume"
+	EventStop      = "stop"
+	EventRetry     = "retry" // a failed song queued to be retried later
+	EventAutoplay  = "autoplay"
+	EventQueueDone = "queue done"
+	EventError     = "error"
+)
+
+// Event is an entry of the player's timeline.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// Diagnostics is a snapshot of what the player runs and what it went through lately, oldest events first.
+type Diagnostics struct {
+	Plays      int     `json:"plays"`      // Play calls that didn't return, one per song played in a row
+	Goroutines int     `json:"goroutines"` // goroutines the player started that still run
+	Errors     []Event `json:"errors"`
+	Timeline   []Event `json:"timeline"`
+}
+
+// playerDiagnostics counts what the player runs and keeps its latest events.
+type playerDiagnostics struct {
+	sync.Mutex
+	plays      atomic.Int64
+	goroutines atomic.Int64
+	events     []Event
+	errors     []Event
+}
+
+// recordEvent adds an event to the timeline, errors are kept apart as well.
+func (p *Player) recordEvent(kind, format string, args ...any) {
+	event := Event{Time: time.Now(), Kind: kind, Message: fmt.Sprintf(format, args...)}
+
+	d := &p.diagnostics
+	d.Lock()
+	defer d.Unlock()
+
+	d.events = appendEvent(d.events, event, timelineEvents)
+	if kind == EventError {
+		d.errors = appendEvent(d.errors, event, lastErrors)
+	}
+}
+
+// appendEvent adds an event to events, dropping the oldest beyond limit.
+func appendEvent(events []Event, event Event, limit int) []Event {
+	events = append(events, event)
+	if len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events
+}
+
+// trackPlay counts a Play call until the returned func is called.
+func (p *Player) trackPlay() func() {
+	p.diagnostics.plays.Add(1)
+	return func() { p.diagnostics.plays.Add(-1) }
+}
+
+// trackGoroutine counts a goroutine of the player until the returned func is called.
+func (p *Player) trackGoroutine() func() {
+	p.diagnostics.goroutines.Add(1)
+	return func() { p.diagnostics.goroutines.Add(-1) }
+}
+
+// GetDiagnostics returns what the player runs and its latest errors and events.
+func (p *Player) GetDiagnostics() Diagnostics {
+	d := &p.diagnostics
+	d.Lock()
+	defer d.Unlock()
+
+	return Diagnostics{
+		Plays:      int(d.plays.Load()),
+		Goroutines: int(d.goroutines.Load()),
+		Errors:     append([]Event{}, d.errors...),
+		Timeline:   append([]Event{}, d.events...),
+	}
+}
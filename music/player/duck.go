@@ -0,0 +1,45 @@
+package player
+
+import "github.com/gookit/slog"
+
+// DuckedVolume is the share of its volume music plays at while people talk in the voice channel.
+const DuckedVolume = 0.25
+
+// SetDucked lowers the volume of the playing song to its DuckedVolume share, or restores it.
+// Songs started while ducked start at the lowered volume.
+func (p *Player) SetDucked(ducked bool) {
+	p.Lock()
+	if p.ducked == ducked {
+		p.Unlock()
+		return
+	}
+	p.ducked = ducked
+	encoding := p.EncodingSession
+	p.Unlock()
+
+	if encoding == nil || !encoding.Running() {
+		return
+	}
+	if err := encoding.SetVolume(p.volume()); err != nil {
+		slog.Warnf("Error changing volume: %v", err)
+	}
+}
+
+// IsDucked reports whether the volume is lowered.
+func (p *Player) IsDucked() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.ducked
+}
+
+// volume is the volume songs are encoded at, lowered while ducked and capped by the guild's ceiling.
+func (p *Player) volume() float32 {
+	volume := float32(1.0)
+	if ceiling := p.GetEncodeOverrides().VolumeCeiling; ceiling != 0 {
+		volume = ceiling
+	}
+	if p.IsDucked() {
+		volume *= DuckedVolume
+	}
+	return volume
+}
@@ -0,0 +1,48 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned by TimeUntil.
+var (
+	ErrNoSuchPosition  = errors.New("no song at this position of the queue")
+	ErrRepeatingTrack  = errors.New("the current song repeats until the repeat mode changes")
+	ErrUnknownDuration = errors.New("a song before it has no known duration")
+)
+
+// TimeUntil estimates how long until the song at a position of the queue, counting from 1, starts playing:
+// the rest of the current song plus the durations of the songs queued before it. It holds for the current
+// queue order, songs queued later may still be placed ahead of it by the queue strategy.
+// A paused song is counted as if it resumed right away.
+func (p *Player) TimeUntil(position int) (time.Duration, error) {
+	queue := p.GetSongQueue()
+	if position < 1 || position > len(queue) {
+		return 0, ErrNoSuchPosition
+	}
+
+	var until time.Duration
+	if current := p.GetCurrentSong(); current != nil {
+		status := p.GetCurrentStatus()
+		if status == StatusPlaying || status == StatusPaused {
+			if p.GetRepeatMode() == RepeatTrack {
+				return 0, ErrRepeatingTrack
+			}
+			if current.Source.Endless() || current.Duration <= 0 {
+				return 0, fmt.Errorf("%q: %w", current.Title, ErrUnknownDuration)
+			}
+			until += max(0, current.Duration-p.GetPlaybackPosition())
+		}
+	}
+
+	for _, song := range queue[:position-1] {
+		if song.Source.Endless() || song.Duration <= 0 {
+			return 0, fmt.Errorf("%q: %w", song.Title, ErrUnknownDuration)
+		}
+		until += song.Duration
+	}
+
+	return until, nil
+}
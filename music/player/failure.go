@@ -0,0 +1,80 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+// Stages a track can fail at.
+const (
+	FailureResolution = "resolution" // looking up the track or its audio URL
+	FailurePlayback   = "playback"   // encoding and streaming it
+)
+
+// Error classes of failures, see ClassifyFailure.
+const (
+	FailureTimeout     = "timeout"
+	FailureRateLimited = "rate limited"
+	FailureForbidden   = "forbidden"
+	FailureUnavailable = "unavailable"
+	FailureNetwork     = "network"
+	FailureServerError = "server error" // the source answered with a 5xx status
+	FailureNoAudio     = "no audio"     // ffmpeg produced nothing from the audio URL
+	FailureInterrupted = "interrupted"  // playback stopped early and was restarted
+	FailureOther       = "other"
+)
+
+// failureMarkers are parts of error messages by the class they point to, checked in order.
+var failureMarkers = []struct {
+	class   string
+	markers []string
+}{
+	{FailureTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+	{FailureRateLimited, []string{"429", "too many requests", "rate limit"}},
+	{FailureForbidden, []string{"403", "forbidden", "sign in", "age restrict", "login required"}},
+	{FailureServerError, []string{"500", "502", "503", "504", "internal server error", "bad gateway", "service unavailable"}},
+	{FailureUnavailable, []string{"404", "not found", "unavailable", "private", "removed", "no video", "no formats"}},
+	{FailureNetwork, []string{"connection", "no such host", "network", "eof", "tls", "broken pipe"}},
+}
+
+var urlPattern = regexp.MustCompile(`\w+://\S+`)
+
+// ClassifyFailure returns the error class of a failure to resolve or play a track.
+func ClassifyFailure(err error) string {
+	if err == nil {
+		return FailureOther
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureTimeout
+	}
+
+	// URLs in messages are left out, their IDs and parameters may look like status codes
+	message := strings.ToLower(urlPattern.ReplaceAllString(err.Error(), ""))
+	for _, class := range failureMarkers {
+		for _, marker := range class.markers {
+			if strings.Contains(message, marker) {
+				return class.class
+			}
+		}
+	}
+	return FailureOther
+}
+
+// recordFailure counts a failure of the song for the guild's failure report.
+func (p *Player) recordFailure(song *Song, stage, class string) {
+	if song == nil || p.VoiceConnection == nil {
+		return
+	}
+
+	if err := history.NewHistory().AddFailure(p.VoiceConnection.GuildID, song.Source.String(), stage, class); err != nil {
+		slog.Warnf("Error counting %v failure of %q: %v", stage, song.Title, err)
+	}
+}
@@ -0,0 +1,80 @@
+package player
+
+import (
+	"context"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/pkg/dca"
+)
+
+// AudioFormat is one of the audio streams a source offers for a song.
+type AudioFormat struct {
+	URL         string
+	Description string // e.g. itag 251 audio/webm; codecs="opus" 160 kbps, for diagnostics
+}
+
+// formatDescription returns the description of the format the song plays, "unknown" if the source gave none.
+func (s *Song) formatDescription() string {
+	for _, format := range s.Formats {
+		if format.URL == s.DownloadURL {
+			return format.Description
+		}
+	}
+	return "unknown"
+}
+
+// nextFormat switches the song to the format after the one it plays and reports whether there was one.
+func (s *Song) nextFormat() bool {
+	for i, format := range s.Formats {
+		if format.URL == s.DownloadURL && i+1 < len(s.Formats) {
+			s.DownloadURL = s.Formats[i+1].URL
+			s.failedFormats++
+			return true
+		}
+	}
+	return false
+}
+
+// retryWithNextFormat plays the song again with its next audio format if ffmpeg produced no audio,
+// it reports false if the song played or has no other format.
+func (p *Player) retryWithNextFormat(ctx context.Context, encoding *dca.EncodeSession, streaming *dca.StreamingSession) bool {
+	song := p.CurrentSong
+	if p.CurrentStatus != StatusPlaying || streaming.Stats().FramesSent > 0 {
+		return false
+	}
+
+	p.recordFailure(song, FailurePlayback, FailureNoAudio)
+
+	failed := song.formatDescription()
+	if !song.nextFormat() {
+		return false
+	}
+
+	slog.Warnf("No audio of %q with format %v (ffmpeg: %v, %v), retrying with format %v",
+		song.Title, failed, encoding.Error(), encoding.FFMPEGMessages(), song.formatDescription())
+	p.recordEvent(EventError, "No audio of %q with format %v (ffmpeg: %v), retrying with format %v",
+		song.Title, failed, encoding.Error(), song.formatDescription())
+
+	encoding.Cleanup()
+	p.VoiceConnection.Speaking(false)
+
+	p.Play(ctx, encoding.Options().StartTime, song)
+	return true
+}
+
+// logWorkingFormat logs the format of a song that plays after others failed, once its first frame is sent.
+func logWorkingFormat(song *Song, streaming *dca.StreamingSession) {
+	if song.failedFormats == 0 {
+		return
+	}
+
+	format, failed := song.formatDescription(), song.failedFormats
+	go func() {
+		select {
+		case <-streaming.FirstFrameSent():
+			slog.Infof("Playing %q with format %v after %d failed", song.Title, format, failed)
+		case <-time.After(firstFrameTimeout):
+		}
+	}()
+}
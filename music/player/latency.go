@@ -0,0 +1,140 @@
+package player
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/pkg/dca"
+)
+
+const (
+	// latencySamples is how many recent plays percentiles are computed over
+	latencySamples = 100
+	// firstFrameTimeout gives up waiting for the first frame of a play that never started
+	firstFrameTimeout = time.Minute
+)
+
+// PlayLatency is the time spent in each stage of starting a song.
+// Resolution and Startup are only known for songs started right away by a command.
+type PlayLatency struct {
+	Resolution time.Duration // command received until the song was looked up
+	Startup    time.Duration // song looked up until encoding started, including joining voice
+	FirstFrame time.Duration // encoding started until the first frame was sent to Discord
+	Total      time.Duration // command received until the first frame was sent
+}
+
+// LatencyMetrics are percentiles of recent play latencies in milliseconds.
+type LatencyMetrics struct {
+	Plays         int     `json:"plays"`    // plays with a first frame
+	Requests      int     `json:"requests"` // plays started right away by a command
+	FirstFrameP50 float64 `json:"first_frame_p50_ms"`
+	FirstFrameP95 float64 `json:"first_frame_p95_ms"`
+	ResolutionP50 float64 `json:"resolution_p50_ms"`
+	ResolutionP95 float64 `json:"resolution_p95_ms"`
+	TotalP50      float64 `json:"total_p50_ms"`
+	TotalP95      float64 `json:"total_p95_ms"`
+}
+
+// latencyRecorder keeps the latencies of recent plays.
+type latencyRecorder struct {
+	sync.Mutex
+	plays    []PlayLatency // every play, FirstFrame only
+	requests []PlayLatency // plays with all stages
+}
+
+func (r *latencyRecorder) add(latency PlayLatency) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.plays = appendSample(r.plays, latency)
+	if latency.Total > 0 {
+		r.requests = appendSample(r.requests, latency)
+	}
+}
+
+func appendSample(samples []PlayLatency, latency PlayLatency) []PlayLatency {
+	samples = append(samples, latency)
+	if len(samples) > latencySamples {
+		samples = samples[len(samples)-latencySamples:]
+	}
+	return samples
+}
+
+func (r *latencyRecorder) metrics() LatencyMetrics {
+	r.Lock()
+	defer r.Unlock()
+
+	firstFrame := durationsOf(r.plays, func(l PlayLatency) time.Duration { return l.FirstFrame })
+	resolution := durationsOf(r.requests, func(l PlayLatency) time.Duration { return l.Resolution })
+	total := durationsOf(r.requests, func(l PlayLatency) time.Duration { return l.Total })
+
+	return LatencyMetrics{
+		Plays:         len(r.plays),
+		Requests:      len(r.requests),
+		FirstFrameP50: percentile(firstFrame, 50),
+		FirstFrameP95: percentile(firstFrame, 95),
+		ResolutionP50: percentile(resolution, 50),
+		ResolutionP95: percentile(resolution, 95),
+		TotalP50:      percentile(total, 50),
+		TotalP95:      percentile(total, 95),
+	}
+}
+
+func durationsOf(samples []PlayLatency, stage func(PlayLatency) time.Duration) []time.Duration {
+	durations := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		durations[i] = stage(sample)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations
+}
+
+// percentile returns the nearest rank percentile of sorted durations in milliseconds.
+func percentile(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
+
+// trackPlayLatency waits for the first frame of a new play, then logs and records how long each stage took.
+func (p *Player) trackPlayLatency(song *Song, encodeStart time.Time, streaming *dca.StreamingSession) {
+	requestedAt, resolvedAt := song.RequestedAt, song.ResolvedAt
+
+	// Only the play the command started counts, not replays of the song from history or restarts
+	song.RequestedAt, song.ResolvedAt = time.Time{}, time.Time{}
+
+	go func() {
+		defer p.trackGoroutine()()
+
+		select {
+		case <-streaming.FirstFrameSent():
+		case <-time.After(firstFrameTimeout):
+			slog.Warnf("No frame of %q was sent within %v", song.Title, firstFrameTimeout)
+			p.recordEvent(EventError, "No frame of %q was sent within %v", song.Title, firstFrameTimeout)
+			return
+		}
+
+		latency := PlayLatency{FirstFrame: time.Since(encodeStart)}
+		if !requestedAt.IsZero() {
+			latency.Resolution = resolvedAt.Sub(requestedAt)
+			latency.Startup = encodeStart.Sub(resolvedAt)
+			latency.Total = time.Since(requestedAt)
+
+			slog.Infof("Play latency of %q: %v total (resolution %v, startup %v, first frame %v)", song.Title,
+				latency.Total.Round(time.Millisecond), latency.Resolution.Round(time.Millisecond),
+				latency.Startup.Round(time.Millisecond), latency.FirstFrame.Round(time.Millisecond))
+		} else {
+			slog.Infof("Play latency of %q: first frame after %v", song.Title, latency.FirstFrame.Round(time.Millisecond))
+		}
+
+		p.latencies.add(latency)
+	}()
+}
@@ -0,0 +1,116 @@
+package player
+
+import (
+	"context"
+
+	"github.com/gookit/slog"
+
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+)
+
+// ResolvedAhead is how many songs at the head of the queue are kept resolved. Songs behind them are
+// lightweight references that are resolved when they move up, so huge playlists keep memory bounded
+// and their download URLs don't expire while waiting.
+const ResolvedAhead = 3
+
+// SongResolver fills in the download URL of a song queued as a lightweight reference.
+type SongResolver func(ctx context.Context, song *Song) error
+
+// Resolve returns the song with its download URL looked up if it's a lightweight reference, the song itself
+// otherwise. Queued songs are read by queue listings while they are resolved ahead, so the lookup fills in a copy
+// that replaces the song once complete rather than the song itself.
+func (s *Song) Resolve(ctx context.Context) (*Song, error) {
+	if s.Resolver == nil || s.DownloadURL != "" {
+		return s, nil
+	}
+
+	ctx, span := tracing.Start(tracing.WithParent(ctx, s.Trace), "resolve queued", tracing.String("song.title", s.Title))
+	defer span.End()
+
+	resolved := *s
+	err := s.Resolver(ctx, &resolved)
+	span.SetError(err)
+	if err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+// released returns a copy of a resolved song turned back into a lightweight reference, nil if there's nothing
+// to release.
+func (s *Song) released() *Song {
+	if s.Resolver == nil || s.DownloadURL == "" {
+		return nil
+	}
+
+	released := *s
+	released.DownloadURL = ""
+	released.Formats = nil
+	released.failedFormats = 0
+	return &released
+}
+
+// keepHeadResolved resolves the first ResolvedAhead songs in the background and releases songs behind them.
+// Must be called with the queue lock held.
+func (q *songQueue) keepHeadResolved() {
+	var head []*Song
+
+	for i, song := range q.songs {
+		if song.Resolver == nil {
+			continue
+		}
+
+		if i >= ResolvedAhead {
+			if released := song.released(); released != nil {
+				q.replace(song, released)
+			}
+			continue
+		}
+		if song.DownloadURL != "" || q.resolving[song] {
+			continue
+		}
+		if q.resolving == nil {
+			q.resolving = make(map[*Song]bool)
+		}
+		q.resolving[song] = true
+		head = append(head, song)
+	}
+
+	if len(head) == 0 {
+		return
+	}
+
+	// The queue outlives single plays, songs are resolved ahead for whichever play takes them
+	go func() {
+		for _, song := range head {
+			resolved, err := song.Resolve(context.Background())
+			if err != nil {
+				slog.Warnf("Error resolving queued song %q: %v", song.Title, err)
+			}
+
+			q.Lock()
+			delete(q.resolving, song)
+			if resolved != nil {
+				q.replace(song, resolved)
+			}
+			q.Unlock()
+		}
+	}()
+}
+
+// replace puts a song in place of another in the queue and its undo history, if the song is still there.
+// Must be called with the queue lock held.
+func (q *songQueue) replace(song, with *Song) {
+	for i := range q.songs {
+		if q.songs[i] == song {
+			q.songs[i] = with
+		}
+	}
+	for _, undo := range q.undo {
+		for i := range undo.songs {
+			if undo.songs[i] == song {
+				undo.songs[i] = with
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func lightweightSongs(count int) []*Song {
+	resolver := func(ctx context.Context, song *Song) error {
+		time.Sleep(time.Millisecond)
+		song.DownloadURL = "https://example.com/" + song.Title
+		song.Duration = time.Minute
+		song.Thumbnail = Thumbnail{URL: "https://example.com/" + song.Title + ".jpg"}
+		return nil
+	}
+
+	songs := make([]*Song, count)
+	for i := range songs {
+		songs[i] = &Song{Title: fmt.Sprint(i), UserURL: fmt.Sprint(i), Resolver: resolver}
+	}
+	return songs
+}
+
+func TestResolveCopiesSong(t *testing.T) {
+	song := lightweightSongs(1)[0]
+
+	resolved, err := song.Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved == song || song.DownloadURL != "" {
+		t.Errorf("Queued song was changed by resolving it")
+	}
+	if resolved.DownloadURL == "" || resolved.Duration != time.Minute {
+		t.Errorf("Incorrect resolved song %+v", resolved)
+	}
+
+	again, err := resolved.Resolve(context.Background())
+	if err != nil || again != resolved {
+		t.Errorf("Resolved song was resolved again")
+	}
+}
+
+func TestKeepHeadResolved(t *testing.T) {
+	q := NewQueue(fifoStrategy{})
+	q.Push(lightweightSongs(10)...)
+
+	var head []*Song
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		head = q.List()[:ResolvedAhead]
+		if head[ResolvedAhead-1].DownloadURL != "" {
+			break
+		}
+	}
+
+	for i, song := range q.List() {
+		if resolved := song.DownloadURL != ""; resolved != (i < ResolvedAhead) {
+			t.Errorf("Song %v at position %v resolved: %v", song.Title, i, resolved)
+		}
+	}
+
+	// Songs moved behind the head are released
+	q.Push(head...)
+	q.Pop()
+	list := q.List()
+	if released := list[len(list)-1]; released.DownloadURL != "" || released.Formats != nil {
+		t.Errorf("Song %v behind the head wasn't released", released.Title)
+	}
+}
+
+// TestKeepHeadResolvedConcurrently is meant for the race detector: queued songs are read while they are resolved
+// ahead and released.
+func TestKeepHeadResolvedConcurrently(t *testing.T) {
+	q := NewQueue(fairStrategy{})
+	q.Push(lightweightSongs(20)...)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for _, song := range q.List() {
+				_ = song.DownloadURL + song.Thumbnail.URL
+				_ = song.Duration
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		if song := q.Pop(); song != nil {
+			q.Push(song)
+		}
+		q.Shuffle()
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	wg.Wait()
+}
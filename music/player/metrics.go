@@ -0,0 +1,120 @@
+package player
+
+import (
+	"sync"
+	"time"
+
+	"github.com/keshon/melodix-discord-player/music/pkg/dca"
+)
+
+// Metrics is a snapshot of the encoder resource usage and stream counters of a player.
+// Counters are totals since the bot started, including the current song.
+type Metrics struct {
+	EncoderRunning bool           `json:"encoder_running"`
+	EncoderPID     int            `json:"encoder_pid,omitempty"`
+	EncoderCPU     float64        `json:"encoder_cpu_percent"`  // share of one core since the previous snapshot
+	EncoderMemory  int64          `json:"encoder_memory_bytes"` // resident memory of ffmpeg
+	BytesStreamed  int64          `json:"bytes_streamed"`
+	FramesSent     int            `json:"frames_sent"`
+	FramesDropped  int            `json:"frames_dropped"`
+	FramesLate     int            `json:"frames_late"`   // frames ffmpeg couldn't encode in time
+	SendStalls     int            `json:"send_stalls"`   // times the voice connection blocked sending
+	JitterMs       float64        `json:"jitter_ms"`     // of the current song
+	BufferFrames   int            `json:"buffer_frames"` // jitter buffer depth of the current song
+	Reconnects     int            `json:"reconnects"`    // ffmpeg reconnects to the source URL
+	Restarts       int            `json:"restarts"`      // playbacks resumed after an interruption
+	Latency        LatencyMetrics `json:"latency"`
+}
+
+// playerMetrics tracks the sessions of the current song and the totals of finished ones.
+type playerMetrics struct {
+	sync.Mutex
+	encoding  *dca.EncodeSession
+	streaming *dca.StreamingSession
+	totals    Metrics
+	lastCPU   *dca.ProcessStats // previous CPU sample of the encoder
+	lastCPUAt time.Time
+}
+
+// track starts tracking the sessions of a new song, adding counters of the previous one to the totals.
+func (m *playerMetrics) track(encoding *dca.EncodeSession, streaming *dca.StreamingSession) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.addSessions(&m.totals)
+	m.encoding, m.streaming = encoding, streaming
+	m.lastCPU = nil
+}
+
+func (m *playerMetrics) addRestart() {
+	m.Lock()
+	m.totals.Restarts++
+	m.Unlock()
+}
+
+// addSessions adds counters of the tracked sessions to metrics.
+func (m *playerMetrics) addSessions(metrics *Metrics) {
+	if m.streaming != nil {
+		stats := m.streaming.Stats()
+		metrics.BytesStreamed += stats.BytesSent
+		metrics.FramesSent += stats.FramesSent
+		metrics.FramesDropped += stats.FramesDropped
+		metrics.FramesLate += stats.FramesLate
+		metrics.SendStalls += stats.SendStalls
+	}
+	if m.encoding != nil {
+		metrics.Reconnects += m.encoding.Reconnects()
+	}
+}
+
+// snapshot returns the current metrics, sampling the encoder process.
+func (m *playerMetrics) snapshot() Metrics {
+	m.Lock()
+	defer m.Unlock()
+
+	metrics := m.totals
+	m.addSessions(&metrics)
+	if m.streaming != nil {
+		metrics.JitterMs = float64(m.streaming.Stats().Jitter) / float64(time.Millisecond)
+		metrics.BufferFrames = m.streaming.BufferFrames()
+	}
+
+	if m.encoding == nil {
+		return metrics
+	}
+
+	process, err := m.encoding.ProcessStats()
+	if err != nil {
+		// Not running or no procfs on this system
+		return metrics
+	}
+
+	metrics.EncoderRunning = true
+	metrics.EncoderPID = process.PID
+	metrics.EncoderMemory = process.RSS
+
+	// CPU usage is the delta to the previous sample, or the average since start on the first one
+	now := time.Now()
+	if m.lastCPU != nil && m.lastCPU.PID == process.PID && now.Sub(m.lastCPUAt) > 0 {
+		metrics.EncoderCPU = percentOf(process.CPUTime-m.lastCPU.CPUTime, now.Sub(m.lastCPUAt))
+	} else {
+		metrics.EncoderCPU = percentOf(process.CPUTime, process.Uptime)
+	}
+	m.lastCPU, m.lastCPUAt = process, now
+
+	return metrics
+}
+
+func percentOf(part, whole time.Duration) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+// GetMetrics returns encoder resource usage and stream counters of the player.
+func (p *Player) GetMetrics() Metrics {
+	metrics := p.metrics.snapshot()
+	metrics.Latency = p.latencies.metrics()
+	return metrics
+}
@@ -0,0 +1,52 @@
+package player
+
+import "fmt"
+
+// EncodeOverrides replace options of the global encode config for one guild, zero values keep the global ones.
+type EncodeOverrides struct {
+	Bitrate       int     // kb/s
+	FrameDuration int     // ms
+	VolumeCeiling float32 // highest volume songs play at, 0.0-1.0
+}
+
+// Validate returns an error if an override is out of the range ffmpeg and Discord accept.
+func (o EncodeOverrides) Validate() error {
+	if o.Bitrate != 0 && (o.Bitrate < 8 || o.Bitrate > 128) {
+		return fmt.Errorf("bitrate must be between 8 and 128 kb/s")
+	}
+	if o.FrameDuration != 0 && o.FrameDuration != 20 && o.FrameDuration != 40 && o.FrameDuration != 60 {
+		return fmt.Errorf("frame duration must be 20, 40 or 60 ms")
+	}
+	if o.VolumeCeiling != 0 && (o.VolumeCeiling < 0.05 || o.VolumeCeiling > 1.0) {
+		return fmt.Errorf("volume ceiling must be between 0.05 and 1.0")
+	}
+	return nil
+}
+
+// GetEncodeOverrides returns the encode options of the guild that replace the global ones.
+func (p *Player) GetEncodeOverrides() EncodeOverrides {
+	p.Lock()
+	defer p.Unlock()
+	return p.overrides
+}
+
+// SetEncodeOverrides replaces encode options of the global config, songs started afterwards use them.
+func (p *Player) SetEncodeOverrides(overrides EncodeOverrides) error {
+	if err := overrides.Validate(); err != nil {
+		return err
+	}
+
+	p.Lock()
+	p.overrides = overrides
+	p.Unlock()
+
+	return nil
+}
+
+// frameDuration returns the frame duration override, or configured if there is none.
+func (p *Player) frameDuration(configured int) int {
+	if duration := p.GetEncodeOverrides().FrameDuration; duration != 0 {
+		return duration
+	}
+	return configured
+}
@@ -17,5 +17,6 @@ func (p *Player) Pause() {
 	if p.CurrentStatus == StatusPlaying {
 		p.StreamingSession.SetPaused(true)
 		p.CurrentStatus = StatusPaused
+		p.recordEvent(EventPause, "Playback paused")
 	}
 }
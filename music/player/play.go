@@ -1,57 +1,118 @@
 package player
 
 import (
+	"context"
 	"io"
 	"sync"
 	"time"
 
 	"github.com/gookit/slog"
-	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
 	"github.com/keshon/melodix-discord-player/music/history"
 	"github.com/keshon/melodix-discord-player/music/pkg/dca"
 	"github.com/keshon/melodix-discord-player/music/utils"
 )
 
-// Play starts playing the current or specified song.
-func (p *Player) Play(startAt int, song *Song) {
+// Play starts playing the current or specified song. Playback stops once the context ends,
+// the songs the player goes on to by itself play under the same context.
+func (p *Player) Play(ctx context.Context, startAt int, song *Song) {
+	defer p.trackPlay()()
+
 	var cleanupDone sync.WaitGroup
 
+	p.Lock()
+	p.ctx = ctx
+	p.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
 	// Listen for skip signal
 	if p.handleSkipSignal() {
 		return
 	}
 
+	// Songs passed as arg are restarts of the current one, only fresh ones count as a new play
+	isNewPlay := song == nil
+
 	// Get current song (from queue or as arg)
-	p.setupCurrentSong(startAt, song)
+	p.setupCurrentSong(ctx, startAt, song)
+	if p.CurrentSong == nil {
+		// Every queued song failed to resolve
+		p.CurrentStatus = StatusResting
+		return
+	}
+
+	// Long-form songs resume where the guild stopped listening to them
+	if isNewPlay && startAt == 0 {
+		startAt = int(p.resumePosition(p.CurrentSong).Seconds())
+	}
+
+	// Playing is traced within the request of the song
+	traceCtx, playSpan := tracing.Start(tracing.WithParent(ctx, p.CurrentSong.Trace), "play",
+		tracing.String("song.title", p.CurrentSong.Title),
+		tracing.String("song.source", p.CurrentSong.Source.String()),
+		tracing.Int("play.start_at", startAt),
+		tracing.Bool("play.restart", !isNewPlay))
 
 	// Setup encoding
 	options := p.createEncodeOptions(startAt)
+	if p.CurrentSong != nil {
+		options.InputFormat = p.CurrentSong.InputFormat
+	}
 
 	// Start encoding
+	_, encodeSpan := tracing.Start(traceCtx, "encode start")
+	encodeStart := time.Now()
 	var encodeSessionError error
 	p.EncodingSession, encodeSessionError = dca.EncodeFile(p.CurrentSong.DownloadURL, options)
 	defer p.EncodingSession.Cleanup()
+	encodeSpan.SetError(encodeSessionError)
+	encodeSpan.End()
+	if encodeSessionError != nil {
+		p.recordEvent(EventError, "Encoding %q: %v", p.CurrentSong.Title, encodeSessionError)
+	}
 
 	// Connect to Discord channel and be ready
+	_, voiceSpan := tracing.Start(traceCtx, "voice ready")
 	p.setupVoiceConnection()
+	voiceSpan.End()
 
 	// Send encoding to Discord stream
+	_, streamSpan := tracing.Start(traceCtx, "stream")
 	done := make(chan error)
-	p.StreamingSession = dca.NewStream(p.EncodingSession, p.VoiceConnection, done)
+	streamOptions := p.createStreamOptions()
+	p.StreamingSession = dca.NewStreamWithOptions(p.EncodingSession, p.VoiceConnection, done, streamOptions)
+	endStream := traceStream(streamSpan, p.StreamingSession)
+	p.metrics.track(p.EncodingSession, p.StreamingSession)
+	if isNewPlay {
+		p.trackPlayLatency(p.CurrentSong, encodeStart, p.StreamingSession)
+	}
+	logWorkingFormat(p.CurrentSong, p.StreamingSession)
 
 	// Set player status
 	p.CurrentStatus = StatusPlaying
+	if isNewPlay {
+		p.recordEvent(EventPlay, "%q from %v", p.CurrentSong.Title, time.Duration(startAt)*time.Second)
+	} else {
+		p.recordEvent(EventRestart, "%q from %v", p.CurrentSong.Title, time.Duration(startAt)*time.Second)
+	}
 
 	// Setup history
 	h := history.NewHistory()
 
 	// Add current track to history
-	p.addSongToHistory(h)
+	p.addSongToHistory(h, isNewPlay)
 
-	p.setupPlaybackDurationStatsTicker(h)
+	stopStatsTicker := p.setupPlaybackDurationStatsTicker(h)
 
-	// Done signal
-	p.handleDoneSignal(done, h, encodeSessionError, &cleanupDone)
+	// Done signal, Play goes on with the next song before returning so the spans end with the stream
+	endSpans := func() {
+		endStream()
+		playSpan.End()
+	}
+	p.handleDoneSignal(ctx, done, h, encodeSessionError, &cleanupDone, stopStatsTicker, endSpans)
 }
 
 func (p *Player) handleSkipSignal() bool {
@@ -59,9 +120,16 @@ func (p *Player) handleSkipSignal() bool {
 	case <-p.SkipInterrupt:
 		slog.Info("Song is interrupted for skip, stopping playback")
 
+		// The skipped song mustn't restart where it was seeked to
+		p.takeSeek()
+
 		if p.VoiceConnection != nil {
 			p.VoiceConnection.Speaking(false)
 		}
+		if p.StreamingSession != nil {
+			// Don't let frames of the jitter buffer play over the next song
+			p.StreamingSession.Stop()
+		}
 		p.EncodingSession.Cleanup()
 
 		return true
@@ -71,7 +139,25 @@ func (p *Player) handleSkipSignal() bool {
 	}
 }
 
-func (p *Player) setupCurrentSong(startAt int, song *Song) {
+func (p *Player) createStreamOptions() dca.StreamOptions {
+	config := p.config
+
+	options := dca.StreamOptions{
+		BufferFrames:    config.DcaStreamBufferFrames,
+		MinBufferFrames: config.DcaStreamBufferFrames,
+		MaxBufferFrames: config.DcaStreamBufferMaxFrames,
+	}
+
+	// Keep a depth grown by send stalls during the previous song, the network is likely still spiky.
+	// It shrinks back to the configured depth once the stalls stop.
+	if p.StreamingSession != nil && options.BufferFrames > 0 && p.StreamingSession.BufferFrames() > options.BufferFrames {
+		options.BufferFrames = p.StreamingSession.BufferFrames()
+	}
+
+	return options
+}
+
+func (p *Player) setupCurrentSong(ctx context.Context, startAt int, song *Song) {
 	if song != nil {
 		p.CurrentSong = song
 	} else {
@@ -80,6 +166,27 @@ func (p *Player) setupCurrentSong(startAt int, song *Song) {
 		}
 	}
 
+	// Songs of large playlists are queued as lightweight references, skip those that can't be played anymore
+	for p.CurrentSong != nil {
+		resolved, err := p.CurrentSong.Resolve(ctx)
+		if err == nil {
+			p.CurrentSong = resolved
+			break
+		}
+		if ctx.Err() != nil {
+			// Not a failure of the song, playback ended while it was looked up
+			p.CurrentSong = nil
+			return
+		}
+		class := ClassifyFailure(err)
+		p.recordFailure(p.CurrentSong, FailureResolution, class)
+		p.recordEvent(EventError, "Resolving %q: %v", p.CurrentSong.Title, err)
+		if !p.retryLater(p.CurrentSong, class) {
+			slog.Warnf("Skipping %q, it could not be resolved: %v", p.CurrentSong.Title, err)
+		}
+		p.CurrentSong = p.Dequeue()
+	}
+
 	if p.CurrentSong == nil {
 		slog.Info("No songs in queue")
 		return
@@ -87,15 +194,12 @@ func (p *Player) setupCurrentSong(startAt int, song *Song) {
 }
 
 func (p *Player) createEncodeOptions(startAt int) *dca.EncodeOptions {
-	config, err := config.NewConfig()
-	if err != nil {
-		slog.Fatalf("Error loading config: %v", err)
-	}
+	config := p.config
 
 	return &dca.EncodeOptions{
-		Volume:                  1.0,
-		FrameDuration:           config.DcaFrameDuration,
-		Bitrate:                 config.DcaBitrate,
+		Volume:                  p.volume(),
+		FrameDuration:           p.frameDuration(config.DcaFrameDuration),
+		Bitrate:                 p.bitrate(config.DcaBitrate),
 		PacketLoss:              config.DcaPacketLoss,
 		RawOutput:               config.DcaRawOutput,
 		Application:             config.DcaApplication,
@@ -132,7 +236,7 @@ func (p *Player) setupVoiceConnection() {
 	}
 }
 
-func (p *Player) addSongToHistory(h history.IHistory) {
+func (p *Player) addSongToHistory(h history.IHistory, isNewPlay bool) {
 	historySong := &history.Song{
 		Name:        p.CurrentSong.Title,
 		UserURL:     p.CurrentSong.UserURL,
@@ -140,26 +244,53 @@ func (p *Player) addSongToHistory(h history.IHistory) {
 		Duration:    p.CurrentSong.Duration,
 		ID:          p.CurrentSong.ID,
 		Thumbnail:   history.Thumbnail(p.CurrentSong.Thumbnail),
+		RequesterID: p.CurrentSong.RequesterID,
+	}
+
+	if err := h.AddTrackToHistory(p.VoiceConnection.GuildID, historySong); err != nil {
+		slog.Warnf("Error adding track to history: %v", err)
+		return
+	}
+
+	if isNewPlay {
+		if err := h.AddRequestToHistory(p.VoiceConnection.GuildID, historySong); err != nil {
+			slog.Warnf("Error adding request to history: %v", err)
+		}
 	}
-	h.AddTrackToHistory(p.VoiceConnection.GuildID, historySong)
 }
 
-func (p *Player) setupPlaybackDurationStatsTicker(h history.IHistory) {
+// setupPlaybackDurationStatsTicker periodically adds played time to history and remembers the position of
+// long-form songs until the returned func is called.
+func (p *Player) setupPlaybackDurationStatsTicker(h history.IHistory) func() {
 	interval := 2 * time.Second
 	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
 	tickerDone := make(chan bool)
 
 	go func() {
+		defer p.trackGoroutine()()
+		defer ticker.Stop()
+		var sinceSaved time.Duration
 		for {
 			select {
 			case <-ticker.C:
 				p.addPlaybackStatsToHistory(h, interval)
+
+				if sinceSaved += interval; sinceSaved >= positionSaveInterval {
+					sinceSaved = 0
+					p.rememberPosition(h)
+				}
 			case <-tickerDone:
 				return
 			}
 		}
 	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(tickerDone)
+		})
+	}
 }
 
 func (p *Player) addPlaybackStatsToHistory(h history.IHistory, interval time.Duration) {
@@ -173,16 +304,44 @@ func (p *Player) addPlaybackStatsToHistory(h history.IHistory, interval time.Dur
 	}
 }
 
-func (p *Player) handleDoneSignal(done chan error, h history.IHistory, errEnc error, cleanupDone *sync.WaitGroup) {
+func (p *Player) handleDoneSignal(ctx context.Context, done chan error, h history.IHistory, errEnc error, cleanupDone *sync.WaitGroup, stopStatsTicker, endSpans func()) {
 	select {
+	case <-ctx.Done():
+		// The voice connection is left to whoever ended the context
+		slog.Infof("Stopping playback: %v", ctx.Err())
+		p.recordEvent(EventStop, "Playback ended: %v", ctx.Err())
+		stopStatsTicker()
+		p.StreamingSession.Stop()
+		p.EncodingSession.Stop()
+		endSpans()
+		p.CurrentStatus = StatusResting
 	case <-done:
+		stopStatsTicker()
+		endSpans()
+
 		cleanupDone.Add(1)
 		go func() {
+			defer p.trackGoroutine()()
+
 			// Auto-restarting logic in case of interruption
 			// Youtube songs checked by their current vs total duration
-			// Streams (radio) never stop
+			// Streams (radio) and ambience loops never stop
 			if p.VoiceConnection != nil && p.StreamingSession != nil && p.CurrentSong != nil {
-				if p.CurrentSong.Source != SourceStream {
+				if position, ok := p.takeSeek(); ok {
+					p.EncodingSession.Cleanup()
+					p.VoiceConnection.Speaking(false)
+
+					p.Play(ctx, int(position.Seconds()), p.CurrentSong)
+
+					return
+				}
+
+				// ffmpeg may fail on the selected format while others of the song still work
+				if p.retryWithNextFormat(ctx, p.EncodingSession, p.StreamingSession) {
+					return
+				}
+
+				if !p.CurrentSong.Source.Endless() {
 					songDuration, songPosition := p.getSongMetrics(p.EncodingSession, p.StreamingSession, p.CurrentSong)
 					if p.CurrentStatus == StatusPlaying {
 						if p.EncodingSession.Stats().Duration.Seconds() > 0 && songPosition.Seconds() > 0 {
@@ -191,12 +350,17 @@ func (p *Player) handleDoneSignal(done chan error, h history.IHistory, errEnc er
 
 								p.EncodingSession.Cleanup()
 								p.VoiceConnection.Speaking(false)
+								p.metrics.addRestart()
+								p.recordFailure(p.CurrentSong, FailurePlayback, FailureInterrupted)
 
-								p.Play(int(songPosition.Seconds()), p.CurrentSong)
+								p.Play(ctx, int(songPosition.Seconds()), p.CurrentSong)
 
 								return
 							}
 						}
+
+						// Listened to the end, played again it starts over
+						p.forgetPosition(h, p.CurrentSong)
 					}
 				} else {
 					if p.CurrentStatus == StatusPlaying {
@@ -205,8 +369,9 @@ func (p *Player) handleDoneSignal(done chan error, h history.IHistory, errEnc er
 
 						p.EncodingSession.Cleanup()
 						p.VoiceConnection.Speaking(false)
+						p.metrics.addRestart()
 
-						p.Play(0, p.CurrentSong)
+						p.Play(ctx, 0, p.CurrentSong)
 
 						return
 
@@ -221,6 +386,10 @@ func (p *Player) handleDoneSignal(done chan error, h history.IHistory, errEnc er
 
 			if errEnc != nil && errEnc != io.EOF {
 				slog.Warnf("Song is done but an unexpected error occurred: %v", errEnc)
+				p.recordEvent(EventError, "Playing %q: %v", p.CurrentSong.Title, errEnc)
+				class := ClassifyFailure(errEnc)
+				p.recordFailure(p.CurrentSong, FailurePlayback, class)
+				p.retryLater(p.CurrentSong, class)
 
 				time.Sleep(250 * time.Millisecond)
 				if p.VoiceConnection != nil {
@@ -234,8 +403,20 @@ func (p *Player) handleDoneSignal(done chan error, h history.IHistory, errEnc er
 
 			slog.Info("Song is done")
 
-			if len(p.GetSongQueue()) == 0 {
+			// Skipped songs end with the player resting, they don't repeat but stay in a repeated queue
+			if p.CurrentSong != nil && p.CurrentStatus == StatusPlaying && p.GetRepeatMode() == RepeatTrack {
+				slog.Info("Repeating song")
+
+				time.Sleep(250 * time.Millisecond)
+				p.Play(ctx, 0, p.CurrentSong)
+
+				return
+			}
+			p.requeue(p.CurrentSong)
+
+			if len(p.GetSongQueue()) == 0 && !p.autoplayNext(ctx, p.CurrentSong) {
 				slog.Info("Queue is done")
+				p.recordEvent(EventQueueDone, "After %q", p.CurrentSong.Title)
 
 				time.Sleep(250 * time.Millisecond)
 				p.Stop()
@@ -246,7 +427,7 @@ func (p *Player) handleDoneSignal(done chan error, h history.IHistory, errEnc er
 			time.Sleep(250 * time.Millisecond)
 
 			slog.Info("Playing next song in queue")
-			p.Play(0, nil)
+			p.Play(ctx, 0, nil)
 		}()
 	}
 	cleanupDone.Wait()
@@ -2,11 +2,14 @@
 package player
 
 import (
+	"context"
 	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
 	"github.com/keshon/melodix-discord-player/music/pkg/dca"
 )
 
@@ -22,27 +25,49 @@ type SongSource int32
 const (
 	SourceYouTube SongSource = iota
 	SourceStream
+	SourceAmbience
+	SourceSoundCloud
 )
 
 // String returns the string representation of the SongSource.
 func (source SongSource) String() string {
 	sources := map[SongSource]string{
-		SourceYouTube: "YouTube",
-		SourceStream:  "Stream",
+		SourceYouTube:    "YouTube",
+		SourceStream:     "Stream",
+		SourceAmbience:   "Ambience",
+		SourceSoundCloud: "SoundCloud",
 	}
 
 	return sources[source]
 }
 
+// Endless reports whether songs of the source never finish: they restart when interrupted and can't be seeked.
+func (source SongSource) Endless() bool {
+	return source == SourceStream || source == SourceAmbience
+}
+
 // Song represents a media item with relevant information.
 type Song struct {
-	Title       string        // Title of the song
-	UserURL     string        // URL provided by the user
-	DownloadURL string        // URL for downloading the song
-	Thumbnail   Thumbnail     // Thumbnail image for the song
-	Duration    time.Duration // Duration of the song
-	ID          string        // Unique ID for the song
-	Source      SongSource    // Source type of the song
+	Title       string              // Title of the song
+	UserURL     string              // URL provided by the user
+	DownloadURL string              // URL for downloading the song
+	Thumbnail   Thumbnail           // Thumbnail image for the song
+	Duration    time.Duration       // Duration of the song
+	ID          string              // Unique ID for the song
+	Source      SongSource          // Source type of the song
+	RequesterID string              // Discord user ID of the requester
+	Priority    int                 // Songs with higher priority play first in weighted queue order
+	RequestedAt time.Time           // When the command that starts this song right away was received, zero otherwise
+	ResolvedAt  time.Time           // When the song was looked up for that command
+	Resolver    SongResolver        `json:"-"` // Set for lightweight songs whose DownloadURL is looked up when they are about to play
+	Formats     []AudioFormat       `json:"-"` // Audio formats best first, DownloadURL is one of them, the next is tried if ffmpeg fails on it
+	InputFormat string              // ffmpeg input format of DownloadURL, empty to probe it, e.g. "lavfi" for generated audio
+	Fresh       bool                // Long-form songs requested fresh start over rather than resuming where they were left
+	Trace       tracing.SpanContext `json:"-"` // Trace of the request, playing the song is traced within it
+	Autoplayed  bool                // Queued by autoplay once the queue ran out rather than requested
+
+	failedFormats int // formats that produced no audio since the song was resolved
+	retryAttempts int // retries after transient failures, see retryLater
 }
 
 // PlaybackStatus represents the playback status of the Player.
@@ -84,19 +109,39 @@ type Player struct {
 	VoiceConnection  *discordgo.VoiceConnection
 	StreamingSession *dca.StreamingSession
 	EncodingSession  *dca.EncodeSession
-	SongQueue        []*Song
+	Queue            Queue
 	CurrentSong      *Song
+	config           *config.Config // read once by NewPlayer
 	CurrentStatus    PlaybackStatus
 	SkipInterrupt    chan bool
+	metrics          playerMetrics
+	latencies        latencyRecorder
+	diagnostics      playerDiagnostics
+	seekPending      bool // set by Seek until the stopped stream restarts at seekPosition
+	seekPosition     time.Duration
+	ducked           bool   // volume lowered while people talk, see SetDucked
+	quality          string // quality preset, empty for normal
+	overrides        EncodeOverrides
+	retries          retryList // songs waiting to be queued again after a transient failure
+	repeat           RepeatMode
+	ctx              context.Context // of the playback started last, see playContext
+	autoplay         AutoplaySource  // nil disconnects once the queue ran out
 }
 
 // IPlayer defines the interface for managing audio playback and song queue.
 type IPlayer interface {
-	Play(startAt int, song *Song)
+	Play(ctx context.Context, startAt int, song *Song)
+	PlayClip(ctx context.Context, url string) error
+	PlayGeneratedClip(ctx context.Context, source string) error
 	Skip()
 	Enqueue(song *Song)
 	Dequeue() *Song
 	ClearQueue()
+	ShuffleQueue() error
+	RemoveFromQueue(position int) (*Song, error)
+	MoveInQueue(from, to int) error
+	UndoQueue() (string, error)
+	GetRetryingSongs() []*Song
 	Stop()
 	Pause()
 	Unpause()
@@ -107,21 +152,53 @@ type IPlayer interface {
 	SetVoiceConnection(voiceConnection *discordgo.VoiceConnection)
 	GetStreamingSession() *dca.StreamingSession
 	GetCurrentSong() *Song
+	GetQueueStrategy() QueueStrategy
+	SetQueueStrategy(strategy QueueStrategy)
+	GetRepeatMode() RepeatMode
+	SetRepeatMode(mode RepeatMode)
+	SetAutoplay(source AutoplaySource)
+	IsAutoplay() bool
+	GetMetrics() Metrics
+	GetDiagnostics() Diagnostics
+	GetPlaybackPosition() time.Duration
+	TimeUntil(position int) (time.Duration, error)
+	Seek(position time.Duration) (time.Duration, error)
+	SeekBy(offset time.Duration) (time.Duration, error)
+	SetDucked(ducked bool)
+	IsDucked() bool
+	GetQuality() string
+	SetQuality(name string) error
+	GetEncodeInfo() (EncodeInfo, bool)
+	GetEncodeOverrides() EncodeOverrides
+	SetEncodeOverrides(overrides EncodeOverrides) error
 }
 
-// NewPlayer creates a new Player instance.
-func NewPlayer(guildID string) IPlayer {
+// NewPlayer creates a new Player instance with the settings of config.
+func NewPlayer(guildID string, config *config.Config) IPlayer {
 	return &Player{
+		config:           config,
 		VoiceConnection:  nil,
 		SkipInterrupt:    make(chan bool, 1),
 		StreamingSession: nil,
 		EncodingSession:  nil,
-		SongQueue:        make([]*Song, 0),
+		Queue:            NewQueue(fifoStrategy{}),
 		CurrentSong:      nil,
 		CurrentStatus:    StatusResting,
 	}
 }
 
+// playContext returns the context playback was last started with. Songs the player goes on to by itself,
+// e.g. after a skip or a retry, play under it.
+func (p *Player) playContext() context.Context {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
 // GetStatus returns the current playback status.
 func (p *Player) GetCurrentStatus() PlaybackStatus {
 	return p.CurrentStatus
@@ -134,9 +211,9 @@ func (p *Player) SetCurrentStatus(status PlaybackStatus) {
 	p.CurrentStatus = status
 }
 
-// GetSongQueue returns the song queue.
+// GetSongQueue returns the song queue in play order.
 func (p *Player) GetSongQueue() []*Song {
-	return p.SongQueue
+	return p.Queue.List()
 }
 
 // GetVoiceConnection returns the voice connection.
@@ -160,3 +237,13 @@ func (p *Player) GetCurrentSong() *Song {
 func (p *Player) GetStreamingSession() *dca.StreamingSession {
 	return p.StreamingSession
 }
+
+// GetQueueStrategy returns the strategy that orders the song queue.
+func (p *Player) GetQueueStrategy() QueueStrategy {
+	return p.Queue.Strategy()
+}
+
+// SetQueueStrategy sets the strategy that orders the song queue and reorders songs already queued.
+func (p *Player) SetQueueStrategy(strategy QueueStrategy) {
+	p.Queue.SetStrategy(strategy)
+}
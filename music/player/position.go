@@ -0,0 +1,77 @@
+package player
+
+import (
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+const (
+	// positionSaveInterval is how often the position of a playing long-form song is remembered
+	positionSaveInterval = 10 * time.Second
+	// resumeEndMargin is how close to its end a long-form song counts as listened to, it starts over then
+	resumeEndMargin = 30 * time.Second
+)
+
+// songKey identifies a song across plays, its YouTube ID or else its URL.
+func songKey(song *Song) string {
+	if song.ID != "" {
+		return song.ID
+	}
+	return song.UserURL
+}
+
+// isLongForm reports whether the song is long enough for its position to be remembered, e.g. a podcast episode
+// or an audiobook. Streams have no position to resume.
+func (p *Player) isLongForm(song *Song) bool {
+	if song.Source.Endless() || song.Duration <= 0 || songKey(song) == "" {
+		return false
+	}
+
+	minMinutes := p.config.ResumeMinMinutes
+	return minMinutes > 0 && song.Duration >= time.Duration(minMinutes)*time.Minute
+}
+
+// resumePosition returns where the guild stopped listening to a long-form song, 0 to play it from the start.
+// Songs requested fresh always start over.
+func (p *Player) resumePosition(song *Song) time.Duration {
+	if song.Fresh || p.VoiceConnection == nil || !p.isLongForm(song) {
+		return 0
+	}
+
+	position, err := history.NewHistory().GetPosition(p.VoiceConnection.GuildID, songKey(song))
+	if err != nil {
+		slog.Warnf("Error getting position of %q: %v", song.Title, err)
+		return 0
+	}
+	if position >= song.Duration-resumeEndMargin {
+		return 0
+	}
+
+	// ffmpeg starts at whole seconds
+	return position.Truncate(time.Second)
+}
+
+// rememberPosition saves the position of the current song if it's a long-form one.
+func (p *Player) rememberPosition(h history.IHistory) {
+	song := p.CurrentSong
+	if p.VoiceConnection == nil || song == nil || !p.isLongForm(song) {
+		return
+	}
+
+	if err := h.SavePosition(p.VoiceConnection.GuildID, songKey(song), p.GetPlaybackPosition()); err != nil {
+		slog.Warnf("Error saving position of %q: %v", song.Title, err)
+	}
+}
+
+// forgetPosition forgets the position of a long-form song that was listened to the end.
+func (p *Player) forgetPosition(h history.IHistory, song *Song) {
+	if p.VoiceConnection == nil || !p.isLongForm(song) {
+		return
+	}
+
+	if err := h.ForgetPosition(p.VoiceConnection.GuildID, songKey(song)); err != nil {
+		slog.Warnf("Error forgetting position of %q: %v", song.Title, err)
+	}
+}
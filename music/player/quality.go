@@ -0,0 +1,119 @@
+package player
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+// Quality presets trade sound quality for bandwidth and encoder CPU.
+const (
+	QualityLow    = "low"
+	QualityNormal = "normal" // the configured encode settings, or the guild's overrides of them
+	QualityHigh   = "high"
+)
+
+// QualityNames lists the available quality presets.
+var QualityNames = []string{QualityLow, QualityNormal, QualityHigh}
+
+// qualityBitrates are the bitrates in kb/s of presets other than normal.
+var qualityBitrates = map[string]int{
+	QualityLow:  48,
+	QualityHigh: 128,
+}
+
+// ErrUnknownQuality is returned for names that aren't one of QualityNames.
+var ErrUnknownQuality = errors.New("unknown quality preset")
+
+// EncodeInfo describes the encoding of the current song.
+type EncodeInfo struct {
+	Quality          string  `json:"quality"`
+	Bitrate          int     `json:"bitrate_kbps"`
+	FrameDuration    int     `json:"frame_duration_ms"`
+	VBR              bool    `json:"vbr"`
+	Application      string  `json:"application"`
+	CompressionLevel int     `json:"compression_level"`
+	Filters          string  `json:"filters"`
+	MeasuredBitrate  float64 `json:"measured_bitrate_kbps"` // opus data sent per second of playback
+}
+
+// GetQuality returns the name of the quality preset.
+func (p *Player) GetQuality() string {
+	p.Lock()
+	defer p.Unlock()
+	return p.qualityLocked()
+}
+
+// SetQuality switches the quality preset. A playing song is encoded again from its current position.
+func (p *Player) SetQuality(name string) error {
+	if _, ok := qualityBitrates[name]; !ok && name != QualityNormal {
+		return ErrUnknownQuality
+	}
+
+	p.Lock()
+	changed := p.qualityLocked() != name
+	p.quality = name
+	p.Unlock()
+
+	if !changed || p.CurrentSong == nil || p.StreamingSession == nil || (p.CurrentStatus != StatusPlaying && p.CurrentStatus != StatusPaused) {
+		return nil
+	}
+
+	// Endless sources can't start anywhere but at their beginning
+	var position time.Duration
+	if !p.CurrentSong.Source.Endless() {
+		position = p.GetPlaybackPosition().Truncate(time.Second)
+	}
+
+	slog.Infof("Switching quality of %q to %v at %v", p.CurrentSong.Title, name, position)
+	p.restartAt(position)
+
+	return nil
+}
+
+// qualityLocked returns the name of the quality preset, the player must be locked.
+func (p *Player) qualityLocked() string {
+	if p.quality == "" {
+		return QualityNormal
+	}
+	return p.quality
+}
+
+// bitrate returns the bitrate of the quality preset. Normal quality uses the guild's override
+// or else configured.
+func (p *Player) bitrate(configured int) int {
+	if bitrate, ok := qualityBitrates[p.GetQuality()]; ok {
+		return bitrate
+	}
+	if bitrate := p.GetEncodeOverrides().Bitrate; bitrate != 0 {
+		return bitrate
+	}
+	return configured
+}
+
+// GetEncodeInfo returns the encode settings and the measured output bitrate of the current song,
+// false if nothing is encoding.
+func (p *Player) GetEncodeInfo() (EncodeInfo, bool) {
+	encoding, streaming := p.EncodingSession, p.StreamingSession
+	if encoding == nil || streaming == nil || p.CurrentSong == nil {
+		return EncodeInfo{}, false
+	}
+
+	options := encoding.Options()
+	info := EncodeInfo{
+		Quality:          p.GetQuality(),
+		Bitrate:          options.Bitrate,
+		FrameDuration:    options.FrameDuration,
+		VBR:              options.VBR,
+		Application:      string(options.Application),
+		CompressionLevel: options.CompressionLevel,
+		Filters:          options.AudioFilters(),
+	}
+
+	if played := streaming.PlaybackPosition(); played > 0 {
+		info.MeasuredBitrate = float64(streaming.Stats().BytesSent) * 8 / 1000 / played.Seconds()
+	}
+
+	return info, true
+}
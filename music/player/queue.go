@@ -1,44 +1,317 @@
 package player
 
-import "github.com/gookit/slog"
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
 
-// Enqueue adds a song to the queue.
-func (p *Player) Enqueue(song *Song) {
-	slog.Infof("Enqueuing song to queue: %v", song.Title)
+	"github.com/gookit/slog"
+)
 
-	p.Lock()
-	defer p.Unlock()
+const (
+	// UndoWindow is how long a destructive queue operation can be undone
+	UndoWindow = 10 * time.Minute
+	// undoDepth is how many destructive operations are kept for undo
+	undoDepth = 10
+)
 
-	p.SongQueue = append(p.SongQueue, song)
+// Queue operations that can be undone, as returned by Undo.
+const (
+	QueueActionClear   = "clear"
+	QueueActionRemove  = "remove"
+	QueueActionMove    = "move"
+	QueueActionShuffle = "shuffle"
+)
+
+// Errors returned by queue operations.
+var (
+	ErrQueuePosition = errors.New("no song at this position of the queue")
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrShuffleSorted = errors.New("the queue strategy sorts songs, shuffling would be undone")
+)
+
+// Queue holds songs waiting to be played in the order given by its strategy.
+type Queue interface {
+	Push(songs ...*Song)
+	Pop() *Song
+	List() []*Song
+	Len() int
+	Clear()
+	Reset()
+	Shuffle() error
+	Remove(position int) (*Song, error)
+	Move(from, to int) error
+	Undo() (string, error)
+	Strategy() QueueStrategy
+	SetStrategy(strategy QueueStrategy)
 }
 
-// Dequeue removes and returns the first song from the queue.
-func (p *Player) Dequeue() *Song {
-	slog.Info("Dequeuing song and returning it from queue")
+// songQueue is an in-memory Queue.
+type songQueue struct {
+	sync.Mutex
+	songs     []*Song
+	last      *Song // last popped song, used by strategies that rotate between requesters
+	strategy  QueueStrategy
+	undo      []queueUndo    // destructive operations, latest last
+	resolving map[*Song]bool // songs being resolved in the background, see keepHeadResolved
+}
+
+// queueUndo is the queue as it would be if a destructive operation hadn't happened.
+// Songs pushed and popped since are applied to it, so undoing doesn't bring back played songs.
+type queueUndo struct {
+	action string
+	songs  []*Song
+	at     time.Time
+}
+
+// NewQueue creates an empty queue ordered by the given strategy.
+func NewQueue(strategy QueueStrategy) Queue {
+	return &songQueue{
+		songs:    make([]*Song, 0),
+		strategy: strategy,
+	}
+}
+
+// Push adds songs to the queue.
+func (q *songQueue) Push(songs ...*Song) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.songs = q.strategy.Order(append(q.songs, songs...), q.last)
+	for i := range q.undo {
+		q.undo[i].songs = append(q.undo[i].songs, songs...)
+	}
+	q.keepHeadResolved()
+}
 
-	p.Lock()
-	defer p.Unlock()
+// Pop removes and returns the next song, nil if the queue is empty.
+func (q *songQueue) Pop() *Song {
+	q.Lock()
+	defer q.Unlock()
 
-	if len(p.SongQueue) == 0 {
+	if len(q.songs) == 0 {
 		return nil
 	}
 
-	firstSong := p.SongQueue[0]
-	p.SongQueue = p.SongQueue[1:]
+	// Reorder since the last played song may have changed since the last push
+	q.songs = q.strategy.Order(q.songs, q.last)
+
+	q.last = q.songs[0]
+	q.songs = q.songs[1:]
+	for i := range q.undo {
+		q.undo[i].songs = withoutSong(q.undo[i].songs, q.last)
+	}
+	q.keepHeadResolved()
+
+	return q.last
+}
+
+// List returns a copy of queued songs in play order.
+func (q *songQueue) List() []*Song {
+	q.Lock()
+	defer q.Unlock()
+
+	songs := make([]*Song, len(q.songs))
+	copy(songs, q.songs)
 
-	return firstSong
+	return songs
+}
+
+// Len returns the number of queued songs.
+func (q *songQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return len(q.songs)
+}
+
+// Clear removes all songs from the queue.
+func (q *songQueue) Clear() {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.songs) > 0 {
+		q.remember(QueueActionClear)
+	}
+	q.songs = make([]*Song, 0)
+	q.last = nil
+}
+
+// Reset empties the queue and forgets its undo history, for when playback ends rather than somebody clearing it.
+func (q *songQueue) Reset() {
+	q.Lock()
+	defer q.Unlock()
+
+	q.songs = make([]*Song, 0)
+	q.last = nil
+	q.undo = nil
+}
+
+// Shuffle randomizes the queue, the strategy still has the final say on the order.
+// Strategies that sort songs by their priority or length would undo it, ErrShuffleSorted is returned with them.
+func (q *songQueue) Shuffle() error {
+	q.Lock()
+	defer q.Unlock()
+
+	if sortingStrategies[q.strategy.Name()] {
+		return ErrShuffleSorted
+	}
+
+	q.remember(QueueActionShuffle)
+	rand.Shuffle(len(q.songs), func(i, j int) {
+		q.songs[i], q.songs[j] = q.songs[j], q.songs[i]
+	})
+	q.songs = q.strategy.Order(q.songs, q.last)
+	q.keepHeadResolved()
+	return nil
+}
+
+// Remove removes and returns the song at a position of the queue, counting from 1.
+func (q *songQueue) Remove(position int) (*Song, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if position < 1 || position > len(q.songs) {
+		return nil, ErrQueuePosition
+	}
+
+	q.remember(QueueActionRemove)
+	song := q.songs[position-1]
+	q.songs = withoutSong(q.songs, song)
+	q.keepHeadResolved()
+
+	return song, nil
+}
+
+// Move moves the song at one position of the queue to another, counting from 1.
+// The strategy still has the final say on the order.
+func (q *songQueue) Move(from, to int) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if from < 1 || from > len(q.songs) || to < 1 || to > len(q.songs) {
+		return ErrQueuePosition
+	}
+
+	q.remember(QueueActionMove)
+	song := q.songs[from-1]
+	songs := withoutSong(q.songs, song)
+	songs = append(songs[:to-1], append([]*Song{song}, songs[to-1:]...)...)
+	q.songs = q.strategy.Order(songs, q.last)
+	q.keepHeadResolved()
+
+	return nil
+}
+
+// Undo reverts the latest destructive operation within UndoWindow and returns its action.
+func (q *songQueue) Undo() (string, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	for len(q.undo) > 0 {
+		latest := q.undo[len(q.undo)-1]
+		q.undo = q.undo[:len(q.undo)-1]
+		if time.Since(latest.at) > UndoWindow {
+			// Older ones expired too
+			q.undo = nil
+			break
+		}
+
+		q.songs = q.strategy.Order(latest.songs, q.last)
+		q.keepHeadResolved()
+		return latest.action, nil
+	}
+
+	return "", ErrNothingToUndo
+}
+
+// remember keeps the queue before a destructive operation for Undo, must be called with the queue lock held.
+func (q *songQueue) remember(action string) {
+	songs := make([]*Song, len(q.songs))
+	copy(songs, q.songs)
+
+	q.undo = append(q.undo, queueUndo{action: action, songs: songs, at: time.Now()})
+	if len(q.undo) > undoDepth {
+		q.undo = q.undo[1:]
+	}
+}
+
+// withoutSong returns songs without the given one, songs isn't modified.
+func withoutSong(songs []*Song, song *Song) []*Song {
+	without := make([]*Song, 0, len(songs))
+	for _, s := range songs {
+		if s != song {
+			without = append(without, s)
+		}
+	}
+	return without
+}
+
+// Strategy returns the strategy that orders the queue.
+func (q *songQueue) Strategy() QueueStrategy {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.strategy
+}
+
+// SetStrategy replaces the strategy and reorders songs already queued.
+func (q *songQueue) SetStrategy(strategy QueueStrategy) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.strategy = strategy
+	q.songs = strategy.Order(q.songs, q.last)
+	q.keepHeadResolved()
+}
+
+// Enqueue adds a song to the queue.
+func (p *Player) Enqueue(song *Song) {
+	slog.Infof("Enqueuing song to queue: %v", song.Title)
+
+	p.Queue.Push(song)
+}
+
+// Dequeue removes and returns the first song from the queue.
+func (p *Player) Dequeue() *Song {
+	slog.Info("Dequeuing song and returning it from queue")
+
+	return p.Queue.Pop()
 }
 
 // ClearQueue clears the song queue.
 func (p *Player) ClearQueue() {
 	slog.Info("Clearing song queue")
 
-	p.Lock()
-	defer p.Unlock()
+	p.Queue.Clear()
+	p.clearRetries()
+}
 
-	if len(p.SongQueue) == 0 {
-		return
-	}
+// ShuffleQueue shuffles the song queue, unless its strategy sorts songs.
+func (p *Player) ShuffleQueue() error {
+	slog.Info("Shuffling song queue")
+
+	return p.Queue.Shuffle()
+}
+
+// RemoveFromQueue removes the song at a position of the queue, counting from 1.
+func (p *Player) RemoveFromQueue(position int) (*Song, error) {
+	slog.Infof("Removing song %v from queue", position)
+
+	return p.Queue.Remove(position)
+}
+
+// MoveInQueue moves the song at one position of the queue to another, counting from 1.
+func (p *Player) MoveInQueue(from, to int) error {
+	slog.Infof("Moving song %v of queue to %v", from, to)
+
+	return p.Queue.Move(from, to)
+}
+
+// UndoQueue reverts the latest clear, remove, move or shuffle of the queue and returns its action.
+func (p *Player) UndoQueue() (string, error) {
+	slog.Info("Undoing queue change")
 
-	p.SongQueue = make([]*Song, 0)
+	return p.Queue.Undo()
 }
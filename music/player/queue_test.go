@@ -0,0 +1,202 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func queueOf(titles string) Queue {
+	q := NewQueue(fifoStrategy{})
+	for _, title := range titles {
+		q.Push(&Song{Title: string(title)})
+	}
+	return q
+}
+
+func undo(t *testing.T, q Queue, action string) {
+	t.Helper()
+	got, err := q.Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got != action {
+		t.Errorf("Undid %v, expected %v", got, action)
+	}
+}
+
+func TestQueueRemove(t *testing.T) {
+	q := queueOf("abcd")
+
+	song, err := q.Remove(2)
+	if err != nil || song.Title != "b" {
+		t.Fatalf("Removed %v, %v (expected b)", song, err)
+	}
+	if got := songTitles(q.List()); got != "acd" {
+		t.Errorf("Incorrect queue after remove (got %v expected acd)", got)
+	}
+
+	for _, position := range []int{0, 4} {
+		if _, err := q.Remove(position); err != ErrQueuePosition {
+			t.Errorf("Removing position %v: %v, expected %v", position, err, ErrQueuePosition)
+		}
+	}
+
+	undo(t, q, QueueActionRemove)
+	if got := songTitles(q.List()); got != "abcd" {
+		t.Errorf("Incorrect queue after undo (got %v expected abcd)", got)
+	}
+	if _, err := q.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Failed remove was recorded for undo")
+	}
+}
+
+func TestQueueMove(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     string
+	}{
+		{1, 4, "bcda"},
+		{4, 1, "dabc"},
+		{2, 3, "acbd"},
+		{3, 3, "abcd"},
+	}
+
+	for _, test := range tests {
+		q := queueOf("abcd")
+		if err := q.Move(test.from, test.to); err != nil {
+			t.Fatalf("Moving %v to %v: %v", test.from, test.to, err)
+		}
+		if got := songTitles(q.List()); got != test.want {
+			t.Errorf("Moving %v to %v (got %v expected %v)", test.from, test.to, got, test.want)
+		}
+
+		undo(t, q, QueueActionMove)
+		if got := songTitles(q.List()); got != "abcd" {
+			t.Errorf("Incorrect queue after undoing move of %v to %v (got %v expected abcd)", test.from, test.to, got)
+		}
+	}
+
+	q := queueOf("abcd")
+	for _, positions := range [][2]int{{0, 1}, {1, 5}, {5, 1}} {
+		if err := q.Move(positions[0], positions[1]); err != ErrQueuePosition {
+			t.Errorf("Moving %v to %v: %v, expected %v", positions[0], positions[1], err, ErrQueuePosition)
+		}
+	}
+}
+
+func TestQueueUndoKeepsPushAndPop(t *testing.T) {
+	q := queueOf("abcd")
+
+	q.Clear()
+	q.Push(&Song{Title: "e"})
+	if _, err := q.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	q.Push(&Song{Title: "f"}, &Song{Title: "g"})
+	if song := q.Pop(); song.Title != "f" {
+		t.Fatalf("Popped %v, expected f", song.Title)
+	}
+
+	// Undoing the remove brings e back but not f, which played since
+	undo(t, q, QueueActionRemove)
+	if got := songTitles(q.List()); got != "eg" {
+		t.Errorf("Incorrect queue after undoing remove (got %v expected eg)", got)
+	}
+
+	// Undoing the clear brings the cleared songs back along with those pushed since, except played ones
+	undo(t, q, QueueActionClear)
+	if got := songTitles(q.List()); got != "abcdeg" {
+		t.Errorf("Incorrect queue after undoing clear (got %v expected abcdeg)", got)
+	}
+
+	if _, err := q.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Undo of an empty history: %v, expected %v", err, ErrNothingToUndo)
+	}
+}
+
+func TestQueueUndoDepth(t *testing.T) {
+	q := queueOf("abcdefghijklmnop")
+	for i := 0; i < undoDepth+2; i++ {
+		if _, err := q.Remove(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < undoDepth; i++ {
+		undo(t, q, QueueActionRemove)
+	}
+	if _, err := q.Undo(); err != ErrNothingToUndo {
+		t.Errorf("More than %v operations were kept for undo", undoDepth)
+	}
+	if got := songTitles(q.List()); got != "cdefghijklmnop" {
+		t.Errorf("Incorrect queue after undoing all (got %v expected cdefghijklmnop)", got)
+	}
+}
+
+func TestQueueUndoWindow(t *testing.T) {
+	q := queueOf("abc").(*songQueue)
+	if _, err := q.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first remove expired, the latest one didn't
+	q.undo[0].at = time.Now().Add(-UndoWindow - time.Minute)
+	undo(t, q, QueueActionRemove)
+	if got := songTitles(q.List()); got != "bc" {
+		t.Errorf("Incorrect queue after undo (got %v expected bc)", got)
+	}
+	if _, err := q.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Expired operation was undone")
+	}
+
+	// Once the latest expired, older ones are dropped with it
+	q.Shuffle()
+	q.Clear()
+	q.undo[1].at = time.Now().Add(-UndoWindow - time.Minute)
+	if _, err := q.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Expired operation was undone")
+	}
+	if len(q.undo) != 0 {
+		t.Errorf("%v expired operations were kept", len(q.undo))
+	}
+}
+
+func TestQueueShuffleSorted(t *testing.T) {
+	for _, strategy := range []QueueStrategy{weightedStrategy{}, shortestStrategy{}} {
+		q := NewQueue(strategy)
+		q.Push(&Song{Title: "a", Duration: time.Minute}, &Song{Title: "b", Duration: 2 * time.Minute})
+
+		if err := q.Shuffle(); err != ErrShuffleSorted {
+			t.Errorf("Shuffling in %v order: %v, expected %v", strategy.Name(), err, ErrShuffleSorted)
+		}
+		if _, err := q.Undo(); err != ErrNothingToUndo {
+			t.Errorf("Refused shuffle in %v order was recorded for undo", strategy.Name())
+		}
+	}
+
+	for _, strategy := range []QueueStrategy{fifoStrategy{}, fairStrategy{}} {
+		q := NewQueue(strategy)
+		q.Push(&Song{Title: "a"}, &Song{Title: "b"})
+		if err := q.Shuffle(); err != nil {
+			t.Errorf("Shuffling in %v order: %v", strategy.Name(), err)
+		}
+	}
+}
+
+func TestStopIsNotUndone(t *testing.T) {
+	p := &Player{Queue: queueOf("abc")}
+	if _, err := p.RemoveFromQueue(1); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Stop()
+	if p.Queue.Len() != 0 {
+		t.Errorf("Queue wasn't emptied by Stop")
+	}
+	if _, err := p.UndoQueue(); err != ErrNothingToUndo {
+		t.Errorf("Undo after Stop: %v, expected %v", err, ErrNothingToUndo)
+	}
+}
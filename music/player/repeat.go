@@ -0,0 +1,72 @@
+package player
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gookit/slog"
+)
+
+// RepeatMode is what happens to songs that played to their end.
+type RepeatMode int32
+
+const (
+	RepeatOff   RepeatMode = iota // finished songs are dropped
+	RepeatTrack                   // the finished song plays again
+	RepeatQueue                   // finished and skipped songs are queued again
+)
+
+// RepeatModeNames are the names of the repeat modes, as accepted by ParseRepeatMode.
+var RepeatModeNames = []string{"off", "track", "queue"}
+
+// String returns the name of the RepeatMode.
+func (mode RepeatMode) String() string {
+	if mode < 0 || int(mode) >= len(RepeatModeNames) {
+		return ""
+	}
+	return RepeatModeNames[mode]
+}
+
+func (mode RepeatMode) StringEmoji() string {
+	modes := map[RepeatMode]string{
+		RepeatOff:   "➡️",
+		RepeatTrack: "🔂",
+		RepeatQueue: "🔁",
+	}
+
+	return modes[mode]
+}
+
+// ParseRepeatMode returns the repeat mode of a name.
+func ParseRepeatMode(name string) (RepeatMode, error) {
+	for i, modeName := range RepeatModeNames {
+		if strings.EqualFold(name, modeName) {
+			return RepeatMode(i), nil
+		}
+	}
+	return RepeatOff, fmt.Errorf("unknown repeat mode %v", name)
+}
+
+// GetRepeatMode returns the repeat mode.
+func (p *Player) GetRepeatMode() RepeatMode {
+	p.Lock()
+	defer p.Unlock()
+	return p.repeat
+}
+
+// SetRepeatMode sets what happens to songs that played to their end.
+func (p *Player) SetRepeatMode(mode RepeatMode) {
+	slog.Infof("Setting repeat mode to %v", mode)
+
+	p.Lock()
+	defer p.Unlock()
+	p.repeat = mode
+}
+
+// requeue queues a song that's done playing again if the whole queue repeats.
+func (p *Player) requeue(song *Song) {
+	if song == nil || p.GetRepeatMode() != RepeatQueue {
+		return
+	}
+	p.Enqueue(song)
+}
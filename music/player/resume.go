@@ -14,12 +14,13 @@ func (p *Player) Unpause() {
 		if p.CurrentStatus == StatusPaused {
 			p.StreamingSession.SetPaused(false)
 			p.CurrentStatus = StatusPlaying
+			p.recordEvent(EventResume, "Playback resumed")
 		}
 	}
 
 	if len(p.GetSongQueue()) > 0 {
 		if p.CurrentStatus == StatusResting {
-			p.Play(0, nil)
+			p.Play(p.playContext(), 0, nil)
 			p.CurrentStatus = StatusPlaying
 		}
 	}
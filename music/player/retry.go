@@ -0,0 +1,101 @@
+package player
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+const (
+	// maxRetryAttempts is how often a song that failed transiently is tried again before it's dropped
+	maxRetryAttempts = 3
+	// retryBackoff is the wait before the first retry, doubled for every further one
+	retryBackoff = 30 * time.Second
+)
+
+// transientFailures are the error classes worth retrying, the source may work again in a moment.
+var transientFailures = map[string]bool{
+	FailureTimeout:     true,
+	FailureRateLimited: true,
+	FailureNetwork:     true,
+	FailureServerError: true,
+}
+
+// IsTransientFailure reports whether failures of the error class may go away by themselves.
+func IsTransientFailure(class string) bool {
+	return transientFailures[class]
+}
+
+// retryList holds the songs waiting to be queued again after a transient failure.
+type retryList struct {
+	sync.Mutex
+	songs  []*Song
+	timers map[*Song]*time.Timer
+}
+
+// retryLater queues the song again after a backoff if the failure is transient,
+// it reports false if the song is dropped.
+func (p *Player) retryLater(song *Song, class string) bool {
+	if !IsTransientFailure(class) || song.retryAttempts >= maxRetryAttempts {
+		return false
+	}
+
+	song.retryAttempts++
+	delay := retryBackoff << (song.retryAttempts - 1)
+	slog.Warnf("Retrying %q in %v after a %v failure (attempt %d of %d)", song.Title, delay, class, song.retryAttempts, maxRetryAttempts)
+	p.recordEvent(EventRetry, "%q in %v after a %v failure (attempt %d of %d)", song.Title, delay, class, song.retryAttempts, maxRetryAttempts)
+
+	p.retries.Lock()
+	defer p.retries.Unlock()
+
+	if p.retries.timers == nil {
+		p.retries.timers = make(map[*Song]*time.Timer)
+	}
+	p.retries.songs = append(p.retries.songs, song)
+	p.retries.timers[song] = time.AfterFunc(delay, func() { p.retryDue(song) })
+
+	return true
+}
+
+// retryDue queues a song whose backoff passed and starts playback if the player sits idle in a voice channel.
+func (p *Player) retryDue(song *Song) {
+	p.retries.Lock()
+	if _, waiting := p.retries.timers[song]; !waiting {
+		// Cleared meanwhile
+		p.retries.Unlock()
+		return
+	}
+	delete(p.retries.timers, song)
+	p.retries.songs = withoutSong(p.retries.songs, song)
+	p.retries.Unlock()
+
+	slog.Infof("Queueing %q again", song.Title)
+	p.Queue.Push(song)
+
+	if p.CurrentStatus == StatusResting && p.VoiceConnection != nil {
+		go p.Play(p.playContext(), 0, nil)
+	}
+}
+
+// clearRetries drops the songs waiting to be retried.
+func (p *Player) clearRetries() {
+	p.retries.Lock()
+	defer p.retries.Unlock()
+
+	for _, timer := range p.retries.timers {
+		timer.Stop()
+	}
+	p.retries.songs = nil
+	p.retries.timers = nil
+}
+
+// GetRetryingSongs returns the songs waiting to be queued again after a transient failure, oldest first.
+func (p *Player) GetRetryingSongs() []*Song {
+	p.retries.Lock()
+	defer p.retries.Unlock()
+
+	songs := make([]*Song, len(p.retries.songs))
+	copy(songs, p.retries.songs)
+	return songs
+}
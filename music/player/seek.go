@@ -0,0 +1,79 @@
+package player
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+// seekEndMargin keeps seeking forward from jumping past the end, the last seconds of the song still play.
+const seekEndMargin = 5 * time.Second
+
+// Errors returned by Seek.
+var (
+	ErrNothingPlaying = errors.New("nothing is playing")
+	ErrNotSeekable    = errors.New("song has no known duration to seek in")
+)
+
+// GetPlaybackPosition returns the position in the current song, counting from the start of the song
+// rather than from where its encoding started.
+func (p *Player) GetPlaybackPosition() time.Duration {
+	if p.StreamingSession == nil || p.EncodingSession == nil {
+		return 0
+	}
+	return time.Duration(p.EncodingSession.Options().StartTime)*time.Second + p.StreamingSession.PlaybackPosition()
+}
+
+// Seek moves playback of the current song to position, clamped to shortly before its end, and returns the new
+// position. Playback restarts from there with the same queue, a paused song resumes.
+func (p *Player) Seek(position time.Duration) (time.Duration, error) {
+	song, streaming := p.CurrentSong, p.StreamingSession
+	if song == nil || streaming == nil || (p.CurrentStatus != StatusPlaying && p.CurrentStatus != StatusPaused) {
+		return 0, ErrNothingPlaying
+	}
+	if song.Source.Endless() || song.Duration <= 0 {
+		return 0, ErrNotSeekable
+	}
+
+	// ffmpeg starts at whole seconds
+	position = max(0, min(position, song.Duration-seekEndMargin)).Truncate(time.Second)
+
+	slog.Infof("Seeking %q to %v", song.Title, position)
+	p.restartAt(position)
+
+	return position, nil
+}
+
+// SeekBy moves playback of the current song by offset, clamped to the start of the song and shortly before its end,
+// and returns the new position.
+func (p *Player) SeekBy(offset time.Duration) (time.Duration, error) {
+	return p.Seek(p.GetPlaybackPosition() + offset)
+}
+
+// restartAt stops the streaming of the current song so it's encoded again from position.
+// A paused song resumes.
+func (p *Player) restartAt(position time.Duration) {
+	streaming := p.StreamingSession
+
+	p.Lock()
+	p.seekPending, p.seekPosition = true, position
+	p.Unlock()
+
+	// A paused stream isn't running, it has to run to notice the stop
+	if p.CurrentStatus == StatusPaused {
+		streaming.SetPaused(false)
+		p.SetCurrentStatus(StatusPlaying)
+	}
+	streaming.Stop()
+}
+
+// takeSeek returns the position of a pending seek and clears it.
+func (p *Player) takeSeek() (time.Duration, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	pending := p.seekPending
+	p.seekPending = false
+	return p.seekPosition, pending
+}
@@ -8,6 +8,9 @@ import (
 // Skip skips to the next song in the queue.
 func (p *Player) Skip() {
 	slog.Info("Skipping to next song")
+	if p.CurrentSong != nil {
+		p.recordEvent(EventSkip, "%q", p.CurrentSong.Title)
+	}
 
 	switch p.CurrentStatus {
 	case StatusPlaying, StatusPaused:
@@ -21,9 +24,12 @@ func (p *Player) Skip() {
 		if len(p.SkipInterrupt) == 0 {
 			history := history.NewHistory()
 			history.AddPlaybackCountStats(p.VoiceConnection.GuildID, p.CurrentSong.ID)
+			if err := history.AddSkipStats(p.VoiceConnection.GuildID, p.CurrentSong.ID); err != nil {
+				slog.Warnf("Error adding skip stats: %v", err)
+			}
 
 			p.SkipInterrupt <- true
-			p.Play(0, nil)
+			p.Play(p.playContext(), 0, nil)
 		}
 	case StatusResting:
 		if p.CurrentSong != nil {
@@ -32,13 +38,13 @@ func (p *Player) Skip() {
 				history.AddPlaybackCountStats(p.VoiceConnection.GuildID, p.CurrentSong.ID)
 
 				p.SkipInterrupt <- true
-				p.Play(0, nil)
+				p.Play(p.playContext(), 0, nil)
 				p.CurrentStatus = StatusPlaying
 			}
 		} else {
 			if len(p.SkipInterrupt) == 0 {
 				p.SkipInterrupt <- true
-				p.Play(0, nil)
+				p.Play(p.playContext(), 0, nil)
 				p.CurrentStatus = StatusPlaying
 			}
 		}
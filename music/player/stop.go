@@ -5,8 +5,11 @@ import "github.com/gookit/slog"
 // Stop stops audio playback and disconnects from the voice channel.
 func (p *Player) Stop() {
 	slog.Info("Stopping audio playback and disconnecting from voice channel")
+	p.recordEvent(EventStop, "Disconnected from voice")
 
-	p.ClearQueue()
+	// Stopping isn't a queue change to undo
+	p.Queue.Reset()
+	p.clearRetries()
 
 	if p.VoiceConnection != nil {
 		err := p.VoiceConnection.Speaking(false)
@@ -23,6 +26,7 @@ func (p *Player) Stop() {
 	}
 
 	if p.StreamingSession != nil {
+		p.StreamingSession.Stop()
 		p.StreamingSession = nil
 	}
 
@@ -0,0 +1,137 @@
+package player
+
+import (
+	"fmt"
+	"sort"
+)
+
+// QueueStrategy decides in which order queued songs are played.
+type QueueStrategy interface {
+	// Name returns the name the strategy is selected by.
+	Name() string
+	// Order returns queued songs in play order, current is the song that is playing or just finished.
+	Order(songs []*Song, current *Song) []*Song
+}
+
+const (
+	StrategyFIFO     = "fifo"
+	StrategyFair     = "fair"
+	StrategyWeighted = "weighted"
+	StrategyShortest = "shortest"
+)
+
+// sortingStrategies order songs by their priority or length, which puts a shuffled queue back in order.
+var sortingStrategies = map[string]bool{StrategyWeighted: true, StrategyShortest: true}
+
+// QueueStrategyNames lists names of all available queue strategies.
+var QueueStrategyNames = []string{StrategyFIFO, StrategyFair, StrategyWeighted, StrategyShortest}
+
+// NewQueueStrategy returns the queue strategy registered under the given name.
+func NewQueueStrategy(name string) (QueueStrategy, error) {
+	switch name {
+	case StrategyFIFO:
+		return fifoStrategy{}, nil
+	case StrategyFair:
+		return fairStrategy{}, nil
+	case StrategyWeighted:
+		return weightedStrategy{}, nil
+	case StrategyShortest:
+		return shortestStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown queue strategy: %v", name)
+	}
+}
+
+// fifoStrategy plays songs in the order they were added.
+type fifoStrategy struct{}
+
+func (fifoStrategy) Name() string {
+	return StrategyFIFO
+}
+
+func (fifoStrategy) Order(songs []*Song, current *Song) []*Song {
+	return songs
+}
+
+// fairStrategy interleaves songs round-robin by requester so nobody is starved by a single long request.
+type fairStrategy struct{}
+
+func (fairStrategy) Name() string {
+	return StrategyFair
+}
+
+func (fairStrategy) Order(songs []*Song, current *Song) []*Song {
+	var requesters []string
+	groups := make(map[string][]*Song)
+
+	// Group songs by requester keeping the order in which requesters appeared
+	for _, song := range songs {
+		if _, ok := groups[song.RequesterID]; !ok {
+			requesters = append(requesters, song.RequesterID)
+		}
+		groups[song.RequesterID] = append(groups[song.RequesterID], song)
+	}
+
+	// Requester of the current song already had a turn so they go last in the first round
+	if current != nil {
+		for i, requester := range requesters {
+			if requester == current.RequesterID {
+				requesters = append(append(requesters[:i:i], requesters[i+1:]...), requester)
+				break
+			}
+		}
+	}
+
+	ordered := make([]*Song, 0, len(songs))
+	for round := 0; len(ordered) < len(songs); round++ {
+		for _, requester := range requesters {
+			if round < len(groups[requester]) {
+				ordered = append(ordered, groups[requester][round])
+			}
+		}
+	}
+
+	return ordered
+}
+
+// weightedStrategy plays songs with higher priority first, songs of equal priority keep their order.
+type weightedStrategy struct{}
+
+func (weightedStrategy) Name() string {
+	return StrategyWeighted
+}
+
+func (weightedStrategy) Order(songs []*Song, current *Song) []*Song {
+	ordered := make([]*Song, len(songs))
+	copy(ordered, songs)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	return ordered
+}
+
+// shortestStrategy plays shorter songs first, songs of unknown length (like streams) go last.
+type shortestStrategy struct{}
+
+func (shortestStrategy) Name() string {
+	return StrategyShortest
+}
+
+func (shortestStrategy) Order(songs []*Song, current *Song) []*Song {
+	ordered := make([]*Song, len(songs))
+	copy(ordered, songs)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[j].Duration <= 0 {
+			return ordered[i].Duration > 0
+		}
+		if ordered[i].Duration <= 0 {
+			return false
+		}
+		return ordered[i].Duration < ordered[j].Duration
+	})
+
+	return ordered
+}
@@ -0,0 +1,86 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func songTitles(songs []*Song) string {
+	titles := ""
+	for _, song := range songs {
+		titles += song.Title
+	}
+	return titles
+}
+
+func TestFIFOStrategy(t *testing.T) {
+	songs := []*Song{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+
+	got := songTitles(fifoStrategy{}.Order(songs, nil))
+	if got != "abc" {
+		t.Errorf("Incorrect order (got %v expected abc)", got)
+	}
+}
+
+func TestFairStrategy(t *testing.T) {
+	songs := []*Song{
+		{Title: "a", RequesterID: "1"},
+		{Title: "b", RequesterID: "1"},
+		{Title: "c", RequesterID: "1"},
+		{Title: "d", RequesterID: "2"},
+		{Title: "e", RequesterID: "3"},
+		{Title: "f", RequesterID: "2"},
+	}
+
+	got := songTitles(fairStrategy{}.Order(songs, nil))
+	if got != "adebfc" {
+		t.Errorf("Incorrect order (got %v expected adebfc)", got)
+	}
+
+	// Requester of the current song goes last in the first round
+	got = songTitles(fairStrategy{}.Order(songs, &Song{RequesterID: "1"}))
+	if got != "deafbc" {
+		t.Errorf("Incorrect order after current song (got %v expected deafbc)", got)
+	}
+}
+
+func TestWeightedStrategy(t *testing.T) {
+	songs := []*Song{{Title: "a"}, {Title: "b", Priority: 1}, {Title: "c"}, {Title: "d", Priority: 2}}
+
+	got := songTitles(weightedStrategy{}.Order(songs, nil))
+	if got != "dbac" {
+		t.Errorf("Incorrect order (got %v expected dbac)", got)
+	}
+}
+
+func TestShortestStrategy(t *testing.T) {
+	songs := []*Song{
+		{Title: "a", Duration: 5 * time.Minute},
+		{Title: "b", Duration: -1},
+		{Title: "c", Duration: 2 * time.Minute},
+		{Title: "d", Duration: 3 * time.Minute},
+	}
+
+	got := songTitles(shortestStrategy{}.Order(songs, nil))
+	if got != "cdab" {
+		t.Errorf("Incorrect order (got %v expected cdab)", got)
+	}
+}
+
+func TestQueuePopRotatesFairOrder(t *testing.T) {
+	queue := NewQueue(fairStrategy{})
+	queue.Push(
+		&Song{Title: "a", RequesterID: "1"},
+		&Song{Title: "b", RequesterID: "1"},
+		&Song{Title: "c", RequesterID: "2"},
+	)
+
+	got := ""
+	for song := queue.Pop(); song != nil; song = queue.Pop() {
+		got += song.Title
+	}
+
+	if got != "acb" {
+		t.Errorf("Incorrect play order (got %v expected acb)", got)
+	}
+}
@@ -0,0 +1,39 @@
+package player
+
+import (
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+	"github.com/keshon/melodix-discord-player/music/pkg/dca"
+)
+
+// traceStream marks when the first frame of a stream was sent on its span and returns a func
+// ending the span with the counters of the stream.
+func traceStream(span *tracing.Span, streaming *dca.StreamingSession) func() {
+	if span == nil {
+		return func() {}
+	}
+
+	ended := make(chan struct{})
+	go func() {
+		select {
+		case <-streaming.FirstFrameSent():
+			span.AddEvent("first frame sent")
+		case <-ended:
+		}
+	}()
+
+	return func() {
+		close(ended)
+
+		stats := streaming.Stats()
+		span.SetAttributes(
+			tracing.Int("stream.frames_sent", stats.FramesSent),
+			tracing.Int("stream.frames_dropped", stats.FramesDropped),
+			tracing.Int("stream.frames_late", stats.FramesLate),
+			tracing.Int("stream.send_stalls", stats.SendStalls),
+			tracing.Duration("stream.jitter_ms", stats.Jitter))
+		if _, err := streaming.Finished(); err != nil {
+			span.SetError(err)
+		}
+		span.End()
+	}
+}
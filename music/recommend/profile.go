@@ -0,0 +1,38 @@
+package recommend
+
+import (
+	"context"
+
+	"github.com/keshon/melodix-discord-player/music/history"
+)
+
+// LoadProfile learns the taste profile of a guild from its stored history and ratings.
+func LoadProfile(ctx context.Context, guildID string) (*Profile, error) {
+	h := history.NewHistory()
+
+	list, err := h.GetHistory(ctx, guildID, "last_played", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := h.GetTrackRatings(guildID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(list))
+	for _, elem := range list {
+		rating := ratings[elem.History.TrackID]
+		entries = append(entries, Entry{
+			ID:         elem.Track.YTID,
+			Title:      elem.Track.Name,
+			PlayCount:  elem.History.PlayCount,
+			SkipCount:  elem.History.SkipCount,
+			LastPlayed: elem.History.LastPlayed,
+			Likes:      rating.Likes,
+			Dislikes:   rating.Dislikes,
+		})
+	}
+
+	return NewProfile(entries), nil
+}
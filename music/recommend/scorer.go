@@ -0,0 +1,243 @@
+// Package recommend ranks candidate tracks, such as YouTube's related videos, by how well they fit
+// the listening taste of a guild learned from its history and ratings. Autoplay picks the track to play
+// after the queue ran out with it, see autoplaySong of the discord package.
+package recommend
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default weights of the scorer, tuned so a strong artist match outweighs the source's own order.
+const (
+	DefaultRecentWindow  = 3 * time.Hour
+	DefaultArtistWeight  = 2.0
+	DefaultKeywordWeight = 0.5
+	DefaultLikeWeight    = 1.0
+	DefaultSkipWeight    = 1.0
+)
+
+// Entry is a track of the guild's history together with its ratings.
+type Entry struct {
+	ID         string // YouTube ID of the track
+	Title      string
+	PlayCount  uint
+	SkipCount  uint
+	LastPlayed time.Time
+	Likes      int64
+	Dislikes   int64
+}
+
+// Candidate is a track that could be played next.
+type Candidate struct {
+	ID    string
+	Title string
+	URL   string
+}
+
+// Profile is the taste of a guild: how much it likes artists and title keywords,
+// and what it played and rated.
+type Profile struct {
+	artists  map[string]float64 // affinity by artist, from -1 to 1
+	keywords map[string]float64 // affinity by title keyword, from -1 to 1
+	entries  map[string]Entry   // history by track ID
+}
+
+// NewProfile learns the taste of a guild from its history.
+func NewProfile(entries []Entry) *Profile {
+	profile := &Profile{
+		artists:  make(map[string]float64),
+		keywords: make(map[string]float64),
+		entries:  make(map[string]Entry, len(entries)),
+	}
+
+	for _, entry := range entries {
+		profile.entries[entry.ID] = entry
+
+		weight := entryWeight(entry)
+		if artist := Artist(entry.Title); artist != "" {
+			profile.artists[artist] += weight
+		}
+		for _, keyword := range Keywords(entry.Title) {
+			profile.keywords[keyword] += weight
+		}
+	}
+
+	normalize(profile.artists)
+	normalize(profile.keywords)
+
+	return profile
+}
+
+// entryWeight is how strongly a history entry speaks for (positive) or against (negative) its artist and keywords.
+// Plays count logarithmically so a single track on repeat doesn't dominate the profile.
+func entryWeight(entry Entry) float64 {
+	weight := math.Log1p(float64(entry.PlayCount))
+	weight += 2 * float64(entry.Likes-entry.Dislikes)
+	if entry.PlayCount > 0 {
+		weight -= float64(entry.SkipCount) / float64(entry.PlayCount)
+	}
+	return weight
+}
+
+// normalize scales affinities into [-1, 1] relative to the strongest one.
+func normalize(affinities map[string]float64) {
+	var strongest float64
+	for _, affinity := range affinities {
+		strongest = math.Max(strongest, math.Abs(affinity))
+	}
+	if strongest == 0 {
+		return
+	}
+	for key, affinity := range affinities {
+		affinities[key] = affinity / strongest
+	}
+}
+
+// Scorer ranks candidates by a guild's taste profile.
+type Scorer struct {
+	Profile       *Profile
+	RecentWindow  time.Duration // tracks played within it are not recommended again
+	ArtistWeight  float64
+	KeywordWeight float64
+	LikeWeight    float64 // bonus per net like of the candidate itself
+	SkipWeight    float64 // penalty for the skip ratio of the candidate itself
+	Now           func() time.Time
+}
+
+// NewScorer returns a scorer of the profile with default weights.
+func NewScorer(profile *Profile) *Scorer {
+	return &Scorer{
+		Profile:       profile,
+		RecentWindow:  DefaultRecentWindow,
+		ArtistWeight:  DefaultArtistWeight,
+		KeywordWeight: DefaultKeywordWeight,
+		LikeWeight:    DefaultLikeWeight,
+		SkipWeight:    DefaultSkipWeight,
+		Now:           time.Now,
+	}
+}
+
+// Allowed reports whether the candidate may be recommended at all:
+// tracks the guild disliked or played recently are left out.
+func (s *Scorer) Allowed(candidate Candidate) bool {
+	entry, played := s.Profile.entries[candidate.ID]
+	if !played {
+		return true
+	}
+	if entry.Dislikes > entry.Likes {
+		return false
+	}
+	return entry.LastPlayed.IsZero() || s.Now().Sub(entry.LastPlayed) >= s.RecentWindow
+}
+
+// Score rates how well the candidate fits the profile, rank is its position in the source's own order.
+func (s *Scorer) Score(candidate Candidate, rank int) float64 {
+	// The source's order still matters, it fades for later candidates
+	score := 1 / float64(rank+1)
+
+	if artist := Artist(candidate.Title); artist != "" {
+		score += s.ArtistWeight * s.Profile.artists[artist]
+	}
+
+	keywords := Keywords(candidate.Title)
+	if len(keywords) > 0 {
+		var affinity float64
+		for _, keyword := range keywords {
+			affinity += s.Profile.keywords[keyword]
+		}
+		score += s.KeywordWeight * affinity / float64(len(keywords))
+	}
+
+	if entry, played := s.Profile.entries[candidate.ID]; played {
+		score += s.LikeWeight * float64(entry.Likes-entry.Dislikes)
+		if entry.PlayCount > 0 {
+			score -= s.SkipWeight * float64(entry.SkipCount) / float64(entry.PlayCount)
+		}
+	}
+
+	return score
+}
+
+// Rank returns the allowed candidates best first, ties keep the source's order.
+func (s *Scorer) Rank(candidates []Candidate) []Candidate {
+	type scored struct {
+		candidate Candidate
+		score     float64
+	}
+
+	var allowed []scored
+	for i, candidate := range candidates {
+		if s.Allowed(candidate) {
+			allowed = append(allowed, scored{candidate, s.Score(candidate, i)})
+		}
+	}
+
+	sort.SliceStable(allowed, func(i, j int) bool {
+		return allowed[i].score > allowed[j].score
+	})
+
+	ranked := make([]Candidate, len(allowed))
+	for i, a := range allowed {
+		ranked[i] = a.candidate
+	}
+	return ranked
+}
+
+// Best returns the best allowed candidate, false if every candidate was left out.
+func (s *Scorer) Best(candidates []Candidate) (Candidate, bool) {
+	ranked := s.Rank(candidates)
+	if len(ranked) == 0 {
+		return Candidate{}, false
+	}
+	return ranked[0], true
+}
+
+// titleSeparators split "Artist - Title" style video titles.
+var titleSeparators = []string{" - ", " – ", " — ", " | "}
+
+// featuring marks guest artists, which are ignored.
+var featuring = []string{" feat.", " feat ", " ft.", " ft ", " featuring "}
+
+// Artist guesses the main artist of a track from an "Artist - Title" style title, empty if there is none.
+func Artist(title string) string {
+	for _, separator := range titleSeparators {
+		if artist, _, found := strings.Cut(title, separator); found {
+			artist = strings.ToLower(strings.TrimSpace(artist))
+			for _, marker := range featuring {
+				artist, _, _ = strings.Cut(artist, marker)
+			}
+			return strings.TrimSpace(artist)
+		}
+	}
+	return ""
+}
+
+// noiseWords are common in video titles but say nothing about the music.
+var noiseWords = map[string]bool{
+	"official": true, "video": true, "audio": true, "lyrics": true, "lyric": true, "music": true,
+	"remastered": true, "remaster": true, "version": true, "full": true, "album": true,
+	"feat": true, "with": true, "from": true, "live": true, "visualizer": true, "explicit": true,
+}
+
+// Keywords returns the distinct lower case words of a title that may hint at its style, e.g. "lofi" or "jazz".
+// Short words and words common to every video title are left out.
+func Keywords(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if len([]rune(word)) < 4 || noiseWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+	return keywords
+}
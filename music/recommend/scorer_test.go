@@ -0,0 +1,115 @@
+package recommend
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func candidateIDs(candidates []Candidate) string {
+	ids := ""
+	for _, candidate := range candidates {
+		ids += candidate.ID
+	}
+	return ids
+}
+
+func TestArtist(t *testing.T) {
+	tests := map[string]string{
+		"Daft Punk - Around the World":           "daft punk",
+		"Daft Punk feat. Pharrell - Get Lucky":   "daft punk",
+		"Nujabes – Aruarian Dance":               "nujabes",
+		"lofi hip hop radio | beats to relax to": "lofi hip hop radio",
+		"Around the World":                       "",
+	}
+
+	for title, expected := range tests {
+		if got := Artist(title); got != expected {
+			t.Errorf("Incorrect artist of %q (got %q expected %q)", title, got, expected)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	got := Keywords("Chill Jazz Mix (Official Video) - jazz for work")
+	expected := []string{"chill", "jazz", "work"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Incorrect keywords (got %v expected %v)", got, expected)
+	}
+}
+
+func TestScorerFavorsFrequentArtists(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	profile := NewProfile([]Entry{
+		{ID: "x", Title: "Nujabes - Feather", PlayCount: 20, LastPlayed: now.Add(-48 * time.Hour)},
+		{ID: "y", Title: "Metallica - One", PlayCount: 1, LastPlayed: now.Add(-48 * time.Hour)},
+	})
+	scorer := NewScorer(profile)
+	scorer.Now = func() time.Time { return now }
+
+	candidates := []Candidate{
+		{ID: "a", Title: "Metallica - Fade to Black"},
+		{ID: "b", Title: "Some Band - Unknown"},
+		{ID: "c", Title: "Nujabes - Luv(sic)"},
+	}
+
+	got := candidateIDs(scorer.Rank(candidates))
+	if got != "cab" {
+		t.Errorf("Incorrect order (got %v expected cab)", got)
+	}
+}
+
+func TestScorerLeavesOutRecentAndDislikedTracks(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	profile := NewProfile([]Entry{
+		{ID: "a", Title: "Artist - Recent", PlayCount: 3, LastPlayed: now.Add(-time.Hour)},
+		{ID: "b", Title: "Artist - Disliked", PlayCount: 3, LastPlayed: now.Add(-48 * time.Hour), Dislikes: 2, Likes: 1},
+		{ID: "c", Title: "Artist - Old", PlayCount: 3, LastPlayed: now.Add(-48 * time.Hour)},
+	})
+	scorer := NewScorer(profile)
+	scorer.Now = func() time.Time { return now }
+
+	candidates := []Candidate{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+
+	got := candidateIDs(scorer.Rank(candidates))
+	if got != "cd" {
+		t.Errorf("Incorrect candidates (got %v expected cd)", got)
+	}
+
+	if _, ok := scorer.Best([]Candidate{{ID: "a"}, {ID: "b"}}); ok {
+		t.Errorf("Expected no candidate when all are left out")
+	}
+}
+
+func TestScorerPenalizesDislikedArtists(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	profile := NewProfile([]Entry{
+		{ID: "x", Title: "Loud Band - Noise", PlayCount: 2, Dislikes: 3, LastPlayed: now.Add(-48 * time.Hour)},
+		{ID: "y", Title: "Calm Band - Waves", PlayCount: 2, Likes: 1, LastPlayed: now.Add(-48 * time.Hour)},
+	})
+	scorer := NewScorer(profile)
+	scorer.Now = func() time.Time { return now }
+
+	// The source ranks the disliked artist first
+	candidates := []Candidate{
+		{ID: "a", Title: "Loud Band - More Noise"},
+		{ID: "b", Title: "Unknown - Song"},
+		{ID: "c", Title: "Calm Band - Tides"},
+	}
+
+	got := candidateIDs(scorer.Rank(candidates))
+	if got != "cba" {
+		t.Errorf("Incorrect order (got %v expected cba)", got)
+	}
+}
+
+func TestScorerKeepsSourceOrderWithoutHistory(t *testing.T) {
+	scorer := NewScorer(NewProfile(nil))
+
+	candidates := []Candidate{{ID: "a", Title: "One - A"}, {ID: "b", Title: "Two - B"}, {ID: "c", Title: "Three - C"}}
+
+	got := candidateIDs(scorer.Rank(candidates))
+	if got != "abc" {
+		t.Errorf("Incorrect order (got %v expected abc)", got)
+	}
+}
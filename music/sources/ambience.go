@@ -0,0 +1,118 @@
+package sources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// ambienceIDPrefix starts the song IDs of ambience loops, the preset name follows.
+const ambienceIDPrefix = "ambience-"
+
+// generatedAmbience are presets ffmpeg generates itself, so they work without any files.
+var generatedAmbience = map[string]string{
+	"white": "anoisesrc=color=white:amplitude=0.1",
+	"pink":  "anoisesrc=color=pink:amplitude=0.15",
+	"brown": "anoisesrc=color=brown:amplitude=0.3",
+}
+
+// ambienceExtensions are the audio files of the ambience directory that become presets.
+var ambienceExtensions = map[string]bool{
+	".ogg": true, ".opus": true, ".mp3": true, ".m4a": true, ".flac": true, ".wav": true,
+}
+
+// Ambience is a source of endless background loops like rain or white noise.
+type Ambience struct {
+	dir  string            // directory of loop files, each named after its preset
+	urls map[string]string // URLs by preset name
+}
+
+// NewAmbience creates a source of the loops in dir and the presets of name=url pairs.
+func NewAmbience(dir string, urls []string) *Ambience {
+	a := &Ambience{dir: dir, urls: make(map[string]string)}
+	for _, pair := range urls {
+		name, url, found := strings.Cut(pair, "=")
+		if found && name != "" && url != "" {
+			a.urls[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(url)
+		}
+	}
+	return a
+}
+
+// Presets returns the names of all available presets, sorted.
+func (a *Ambience) Presets() []string {
+	seen := make(map[string]bool)
+	for name := range generatedAmbience {
+		seen[name] = true
+	}
+	for name := range a.urls {
+		seen[name] = true
+	}
+	for name := range a.files() {
+		seen[name] = true
+	}
+
+	presets := make([]string, 0, len(seen))
+	for name := range seen {
+		presets = append(presets, name)
+	}
+	sort.Strings(presets)
+	return presets
+}
+
+// FetchAmbience returns the loop of a preset, URLs take precedence over files and files over generated noise.
+func (a *Ambience) FetchAmbience(name string) (*player.Song, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	song := &player.Song{
+		Title:    fmt.Sprintf("Ambience: %v", name),
+		Duration: -1,
+		ID:       ambienceIDPrefix + name,
+		Source:   player.SourceAmbience,
+	}
+
+	if url, ok := a.urls[name]; ok {
+		song.UserURL, song.DownloadURL = url, url
+		return song, nil
+	}
+	if path, ok := a.files()[name]; ok {
+		song.DownloadURL = path
+		return song, nil
+	}
+	if source, ok := generatedAmbience[name]; ok {
+		song.DownloadURL, song.InputFormat = source, "lavfi"
+		return song, nil
+	}
+
+	return nil, fmt.Errorf("unknown ambience %v", name)
+}
+
+// AmbienceName returns the preset name of an ambience song ID.
+func AmbienceName(songID string) string {
+	return strings.TrimPrefix(songID, ambienceIDPrefix)
+}
+
+// files returns the loop files of the directory by preset name, none if it doesn't exist.
+func (a *Ambience) files() map[string]string {
+	files := make(map[string]string)
+
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return files
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !ambienceExtensions[ext] {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		files[name] = filepath.Join(a.dir, entry.Name())
+	}
+
+	return files
+}
@@ -0,0 +1,52 @@
+package sources
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+// metadataTTL is how long looked up metadata is cached. Download URLs expire within hours and only work
+// for the address that looked them up, so they are never cached.
+const metadataTTL = 24 * time.Hour
+
+// MetadataCache keeps looked up track metadata, shared by all processes using it.
+type MetadataCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// Metadata caches YouTube searches and playlists, nil disables caching. main sets it when Redis is configured.
+var Metadata MetadataCache
+
+// cached decodes the cached value of a key into v, false if it isn't cached.
+func cached(key string, v interface{}) bool {
+	if Metadata == nil {
+		return false
+	}
+
+	data, ok := Metadata.Get(key)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		slog.Warnf("Error decoding cached %v: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// cache stores v as the cached value of a key.
+func cache(key string, v interface{}) {
+	if Metadata == nil {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Warnf("Error encoding %v for the cache: %v", key, err)
+		return
+	}
+	Metadata.Set(key, data, metadataTTL)
+}
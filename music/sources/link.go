@@ -0,0 +1,48 @@
+package sources
+
+import (
+	"context"
+	"sync"
+
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+// LinkSource plays the links of a service other than YouTube, e.g. SoundCloud. Sources register themselves
+// with RegisterLinkSource, play commands and saved tracks find them by their links.
+type LinkSource interface {
+	// Name is the source the songs are counted under in the failure report
+	Name() string
+	// Matches reports whether a link is one of the source
+	Matches(link string) bool
+	// FetchSongsByURLs looks up the songs of links of the source, a link may stand for several songs
+	FetchSongsByURLs(ctx context.Context, urls []string) ([]*player.Song, error)
+	// ReferenceSong creates a song of a known link without looking it up,
+	// its download URL is extracted once it's about to play
+	ReferenceSong(title, link, id string) *player.Song
+}
+
+var linkSources struct {
+	sync.RWMutex
+	list []LinkSource
+}
+
+// RegisterLinkSource adds a source of links, sources registered first take precedence.
+func RegisterLinkSource(source LinkSource) {
+	linkSources.Lock()
+	defer linkSources.Unlock()
+
+	linkSources.list = append(linkSources.list, source)
+}
+
+// LinkSourceFor returns the registered source of a link, nil if none matches it.
+func LinkSourceFor(link string) LinkSource {
+	linkSources.RLock()
+	defer linkSources.RUnlock()
+
+	for _, source := range linkSources.list {
+		if source.Matches(link) {
+			return source
+		}
+	}
+	return nil
+}
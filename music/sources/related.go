@@ -0,0 +1,98 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+	"github.com/keshon/melodix-discord-player/music/recommend"
+)
+
+var (
+	// relatedVideoID matches the IDs of videos in the "up next" list of a watch page
+	relatedVideoID = regexp.MustCompile(`"(?:videoId|contentId)":"([a-zA-Z0-9_-]{11})"`)
+	// relatedVideoTitle matches the ID and title of the "up next" entries of the watch page layouts known
+	relatedVideoTitle = regexp.MustCompile(`"compactVideoRenderer":\{"videoId":"([a-zA-Z0-9_-]{11})".*?"title":\{(?:"accessibility":\{.*?\},)?"simpleText":"((?:[^"\\]|\\.)*)"` +
+		`|"contentId":"([a-zA-Z0-9_-]{11})".*?"title":\{"content":"((?:[^"\\]|\\.)*)"`)
+)
+
+// RelatedVideos lists the videos YouTube suggests watching after a video, in its order.
+// Videos whose title couldn't be read have an empty title.
+func (y *Youtube) RelatedVideos(ctx context.Context, videoID string) ([]recommend.Candidate, error) {
+	key := "youtube:related:" + videoID
+
+	var candidates []recommend.Candidate
+	if cached(key, &candidates) {
+		return candidates, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "youtube related", tracing.String("youtube.video_id", videoID))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.youtube.com/watch?v="+videoID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP request failed with status code %v", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The suggestions follow the video itself and its comments teaser in the page data
+	page := string(body)
+	if start := strings.Index(page, `"secondaryResults"`); start >= 0 {
+		page = page[start:]
+	} else {
+		return nil, fmt.Errorf("no suggestions found for video %v", videoID)
+	}
+
+	titles := make(map[string]string)
+	for _, match := range relatedVideoTitle.FindAllStringSubmatch(page, -1) {
+		id, title := match[1], match[2]
+		if id == "" {
+			id, title = match[3], match[4]
+		}
+		if unquoted, err := strconv.Unquote(`"` + title + `"`); err == nil {
+			title = unquoted
+		}
+		if _, found := titles[id]; !found {
+			titles[id] = title
+		}
+	}
+
+	seen := map[string]bool{videoID: true}
+	for _, match := range relatedVideoID.FindAllStringSubmatch(page, -1) {
+		id := match[1]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		candidates = append(candidates, recommend.Candidate{
+			ID:    id,
+			Title: titles[id],
+			URL:   "https://www.youtube.com/watch?v=" + id,
+		})
+	}
+	span.SetAttributes(tracing.Int("youtube.related", len(candidates)))
+
+	cache(key, candidates)
+	return candidates, nil
+}
@@ -0,0 +1,431 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	soundCloudAPIURL = "https://api-v2.soundcloud.com"
+	soundCloudWebURL = "https://soundcloud.com"
+
+	// soundCloudTrackLimit caps the tracks taken from a set
+	soundCloudTrackLimit = 200
+	// soundCloudTracksPerRequest is the most tracks the API returns by their IDs at once
+	soundCloudTracksPerRequest = 50
+	soundCloudTimeout          = 10 * time.Second
+)
+
+var (
+	// soundCloudScriptPattern finds the scripts of the web player, one of them holds its client ID
+	soundCloudScriptPattern = regexp.MustCompile(`<script[^>]+src="(https://[^"]+\.sndcdn\.com/assets/[^"]+\.js)"`)
+	// soundCloudClientIDPattern finds the client ID in a script of the web player
+	soundCloudClientIDPattern = regexp.MustCompile(`client_id\s*[:=]\s*"([a-zA-Z0-9]{32})"`)
+
+	errSoundCloudUnauthorized = errors.New("SoundCloud API refused the client ID")
+)
+
+// SoundCloud plays tracks and sets (playlists and albums) of soundcloud.com links.
+type SoundCloud struct {
+	httpClient *http.Client
+}
+
+// soundCloudClientID is the client ID of the API, taken from the web player unless one is configured.
+// It's shared by all SoundCloud sources and looked up again once the API refuses it.
+var soundCloudClientID struct {
+	sync.Mutex
+	value string
+}
+
+// soundCloudTrack is a track object of the API, tracks of long sets only carry their ID.
+type soundCloudTrack struct {
+	ID                 int64  `json:"id"`
+	Kind               string `json:"kind"`
+	Title              string `json:"title"`
+	Duration           int64  `json:"duration"` // ms
+	ArtworkURL         string `json:"artwork_url"`
+	PermalinkURL       string `json:"permalink_url"`
+	Policy             string `json:"policy"` // BLOCK for tracks that can't be played in the region
+	TrackAuthorization string `json:"track_authorization"`
+	User               struct {
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	Media struct {
+		Transcodings []soundCloudTranscoding `json:"transcodings"`
+	} `json:"media"`
+}
+
+// soundCloudTranscoding is an encoding of a track, its URL answers with the URL of the audio.
+type soundCloudTranscoding struct {
+	URL     string `json:"url"`
+	Preset  string `json:"preset"`  // e.g. mp3_0_0, opus_0_0
+	Snipped bool   `json:"snipped"` // only a preview of the track
+	Format  struct {
+		Protocol string `json:"protocol"` // progressive or hls
+		MimeType string `json:"mime_type"`
+	} `json:"format"`
+}
+
+// soundCloudResource is what a link resolves to, a track or a set of tracks.
+type soundCloudResource struct {
+	soundCloudTrack
+	Tracks []soundCloudTrack `json:"tracks"`
+}
+
+func init() {
+	RegisterLinkSource(NewSoundCloud())
+}
+
+// NewSoundCloud creates a SoundCloud source.
+func NewSoundCloud() *SoundCloud {
+	return &SoundCloud{
+		httpClient: &http.Client{Timeout: soundCloudTimeout},
+	}
+}
+
+// Name returns the name SoundCloud songs are counted under in the failure report.
+func (sc *SoundCloud) Name() string {
+	return player.SourceSoundCloud.String()
+}
+
+// Matches reports whether a link points to soundcloud.com, e.g. a track, a set or a short on.soundcloud.com link.
+func (sc *SoundCloud) Matches(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || strings.Trim(u.Path, "/") == "" {
+		return false
+	}
+
+	switch u.Host {
+	case "soundcloud.com", "www.soundcloud.com", "m.soundcloud.com", "on.soundcloud.com":
+		return true
+	}
+	return false
+}
+
+// FetchSongsByURLs looks up the tracks of SoundCloud links, up to soundCloudTrackLimit of a set. The audio URLs
+// expire quickly, so the songs are extracted once they are about to play. Tracks that can't be played are skipped.
+func (sc *SoundCloud) FetchSongsByURLs(ctx context.Context, urls []string) ([]*player.Song, error) {
+	var songs []*player.Song
+	for _, link := range urls {
+		tracksCtx, span := tracing.Start(ctx, "soundcloud tracks", tracing.String("soundcloud.url", link))
+		tracks, err := sc.tracks(tracksCtx, link)
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			return nil, fmt.Errorf("error getting SoundCloud %v: %w", link, err)
+		}
+
+		var found int
+		for _, track := range tracks {
+			if track.Policy == "BLOCK" || len(track.Media.Transcodings) == 0 {
+				slog.Warnf("Skipping SoundCloud track %q, it can't be played", track.Title)
+				continue
+			}
+			songs = append(songs, sc.songOf(track))
+			found++
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("no playable SoundCloud track at %v", link)
+		}
+	}
+
+	return songs, nil
+}
+
+// ReferenceSong creates a song of a SoundCloud track without looking it up, its audio URL is extracted once
+// it's about to play.
+func (sc *SoundCloud) ReferenceSong(title, link, id string) *player.Song {
+	return &player.Song{
+		Title:    title,
+		UserURL:  link,
+		ID:       id,
+		Source:   player.SourceSoundCloud,
+		Resolver: sc.resolveSong,
+	}
+}
+
+// songOf creates a song of a track, its audio URL is extracted once it's about to play.
+func (sc *SoundCloud) songOf(track soundCloudTrack) *player.Song {
+	song := sc.ReferenceSong(track.Title, track.PermalinkURL, soundCloudSongID(track.ID))
+	song.Duration = time.Duration(track.Duration) * time.Millisecond
+	song.Thumbnail = soundCloudArtwork(track)
+	return song
+}
+
+// soundCloudSongID tells SoundCloud tracks apart from YouTube videos in the history.
+func soundCloudSongID(trackID int64) string {
+	return fmt.Sprintf("soundcloud:%d", trackID)
+}
+
+// soundCloudArtwork returns the artwork of a track in its larger size, the avatar of its author if it has none.
+func soundCloudArtwork(track soundCloudTrack) player.Thumbnail {
+	artwork := track.ArtworkURL
+	if artwork == "" {
+		artwork = track.User.AvatarURL
+	}
+	if artwork == "" {
+		return player.Thumbnail{}
+	}
+	return player.Thumbnail{URL: strings.Replace(artwork, "-large.", "-t500x500.", 1), Width: 500, Height: 500}
+}
+
+// tracks returns the track of a link or the tracks of a set, up to soundCloudTrackLimit.
+func (sc *SoundCloud) tracks(ctx context.Context, link string) ([]soundCloudTrack, error) {
+	key := "soundcloud:resolve:" + link
+
+	var tracks []soundCloudTrack
+	if cached(key, &tracks) {
+		return tracks, nil
+	}
+
+	resource, err := sc.resolve(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resource.Kind {
+	case "track":
+		tracks = append(tracks, resource.soundCloudTrack)
+	case "playlist":
+		if tracks, err = sc.completeTracks(ctx, resource.Tracks); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("not a track or a set but a %v", resource.Kind)
+	}
+
+	cache(key, tracks)
+	return tracks, nil
+}
+
+// resolve looks up what a link points to, short links are followed first.
+func (sc *SoundCloud) resolve(ctx context.Context, link string) (*soundCloudResource, error) {
+	if u, err := url.Parse(link); err == nil && u.Host == "on.soundcloud.com" {
+		resp, err := sc.fetch(ctx, link)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		link = resp.Request.URL.String()
+	}
+
+	var resource soundCloudResource
+	if err := sc.get(ctx, soundCloudAPIURL+"/resolve", url.Values{"url": {link}}, &resource); err != nil {
+		return nil, err
+	}
+	return &resource, nil
+}
+
+// completeTracks looks up the tracks of a set that only carry their ID, keeping the order of the set.
+func (sc *SoundCloud) completeTracks(ctx context.Context, tracks []soundCloudTrack) ([]soundCloudTrack, error) {
+	if len(tracks) > soundCloudTrackLimit {
+		tracks = tracks[:soundCloudTrackLimit]
+	}
+
+	var missing []string
+	for _, track := range tracks {
+		if track.Title == "" {
+			missing = append(missing, fmt.Sprint(track.ID))
+		}
+	}
+
+	looked := make(map[int64]soundCloudTrack)
+	for start := 0; start < len(missing); start += soundCloudTracksPerRequest {
+		end := min(start+soundCloudTracksPerRequest, len(missing))
+
+		var batch []soundCloudTrack
+		if err := sc.get(ctx, soundCloudAPIURL+"/tracks", url.Values{"ids": {strings.Join(missing[start:end], ",")}}, &batch); err != nil {
+			return nil, err
+		}
+		for _, track := range batch {
+			looked[track.ID] = track
+		}
+	}
+
+	complete := make([]soundCloudTrack, 0, len(tracks))
+	for _, track := range tracks {
+		if track.Title == "" {
+			var ok bool
+			if track, ok = looked[track.ID]; !ok {
+				continue // deleted or private
+			}
+		}
+		complete = append(complete, track)
+	}
+	return complete, nil
+}
+
+// resolveSong extracts the audio URLs of a song, progressive ones before HLS playlists and previews last.
+func (sc *SoundCloud) resolveSong(ctx context.Context, song *player.Song) error {
+	resource, err := sc.resolve(ctx, song.UserURL)
+	if err != nil {
+		return err
+	}
+	if resource.Kind != "track" {
+		return fmt.Errorf("%v is a %v rather than a track", song.UserURL, resource.Kind)
+	}
+	track := resource.soundCloudTrack
+
+	transcodings := track.Media.Transcodings
+	sort.SliceStable(transcodings, func(i, j int) bool {
+		if transcodings[i].Snipped != transcodings[j].Snipped {
+			return !transcodings[i].Snipped
+		}
+		return transcodings[i].Format.Protocol == "progressive" && transcodings[j].Format.Protocol != "progressive"
+	})
+
+	var formats []player.AudioFormat
+	for _, transcoding := range transcodings {
+		var stream struct {
+			URL string `json:"url"`
+		}
+		params := url.Values{}
+		if track.TrackAuthorization != "" {
+			params.Set("track_authorization", track.TrackAuthorization)
+		}
+		if err := sc.get(ctx, transcoding.URL, params, &stream); err != nil || stream.URL == "" {
+			slog.Warnf("Skipping %v transcoding of %q: %v", transcoding.Preset, song.Title, err)
+			continue
+		}
+		formats = append(formats, player.AudioFormat{
+			URL:         stream.URL,
+			Description: fmt.Sprintf("%v %v %v", transcoding.Preset, transcoding.Format.Protocol, transcoding.Format.MimeType),
+		})
+	}
+	if len(formats) == 0 {
+		return fmt.Errorf("no audio found for %v", song.UserURL)
+	}
+
+	song.DownloadURL = formats[0].URL
+	song.Formats = formats
+	song.Duration = time.Duration(track.Duration) * time.Millisecond
+	song.Thumbnail = soundCloudArtwork(track)
+	return nil
+}
+
+// get requests an API URL with the client ID and decodes the JSON answer into v. A refused client ID
+// is looked up again once.
+func (sc *SoundCloud) get(ctx context.Context, apiURL string, params url.Values, v interface{}) error {
+	err := sc.getWithClientID(ctx, apiURL, params, v)
+	if errors.Is(err, errSoundCloudUnauthorized) {
+		soundCloudClientID.Lock()
+		soundCloudClientID.value = ""
+		soundCloudClientID.Unlock()
+
+		err = sc.getWithClientID(ctx, apiURL, params, v)
+	}
+	return err
+}
+
+func (sc *SoundCloud) getWithClientID(ctx context.Context, apiURL string, params url.Values, v interface{}) error {
+	clientID, err := sc.clientID(ctx)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("client_id", clientID)
+	u.RawQuery = query.Encode()
+
+	resp, err := sc.fetch(ctx, u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errSoundCloudUnauthorized
+	}
+	return fmt.Errorf("SoundCloud API answered %v", resp.Status)
+}
+
+// clientID returns the configured client ID, or else the one of the web player.
+func (sc *SoundCloud) clientID(ctx context.Context) (string, error) {
+	config, err := config.NewConfig()
+	if err != nil {
+		return "", err
+	}
+	if config.SoundCloudClientID != "" {
+		return config.SoundCloudClientID, nil
+	}
+
+	soundCloudClientID.Lock()
+	defer soundCloudClientID.Unlock()
+
+	if soundCloudClientID.value != "" {
+		return soundCloudClientID.value, nil
+	}
+
+	page, err := sc.fetchText(ctx, soundCloudWebURL)
+	if err != nil {
+		return "", fmt.Errorf("error loading the SoundCloud web player: %w", err)
+	}
+
+	// The client ID is in one of the last scripts
+	scripts := soundCloudScriptPattern.FindAllStringSubmatch(page, -1)
+	for i := len(scripts) - 1; i >= 0; i-- {
+		script, err := sc.fetchText(ctx, scripts[i][1])
+		if err != nil {
+			slog.Debugf("Error loading SoundCloud script %v: %v", scripts[i][1], err)
+			continue
+		}
+		if match := soundCloudClientIDPattern.FindStringSubmatch(script); match != nil {
+			soundCloudClientID.value = match[1]
+			return match[1], nil
+		}
+	}
+
+	return "", errors.New("no SoundCloud client ID found in the web player, set SOUNDCLOUD_CLIENT_ID")
+}
+
+func (sc *SoundCloud) fetchText(ctx context.Context, link string) (string, error) {
+	resp, err := sc.fetch(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP request failed with status code %v", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// fetch requests a URL with the HTTP client of the source.
+func (sc *SoundCloud) fetch(ctx context.Context, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sc.httpClient.Do(req)
+}
@@ -0,0 +1,370 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
+	"github.com/keshon/melodix-discord-player/music/player"
+)
+
+const (
+	spotifyTokenURL = "https://accounts.spotify.com/api/token"
+	spotifyAPIURL   = "https://api.spotify.com/v1"
+
+	// SpotifySourceName is the source Spotify lookups are counted under in the failure report,
+	// the songs themselves play from YouTube
+	SpotifySourceName = "Spotify"
+
+	// spotifyTrackLimit caps the tracks taken from an album or playlist, each costs a YouTube search
+	spotifyTrackLimit = 200
+	// spotifySearchWorkers is how many YouTube searches run at once for albums and playlists
+	spotifySearchWorkers = 4
+	spotifyTimeout       = 10 * time.Second
+)
+
+// ErrSpotifyNotConfigured is returned for Spotify links when no client credentials are configured.
+var ErrSpotifyNotConfigured = errors.New("Spotify isn't configured, SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET are missing")
+
+// Spotify looks up tracks, albums and playlists with the Spotify Web API and plays their tracks from YouTube.
+type Spotify struct {
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	youtube      *Youtube
+}
+
+// spotifyToken is the access token of the client credentials, shared by all Spotify sources.
+var spotifyToken struct {
+	sync.Mutex
+	clientID string
+	value    string
+	expires  time.Time
+}
+
+// spotifyTrack is a track object of the Web API.
+type spotifyTrack struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	DurationMs int    `json:"duration_ms"`
+	Artists    []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Images []spotifyImage `json:"images"`
+	} `json:"album"`
+	ExternalURLs struct {
+		Spotify string `json:"spotify"`
+	} `json:"external_urls"`
+}
+
+type spotifyImage struct {
+	URL    string `json:"url"`
+	Width  uint   `json:"width"`
+	Height uint   `json:"height"`
+}
+
+// spotifyPage is a page of album tracks or playlist items.
+type spotifyPage struct {
+	Items []struct {
+		spotifyTrack
+		Track *spotifyTrack `json:"track"` // set for playlist items only
+	} `json:"items"`
+	Next string `json:"next"`
+}
+
+// NewSpotify creates a Spotify source with the client credentials of a Spotify app.
+func NewSpotify(clientID, clientSecret string) *Spotify {
+	return &Spotify{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: spotifyTimeout},
+		youtube:      NewYoutube(),
+	}
+}
+
+// IsSpotifyURL reports whether a link points to a Spotify track, album or playlist.
+func IsSpotifyURL(link string) bool {
+	_, _, ok := parseSpotifyURL(link)
+	return ok
+}
+
+// parseSpotifyURL returns the kind (track, album or playlist) and ID of an open.spotify.com link or a spotify: URI.
+func parseSpotifyURL(link string) (string, string, bool) {
+	var parts []string
+	if uri, ok := strings.CutPrefix(link, "spotify:"); ok {
+		parts = strings.Split(uri, ":")
+	} else {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host != "open.spotify.com" {
+			return "", "", false
+		}
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+		// Localized links start with the market, e.g. /intl-de/track/<id>
+		if len(parts) > 0 && strings.HasPrefix(parts[0], "intl-") {
+			parts = parts[1:]
+		}
+	}
+
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", false
+	}
+	switch parts[0] {
+	case "track", "album", "playlist":
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+// FetchSongsByURLs looks up the tracks of Spotify links and finds each on YouTube. The songs are
+// looked up on YouTube once they are about to play, tracks without a search result are skipped.
+func (sp *Spotify) FetchSongsByURLs(ctx context.Context, urls []string) ([]*player.Song, error) {
+	if sp.clientID == "" || sp.clientSecret == "" {
+		return nil, ErrSpotifyNotConfigured
+	}
+
+	var songs []*player.Song
+	for _, link := range urls {
+		kind, id, ok := parseSpotifyURL(link)
+		if !ok {
+			return nil, fmt.Errorf("not a Spotify track, album or playlist: %v", link)
+		}
+
+		tracksCtx, span := tracing.Start(ctx, "spotify tracks", tracing.String("spotify.kind", kind), tracing.String("spotify.id", id))
+		tracks, err := sp.tracks(tracksCtx, kind, id)
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			return nil, fmt.Errorf("error getting Spotify %v %v: %w", kind, id, err)
+		}
+
+		found := sp.findOnYoutube(ctx, tracks)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no video found for the tracks of Spotify %v %v", kind, id)
+		}
+		songs = append(songs, found...)
+	}
+
+	return songs, nil
+}
+
+// tracks returns the tracks of a track, album or playlist, up to spotifyTrackLimit.
+func (sp *Spotify) tracks(ctx context.Context, kind, id string) ([]spotifyTrack, error) {
+	key := fmt.Sprintf("spotify:%v:%v", kind, id)
+
+	var tracks []spotifyTrack
+	if cached(key, &tracks) {
+		return tracks, nil
+	}
+
+	switch kind {
+	case "track":
+		var track spotifyTrack
+		if err := sp.get(ctx, fmt.Sprintf("%v/tracks/%v", spotifyAPIURL, url.PathEscape(id)), &track); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, track)
+	case "album":
+		// Album tracks carry no album, its images are taken from the album itself
+		var album struct {
+			Images []spotifyImage `json:"images"`
+			Tracks spotifyPage    `json:"tracks"`
+		}
+		if err := sp.get(ctx, fmt.Sprintf("%v/albums/%v", spotifyAPIURL, url.PathEscape(id)), &album); err != nil {
+			return nil, err
+		}
+		all, err := sp.pages(ctx, album.Tracks)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range all {
+			track.Album.Images = album.Images
+			tracks = append(tracks, track)
+		}
+	case "playlist":
+		var first spotifyPage
+		if err := sp.get(ctx, fmt.Sprintf("%v/playlists/%v/tracks?limit=100", spotifyAPIURL, url.PathEscape(id)), &first); err != nil {
+			return nil, err
+		}
+		var err error
+		if tracks, err = sp.pages(ctx, first); err != nil {
+			return nil, err
+		}
+	}
+
+	cache(key, tracks)
+	return tracks, nil
+}
+
+// pages collects the tracks of a page and the pages after it, up to spotifyTrackLimit.
+// Playlist items without a track, e.g. removed or local ones, are skipped.
+func (sp *Spotify) pages(ctx context.Context, page spotifyPage) ([]spotifyTrack, error) {
+	var tracks []spotifyTrack
+	for {
+		for _, item := range page.Items {
+			track := item.spotifyTrack
+			if item.Track != nil {
+				track = *item.Track
+			}
+			if track.ID == "" {
+				continue
+			}
+			tracks = append(tracks, track)
+			if len(tracks) == spotifyTrackLimit {
+				return tracks, nil
+			}
+		}
+
+		if page.Next == "" {
+			return tracks, nil
+		}
+		next := page.Next
+		page = spotifyPage{}
+		if err := sp.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// findOnYoutube searches YouTube for each track by its artists and name, keeping the order of the tracks.
+// Searches stop once the context ends.
+func (sp *Spotify) findOnYoutube(ctx context.Context, tracks []spotifyTrack) []*player.Song {
+	found := make([]*player.Song, len(tracks))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < spotifySearchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				song, err := sp.findTrack(ctx, tracks[i])
+				if err != nil {
+					slog.Warnf("Skipping Spotify track %q: %v", tracks[i].Name, err)
+					continue
+				}
+				found[i] = song
+			}
+		}()
+	}
+	for i := range tracks {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var songs []*player.Song
+	for _, song := range found {
+		if song != nil {
+			songs = append(songs, song)
+		}
+	}
+	return songs
+}
+
+// findTrack creates a song of the YouTube video found for a track, its download URL is looked up once it's about to play.
+func (sp *Spotify) findTrack(ctx context.Context, track spotifyTrack) (*player.Song, error) {
+	var artists []string
+	for _, artist := range track.Artists {
+		artists = append(artists, artist.Name)
+	}
+	title := track.Name
+	if len(artists) > 0 {
+		title = strings.Join(artists, ", ") + " - " + track.Name
+	}
+
+	videoURL, err := sp.youtube.getVideoURLFromTitle(ctx, url.QueryEscape(title))
+	if err != nil {
+		return nil, err
+	}
+	// The search may have matched a video of a playlist, only the video is played
+	videoURL, _, _ = strings.Cut(videoURL, "&")
+	videoID := strings.TrimPrefix(videoURL, "https://www.youtube.com/watch?v=")
+
+	song := sp.youtube.LightweightSong(title, videoURL, videoID)
+	song.Duration = time.Duration(track.DurationMs) * time.Millisecond
+	if len(track.Album.Images) > 0 {
+		song.Thumbnail = player.Thumbnail(track.Album.Images[0])
+	}
+	return song, nil
+}
+
+// get requests an API URL and decodes the JSON answer into v.
+func (sp *Spotify) get(ctx context.Context, apiURL string, v interface{}) error {
+	token, err := sp.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := sp.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Spotify API answered %v", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// token returns an access token of the client credentials, requesting a new one shortly before the current one expires.
+func (sp *Spotify) token(ctx context.Context) (string, error) {
+	spotifyToken.Lock()
+	defer spotifyToken.Unlock()
+
+	if spotifyToken.clientID == sp.clientID && time.Until(spotifyToken.expires) > time.Minute {
+		return spotifyToken.value, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, spotifyTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(sp.clientID, sp.clientSecret)
+
+	resp, err := sp.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Spotify token request answered %v, check the client ID and secret", resp.Status)
+	}
+
+	var answer struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return "", err
+	}
+
+	spotifyToken.clientID = sp.clientID
+	spotifyToken.value = answer.AccessToken
+	spotifyToken.expires = time.Now().Add(time.Duration(answer.ExpiresIn) * time.Second)
+	return answer.AccessToken, nil
+}
@@ -1,6 +1,7 @@
 package sources
 
 import (
+	"context"
 	"fmt"
 	"hash/crc32"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/gookit/slog"
 
+	"github.com/keshon/melodix-discord-player/internal/tracing"
 	"github.com/keshon/melodix-discord-player/music/player"
 )
 
@@ -20,7 +22,7 @@ func NewStream() *Stream {
 }
 
 // FetchStreamsByURLs fetches stream URLs into Song struct.
-func (s *Stream) FetchStreamsByURLs(urls []string) ([]*player.Song, error) {
+func (s *Stream) FetchStreamsByURLs(ctx context.Context, urls []string) ([]*player.Song, error) {
 	var songs []*player.Song
 
 	for _, elem := range urls {
@@ -36,8 +38,11 @@ func (s *Stream) FetchStreamsByURLs(urls []string) ([]*player.Song, error) {
 		hash := crc32.ChecksumIEEE([]byte(u.Host))
 
 		// Fetch the stream and check the content type
-		contentType, err := getContentType(u.String())
+		contentType, err := getContentType(ctx, u.String())
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			slog.Errorf("Error fetching content type: %v", err)
 			continue
 		}
@@ -62,9 +67,18 @@ func (s *Stream) FetchStreamsByURLs(urls []string) ([]*player.Song, error) {
 }
 
 // getContentType fetches the content type of a given URL.
-func getContentType(url string) (string, error) {
-	resp, err := http.Head(url)
+func getContentType(ctx context.Context, url string) (string, error) {
+	ctx, span := tracing.Start(ctx, "stream probe", tracing.String("stream.url", url))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		span.SetError(err)
 		return "", err
 	}
 	defer resp.Body.Close()
@@ -1,16 +1,20 @@
 package sources
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
 
 	"github.com/gookit/slog"
+	"github.com/keshon/melodix-discord-player/internal/config"
+	"github.com/keshon/melodix-discord-player/internal/tracing"
 	"github.com/keshon/melodix-discord-player/music/history"
 	"github.com/keshon/melodix-discord-player/music/player"
 
@@ -20,6 +24,7 @@ import (
 // Youtube is a struct that encapsulates the YouTube functionality.
 type Youtube struct {
 	youtubeClient *kkdai_youtube.Client
+	onPlaylist    func(videos, queued int)
 }
 
 // NewYoutube creates a new instance of kkdai_youtube.
@@ -29,10 +34,19 @@ func NewYoutube() *Youtube {
 	}
 }
 
+// OnPlaylist sets a func told about each playlist found, with the number of its videos and of those queued.
+func (y *Youtube) OnPlaylist(found func(videos, queued int)) {
+	y.onPlaylist = found
+}
+
 // GetSongFromVideoURL creates a new Song instance using the provided YouTube URL.
-func (y *Youtube) GetSongFromVideoURL(url string) (*player.Song, error) {
-	song, err := y.youtubeClient.GetVideo(url)
+func (y *Youtube) GetSongFromVideoURL(ctx context.Context, url string) (*player.Song, error) {
+	ctx, span := tracing.Start(ctx, "youtube video", tracing.String("youtube.url", url))
+	defer span.End()
+
+	song, err := y.youtubeClient.GetVideoContext(ctx, url)
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
@@ -41,10 +55,16 @@ func (y *Youtube) GetSongFromVideoURL(url string) (*player.Song, error) {
 		thumbnail = player.Thumbnail(song.Thumbnails[0])
 	}
 
+	formats := audioFormats(song.Formats.WithAudioChannels())
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no audio formats found for %v", url)
+	}
+
 	return &player.Song{
 		Title:       song.Title,
 		UserURL:     url,
-		DownloadURL: song.Formats.WithAudioChannels()[0].URL,
+		DownloadURL: formats[0].URL,
+		Formats:     formats,
 		Duration:    song.Duration,
 		Thumbnail:   thumbnail,
 		ID:          song.ID,
@@ -52,60 +72,101 @@ func (y *Youtube) GetSongFromVideoURL(url string) (*player.Song, error) {
 	}, nil
 }
 
-// getAllSongsFromURL creates an array of Song instances from a YouTube playlist.
-func (y *Youtube) getAllSongsFromURL(url string) ([]*player.Song, error) {
+// audioFormats lists the formats with audio best first, skipping those without a direct URL: audio-only formats
+// before those with video, which ffmpeg has to download the video of as well, then by bitrate.
+func audioFormats(list kkdai_youtube.FormatList) []player.AudioFormat {
+	list = append(kkdai_youtube.FormatList{}, list...)
+	sort.SliceStable(list, func(i, j int) bool {
+		iAudioOnly, jAudioOnly := strings.HasPrefix(list[i].MimeType, "audio/"), strings.HasPrefix(list[j].MimeType, "audio/")
+		if iAudioOnly != jAudioOnly {
+			return iAudioOnly
+		}
+		return list[i].Bitrate > list[j].Bitrate
+	})
+
+	var formats []player.AudioFormat
+	for _, format := range list {
+		if format.URL == "" {
+			continue
+		}
+		formats = append(formats, player.AudioFormat{
+			URL:         format.URL,
+			Description: fmt.Sprintf("itag %d %v %d kbps", format.ItagNo, format.MimeType, format.Bitrate/1000),
+		})
+	}
+	return formats
+}
+
+// getAllSongsFromURL creates an array of Song instances from a YouTube video or playlist URL,
+// a playlist is cut off at the configured limit.
+func (y *Youtube) getAllSongsFromURL(ctx context.Context, url string) ([]*player.Song, error) {
 	var songs []*player.Song
 
-	if strings.Contains(url, "list=") {
-		// It's a playlist
-		playlistID := y.extractPlaylistID(url)
-		playlistVideos, err := y.youtubeClient.GetPlaylist(playlistID)
+	playlistID := y.extractPlaylistID(url)
+	var videos []*kkdai_youtube.PlaylistEntry
+	if playlistID != "" {
+		var err error
+		videos, err = y.getPlaylistVideos(ctx, playlistID)
 		if err != nil {
-			return nil, err
+			// Mixes and private playlists can't be listed, their video still plays
+			if !strings.Contains(url, "v=") {
+				return nil, err
+			}
+			slog.Warnf("Playing only the video of %v, its playlist can't be listed: %v", url, err)
+			playlistID = ""
+		}
+	}
+
+	if playlistID != "" {
+		// It's a playlist
+		total := len(videos)
+		if limit := playlistLimit(); limit > 0 && len(videos) > limit {
+			videos = videos[:limit]
+		}
+		if y.onPlaylist != nil {
+			y.onPlaylist(total, len(videos))
 		}
 
-		// Use a WaitGroup to wait for all goroutines to finish
+		songs = make([]*player.Song, len(videos))
+
+		// Only the first songs are resolved right away, the queue resolves the rest when they move up
 		var wg sync.WaitGroup
+		for i, video := range videos {
+			videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.ID)
 
-		// Create a map to store the index of each video ID in the playlist
-		videoIndex := make(map[string]int)
-		for i, video := range playlistVideos.Videos {
-			videoIndex[video.ID] = i
-		}
+			if i >= player.ResolvedAhead {
+				songs[i] = y.lightweightSong(video, videoURL)
+				continue
+			}
 
-		for _, video := range playlistVideos.Videos {
 			wg.Add(1)
-			go func(videoID string) {
+			go func(i int, videoURL string) {
 				defer wg.Done()
 
-				videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
-				song, err := y.GetSongFromVideoURL(videoURL)
+				song, err := y.GetSongFromVideoURL(ctx, videoURL)
 				if err != nil {
-					// Handle the error, e.g., log it
-					fmt.Printf("Error fetching song for video ID %s: %v\n", videoID, err)
+					slog.Warnf("Error fetching song %v: %v", videoURL, err)
 					return
 				}
-
-				// Append the song to the songs slice
-				songs = append(songs, song)
-			}(video.ID)
+				songs[i] = song
+			}(i, videoURL)
 		}
-
-		// Wait for all goroutines to finish
 		wg.Wait()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 
-		// Sort the songs based on the order of playlistVideos.Videos
-		sort.SliceStable(songs, func(i, j int) bool {
-			// Get the index of each song's video ID in the playlist
-			indexI := videoIndex[songs[i].ID]
-			indexJ := videoIndex[songs[j].ID]
-
-			// Compare the indices to determine the order
-			return indexI < indexJ
-		})
+		// Drop songs that failed to resolve
+		resolved := songs[:0]
+		for _, song := range songs {
+			if song != nil {
+				resolved = append(resolved, song)
+			}
+		}
+		songs = resolved
 	} else {
 		// It's a single song
-		song, err := y.GetSongFromVideoURL(url)
+		song, err := y.GetSongFromVideoURL(ctx, url)
 		if err != nil {
 			return nil, err
 		}
@@ -115,23 +176,102 @@ func (y *Youtube) getAllSongsFromURL(url string) ([]*player.Song, error) {
 	return songs, nil
 }
 
-// extractPlaylistID extracts the playlist ID from the given URL.
-func (y *Youtube) extractPlaylistID(url string) string {
-	if strings.Contains(url, "list=") {
-		splitURL := strings.Split(url, "list=")
-		if len(splitURL) > 1 {
-			return splitURL[1]
-		}
+// getPlaylistVideos lists the videos of a playlist, cached playlists aren't looked up again.
+func (y *Youtube) getPlaylistVideos(ctx context.Context, playlistID string) ([]*kkdai_youtube.PlaylistEntry, error) {
+	key := "youtube:playlist:" + playlistID
+
+	var videos []*kkdai_youtube.PlaylistEntry
+	if cached(key, &videos) {
+		return videos, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "youtube playlist", tracing.String("youtube.playlist_id", playlistID))
+	defer span.End()
+
+	playlist, err := y.youtubeClient.GetPlaylistContext(ctx, playlistID)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttributes(tracing.Int("youtube.videos", len(playlist.Videos)))
+
+	cache(key, playlist.Videos)
+	return playlist.Videos, nil
+}
+
+// lightweightSong creates a Song of a playlist entry without its download URL, it's looked up once the song is about to play.
+func (y *Youtube) lightweightSong(video *kkdai_youtube.PlaylistEntry, videoURL string) *player.Song {
+	var thumbnail player.Thumbnail
+	if len(video.Thumbnails) > 0 {
+		thumbnail = player.Thumbnail(video.Thumbnails[0])
+	}
+
+	return &player.Song{
+		Title:     video.Title,
+		UserURL:   videoURL,
+		Duration:  video.Duration,
+		Thumbnail: thumbnail,
+		ID:        video.ID,
+		Source:    player.SourceYouTube,
+		Resolver:  y.resolveSong,
 	}
-	return ""
+}
+
+// resolveSong looks up the download URL of a lightweight song.
+func (y *Youtube) resolveSong(ctx context.Context, song *player.Song) error {
+	resolved, err := y.GetSongFromVideoURL(ctx, song.UserURL)
+	if err != nil {
+		return err
+	}
+
+	song.DownloadURL = resolved.DownloadURL
+	song.Formats = resolved.Formats
+	song.Duration = resolved.Duration
+	song.Thumbnail = resolved.Thumbnail
+	return nil
+}
+
+// extractPlaylistID extracts the playlist ID from the given URL, empty if it has none.
+func (y *Youtube) extractPlaylistID(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("list")
+}
+
+// playlistLimit returns the most videos queued of a playlist, 0 for all of them.
+func playlistLimit() int {
+	config, err := config.NewConfig()
+	if err != nil {
+		slog.Warnf("Error loading config: %v", err)
+		return 0
+	}
+	return config.YoutubePlaylistLimit
 }
 
 // getVideoURLFromTitle retrieves the YouTube video URL from the given title.
-func (y *Youtube) getVideoURLFromTitle(title string) (string, error) {
+func (y *Youtube) getVideoURLFromTitle(ctx context.Context, title string) (string, error) {
+	key := "youtube:search:" + title
+
+	var url string
+	if cached(key, &url) {
+		return url, nil
+	}
+
 	searchURL := fmt.Sprintf("https://www.youtube.com/results?search_query=%v", strings.ReplaceAll(title, " ", "+"))
 
-	resp, err := http.Get(searchURL)
+	ctx, span := tracing.Start(ctx, "youtube search", tracing.String("youtube.query", title))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		span.SetError(err)
 		return "", err
 	}
 	defer resp.Body.Close()
@@ -151,31 +291,41 @@ func (y *Youtube) getVideoURLFromTitle(title string) (string, error) {
 		videoID := matches[0][1]
 		listID := matches[0][2]
 
-		url := "https://www.youtube.com/watch?v=" + videoID
+		url = "https://www.youtube.com/watch?v=" + videoID
 		if listID != "" {
 			url += "&list=" + listID
 		}
 
 		slog.Info(url)
 
+		cache(key, url)
 		return url, nil
 	}
 
 	return "", fmt.Errorf("No video found for the given title")
 }
 
-// FetchSongsByIDs fetches songs by their IDs from the history.
-func (y *Youtube) FetchSongsByIDs(guildID string, ids []int) ([]*player.Song, error) {
+// FetchSongsByIDs fetches songs by their IDs from the history, tracks of other link sources are looked up there.
+func (y *Youtube) FetchSongsByIDs(ctx context.Context, guildID string, ids []int) ([]*player.Song, error) {
 	h := history.NewHistory()
 	var songs []*player.Song
 
 	for _, id := range ids {
-		track, err := h.GetTrackFromHistory(guildID, uint(id))
+		track, err := h.GetTrackFromHistory(ctx, guildID, uint(id))
 		if err != nil {
 			return nil, fmt.Errorf("Error getting track from history with ID %v", id)
 		}
 
-		song, err := y.getAllSongsFromURL(track.URL)
+		if source := LinkSourceFor(track.URL); source != nil {
+			song, err := source.FetchSongsByURLs(ctx, []string{track.URL})
+			if err != nil {
+				return nil, fmt.Errorf("Error fetching new songs from URL: %v", err)
+			}
+			songs = append(songs, song...)
+			continue
+		}
+
+		song, err := y.getAllSongsFromURL(ctx, track.URL)
 		if err != nil {
 			return nil, fmt.Errorf("Error fetching new songs from URL: %v", err)
 		}
@@ -186,17 +336,51 @@ func (y *Youtube) FetchSongsByIDs(guildID string, ids []int) ([]*player.Song, er
 	return songs, nil
 }
 
+// FetchLightweightSongsByIDs creates songs of tracks in the history without looking them up on YouTube
+// or their link source, their download URLs are looked up once they are about to play.
+func (y *Youtube) FetchLightweightSongsByIDs(ctx context.Context, guildID string, ids []uint) ([]*player.Song, error) {
+	h := history.NewHistory()
+	var songs []*player.Song
+
+	for _, id := range ids {
+		track, err := h.GetTrackFromHistory(ctx, guildID, id)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting track from history with ID %v", id)
+		}
+
+		if source := LinkSourceFor(track.URL); source != nil {
+			songs = append(songs, source.ReferenceSong(track.Name, track.URL, track.YTID))
+			continue
+		}
+		songs = append(songs, y.LightweightSong(track.Name, track.URL, track.YTID))
+	}
+
+	return songs, nil
+}
+
+// LightweightSong creates a song of a known YouTube video without looking it up,
+// its download URL is looked up once it's about to play.
+func (y *Youtube) LightweightSong(title, url, id string) *player.Song {
+	return &player.Song{
+		Title:    title,
+		UserURL:  url,
+		ID:       id,
+		Source:   player.SourceYouTube,
+		Resolver: y.resolveSong,
+	}
+}
+
 // FetchSongsByTitles fetches songs by their titles from youtube.
-func (y *Youtube) FetchSongsByTitles(titles []string) ([]*player.Song, error) {
+func (y *Youtube) FetchSongsByTitles(ctx context.Context, titles []string) ([]*player.Song, error) {
 	var songs []*player.Song
 
 	for _, title := range titles {
-		url, err := y.getVideoURLFromTitle(title)
+		url, err := y.getVideoURLFromTitle(ctx, title)
 		if err != nil {
 			return nil, fmt.Errorf("Error getting YouTube video URL by title: %v", err)
 		}
 
-		songs, err = y.getAllSongsFromURL(url)
+		songs, err = y.getAllSongsFromURL(ctx, url)
 		if err != nil {
 			return nil, fmt.Errorf("Error fetching new songs from URL: %v", err)
 		}
@@ -206,15 +390,15 @@ func (y *Youtube) FetchSongsByTitles(titles []string) ([]*player.Song, error) {
 }
 
 // FetchSongsByTitle fetches song by its title from youtube. Or songs if the initial song was part of playlist
-func (y *Youtube) FetchSongsByTitle(title string) ([]*player.Song, error) {
+func (y *Youtube) FetchSongsByTitle(ctx context.Context, title string) ([]*player.Song, error) {
 	var songs []*player.Song
 
-	url, err := y.getVideoURLFromTitle(title)
+	url, err := y.getVideoURLFromTitle(ctx, title)
 	if err != nil {
 		return nil, fmt.Errorf("Error getting YouTube video URL by title: %v", err)
 	}
 
-	songs, err = y.getAllSongsFromURL(url)
+	songs, err = y.getAllSongsFromURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("Error fetching new songs from URL: %v", err)
 	}
@@ -223,11 +407,11 @@ func (y *Youtube) FetchSongsByTitle(title string) ([]*player.Song, error) {
 }
 
 // FetchSongsByURLs fetches songs by their URLs.
-func (y *Youtube) FetchSongsByURLs(urls []string) ([]*player.Song, error) {
+func (y *Youtube) FetchSongsByURLs(ctx context.Context, urls []string) ([]*player.Song, error) {
 	var songs []*player.Song
 
 	for _, url := range urls {
-		song, err := y.getAllSongsFromURL(url)
+		song, err := y.getAllSongsFromURL(ctx, url)
 		if err != nil {
 			return nil, fmt.Errorf("Error fetching new songs from URL: %v", err)
 		}
@@ -239,10 +423,10 @@ func (y *Youtube) FetchSongsByURLs(urls []string) ([]*player.Song, error) {
 }
 
 // FetchSongByURLs fetches song by its URL. Or songs if the initial song was part of playlist
-func (y *Youtube) FetchSongByURLs(url string) ([]*player.Song, error) {
+func (y *Youtube) FetchSongByURLs(ctx context.Context, url string) ([]*player.Song, error) {
 	var songs []*player.Song
 
-	song, err := y.getAllSongsFromURL(url)
+	song, err := y.getAllSongsFromURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("Error fetching new songs from URL: %v", err)
 	}
@@ -0,0 +1,31 @@
+package sources
+
+import (
+	"testing"
+
+	kkdai_youtube "github.com/kkdai/youtube/v2"
+)
+
+func TestAudioFormatsOrder(t *testing.T) {
+	list := kkdai_youtube.FormatList{
+		{ItagNo: 18, URL: "18", MimeType: `video/mp4; codecs="avc1.42001E, mp4a.40.2"`, Bitrate: 500000},
+		{ItagNo: 139, URL: "139", MimeType: `audio/mp4; codecs="mp4a.40.5"`, Bitrate: 48000},
+		{ItagNo: 251, URL: "251", MimeType: `audio/webm; codecs="opus"`, Bitrate: 160000},
+		{ItagNo: 250, URL: "", MimeType: `audio/webm; codecs="opus"`, Bitrate: 70000},
+		{ItagNo: 22, URL: "22", MimeType: `video/mp4; codecs="avc1.64001F, mp4a.40.2"`, Bitrate: 900000},
+	}
+
+	formats := audioFormats(list)
+	want := []string{"251", "139", "22", "18"}
+	if len(formats) != len(want) {
+		t.Fatalf("Got %v formats, want %v", len(formats), len(want))
+	}
+	for i, format := range formats {
+		if format.URL != want[i] {
+			t.Errorf("Format %v is %v, want %v", i, format.URL, want[i])
+		}
+	}
+	if list[0].ItagNo != 18 {
+		t.Errorf("Format list of the video was reordered")
+	}
+}
@@ -0,0 +1,83 @@
+// Package stats aggregates listening statistics and renders them as images.
+package stats
+
+import (
+	"time"
+
+	"github.com/keshon/melodix-discord-player/internal/db"
+)
+
+// Heatmap holds listened seconds by weekday (Monday first) and hour of day.
+type Heatmap [7][24]float64
+
+// BuildHeatmap spreads hourly listening activity over weekdays and hours in the given location.
+func BuildHeatmap(activity []db.ListeningActivity, loc *time.Location) Heatmap {
+	var heatmap Heatmap
+
+	for _, bucket := range activity {
+		t := bucket.Hour.In(loc)
+		weekday := (int(t.Weekday()) + 6) % 7 // Monday first
+		heatmap[weekday][t.Hour()] += bucket.Seconds
+	}
+
+	return heatmap
+}
+
+// DailyTotals returns listened seconds for each of the last days ending with the day of now, oldest first.
+func DailyTotals(activity []db.ListeningActivity, days int, now time.Time, loc *time.Location) []float64 {
+	totals := make([]float64, days)
+
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	for _, bucket := range activity {
+		t := bucket.Hour.In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+		daysAgo := int(today.Sub(day).Hours()/24 + 0.5)
+		if daysAgo < 0 || daysAgo >= days {
+			continue
+		}
+
+		totals[days-1-daysAgo] += bucket.Seconds
+	}
+
+	return totals
+}
+
+// TotalSeconds sums up all listening activity.
+func TotalSeconds(activity []db.ListeningActivity) float64 {
+	total := 0.0
+	for _, bucket := range activity {
+		total += bucket.Seconds
+	}
+	return total
+}
+
+// Streak is a run of consecutive hours with listening activity.
+type Streak struct {
+	Start   time.Time
+	Hours   int
+	Seconds float64
+}
+
+// LongestStreak finds the longest run of consecutive active hours, ties broken by listened seconds.
+// Activity must be sorted by hour.
+func LongestStreak(activity []db.ListeningActivity) Streak {
+	var longest, current Streak
+
+	for i, bucket := range activity {
+		if i > 0 && bucket.Hour.Sub(activity[i-1].Hour) == time.Hour {
+			current.Hours++
+			current.Seconds += bucket.Seconds
+		} else {
+			current = Streak{Start: bucket.Hour, Hours: 1, Seconds: bucket.Seconds}
+		}
+
+		if current.Hours > longest.Hours || (current.Hours == longest.Hours && current.Seconds > longest.Seconds) {
+			longest = current
+		}
+	}
+
+	return longest
+}
@@ -0,0 +1,161 @@
+package stats
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+)
+
+const (
+	cellSize     = 24
+	labelWidth   = 24
+	labelHeight  = 18
+	padding      = 10
+	chartHeight  = 120
+	chartSpacing = 24
+	glyphScale   = 2
+)
+
+var (
+	backgroundColor = color.RGBA{0x2b, 0x2d, 0x31, 0xff}
+	emptyColor      = color.RGBA{0x3a, 0x3c, 0x42, 0xff}
+	accentColor     = color.RGBA{0x9f, 0x00, 0xd4, 0xff}
+	labelColor      = color.RGBA{0xb5, 0xba, 0xc1, 0xff}
+)
+
+// glyphs is a tiny 3x5 pixel font covering the labels drawn on graphs.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'S': {"###", "#..", "###", "..#", "###"},
+}
+
+var weekdayLabels = []string{"M", "T", "W", "T", "F", "S", "S"}
+
+// RenderActivityGraph draws a weekday by hour heatmap with a bar chart of daily totals below it and returns it as PNG.
+func RenderActivityGraph(heatmap Heatmap, daily []float64) ([]byte, error) {
+	gridWidth := 24 * cellSize
+	width := padding + labelWidth + gridWidth + padding
+	height := padding + labelHeight + 7*cellSize + chartSpacing + chartHeight + padding
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{backgroundColor}, image.Point{}, draw.Src)
+
+	gridX := padding + labelWidth
+	gridY := padding + labelHeight
+
+	// Hour labels
+	for hour := 0; hour < 24; hour += 3 {
+		drawText(img, gridX+hour*cellSize+2, padding, strconv.Itoa(hour), labelColor)
+	}
+
+	// Heatmap cells
+	maxValue := 0.0
+	for _, hours := range heatmap {
+		for _, value := range hours {
+			if value > maxValue {
+				maxValue = value
+			}
+		}
+	}
+
+	for weekday, hours := range heatmap {
+		drawText(img, padding, gridY+weekday*cellSize+7, weekdayLabels[weekday], labelColor)
+
+		for hour, value := range hours {
+			cell := image.Rect(
+				gridX+hour*cellSize+1, gridY+weekday*cellSize+1,
+				gridX+(hour+1)*cellSize-1, gridY+(weekday+1)*cellSize-1,
+			)
+			draw.Draw(img, cell, &image.Uniform{intensityColor(value, maxValue)}, image.Point{}, draw.Src)
+		}
+	}
+
+	// Daily bars
+	if len(daily) > 0 {
+		chartY := gridY + 7*cellSize + chartSpacing
+		barWidth := gridWidth / len(daily)
+
+		maxDaily := 0.0
+		for _, value := range daily {
+			if value > maxDaily {
+				maxDaily = value
+			}
+		}
+
+		baseline := image.Rect(gridX, chartY+chartHeight, gridX+barWidth*len(daily), chartY+chartHeight+1)
+		draw.Draw(img, baseline, &image.Uniform{emptyColor}, image.Point{}, draw.Src)
+
+		for i, value := range daily {
+			if maxDaily == 0 || value == 0 {
+				continue
+			}
+			barHeight := int(value / maxDaily * float64(chartHeight))
+			if barHeight < 1 {
+				barHeight = 1
+			}
+			bar := image.Rect(gridX+i*barWidth+1, chartY+chartHeight-barHeight, gridX+(i+1)*barWidth-1, chartY+chartHeight)
+			draw.Draw(img, bar, &image.Uniform{accentColor}, image.Point{}, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// intensityColor blends from the empty cell color to the accent color proportionally to value.
+func intensityColor(value, maxValue float64) color.RGBA {
+	if maxValue == 0 || value == 0 {
+		return emptyColor
+	}
+
+	ratio := value / maxValue
+	blend := func(from, to uint8) uint8 {
+		return uint8(float64(from) + (float64(to)-float64(from))*ratio)
+	}
+
+	return color.RGBA{
+		R: blend(emptyColor.R, accentColor.R),
+		G: blend(emptyColor.G, accentColor.G),
+		B: blend(emptyColor.B, accentColor.B),
+		A: 0xff,
+	}
+}
+
+// drawText draws text with the built-in pixel font, unknown characters are skipped.
+func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
+	for _, r := range text {
+		glyph, ok := glyphs[r]
+		if ok {
+			for row, line := range glyph {
+				for col, pixel := range line {
+					if pixel != '#' {
+						continue
+					}
+					dot := image.Rect(x+col*glyphScale, y+row*glyphScale, x+(col+1)*glyphScale, y+(row+1)*glyphScale)
+					draw.Draw(img, dot, &image.Uniform{c}, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += 4 * glyphScale
+	}
+}
@@ -1,15 +1,9 @@
 package utils
 
 import (
-	"encoding/base64"
 	"fmt"
 	"math"
-	"math/rand"
-	"net"
-	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"regexp"
 )
 
@@ -24,18 +18,6 @@ func FormatDuration(seconds float64) string {
 	return fmt.Sprintf("%02d:%02d:%02.0f", hours, minutes, seconds)
 }
 
-// ReadFileToBase64 reads a file and returns its base64 representation with data URI.
-// Example: base64Data, err := ReadFileToBase64("/path/to/image.jpg")
-func ReadFileToBase64(filePath string) (string, error) {
-	fileContent, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("error reading the file: %v", err)
-	}
-
-	base64Content := base64.StdEncoding.EncodeToString(fileContent)
-	return fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(fileContent), base64Content), nil
-}
-
 // SanitizeString removes unwanted characters from the input string.
 // Example: sanitizedStr := SanitizeString("Hello#World!")
 func SanitizeString(input string) string {
@@ -43,20 +25,6 @@ func SanitizeString(input string) string {
 	return unwantedCharRegex.ReplaceAllString(input, "")
 }
 
-// InferProtocolByPort attempts to infer the protocol based on the availability of a specific port.
-// Example: protocol := InferProtocolByPort("example.com", 443)
-func InferProtocolByPort(hostname string, port int) string {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", hostname, port))
-	if err != nil {
-		// Assuming it's not available, default to HTTP
-		return "http://"
-	}
-	defer conn.Close()
-
-	// The port is available, use HTTPS
-	return "https://"
-}
-
 // ParseQueryParamsFromURL parses query parameters from a URL.
 // Example: params, err := ParseQueryParamsFromURL("https://www.example.com/path?param1=value1&param2=value2")
 func ParseQueryParamsFromURL(urlString string) (map[string]string, error) {
@@ -76,34 +44,6 @@ func ParseQueryParamsFromURL(urlString string) (map[string]string, error) {
 	return params, nil
 }
 
-// GetRandomImagePathFromPath returns the path to a randomly selected file in the specified folder.
-// Example: imagePath, err := GetRandomImagePathFromPath("/path/to/images")
-func GetRandomImagePathFromPath(folderPath string) (string, error) {
-	var validFiles []string
-	files, err := os.ReadDir(folderPath)
-	if err != nil {
-		return "", err
-	}
-
-	// Filter only files with certain extensions (you can modify this if needed)
-	for _, file := range files {
-		if ext := filepath.Ext(file.Name()); ext == ".jpg" || ext == ".png" {
-			validFiles = append(validFiles, file.Name())
-		}
-	}
-
-	if len(validFiles) == 0 {
-		return "", fmt.Errorf("no valid images found")
-	}
-
-	// Get a random index
-	randomIndex := rand.Intn(len(validFiles))
-	randomImage := validFiles[randomIndex]
-	imagePath := filepath.Join(folderPath, randomImage)
-
-	return imagePath, nil
-}
-
 // TrimString trims the string's ending beyond the specified character limit.
 // Example: trimmedText := TrimString("This is a long text.", 10) // Returns "This is a"
 func TrimString(input string, limit int) string {